@@ -21,6 +21,7 @@ import (
 	metrics "github.com/micro/go-plugins/wrapper/monitoring/prometheus"
 	"github.com/paysuper/paysuper-billing-server/internal/config"
 	"github.com/paysuper/paysuper-billing-server/internal/database"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
 	"github.com/paysuper/paysuper-billing-server/internal/service"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	paysuperI18n "github.com/paysuper/paysuper-i18n"
@@ -37,10 +38,12 @@ import (
 	"go.uber.org/zap"
 	"gopkg.in/ProtocolONE/rabbitmq.v1/pkg"
 	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 )
@@ -373,6 +376,14 @@ func (app *Application) TaskAutoAcceptRoyaltyReports() error {
 	return app.svc.AutoAcceptRoyaltyReports(context.TODO(), &billingpb.EmptyRequest{}, &billingpb.EmptyResponse{})
 }
 
+func (app *Application) TaskReleaseRollingReserves() error {
+	return app.svc.ReleaseRollingReserves(context.TODO(), &billingpb.EmptyRequest{}, &billingpb.EmptyResponse{})
+}
+
+func (app *Application) TaskEscalateMerchantDebts() error {
+	return app.svc.EscalateMerchantDebts(context.TODO(), &billingpb.EmptyRequest{}, &billingpb.EmptyResponse{})
+}
+
 func (app *Application) TaskAutoCreatePayouts() error {
 	return app.svc.AutoCreatePayoutDocuments(context.TODO(), &billingpb.EmptyRequest{}, &billingpb.EmptyResponse{})
 }
@@ -389,6 +400,120 @@ func (app *Application) TaskFixTaxes() error {
 	return app.svc.FixTaxes(context.TODO())
 }
 
+func (app *Application) TaskProcessInvoices() error {
+	return app.svc.ProcessInvoices(context.TODO())
+}
+
+func (app *Application) TaskDeliverGifts() error {
+	return app.svc.DeliverScheduledGifts(context.TODO())
+}
+
+func (app *Application) TaskGeneratePriceTable() error {
+	res := &intPkg.PriceTableVersionResponse{}
+	err := app.svc.GeneratePriceTable(context.TODO(), &billingpb.EmptyRequest{}, res)
+
+	if err != nil {
+		return err
+	}
+
+	if res.Status != billingpb.ResponseStatusOk {
+		return res.Message
+	}
+
+	zap.L().Info("Price table draft version created", zap.Int32("version", res.Item.Version))
+
+	return nil
+}
+
+func (app *Application) TaskCheckAccountingIntegrity(date string) error {
+	dateFrom := time.Now().UTC().AddDate(0, 0, -1).Truncate(24 * time.Hour)
+
+	if date != "" {
+		var err error
+		dateFrom, err = time.Parse("2006-01-02", date)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	req := &intPkg.CheckAccountingIntegrityRequest{
+		DateFrom: dateFrom,
+		DateTo:   dateFrom.Add(24 * time.Hour),
+	}
+	res := &intPkg.CheckAccountingIntegrityResponse{}
+	err := app.svc.CheckAccountingIntegrity(context.TODO(), req, res)
+
+	if err != nil {
+		return err
+	}
+
+	if res.Status != billingpb.ResponseStatusOk {
+		return res.Message
+	}
+
+	zap.L().Info(
+		"Accounting integrity check finished",
+		zap.Int32("checked_orders", res.Item.CheckedOrders),
+		zap.Int32("checked_refunds", res.Item.CheckedRefunds),
+		zap.Int32("violations_count", res.Item.ViolationsCount),
+	)
+
+	for _, violation := range res.Item.Violations {
+		zap.L().Warn(
+			"Accounting integrity violation",
+			zap.String("source_id", violation.SourceId),
+			zap.String("source_type", violation.SourceType),
+			zap.String("type", violation.Type),
+			zap.String("entry_type", violation.EntryType),
+			zap.String("identity", violation.Identity),
+			zap.Float64("expected", violation.Expected),
+			zap.Float64("actual", violation.Actual),
+			zap.String("currency", violation.Currency),
+		)
+	}
+
+	return nil
+}
+
+func (app *Application) TaskExportGeneralLedger(date string) error {
+	now := time.Now().UTC()
+	dateFrom := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+
+	if date != "" {
+		d, err := time.Parse("2006-01-02", date)
+
+		if err != nil {
+			return err
+		}
+
+		dateFrom = time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	dateTo := dateFrom.AddDate(0, 1, 0).Add(-time.Nanosecond)
+	exports, err := app.svc.ExportGeneralLedgers(context.TODO(), dateFrom, dateTo)
+
+	if err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		path := filepath.Join(app.cfg.GeneralLedgerExportPath, export.FileName)
+
+		if err = ioutil.WriteFile(path, export.Content, 0644); err != nil {
+			return err
+		}
+
+		zap.L().Info(
+			"General ledger journal exported",
+			zap.String("file", path),
+			zap.Int32("entries_count", export.EntriesCount),
+		)
+	}
+
+	return nil
+}
+
 func (app *Application) KeyDaemonStart() {
 	zap.L().Info("Key daemon started", zap.Int64("RestartInterval", app.cfg.KeyDaemonRestartInterval))
 
@@ -416,3 +541,63 @@ func (app *Application) KeyDaemonStart() {
 		}
 	}()
 }
+
+func (app *Application) TaskVerifyAccountingChain() error {
+	reports, err := app.svc.VerifyAccountingChains(context.TODO())
+
+	if err != nil {
+		return err
+	}
+
+	for _, report := range reports {
+		if report.BrokenLink == nil {
+			zap.L().Info(
+				"Accounting chain verified",
+				zap.String("operating_company_id", report.OperatingCompanyId),
+				zap.Int64("checked_links", report.CheckedLinks),
+			)
+			continue
+		}
+
+		zap.L().Error(
+			"Accounting chain is broken",
+			zap.String("operating_company_id", report.OperatingCompanyId),
+			zap.Int64("sequence", report.BrokenLink.Sequence),
+			zap.String("entry_id", report.BrokenLink.EntryId),
+			zap.String("reason", report.BrokenLink.Reason),
+			zap.String("expected_hash", report.BrokenLink.ExpectedHash),
+			zap.String("actual_hash", report.BrokenLink.ActualHash),
+		)
+	}
+
+	return nil
+}
+
+func (app *Application) TaskReconcileMerchantBalances() error {
+	res := &intPkg.ReconcileMerchantBalanceLedgerResponse{}
+	err := app.svc.ReconcileMerchantBalanceLedger(context.TODO(), &intPkg.ReconcileMerchantBalanceLedgerRequest{}, res)
+
+	if err != nil {
+		return err
+	}
+
+	if res.Status != billingpb.ResponseStatusOk {
+		return res.Message
+	}
+
+	mismatches := 0
+
+	for _, item := range res.Items {
+		if !item.Matched {
+			mismatches++
+		}
+	}
+
+	zap.L().Info(
+		"Merchant balance ledger reconciliation finished",
+		zap.Int("checked_balances", len(res.Items)),
+		zap.Int("mismatches_count", mismatches),
+	)
+
+	return nil
+}
@@ -53,6 +53,10 @@ type EmailTemplates struct {
 	OnboardingCompleted            string `envconfig:"EMAIL_MERCHANT_ONBOARDING_REQUEST_COMPLETE_TEMPLATE" default:"p1_email_merchant_onboarding_request_complete_template"`
 	UserInvite                     string `envconfig:"EMAIL_INVITE_TEMPLATE" default:"code-your-own"`
 	MerchantAgreementSigned        string `envconfig:"EMAIL_MERCHANT_AGREEMENT_SIGNED" default:"p1_agreement_fully_signed"`
+	InvoiceDelivery                string `envconfig:"EMAIL_INVOICE_DELIVERY_TEMPLATE" default:"p1_invoice_delivery"`
+	InvoiceReminder                string `envconfig:"EMAIL_INVOICE_REMINDER_TEMPLATE" default:"p1_invoice_reminder"`
+	GiftDelivery                   string `envconfig:"EMAIL_GIFT_DELIVERY_TEMPLATE" default:"p1_gift_delivery"`
+	GiftRevoked                    string `envconfig:"EMAIL_GIFT_REVOKED_TEMPLATE" default:"p1_gift_revoked"`
 }
 
 type Centrifugo struct {
@@ -102,6 +106,19 @@ type Config struct {
 
 	CentrifugoOrderChannel string `envconfig:"CENTRIFUGO_ORDER_CHANNEL" default:"paysuper:order#%s"`
 
+	// InvoiceReminderDays is the number of days before the invoice due date to send the payment reminder to customer
+	InvoiceReminderDays int `envconfig:"INVOICE_REMINDER_DAYS" default:"3"`
+
+	// TaxEngineMode is the mode of the tax rates calculation, one of "remote", "fallback" or "local".
+	// In fallback mode the local tax engine is used when the tax service is unavailable
+	TaxEngineMode string `envconfig:"TAX_ENGINE_MODE" default:"fallback"`
+	// TaxEngineShadowMode enables comparison of the rates of the primary tax engine with the rates of another one,
+	// mismatches are written to the log
+	TaxEngineShadowMode bool `envconfig:"TAX_ENGINE_SHADOW_MODE" default:"false"`
+
+	// GeneralLedgerExportPath is the directory where the general ledger export task writes the journal files
+	GeneralLedgerExportPath string `envconfig:"GENERAL_LEDGER_EXPORT_PATH" default:"."`
+
 	UserInviteTokenSecret  string `envconfig:"USER_INVITE_TOKEN_SECRET" required:"true"`
 	UserInviteTokenTimeout int64  `envconfig:"USER_INVITE_TOKEN_TIMEOUT" default:"48"`
 
@@ -119,6 +136,25 @@ type Config struct {
 
 	MigrationsLockTimeout int64 `envconfig:"MIGRATIONS_LOCK_TIMEOUT" default:"60"`
 
+	// PriceTableBaseRegion is the region which price table is used as a source for generating other regions tables
+	PriceTableBaseRegion string `envconfig:"PRICE_TABLE_BASE_REGION" default:"USD"`
+	// PriceTablePurchasingPowerCoefficients contains coefficients of the purchasing power for price groups regions,
+	// for example "RUB:0.6,CIS:0.55". Coefficient for regions missing in the list is 1
+	PriceTablePurchasingPowerCoefficients map[string]float64 `envconfig:"PRICE_TABLE_PPP_COEFFICIENTS"`
+
+	// PayoutDualApprovalAmounts contains the payout amounts by currency above which the payout must be approved
+	// by two different approvers, for example "USD:10000,EUR:9000". The payouts in missing currencies require one approval
+	PayoutDualApprovalAmounts map[string]float64 `envconfig:"PAYOUT_DUAL_APPROVAL_AMOUNTS"`
+
+	// The days after opening of the merchant debt when the escalation step is applied, zero disables the step
+	MerchantDebtNotifyDays          int `envconfig:"MERCHANT_DEBT_NOTIFY_DAYS" default:"1"`
+	MerchantDebtSuspendPayoutsDays  int `envconfig:"MERCHANT_DEBT_SUSPEND_PAYOUTS_DAYS" default:"14"`
+	MerchantDebtSuspendPaymentsDays int `envconfig:"MERCHANT_DEBT_SUSPEND_PAYMENTS_DAYS" default:"30"`
+
+	// The default limit of the on-demand payout requests of the merchant per the rolling period in days
+	PayoutRequestsMax        int32 `envconfig:"PAYOUT_REQUESTS_MAX" default:"2"`
+	PayoutRequestsPeriodDays int32 `envconfig:"PAYOUT_REQUESTS_PERIOD_DAYS" default:"30"`
+
 	DashboardUrl string `envconfig:"DASHBOARD_URL" default:"https://paysupermgmt.tst.protocol.one"`
 	CheckoutUrl  string `envconfig:"CHECKOUT_URL" default:"https://checkout.tst.pay.super.com"`
 }
@@ -234,6 +270,10 @@ func (cfg *Config) GetReceiptRefundUrl(transactionId, receiptId string) string {
 	return fmt.Sprintf(pkg.ReceiptRefundUrl, cfg.CheckoutUrl, receiptId, transactionId)
 }
 
+func (cfg *Config) GetInvoiceCheckoutUrl(invoiceUuid string) string {
+	return fmt.Sprintf(pkg.InvoiceCheckoutUrl, cfg.CheckoutUrl, invoiceUuid)
+}
+
 func (cfg *Config) GetEmailConfirmUrl() string {
 	return fmt.Sprintf(pkg.EmailConfirmUrl, cfg.DashboardUrl)
 }
@@ -37,18 +37,50 @@ func (_m *AccountingEntryRepositoryInterface) ApplyObjectSource(_a0 context.Cont
 	return r0, r1
 }
 
-// BulkWrite provides a mock function with given fields: _a0, _a1
-func (_m *AccountingEntryRepositoryInterface) BulkWrite(_a0 context.Context, _a1 []*billingpb.AccountingEntry) error {
+// FindByIds provides a mock function with given fields: _a0, _a1
+func (_m *AccountingEntryRepositoryInterface) FindByIds(_a0 context.Context, _a1 []string) ([]*billingpb.AccountingEntry, error) {
 	ret := _m.Called(_a0, _a1)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, []*billingpb.AccountingEntry) error); ok {
+	var r0 []*billingpb.AccountingEntry
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []*billingpb.AccountingEntry); ok {
 		r0 = rf(_a0, _a1)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*billingpb.AccountingEntry)
+		}
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindByOperatingCompanyDates provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *AccountingEntryRepositoryInterface) FindByOperatingCompanyDates(_a0 context.Context, _a1 string, _a2 time.Time, _a3 time.Time) ([]*billingpb.AccountingEntry, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 []*billingpb.AccountingEntry
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Time) []*billingpb.AccountingEntry); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*billingpb.AccountingEntry)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time, time.Time) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // FindBySource provides a mock function with given fields: _a0, _a1, _a2
@@ -74,6 +106,29 @@ func (_m *AccountingEntryRepositoryInterface) FindBySource(_a0 context.Context,
 	return r0, r1
 }
 
+// FindBySourceTypeDates provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *AccountingEntryRepositoryInterface) FindBySourceTypeDates(_a0 context.Context, _a1 string, _a2 time.Time, _a3 time.Time) ([]*billingpb.AccountingEntry, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 []*billingpb.AccountingEntry
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Time) []*billingpb.AccountingEntry); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*billingpb.AccountingEntry)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time, time.Time) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // FindByTypeCountryDates provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4
 func (_m *AccountingEntryRepositoryInterface) FindByTypeCountryDates(_a0 context.Context, _a1 string, _a2 []string, _a3 time.Time, _a4 time.Time) ([]*billingpb.AccountingEntry, error) {
 	ret := _m.Called(_a0, _a1, _a2, _a3, _a4)
@@ -97,6 +152,29 @@ func (_m *AccountingEntryRepositoryInterface) FindByTypeCountryDates(_a0 context
 	return r0, r1
 }
 
+// FindIdsByOperatingCompany provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *AccountingEntryRepositoryInterface) FindIdsByOperatingCompany(_a0 context.Context, _a1 string, _a2 string, _a3 int64) ([]string, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) []string); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int64) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetById provides a mock function with given fields: _a0, _a1
 func (_m *AccountingEntryRepositoryInterface) GetById(_a0 context.Context, _a1 string) (*billingpb.AccountingEntry, error) {
 	ret := _m.Called(_a0, _a1)
@@ -103,6 +103,27 @@ func (_m *CountryRepositoryInterface) GetByIsoCodeA2(_a0 context.Context, _a1 st
 	return r0, r1
 }
 
+// GetVatRate provides a mock function with given fields: ctx, code
+func (_m *CountryRepositoryInterface) GetVatRate(ctx context.Context, code string) (float64, error) {
+	ret := _m.Called(ctx, code)
+
+	var r0 float64
+	if rf, ok := ret.Get(0).(func(context.Context, string) float64); ok {
+		r0 = rf(ctx, code)
+	} else {
+		r0 = ret.Get(0).(float64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Insert provides a mock function with given fields: _a0, _a1
 func (_m *CountryRepositoryInterface) Insert(_a0 context.Context, _a1 *billingpb.Country) error {
 	ret := _m.Called(_a0, _a1)
@@ -145,6 +166,20 @@ func (_m *CountryRepositoryInterface) MultipleInsert(_a0 context.Context, _a1 []
 	return r0
 }
 
+// SetVatRate provides a mock function with given fields: ctx, code, rate
+func (_m *CountryRepositoryInterface) SetVatRate(ctx context.Context, code string, rate float64) error {
+	ret := _m.Called(ctx, code, rate)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, float64) error); ok {
+		r0 = rf(ctx, code, rate)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Update provides a mock function with given fields: _a0, _a1
 func (_m *CountryRepositoryInterface) Update(_a0 context.Context, _a1 *billingpb.Country) error {
 	ret := _m.Called(_a0, _a1)
@@ -59,29 +59,6 @@ func (_m *OrderViewRepositoryInterface) GetById(_a0 context.Context, _a1 string)
 	return r0, r1
 }
 
-// GetOrderBy provides a mock function with given fields: ctx, id, uuid, merchantId, receiver
-func (_m *OrderViewRepositoryInterface) GetOrderBy(ctx context.Context, id string, uuid string, merchantId string, receiver interface{}) (interface{}, error) {
-	ret := _m.Called(ctx, id, uuid, merchantId, receiver)
-
-	var r0 interface{}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, interface{}) interface{}); ok {
-		r0 = rf(ctx, id, uuid, merchantId, receiver)
-	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(interface{})
-		}
-	}
-
-	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, interface{}) error); ok {
-		r1 = rf(ctx, id, uuid, merchantId, receiver)
-	} else {
-		r1 = ret.Error(1)
-	}
-
-	return r0, r1
-}
-
 // GetPaylinkStat provides a mock function with given fields: ctx, paylinkId, merchantId, from, to
 func (_m *OrderViewRepositoryInterface) GetPaylinkStat(ctx context.Context, paylinkId string, merchantId string, from int64, to int64) (*billingpb.StatCommon, error) {
 	ret := _m.Called(ctx, paylinkId, merchantId, from, to)
@@ -197,6 +174,29 @@ func (_m *OrderViewRepositoryInterface) GetPaylinkStatByUtm(ctx context.Context,
 	return r0, r1
 }
 
+// GetPrivateOrderBy provides a mock function with given fields: ctx, id, uuid, merchantId
+func (_m *OrderViewRepositoryInterface) GetPrivateOrderBy(ctx context.Context, id string, uuid string, merchantId string) (*billingpb.OrderViewPrivate, error) {
+	ret := _m.Called(ctx, id, uuid, merchantId)
+
+	var r0 *billingpb.OrderViewPrivate
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *billingpb.OrderViewPrivate); ok {
+		r0 = rf(ctx, id, uuid, merchantId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*billingpb.OrderViewPrivate)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, id, uuid, merchantId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetPublicByOrderId provides a mock function with given fields: ctx, merchantId
 func (_m *OrderViewRepositoryInterface) GetPublicByOrderId(ctx context.Context, merchantId string) (*billingpb.OrderViewPublic, error) {
 	ret := _m.Called(ctx, merchantId)
@@ -220,6 +220,29 @@ func (_m *OrderViewRepositoryInterface) GetPublicByOrderId(ctx context.Context,
 	return r0, r1
 }
 
+// GetPublicOrderBy provides a mock function with given fields: ctx, id, uuid, merchantId
+func (_m *OrderViewRepositoryInterface) GetPublicOrderBy(ctx context.Context, id string, uuid string, merchantId string) (*billingpb.OrderViewPublic, error) {
+	ret := _m.Called(ctx, id, uuid, merchantId)
+
+	var r0 *billingpb.OrderViewPublic
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *billingpb.OrderViewPublic); ok {
+		r0 = rf(ctx, id, uuid, merchantId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*billingpb.OrderViewPublic)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, id, uuid, merchantId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetRoyaltyForMerchants provides a mock function with given fields: _a0, _a1, _a2, _a3
 func (_m *OrderViewRepositoryInterface) GetRoyaltyForMerchants(_a0 context.Context, _a1 []string, _a2 time.Time, _a3 time.Time) ([]*pkg.RoyaltyReportMerchant, error) {
 	ret := _m.Called(_a0, _a1, _a2, _a3)
@@ -344,6 +367,29 @@ func (_m *OrderViewRepositoryInterface) GetTurnoverSummary(_a0 context.Context,
 	return r0, r1
 }
 
+// GetVatReverseChargeSummary provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4
+func (_m *OrderViewRepositoryInterface) GetVatReverseChargeSummary(_a0 context.Context, _a1 string, _a2 string, _a3 time.Time, _a4 time.Time) ([]*pkg.VatReportQueryResItem, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3, _a4)
+
+	var r0 []*pkg.VatReportQueryResItem
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time, time.Time) []*pkg.VatReportQueryResItem); ok {
+		r0 = rf(_a0, _a1, _a2, _a3, _a4)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*pkg.VatReportQueryResItem)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time, time.Time) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3, _a4)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetVatSummary provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4, _a5
 func (_m *OrderViewRepositoryInterface) GetVatSummary(_a0 context.Context, _a1 string, _a2 string, _a3 bool, _a4 time.Time, _a5 time.Time) ([]*pkg.VatReportQueryResItem, error) {
 	ret := _m.Called(_a0, _a1, _a2, _a3, _a4, _a5)
@@ -11,6 +11,29 @@ type PayoutRepositoryInterface struct {
 	mock.Mock
 }
 
+// ClaimForBatch provides a mock function with given fields: ctx, ids, status, batchId
+func (_m *PayoutRepositoryInterface) ClaimForBatch(ctx context.Context, ids []string, status string, batchId string) ([]string, error) {
+	ret := _m.Called(ctx, ids, status, batchId)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, []string, string, string) []string); ok {
+		r0 = rf(ctx, ids, status, batchId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []string, string, string) error); ok {
+		r1 = rf(ctx, ids, status, batchId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Find provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4, _a5, _a6
 func (_m *PayoutRepositoryInterface) Find(_a0 context.Context, _a1 string, _a2 []string, _a3 int64, _a4 int64, _a5 int64, _a6 int64) ([]*billingpb.PayoutDocument, error) {
 	ret := _m.Called(_a0, _a1, _a2, _a3, _a4, _a5, _a6)
@@ -76,6 +99,29 @@ func (_m *PayoutRepositoryInterface) GetBalanceAmount(_a0 context.Context, _a1 s
 	return r0, r1
 }
 
+// GetBatchIds provides a mock function with given fields: _a0, _a1
+func (_m *PayoutRepositoryInterface) GetBatchIds(_a0 context.Context, _a1 []string) (map[string]string, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 map[string]string
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string]string); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetById provides a mock function with given fields: _a0, _a1
 func (_m *PayoutRepositoryInterface) GetById(_a0 context.Context, _a1 string) (*billingpb.PayoutDocument, error) {
 	ret := _m.Called(_a0, _a1)
@@ -159,6 +205,20 @@ func (_m *PayoutRepositoryInterface) Insert(_a0 context.Context, _a1 *billingpb.
 	return r0
 }
 
+// ReleaseBatch provides a mock function with given fields: _a0, _a1
+func (_m *PayoutRepositoryInterface) ReleaseBatch(_a0 context.Context, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Update provides a mock function with given fields: _a0, _a1, _a2, _a3
 func (_m *PayoutRepositoryInterface) Update(_a0 context.Context, _a1 *billingpb.PayoutDocument, _a2 string, _a3 string) error {
 	ret := _m.Called(_a0, _a1, _a2, _a3)
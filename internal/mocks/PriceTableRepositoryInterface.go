@@ -11,6 +11,29 @@ type PriceTableRepositoryInterface struct {
 	mock.Mock
 }
 
+// GetAll provides a mock function with given fields: _a0
+func (_m *PriceTableRepositoryInterface) GetAll(_a0 context.Context) ([]*billingpb.PriceTable, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []*billingpb.PriceTable
+	if rf, ok := ret.Get(0).(func(context.Context) []*billingpb.PriceTable); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*billingpb.PriceTable)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetByRegion provides a mock function with given fields: _a0, _a1
 func (_m *PriceTableRepositoryInterface) GetByRegion(_a0 context.Context, _a1 string) (*billingpb.PriceTable, error) {
 	ret := _m.Called(_a0, _a1)
@@ -47,3 +70,17 @@ func (_m *PriceTableRepositoryInterface) Insert(_a0 context.Context, _a1 *billin
 
 	return r0
 }
+
+// ReplaceAll provides a mock function with given fields: _a0, _a1
+func (_m *PriceTableRepositoryInterface) ReplaceAll(_a0 context.Context, _a1 []*billingpb.PriceTable) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []*billingpb.PriceTable) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
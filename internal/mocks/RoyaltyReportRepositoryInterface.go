@@ -102,6 +102,29 @@ func (_m *RoyaltyReportRepositoryInterface) GetBalanceAmount(ctx context.Context
 	return r0, r1
 }
 
+// GetBalanceCorrectionEntryIds provides a mock function with given fields: ctx, merchantId, currency, entryIds
+func (_m *RoyaltyReportRepositoryInterface) GetBalanceCorrectionEntryIds(ctx context.Context, merchantId string, currency string, entryIds []string) ([]string, error) {
+	ret := _m.Called(ctx, merchantId, currency, entryIds)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []string) []string); ok {
+		r0 = rf(ctx, merchantId, currency, entryIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, []string) error); ok {
+		r1 = rf(ctx, merchantId, currency, entryIds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetByAcceptedExpireWithStatus provides a mock function with given fields: _a0, _a1, _a2
 func (_m *RoyaltyReportRepositoryInterface) GetByAcceptedExpireWithStatus(_a0 context.Context, _a1 time.Time, _a2 string) ([]*billingpb.RoyaltyReport, error) {
 	ret := _m.Called(_a0, _a1, _a2)
@@ -194,6 +217,29 @@ func (_m *RoyaltyReportRepositoryInterface) GetByPeriod(_a0 context.Context, _a1
 	return r0, r1
 }
 
+// GetLastPeriodByMerchantId provides a mock function with given fields: ctx, merchantId
+func (_m *RoyaltyReportRepositoryInterface) GetLastPeriodByMerchantId(ctx context.Context, merchantId string) ([]*billingpb.RoyaltyReport, error) {
+	ret := _m.Called(ctx, merchantId)
+
+	var r0 []*billingpb.RoyaltyReport
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*billingpb.RoyaltyReport); ok {
+		r0 = rf(ctx, merchantId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*billingpb.RoyaltyReport)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, merchantId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetNonPayoutReports provides a mock function with given fields: ctx, merchantId, currency
 func (_m *RoyaltyReportRepositoryInterface) GetNonPayoutReports(ctx context.Context, merchantId string, currency string) ([]*billingpb.RoyaltyReport, error) {
 	ret := _m.Called(ctx, merchantId, currency)
@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"errors"
 	"github.com/micro/go-micro/client"
 	"github.com/paysuper/paysuper-proto/go/taxpb"
 )
@@ -61,3 +62,41 @@ func (m *TaxServiceOkMock) DeleteRateById(
 ) (*taxpb.DeleteRateResponse, error) {
 	return &taxpb.DeleteRateResponse{}, nil
 }
+
+type TaxServiceErrorMock struct{}
+
+func NewTaxServiceErrorMock() taxpb.TaxService {
+	return &TaxServiceErrorMock{}
+}
+
+func (m *TaxServiceErrorMock) GetRate(
+	ctx context.Context,
+	in *taxpb.GeoIdentity,
+	opts ...client.CallOption,
+) (*taxpb.TaxRate, error) {
+	return nil, errors.New(SomeError)
+}
+
+func (m *TaxServiceErrorMock) GetRates(
+	ctx context.Context,
+	in *taxpb.GetRatesRequest,
+	opts ...client.CallOption,
+) (*taxpb.GetRatesResponse, error) {
+	return nil, errors.New(SomeError)
+}
+
+func (m *TaxServiceErrorMock) CreateOrUpdate(
+	ctx context.Context,
+	in *taxpb.TaxRate,
+	opts ...client.CallOption,
+) (*taxpb.TaxRate, error) {
+	return nil, errors.New(SomeError)
+}
+
+func (m *TaxServiceErrorMock) DeleteRateById(
+	ctx context.Context,
+	in *taxpb.DeleteRateRequest,
+	opts ...client.CallOption,
+) (*taxpb.DeleteRateResponse, error) {
+	return nil, errors.New(SomeError)
+}
@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	// AccountingChainBrokenLinkEntryNotFound means the accounting entry of the chain link was deleted.
+	AccountingChainBrokenLinkEntryNotFound = "entry_not_found"
+	// AccountingChainBrokenLinkHashMismatch means the accounting entry was changed after it was added to the chain.
+	AccountingChainBrokenLinkHashMismatch = "hash_mismatch"
+	// AccountingChainBrokenLinkPreviousHashMismatch means the chain link doesn't refer to the hash of the previous link.
+	AccountingChainBrokenLinkPreviousHashMismatch = "previous_hash_mismatch"
+	// AccountingChainBrokenLinkSequenceGap means the chain link is missing.
+	AccountingChainBrokenLinkSequenceGap = "sequence_gap"
+	// AccountingChainBrokenLinkEntryNotLinked means the accounting entry was saved without the chain link.
+	AccountingChainBrokenLinkEntryNotLinked = "entry_not_linked"
+
+	// AccountingReversalReason is the prefix of the reason of the entry reversing the changed accounting entry.
+	AccountingReversalReason = "reversal"
+	// AccountingReplacementReason is the prefix of the reason of the entry replacing the reversed accounting entry.
+	AccountingReplacementReason = "replacement"
+)
+
+// AccountingChainLink binds the accounting entry to the hash chain of the operating company entries.
+// The hash is calculated from the entry data and the hash of the previous link, so any change
+// of the stored entry breaks the chain.
+type AccountingChainLink struct {
+	Id                 primitive.ObjectID `bson:"_id" json:"id"`
+	OperatingCompanyId string             `bson:"operating_company_id" json:"operating_company_id"`
+	Sequence           int64              `bson:"sequence" json:"sequence"`
+	EntryId            string             `bson:"entry_id" json:"entry_id"`
+	PreviousHash       string             `bson:"previous_hash" json:"previous_hash"`
+	Hash               string             `bson:"hash" json:"hash"`
+	CreatedAt          time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// AccountingEntryReversal links the changed accounting entry with the entries reversing and replacing it.
+// The accounting entry can be reversed only once.
+type AccountingEntryReversal struct {
+	Id                 primitive.ObjectID `bson:"_id" json:"id"`
+	EntryId            string             `bson:"entry_id" json:"entry_id"`
+	ReversalEntryId    string             `bson:"reversal_entry_id" json:"reversal_entry_id"`
+	ReplacementEntryId string             `bson:"replacement_entry_id" json:"replacement_entry_id"`
+	CreatedAt          time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// AccountingChainBrokenLink describes the first link of the chain which failed the verification.
+// The sequence is zero for the accounting entry without the link.
+type AccountingChainBrokenLink struct {
+	Sequence     int64  `json:"sequence"`
+	EntryId      string `json:"entry_id"`
+	Reason       string `json:"reason"`
+	ExpectedHash string `json:"expected_hash"`
+	ActualHash   string `json:"actual_hash"`
+}
+
+// AccountingChainReport contains the result of the hash chain verification of the operating company.
+type AccountingChainReport struct {
+	OperatingCompanyId string                     `json:"operating_company_id"`
+	CheckedLinks       int64                      `json:"checked_links"`
+	BrokenLink         *AccountingChainBrokenLink `json:"broken_link,omitempty"`
+	CreatedAt          time.Time                  `json:"created_at"`
+}
+
+type VerifyAccountingChainRequest struct {
+	OperatingCompanyId string `json:"operating_company_id"`
+}
+
+type VerifyAccountingChainResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *AccountingChainReport          `json:"item,omitempty"`
+}
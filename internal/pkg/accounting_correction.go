@@ -0,0 +1,95 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	// AccountingCorrectionStatusPending is the status of the correction waiting for the financier review.
+	AccountingCorrectionStatusPending = "pending"
+	// AccountingCorrectionStatusApproved is the status of the approved correction which accounting entry isn't
+	// written yet. The approval of the correction in this status is repeated to write the entry.
+	AccountingCorrectionStatusApproved = "approved"
+	// AccountingCorrectionStatusApplied is the status of the approved correction written to the accounting entries.
+	AccountingCorrectionStatusApplied  = "applied"
+	AccountingCorrectionStatusRejected = "rejected"
+
+	AccountingCorrectionActionCreate  = "create"
+	AccountingCorrectionActionApprove = "approve"
+	AccountingCorrectionActionReject  = "reject"
+)
+
+// AccountingCorrectionAttachment contains the metadata of the document attached to the correction.
+// The file itself is stored in the external storage.
+type AccountingCorrectionAttachment struct {
+	Name     string `bson:"name" json:"name"`
+	Url      string `bson:"url" json:"url"`
+	MimeType string `bson:"mime_type" json:"mime_type"`
+	Size     int64  `bson:"size" json:"size"`
+}
+
+// AccountingCorrectionHistoryItem is the record of the audit trail of the correction.
+type AccountingCorrectionHistoryItem struct {
+	Action    string    `bson:"action" json:"action"`
+	UserId    string    `bson:"user_id" json:"user_id"`
+	Comment   string    `bson:"comment" json:"comment"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// AccountingCorrection is the request for the manual correction of the merchant balance.
+// The accounting entry of the correction is created only after the approval by the financier.
+type AccountingCorrection struct {
+	Id                primitive.ObjectID                 `bson:"_id" json:"id"`
+	MerchantId        primitive.ObjectID                 `bson:"merchant_id" json:"merchant_id"`
+	Type              string                             `bson:"type" json:"type"`
+	Amount            float64                            `bson:"amount" json:"amount"`
+	Currency          string                             `bson:"currency" json:"currency"`
+	Reason            string                             `bson:"reason" json:"reason"`
+	Attachments       []*AccountingCorrectionAttachment  `bson:"attachments" json:"attachments"`
+	Status            string                             `bson:"status" json:"status"`
+	CreatedBy         string                             `bson:"created_by" json:"created_by"`
+	ReviewedBy        string                             `bson:"reviewed_by" json:"reviewed_by"`
+	ReviewedAt        time.Time                          `bson:"reviewed_at" json:"reviewed_at"`
+	AccountingEntryId string                             `bson:"accounting_entry_id" json:"accounting_entry_id"`
+	History           []*AccountingCorrectionHistoryItem `bson:"history" json:"history"`
+	CreatedAt         time.Time                          `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time                          `bson:"updated_at" json:"updated_at"`
+}
+
+type CreateAccountingCorrectionRequest struct {
+	MerchantId  string                            `json:"merchant_id"`
+	UserId      string                            `json:"user_id"`
+	Type        string                            `json:"type"`
+	Amount      float64                           `json:"amount"`
+	Currency    string                            `json:"currency"`
+	Reason      string                            `json:"reason"`
+	Attachments []*AccountingCorrectionAttachment `json:"attachments"`
+}
+
+type ReviewAccountingCorrectionRequest struct {
+	Id      string `json:"id"`
+	UserId  string `json:"user_id"`
+	Comment string `json:"comment"`
+}
+
+type AccountingCorrectionResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *AccountingCorrection           `json:"item,omitempty"`
+}
+
+type ListAccountingCorrectionsRequest struct {
+	MerchantId string `json:"merchant_id"`
+	Status     string `json:"status"`
+	Limit      int64  `json:"limit"`
+	Offset     int64  `json:"offset"`
+}
+
+type ListAccountingCorrectionsResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Count   int64                           `json:"count"`
+	Items   []*AccountingCorrection         `json:"items"`
+}
@@ -0,0 +1,63 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"strings"
+)
+
+const (
+	// AccountingRecomputationReason is the prefix of the reason of the correction entries written
+	// from the difference between the recomputed and stored accounting entries.
+	AccountingRecomputationReason = "recomputation correction"
+)
+
+// AccountingDryRunDiff contains the stored and recomputed amounts of the accounting entry type of the source.
+type AccountingDryRunDiff struct {
+	EntryType      string  `json:"entry_type"`
+	Currency       string  `json:"currency"`
+	StoredAmount   float64 `json:"stored_amount"`
+	ComputedAmount float64 `json:"computed_amount"`
+	Difference     float64 `json:"difference"`
+}
+
+// AccountingDryRunResult is the result of the accounting recomputation of the single order or refund.
+type AccountingDryRunResult struct {
+	SourceType         string                  `json:"source_type"`
+	SourceId           string                  `json:"source_id"`
+	MerchantId         string                  `json:"merchant_id"`
+	Changed            bool                    `json:"changed"`
+	Diffs              []*AccountingDryRunDiff `json:"diffs"`
+	CorrectionEntryIds []string                `json:"correction_entry_ids,omitempty"`
+	Error              string                  `json:"error,omitempty"`
+}
+
+type AccountingDryRunRequest struct {
+	OrderIds  []string `json:"order_ids"`
+	RefundIds []string `json:"refund_ids"`
+	// Apply enables writing of the differences as correction entries
+	Apply  bool   `json:"apply"`
+	UserId string `json:"user_id"`
+	Reason string `json:"reason"`
+}
+
+type AccountingDryRunResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Items   []*AccountingDryRunResult       `json:"items"`
+}
+
+// IsAccountingAdjustment reports whether the accounting entry is the adjustment of another entry of the same source
+// and type, written as the closed period adjustment, the recomputation correction or the reversal of the entry.
+func IsAccountingAdjustment(entry *billingpb.AccountingEntry) bool {
+	if IsAccountingPeriodAdjustment(entry) {
+		return true
+	}
+
+	for _, reason := range []string{AccountingRecomputationReason, AccountingReversalReason, AccountingReplacementReason} {
+		if strings.HasPrefix(entry.GetReason(), reason) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,53 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"time"
+)
+
+const (
+	// AccountingIntegrityViolationMissingEntry means the required accounting entry type not found for the source.
+	AccountingIntegrityViolationMissingEntry = "missing_entry"
+	// AccountingIntegrityViolationDuplicateEntry means the accounting entry type found more than once for the source.
+	AccountingIntegrityViolationDuplicateEntry = "duplicate_entry"
+	// AccountingIntegrityViolationCurrencyMismatch means the accounting entries of the source have different currencies.
+	AccountingIntegrityViolationCurrencyMismatch = "currency_mismatch"
+	// AccountingIntegrityViolationIdentityMismatch means the expected identity between the entries is broken.
+	AccountingIntegrityViolationIdentityMismatch = "identity_mismatch"
+	// AccountingIntegrityViolationSourceNotFound means the order or refund of the accounting entries not found.
+	AccountingIntegrityViolationSourceNotFound = "source_not_found"
+)
+
+// AccountingIntegrityViolation describes the single inconsistency found in the accounting entries of the source.
+type AccountingIntegrityViolation struct {
+	SourceId   string  `json:"source_id"`
+	SourceType string  `json:"source_type"`
+	Type       string  `json:"type"`
+	EntryType  string  `json:"entry_type,omitempty"`
+	Identity   string  `json:"identity,omitempty"`
+	Expected   float64 `json:"expected"`
+	Actual     float64 `json:"actual"`
+	Currency   string  `json:"currency,omitempty"`
+}
+
+// AccountingIntegrityReport contains the result of the accounting entries check for the date range.
+type AccountingIntegrityReport struct {
+	DateFrom        time.Time                       `json:"date_from"`
+	DateTo          time.Time                       `json:"date_to"`
+	CheckedOrders   int32                           `json:"checked_orders"`
+	CheckedRefunds  int32                           `json:"checked_refunds"`
+	ViolationsCount int32                           `json:"violations_count"`
+	Violations      []*AccountingIntegrityViolation `json:"violations"`
+	CreatedAt       time.Time                       `json:"created_at"`
+}
+
+type CheckAccountingIntegrityRequest struct {
+	DateFrom time.Time `json:"date_from"`
+	DateTo   time.Time `json:"date_to"`
+}
+
+type CheckAccountingIntegrityResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *AccountingIntegrityReport      `json:"item,omitempty"`
+}
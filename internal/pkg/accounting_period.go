@@ -0,0 +1,89 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+	"time"
+)
+
+const (
+	// AccountingPeriodStatusClosed is the status of the period locked for the changes of the accounting entries.
+	AccountingPeriodStatusClosed = "closed"
+	// AccountingPeriodStatusReopened is the status of the previously closed period opened by the admin.
+	AccountingPeriodStatusReopened = "reopened"
+
+	AccountingPeriodActionClose  = "close"
+	AccountingPeriodActionReopen = "reopen"
+
+	// AccountingPeriodAdjustmentReason is the prefix of the reason of the accounting entries written into the
+	// current open period instead of the closed one.
+	AccountingPeriodAdjustmentReason = "closed period adjustment"
+)
+
+// AccountingPeriodHistoryItem is the record of the audit trail of the accounting period.
+type AccountingPeriodHistoryItem struct {
+	Action    string    `bson:"action" json:"action"`
+	UserId    string    `bson:"user_id" json:"user_id"`
+	Comment   string    `bson:"comment" json:"comment"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// AccountingPeriod is the date range of the operating company accounting. The accounting entries dated inside
+// the closed period can't be added or changed, the late adjustments are written into the current open period.
+type AccountingPeriod struct {
+	Id                 primitive.ObjectID             `bson:"_id" json:"id"`
+	OperatingCompanyId string                         `bson:"operating_company_id" json:"operating_company_id"`
+	DateFrom           time.Time                      `bson:"date_from" json:"date_from"`
+	DateTo             time.Time                      `bson:"date_to" json:"date_to"`
+	Status             string                         `bson:"status" json:"status"`
+	History            []*AccountingPeriodHistoryItem `bson:"history" json:"history"`
+	CreatedAt          time.Time                      `bson:"created_at" json:"created_at"`
+	UpdatedAt          time.Time                      `bson:"updated_at" json:"updated_at"`
+}
+
+// Contains reports whether the date is inside the period.
+func (p *AccountingPeriod) Contains(t time.Time) bool {
+	return !t.Before(p.DateFrom) && !t.After(p.DateTo)
+}
+
+// Overlaps reports whether the period has common dates with the passed date range.
+func (p *AccountingPeriod) Overlaps(from, to time.Time) bool {
+	return !from.After(p.DateTo) && !to.Before(p.DateFrom)
+}
+
+// IsAccountingPeriodAdjustment reports whether the accounting entry was written as adjustment of the closed period.
+func IsAccountingPeriodAdjustment(entry *billingpb.AccountingEntry) bool {
+	return strings.HasPrefix(entry.GetReason(), AccountingPeriodAdjustmentReason)
+}
+
+type CloseAccountingPeriodRequest struct {
+	OperatingCompanyId string    `json:"operating_company_id"`
+	UserId             string    `json:"user_id"`
+	DateFrom           time.Time `json:"date_from"`
+	DateTo             time.Time `json:"date_to"`
+	Comment            string    `json:"comment"`
+}
+
+type ReopenAccountingPeriodRequest struct {
+	Id      string `json:"id"`
+	UserId  string `json:"user_id"`
+	Comment string `json:"comment"`
+}
+
+type AccountingPeriodResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *AccountingPeriod               `json:"item,omitempty"`
+}
+
+type ListAccountingPeriodsRequest struct {
+	OperatingCompanyId string `json:"operating_company_id"`
+	Status             string `json:"status"`
+}
+
+type ListAccountingPeriodsResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Items   []*AccountingPeriod             `json:"items"`
+}
@@ -0,0 +1,61 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"time"
+)
+
+// FxRevaluationReportItem contains the FX result of the payments in the original currency booked in the currency
+// of the merchant balance. The booked amount is converted with the rates stored in the accounting entries.
+// The realized result is calculated with the rates on the payout dates for the amounts already paid to the merchant,
+// the unrealized result is calculated with the rates on the valuation date for the amounts not paid yet.
+type FxRevaluationReportItem struct {
+	OriginalCurrency         string  `json:"original_currency"`
+	Currency                 string  `json:"currency"`
+	RealizedOriginalAmount   float64 `json:"realized_original_amount"`
+	RealizedBookedAmount     float64 `json:"realized_booked_amount"`
+	RealizedAmount           float64 `json:"realized_amount"`
+	RealizedGainLoss         float64 `json:"realized_gain_loss"`
+	UnrealizedOriginalAmount float64 `json:"unrealized_original_amount"`
+	UnrealizedBookedAmount   float64 `json:"unrealized_booked_amount"`
+	UnrealizedAmount         float64 `json:"unrealized_amount"`
+	UnrealizedGainLoss       float64 `json:"unrealized_gain_loss"`
+	// ConversionMargin is the FX result booked at the payment time as the difference of the merchant and common rates
+	ConversionMargin float64 `json:"conversion_margin"`
+}
+
+// FxRevaluationReportTotal contains the FX results of the operating company in the currency of merchant balances.
+type FxRevaluationReportTotal struct {
+	Currency           string  `json:"currency"`
+	RealizedGainLoss   float64 `json:"realized_gain_loss"`
+	UnrealizedGainLoss float64 `json:"unrealized_gain_loss"`
+	ConversionMargin   float64 `json:"conversion_margin"`
+	Total              float64 `json:"total"`
+}
+
+// FxRevaluationReport is the report of FX gains and losses of the operating company between payment and payout
+// for the payments and refunds booked in the period.
+type FxRevaluationReport struct {
+	OperatingCompanyId string                      `json:"operating_company_id"`
+	DateFrom           time.Time                   `json:"date_from"`
+	DateTo             time.Time                   `json:"date_to"`
+	ValuationDate      time.Time                   `json:"valuation_date"`
+	EntriesCount       int32                       `json:"entries_count"`
+	Items              []*FxRevaluationReportItem  `json:"items"`
+	Totals             []*FxRevaluationReportTotal `json:"totals"`
+	CreatedAt          time.Time                   `json:"created_at"`
+}
+
+type GetFxRevaluationReportRequest struct {
+	OperatingCompanyId string    `json:"operating_company_id"`
+	DateFrom           time.Time `json:"date_from"`
+	DateTo             time.Time `json:"date_to"`
+	// ValuationDate is the date of rates for the unrealized result, the current date by default
+	ValuationDate time.Time `json:"valuation_date"`
+}
+
+type GetFxRevaluationReportResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *FxRevaluationReport            `json:"item,omitempty"`
+}
@@ -0,0 +1,119 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	GeneralLedgerExportFormatCsv = "csv"
+	GeneralLedgerExportFormatXml = "xml"
+)
+
+// ChartOfAccountsItem maps the accounting entry type to the debit and credit accounts of the general ledger.
+// The accounts are swapped for the entries with negative amount.
+type ChartOfAccountsItem struct {
+	EntryType     string `bson:"entry_type" json:"entry_type"`
+	DebitAccount  string `bson:"debit_account" json:"debit_account"`
+	CreditAccount string `bson:"credit_account" json:"credit_account"`
+	Description   string `bson:"description" json:"description"`
+}
+
+// ChartOfAccounts contains the general ledger accounts mapping of the operating company.
+type ChartOfAccounts struct {
+	Id                 primitive.ObjectID     `bson:"_id" json:"id"`
+	OperatingCompanyId string                 `bson:"operating_company_id" json:"operating_company_id"`
+	Items              []*ChartOfAccountsItem `bson:"items" json:"items"`
+	UpdatedBy          string                 `bson:"updated_by" json:"updated_by"`
+	CreatedAt          time.Time              `bson:"created_at" json:"created_at"`
+	UpdatedAt          time.Time              `bson:"updated_at" json:"updated_at"`
+}
+
+// GetItem returns the accounts mapping of the accounting entry type or nil if the type isn't mapped.
+func (c *ChartOfAccounts) GetItem(entryType string) *ChartOfAccountsItem {
+	for _, item := range c.Items {
+		if item.EntryType == entryType {
+			return item
+		}
+	}
+
+	return nil
+}
+
+// GeneralLedgerJournalLine is the debit or credit line of the journal. Every accounting entry produces
+// two lines with the same amount, so the journal is always balanced.
+type GeneralLedgerJournalLine struct {
+	LineNumber  int32     `json:"line_number"`
+	EntryId     string    `json:"entry_id"`
+	EntryType   string    `json:"entry_type"`
+	Date        time.Time `json:"date"`
+	Account     string    `json:"account"`
+	Debit       float64   `json:"debit"`
+	Credit      float64   `json:"credit"`
+	Currency    string    `json:"currency"`
+	SourceType  string    `json:"source_type"`
+	SourceId    string    `json:"source_id"`
+	MerchantId  string    `json:"merchant_id"`
+	Description string    `json:"description"`
+}
+
+// GeneralLedgerJournalTotal contains the totals of the journal lines in the currency. The debit and credit are summed
+// independently, so the difference isn't zero if the journal isn't balanced.
+type GeneralLedgerJournalTotal struct {
+	Currency   string  `json:"currency"`
+	Debit      float64 `json:"debit"`
+	Credit     float64 `json:"credit"`
+	Difference float64 `json:"difference"`
+}
+
+// GeneralLedgerJournal is the double-entry journal of the operating company accounting entries for the period.
+type GeneralLedgerJournal struct {
+	OperatingCompany *billingpb.OperatingCompany  `json:"operating_company"`
+	Accounts         []*ChartOfAccountsItem       `json:"accounts"`
+	DateFrom         time.Time                    `json:"date_from"`
+	DateTo           time.Time                    `json:"date_to"`
+	EntriesCount     int32                        `json:"entries_count"`
+	Lines            []*GeneralLedgerJournalLine  `json:"lines"`
+	Totals           []*GeneralLedgerJournalTotal `json:"totals"`
+	CreatedAt        time.Time                    `json:"created_at"`
+}
+
+// GeneralLedgerExport is the journal file for the import into the ERP.
+type GeneralLedgerExport struct {
+	Format       string                       `json:"format"`
+	FileName     string                       `json:"file_name"`
+	ContentType  string                       `json:"content_type"`
+	Content      []byte                       `json:"content"`
+	EntriesCount int32                        `json:"entries_count"`
+	Totals       []*GeneralLedgerJournalTotal `json:"totals"`
+}
+
+type SetChartOfAccountsRequest struct {
+	OperatingCompanyId string                 `json:"operating_company_id"`
+	UserId             string                 `json:"user_id"`
+	Items              []*ChartOfAccountsItem `json:"items"`
+}
+
+type GetChartOfAccountsRequest struct {
+	OperatingCompanyId string `json:"operating_company_id"`
+}
+
+type ChartOfAccountsResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *ChartOfAccounts                `json:"item,omitempty"`
+}
+
+type ExportGeneralLedgerRequest struct {
+	OperatingCompanyId string    `json:"operating_company_id"`
+	DateFrom           time.Time `json:"date_from"`
+	DateTo             time.Time `json:"date_to"`
+	Format             string    `json:"format"`
+}
+
+type ExportGeneralLedgerResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *GeneralLedgerExport            `json:"item,omitempty"`
+}
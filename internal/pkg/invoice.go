@@ -0,0 +1,117 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	InvoiceStatusDraft   = "draft"
+	InvoiceStatusSent    = "sent"
+	InvoiceStatusPaid    = "paid"
+	InvoiceStatusOverdue = "overdue"
+	InvoiceStatusVoid    = "void"
+)
+
+type InvoiceItem struct {
+	Name     string  `bson:"name" json:"name"`
+	Quantity int32   `bson:"quantity" json:"quantity"`
+	Price    float64 `bson:"price" json:"price"`
+	Amount   float64 `bson:"amount" json:"amount"`
+}
+
+// InvoiceDuplicatePayment is the payment of the already paid invoice. The duplicate payment is refunded
+// automatically, the payment which refund failed stays flagged for the manual processing by the merchant.
+type InvoiceDuplicatePayment struct {
+	OrderId   string    `bson:"order_id" json:"order_id"`
+	Amount    float64   `bson:"amount" json:"amount"`
+	Currency  string    `bson:"currency" json:"currency"`
+	RefundId  string    `bson:"refund_id" json:"refund_id,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// Invoice is a bill issued by the merchant to the named customer for the specific amount.
+// Unlike the paylink the invoice can be paid only once, the order created on the invoice checkout
+// is linked to the invoice after the successful payment.
+type Invoice struct {
+	Id            primitive.ObjectID `bson:"_id" json:"id"`
+	Uuid          string             `bson:"uuid" json:"uuid"`
+	MerchantId    primitive.ObjectID `bson:"merchant_id" json:"merchant_id"`
+	ProjectId     primitive.ObjectID `bson:"project_id" json:"project_id"`
+	CustomerEmail string             `bson:"customer_email" json:"customer_email"`
+	Currency      string             `bson:"currency" json:"currency"`
+	Items         []*InvoiceItem     `bson:"items" json:"items"`
+	TotalAmount   float64            `bson:"total_amount" json:"total_amount"`
+	Memo          string             `bson:"memo" json:"memo"`
+	Status        string             `bson:"status" json:"status"`
+	DueDate       time.Time          `bson:"due_date" json:"due_date"`
+	OrderId       string             `bson:"order_id" json:"order_id,omitempty"`
+	// ProcessingOrderId is the last order created on the invoice checkout, the new order can't be created
+	// while the payment of this order is in progress
+	ProcessingOrderId string `bson:"processing_order_id" json:"processing_order_id,omitempty"`
+	// ProcessingStartedAt is the time when the checkout reserved the invoice for the creation of the order
+	ProcessingStartedAt time.Time                  `bson:"processing_started_at" json:"-"`
+	DuplicatePayments   []*InvoiceDuplicatePayment `bson:"duplicate_payments" json:"duplicate_payments,omitempty"`
+	SentAt              time.Time                  `bson:"sent_at" json:"sent_at"`
+	RemindedAt          time.Time                  `bson:"reminded_at" json:"reminded_at"`
+	PaidAt              time.Time                  `bson:"paid_at" json:"paid_at"`
+	CreatedAt           time.Time                  `bson:"created_at" json:"created_at"`
+	UpdatedAt           time.Time                  `bson:"updated_at" json:"updated_at"`
+}
+
+// IsPayable checks that customer can pay the invoice.
+func (m *Invoice) IsPayable() bool {
+	return m.Status == InvoiceStatusSent || m.Status == InvoiceStatusOverdue
+}
+
+type CreateOrUpdateInvoiceRequest struct {
+	Id            string         `json:"id"`
+	MerchantId    string         `json:"merchant_id"`
+	ProjectId     string         `json:"project_id"`
+	CustomerEmail string         `json:"customer_email"`
+	Currency      string         `json:"currency"`
+	Items         []*InvoiceItem `json:"items"`
+	DueDate       time.Time      `json:"due_date"`
+	Memo          string         `json:"memo"`
+}
+
+type InvoiceRequest struct {
+	Id         string `json:"id"`
+	MerchantId string `json:"merchant_id"`
+}
+
+type InvoiceResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *Invoice                        `json:"item,omitempty"`
+}
+
+type ListInvoicesRequest struct {
+	MerchantId string `json:"merchant_id"`
+	Status     string `json:"status"`
+	Limit      int64  `json:"limit"`
+	Offset     int64  `json:"offset"`
+}
+
+type ListInvoicesResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Count   int64                           `json:"count"`
+	Items   []*Invoice                      `json:"items"`
+}
+
+type GetInvoiceUrlResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Url     string                          `json:"url"`
+}
+
+// OrderCreateByInvoiceRequest contains the data of the customer opened the invoice checkout url.
+type OrderCreateByInvoiceRequest struct {
+	InvoiceUuid string `json:"invoice_uuid"`
+	PayerIp     string `json:"payer_ip"`
+	IssuerUrl   string `json:"issuer_url"`
+	IsEmbedded  bool   `json:"is_embedded"`
+	Cookie      string `json:"cookie"`
+}
@@ -0,0 +1,45 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	// TaxEngineModeRemote means the tax rates are calculated by the tax service only.
+	TaxEngineModeRemote = "remote"
+	// TaxEngineModeFallback means the tax rates are calculated by the tax service and
+	// the local tax engine is used when the tax service is unavailable.
+	TaxEngineModeFallback = "fallback"
+	// TaxEngineModeLocal means the tax rates are calculated by the local tax engine only.
+	TaxEngineModeLocal = "local"
+)
+
+// LocalTaxRate contains the tax rate used by the local tax engine. Rate with empty state and zip
+// is the country rate, for US the rate can be set for the whole state or for the exact zip code.
+type LocalTaxRate struct {
+	Id        primitive.ObjectID `bson:"_id" json:"id"`
+	Country   string             `bson:"country" json:"country"`
+	State     string             `bson:"state" json:"state"`
+	Zip       string             `bson:"zip" json:"zip"`
+	Rate      float64            `bson:"rate" json:"rate"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type LocalTaxRateResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *LocalTaxRate                   `json:"item,omitempty"`
+}
+
+type ListLocalTaxRatesRequest struct {
+	Country string `json:"country"`
+}
+
+type ListLocalTaxRatesResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Items   []*LocalTaxRate                 `json:"items"`
+}
@@ -0,0 +1,84 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	MerchantBalanceLedgerEventAccounting     = "accounting"
+	MerchantBalanceLedgerEventRoyaltyReport  = "royalty_report"
+	MerchantBalanceLedgerEventPayout         = "payout"
+	MerchantBalanceLedgerEventReconciliation = "reconciliation"
+)
+
+// MerchantBalanceLedger is the current balance of the merchant in the currency. The balance is updated incrementally
+// by every accounting event instead of the recomputation from all royalty reports and payouts.
+//
+// Pending is the merchant revenue of the accounting entries which are not included into the accepted royalty report yet,
+// available is the amount of the accepted royalty reports without payouts and reserved is the rolling reserve amount.
+type MerchantBalanceLedger struct {
+	Id         primitive.ObjectID `bson:"_id" json:"id"`
+	MerchantId primitive.ObjectID `bson:"merchant_id" json:"merchant_id"`
+	Currency   string             `bson:"currency" json:"currency"`
+	Pending    float64            `bson:"pending" json:"pending"`
+	Available  float64            `bson:"available" json:"available"`
+	Reserved   float64            `bson:"reserved" json:"reserved"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// MerchantBalanceLedgerEntry is the change of the merchant balance made by the accounting event.
+// The key of the accounting entries change is unique, so the same entries can't be applied to the balance twice.
+type MerchantBalanceLedgerEntry struct {
+	Id         primitive.ObjectID `bson:"_id" json:"id"`
+	Key        string             `bson:"key" json:"key"`
+	MerchantId primitive.ObjectID `bson:"merchant_id" json:"merchant_id"`
+	Currency   string             `bson:"currency" json:"currency"`
+	Event      string             `bson:"event" json:"event"`
+	SourceId   string             `bson:"source_id" json:"source_id"`
+	Pending    float64            `bson:"pending" json:"pending"`
+	Available  float64            `bson:"available" json:"available"`
+	Reserved   float64            `bson:"reserved" json:"reserved"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// MerchantBalanceReconciliationItem contains the comparison of the ledger balance with the balance recomputed
+// from royalty reports, payouts and rolling reserve accounting entries.
+type MerchantBalanceReconciliationItem struct {
+	MerchantId          string  `json:"merchant_id"`
+	Currency            string  `json:"currency"`
+	LedgerAvailable     float64 `json:"ledger_available"`
+	ComputedAvailable   float64 `json:"computed_available"`
+	AvailableDifference float64 `json:"available_difference"`
+	LedgerReserved      float64 `json:"ledger_reserved"`
+	ComputedReserved    float64 `json:"computed_reserved"`
+	ReservedDifference  float64 `json:"reserved_difference"`
+	Matched             bool    `json:"matched"`
+	Fixed               bool    `json:"fixed"`
+}
+
+type GetMerchantBalanceLedgerRequest struct {
+	MerchantId string `json:"merchant_id"`
+}
+
+type GetMerchantBalanceLedgerResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Items   []*MerchantBalanceLedger        `json:"items"`
+}
+
+type ReconcileMerchantBalanceLedgerRequest struct {
+	// MerchantId is the merchant to reconcile, all merchants are reconciled if empty
+	MerchantId string `json:"merchant_id"`
+	// Fix allows to write the differences into the ledger, only admin can fix the ledger
+	Fix    bool   `json:"fix"`
+	UserId string `json:"user_id"`
+}
+
+type ReconcileMerchantBalanceLedgerResponse struct {
+	Status  int32                                `json:"status"`
+	Message *billingpb.ResponseErrorMessage      `json:"message,omitempty"`
+	Items   []*MerchantBalanceReconciliationItem `json:"items"`
+}
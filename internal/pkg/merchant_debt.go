@@ -0,0 +1,94 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	MerchantDebtStatusOpen      = "open"
+	MerchantDebtStatusRecovered = "recovered"
+
+	// The escalation levels of the open debt in the order of strictness.
+	MerchantDebtEscalationNone              = ""
+	MerchantDebtEscalationNotified          = "notified"
+	MerchantDebtEscalationPayoutsSuspended  = "payouts_suspended"
+	MerchantDebtEscalationPaymentsSuspended = "payments_suspended"
+
+	MerchantDebtActionOpen      = "open"
+	MerchantDebtActionChange    = "change"
+	MerchantDebtActionRepayment = "repayment"
+	MerchantDebtActionEscalate  = "escalate"
+	MerchantDebtActionRecover   = "recover"
+)
+
+// MerchantDebt is the negative balance of the merchant in the payout currency.
+//
+// The outstanding amount is netted automatically by the following royalty reports and reduced by the repayments
+// recorded by financiers. The repayments are pending until the royalty report with the repayment correction
+// is included into the balance.
+type MerchantDebt struct {
+	Id              primitive.ObjectID         `bson:"_id" json:"id"`
+	MerchantId      primitive.ObjectID         `bson:"merchant_id" json:"merchant_id"`
+	Currency        string                     `bson:"currency" json:"currency"`
+	Outstanding     float64                    `bson:"outstanding" json:"outstanding"`
+	MaxOutstanding  float64                    `bson:"max_outstanding" json:"max_outstanding"`
+	Status          string                     `bson:"status" json:"status"`
+	EscalationLevel string                     `bson:"escalation_level" json:"escalation_level"`
+	Repayments      []*MerchantDebtRepayment   `bson:"repayments" json:"repayments"`
+	History         []*MerchantDebtHistoryItem `bson:"history" json:"history"`
+	OpenedAt        time.Time                  `bson:"opened_at" json:"opened_at"`
+	RecoveredAt     time.Time                  `bson:"recovered_at" json:"recovered_at"`
+	CreatedAt       time.Time                  `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time                  `bson:"updated_at" json:"updated_at"`
+}
+
+// IsPayoutsSuspended checks that the payouts of the merchant are suspended by the escalation of the debt.
+func (d *MerchantDebt) IsPayoutsSuspended() bool {
+	return d.Status == MerchantDebtStatusOpen &&
+		(d.EscalationLevel == MerchantDebtEscalationPayoutsSuspended ||
+			d.EscalationLevel == MerchantDebtEscalationPaymentsSuspended)
+}
+
+// IsPaymentsSuspended checks that the new payments to the merchant are suspended by the escalation of the debt.
+func (d *MerchantDebt) IsPaymentsSuspended() bool {
+	return d.Status == MerchantDebtStatusOpen && d.EscalationLevel == MerchantDebtEscalationPaymentsSuspended
+}
+
+type MerchantDebtRepayment struct {
+	AccountingEntryId string  `bson:"accounting_entry_id" json:"accounting_entry_id"`
+	Amount            float64 `bson:"amount" json:"amount"`
+	Reference         string  `bson:"reference" json:"reference"`
+	UserId            string  `bson:"user_id" json:"user_id"`
+	// Settled is true when the repayment correction is included into the merchant balance
+	Settled   bool      `bson:"settled" json:"settled"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+type MerchantDebtHistoryItem struct {
+	Action      string    `bson:"action" json:"action"`
+	Outstanding float64   `bson:"outstanding" json:"outstanding"`
+	Comment     string    `bson:"comment" json:"comment"`
+	UserId      string    `bson:"user_id" json:"user_id"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+}
+
+type RecordMerchantRepaymentRequest struct {
+	MerchantId string  `json:"merchant_id"`
+	Amount     float64 `json:"amount"`
+	Currency   string  `json:"currency"`
+	// Reference is the bank reference of the incoming transfer
+	Reference string `json:"reference"`
+	UserId    string `json:"user_id"`
+}
+
+type GetMerchantDebtRequest struct {
+	MerchantId string `json:"merchant_id"`
+}
+
+type MerchantDebtResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *MerchantDebt                   `json:"item,omitempty"`
+}
@@ -0,0 +1,120 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"time"
+)
+
+// MerchantPayoutRouting is the multi-currency payout settings of the merchant.
+//
+// The sales in the currency of the route are settled into the payout currency of the route, the sales in other
+// currencies are settled into the main payout currency of the merchant. The royalty reports, balances and payouts
+// are kept separately for every payout currency, the additional currencies are paid out to their own bank accounts.
+type MerchantPayoutRouting struct {
+	Id         primitive.ObjectID       `bson:"_id" json:"id"`
+	MerchantId primitive.ObjectID       `bson:"merchant_id" json:"merchant_id"`
+	Accounts   []*MerchantPayoutAccount `bson:"accounts" json:"accounts"`
+	// Routes maps the sales currency to the payout currency
+	Routes    map[string]string `bson:"routes" json:"routes"`
+	UpdatedBy string            `bson:"updated_by" json:"updated_by"`
+	CreatedAt time.Time         `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time         `bson:"updated_at" json:"updated_at"`
+}
+
+// MerchantPayoutAccount is the bank account for the payouts in the additional payout currency.
+type MerchantPayoutAccount struct {
+	Currency             string `bson:"currency" json:"currency"`
+	Name                 string `bson:"name" json:"name"`
+	Address              string `bson:"address" json:"address"`
+	AccountNumber        string `bson:"account_number" json:"account_number"`
+	Swift                string `bson:"swift" json:"swift"`
+	CorrespondentAccount string `bson:"correspondent_account" json:"correspondent_account"`
+	Details              string `bson:"details" json:"details"`
+}
+
+// GetPayoutCurrency returns the payout currency for the sales in the currency.
+func (r *MerchantPayoutRouting) GetPayoutCurrency(salesCurrency, defaultCurrency string) string {
+	if r == nil {
+		return defaultCurrency
+	}
+
+	if currency, ok := r.Routes[salesCurrency]; ok && currency != "" {
+		return currency
+	}
+
+	return defaultCurrency
+}
+
+// GetAccount returns the bank account of the additional payout currency.
+func (r *MerchantPayoutRouting) GetAccount(currency string) *MerchantPayoutAccount {
+	if r == nil {
+		return nil
+	}
+
+	for _, account := range r.Accounts {
+		if account.Currency == currency {
+			return account
+		}
+	}
+
+	return nil
+}
+
+// GetCurrencies returns all payout currencies of the merchant, the main payout currency goes first.
+func (r *MerchantPayoutRouting) GetCurrencies(defaultCurrency string) []string {
+	currencies := []string{defaultCurrency}
+
+	if r == nil {
+		return currencies
+	}
+
+	var additional []string
+
+	for _, account := range r.Accounts {
+		if account.Currency != defaultCurrency {
+			additional = append(additional, account.Currency)
+		}
+	}
+
+	sort.Strings(additional)
+
+	return append(currencies, additional...)
+}
+
+// ToBanking returns the account as the banking requisites of the payout document.
+func (a *MerchantPayoutAccount) ToBanking() *billingpb.MerchantBanking {
+	return &billingpb.MerchantBanking{
+		Currency:             a.Currency,
+		Name:                 a.Name,
+		Address:              a.Address,
+		AccountNumber:        a.AccountNumber,
+		Swift:                a.Swift,
+		CorrespondentAccount: a.CorrespondentAccount,
+		Details:              a.Details,
+	}
+}
+
+type SetMerchantPayoutRoutingRequest struct {
+	MerchantId string                   `json:"merchant_id"`
+	Accounts   []*MerchantPayoutAccount `json:"accounts"`
+	Routes     map[string]string        `json:"routes"`
+	UserId     string                   `json:"user_id"`
+}
+
+type GetMerchantPayoutRoutingRequest struct {
+	MerchantId string `json:"merchant_id"`
+}
+
+type MerchantPayoutRoutingResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *MerchantPayoutRouting          `json:"item,omitempty"`
+}
+
+type GetMerchantBalancesResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Items   []*billingpb.MerchantBalance    `json:"items,omitempty"`
+}
@@ -0,0 +1,34 @@
+package pkg
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMerchantPayoutRouting_GetPayoutCurrency(t *testing.T) {
+	r := &MerchantPayoutRouting{
+		Accounts: []*MerchantPayoutAccount{{Currency: "EUR"}, {Currency: "USD"}},
+		Routes:   map[string]string{"EUR": "EUR", "GBP": "EUR", "USD": "USD"},
+	}
+
+	assert.Equal(t, "EUR", r.GetPayoutCurrency("GBP", "RUB"))
+	assert.Equal(t, "USD", r.GetPayoutCurrency("USD", "RUB"))
+	assert.Equal(t, "RUB", r.GetPayoutCurrency("KZT", "RUB"))
+
+	var empty *MerchantPayoutRouting
+	assert.Equal(t, "RUB", empty.GetPayoutCurrency("USD", "RUB"))
+}
+
+func TestMerchantPayoutRouting_GetCurrencies(t *testing.T) {
+	r := &MerchantPayoutRouting{
+		Accounts: []*MerchantPayoutAccount{{Currency: "USD"}, {Currency: "EUR"}},
+	}
+
+	assert.Equal(t, []string{"RUB", "EUR", "USD"}, r.GetCurrencies("RUB"))
+	assert.NotNil(t, r.GetAccount("USD"))
+	assert.Nil(t, r.GetAccount("GBP"))
+
+	var empty *MerchantPayoutRouting
+	assert.Equal(t, []string{"RUB"}, empty.GetCurrencies("RUB"))
+	assert.Nil(t, empty.GetAccount("USD"))
+}
@@ -0,0 +1,233 @@
+package pkg
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+const (
+	// MoneyScale is the number of decimal places kept by the money amount in the intermediate calculations.
+	MoneyScale = 8
+	// MoneyAccountingPrecision is the number of decimal places of the amounts stored in the accounting entries.
+	MoneyAccountingPrecision = 6
+	// MoneyDefaultPrecision is the precision of the currency which is not found in the currencies precision list.
+	MoneyDefaultPrecision = 2
+
+	moneyErrorCurrencyMismatch = "money amounts in different currencies can't be summed: %s and %s"
+)
+
+var (
+	moneyScaleMultiplier = new(big.Int).Exp(big.NewInt(10), big.NewInt(MoneyScale), nil)
+)
+
+// Money is the fixed-point amount of money in the currency. The amount is kept as an integer number
+// of 10^-MoneyScale parts of the currency unit, so the sums and differences of the amounts are exact
+// and the rounding is made only once with the currency precision.
+// Money is immutable, all operations return the new value.
+type Money struct {
+	value     *big.Int
+	currency  string
+	precision int32
+}
+
+// NewMoney creates the money amount from the float value. The float value is converted using its
+// shortest decimal representation, so 1.005 is treated as 1.005 and not as 1.00499999999999989.
+func NewMoney(amount float64, currency string, precision int32) Money {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(amount, 'f', -1, 64))
+
+	if !ok {
+		r = new(big.Rat)
+	}
+
+	return Money{
+		value:     roundRat(r, MoneyScale),
+		currency:  currency,
+		precision: precision,
+	}
+}
+
+// Currency returns the currency code of the amount.
+func (m Money) Currency() string {
+	return m.currency
+}
+
+// Precision returns the number of decimal places of the currency.
+func (m Money) Precision() int32 {
+	return m.precision
+}
+
+// Add returns the sum of the amount and the passed amounts. All amounts must be in the same currency,
+// the zero value of Money takes the currency of the passed amount. Add panics on the currencies mismatch.
+func (m Money) Add(amounts ...Money) Money {
+	value := new(big.Int).Set(m.getValue())
+
+	for _, amount := range amounts {
+		m = m.withCurrencyOf(amount)
+		value.Add(value, amount.getValue())
+	}
+
+	return m.withValue(value)
+}
+
+// Sub returns the difference of the amount and the passed amounts. All amounts must be in the same currency,
+// the zero value of Money takes the currency of the passed amount. Sub panics on the currencies mismatch.
+func (m Money) Sub(amounts ...Money) Money {
+	value := new(big.Int).Set(m.getValue())
+
+	for _, amount := range amounts {
+		m = m.withCurrencyOf(amount)
+		value.Sub(value, amount.getValue())
+	}
+
+	return m.withValue(value)
+}
+
+// Mul returns the amount multiplied by the factor, for example by the percent of the fee or by the exchange rate.
+func (m Money) Mul(factor float64) Money {
+	return m.withValue(roundRat(new(big.Rat).Mul(m.rat(), floatRat(factor)), MoneyScale))
+}
+
+// Div returns the amount divided by the divisor. Division by zero returns the zero amount.
+func (m Money) Div(divisor float64) Money {
+	if divisor == 0 {
+		return m.withValue(new(big.Int))
+	}
+
+	return m.withValue(roundRat(new(big.Rat).Quo(m.rat(), floatRat(divisor)), MoneyScale))
+}
+
+// PercentPart returns the part of the amount that corresponds to the rate included into the amount,
+// for example the VAT amount of the gross amount with VAT.
+func (m Money) PercentPart(rate float64) Money {
+	r := floatRat(rate)
+	divisor := new(big.Rat).Add(big.NewRat(1, 1), r)
+
+	if divisor.Sign() == 0 {
+		return m.withValue(new(big.Int))
+	}
+
+	r.Quo(r, divisor)
+
+	return m.withValue(roundRat(r.Mul(r, m.rat()), MoneyScale))
+}
+
+// Neg returns the amount with the opposite sign.
+func (m Money) Neg() Money {
+	return m.withValue(new(big.Int).Neg(m.getValue()))
+}
+
+// Round returns the amount rounded half away from zero to the precision of the currency.
+func (m Money) Round() Money {
+	return m.RoundTo(m.precision)
+}
+
+// RoundTo returns the amount rounded half away from zero to the passed number of decimal places.
+func (m Money) RoundTo(precision int32) Money {
+	if precision >= MoneyScale {
+		return m
+	}
+
+	if precision < 0 {
+		precision = 0
+	}
+
+	value := roundRat(m.rat(), precision)
+	value.Mul(value, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(MoneyScale-precision)), nil))
+
+	return m.withValue(value)
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of the amount.
+func (m Money) Sign() int {
+	return m.getValue().Sign()
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.Sign() == 0
+}
+
+// Cmp compares the amounts and returns -1, 0 or +1.
+func (m Money) Cmp(amount Money) int {
+	return m.getValue().Cmp(amount.getValue())
+}
+
+// Float64 returns the amount as float value for storing in the protobuf messages and database documents.
+func (m Money) Float64() float64 {
+	f, _ := strconv.ParseFloat(m.rat().FloatString(MoneyScale), 64)
+	return f
+}
+
+// String returns the amount formatted with the precision of the currency.
+func (m Money) String() string {
+	return m.Round().rat().FloatString(int(m.precision))
+}
+
+func (m Money) getValue() *big.Int {
+	if m.value == nil {
+		return new(big.Int)
+	}
+
+	return m.value
+}
+
+func (m Money) withValue(value *big.Int) Money {
+	return Money{
+		value:     value,
+		currency:  m.currency,
+		precision: m.precision,
+	}
+}
+
+func (m Money) withCurrencyOf(amount Money) Money {
+	if amount.currency == "" || amount.currency == m.currency {
+		return m
+	}
+
+	if m.currency != "" {
+		panic(fmt.Sprintf(moneyErrorCurrencyMismatch, m.currency, amount.currency))
+	}
+
+	return Money{
+		value:     m.value,
+		currency:  amount.currency,
+		precision: amount.precision,
+	}
+}
+
+func (m Money) rat() *big.Rat {
+	return new(big.Rat).SetFrac(m.getValue(), moneyScaleMultiplier)
+}
+
+func floatRat(f float64) *big.Rat {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+
+	if !ok {
+		return new(big.Rat)
+	}
+
+	return r
+}
+
+// roundRat returns the rational number multiplied by 10^precision and rounded half away from zero.
+func roundRat(r *big.Rat, precision int32) *big.Int {
+	num := new(big.Int).Mul(r.Num(), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(precision)), nil))
+	quo, rem := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+
+	if rem.Sign() == 0 {
+		return quo
+	}
+
+	rem.Abs(rem).Mul(rem, big.NewInt(2))
+
+	if rem.Cmp(r.Denom()) >= 0 {
+		if num.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+
+	return quo
+}
@@ -0,0 +1,70 @@
+package pkg
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMoney_NewMoney_ShortestDecimalRepresentation(t *testing.T) {
+	m := NewMoney(1.005, "USD", 2)
+	assert.Equal(t, 1.005, m.Float64())
+	assert.Equal(t, 1.01, m.Round().Float64())
+	assert.Equal(t, "1.01", m.String())
+	assert.Equal(t, "USD", m.Currency())
+	assert.EqualValues(t, 2, m.Precision())
+}
+
+func TestMoney_AddSub_Exact(t *testing.T) {
+	m := NewMoney(0.1, "USD", 2).Add(NewMoney(0.2, "USD", 2))
+	assert.Equal(t, 0.3, m.Float64())
+
+	m = NewMoney(100, "USD", 2).Sub(NewMoney(33.33, "USD", 2), NewMoney(33.33, "USD", 2), NewMoney(33.34, "USD", 2))
+	assert.True(t, m.IsZero())
+
+	var sum Money
+	for i := 0; i < 1000; i++ {
+		sum = sum.Add(NewMoney(0.01, "USD", 2))
+	}
+	assert.Equal(t, float64(10), sum.Float64())
+}
+
+func TestMoney_AddSub_CurrencyMismatch(t *testing.T) {
+	assert.Panics(t, func() { NewMoney(1, "USD", 2).Add(NewMoney(1, "EUR", 2)) })
+	assert.Panics(t, func() { NewMoney(1, "USD", 2).Sub(NewMoney(1, "USD", 2), NewMoney(1, "RUB", 2)) })
+
+	var sum Money
+	sum = sum.Add(NewMoney(1.005, "JPY", 0))
+	assert.Equal(t, "JPY", sum.Currency())
+	assert.Equal(t, "1", sum.String())
+}
+
+func TestMoney_MulDiv(t *testing.T) {
+	assert.Equal(t, 2.45, NewMoney(100, "USD", 2).Mul(0.0245).Float64())
+	assert.Equal(t, "3", NewMoney(10, "CLP", 0).Div(3).String())
+	assert.True(t, NewMoney(10, "USD", 2).Div(0).IsZero())
+}
+
+func TestMoney_PercentPart(t *testing.T) {
+	assert.Equal(t, float64(20), NewMoney(120, "RUB", 2).PercentPart(0.2).Float64())
+	assert.Equal(t, 19.13, NewMoney(100, "EUR", 2).PercentPart(0.2365).Round().Float64())
+	assert.True(t, NewMoney(100, "EUR", 2).PercentPart(0).IsZero())
+}
+
+func TestMoney_Round_HalfAwayFromZero(t *testing.T) {
+	assert.Equal(t, 2.35, NewMoney(2.345, "RUB", 2).Round().Float64())
+	assert.Equal(t, -2.35, NewMoney(-2.345, "RUB", 2).Round().Float64())
+	assert.Equal(t, 0.001, NewMoney(0.0005, "BHD", 3).Round().Float64())
+	assert.Equal(t, 1.468801, NewMoney(1.4688005, "RUB", 2).RoundTo(MoneyAccountingPrecision).Float64())
+	assert.Equal(t, float64(-2), NewMoney(-2.345, "RUB", 2).RoundTo(0).Float64())
+}
+
+func TestMoney_Compare(t *testing.T) {
+	a := NewMoney(10.5, "USD", 2)
+	b := NewMoney(10.49, "USD", 2)
+
+	assert.Equal(t, 1, a.Cmp(b))
+	assert.Equal(t, -1, b.Cmp(a))
+	assert.Equal(t, 0, a.Cmp(NewMoney(10.5, "USD", 2)))
+	assert.Equal(t, -1, a.Neg().Sign())
+	assert.True(t, Money{}.IsZero())
+}
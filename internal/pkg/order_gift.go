@@ -0,0 +1,55 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	// OrderGiftStatusPending is the status of the gift of the order which is not paid yet.
+	OrderGiftStatusPending = "pending"
+	// OrderGiftStatusScheduled is the status of the gift of the paid order waiting for the delivery date.
+	OrderGiftStatusScheduled = "scheduled"
+	OrderGiftStatusDelivered = "delivered"
+	OrderGiftStatusRevoked   = "revoked"
+)
+
+// OrderGift contains the data of the order bought by the payer for another recipient.
+// Keys and activation emails of the gift order are sent to the recipient at the delivery date,
+// the payer receives the receipt only.
+type OrderGift struct {
+	Id             primitive.ObjectID `bson:"_id" json:"id"`
+	OrderId        primitive.ObjectID `bson:"order_id" json:"order_id"`
+	MerchantId     primitive.ObjectID `bson:"merchant_id" json:"merchant_id"`
+	RecipientEmail string             `bson:"recipient_email" json:"recipient_email"`
+	Message        string             `bson:"message" json:"message"`
+	DeliveryDate   time.Time          `bson:"delivery_date" json:"delivery_date"`
+	Status         string             `bson:"status" json:"status"`
+	DeliveredAt    time.Time          `bson:"delivered_at" json:"delivered_at"`
+	RevokedAt      time.Time          `bson:"revoked_at" json:"revoked_at"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+	// DeliveredKeys contains identifiers of the keys which activation emails are queued for the recipient
+	DeliveredKeys []string `bson:"delivered_keys" json:"delivered_keys,omitempty"`
+}
+
+// SetOrderGiftRequest contains the gift data entered by the payer on the payment form.
+// Empty delivery date means the gift must be delivered right after the payment.
+type SetOrderGiftRequest struct {
+	OrderId        string    `json:"order_id"`
+	RecipientEmail string    `json:"recipient_email"`
+	Message        string    `json:"message"`
+	DeliveryDate   time.Time `json:"delivery_date"`
+}
+
+type OrderGiftRequest struct {
+	OrderId    string `json:"order_id"`
+	MerchantId string `json:"merchant_id"`
+}
+
+type OrderGiftResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *OrderGift                      `json:"item,omitempty"`
+}
@@ -0,0 +1,108 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	PayoutApprovalStatusAwaiting = "awaiting_approval"
+	PayoutApprovalStatusApproved = "approved"
+	PayoutApprovalStatusRejected = "rejected"
+	PayoutApprovalStatusPaid     = "paid"
+
+	PayoutApprovalActionCreate  = "create"
+	PayoutApprovalActionApprove = "approve"
+	PayoutApprovalActionReject  = "reject"
+	PayoutApprovalActionPaid    = "paid"
+)
+
+// PayoutApprover contains the maximal payout amounts by currency which the user can approve.
+type PayoutApprover struct {
+	Id        primitive.ObjectID `bson:"_id" json:"id"`
+	UserId    string             `bson:"user_id" json:"user_id"`
+	Limits    map[string]float64 `bson:"limits" json:"limits"`
+	UpdatedBy string             `bson:"updated_by" json:"updated_by"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// CanApprove checks that the approver limit in the currency covers the amount.
+func (a *PayoutApprover) CanApprove(amount float64, currency string) bool {
+	limit, ok := a.Limits[currency]
+	return ok && limit >= amount
+}
+
+type PayoutApprovalHistoryItem struct {
+	Action    string    `bson:"action" json:"action"`
+	UserId    string    `bson:"user_id" json:"user_id"`
+	Comment   string    `bson:"comment" json:"comment"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// PayoutApproval is the maker-checker state of the payout document.
+//
+// The payout document is created, approved and marked as paid by different users, the payouts above the dual
+// approval amount require two approvers. History keeps the audit trail of all actions.
+type PayoutApproval struct {
+	Id                primitive.ObjectID           `bson:"_id" json:"id"`
+	PayoutDocumentId  string                       `bson:"payout_document_id" json:"payout_document_id"`
+	MerchantId        string                       `bson:"merchant_id" json:"merchant_id"`
+	Amount            float64                      `bson:"amount" json:"amount"`
+	Currency          string                       `bson:"currency" json:"currency"`
+	Status            string                       `bson:"status" json:"status"`
+	RequiredApprovals int32                        `bson:"required_approvals" json:"required_approvals"`
+	CreatedBy         string                       `bson:"created_by" json:"created_by"`
+	ApprovedBy        []string                     `bson:"approved_by" json:"approved_by"`
+	PaidBy            string                       `bson:"paid_by" json:"paid_by"`
+	History           []*PayoutApprovalHistoryItem `bson:"history" json:"history"`
+	CreatedAt         time.Time                    `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time                    `bson:"updated_at" json:"updated_at"`
+}
+
+// IsParticipant checks that the user already created or approved the payout.
+func (a *PayoutApproval) IsParticipant(userId string) bool {
+	if a.CreatedBy == userId {
+		return true
+	}
+
+	for _, approver := range a.ApprovedBy {
+		if approver == userId {
+			return true
+		}
+	}
+
+	return false
+}
+
+type SetPayoutApproverRequest struct {
+	ApproverId string             `json:"approver_id"`
+	Limits     map[string]float64 `json:"limits"`
+	UserId     string             `json:"user_id"`
+}
+
+type PayoutApproverResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *PayoutApprover                 `json:"item,omitempty"`
+}
+
+type PayoutApprovalActionRequest struct {
+	PayoutDocumentId string `json:"payout_document_id"`
+	UserId           string `json:"user_id"`
+	Comment          string `json:"comment"`
+	// Transaction is the bank transaction of the paid payout
+	Transaction string `json:"transaction"`
+	Ip          string `json:"ip"`
+}
+
+type GetPayoutApprovalRequest struct {
+	PayoutDocumentId string `json:"payout_document_id"`
+}
+
+type PayoutApprovalResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *PayoutApproval                 `json:"item,omitempty"`
+}
@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	// PayoutBatchFormatSepa is the ISO 20022 pain.001.001.03 credit transfer for EUR payouts to SEPA accounts.
+	PayoutBatchFormatSepa = "sepa_pain001"
+	// PayoutBatchFormatNacha is the NACHA file of ACH credits for USD payouts to the US bank accounts.
+	PayoutBatchFormatNacha = "nacha"
+	// PayoutBatchFormatSwiftCsv is the generic CSV of SWIFT transfers for all other payouts.
+	PayoutBatchFormatSwiftCsv = "swift_csv"
+)
+
+// PayoutBatchDebtor is the bank account of the operating company used to pay the payout documents of the batch.
+// IBAN and BIC are required for the SEPA transfers, the routing number, account number and company identification
+// are required for the ACH transfers.
+type PayoutBatchDebtor struct {
+	Name          string `bson:"name" json:"name"`
+	Iban          string `bson:"iban" json:"iban"`
+	Bic           string `bson:"bic" json:"bic"`
+	BankName      string `bson:"bank_name" json:"bank_name"`
+	RoutingNumber string `bson:"routing_number" json:"routing_number"`
+	AccountNumber string `bson:"account_number" json:"account_number"`
+	CompanyId     string `bson:"company_id" json:"company_id"`
+}
+
+// PayoutBatch is the bank transfer file generated for the set of pending payout documents.
+type PayoutBatch struct {
+	Id                primitive.ObjectID `bson:"_id" json:"id"`
+	Format            string             `bson:"format" json:"format"`
+	Currency          string             `bson:"currency" json:"currency"`
+	PayoutDocumentIds []string           `bson:"payout_document_ids" json:"payout_document_ids"`
+	ItemsCount        int32              `bson:"items_count" json:"items_count"`
+	TotalAmount       float64            `bson:"total_amount" json:"total_amount"`
+	Debtor            *PayoutBatchDebtor `bson:"debtor" json:"debtor"`
+	ExecutionDate     time.Time          `bson:"execution_date" json:"execution_date"`
+	FileName          string             `bson:"file_name" json:"file_name"`
+	Content           []byte             `bson:"content" json:"content"`
+	CreatedBy         string             `bson:"created_by" json:"created_by"`
+	CreatedAt         time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// PayoutBatchRejectedItem is the payout document which can't be included into the batch.
+type PayoutBatchRejectedItem struct {
+	PayoutDocumentId string `json:"payout_document_id"`
+	Reason           string `json:"reason"`
+}
+
+type ExportPayoutBatchesRequest struct {
+	PayoutDocumentIds []string           `json:"payout_document_ids"`
+	Debtor            *PayoutBatchDebtor `json:"debtor"`
+	// ExecutionDate is the requested date of the transfers, the next day is used if empty
+	ExecutionDate time.Time `json:"execution_date"`
+	UserId        string    `json:"user_id"`
+}
+
+type ExportPayoutBatchesResponse struct {
+	Status   int32                           `json:"status"`
+	Message  *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Items    []*PayoutBatch                  `json:"items"`
+	Rejected []*PayoutBatchRejectedItem      `json:"rejected"`
+}
+
+type GetPayoutBatchRequest struct {
+	Id string `json:"id"`
+}
+
+type PayoutBatchResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *PayoutBatch                    `json:"item,omitempty"`
+}
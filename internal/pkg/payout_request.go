@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// PayoutRequestLimit is the limit of the on-demand payout requests of the merchant per the rolling period.
+type PayoutRequestLimit struct {
+	Id          primitive.ObjectID `bson:"_id" json:"id"`
+	MerchantId  primitive.ObjectID `bson:"merchant_id" json:"merchant_id"`
+	MaxRequests int32              `bson:"max_requests" json:"max_requests"`
+	PeriodDays  int32              `bson:"period_days" json:"period_days"`
+	UpdatedBy   string             `bson:"updated_by" json:"updated_by"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// PayoutRequest is the on-demand payout requested by the merchant.
+type PayoutRequest struct {
+	Id               primitive.ObjectID `bson:"_id" json:"id"`
+	MerchantId       primitive.ObjectID `bson:"merchant_id" json:"merchant_id"`
+	PayoutDocumentId string             `bson:"payout_document_id" json:"payout_document_id"`
+	Currency         string             `bson:"currency" json:"currency"`
+	Amount           float64            `bson:"amount" json:"amount"`
+	UserId           string             `bson:"user_id" json:"user_id"`
+	Ip               string             `bson:"ip" json:"ip"`
+	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// PayoutRequestReservation is the reservation of the balance of the merchant by the on-demand payout request
+// in progress. The reservation is identified by the merchant, so the merchant has at most one reservation at a time
+// and the limit of requests and the available balance are checked and spent by one request only.
+type PayoutRequestReservation struct {
+	MerchantId    primitive.ObjectID `bson:"_id" json:"merchant_id"`
+	ReservationId string             `bson:"reservation_id" json:"reservation_id"`
+	Currency      string             `bson:"currency" json:"currency"`
+	// Amount is the requested amount, zero if the whole available balance is requested
+	Amount     float64   `bson:"amount" json:"amount"`
+	ReservedAt time.Time `bson:"reserved_at" json:"reserved_at"`
+}
+
+type RequestPayoutRequest struct {
+	MerchantId string `json:"merchant_id"`
+	// Currency is the payout currency of the balance, the main payout currency of the merchant is used if empty
+	Currency string `json:"currency"`
+	// Amount is the requested part of the available balance, the whole available balance is requested if zero
+	Amount      float64 `json:"amount"`
+	Description string  `json:"description"`
+	UserId      string  `json:"user_id"`
+	Ip          string  `json:"ip"`
+}
+
+type RequestPayoutResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *billingpb.PayoutDocument       `json:"item,omitempty"`
+}
+
+type SetPayoutRequestLimitRequest struct {
+	MerchantId  string `json:"merchant_id"`
+	MaxRequests int32  `json:"max_requests"`
+	PeriodDays  int32  `json:"period_days"`
+	UserId      string `json:"user_id"`
+}
+
+type GetPayoutRequestLimitRequest struct {
+	MerchantId string `json:"merchant_id"`
+}
+
+type PayoutRequestLimitResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *PayoutRequestLimit             `json:"item,omitempty"`
+}
@@ -0,0 +1,128 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	PayoutSchedulePeriodWeekly   = "weekly"
+	PayoutSchedulePeriodBiWeekly = "bi-weekly"
+	PayoutSchedulePeriodMonthly  = "monthly"
+
+	// PayoutScheduleMaxDayOfMonth is the last day of month which exists in every month.
+	PayoutScheduleMaxDayOfMonth = 28
+)
+
+// PayoutSchedule is the schedule of the automatic payouts of the merchant.
+//
+// The weekly and bi-weekly payouts are made on the day of week counting from the anchor date, the monthly payouts
+// on the day of month. The balance below the minimum amount of the currency is held until it crosses the minimum.
+type PayoutSchedule struct {
+	Id         primitive.ObjectID `bson:"_id" json:"id"`
+	MerchantId primitive.ObjectID `bson:"merchant_id" json:"merchant_id"`
+	Period     string             `bson:"period" json:"period"`
+	// DayOfWeek is the day of the weekly and bi-weekly payouts, 0 is Sunday
+	DayOfWeek  int32              `bson:"day_of_week" json:"day_of_week"`
+	DayOfMonth int32              `bson:"day_of_month" json:"day_of_month"`
+	AnchorDate time.Time          `bson:"anchor_date" json:"anchor_date"`
+	MinAmounts map[string]float64 `bson:"min_amounts" json:"min_amounts"`
+	// LastPayoutAt is the time of the last scheduled run of the automatic payout, including the held ones
+	LastPayoutAt time.Time `bson:"last_payout_at" json:"last_payout_at"`
+	UpdatedBy    string    `bson:"updated_by" json:"updated_by"`
+	CreatedAt    time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// NextDate returns the first payout date of the schedule strictly after the day of the given time.
+func (s *PayoutSchedule) NextDate(after time.Time) time.Time {
+	day := truncateToDay(after).AddDate(0, 0, 1)
+
+	if s.Period == PayoutSchedulePeriodMonthly {
+		date := time.Date(day.Year(), day.Month(), int(s.DayOfMonth), 0, 0, 0, 0, time.UTC)
+
+		if date.Before(day) {
+			date = date.AddDate(0, 1, 0)
+		}
+
+		return date
+	}
+
+	step := 7
+
+	if s.Period == PayoutSchedulePeriodBiWeekly {
+		step = 14
+	}
+
+	anchor := truncateToDay(s.AnchorDate)
+
+	if !anchor.Before(day) {
+		return anchor
+	}
+
+	days := int(day.Sub(anchor).Hours() / 24)
+	periods := (days + step - 1) / step
+
+	return anchor.AddDate(0, 0, periods*step)
+}
+
+// NextPayoutDate returns the date of the next scheduled payout, the date in the past means that the payout is due.
+func (s *PayoutSchedule) NextPayoutDate() time.Time {
+	if s.LastPayoutAt.IsZero() {
+		return s.NextDate(s.CreatedAt.AddDate(0, 0, -1))
+	}
+
+	return s.NextDate(s.LastPayoutAt)
+}
+
+// IsDue checks that the scheduled payout date is reached.
+func (s *PayoutSchedule) IsDue(now time.Time) bool {
+	return !s.NextPayoutDate().After(now)
+}
+
+// GetMinAmount returns the minimum payout amount in the currency, zero means that any positive balance is paid.
+func (s *PayoutSchedule) GetMinAmount(currency string) float64 {
+	return s.MinAmounts[currency]
+}
+
+// GetPayoutScheduleAnchorDate returns the first day on or after the given time falling on the day of week.
+func GetPayoutScheduleAnchorDate(from time.Time, dayOfWeek int32) time.Time {
+	day := truncateToDay(from)
+	shift := (int(dayOfWeek) - int(day.Weekday()) + 7) % 7
+
+	return day.AddDate(0, 0, shift)
+}
+
+func truncateToDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+type SetPayoutScheduleRequest struct {
+	MerchantId string             `json:"merchant_id"`
+	Period     string             `json:"period"`
+	DayOfWeek  int32              `json:"day_of_week"`
+	DayOfMonth int32              `json:"day_of_month"`
+	MinAmounts map[string]float64 `json:"min_amounts"`
+	UserId     string             `json:"user_id"`
+}
+
+type GetPayoutScheduleRequest struct {
+	MerchantId string `json:"merchant_id"`
+}
+
+type PayoutScheduleResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *PayoutSchedule                 `json:"item,omitempty"`
+}
+
+// MerchantNextPayout is the expected automatic payout of the merchant by the payout schedule.
+type MerchantNextPayout struct {
+	Date      time.Time `json:"date"`
+	Amount    float64   `json:"amount"`
+	MinAmount float64   `json:"min_amount"`
+	// Held is true when the balance is below the minimum amount and the payout will be skipped on the date
+	Held bool `json:"held"`
+}
@@ -0,0 +1,63 @@
+package pkg
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPayoutSchedule_NextDate_Weekly(t *testing.T) {
+	s := &PayoutSchedule{
+		Period:     PayoutSchedulePeriodWeekly,
+		DayOfWeek:  int32(time.Monday),
+		AnchorDate: time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	assert.Equal(t, time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC), s.NextDate(time.Date(2020, 3, 1, 23, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2020, 3, 9, 0, 0, 0, 0, time.UTC), s.NextDate(time.Date(2020, 3, 2, 10, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2020, 3, 16, 0, 0, 0, 0, time.UTC), s.NextDate(time.Date(2020, 3, 9, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestPayoutSchedule_NextDate_BiWeekly(t *testing.T) {
+	s := &PayoutSchedule{
+		Period:     PayoutSchedulePeriodBiWeekly,
+		DayOfWeek:  int32(time.Monday),
+		AnchorDate: time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	assert.Equal(t, time.Date(2020, 3, 16, 0, 0, 0, 0, time.UTC), s.NextDate(time.Date(2020, 3, 3, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2020, 3, 16, 0, 0, 0, 0, time.UTC), s.NextDate(time.Date(2020, 3, 15, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2020, 3, 30, 0, 0, 0, 0, time.UTC), s.NextDate(time.Date(2020, 3, 16, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestPayoutSchedule_NextDate_Monthly(t *testing.T) {
+	s := &PayoutSchedule{Period: PayoutSchedulePeriodMonthly, DayOfMonth: 15}
+
+	assert.Equal(t, time.Date(2020, 3, 15, 0, 0, 0, 0, time.UTC), s.NextDate(time.Date(2020, 3, 14, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2020, 4, 15, 0, 0, 0, 0, time.UTC), s.NextDate(time.Date(2020, 3, 15, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2021, 1, 15, 0, 0, 0, 0, time.UTC), s.NextDate(time.Date(2020, 12, 20, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestPayoutSchedule_IsDue(t *testing.T) {
+	s := &PayoutSchedule{
+		Period:     PayoutSchedulePeriodWeekly,
+		DayOfWeek:  int32(time.Monday),
+		AnchorDate: GetPayoutScheduleAnchorDate(time.Date(2020, 2, 28, 12, 0, 0, 0, time.UTC), int32(time.Monday)),
+		CreatedAt:  time.Date(2020, 2, 28, 12, 0, 0, 0, time.UTC),
+	}
+
+	assert.Equal(t, time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC), s.AnchorDate)
+	assert.False(t, s.IsDue(time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, s.IsDue(time.Date(2020, 3, 2, 8, 0, 0, 0, time.UTC)))
+
+	s.LastPayoutAt = time.Date(2020, 3, 2, 8, 0, 0, 0, time.UTC)
+	assert.False(t, s.IsDue(time.Date(2020, 3, 5, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, s.IsDue(time.Date(2020, 3, 10, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestPayoutSchedule_GetMinAmount(t *testing.T) {
+	s := &PayoutSchedule{MinAmounts: map[string]float64{"USD": 100}}
+
+	assert.Equal(t, float64(100), s.GetMinAmount("USD"))
+	assert.Equal(t, float64(0), s.GetMinAmount("EUR"))
+}
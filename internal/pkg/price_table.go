@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-billing-server/internal/repository/models"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	PriceTableVersionStatusDraft    = "draft"
+	PriceTableVersionStatusActive   = "active"
+	PriceTableVersionStatusArchived = "archived"
+)
+
+// PriceTableVersion is a snapshot of the price tables of all regions.
+// The active version always matches the content of the price table collection,
+// archived versions are kept to be able to rollback the price tables.
+type PriceTableVersion struct {
+	Id           primitive.ObjectID      `bson:"_id" json:"id"`
+	Version      int32                   `bson:"version" json:"version"`
+	Status       string                  `bson:"status" json:"status"`
+	BaseRegion   string                  `bson:"base_region" json:"base_region"`
+	Rates        map[string]float64      `bson:"rates" json:"rates"`
+	Coefficients map[string]float64      `bson:"coefficients" json:"coefficients"`
+	Tables       []*models.MgoPriceTable `bson:"tables" json:"tables"`
+	CreatedAt    time.Time               `bson:"created_at" json:"created_at"`
+	ActivatedAt  time.Time               `bson:"activated_at" json:"activated_at"`
+}
+
+type PriceTableRangeDiff struct {
+	Position      int32   `json:"position"`
+	CurrentFrom   float64 `json:"current_from"`
+	CurrentTo     float64 `json:"current_to"`
+	NewFrom       float64 `json:"new_from"`
+	NewTo         float64 `json:"new_to"`
+	ChangePercent float64 `json:"change_percent"`
+}
+
+type PriceTableRegionDiff struct {
+	Region  string                 `json:"region"`
+	Added   bool                   `json:"added"`
+	Removed bool                   `json:"removed"`
+	Ranges  []*PriceTableRangeDiff `json:"ranges"`
+}
+
+type PriceTableVersionRequest struct {
+	VersionId string `json:"version_id"`
+}
+
+type PriceTableVersionResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *PriceTableVersion              `json:"item,omitempty"`
+}
+
+type PriceTableDiffResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Version *PriceTableVersion              `json:"version,omitempty"`
+	Regions []*PriceTableRegionDiff         `json:"regions,omitempty"`
+}
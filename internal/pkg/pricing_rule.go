@@ -0,0 +1,73 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	// PricingRuleTypeFraction sets the fractional part of the price, for example 0.99.
+	PricingRuleTypeFraction = "fraction"
+	// PricingRuleTypeRoundTo rounds the price up to the multiple of the value, for example 100.
+	PricingRuleTypeRoundTo = "round_to"
+	// PricingRuleTypeThreshold moves the price down to the nearest psychological threshold (for example 999 or 1999)
+	// if the price exceeds it not more than on the value percents.
+	PricingRuleTypeThreshold = "threshold"
+	// PricingRuleTypeMinimum sets the minimal price.
+	PricingRuleTypeMinimum = "minimum"
+)
+
+type PricingRule struct {
+	Type       string    `bson:"type" json:"type"`
+	Value      float64   `bson:"value" json:"value"`
+	Thresholds []float64 `bson:"thresholds" json:"thresholds,omitempty"`
+}
+
+// PriceGroupPricingRules contains the chain of the pricing rules applied to the recommended prices of the price group.
+// Rules are applied in the order of declaration.
+type PriceGroupPricingRules struct {
+	Id           primitive.ObjectID `bson:"_id" json:"id"`
+	PriceGroupId primitive.ObjectID `bson:"price_group_id" json:"price_group_id"`
+	Rules        []*PricingRule     `bson:"rules" json:"rules"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type GetPricingRulesRequest struct {
+	PriceGroupId string `json:"price_group_id"`
+}
+
+type SetPricingRulesRequest struct {
+	PriceGroupId string         `json:"price_group_id"`
+	Rules        []*PricingRule `json:"rules"`
+}
+
+type PricingRulesResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *PriceGroupPricingRules         `json:"item,omitempty"`
+}
+
+const (
+	ProductPricesFillMethodPriceGroup = "price_group"
+	ProductPricesFillMethodConversion = "conversion"
+)
+
+// FillProductPricesRequest contains parameters for automatic filling of the product prices
+// in all price groups regions by the price in the base currency.
+type FillProductPricesRequest struct {
+	MerchantId string  `json:"merchant_id"`
+	ProductId  string  `json:"product_id"`
+	Currency   string  `json:"currency"`
+	Amount     float64 `json:"amount"`
+	Method     string  `json:"method"`
+	// Overwrite replaces the prices already set on the product, otherwise only missing regions are filled
+	Overwrite bool `json:"overwrite"`
+}
+
+type FillProductPricesResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Prices  []*billingpb.ProductPrice       `json:"prices,omitempty"`
+}
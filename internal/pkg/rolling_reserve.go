@@ -0,0 +1,81 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	// RollingReserveStatusHeld is the status of the reserve waiting for the release date.
+	RollingReserveStatusHeld     = "held"
+	RollingReserveStatusReleased = "released"
+)
+
+// RollingReservePolicy is the rule of the automatic rolling reserve of the merchant.
+//
+// The percent (10 means 10%) of the gross revenue of each royalty period is held for the hold days. If the cap is set the total held
+// amount never exceeds it, the policy without percent holds the fixed amount of the cap.
+type RollingReservePolicy struct {
+	Id         primitive.ObjectID `bson:"_id" json:"id"`
+	MerchantId primitive.ObjectID `bson:"merchant_id" json:"merchant_id"`
+	Percent    float64            `bson:"percent" json:"percent"`
+	HoldDays   int32              `bson:"hold_days" json:"hold_days"`
+	Cap        float64            `bson:"cap" json:"cap"`
+	Enabled    bool               `bson:"enabled" json:"enabled"`
+	UpdatedBy  string             `bson:"updated_by" json:"updated_by"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// RollingReserve is the amount held from the royalty period of the merchant until the release date.
+type RollingReserve struct {
+	Id              primitive.ObjectID `bson:"_id" json:"id"`
+	MerchantId      primitive.ObjectID `bson:"merchant_id" json:"merchant_id"`
+	Currency        string             `bson:"currency" json:"currency"`
+	RoyaltyReportId string             `bson:"royalty_report_id" json:"royalty_report_id"`
+	PeriodFrom      time.Time          `bson:"period_from" json:"period_from"`
+	PeriodTo        time.Time          `bson:"period_to" json:"period_to"`
+	Amount          float64            `bson:"amount" json:"amount"`
+	Status          string             `bson:"status" json:"status"`
+	CreateEntryId   string             `bson:"create_entry_id" json:"create_entry_id"`
+	ReleaseEntryId  string             `bson:"release_entry_id" json:"release_entry_id"`
+	ReleaseAt       time.Time          `bson:"release_at" json:"release_at"`
+	ReleasedAt      time.Time          `bson:"released_at" json:"released_at"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type SetRollingReservePolicyRequest struct {
+	MerchantId string  `json:"merchant_id"`
+	Percent    float64 `json:"percent"`
+	HoldDays   int32   `json:"hold_days"`
+	Cap        float64 `json:"cap"`
+	Enabled    bool    `json:"enabled"`
+	UserId     string  `json:"user_id"`
+}
+
+type GetRollingReservePolicyRequest struct {
+	MerchantId string `json:"merchant_id"`
+}
+
+type RollingReservePolicyResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *RollingReservePolicy           `json:"item,omitempty"`
+}
+
+// MerchantBalanceDetails extends the merchant balance with the data which can't be passed in the balance message.
+type MerchantBalanceDetails struct {
+	Balance *billingpb.MerchantBalance `json:"balance"`
+	// RollingReserveSchedule contains the held rolling reserves ordered by the release date
+	RollingReserveSchedule []*RollingReserve `json:"rolling_reserve_schedule"`
+	// NextPayout is set for the merchants with the automatic payouts by the payout schedule
+	NextPayout *MerchantNextPayout `json:"next_payout,omitempty"`
+}
+
+type GetMerchantBalanceDetailsResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *MerchantBalanceDetails         `json:"item,omitempty"`
+}
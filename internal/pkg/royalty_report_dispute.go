@@ -0,0 +1,152 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	RoyaltyReportDisputeStatusOpen   = "open"
+	RoyaltyReportDisputeStatusClosed = "closed"
+
+	// RoyaltyReportDisputeLineTypeReport is the line disputing the whole royalty report
+	RoyaltyReportDisputeLineTypeReport  = "report"
+	RoyaltyReportDisputeLineTypeOrder   = "order"
+	RoyaltyReportDisputeLineTypeSummary = "summary"
+
+	RoyaltyReportDisputeLineStatusOpen      = "open"
+	RoyaltyReportDisputeLineStatusCorrected = "corrected"
+	RoyaltyReportDisputeLineStatusRejected  = "rejected"
+
+	RoyaltyReportDisputeAuthorMerchant  = "merchant"
+	RoyaltyReportDisputeAuthorFinancier = "financier"
+)
+
+// RoyaltyReportDispute is the dispute of the royalty report by the merchant.
+//
+// The merchant disputes the orders or the summary lines of the report, every line is resolved separately
+// by the correction of the report or the rejection. The dispute is closed when all lines are resolved
+// and the report returns to the merchant for the review.
+type RoyaltyReportDispute struct {
+	Id         primitive.ObjectID             `bson:"_id" json:"id"`
+	ReportId   string                         `bson:"report_id" json:"report_id"`
+	MerchantId string                         `bson:"merchant_id" json:"merchant_id"`
+	Status     string                         `bson:"status" json:"status"`
+	Lines      []*RoyaltyReportDisputeLine    `bson:"lines" json:"lines"`
+	Comments   []*RoyaltyReportDisputeComment `bson:"comments" json:"comments"`
+	CreatedBy  string                         `bson:"created_by" json:"created_by"`
+	CreatedAt  time.Time                      `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time                      `bson:"updated_at" json:"updated_at"`
+	ClosedAt   time.Time                      `bson:"closed_at" json:"closed_at"`
+}
+
+// RoyaltyReportDisputeLine is the disputed order or summary line of the royalty report.
+type RoyaltyReportDisputeLine struct {
+	Id   string `bson:"id" json:"id"`
+	Type string `bson:"type" json:"type"`
+	// OrderId is the public identifier of the disputed order
+	OrderId string `bson:"order_id" json:"order_id"`
+	// Product and Region identify the disputed summary line of the report
+	Product string  `bson:"product" json:"product"`
+	Region  string  `bson:"region" json:"region"`
+	Amount  float64 `bson:"amount" json:"amount"`
+	Reason  string  `bson:"reason" json:"reason"`
+	Status  string  `bson:"status" json:"status"`
+	// AccountingEntryId is the correction accounting entry of the corrected line
+	AccountingEntryId string    `bson:"accounting_entry_id" json:"accounting_entry_id"`
+	CorrectionAmount  float64   `bson:"correction_amount" json:"correction_amount"`
+	ResolutionComment string    `bson:"resolution_comment" json:"resolution_comment"`
+	ResolvedBy        string    `bson:"resolved_by" json:"resolved_by"`
+	ResolvedAt        time.Time `bson:"resolved_at" json:"resolved_at"`
+}
+
+// RoyaltyReportDisputeComment is the comment of the dispute thread, the comment with the line identifier
+// belongs to the thread of the line.
+type RoyaltyReportDisputeComment struct {
+	Id          string                            `bson:"id" json:"id"`
+	LineId      string                            `bson:"line_id" json:"line_id"`
+	Author      string                            `bson:"author" json:"author"`
+	UserId      string                            `bson:"user_id" json:"user_id"`
+	Text        string                            `bson:"text" json:"text"`
+	Attachments []*RoyaltyReportDisputeAttachment `bson:"attachments" json:"attachments"`
+	CreatedAt   time.Time                         `bson:"created_at" json:"created_at"`
+}
+
+// RoyaltyReportDisputeAttachment is the metadata of the file uploaded to the file storage.
+type RoyaltyReportDisputeAttachment struct {
+	Name        string `bson:"name" json:"name"`
+	ContentType string `bson:"content_type" json:"content_type"`
+	Size        int64  `bson:"size" json:"size"`
+	Url         string `bson:"url" json:"url"`
+}
+
+// GetLine returns the line of the dispute by the identifier.
+func (d *RoyaltyReportDispute) GetLine(id string) *RoyaltyReportDisputeLine {
+	for _, line := range d.Lines {
+		if line.Id == id {
+			return line
+		}
+	}
+
+	return nil
+}
+
+// IsResolved checks that all lines of the dispute are resolved.
+func (d *RoyaltyReportDispute) IsResolved() bool {
+	for _, line := range d.Lines {
+		if line.Status == RoyaltyReportDisputeLineStatusOpen {
+			return false
+		}
+	}
+
+	return true
+}
+
+type OpenRoyaltyReportDisputeRequest struct {
+	ReportId    string                            `json:"report_id"`
+	MerchantId  string                            `json:"merchant_id"`
+	UserId      string                            `json:"user_id"`
+	Ip          string                            `json:"ip"`
+	Lines       []*RoyaltyReportDisputeLine       `json:"lines"`
+	Comment     string                            `json:"comment"`
+	Attachments []*RoyaltyReportDisputeAttachment `json:"attachments"`
+}
+
+type AddRoyaltyReportDisputeCommentRequest struct {
+	DisputeId string `json:"dispute_id"`
+	LineId    string `json:"line_id"`
+	// MerchantId is set for the comments of the merchant, the comments without it are written by the financiers
+	MerchantId  string                            `json:"merchant_id"`
+	UserId      string                            `json:"user_id"`
+	Text        string                            `json:"text"`
+	Attachments []*RoyaltyReportDisputeAttachment `json:"attachments"`
+}
+
+type ResolveRoyaltyReportDisputeLineRequest struct {
+	DisputeId string `json:"dispute_id"`
+	LineId    string `json:"line_id"`
+	// Resolution is the new status of the line: corrected or rejected
+	Resolution       string  `json:"resolution"`
+	CorrectionAmount float64 `json:"correction_amount"`
+	Comment          string  `json:"comment"`
+	UserId           string  `json:"user_id"`
+	Ip               string  `json:"ip"`
+}
+
+type GetRoyaltyReportDisputesRequest struct {
+	ReportId   string `json:"report_id"`
+	MerchantId string `json:"merchant_id"`
+}
+
+type RoyaltyReportDisputeResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *RoyaltyReportDispute           `json:"item,omitempty"`
+}
+
+type RoyaltyReportDisputesResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Items   []*RoyaltyReportDispute         `json:"items,omitempty"`
+}
@@ -0,0 +1,28 @@
+package pkg
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRoyaltyReportDispute_GetLine(t *testing.T) {
+	d := &RoyaltyReportDispute{
+		Lines: []*RoyaltyReportDisputeLine{{Id: "1"}, {Id: "2"}},
+	}
+
+	assert.Equal(t, d.Lines[1], d.GetLine("2"))
+	assert.Nil(t, d.GetLine("3"))
+}
+
+func TestRoyaltyReportDispute_IsResolved(t *testing.T) {
+	d := &RoyaltyReportDispute{
+		Lines: []*RoyaltyReportDisputeLine{
+			{Id: "1", Status: RoyaltyReportDisputeLineStatusRejected},
+			{Id: "2", Status: RoyaltyReportDisputeLineStatusOpen},
+		},
+	}
+	assert.False(t, d.IsResolved())
+
+	d.Lines[1].Status = RoyaltyReportDisputeLineStatusCorrected
+	assert.True(t, d.IsResolved())
+}
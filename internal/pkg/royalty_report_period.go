@@ -0,0 +1,111 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	RoyaltyReportPeriodWeekly   = "weekly"
+	RoyaltyReportPeriodBiWeekly = "bi-weekly"
+	RoyaltyReportPeriodMonthly  = "monthly"
+
+	// RoyaltyReportMaxDayOfMonth is the last day of month which exists in every month.
+	RoyaltyReportMaxDayOfMonth = 28
+)
+
+// RoyaltyReportPeriod is the royalty report period of the merchant.
+//
+// The weekly and bi-weekly periods end on the day of week, the monthly periods on the day of month, at the cut-off
+// hour in the time zone of the merchant. The next period always starts right after the end of the previous royalty
+// report of the merchant, so the change of the settings shortens or extends the first period by the new settings
+// instead of leaving a gap or an overlap.
+type RoyaltyReportPeriod struct {
+	Id         primitive.ObjectID `bson:"_id" json:"id"`
+	MerchantId primitive.ObjectID `bson:"merchant_id" json:"merchant_id"`
+	Period     string             `bson:"period" json:"period"`
+	TimeZone   string             `bson:"time_zone" json:"time_zone"`
+	// DayOfWeek is the last day of the weekly and bi-weekly periods, 0 is Sunday
+	DayOfWeek  int32     `bson:"day_of_week" json:"day_of_week"`
+	DayOfMonth int32     `bson:"day_of_month" json:"day_of_month"`
+	CutOffHour int32     `bson:"cut_off_hour" json:"cut_off_hour"`
+	UpdatedBy  string    `bson:"updated_by" json:"updated_by"`
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// GetLastCutOff returns the last cut-off of the period on or before the given time.
+func (p *RoyaltyReportPeriod) GetLastCutOff(t time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(p.TimeZone)
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t = t.In(loc)
+
+	if p.Period == RoyaltyReportPeriodMonthly {
+		cutOff := time.Date(t.Year(), t.Month(), int(p.DayOfMonth), int(p.CutOffHour), 0, 0, 0, loc)
+
+		if cutOff.After(t) {
+			cutOff = cutOff.AddDate(0, -1, 0)
+		}
+
+		return cutOff, nil
+	}
+
+	shift := (int(t.Weekday()) - int(p.DayOfWeek) + 7) % 7
+	cutOff := time.Date(t.Year(), t.Month(), t.Day()-shift, int(p.CutOffHour), 0, 0, 0, loc)
+
+	if cutOff.After(t) {
+		cutOff = cutOff.AddDate(0, 0, -7)
+	}
+
+	return cutOff, nil
+}
+
+// GetPeriodStart returns the start of the full period ending at the cut-off,
+// it's used for the first royalty report of the merchant.
+func (p *RoyaltyReportPeriod) GetPeriodStart(cutOff time.Time) time.Time {
+	switch p.Period {
+	case RoyaltyReportPeriodMonthly:
+		cutOff = cutOff.AddDate(0, -1, 0)
+	case RoyaltyReportPeriodBiWeekly:
+		cutOff = cutOff.AddDate(0, 0, -14)
+	default:
+		cutOff = cutOff.AddDate(0, 0, -7)
+	}
+
+	return cutOff.Add(1 * time.Millisecond)
+}
+
+// IsClosed checks that the period started after the end of the previous period is closed by the cut-off.
+// The bi-weekly period is closed by the first cut-off which is more than a week after the previous period end.
+func (p *RoyaltyReportPeriod) IsClosed(previousEnd, cutOff time.Time) bool {
+	if p.Period == RoyaltyReportPeriodBiWeekly {
+		return cutOff.After(previousEnd.In(cutOff.Location()).AddDate(0, 0, 7))
+	}
+
+	return cutOff.After(previousEnd)
+}
+
+type SetRoyaltyReportPeriodRequest struct {
+	MerchantId string `json:"merchant_id"`
+	Period     string `json:"period"`
+	TimeZone   string `json:"time_zone"`
+	DayOfWeek  int32  `json:"day_of_week"`
+	DayOfMonth int32  `json:"day_of_month"`
+	CutOffHour int32  `json:"cut_off_hour"`
+	UserId     string `json:"user_id"`
+}
+
+type GetRoyaltyReportPeriodRequest struct {
+	MerchantId string `json:"merchant_id"`
+}
+
+type RoyaltyReportPeriodResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *RoyaltyReportPeriod            `json:"item,omitempty"`
+}
@@ -0,0 +1,83 @@
+package pkg
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRoyaltyReportPeriod_GetLastCutOff_Weekly(t *testing.T) {
+	p := &RoyaltyReportPeriod{
+		Period:     RoyaltyReportPeriodWeekly,
+		TimeZone:   "Europe/Moscow",
+		DayOfWeek:  int32(time.Monday),
+		CutOffHour: 18,
+	}
+	loc, _ := time.LoadLocation(p.TimeZone)
+
+	cutOff, err := p.GetLastCutOff(time.Date(2020, 3, 4, 10, 0, 0, 0, loc))
+	assert.NoError(t, err)
+	assert.True(t, cutOff.Equal(time.Date(2020, 3, 2, 18, 0, 0, 0, loc)))
+
+	cutOff, err = p.GetLastCutOff(time.Date(2020, 3, 9, 17, 0, 0, 0, loc))
+	assert.NoError(t, err)
+	assert.True(t, cutOff.Equal(time.Date(2020, 3, 2, 18, 0, 0, 0, loc)))
+
+	cutOff, err = p.GetLastCutOff(time.Date(2020, 3, 9, 18, 0, 0, 0, loc))
+	assert.NoError(t, err)
+	assert.True(t, cutOff.Equal(time.Date(2020, 3, 9, 18, 0, 0, 0, loc)))
+}
+
+func TestRoyaltyReportPeriod_GetLastCutOff_Monthly(t *testing.T) {
+	p := &RoyaltyReportPeriod{Period: RoyaltyReportPeriodMonthly, TimeZone: "America/New_York", DayOfMonth: 1}
+	loc, _ := time.LoadLocation(p.TimeZone)
+
+	cutOff, err := p.GetLastCutOff(time.Date(2020, 3, 20, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.True(t, cutOff.Equal(time.Date(2020, 3, 1, 0, 0, 0, 0, loc)))
+
+	// it's still February in New York
+	cutOff, err = p.GetLastCutOff(time.Date(2020, 3, 1, 2, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.True(t, cutOff.Equal(time.Date(2020, 2, 1, 0, 0, 0, 0, loc)))
+}
+
+func TestRoyaltyReportPeriod_GetLastCutOff_TimeZoneInvalid(t *testing.T) {
+	p := &RoyaltyReportPeriod{Period: RoyaltyReportPeriodWeekly, TimeZone: "incorrect_timezone"}
+
+	_, err := p.GetLastCutOff(time.Now())
+	assert.Error(t, err)
+}
+
+func TestRoyaltyReportPeriod_GetPeriodStart(t *testing.T) {
+	cutOff := time.Date(2020, 3, 16, 18, 0, 0, 0, time.UTC)
+
+	p := &RoyaltyReportPeriod{Period: RoyaltyReportPeriodWeekly}
+	assert.Equal(t, time.Date(2020, 3, 9, 18, 0, 0, int(time.Millisecond), time.UTC), p.GetPeriodStart(cutOff))
+
+	p.Period = RoyaltyReportPeriodBiWeekly
+	assert.Equal(t, time.Date(2020, 3, 2, 18, 0, 0, int(time.Millisecond), time.UTC), p.GetPeriodStart(cutOff))
+
+	p.Period = RoyaltyReportPeriodMonthly
+	assert.Equal(t, time.Date(2020, 2, 16, 18, 0, 0, int(time.Millisecond), time.UTC), p.GetPeriodStart(cutOff))
+}
+
+func TestRoyaltyReportPeriod_IsClosed_BiWeekly(t *testing.T) {
+	p := &RoyaltyReportPeriod{Period: RoyaltyReportPeriodBiWeekly}
+	previousEnd := time.Date(2020, 3, 2, 18, 0, 0, 0, time.UTC)
+
+	assert.False(t, p.IsClosed(previousEnd, previousEnd))
+	assert.False(t, p.IsClosed(previousEnd, time.Date(2020, 3, 9, 18, 0, 0, 0, time.UTC)))
+	assert.True(t, p.IsClosed(previousEnd, time.Date(2020, 3, 16, 18, 0, 0, 0, time.UTC)))
+
+	// the first period after the change of the settings from the monthly period
+	assert.True(t, p.IsClosed(time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 3, 9, 18, 0, 0, 0, time.UTC)))
+}
+
+func TestRoyaltyReportPeriod_IsClosed_Weekly(t *testing.T) {
+	p := &RoyaltyReportPeriod{Period: RoyaltyReportPeriodWeekly}
+	previousEnd := time.Date(2020, 3, 2, 18, 0, 0, 0, time.UTC)
+
+	assert.False(t, p.IsClosed(previousEnd, previousEnd))
+	assert.True(t, p.IsClosed(previousEnd, time.Date(2020, 3, 9, 18, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,98 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"time"
+)
+
+// RoyaltyReportVersion is the generation of the royalty report. The report is regenerated as the new version
+// while it's pending or disputed, the version keeps the totals of the report and their changes
+// against the previous version.
+type RoyaltyReportVersion struct {
+	Id         primitive.ObjectID `bson:"_id" json:"id"`
+	ReportId   string             `bson:"report_id" json:"report_id"`
+	MerchantId string             `bson:"merchant_id" json:"merchant_id"`
+	Version    int32              `bson:"version" json:"version"`
+	Currency   string             `bson:"currency" json:"currency"`
+	// Status is the status of the royalty report at the moment of the generation
+	Status string                      `bson:"status" json:"status"`
+	Totals *RoyaltyReportVersionTotals `bson:"totals" json:"totals"`
+	// Diff is the change of the totals against the previous version, it's empty for the first version
+	Diff      *RoyaltyReportVersionTotals `bson:"diff" json:"diff"`
+	Reason    string                      `bson:"reason" json:"reason"`
+	CreatedBy string                      `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time                   `bson:"created_at" json:"created_at"`
+}
+
+type RoyaltyReportVersionTotals struct {
+	TransactionsCount    int32   `bson:"transactions_count" json:"transactions_count"`
+	FeeAmount            float64 `bson:"fee_amount" json:"fee_amount"`
+	VatAmount            float64 `bson:"vat_amount" json:"vat_amount"`
+	PayoutAmount         float64 `bson:"payout_amount" json:"payout_amount"`
+	CorrectionAmount     float64 `bson:"correction_amount" json:"correction_amount"`
+	RollingReserveAmount float64 `bson:"rolling_reserve_amount" json:"rolling_reserve_amount"`
+}
+
+// NewRoyaltyReportVersionTotals returns the copy of the royalty report totals.
+func NewRoyaltyReportVersionTotals(totals *billingpb.RoyaltyReportTotals) *RoyaltyReportVersionTotals {
+	if totals == nil {
+		return &RoyaltyReportVersionTotals{}
+	}
+
+	return &RoyaltyReportVersionTotals{
+		TransactionsCount:    totals.TransactionsCount,
+		FeeAmount:            totals.FeeAmount,
+		VatAmount:            totals.VatAmount,
+		PayoutAmount:         totals.PayoutAmount,
+		CorrectionAmount:     totals.CorrectionAmount,
+		RollingReserveAmount: totals.RollingReserveAmount,
+	}
+}
+
+// Sub returns the difference of the totals and the previous totals rounded to the cents.
+func (t *RoyaltyReportVersionTotals) Sub(previous *RoyaltyReportVersionTotals) *RoyaltyReportVersionTotals {
+	return &RoyaltyReportVersionTotals{
+		TransactionsCount:    t.TransactionsCount - previous.TransactionsCount,
+		FeeAmount:            roundRoyaltyReportVersionAmount(t.FeeAmount - previous.FeeAmount),
+		VatAmount:            roundRoyaltyReportVersionAmount(t.VatAmount - previous.VatAmount),
+		PayoutAmount:         roundRoyaltyReportVersionAmount(t.PayoutAmount - previous.PayoutAmount),
+		CorrectionAmount:     roundRoyaltyReportVersionAmount(t.CorrectionAmount - previous.CorrectionAmount),
+		RollingReserveAmount: roundRoyaltyReportVersionAmount(t.RollingReserveAmount - previous.RollingReserveAmount),
+	}
+}
+
+// IsZero checks that the totals have no changes.
+func (t *RoyaltyReportVersionTotals) IsZero() bool {
+	return *t == RoyaltyReportVersionTotals{}
+}
+
+func roundRoyaltyReportVersionAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+type RegenerateRoyaltyReportRequest struct {
+	ReportId string `json:"report_id"`
+	// Reason is the reason of the regeneration shown to the merchant, for example the late refund
+	Reason string `json:"reason"`
+	UserId string `json:"user_id"`
+	Ip     string `json:"ip"`
+}
+
+type GetRoyaltyReportVersionsRequest struct {
+	ReportId   string `json:"report_id"`
+	MerchantId string `json:"merchant_id"`
+}
+
+type RoyaltyReportVersionResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Item    *RoyaltyReportVersion           `json:"item,omitempty"`
+}
+
+type RoyaltyReportVersionsResponse struct {
+	Status  int32                           `json:"status"`
+	Message *billingpb.ResponseErrorMessage `json:"message,omitempty"`
+	Items   []*RoyaltyReportVersion         `json:"items,omitempty"`
+}
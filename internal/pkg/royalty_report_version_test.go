@@ -0,0 +1,19 @@
+package pkg
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRoyaltyReportVersionTotals_Sub(t *testing.T) {
+	previous := &RoyaltyReportVersionTotals{TransactionsCount: 10, PayoutAmount: 100.1, FeeAmount: 5.2}
+	current := &RoyaltyReportVersionTotals{TransactionsCount: 9, PayoutAmount: 90.3, FeeAmount: 5.2}
+
+	diff := current.Sub(previous)
+	assert.EqualValues(t, -1, diff.TransactionsCount)
+	assert.Equal(t, -9.8, diff.PayoutAmount)
+	assert.Zero(t, diff.FeeAmount)
+	assert.False(t, diff.IsZero())
+
+	assert.True(t, current.Sub(current).IsZero())
+}
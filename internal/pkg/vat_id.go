@@ -0,0 +1,63 @@
+package pkg
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	// VatIdValidationSourceOffline means the VAT ID was validated by the format and checksum rules only.
+	VatIdValidationSourceOffline = "offline"
+	// VatIdValidationSourceExternal means the VAT ID was additionally confirmed by the external validator.
+	VatIdValidationSourceExternal = "external"
+)
+
+// OrderVatId contains the VAT identification number of the business customer and the result of its validation.
+// Orders with the valid VAT ID of the billing country are not charged with VAT under the reverse charge.
+// The validation result is stored by the server only and never taken from the metadata of the order.
+type OrderVatId struct {
+	Id               primitive.ObjectID `bson:"_id" json:"-"`
+	OrderId          primitive.ObjectID `bson:"order_id" json:"-"`
+	VatId            string             `bson:"vat_id" json:"vat_id"`
+	Country          string             `bson:"country" json:"country"`
+	IsValid          bool               `bson:"is_valid" json:"is_valid"`
+	ValidationSource string             `bson:"validation_source" json:"validation_source"`
+	ValidatedAt      time.Time          `bson:"validated_at" json:"validated_at"`
+	IsReverseCharge  bool               `bson:"is_reverse_charge" json:"is_reverse_charge"`
+}
+
+type SetOrderVatIdRequest struct {
+	OrderId string `json:"order_id"`
+	VatId   string `json:"vat_id"`
+}
+
+type SetOrderVatIdResponse struct {
+	Status  int32                                        `json:"status"`
+	Message *billingpb.ResponseErrorMessage              `json:"message,omitempty"`
+	VatId   *OrderVatId                                  `json:"vat_id,omitempty"`
+	Item    *billingpb.ProcessBillingAddressResponseItem `json:"item,omitempty"`
+}
+
+// VatReportReverseCharge contains the summary of the reverse charge orders of the VAT report period.
+// These orders are excluded from the payable VAT of the report and listed separately.
+type VatReportReverseCharge struct {
+	Id                 primitive.ObjectID `bson:"_id" json:"id"`
+	VatReportId        string             `bson:"vat_report_id" json:"vat_report_id"`
+	Country            string             `bson:"country" json:"country"`
+	OperatingCompanyId string             `bson:"operating_company_id" json:"operating_company_id"`
+	Currency           string             `bson:"currency" json:"currency"`
+	TransactionsCount  int32              `bson:"transactions_count" json:"transactions_count"`
+	GrossRevenue       float64            `bson:"gross_revenue" json:"gross_revenue"`
+	DateFrom           time.Time          `bson:"date_from" json:"date_from"`
+	DateTo             time.Time          `bson:"date_to" json:"date_to"`
+	CreatedAt          time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt          time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type VatReportReverseChargesResponse struct {
+	Status  int32                                  `json:"status"`
+	Message *billingpb.ResponseErrorMessage        `json:"message,omitempty"`
+	Summary *VatReportReverseCharge                `json:"summary,omitempty"`
+	Data    *billingpb.PrivateTransactionsPaginate `json:"data,omitempty"`
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+)
+
+const (
+	collectionAccountingChain = "accounting_entry_chain"
+)
+
+type accountingChainRepository repository
+
+// NewAccountingChainRepository create and return an object for working with the accounting chain repository.
+// The returned object implements the AccountingChainRepositoryInterface interface.
+func NewAccountingChainRepository(db mongodb.SourceInterface) AccountingChainRepositoryInterface {
+	s := &accountingChainRepository{db: db}
+	return s
+}
+
+func (r *accountingChainRepository) Insert(ctx context.Context, obj *internalPkg.AccountingChainLink) error {
+	_, err := r.db.Collection(collectionAccountingChain).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingChain),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *accountingChainRepository) GetLast(
+	ctx context.Context,
+	operatingCompanyId string,
+) (*internalPkg.AccountingChainLink, error) {
+	query := bson.M{"operating_company_id": operatingCompanyId}
+	opts := options.FindOne().SetSort(bson.M{"sequence": -1})
+	obj := &internalPkg.AccountingChainLink{}
+	err := r.db.Collection(collectionAccountingChain).FindOne(ctx, query, opts).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingChain),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+func (r *accountingChainRepository) GetByEntryId(
+	ctx context.Context,
+	entryId string,
+) (*internalPkg.AccountingChainLink, error) {
+	query := bson.M{"entry_id": entryId}
+	obj := &internalPkg.AccountingChainLink{}
+	err := r.db.Collection(collectionAccountingChain).FindOne(ctx, query).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingChain),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+func (r *accountingChainRepository) FindByOperatingCompany(
+	ctx context.Context,
+	operatingCompanyId string,
+	fromSequence, limit int64,
+) ([]*internalPkg.AccountingChainLink, error) {
+	query := bson.M{
+		"operating_company_id": operatingCompanyId,
+		"sequence":             bson.M{"$gte": fromSequence},
+	}
+
+	if limit <= 0 {
+		limit = pkg.DatabaseRequestDefaultLimit
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"sequence": 1}).
+		SetLimit(limit)
+	cursor, err := r.db.Collection(collectionAccountingChain).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingChain),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*internalPkg.AccountingChainLink
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingChain),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
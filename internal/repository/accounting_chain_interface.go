@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// AccountingChainRepositoryInterface is abstraction layer for working with hash chain of accounting entries
+// and representation in database.
+type AccountingChainRepositoryInterface interface {
+	// Insert adds the link to the chain. Returns error if the link with the same sequence already exists.
+	Insert(context.Context, *pkg.AccountingChainLink) error
+
+	// GetLast returns the last link of the operating company chain.
+	GetLast(context.Context, string) (*pkg.AccountingChainLink, error)
+
+	// GetByEntryId returns the link of the accounting entry.
+	GetByEntryId(context.Context, string) (*pkg.AccountingChainLink, error)
+
+	// FindByOperatingCompany returns the links of the operating company chain starting from the sequence.
+	FindByOperatingCompany(context.Context, string, int64, int64) ([]*pkg.AccountingChainLink, error)
+}
@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+)
+
+const (
+	collectionAccountingCorrection = "accounting_corrections"
+)
+
+type accountingCorrectionRepository repository
+
+// NewAccountingCorrectionRepository create and return an object for working with the accounting correction repository.
+// The returned object implements the AccountingCorrectionRepositoryInterface interface.
+func NewAccountingCorrectionRepository(db mongodb.SourceInterface) AccountingCorrectionRepositoryInterface {
+	s := &accountingCorrectionRepository{db: db}
+	return s
+}
+
+func (r *accountingCorrectionRepository) Insert(ctx context.Context, obj *internalPkg.AccountingCorrection) error {
+	_, err := r.db.Collection(collectionAccountingCorrection).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingCorrection),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *accountingCorrectionRepository) Update(ctx context.Context, obj *internalPkg.AccountingCorrection) error {
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionAccountingCorrection).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingCorrection),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *accountingCorrectionRepository) UpdateByStatus(
+	ctx context.Context,
+	obj *internalPkg.AccountingCorrection,
+	status string,
+) error {
+	filter := bson.M{"_id": obj.Id, "status": status}
+	res, err := r.db.Collection(collectionAccountingCorrection).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingCorrection),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	if res.MatchedCount <= 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *accountingCorrectionRepository) GetById(ctx context.Context, id string) (*internalPkg.AccountingCorrection, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingCorrection),
+			zap.String(pkg.ErrorDatabaseFieldQuery, id),
+		)
+		return nil, err
+	}
+
+	return r.findOne(ctx, bson.M{"_id": oid})
+}
+
+func (r *accountingCorrectionRepository) Find(
+	ctx context.Context,
+	merchantId, status string,
+	limit, offset int64,
+) ([]*internalPkg.AccountingCorrection, error) {
+	query, err := r.getFindQuery(merchantId, status)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = pkg.DatabaseRequestDefaultLimit
+	}
+
+	if offset <= 0 {
+		offset = 0
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"_id": -1}).
+		SetLimit(limit).
+		SetSkip(offset)
+
+	return r.find(ctx, query, opts)
+}
+
+func (r *accountingCorrectionRepository) FindCount(ctx context.Context, merchantId, status string) (int64, error) {
+	query, err := r.getFindQuery(merchantId, status)
+
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := r.db.Collection(collectionAccountingCorrection).CountDocuments(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingCorrection),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationCount),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func (r *accountingCorrectionRepository) getFindQuery(merchantId, status string) (bson.M, error) {
+	merchantOid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingCorrection),
+			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"merchant_id": merchantOid}
+
+	if status != "" {
+		query["status"] = status
+	}
+
+	return query, nil
+}
+
+func (r *accountingCorrectionRepository) find(
+	ctx context.Context,
+	query bson.M,
+	opts ...*options.FindOptions,
+) ([]*internalPkg.AccountingCorrection, error) {
+	cursor, err := r.db.Collection(collectionAccountingCorrection).Find(ctx, query, opts...)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingCorrection),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*internalPkg.AccountingCorrection
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingCorrection),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *accountingCorrectionRepository) findOne(ctx context.Context, query bson.M) (*internalPkg.AccountingCorrection, error) {
+	obj := &internalPkg.AccountingCorrection{}
+	err := r.db.Collection(collectionAccountingCorrection).FindOne(ctx, query).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingCorrection),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// AccountingCorrectionRepositoryInterface is abstraction layer for working with manual corrections
+// of merchant balance and representation in database.
+type AccountingCorrectionRepositoryInterface interface {
+	// Insert adds the correction to the collection.
+	Insert(context.Context, *pkg.AccountingCorrection) error
+
+	// Update updates the correction in the collection.
+	Update(context.Context, *pkg.AccountingCorrection) error
+
+	// UpdateByStatus updates the correction only if it's still in the passed status.
+	// Returns mongo.ErrNoDocuments if the status of the correction was changed concurrently.
+	UpdateByStatus(context.Context, *pkg.AccountingCorrection, string) error
+
+	// GetById returns the correction by unique identity.
+	GetById(context.Context, string) (*pkg.AccountingCorrection, error)
+
+	// Find returns list of corrections by merchant id and status with pagination.
+	Find(context.Context, string, string, int64, int64) ([]*pkg.AccountingCorrection, error)
+
+	// FindCount returns count of corrections by merchant id and status.
+	FindCount(context.Context, string, string) (int64, error)
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
 	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
@@ -16,7 +15,7 @@ import (
 )
 
 const (
-	collectionAccountingEntry = "accounting_entry"
+	CollectionAccountingEntry = "accounting_entry"
 )
 
 type accountingEntryRepository repository
@@ -44,13 +43,13 @@ func (r *accountingEntryRepository) MultipleInsert(ctx context.Context, objs []*
 		c[i] = mgo
 	}
 
-	_, err := r.db.Collection(collectionAccountingEntry).InsertMany(ctx, c)
+	_, err := r.db.Collection(CollectionAccountingEntry).InsertMany(ctx, c)
 
 	if err != nil {
 		zap.L().Error(
 			pkg.ErrorDatabaseQueryFailed,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
 			zap.Any(pkg.ErrorDatabaseFieldQuery, c),
 		)
@@ -67,7 +66,7 @@ func (r *accountingEntryRepository) GetById(ctx context.Context, id string) (*bi
 		zap.L().Error(
 			pkg.ErrorDatabaseInvalidObjectId,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.String(pkg.ErrorDatabaseFieldQuery, id),
 		)
 		return nil, err
@@ -75,13 +74,13 @@ func (r *accountingEntryRepository) GetById(ctx context.Context, id string) (*bi
 
 	var mgo = models.MgoAccountingEntry{}
 	query := bson.M{"_id": oid}
-	err = r.db.Collection(collectionAccountingEntry).FindOne(ctx, query).Decode(&mgo)
+	err = r.db.Collection(CollectionAccountingEntry).FindOne(ctx, query).Decode(&mgo)
 
 	if err != nil {
 		zap.L().Error(
 			pkg.ErrorDatabaseQueryFailed,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
 		)
 		return nil, err
@@ -108,7 +107,7 @@ func (r *accountingEntryRepository) GetByObjectSource(ctx context.Context, objec
 		zap.L().Error(
 			pkg.ErrorDatabaseInvalidObjectId,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.String(pkg.ErrorDatabaseFieldQuery, sourceId),
 		)
 		return nil, err
@@ -121,13 +120,13 @@ func (r *accountingEntryRepository) GetByObjectSource(ctx context.Context, objec
 	}
 
 	var mgo = models.MgoAccountingEntry{}
-	err = r.db.Collection(collectionAccountingEntry).FindOne(ctx, query).Decode(&mgo)
+	err = r.db.Collection(CollectionAccountingEntry).FindOne(ctx, query).Decode(&mgo)
 
 	if err != nil {
 		zap.L().Error(
 			pkg.ErrorDatabaseQueryFailed,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
 		)
 		return nil, err
@@ -156,7 +155,7 @@ func (r *accountingEntryRepository) ApplyObjectSource(
 		zap.L().Error(
 			pkg.ErrorDatabaseInvalidObjectId,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.String(pkg.ErrorDatabaseFieldQuery, sourceId),
 		)
 		return nil, err
@@ -181,13 +180,13 @@ func (r *accountingEntryRepository) ApplyObjectSource(
 	}
 
 	var mgo = obj.(*models.MgoAccountingEntry)
-	err = r.db.Collection(collectionAccountingEntry).FindOne(ctx, query).Decode(&mgo)
+	err = r.db.Collection(CollectionAccountingEntry).FindOne(ctx, query).Decode(&mgo)
 
 	if err != nil {
 		zap.L().Error(
 			pkg.ErrorDatabaseQueryFailed,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
 		)
 		return nil, err
@@ -214,7 +213,7 @@ func (r *accountingEntryRepository) FindBySource(ctx context.Context, sourceId,
 		zap.L().Error(
 			pkg.ErrorDatabaseInvalidObjectId,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.String(pkg.ErrorDatabaseFieldQuery, sourceId),
 		)
 		return nil, err
@@ -225,13 +224,13 @@ func (r *accountingEntryRepository) FindBySource(ctx context.Context, sourceId,
 		"source.type": sourceType,
 	}
 
-	cursor, err := r.db.Collection(collectionAccountingEntry).Find(ctx, query)
+	cursor, err := r.db.Collection(CollectionAccountingEntry).Find(ctx, query)
 
 	if err != nil {
 		zap.L().Error(
 			pkg.ErrorDatabaseQueryFailed,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
 		)
 		return nil, err
@@ -244,7 +243,7 @@ func (r *accountingEntryRepository) FindBySource(ctx context.Context, sourceId,
 		zap.L().Error(
 			pkg.ErrorDatabaseQueryFailed,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
 		)
 		return nil, err
@@ -277,13 +276,13 @@ func (r *accountingEntryRepository) GetByTypeWithTaxes(ctx context.Context, type
 	opts := options.Find().
 		SetSort(mongodb.ToSortOption([]string{"source.type", "source.id", "-type"}))
 
-	cursor, err := r.db.Collection(collectionAccountingEntry).Find(ctx, query, opts)
+	cursor, err := r.db.Collection(CollectionAccountingEntry).Find(ctx, query, opts)
 
 	if err != nil {
 		zap.L().Error(
 			pkg.ErrorDatabaseQueryFailed,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
 		)
 		return nil, err
@@ -296,7 +295,7 @@ func (r *accountingEntryRepository) GetByTypeWithTaxes(ctx context.Context, type
 		zap.L().Error(
 			pkg.ErrorDatabaseQueryFailed,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
 		)
 		return nil, err
@@ -332,13 +331,13 @@ func (r *accountingEntryRepository) FindByTypeCountryDates(
 		"type":    bson.M{"$in": types},
 	}
 
-	cursor, err := r.db.Collection(collectionAccountingEntry).Find(ctx, query)
+	cursor, err := r.db.Collection(CollectionAccountingEntry).Find(ctx, query)
 
 	if err != nil {
 		zap.L().Error(
 			pkg.ErrorDatabaseQueryFailed,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
 		)
 		return nil, err
@@ -351,7 +350,7 @@ func (r *accountingEntryRepository) FindByTypeCountryDates(
 		zap.L().Error(
 			pkg.ErrorQueryCursorExecutionFailed,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
 		)
 		return nil, err
@@ -375,14 +374,260 @@ func (r *accountingEntryRepository) FindByTypeCountryDates(
 	return objs, nil
 }
 
+func (r *accountingEntryRepository) FindBySourceTypeDates(
+	ctx context.Context, sourceType string, dateFrom, dateTo time.Time,
+) ([]*billingpb.AccountingEntry, error) {
+	query := bson.M{
+		"created_at": bson.M{
+			"$gte": dateFrom,
+			"$lte": dateTo,
+		},
+		"source.type": sourceType,
+	}
+
+	opts := options.Find().
+		SetSort(mongodb.ToSortOption([]string{"source.id", "type"}))
+
+	cursor, err := r.db.Collection(CollectionAccountingEntry).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*models.MgoAccountingEntry
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	objs := make([]*billingpb.AccountingEntry, len(list))
+
+	for i, obj := range list {
+		v, err := r.mapper.MapMgoToObject(obj)
+		if err != nil {
+			zap.L().Error(
+				pkg.ErrorDatabaseMapModelFailed,
+				zap.Error(err),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+			)
+			return nil, err
+		}
+		objs[i] = v.(*billingpb.AccountingEntry)
+	}
+
+	return objs, nil
+}
+
+func (r *accountingEntryRepository) FindByOperatingCompanyDates(
+	ctx context.Context, operatingCompanyId string, dateFrom, dateTo time.Time,
+) ([]*billingpb.AccountingEntry, error) {
+	query := bson.M{
+		"created_at": bson.M{
+			"$gte": dateFrom,
+			"$lte": dateTo,
+		},
+		"operating_company_id": operatingCompanyId,
+	}
+
+	opts := options.Find().
+		SetSort(mongodb.ToSortOption([]string{"created_at", "_id"}))
+
+	cursor, err := r.db.Collection(CollectionAccountingEntry).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*models.MgoAccountingEntry
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	objs := make([]*billingpb.AccountingEntry, len(list))
+
+	for i, obj := range list {
+		v, err := r.mapper.MapMgoToObject(obj)
+		if err != nil {
+			zap.L().Error(
+				pkg.ErrorDatabaseMapModelFailed,
+				zap.Error(err),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+			)
+			return nil, err
+		}
+		objs[i] = v.(*billingpb.AccountingEntry)
+	}
+
+	return objs, nil
+}
+
+func (r *accountingEntryRepository) FindIdsByOperatingCompany(
+	ctx context.Context,
+	operatingCompanyId, afterId string,
+	limit int64,
+) ([]string, error) {
+	query := bson.M{"operating_company_id": operatingCompanyId}
+
+	if afterId != "" {
+		oid, err := primitive.ObjectIDFromHex(afterId)
+
+		if err != nil {
+			zap.L().Error(
+				pkg.ErrorDatabaseInvalidObjectId,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
+				zap.String(pkg.ErrorDatabaseFieldQuery, afterId),
+			)
+			return nil, err
+		}
+
+		query["_id"] = bson.M{"$gt": oid}
+	}
+
+	if limit <= 0 {
+		limit = pkg.DatabaseRequestDefaultLimit
+	}
+
+	opts := options.Find().
+		SetProjection(bson.M{"_id": 1}).
+		SetSort(bson.M{"_id": 1}).
+		SetLimit(limit)
+	cursor, err := r.db.Collection(CollectionAccountingEntry).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []struct {
+		Id primitive.ObjectID `bson:"_id"`
+	}
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	ids := make([]string, len(list))
+
+	for i, v := range list {
+		ids[i] = v.Id.Hex()
+	}
+
+	return ids, nil
+}
+
+func (r *accountingEntryRepository) FindByIds(ctx context.Context, ids []string) ([]*billingpb.AccountingEntry, error) {
+	oids := make([]primitive.ObjectID, len(ids))
+
+	for i, id := range ids {
+		oid, err := primitive.ObjectIDFromHex(id)
+
+		if err != nil {
+			zap.L().Error(
+				pkg.ErrorDatabaseInvalidObjectId,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
+				zap.String(pkg.ErrorDatabaseFieldQuery, id),
+			)
+			return nil, err
+		}
+
+		oids[i] = oid
+	}
+
+	query := bson.M{"_id": bson.M{"$in": oids}}
+	cursor, err := r.db.Collection(CollectionAccountingEntry).Find(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*models.MgoAccountingEntry
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	objs := make([]*billingpb.AccountingEntry, len(list))
+
+	for i, obj := range list {
+		v, err := r.mapper.MapMgoToObject(obj)
+
+		if err != nil {
+			zap.L().Error(
+				pkg.ErrorDatabaseMapModelFailed,
+				zap.Error(err),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+			)
+			return nil, err
+		}
+
+		objs[i] = v.(*billingpb.AccountingEntry)
+	}
+
+	return objs, nil
+}
+
 func (r *accountingEntryRepository) GetDistinctBySourceId(ctx context.Context) ([]string, error) {
-	res, err := r.db.Collection(collectionAccountingEntry).Distinct(ctx, "source.id", bson.M{})
+	res, err := r.db.Collection(CollectionAccountingEntry).Distinct(ctx, "source.id", bson.M{})
 
 	if err != nil {
 		zap.L().Error(
 			pkg.ErrorDatabaseQueryFailed,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 		)
 		return nil, err
 	}
@@ -405,7 +650,7 @@ func (r *accountingEntryRepository) GetCorrectionsForRoyaltyReport(
 		zap.L().Error(
 			pkg.ErrorDatabaseInvalidObjectId,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
 		)
 		return nil, err
@@ -421,13 +666,13 @@ func (r *accountingEntryRepository) GetCorrectionsForRoyaltyReport(
 	sorts := bson.M{"created_at": 1}
 	opts := options.Find()
 	opts.SetSort(sorts)
-	cursor, err := r.db.Collection(collectionAccountingEntry).Find(ctx, query, opts)
+	cursor, err := r.db.Collection(CollectionAccountingEntry).Find(ctx, query, opts)
 
 	if err != nil {
 		zap.L().Error(
 			pkg.ErrorDatabaseQueryFailed,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
 			zap.Any(pkg.ErrorDatabaseFieldSorts, sorts),
 		)
@@ -441,7 +686,7 @@ func (r *accountingEntryRepository) GetCorrectionsForRoyaltyReport(
 		zap.L().Error(
 			pkg.ErrorQueryCursorExecutionFailed,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
 			zap.Any(pkg.ErrorDatabaseFieldSorts, sorts),
 		)
@@ -475,7 +720,7 @@ func (r *accountingEntryRepository) GetRollingReservesForRoyaltyReport(
 		zap.L().Error(
 			pkg.ErrorDatabaseInvalidObjectId,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
 		)
 		return nil, err
@@ -491,13 +736,13 @@ func (r *accountingEntryRepository) GetRollingReservesForRoyaltyReport(
 	sorts := bson.M{"created_at": 1}
 	opts := options.Find()
 	opts.SetSort(sorts)
-	cursor, err := r.db.Collection(collectionAccountingEntry).Find(ctx, query, opts)
+	cursor, err := r.db.Collection(CollectionAccountingEntry).Find(ctx, query, opts)
 
 	if err != nil {
 		zap.L().Error(
 			pkg.ErrorDatabaseQueryFailed,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
 			zap.Any(pkg.ErrorDatabaseFieldSorts, sorts),
 		)
@@ -511,7 +756,7 @@ func (r *accountingEntryRepository) GetRollingReservesForRoyaltyReport(
 		zap.L().Error(
 			pkg.ErrorQueryCursorExecutionFailed,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
 			zap.Any(pkg.ErrorDatabaseFieldSorts, sorts),
 		)
@@ -545,7 +790,7 @@ func (r *accountingEntryRepository) GetRollingReserveForBalance(
 		zap.L().Error(
 			pkg.ErrorDatabaseInvalidObjectId,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
 		)
 		return nil, err
@@ -571,13 +816,13 @@ func (r *accountingEntryRepository) GetRollingReserveForBalance(
 	}
 
 	var items []*pkg2.ReserveQueryResItem
-	cursor, err := r.db.Collection(collectionAccountingEntry).Aggregate(ctx, query)
+	cursor, err := r.db.Collection(CollectionAccountingEntry).Aggregate(ctx, query)
 
 	if err != nil {
 		zap.L().Error(
 			pkg.ErrorDatabaseQueryFailed,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
 		)
 		return nil, err
@@ -589,7 +834,7 @@ func (r *accountingEntryRepository) GetRollingReserveForBalance(
 		zap.L().Error(
 			pkg.ErrorQueryCursorExecutionFailed,
 			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionAccountingEntry),
 			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
 		)
 		return nil, err
@@ -597,50 +842,3 @@ func (r *accountingEntryRepository) GetRollingReserveForBalance(
 
 	return items, nil
 }
-
-func (r *accountingEntryRepository) BulkWrite(ctx context.Context, list []*billingpb.AccountingEntry) error {
-	var operations []mongo.WriteModel
-
-	for _, ae := range list {
-		oid, err := primitive.ObjectIDFromHex(ae.Id)
-
-		if err != nil {
-			zap.L().Error(
-				pkg.ErrorDatabaseInvalidObjectId,
-				zap.Error(err),
-				zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
-				zap.String(pkg.ErrorDatabaseFieldQuery, ae.Id),
-			)
-			return err
-		}
-
-		mgo, err := r.mapper.MapObjectToMgo(ae)
-
-		if err != nil {
-			zap.L().Error(
-				pkg.ErrorDatabaseMapModelFailed,
-				zap.Error(err),
-				zap.Any(pkg.ErrorDatabaseFieldQuery, ae),
-			)
-			return err
-		}
-
-		operation := mongo.NewUpdateOneModel().
-			SetFilter(bson.M{"_id": oid}).
-			SetUpdate(mgo)
-		operations = append(operations, operation)
-	}
-
-	_, err := r.db.Collection(collectionAccountingEntry).BulkWrite(ctx, operations)
-
-	if err != nil {
-		zap.L().Error(
-			pkg.ErrorQueryCursorExecutionFailed,
-			zap.Error(err),
-			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
-		)
-		return err
-	}
-
-	return nil
-}
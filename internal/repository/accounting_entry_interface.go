@@ -42,6 +42,16 @@ type AccountingEntryRepositoryInterface interface {
 	// FindByTypeCountryDates returns the account entries by type, country and dates.
 	FindByTypeCountryDates(context.Context, string, []string, time.Time, time.Time) ([]*billingpb.AccountingEntry, error)
 
-	// BulkWrite writing account entries.
-	BulkWrite(context.Context, []*billingpb.AccountingEntry) error
+	// FindBySourceTypeDates returns the account entries by source type and dates sorted by source id.
+	FindBySourceTypeDates(context.Context, string, time.Time, time.Time) ([]*billingpb.AccountingEntry, error)
+
+	// FindByOperatingCompanyDates returns the account entries by operating company id and dates sorted by date.
+	FindByOperatingCompanyDates(context.Context, string, time.Time, time.Time) ([]*billingpb.AccountingEntry, error)
+
+	// FindIdsByOperatingCompany returns the ids of the account entries of the operating company sorted by id
+	// starting after the id.
+	FindIdsByOperatingCompany(context.Context, string, string, int64) ([]string, error)
+
+	// FindByIds returns the account entries by the list of ids.
+	FindByIds(context.Context, []string) ([]*billingpb.AccountingEntry, error)
 }
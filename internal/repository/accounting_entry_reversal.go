@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+)
+
+const (
+	collectionAccountingEntryReversal = "accounting_entry_reversals"
+)
+
+type accountingEntryReversalRepository repository
+
+// NewAccountingEntryReversalRepository create and return an object for working with the accounting entry reversal
+// repository. The returned object implements the AccountingEntryReversalRepositoryInterface interface.
+func NewAccountingEntryReversalRepository(db mongodb.SourceInterface) AccountingEntryReversalRepositoryInterface {
+	s := &accountingEntryReversalRepository{db: db}
+	return s
+}
+
+func (r *accountingEntryReversalRepository) Insert(
+	ctx context.Context,
+	obj *internalPkg.AccountingEntryReversal,
+) error {
+	_, err := r.db.Collection(collectionAccountingEntryReversal).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntryReversal),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *accountingEntryReversalRepository) FindByEntryIds(
+	ctx context.Context,
+	ids []string,
+) ([]*internalPkg.AccountingEntryReversal, error) {
+	query := bson.M{
+		"$or": []bson.M{
+			{"entry_id": bson.M{"$in": ids}},
+			{"reversal_entry_id": bson.M{"$in": ids}},
+		},
+	}
+	cursor, err := r.db.Collection(collectionAccountingEntryReversal).Find(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntryReversal),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*internalPkg.AccountingEntryReversal
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntryReversal),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
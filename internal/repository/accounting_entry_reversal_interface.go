@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// AccountingEntryReversalRepositoryInterface is abstraction layer for working with reversals of changed accounting
+// entries and representation in database.
+type AccountingEntryReversalRepositoryInterface interface {
+	// Insert adds the reversal of the accounting entry. Returns error if the entry is already reversed.
+	Insert(context.Context, *pkg.AccountingEntryReversal) error
+
+	// FindByEntryIds returns the reversals of the accounting entries or written by the accounting entries.
+	FindByEntryIds(context.Context, []string) ([]*pkg.AccountingEntryReversal, error)
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+)
+
+const (
+	collectionAccountingPeriod = "accounting_periods"
+)
+
+type accountingPeriodRepository repository
+
+// NewAccountingPeriodRepository create and return an object for working with the accounting period repository.
+// The returned object implements the AccountingPeriodRepositoryInterface interface.
+func NewAccountingPeriodRepository(db mongodb.SourceInterface) AccountingPeriodRepositoryInterface {
+	s := &accountingPeriodRepository{db: db}
+	return s
+}
+
+func (r *accountingPeriodRepository) Insert(ctx context.Context, obj *internalPkg.AccountingPeriod) error {
+	_, err := r.db.Collection(collectionAccountingPeriod).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingPeriod),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *accountingPeriodRepository) Update(ctx context.Context, obj *internalPkg.AccountingPeriod) error {
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionAccountingPeriod).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingPeriod),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *accountingPeriodRepository) GetById(ctx context.Context, id string) (*internalPkg.AccountingPeriod, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingPeriod),
+			zap.String(pkg.ErrorDatabaseFieldQuery, id),
+		)
+		return nil, err
+	}
+
+	return r.findOne(ctx, bson.M{"_id": oid})
+}
+
+func (r *accountingPeriodRepository) FindByOperatingCompany(
+	ctx context.Context,
+	operatingCompanyId, status string,
+) ([]*internalPkg.AccountingPeriod, error) {
+	query := bson.M{"operating_company_id": operatingCompanyId}
+
+	if status != "" {
+		query["status"] = status
+	}
+
+	opts := options.Find().SetSort(bson.M{"date_from": 1})
+
+	return r.find(ctx, query, opts)
+}
+
+func (r *accountingPeriodRepository) find(
+	ctx context.Context,
+	query bson.M,
+	opts ...*options.FindOptions,
+) ([]*internalPkg.AccountingPeriod, error) {
+	cursor, err := r.db.Collection(collectionAccountingPeriod).Find(ctx, query, opts...)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingPeriod),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*internalPkg.AccountingPeriod
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingPeriod),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *accountingPeriodRepository) findOne(ctx context.Context, query bson.M) (*internalPkg.AccountingPeriod, error) {
+	obj := &internalPkg.AccountingPeriod{}
+	err := r.db.Collection(collectionAccountingPeriod).FindOne(ctx, query).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingPeriod),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// AccountingPeriodRepositoryInterface is abstraction layer for working with accounting periods of operating
+// companies and representation in database.
+type AccountingPeriodRepositoryInterface interface {
+	// Insert adds the accounting period to the collection.
+	Insert(context.Context, *pkg.AccountingPeriod) error
+
+	// Update updates the accounting period in the collection.
+	Update(context.Context, *pkg.AccountingPeriod) error
+
+	// GetById returns the accounting period by unique identity.
+	GetById(context.Context, string) (*pkg.AccountingPeriod, error)
+
+	// FindByOperatingCompany returns list of accounting periods by operating company id and status sorted by start date.
+	FindByOperatingCompany(context.Context, string, string) ([]*pkg.AccountingPeriod, error)
+}
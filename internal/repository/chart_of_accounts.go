@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+)
+
+const (
+	collectionChartOfAccounts = "chart_of_accounts"
+)
+
+type chartOfAccountsRepository repository
+
+// NewChartOfAccountsRepository create and return an object for working with the chart of accounts repository.
+// The returned object implements the ChartOfAccountsRepositoryInterface interface.
+func NewChartOfAccountsRepository(db mongodb.SourceInterface) ChartOfAccountsRepositoryInterface {
+	s := &chartOfAccountsRepository{db: db}
+	return s
+}
+
+func (r *chartOfAccountsRepository) Upsert(ctx context.Context, obj *internalPkg.ChartOfAccounts) error {
+	filter := bson.M{"operating_company_id": obj.OperatingCompanyId}
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.db.Collection(collectionChartOfAccounts).ReplaceOne(ctx, filter, obj, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionChartOfAccounts),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *chartOfAccountsRepository) GetByOperatingCompanyId(
+	ctx context.Context,
+	operatingCompanyId string,
+) (*internalPkg.ChartOfAccounts, error) {
+	query := bson.M{"operating_company_id": operatingCompanyId}
+	obj := &internalPkg.ChartOfAccounts{}
+	err := r.db.Collection(collectionChartOfAccounts).FindOne(ctx, query).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionChartOfAccounts),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
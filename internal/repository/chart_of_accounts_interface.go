@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// ChartOfAccountsRepositoryInterface is abstraction layer for working with general ledger accounts mapping
+// of operating companies and representation in database.
+type ChartOfAccountsRepositoryInterface interface {
+	// Upsert adds or replaces the chart of accounts of the operating company.
+	Upsert(context.Context, *pkg.ChartOfAccounts) error
+
+	// GetByOperatingCompanyId returns the chart of accounts by operating company id.
+	GetByOperatingCompanyId(context.Context, string) (*pkg.ChartOfAccounts, error)
+}
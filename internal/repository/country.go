@@ -11,6 +11,7 @@ import (
 	tools "github.com/paysuper/paysuper-tools/number"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
 	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
@@ -25,8 +26,14 @@ const (
 	cacheCountryAll              = "country:all"
 	cacheCountryRegions          = "country:regions"
 	cacheCountriesWithVatEnabled = "country:with_vat"
+	cacheCountryVatRate          = "country:vat_rate:%s"
 )
 
+// countryVatRate is the projection of the country VAT rate used by the local tax engine.
+type countryVatRate struct {
+	VatRate *float64 `bson:"vat_rate"`
+}
+
 type countryRepository repository
 
 // NewCountryRepository create and return an object for working with the country repository.
@@ -149,6 +156,22 @@ func (h *countryRepository) Update(ctx context.Context, country *billingpb.Count
 		return err
 	}
 
+	// the VAT rate isn't the part of the country message, so the stored rate is kept in the replaced document
+	current := &countryVatRate{}
+	opts := options.FindOne().SetProjection(bson.M{"vat_rate": 1})
+	err = h.db.Collection(CollectionCountry).FindOne(ctx, bson.M{"_id": oid}, opts).Decode(current)
+
+	if err != nil && err != mongo.ErrNoDocuments {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionCountry),
+			zap.String(pkg.ErrorDatabaseFieldQuery, country.Id),
+		)
+		return err
+	}
+
+	mgo.(*models.MgoCountry).VatRate = current.VatRate
 	_, err = h.db.Collection(CollectionCountry).ReplaceOne(ctx, bson.M{"_id": oid}, mgo)
 
 	if err != nil {
@@ -423,6 +446,85 @@ func (h *countryRepository) FindByHighRisk(ctx context.Context, isHighRiskOrder
 	return c, nil
 }
 
+func (h *countryRepository) GetVatRate(ctx context.Context, code string) (float64, error) {
+	var rate float64
+	key := fmt.Sprintf(cacheCountryVatRate, code)
+
+	if err := h.cache.Get(key, &rate); err == nil {
+		return rate, nil
+	}
+
+	obj := &countryVatRate{}
+	query := bson.M{"iso_code_a2": code}
+	opts := options.FindOne().SetProjection(bson.M{"vat_rate": 1})
+	err := h.db.Collection(CollectionCountry).FindOne(ctx, query, opts).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, CollectionCountry),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+
+		return 0, err
+	}
+
+	if obj.VatRate == nil {
+		return 0, mongo.ErrNoDocuments
+	}
+
+	if err = h.cache.Set(key, *obj.VatRate, 0); err != nil {
+		zap.L().Error(
+			pkg.ErrorCacheQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorCacheFieldCmd, "SET"),
+			zap.String(pkg.ErrorCacheFieldKey, key),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, *obj.VatRate),
+		)
+	}
+
+	return *obj.VatRate, nil
+}
+
+func (h *countryRepository) SetVatRate(ctx context.Context, code string, rate float64) error {
+	query := bson.M{"iso_code_a2": code}
+	set := bson.M{"$set": bson.M{"vat_rate": rate}}
+	res, err := h.db.Collection(CollectionCountry).UpdateOne(ctx, query, set)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionCountry),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			zap.Any(pkg.ErrorDatabaseFieldSet, set),
+		)
+		return err
+	}
+
+	if res.MatchedCount <= 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	key := fmt.Sprintf(cacheCountryVatRate, code)
+
+	if err = h.cache.Delete(key); err != nil {
+		zap.L().Error(
+			pkg.ErrorCacheQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorCacheFieldCmd, "DELETE"),
+			zap.String(pkg.ErrorCacheFieldKey, key),
+		)
+		return err
+	}
+
+	return nil
+}
+
 func (h *countryRepository) IsTariffRegionSupported(region string) bool {
 	return helper.Contains(pkg.SupportedTariffRegions, region)
 }
@@ -30,4 +30,10 @@ type CountryRepositoryInterface interface {
 
 	// FindByVatEnabled returns countries with enabled vat (except the US).
 	FindByVatEnabled(context.Context) (*billingpb.CountriesList, error)
+
+	// GetVatRate returns the VAT rate of the country used by the local tax engine.
+	GetVatRate(ctx context.Context, code string) (float64, error)
+
+	// SetVatRate sets the VAT rate of the country used by the local tax engine.
+	SetVatRate(ctx context.Context, code string, rate float64) error
 }
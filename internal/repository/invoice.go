@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionInvoice = "invoices"
+)
+
+type invoiceRepository repository
+
+// NewInvoiceRepository create and return an object for working with the invoice repository.
+// The returned object implements the InvoiceRepositoryInterface interface.
+func NewInvoiceRepository(db mongodb.SourceInterface) InvoiceRepositoryInterface {
+	s := &invoiceRepository{db: db}
+	return s
+}
+
+func (r *invoiceRepository) Insert(ctx context.Context, obj *internalPkg.Invoice) error {
+	_, err := r.db.Collection(collectionInvoice).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionInvoice),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *invoiceRepository) Update(ctx context.Context, obj *internalPkg.Invoice) error {
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionInvoice).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionInvoice),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *invoiceRepository) SetProcessingOrderId(
+	ctx context.Context,
+	id primitive.ObjectID,
+	currentOrderId, orderId string,
+) error {
+	now := time.Now()
+	filter := bson.M{
+		"_id":                 id,
+		"processing_order_id": currentOrderId,
+		"status":              bson.M{"$in": []string{internalPkg.InvoiceStatusSent, internalPkg.InvoiceStatusOverdue}},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"processing_order_id":   orderId,
+			"processing_started_at": now,
+			"updated_at":            now,
+		},
+	}
+	res, err := r.db.Collection(collectionInvoice).UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionInvoice),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, update),
+		)
+		return err
+	}
+
+	if res.MatchedCount <= 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *invoiceRepository) GetById(ctx context.Context, id string) (*internalPkg.Invoice, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionInvoice),
+			zap.String(pkg.ErrorDatabaseFieldQuery, id),
+		)
+		return nil, err
+	}
+
+	return r.findOne(ctx, bson.M{"_id": oid})
+}
+
+func (r *invoiceRepository) GetByIdAndMerchant(
+	ctx context.Context,
+	id, merchantId string,
+) (*internalPkg.Invoice, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionInvoice),
+			zap.String(pkg.ErrorDatabaseFieldQuery, id),
+		)
+		return nil, err
+	}
+
+	merchantOid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionInvoice),
+			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+		)
+		return nil, err
+	}
+
+	return r.findOne(ctx, bson.M{"_id": oid, "merchant_id": merchantOid})
+}
+
+func (r *invoiceRepository) GetByUuid(ctx context.Context, uuid string) (*internalPkg.Invoice, error) {
+	return r.findOne(ctx, bson.M{"uuid": uuid})
+}
+
+func (r *invoiceRepository) Find(
+	ctx context.Context,
+	merchantId, status string,
+	limit, offset int64,
+) ([]*internalPkg.Invoice, error) {
+	query, err := r.getFindQuery(merchantId, status)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = pkg.DatabaseRequestDefaultLimit
+	}
+
+	if offset <= 0 {
+		offset = 0
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"_id": -1}).
+		SetLimit(limit).
+		SetSkip(offset)
+
+	return r.find(ctx, query, opts)
+}
+
+func (r *invoiceRepository) FindCount(ctx context.Context, merchantId, status string) (int64, error) {
+	query, err := r.getFindQuery(merchantId, status)
+
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := r.db.Collection(collectionInvoice).CountDocuments(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionInvoice),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationCount),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func (r *invoiceRepository) FindByStatusDueBefore(
+	ctx context.Context,
+	status string,
+	date time.Time,
+) ([]*internalPkg.Invoice, error) {
+	query := bson.M{
+		"status":   status,
+		"due_date": bson.M{"$lt": date},
+	}
+
+	return r.find(ctx, query)
+}
+
+func (r *invoiceRepository) getFindQuery(merchantId, status string) (bson.M, error) {
+	merchantOid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionInvoice),
+			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"merchant_id": merchantOid}
+
+	if status != "" {
+		query["status"] = status
+	}
+
+	return query, nil
+}
+
+func (r *invoiceRepository) find(
+	ctx context.Context,
+	query bson.M,
+	opts ...*options.FindOptions,
+) ([]*internalPkg.Invoice, error) {
+	cursor, err := r.db.Collection(collectionInvoice).Find(ctx, query, opts...)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionInvoice),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*internalPkg.Invoice
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionInvoice),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *invoiceRepository) findOne(ctx context.Context, query bson.M) (*internalPkg.Invoice, error) {
+	obj := &internalPkg.Invoice{}
+	err := r.db.Collection(collectionInvoice).FindOne(ctx, query).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionInvoice),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
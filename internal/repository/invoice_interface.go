@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// InvoiceRepositoryInterface is abstraction layer for working with merchant invoices and representation in database.
+type InvoiceRepositoryInterface interface {
+	// Insert adds the invoice to the collection.
+	Insert(context.Context, *pkg.Invoice) error
+
+	// Update updates the invoice in the collection.
+	Update(context.Context, *pkg.Invoice) error
+
+	// SetProcessingOrderId replaces the processing order of the payable invoice only if it's still equal to the
+	// current one. Returns mongo.ErrNoDocuments if the processing order was changed concurrently.
+	SetProcessingOrderId(ctx context.Context, id primitive.ObjectID, currentOrderId, orderId string) error
+
+	// GetById returns the invoice by unique identity.
+	GetById(context.Context, string) (*pkg.Invoice, error)
+
+	// GetByIdAndMerchant returns the invoice by unique identity and merchant id.
+	GetByIdAndMerchant(context.Context, string, string) (*pkg.Invoice, error)
+
+	// GetByUuid returns the invoice by checkout identity.
+	GetByUuid(context.Context, string) (*pkg.Invoice, error)
+
+	// Find returns list of invoices by merchant id and status with pagination.
+	Find(context.Context, string, string, int64, int64) ([]*pkg.Invoice, error)
+
+	// FindCount returns count of invoices by merchant id and status.
+	FindCount(context.Context, string, string) (int64, error)
+
+	// FindByStatusDueBefore returns list of invoices with the passed status and due date before the passed date.
+	FindByStatusDueBefore(context.Context, string, time.Time) ([]*pkg.Invoice, error)
+}
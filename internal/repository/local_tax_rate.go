@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+)
+
+const (
+	collectionLocalTaxRate = "local_tax_rates"
+)
+
+type localTaxRateRepository repository
+
+// NewLocalTaxRateRepository create and return an object for working with the local tax rates repository.
+// The returned object implements the LocalTaxRateRepositoryInterface interface.
+func NewLocalTaxRateRepository(db mongodb.SourceInterface) LocalTaxRateRepositoryInterface {
+	s := &localTaxRateRepository{db: db}
+	return s
+}
+
+func (r *localTaxRateRepository) Upsert(ctx context.Context, obj *internalPkg.LocalTaxRate) error {
+	filter := bson.M{"country": obj.Country, "state": obj.State, "zip": obj.Zip}
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.db.Collection(collectionLocalTaxRate).ReplaceOne(ctx, filter, obj, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionLocalTaxRate),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *localTaxRateRepository) Get(
+	ctx context.Context,
+	country, state, zip string,
+) (*internalPkg.LocalTaxRate, error) {
+	query := bson.M{"country": country, "state": state, "zip": zip}
+	obj := &internalPkg.LocalTaxRate{}
+	err := r.db.Collection(collectionLocalTaxRate).FindOne(ctx, query).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionLocalTaxRate),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+func (r *localTaxRateRepository) FindByCountry(ctx context.Context, country string) ([]*internalPkg.LocalTaxRate, error) {
+	query := bson.M{"country": country}
+	opts := options.Find().SetSort(bson.D{{"state", 1}, {"zip", 1}})
+	cursor, err := r.db.Collection(collectionLocalTaxRate).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionLocalTaxRate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*internalPkg.LocalTaxRate
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionLocalTaxRate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
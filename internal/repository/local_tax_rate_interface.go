@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// LocalTaxRateRepositoryInterface is abstraction layer for working with tax rates of the local tax engine
+// and representation in database.
+type LocalTaxRateRepositoryInterface interface {
+	// Upsert adds or updates the tax rate for the country, state and zip code.
+	Upsert(context.Context, *pkg.LocalTaxRate) error
+
+	// Get returns the tax rate by exact country, state and zip code.
+	Get(context.Context, string, string, string) (*pkg.LocalTaxRate, error)
+
+	// FindByCountry returns list of the tax rates of the country.
+	FindByCountry(context.Context, string) ([]*pkg.LocalTaxRate, error)
+}
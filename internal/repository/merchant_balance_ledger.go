@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionMerchantBalanceLedger      = "merchant_balance_ledger"
+	collectionMerchantBalanceLedgerEntry = "merchant_balance_ledger_entry"
+)
+
+type merchantBalanceLedgerRepository repository
+
+// NewMerchantBalanceLedgerRepository create and return an object for working with the merchant balance ledger repository.
+// The returned object implements the MerchantBalanceLedgerRepositoryInterface interface.
+func NewMerchantBalanceLedgerRepository(db mongodb.SourceInterface) MerchantBalanceLedgerRepositoryInterface {
+	s := &merchantBalanceLedgerRepository{db: db}
+	return s
+}
+
+// Apply changes the merchant balance by the ledger entry once per the entry key. The entry is saved to the ledger
+// history and the balance is changed in the single transaction, the unique index of the history key rejects
+// the concurrent apply of the same key, so the balance can't be changed twice by the repeated apply.
+func (r *merchantBalanceLedgerRepository) Apply(ctx context.Context, entry *internalPkg.MerchantBalanceLedgerEntry) error {
+	return WithTransaction(ctx, r.db, func(ctx context.Context) error {
+		query := bson.M{"key": entry.Key}
+		count, err := r.db.Collection(collectionMerchantBalanceLedgerEntry).CountDocuments(ctx, query)
+
+		if err != nil {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantBalanceLedgerEntry),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+			return err
+		}
+
+		if count > 0 {
+			return nil
+		}
+
+		_, err = r.db.Collection(collectionMerchantBalanceLedgerEntry).InsertOne(ctx, entry)
+
+		if err != nil {
+			if IsDuplicateKeyError(err) {
+				return nil
+			}
+
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantBalanceLedgerEntry),
+				zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+				zap.Any(pkg.ErrorDatabaseFieldDocument, entry),
+			)
+			return err
+		}
+
+		filter := bson.M{"merchant_id": entry.MerchantId, "currency": entry.Currency}
+		update := mongo.Pipeline{
+			{{"$set", bson.M{
+				"pending":    r.getBalanceIncrement("$pending", entry.Pending),
+				"available":  r.getBalanceIncrement("$available", entry.Available),
+				"reserved":   r.getBalanceIncrement("$reserved", entry.Reserved),
+				"created_at": bson.M{"$ifNull": bson.A{"$created_at", entry.CreatedAt}},
+				"updated_at": time.Now(),
+			}}},
+		}
+		opts := options.Update().SetUpsert(true)
+		_, err = r.db.Collection(collectionMerchantBalanceLedger).UpdateOne(ctx, filter, update, opts)
+
+		if err != nil {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantBalanceLedger),
+				zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpsert),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+				zap.Any(pkg.ErrorDatabaseFieldSet, update),
+			)
+			return err
+		}
+
+		return nil
+	})
+}
+
+// getBalanceIncrement returns the expression of the balance field increased by the amount. The sum is rounded
+// to the accounting precision, so the errors of the float values aren't accumulated in the balance.
+func (r *merchantBalanceLedgerRepository) getBalanceIncrement(field string, amount float64) bson.M {
+	return bson.M{
+		"$round": bson.A{
+			bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{field, 0}}, amount}},
+			internalPkg.MoneyAccountingPrecision,
+		},
+	}
+}
+
+func (r *merchantBalanceLedgerRepository) GetByMerchantId(
+	ctx context.Context,
+	merchantId string,
+) ([]*internalPkg.MerchantBalanceLedger, error) {
+	oid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantBalanceLedger),
+			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"merchant_id": oid}
+	opts := options.Find().SetSort(bson.M{"currency": 1})
+	cursor, err := r.db.Collection(collectionMerchantBalanceLedger).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantBalanceLedger),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*internalPkg.MerchantBalanceLedger
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantBalanceLedger),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *merchantBalanceLedgerRepository) GetByMerchantIdAndCurrency(
+	ctx context.Context,
+	merchantId, currency string,
+) (*internalPkg.MerchantBalanceLedger, error) {
+	oid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantBalanceLedger),
+			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"merchant_id": oid, "currency": currency}
+	obj := &internalPkg.MerchantBalanceLedger{}
+	err = r.db.Collection(collectionMerchantBalanceLedger).FindOne(ctx, query).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantBalanceLedger),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
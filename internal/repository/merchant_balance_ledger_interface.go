@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// MerchantBalanceLedgerRepositoryInterface is abstraction layer for working with merchant balance ledger
+// and representation in database.
+type MerchantBalanceLedgerRepositoryInterface interface {
+	// Apply adds the entry to the ledger and increments the merchant balance by the entry amounts in one transaction.
+	// The entry with the already applied key is ignored.
+	Apply(context.Context, *pkg.MerchantBalanceLedgerEntry) error
+
+	// GetByMerchantId returns the merchant balances in all currencies.
+	GetByMerchantId(context.Context, string) ([]*pkg.MerchantBalanceLedger, error)
+
+	// GetByMerchantIdAndCurrency returns the merchant balance in the currency.
+	GetByMerchantIdAndCurrency(context.Context, string, string) (*pkg.MerchantBalanceLedger, error)
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+)
+
+const (
+	collectionMerchantDebt = "merchant_debts"
+)
+
+type merchantDebtRepository repository
+
+// NewMerchantDebtRepository create and return an object for working with the merchant debt repository.
+// The returned object implements the MerchantDebtRepositoryInterface interface.
+func NewMerchantDebtRepository(db mongodb.SourceInterface) MerchantDebtRepositoryInterface {
+	s := &merchantDebtRepository{db: db}
+	return s
+}
+
+func (r *merchantDebtRepository) Insert(ctx context.Context, obj *internalPkg.MerchantDebt) error {
+	_, err := r.db.Collection(collectionMerchantDebt).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantDebt),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *merchantDebtRepository) Update(ctx context.Context, obj *internalPkg.MerchantDebt) error {
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionMerchantDebt).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantDebt),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *merchantDebtRepository) GetOpen(
+	ctx context.Context,
+	merchantId, currency string,
+) (*internalPkg.MerchantDebt, error) {
+	oid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantDebt),
+			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"merchant_id": oid, "currency": currency, "status": internalPkg.MerchantDebtStatusOpen}
+
+	return r.findOne(ctx, query)
+}
+
+func (r *merchantDebtRepository) GetLastByMerchantId(
+	ctx context.Context,
+	merchantId string,
+) (*internalPkg.MerchantDebt, error) {
+	oid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantDebt),
+			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"merchant_id": oid}
+
+	return r.findOne(ctx, query, options.FindOne().SetSort(bson.M{"created_at": -1}))
+}
+
+func (r *merchantDebtRepository) GetLastByMerchantIdAndCurrency(
+	ctx context.Context,
+	merchantId, currency string,
+) (*internalPkg.MerchantDebt, error) {
+	oid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantDebt),
+			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"merchant_id": oid, "currency": currency}
+
+	return r.findOne(ctx, query, options.FindOne().SetSort(bson.M{"created_at": -1}))
+}
+
+func (r *merchantDebtRepository) FindOpen(ctx context.Context) ([]*internalPkg.MerchantDebt, error) {
+	query := bson.M{"status": internalPkg.MerchantDebtStatusOpen}
+	cursor, err := r.db.Collection(collectionMerchantDebt).Find(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantDebt),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*internalPkg.MerchantDebt
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantDebt),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *merchantDebtRepository) findOne(
+	ctx context.Context,
+	query bson.M,
+	opts ...*options.FindOneOptions,
+) (*internalPkg.MerchantDebt, error) {
+	obj := &internalPkg.MerchantDebt{}
+	err := r.db.Collection(collectionMerchantDebt).FindOne(ctx, query, opts...).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantDebt),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
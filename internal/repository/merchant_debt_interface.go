@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// MerchantDebtRepositoryInterface is abstraction layer for working with debts of merchants
+// and representation in database.
+type MerchantDebtRepositoryInterface interface {
+	// Insert adds the debt of the merchant.
+	Insert(context.Context, *pkg.MerchantDebt) error
+
+	// Update updates the debt of the merchant.
+	Update(context.Context, *pkg.MerchantDebt) error
+
+	// GetOpen returns the open debt of the merchant in the currency.
+	GetOpen(context.Context, string, string) (*pkg.MerchantDebt, error)
+
+	// GetLastByMerchantId returns the latest debt of the merchant.
+	GetLastByMerchantId(context.Context, string) (*pkg.MerchantDebt, error)
+
+	// GetLastByMerchantIdAndCurrency returns the latest debt of the merchant in the currency.
+	GetLastByMerchantIdAndCurrency(context.Context, string, string) (*pkg.MerchantDebt, error)
+
+	// FindOpen returns all open debts.
+	FindOpen(context.Context) ([]*pkg.MerchantDebt, error)
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+)
+
+const (
+	collectionMerchantPayoutRouting = "merchant_payout_routings"
+)
+
+type merchantPayoutRoutingRepository repository
+
+// NewMerchantPayoutRoutingRepository create and return an object for working with the merchant payout routing repository.
+// The returned object implements the MerchantPayoutRoutingRepositoryInterface interface.
+func NewMerchantPayoutRoutingRepository(db mongodb.SourceInterface) MerchantPayoutRoutingRepositoryInterface {
+	s := &merchantPayoutRoutingRepository{db: db}
+	return s
+}
+
+func (r *merchantPayoutRoutingRepository) Upsert(ctx context.Context, obj *internalPkg.MerchantPayoutRouting) error {
+	filter := bson.M{"merchant_id": obj.MerchantId}
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.db.Collection(collectionMerchantPayoutRouting).ReplaceOne(ctx, filter, obj, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantPayoutRouting),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *merchantPayoutRoutingRepository) GetByMerchantId(
+	ctx context.Context,
+	merchantId string,
+) (*internalPkg.MerchantPayoutRouting, error) {
+	oid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantPayoutRouting),
+			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"merchant_id": oid}
+	obj := &internalPkg.MerchantPayoutRouting{}
+	err = r.db.Collection(collectionMerchantPayoutRouting).FindOne(ctx, query).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantPayoutRouting),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
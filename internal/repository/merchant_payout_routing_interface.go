@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// MerchantPayoutRoutingRepositoryInterface is abstraction layer for working with multi-currency payout settings
+// of merchants and representation in database.
+type MerchantPayoutRoutingRepositoryInterface interface {
+	// Upsert adds or replaces the payout routing of the merchant.
+	Upsert(context.Context, *pkg.MerchantPayoutRouting) error
+
+	// GetByMerchantId returns the payout routing of the merchant.
+	GetByMerchantId(context.Context, string) (*pkg.MerchantPayoutRouting, error)
+}
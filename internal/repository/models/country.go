@@ -29,6 +29,7 @@ type MgoCountry struct {
 	UpdatedAt               time.Time                      `bson:"updated_at"`
 	HighRiskPaymentsAllowed bool                           `bson:"high_risk_payments_allowed"`
 	HighRiskChangeAllowed   bool                           `bson:"high_risk_change_allowed"`
+	VatRate                 *float64                       `bson:"vat_rate,omitempty"`
 }
 
 type countryMapper struct{}
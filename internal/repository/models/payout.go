@@ -43,6 +43,8 @@ type MgoPayoutDocument struct {
 	ArrivalDate             time.Time                      `bson:"arrival_date"`
 	PaidAt                  time.Time                      `bson:"paid_at"`
 	OperatingCompanyId      string                         `bson:"operating_company_id"`
+	// BatchId isn't present in the payout message, it's set only by the bank transfer file export
+	BatchId string `bson:"batch_id,omitempty"`
 }
 
 type MgoPayoutDocumentChanges struct {
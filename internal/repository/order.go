@@ -3351,6 +3351,13 @@ func (h *orderRepository) UpdateOrderView(ctx context.Context, ids []string) err
 				"refund_allowed":                                    "$is_refund_allowed",
 				"vat_payer":                                         1,
 				"is_production":                                     1,
+				"gift_recipient_email":                              "$private_metadata." + pkg.OrderPrivateMetadataGiftRecipientEmail,
+				"gift_message":                                      "$private_metadata." + pkg.OrderPrivateMetadataGiftMessage,
+				"gift_delivery_date":                                "$private_metadata." + pkg.OrderPrivateMetadataGiftDeliveryDate,
+				"vat_id":                                            "$private_metadata." + pkg.OrderPrivateMetadataVatId,
+				"is_reverse_charge": bson.M{
+					"$eq": []interface{}{"$private_metadata." + pkg.OrderPrivateMetadataReverseCharge, "true"},
+				},
 				"merchant_payout_currency": bson.M{
 					"$ifNull": []interface{}{"$net_revenue.currency", "$refund_reverse_revenue.currency"},
 				},
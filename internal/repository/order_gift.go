@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionOrderGift = "order_gifts"
+)
+
+type orderGiftRepository repository
+
+// NewOrderGiftRepository create and return an object for working with the order gift repository.
+// The returned object implements the OrderGiftRepositoryInterface interface.
+func NewOrderGiftRepository(db mongodb.SourceInterface) OrderGiftRepositoryInterface {
+	s := &orderGiftRepository{db: db}
+	return s
+}
+
+func (r *orderGiftRepository) Upsert(ctx context.Context, obj *internalPkg.OrderGift) error {
+	filter := bson.M{"_id": obj.Id}
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.db.Collection(collectionOrderGift).ReplaceOne(ctx, filter, obj, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderGift),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *orderGiftRepository) GetByOrderId(ctx context.Context, orderId string) (*internalPkg.OrderGift, error) {
+	oid, err := primitive.ObjectIDFromHex(orderId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderGift),
+			zap.String(pkg.ErrorDatabaseFieldQuery, orderId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"order_id": oid}
+	obj := &internalPkg.OrderGift{}
+	err = r.db.Collection(collectionOrderGift).FindOne(ctx, query).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderGift),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+func (r *orderGiftRepository) FindScheduled(ctx context.Context, date time.Time) ([]*internalPkg.OrderGift, error) {
+	query := bson.M{
+		"status":        internalPkg.OrderGiftStatusScheduled,
+		"delivery_date": bson.M{"$lte": date},
+	}
+	cursor, err := r.db.Collection(collectionOrderGift).Find(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderGift),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*internalPkg.OrderGift
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderGift),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
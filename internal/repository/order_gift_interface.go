@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"time"
+)
+
+// OrderGiftRepositoryInterface is abstraction layer for working with gifts of orders and representation in database.
+type OrderGiftRepositoryInterface interface {
+	// Upsert adds or updates the order gift in the collection.
+	Upsert(context.Context, *pkg.OrderGift) error
+
+	// GetByOrderId returns the gift of the order.
+	GetByOrderId(context.Context, string) (*pkg.OrderGift, error)
+
+	// FindScheduled returns list of the scheduled gifts with delivery date before the passed date.
+	FindScheduled(context.Context, time.Time) ([]*pkg.OrderGift, error)
+}
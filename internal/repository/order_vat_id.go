@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+)
+
+const (
+	collectionOrderVatId = "order_vat_ids"
+)
+
+type orderVatIdRepository repository
+
+// NewOrderVatIdRepository create and return an object for working with the order VAT ID repository.
+// The returned object implements the OrderVatIdRepositoryInterface interface.
+func NewOrderVatIdRepository(db mongodb.SourceInterface) OrderVatIdRepositoryInterface {
+	s := &orderVatIdRepository{db: db}
+	return s
+}
+
+func (r *orderVatIdRepository) Upsert(ctx context.Context, obj *internalPkg.OrderVatId) error {
+	filter := bson.M{"_id": obj.Id}
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.db.Collection(collectionOrderVatId).ReplaceOne(ctx, filter, obj, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderVatId),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *orderVatIdRepository) GetByOrderId(ctx context.Context, orderId string) (*internalPkg.OrderVatId, error) {
+	oid, err := primitive.ObjectIDFromHex(orderId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderVatId),
+			zap.String(pkg.ErrorDatabaseFieldQuery, orderId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"order_id": oid}
+	obj := &internalPkg.OrderVatId{}
+	err = r.db.Collection(collectionOrderVatId).FindOne(ctx, query).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderVatId),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+func (r *orderVatIdRepository) DeleteByOrderId(ctx context.Context, orderId string) error {
+	oid, err := primitive.ObjectIDFromHex(orderId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderVatId),
+			zap.String(pkg.ErrorDatabaseFieldQuery, orderId),
+		)
+		return err
+	}
+
+	query := bson.M{"order_id": oid}
+	_, err = r.db.Collection(collectionOrderVatId).DeleteOne(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderVatId),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationDelete),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// OrderVatIdRepositoryInterface is abstraction layer for working with VAT IDs of orders and representation in database.
+type OrderVatIdRepositoryInterface interface {
+	// Upsert adds or updates the VAT ID of the order in the collection.
+	Upsert(context.Context, *pkg.OrderVatId) error
+
+	// GetByOrderId returns the VAT ID of the order.
+	GetByOrderId(context.Context, string) (*pkg.OrderVatId, error)
+
+	// DeleteByOrderId removes the VAT ID of the order.
+	DeleteByOrderId(context.Context, string) error
+}
@@ -381,21 +381,27 @@ func (r *orderViewRepository) GetRoyaltySummary(
 	}
 
 	for _, item := range items {
-		r.royaltySummaryItemPrecise(item)
+		r.royaltySummaryItemPrecise(item, currency)
 	}
 
-	r.royaltySummaryItemPrecise(total)
+	r.royaltySummaryItemPrecise(total, currency)
 
 	return
 }
 
-func (r *orderViewRepository) royaltySummaryItemPrecise(item *billingpb.RoyaltyReportProductSummaryItem) {
-	item.GrossSalesAmount = tools.ToPrecise(item.GrossSalesAmount)
-	item.GrossReturnsAmount = tools.ToPrecise(item.GrossReturnsAmount)
-	item.GrossTotalAmount = tools.ToPrecise(item.GrossTotalAmount)
-	item.TotalFees = tools.ToPrecise(item.TotalFees)
-	item.TotalVat = tools.ToPrecise(item.TotalVat)
-	item.PayoutAmount = tools.ToPrecise(item.PayoutAmount)
+func (r *orderViewRepository) royaltySummaryItemPrecise(item *billingpb.RoyaltyReportProductSummaryItem, currency string) {
+	precise := func(amount float64) float64 {
+		return pkg2.NewMoney(amount, currency, pkg2.MoneyDefaultPrecision).
+			RoundTo(pkg2.MoneyAccountingPrecision).
+			Float64()
+	}
+
+	item.GrossSalesAmount = precise(item.GrossSalesAmount)
+	item.GrossReturnsAmount = precise(item.GrossReturnsAmount)
+	item.GrossTotalAmount = precise(item.GrossTotalAmount)
+	item.TotalFees = precise(item.TotalFees)
+	item.TotalVat = precise(item.TotalVat)
+	item.PayoutAmount = precise(item.PayoutAmount)
 }
 
 
@@ -768,8 +774,30 @@ func (r *orderViewRepository) GetVatSummary(
 		"is_vat_deduction":     isVatDeduction,
 		"operating_company_id": operatingCompanyId,
 		"is_production":        true,
+		"is_reverse_charge":    bson.M{"$ne": true},
+	}
+
+	return r.getVatSummary(ctx, matchQuery)
+}
+
+func (r *orderViewRepository) GetVatReverseChargeSummary(
+	ctx context.Context, operatingCompanyId, country string, from, to time.Time,
+) (items []*pkg2.VatReportQueryResItem, err error) {
+	matchQuery := bson.M{
+		"pm_order_close_date": bson.M{
+			"$gte": now.New(from).BeginningOfDay(),
+			"$lte": now.New(to).EndOfDay(),
+		},
+		"country_code":         country,
+		"operating_company_id": operatingCompanyId,
+		"is_production":        true,
+		"is_reverse_charge":    true,
 	}
 
+	return r.getVatSummary(ctx, matchQuery)
+}
+
+func (r *orderViewRepository) getVatSummary(ctx context.Context, matchQuery bson.M) ([]*pkg2.VatReportQueryResItem, error) {
 	query := []bson.M{
 		{
 			"$match": &matchQuery,
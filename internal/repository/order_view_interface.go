@@ -52,6 +52,9 @@ type OrderViewRepositoryInterface interface {
 	// GetVatSummary returns orders for summary vat report by operating company id, country, vat deduction and dates.
 	GetVatSummary(context.Context, string, string, bool, time.Time, time.Time) ([]*pkg.VatReportQueryResItem, error)
 
+	// GetVatReverseChargeSummary returns summary of the reverse charge orders by operating company id, country and dates.
+	GetVatReverseChargeSummary(context.Context, string, string, time.Time, time.Time) ([]*pkg.VatReportQueryResItem, error)
+
 	// GetTurnoverSummary returns orders for summary turnover report by operating company id, country, currency policy and dates.
 	GetTurnoverSummary(context.Context, string, string, string, time.Time, time.Time) ([]*pkg.TurnoverQueryResItem, error)
 
@@ -94,8 +94,9 @@ func (r *payoutRepository) Update(ctx context.Context, pd *billingpb.PayoutDocum
 		return err
 	}
 
+	// the document is updated with $set to keep the fields which aren't present in the payout message, like batch id
 	filter := bson.M{"_id": oid}
-	_, err = r.db.Collection(collectionPayoutDocuments).ReplaceOne(ctx, filter, mgo)
+	_, err = r.db.Collection(collectionPayoutDocuments).UpdateOne(ctx, filter, bson.M{"$set": mgo})
 
 	if err != nil {
 		zap.L().Error(
@@ -495,6 +496,135 @@ func (r *payoutRepository) Find(
 	return objs, nil
 }
 
+func (r *payoutRepository) ClaimForBatch(ctx context.Context, ids []string, status, batchId string) ([]string, error) {
+	oids, err := r.getObjectIds(ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{
+		"_id":      bson.M{"$in": oids},
+		"status":   status,
+		"batch_id": bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{"batch_id": batchId}}
+	_, err = r.db.Collection(collectionPayoutDocuments).UpdateMany(ctx, filter, update)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutDocuments),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, update),
+		)
+		return nil, err
+	}
+
+	batchIds, err := r.GetBatchIds(ctx, ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := make([]string, 0, len(batchIds))
+
+	for _, id := range ids {
+		if batchIds[id] == batchId {
+			claimed = append(claimed, id)
+		}
+	}
+
+	return claimed, nil
+}
+
+func (r *payoutRepository) ReleaseBatch(ctx context.Context, batchId string) error {
+	filter := bson.M{"batch_id": batchId}
+	update := bson.M{"$unset": bson.M{"batch_id": ""}}
+	_, err := r.db.Collection(collectionPayoutDocuments).UpdateMany(ctx, filter, update)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutDocuments),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, update),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *payoutRepository) GetBatchIds(ctx context.Context, ids []string) (map[string]string, error) {
+	oids, err := r.getObjectIds(ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	query := bson.M{"_id": bson.M{"$in": oids}, "batch_id": bson.M{"$exists": true, "$ne": ""}}
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "batch_id": 1})
+	cursor, err := r.db.Collection(collectionPayoutDocuments).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutDocuments),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*models.MgoPayoutDocument
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutDocuments),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	result := make(map[string]string, len(list))
+
+	for _, item := range list {
+		result[item.Id.Hex()] = item.BatchId
+	}
+
+	return result, nil
+}
+
+func (r *payoutRepository) getObjectIds(ids []string) ([]primitive.ObjectID, error) {
+	oids := make([]primitive.ObjectID, 0, len(ids))
+
+	for _, id := range ids {
+		oid, err := primitive.ObjectIDFromHex(id)
+
+		if err != nil {
+			zap.L().Error(
+				pkg.ErrorDatabaseInvalidObjectId,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutDocuments),
+				zap.String(pkg.ErrorDatabaseFieldQuery, id),
+			)
+			return nil, err
+		}
+
+		oids = append(oids, oid)
+	}
+
+	return oids, nil
+}
+
 func (r *payoutRepository) updateCaches(pd *billingpb.PayoutDocument) (err error) {
 	key1 := fmt.Sprintf(cacheKeyPayoutDocument, pd.Id)
 	key2 := fmt.Sprintf(cacheKeyPayoutDocumentMerchant, pd.Id, pd.MerchantId)
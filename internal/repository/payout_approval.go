@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+)
+
+const (
+	collectionPayoutApproval = "payout_approvals"
+)
+
+type payoutApprovalRepository repository
+
+// NewPayoutApprovalRepository create and return an object for working with the payout approval repository.
+// The returned object implements the PayoutApprovalRepositoryInterface interface.
+func NewPayoutApprovalRepository(db mongodb.SourceInterface) PayoutApprovalRepositoryInterface {
+	s := &payoutApprovalRepository{db: db}
+	return s
+}
+
+func (r *payoutApprovalRepository) Insert(ctx context.Context, obj *internalPkg.PayoutApproval) error {
+	_, err := r.db.Collection(collectionPayoutApproval).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutApproval),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *payoutApprovalRepository) Update(ctx context.Context, obj *internalPkg.PayoutApproval) error {
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionPayoutApproval).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutApproval),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *payoutApprovalRepository) UpdateByState(
+	ctx context.Context,
+	obj *internalPkg.PayoutApproval,
+	status string,
+	approvedBy []string,
+) error {
+	if approvedBy == nil {
+		approvedBy = []string{}
+	}
+
+	filter := bson.M{"_id": obj.Id, "status": status, "approved_by": approvedBy}
+	res, err := r.db.Collection(collectionPayoutApproval).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutApproval),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	if res.MatchedCount <= 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *payoutApprovalRepository) GetByPayoutDocumentId(
+	ctx context.Context,
+	payoutDocumentId string,
+) (*internalPkg.PayoutApproval, error) {
+	query := bson.M{"payout_document_id": payoutDocumentId}
+	obj := &internalPkg.PayoutApproval{}
+	err := r.db.Collection(collectionPayoutApproval).FindOne(ctx, query).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutApproval),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// PayoutApprovalRepositoryInterface is abstraction layer for working with approvals of payout documents
+// and representation in database.
+type PayoutApprovalRepositoryInterface interface {
+	// Insert adds the approval of the payout document.
+	Insert(context.Context, *pkg.PayoutApproval) error
+
+	// Update updates the approval of the payout document.
+	Update(context.Context, *pkg.PayoutApproval) error
+
+	// UpdateByState updates the approval of the payout document only if its status and approvers are still equal
+	// to the passed ones. Returns mongo.ErrNoDocuments if the approval was changed concurrently.
+	UpdateByState(ctx context.Context, obj *pkg.PayoutApproval, status string, approvedBy []string) error
+
+	// GetByPayoutDocumentId returns the approval of the payout document.
+	GetByPayoutDocumentId(context.Context, string) (*pkg.PayoutApproval, error)
+}
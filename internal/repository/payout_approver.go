@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+)
+
+const (
+	collectionPayoutApprover = "payout_approvers"
+)
+
+type payoutApproverRepository repository
+
+// NewPayoutApproverRepository create and return an object for working with the payout approver repository.
+// The returned object implements the PayoutApproverRepositoryInterface interface.
+func NewPayoutApproverRepository(db mongodb.SourceInterface) PayoutApproverRepositoryInterface {
+	s := &payoutApproverRepository{db: db}
+	return s
+}
+
+func (r *payoutApproverRepository) Upsert(ctx context.Context, obj *internalPkg.PayoutApprover) error {
+	filter := bson.M{"user_id": obj.UserId}
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.db.Collection(collectionPayoutApprover).ReplaceOne(ctx, filter, obj, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutApprover),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *payoutApproverRepository) GetByUserId(
+	ctx context.Context,
+	userId string,
+) (*internalPkg.PayoutApprover, error) {
+	query := bson.M{"user_id": userId}
+	obj := &internalPkg.PayoutApprover{}
+	err := r.db.Collection(collectionPayoutApprover).FindOne(ctx, query).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutApprover),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// PayoutApproverRepositoryInterface is abstraction layer for working with limits of payout approvers
+// and representation in database.
+type PayoutApproverRepositoryInterface interface {
+	// Upsert adds or replaces the limits of the approver.
+	Upsert(context.Context, *pkg.PayoutApprover) error
+
+	// GetByUserId returns the limits of the approver by user identifier.
+	GetByUserId(context.Context, string) (*pkg.PayoutApprover, error)
+}
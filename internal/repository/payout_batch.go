@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+)
+
+const (
+	collectionPayoutBatch = "payout_batches"
+)
+
+type payoutBatchRepository repository
+
+// NewPayoutBatchRepository create and return an object for working with the payout batch repository.
+// The returned object implements the PayoutBatchRepositoryInterface interface.
+func NewPayoutBatchRepository(db mongodb.SourceInterface) PayoutBatchRepositoryInterface {
+	s := &payoutBatchRepository{db: db}
+	return s
+}
+
+func (r *payoutBatchRepository) Insert(ctx context.Context, obj *internalPkg.PayoutBatch) error {
+	_, err := r.db.Collection(collectionPayoutBatch).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutBatch),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, obj.Id.Hex()),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *payoutBatchRepository) GetById(ctx context.Context, id string) (*internalPkg.PayoutBatch, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutBatch),
+			zap.String(pkg.ErrorDatabaseFieldQuery, id),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"_id": oid}
+	obj := &internalPkg.PayoutBatch{}
+	err = r.db.Collection(collectionPayoutBatch).FindOne(ctx, query).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutBatch),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// PayoutBatchRepositoryInterface is abstraction layer for working with bank transfer files of payout documents
+// and representation in database.
+type PayoutBatchRepositoryInterface interface {
+	// Insert adds the batch to the collection.
+	Insert(context.Context, *pkg.PayoutBatch) error
+
+	// GetById returns the batch by unique identity.
+	GetById(context.Context, string) (*pkg.PayoutBatch, error)
+}
@@ -30,4 +30,14 @@ type PayoutRepositoryInterface interface {
 
 	// FindCount return count of payouts by merchant, statuses and dates from/to.
 	FindCount(context.Context, string, []string, int64, int64) (int64, error)
+
+	// ClaimForBatch atomically records the id of the bank transfer batch on the payouts in the passed status which
+	// aren't included into another batch yet and returns the ids of the claimed payouts.
+	ClaimForBatch(ctx context.Context, ids []string, status, batchId string) ([]string, error)
+
+	// ReleaseBatch removes the id of the bank transfer batch from the payouts, for example if the batch isn't saved.
+	ReleaseBatch(context.Context, string) error
+
+	// GetBatchIds returns the batch ids of the payouts included into the bank transfer batches.
+	GetBatchIds(context.Context, []string) (map[string]string, error)
 }
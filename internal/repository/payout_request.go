@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionPayoutRequest            = "payout_requests"
+	collectionPayoutRequestReservation = "payout_request_reservations"
+)
+
+type payoutRequestRepository repository
+
+// NewPayoutRequestRepository create and return an object for working with the payout request repository.
+// The returned object implements the PayoutRequestRepositoryInterface interface.
+func NewPayoutRequestRepository(db mongodb.SourceInterface) PayoutRequestRepositoryInterface {
+	s := &payoutRequestRepository{db: db}
+	return s
+}
+
+func (r *payoutRequestRepository) Insert(ctx context.Context, obj *internalPkg.PayoutRequest) error {
+	_, err := r.db.Collection(collectionPayoutRequest).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutRequest),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *payoutRequestRepository) CountByMerchantId(ctx context.Context, merchantId string, since time.Time) (int64, error) {
+	oid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutRequest),
+			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+		)
+		return 0, err
+	}
+
+	query := bson.M{
+		"merchant_id": oid,
+		"created_at":  bson.M{"$gte": since},
+	}
+	count, err := r.db.Collection(collectionPayoutRequest).CountDocuments(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutRequest),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *payoutRequestRepository) Reserve(
+	ctx context.Context,
+	obj *internalPkg.PayoutRequestReservation,
+	reservedBefore time.Time,
+) error {
+	// the reservation made after the time is still in progress, so the filter doesn't match it and the upsert
+	// fails on the duplicate identifier of the merchant
+	filter := bson.M{
+		"_id":         obj.MerchantId,
+		"reserved_at": bson.M{"$lt": reservedBefore},
+	}
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.db.Collection(collectionPayoutRequestReservation).ReplaceOne(ctx, filter, obj, opts)
+
+	if err != nil {
+		if IsDuplicateKeyError(err) {
+			return mongo.ErrNoDocuments
+		}
+
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutRequestReservation),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *payoutRequestRepository) Release(ctx context.Context, obj *internalPkg.PayoutRequestReservation) error {
+	filter := bson.M{
+		"_id":            obj.MerchantId,
+		"reservation_id": obj.ReservationId,
+	}
+	set := bson.M{"$set": bson.M{"reserved_at": time.Time{}}}
+	_, err := r.db.Collection(collectionPayoutRequestReservation).UpdateOne(ctx, filter, set)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPayoutRequestReservation),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, set),
+		)
+		return err
+	}
+
+	return nil
+}
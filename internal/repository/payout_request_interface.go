@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"time"
+)
+
+// PayoutRequestRepositoryInterface is abstraction layer for working with on-demand payout requests of merchants
+// and representation in database.
+type PayoutRequestRepositoryInterface interface {
+	// Insert adds the payout request of the merchant.
+	Insert(context.Context, *pkg.PayoutRequest) error
+
+	// CountByMerchantId returns the number of the payout requests of the merchant created since the time.
+	CountByMerchantId(context.Context, string, time.Time) (int64, error)
+
+	// Reserve reserves the balance of the merchant for the payout request if the merchant hasn't got the reservation
+	// made after the time, mongo.ErrNoDocuments is returned if the balance is already reserved.
+	Reserve(context.Context, *pkg.PayoutRequestReservation, time.Time) error
+
+	// Release releases the reservation of the balance of the merchant.
+	Release(context.Context, *pkg.PayoutRequestReservation) error
+}
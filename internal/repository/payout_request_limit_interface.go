@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// PayoutRequestLimitRepositoryInterface is abstraction layer for working with limits of on-demand payout requests
+// of merchants and representation in database.
+type PayoutRequestLimitRepositoryInterface interface {
+	// Upsert adds or replaces the payout request limit of the merchant.
+	Upsert(context.Context, *pkg.PayoutRequestLimit) error
+
+	// GetByMerchantId returns the payout request limit of the merchant.
+	GetByMerchantId(context.Context, string) (*pkg.PayoutRequestLimit, error)
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// PayoutScheduleRepositoryInterface is abstraction layer for working with payout schedules of merchants
+// and representation in database.
+type PayoutScheduleRepositoryInterface interface {
+	// Upsert adds or replaces the payout schedule of the merchant.
+	Upsert(context.Context, *pkg.PayoutSchedule) error
+
+	// GetByMerchantId returns the payout schedule of the merchant.
+	GetByMerchantId(context.Context, string) (*pkg.PayoutSchedule, error)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
 )
@@ -80,3 +81,101 @@ func (r priceTableRepository) GetByRegion(ctx context.Context, region string) (*
 
 	return obj.(*billingpb.PriceTable), nil
 }
+
+func (r priceTableRepository) GetAll(ctx context.Context) ([]*billingpb.PriceTable, error) {
+	query := bson.M{}
+	cursor, err := r.db.Collection(collectionPriceTable).Find(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPriceTable),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*models.MgoPriceTable
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPriceTable),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	objs := make([]*billingpb.PriceTable, len(list))
+
+	for i, mgo := range list {
+		obj, err := r.mapper.MapMgoToObject(mgo)
+
+		if err != nil {
+			zap.L().Error(
+				pkg.ErrorDatabaseMapModelFailed,
+				zap.Error(err),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, mgo),
+			)
+			return nil, err
+		}
+
+		objs[i] = obj.(*billingpb.PriceTable)
+	}
+
+	return objs, nil
+}
+
+func (r *priceTableRepository) ReplaceAll(ctx context.Context, tables []*billingpb.PriceTable) error {
+	var operations []mongo.WriteModel
+
+	regions := make([]string, 0, len(tables))
+
+	for _, table := range tables {
+		mgo, err := r.mapper.MapObjectToMgo(table)
+
+		if err != nil {
+			zap.L().Error(
+				pkg.ErrorDatabaseMapModelFailed,
+				zap.Error(err),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, table),
+			)
+			return err
+		}
+
+		// keep identity of the existing region document, the region name is the real key of the collection
+		set := mgo.(*models.MgoPriceTable)
+		operation := mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"currency": table.Currency}).
+			SetUpdate(bson.M{"$set": bson.M{"range": set.Ranges}, "$setOnInsert": bson.M{"_id": set.Id}}).
+			SetUpsert(true)
+		operations = append(operations, operation)
+		regions = append(regions, table.Currency)
+	}
+
+	if len(operations) <= 0 {
+		return nil
+	}
+
+	// the ordered bulk write removes the regions missing in the passed tables after the upsert of the passed ones,
+	// so the repeated call after the failure gives the same result
+	operation := mongo.NewDeleteManyModel().SetFilter(bson.M{"currency": bson.M{"$nin": regions}})
+	operations = append(operations, operation)
+
+	_, err := r.db.Collection(collectionPriceTable).BulkWrite(ctx, operations)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPriceTable),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpsert),
+		)
+		return err
+	}
+
+	return nil
+}
@@ -12,4 +12,11 @@ type PriceTableRepositoryInterface interface {
 
 	// GetByRegion returns the price table by region name.
 	GetByRegion(context.Context, string) (*billingpb.PriceTable, error)
+
+	// GetAll returns the price tables of all regions.
+	GetAll(context.Context) ([]*billingpb.PriceTable, error)
+
+	// ReplaceAll replaces the price tables of the regions by the passed ones and removes the regions
+	// missing in the passed tables.
+	ReplaceAll(context.Context, []*billingpb.PriceTable) error
 }
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+)
+
+const (
+	collectionPriceTableVersion = "price_table_versions"
+)
+
+type priceTableVersionRepository repository
+
+// NewPriceTableVersionRepository create and return an object for working with the price table version repository.
+// The returned object implements the PriceTableVersionRepositoryInterface interface.
+func NewPriceTableVersionRepository(db mongodb.SourceInterface) PriceTableVersionRepositoryInterface {
+	s := &priceTableVersionRepository{db: db}
+	return s
+}
+
+func (r *priceTableVersionRepository) Insert(ctx context.Context, obj *internalPkg.PriceTableVersion) error {
+	_, err := r.db.Collection(collectionPriceTableVersion).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPriceTableVersion),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *priceTableVersionRepository) Update(ctx context.Context, obj *internalPkg.PriceTableVersion) error {
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionPriceTableVersion).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPriceTableVersion),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *priceTableVersionRepository) GetById(ctx context.Context, id string) (*internalPkg.PriceTableVersion, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPriceTableVersion),
+			zap.String(pkg.ErrorDatabaseFieldQuery, id),
+		)
+		return nil, err
+	}
+
+	return r.findOne(ctx, bson.M{"_id": oid})
+}
+
+func (r *priceTableVersionRepository) GetLastByStatus(ctx context.Context, status string) (*internalPkg.PriceTableVersion, error) {
+	opts := options.FindOne().SetSort(bson.D{{"activated_at", -1}, {"version", -1}})
+	return r.findOne(ctx, bson.M{"status": status}, opts)
+}
+
+func (r *priceTableVersionRepository) CountByStatus(ctx context.Context, status string) (int64, error) {
+	query := bson.M{"status": status}
+	count, err := r.db.Collection(collectionPriceTableVersion).CountDocuments(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPriceTableVersion),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *priceTableVersionRepository) ArchiveActive(ctx context.Context, except primitive.ObjectID) error {
+	query := bson.M{
+		"_id":    bson.M{"$ne": except},
+		"status": internalPkg.PriceTableVersionStatusActive,
+	}
+	set := bson.M{"$set": bson.M{"status": internalPkg.PriceTableVersionStatusArchived}}
+	_, err := r.db.Collection(collectionPriceTableVersion).UpdateMany(ctx, query, set)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPriceTableVersion),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			zap.Any(pkg.ErrorDatabaseFieldSet, set),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *priceTableVersionRepository) GetPreviousArchived(
+	ctx context.Context,
+	version int32,
+) (*internalPkg.PriceTableVersion, error) {
+	query := bson.M{
+		"status":  internalPkg.PriceTableVersionStatusArchived,
+		"version": bson.M{"$lt": version},
+	}
+	opts := options.FindOne().SetSort(bson.M{"version": -1})
+	return r.findOne(ctx, query, opts)
+}
+
+func (r *priceTableVersionRepository) GetLastVersionNumber(ctx context.Context) (int32, error) {
+	opts := options.FindOne().SetSort(bson.M{"version": -1})
+	obj, err := r.findOne(ctx, bson.M{}, opts)
+
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return obj.Version, nil
+}
+
+func (r *priceTableVersionRepository) findOne(
+	ctx context.Context,
+	query bson.M,
+	opts ...*options.FindOneOptions,
+) (*internalPkg.PriceTableVersion, error) {
+	obj := &internalPkg.PriceTableVersion{}
+	err := r.db.Collection(collectionPriceTableVersion).FindOne(ctx, query, opts...).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionPriceTableVersion),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PriceTableVersionRepositoryInterface is abstraction layer for working with versions of the price tables
+// and representation in database.
+type PriceTableVersionRepositoryInterface interface {
+	// Insert adds the price table version to the collection.
+	Insert(context.Context, *pkg.PriceTableVersion) error
+
+	// Update updates the price table version in the collection.
+	Update(context.Context, *pkg.PriceTableVersion) error
+
+	// GetById returns the price table version by unique identity.
+	GetById(context.Context, string) (*pkg.PriceTableVersion, error)
+
+	// GetLastByStatus returns the last activated price table version with the passed status,
+	// the versions which were never activated are ordered by the version number.
+	GetLastByStatus(context.Context, string) (*pkg.PriceTableVersion, error)
+
+	// CountByStatus returns the number of the price table versions with the passed status.
+	CountByStatus(context.Context, string) (int64, error)
+
+	// ArchiveActive sets the archived status to all active versions except the passed one.
+	ArchiveActive(ctx context.Context, except primitive.ObjectID) error
+
+	// GetPreviousArchived returns the archived version preceding the passed version number.
+	GetPreviousArchived(context.Context, int32) (*pkg.PriceTableVersion, error)
+
+	// GetLastVersionNumber returns the greatest version number or zero if no versions exist.
+	GetLastVersionNumber(context.Context) (int32, error)
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+)
+
+const (
+	cachePricingRulesPriceGroupId = "pricing_rules:price_group_id:%s"
+	cachePricingRulesAll          = "pricing_rules:all"
+
+	collectionPricingRules = "price_group_pricing_rules"
+)
+
+type pricingRuleRepository repository
+
+// NewPricingRuleRepository create and return an object for working with the pricing rules repository.
+// The returned object implements the PricingRuleRepositoryInterface interface.
+func NewPricingRuleRepository(db mongodb.SourceInterface, cache database.CacheInterface) PricingRuleRepositoryInterface {
+	s := &pricingRuleRepository{db: db, cache: cache}
+	return s
+}
+
+func (r *pricingRuleRepository) Upsert(ctx context.Context, obj *internalPkg.PriceGroupPricingRules) error {
+	filter := bson.M{"price_group_id": obj.PriceGroupId}
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.db.Collection(collectionPricingRules).ReplaceOne(ctx, filter, obj, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPricingRules),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	key := fmt.Sprintf(cachePricingRulesPriceGroupId, obj.PriceGroupId.Hex())
+
+	if err = r.cache.Set(key, obj, 0); err != nil {
+		zap.L().Error(
+			pkg.ErrorCacheQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorCacheFieldCmd, "SET"),
+			zap.String(pkg.ErrorCacheFieldKey, key),
+			zap.Any(pkg.ErrorCacheFieldData, obj),
+		)
+		return err
+	}
+
+	if err = r.cache.Delete(cachePricingRulesAll); err != nil {
+		zap.L().Error(
+			pkg.ErrorCacheQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorCacheFieldCmd, "DELETE"),
+			zap.String(pkg.ErrorCacheFieldKey, cachePricingRulesAll),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *pricingRuleRepository) GetByPriceGroupId(
+	ctx context.Context,
+	priceGroupId string,
+) (*internalPkg.PriceGroupPricingRules, error) {
+	obj := &internalPkg.PriceGroupPricingRules{}
+	key := fmt.Sprintf(cachePricingRulesPriceGroupId, priceGroupId)
+
+	if err := r.cache.Get(key, obj); err == nil {
+		return obj, nil
+	}
+
+	oid, err := primitive.ObjectIDFromHex(priceGroupId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPricingRules),
+			zap.String(pkg.ErrorDatabaseFieldQuery, priceGroupId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"price_group_id": oid}
+	err = r.db.Collection(collectionPricingRules).FindOne(ctx, query).Decode(obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPricingRules),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	if err = r.cache.Set(key, obj, 0); err != nil {
+		zap.L().Error(
+			pkg.ErrorCacheQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorCacheFieldCmd, "SET"),
+			zap.String(pkg.ErrorCacheFieldKey, key),
+			zap.Any(pkg.ErrorCacheFieldData, obj),
+		)
+	}
+
+	return obj, nil
+}
+
+func (r *pricingRuleRepository) GetAll(ctx context.Context) ([]*internalPkg.PriceGroupPricingRules, error) {
+	var list []*internalPkg.PriceGroupPricingRules
+
+	if err := r.cache.Get(cachePricingRulesAll, &list); err == nil {
+		return list, nil
+	}
+
+	query := bson.M{}
+	cursor, err := r.db.Collection(collectionPricingRules).Find(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPricingRules),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPricingRules),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	if err = r.cache.Set(cachePricingRulesAll, list, 0); err != nil {
+		zap.L().Error(
+			pkg.ErrorCacheQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorCacheFieldCmd, "SET"),
+			zap.String(pkg.ErrorCacheFieldKey, cachePricingRulesAll),
+			zap.Any(pkg.ErrorCacheFieldData, list),
+		)
+	}
+
+	return list, nil
+}
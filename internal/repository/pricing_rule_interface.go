@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// PricingRuleRepositoryInterface is abstraction layer for working with pricing rules of the price groups
+// and representation in database.
+type PricingRuleRepositoryInterface interface {
+	// Upsert adds or replaces the pricing rules of the price group.
+	Upsert(context.Context, *pkg.PriceGroupPricingRules) error
+
+	// GetByPriceGroupId returns the pricing rules by price group identity.
+	GetByPriceGroupId(context.Context, string) (*pkg.PriceGroupPricingRules, error)
+
+	// GetAll returns the pricing rules of all price groups.
+	GetAll(context.Context) ([]*pkg.PriceGroupPricingRules, error)
+}
@@ -1,9 +1,15 @@
 package repository
 
 import (
+	"context"
 	"github.com/paysuper/paysuper-billing-server/internal/database"
 	"github.com/paysuper/paysuper-billing-server/internal/repository/models"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
 	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"sync"
 )
 
 type repository struct {
@@ -11,3 +17,83 @@ type repository struct {
 	cache  database.CacheInterface
 	mapper models.Mapper
 }
+
+const (
+	mongoDuplicateKeyErrorCode = 11000
+)
+
+// transactionSupport caches whether the database server of the client supports the transactions.
+var transactionSupport sync.Map
+
+// IsDuplicateKeyError checks that the error is caused by the duplicate value of the unique index.
+func IsDuplicateKeyError(err error) bool {
+	switch e := err.(type) {
+	case mongo.WriteException:
+		for _, we := range e.WriteErrors {
+			if we.Code == mongoDuplicateKeyErrorCode {
+				return true
+			}
+		}
+	case mongo.CommandError:
+		return e.Code == mongoDuplicateKeyErrorCode
+	}
+
+	return false
+}
+
+// WithTransaction executes the function in the database transaction, the repository methods called with the context
+// passed to the function are executed in the transaction. The driver retries the transaction on the transient errors,
+// so the function can be executed several times. The function called inside another transaction joins it.
+//
+// The standalone database server doesn't support the transactions, the function is executed without transaction there.
+func WithTransaction(ctx context.Context, db mongodb.SourceInterface, fn func(context.Context) error) error {
+	if _, ok := ctx.(mongo.SessionContext); ok {
+		return fn(ctx)
+	}
+
+	client := db.Collection(CollectionAccountingEntry).Database().Client()
+	supported, err := isTransactionSupported(ctx, client)
+
+	if err != nil {
+		return err
+	}
+
+	if !supported {
+		return fn(ctx)
+	}
+
+	return client.UseSession(ctx, func(sessCtx mongo.SessionContext) error {
+		_, err := sessCtx.WithTransaction(sessCtx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+			return nil, fn(sessCtx)
+		})
+		return err
+	})
+}
+
+// isTransactionSupported checks that the database server is the replica set member or the sharded cluster router.
+func isTransactionSupported(ctx context.Context, client *mongo.Client) (bool, error) {
+	if supported, ok := transactionSupport.Load(client); ok {
+		return supported.(bool), nil
+	}
+
+	command := bson.D{{"isMaster", 1}}
+	res := struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}{}
+	err := client.Database("admin").RunCommand(ctx, command).Decode(&res)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, command),
+		)
+		return false, err
+	}
+
+	supported := res.SetName != "" || res.Msg == "isdbgrid"
+	transactionSupport.Store(client, supported)
+
+	return supported, nil
+}
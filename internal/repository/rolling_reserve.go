@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionRollingReserve = "rolling_reserves"
+)
+
+type rollingReserveRepository repository
+
+// NewRollingReserveRepository create and return an object for working with the rolling reserve repository.
+// The returned object implements the RollingReserveRepositoryInterface interface.
+func NewRollingReserveRepository(db mongodb.SourceInterface) RollingReserveRepositoryInterface {
+	s := &rollingReserveRepository{db: db}
+	return s
+}
+
+func (r *rollingReserveRepository) Insert(ctx context.Context, obj *internalPkg.RollingReserve) error {
+	_, err := r.db.Collection(collectionRollingReserve).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRollingReserve),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *rollingReserveRepository) Update(ctx context.Context, obj *internalPkg.RollingReserve) error {
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionRollingReserve).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRollingReserve),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *rollingReserveRepository) Release(ctx context.Context, obj *internalPkg.RollingReserve) error {
+	filter := bson.M{"_id": obj.Id, "status": internalPkg.RollingReserveStatusHeld}
+	set := bson.M{
+		"$set": bson.M{
+			"status":           obj.Status,
+			"release_entry_id": obj.ReleaseEntryId,
+			"released_at":      obj.ReleasedAt,
+			"updated_at":       obj.UpdatedAt,
+		},
+	}
+	res, err := r.db.Collection(collectionRollingReserve).UpdateOne(ctx, filter, set)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRollingReserve),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, set),
+		)
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *rollingReserveRepository) GetByMerchantPeriod(
+	ctx context.Context,
+	merchantId, currency string,
+	periodFrom time.Time,
+) (*internalPkg.RollingReserve, error) {
+	oid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRollingReserve),
+			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"merchant_id": oid, "currency": currency, "period_from": periodFrom}
+	obj := &internalPkg.RollingReserve{}
+	err = r.db.Collection(collectionRollingReserve).FindOne(ctx, query).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionRollingReserve),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+func (r *rollingReserveRepository) FindByMerchantId(
+	ctx context.Context,
+	merchantId, currency, status string,
+) ([]*internalPkg.RollingReserve, error) {
+	oid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRollingReserve),
+			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"merchant_id": oid, "currency": currency}
+
+	if status != "" {
+		query["status"] = status
+	}
+
+	return r.find(ctx, query, options.Find().SetSort(bson.M{"release_at": 1}))
+}
+
+func (r *rollingReserveRepository) FindToRelease(
+	ctx context.Context,
+	date time.Time,
+) ([]*internalPkg.RollingReserve, error) {
+	query := bson.M{
+		"status":     internalPkg.RollingReserveStatusHeld,
+		"release_at": bson.M{"$lte": date},
+	}
+
+	return r.find(ctx, query, options.Find().SetSort(bson.M{"release_at": 1}))
+}
+
+func (r *rollingReserveRepository) find(
+	ctx context.Context,
+	query bson.M,
+	opts ...*options.FindOptions,
+) ([]*internalPkg.RollingReserve, error) {
+	cursor, err := r.db.Collection(collectionRollingReserve).Find(ctx, query, opts...)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRollingReserve),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*internalPkg.RollingReserve
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRollingReserve),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"time"
+)
+
+// RollingReserveRepositoryInterface is abstraction layer for working with held rolling reserves
+// and representation in database.
+type RollingReserveRepositoryInterface interface {
+	// Insert adds the rolling reserve to the collection.
+	Insert(context.Context, *pkg.RollingReserve) error
+
+	// Update updates the rolling reserve in the collection.
+	Update(context.Context, *pkg.RollingReserve) error
+
+	// Release changes the held rolling reserve to the released status.
+	// Returns mongo.ErrNoDocuments if the reserve isn't held anymore.
+	Release(context.Context, *pkg.RollingReserve) error
+
+	// GetByMerchantPeriod returns the rolling reserve by merchant id, currency and start of the royalty period.
+	GetByMerchantPeriod(context.Context, string, string, time.Time) (*pkg.RollingReserve, error)
+
+	// FindByMerchantId returns the rolling reserves of the merchant in the currency with the status ordered by release date.
+	FindByMerchantId(context.Context, string, string, string) ([]*pkg.RollingReserve, error)
+
+	// FindToRelease returns the held rolling reserves with the release date before the passed date.
+	FindToRelease(context.Context, time.Time) ([]*pkg.RollingReserve, error)
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// RollingReservePolicyRepositoryInterface is abstraction layer for working with rolling reserve policies
+// of merchants and representation in database.
+type RollingReservePolicyRepositoryInterface interface {
+	// Upsert adds or replaces the policy of the merchant.
+	Upsert(context.Context, *pkg.RollingReservePolicy) error
+
+	// GetByMerchantId returns the policy of the merchant.
+	GetByMerchantId(context.Context, string) (*pkg.RollingReservePolicy, error)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
 	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
@@ -26,6 +27,14 @@ const (
 	cacheKeyRoyaltyReport = "royalty_report:id:%s"
 )
 
+var (
+	royaltyReportsStatusForBalance = []string{
+		billingpb.RoyaltyReportStatusAccepted,
+		billingpb.RoyaltyReportStatusWaitForPayment,
+		billingpb.RoyaltyReportStatusPaid,
+	}
+)
+
 type royaltyReportRepository repository
 
 // NewRoyaltyReportRepository create and return an object for working with the royalty report repository.
@@ -505,11 +514,6 @@ func (r *royaltyReportRepository) GetBalanceAmount(ctx context.Context, merchant
 		return float64(0), err
 	}
 
-	royaltyReportsStatusForBalance := []string{
-		billingpb.RoyaltyReportStatusAccepted,
-		billingpb.RoyaltyReportStatusWaitForPayment,
-		billingpb.RoyaltyReportStatusPaid,
-	}
 	query := []bson.M{
 		{
 			"$match": bson.M{
@@ -574,6 +578,90 @@ func (r *royaltyReportRepository) GetBalanceAmount(ctx context.Context, merchant
 	return res.Amount, nil
 }
 
+func (r *royaltyReportRepository) GetBalanceCorrectionEntryIds(
+	ctx context.Context,
+	merchantId, currency string,
+	entryIds []string,
+) ([]string, error) {
+	oid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionRoyaltyReport),
+			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+		)
+		return nil, err
+	}
+
+	entryOids := make([]primitive.ObjectID, 0, len(entryIds))
+
+	for _, id := range entryIds {
+		entryOid, err := primitive.ObjectIDFromHex(id)
+
+		if err != nil {
+			zap.L().Error(
+				pkg.ErrorDatabaseInvalidObjectId,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, CollectionRoyaltyReport),
+				zap.String(pkg.ErrorDatabaseFieldQuery, id),
+			)
+			return nil, err
+		}
+
+		entryOids = append(entryOids, entryOid)
+	}
+
+	query := []bson.M{
+		{
+			"$match": bson.M{
+				"merchant_id": oid,
+				"currency":    currency,
+				"status":      bson.M{"$in": royaltyReportsStatusForBalance},
+				"summary.corrections.accounting_entry_id": bson.M{"$in": entryOids},
+			},
+		},
+		{"$unwind": "$summary.corrections"},
+		{"$match": bson.M{"summary.corrections.accounting_entry_id": bson.M{"$in": entryOids}}},
+		{"$project": bson.M{"_id": 0, "id": "$summary.corrections.accounting_entry_id"}},
+	}
+
+	cursor, err := r.db.Collection(CollectionRoyaltyReport).Aggregate(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionRoyaltyReport),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var items []struct {
+		Id primitive.ObjectID `bson:"id"`
+	}
+
+	if err = cursor.All(ctx, &items); err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionRoyaltyReport),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	result := make([]string, 0, len(items))
+
+	for _, item := range items {
+		result = append(result, item.Id.Hex())
+	}
+
+	return result, nil
+}
+
 func (r *royaltyReportRepository) GetReportExists(
 	ctx context.Context,
 	merchantId, currency string,
@@ -624,6 +712,85 @@ func (r *royaltyReportRepository) GetReportExists(
 	return obj.(*billingpb.RoyaltyReport)
 }
 
+func (r *royaltyReportRepository) GetLastPeriodByMerchantId(
+	ctx context.Context,
+	merchantId string,
+) ([]*billingpb.RoyaltyReport, error) {
+	oid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionRoyaltyReport),
+			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+		)
+		return nil, err
+	}
+
+	var last = models.MgoRoyaltyReport{}
+	query := bson.M{"merchant_id": oid}
+	opts := options.FindOne().SetSort(bson.M{"period_to": -1})
+	err = r.db.Collection(CollectionRoyaltyReport).FindOne(ctx, query, opts).Decode(&last)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, CollectionRoyaltyReport),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	query["period_to"] = last.PeriodTo
+	cursor, err := r.db.Collection(CollectionRoyaltyReport).Find(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionRoyaltyReport),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*models.MgoRoyaltyReport
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionRoyaltyReport),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	objs := make([]*billingpb.RoyaltyReport, len(list))
+
+	for i, obj := range list {
+		v, err := r.mapper.MapMgoToObject(obj)
+
+		if err != nil {
+			zap.L().Error(
+				pkg.ErrorDatabaseMapModelFailed,
+				zap.Error(err),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+			)
+			return nil, err
+		}
+
+		objs[i] = v.(*billingpb.RoyaltyReport)
+	}
+
+	return objs, nil
+}
+
 func (r *royaltyReportRepository) Insert(ctx context.Context, rr *billingpb.RoyaltyReport, ip, source string) (err error) {
 	mgo, err := r.mapper.MapObjectToMgo(rr)
 
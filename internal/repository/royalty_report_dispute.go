@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+)
+
+const (
+	collectionRoyaltyReportDispute = "royalty_report_disputes"
+)
+
+type royaltyReportDisputeRepository repository
+
+// NewRoyaltyReportDisputeRepository create and return an object for working with the royalty report dispute repository.
+// The returned object implements the RoyaltyReportDisputeRepositoryInterface interface.
+func NewRoyaltyReportDisputeRepository(db mongodb.SourceInterface) RoyaltyReportDisputeRepositoryInterface {
+	s := &royaltyReportDisputeRepository{db: db}
+	return s
+}
+
+func (r *royaltyReportDisputeRepository) Insert(ctx context.Context, obj *internalPkg.RoyaltyReportDispute) error {
+	_, err := r.db.Collection(collectionRoyaltyReportDispute).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRoyaltyReportDispute),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *royaltyReportDisputeRepository) Update(ctx context.Context, obj *internalPkg.RoyaltyReportDispute) error {
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionRoyaltyReportDispute).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRoyaltyReportDispute),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *royaltyReportDisputeRepository) GetById(ctx context.Context, id string) (*internalPkg.RoyaltyReportDispute, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRoyaltyReportDispute),
+			zap.String(pkg.ErrorDatabaseFieldQuery, id),
+		)
+		return nil, err
+	}
+
+	return r.findOne(ctx, bson.M{"_id": oid})
+}
+
+func (r *royaltyReportDisputeRepository) GetOpenByReportId(
+	ctx context.Context,
+	reportId string,
+) (*internalPkg.RoyaltyReportDispute, error) {
+	query := bson.M{"report_id": reportId, "status": internalPkg.RoyaltyReportDisputeStatusOpen}
+	return r.findOne(ctx, query)
+}
+
+func (r *royaltyReportDisputeRepository) GetByReportId(
+	ctx context.Context,
+	reportId string,
+) ([]*internalPkg.RoyaltyReportDispute, error) {
+	query := bson.M{"report_id": reportId}
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+	cursor, err := r.db.Collection(collectionRoyaltyReportDispute).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRoyaltyReportDispute),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*internalPkg.RoyaltyReportDispute
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRoyaltyReportDispute),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *royaltyReportDisputeRepository) findOne(
+	ctx context.Context,
+	query bson.M,
+) (*internalPkg.RoyaltyReportDispute, error) {
+	obj := &internalPkg.RoyaltyReportDispute{}
+	err := r.db.Collection(collectionRoyaltyReportDispute).FindOne(ctx, query).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionRoyaltyReportDispute),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// RoyaltyReportDisputeRepositoryInterface is abstraction layer for working with disputes of royalty reports
+// and representation in database.
+type RoyaltyReportDisputeRepositoryInterface interface {
+	// Insert adds the dispute of the royalty report.
+	Insert(context.Context, *pkg.RoyaltyReportDispute) error
+
+	// Update updates the dispute of the royalty report.
+	Update(context.Context, *pkg.RoyaltyReportDispute) error
+
+	// GetById returns the dispute by unique identity.
+	GetById(context.Context, string) (*pkg.RoyaltyReportDispute, error)
+
+	// GetOpenByReportId returns the open dispute of the royalty report.
+	GetOpenByReportId(context.Context, string) (*pkg.RoyaltyReportDispute, error)
+
+	// GetByReportId returns all disputes of the royalty report.
+	GetByReportId(context.Context, string) ([]*pkg.RoyaltyReportDispute, error)
+}
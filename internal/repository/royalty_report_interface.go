@@ -30,9 +30,16 @@ type RoyaltyReportRepositoryInterface interface {
 	// GetBalanceAmount returns royalty balance amount for merchant and currency.
 	GetBalanceAmount(ctx context.Context, merchantId, currency string) (float64, error)
 
+	// GetBalanceCorrectionEntryIds returns the identifiers of the correction accounting entries included
+	// into the royalty reports counted in the merchant balance.
+	GetBalanceCorrectionEntryIds(ctx context.Context, merchantId, currency string, entryIds []string) ([]string, error)
+
 	// GetReportExists returns exists a royalty reports by merchant id, currency and dates from/to.
 	GetReportExists(ctx context.Context, merchantId, currency string, from, to time.Time) (report *billingpb.RoyaltyReport)
 
+	// GetLastPeriodByMerchantId returns the royalty reports of the merchant in all currencies with the latest period.
+	GetLastPeriodByMerchantId(ctx context.Context, merchantId string) ([]*billingpb.RoyaltyReport, error)
+
 	// GetAll returns the all royalty reports.
 	GetAll(ctx context.Context) ([]*billingpb.RoyaltyReport, error)
 
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// RoyaltyReportPeriodRepositoryInterface is abstraction layer for working with royalty report periods of merchants
+// and representation in database.
+type RoyaltyReportPeriodRepositoryInterface interface {
+	// Upsert adds or replaces the royalty report period of the merchant.
+	Upsert(context.Context, *pkg.RoyaltyReportPeriod) error
+
+	// GetByMerchantId returns the royalty report period of the merchant.
+	GetByMerchantId(context.Context, string) (*pkg.RoyaltyReportPeriod, error)
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	internalPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+)
+
+const (
+	collectionRoyaltyReportVersion = "royalty_report_versions"
+)
+
+type royaltyReportVersionRepository repository
+
+// NewRoyaltyReportVersionRepository create and return an object for working with the royalty report version repository.
+// The returned object implements the RoyaltyReportVersionRepositoryInterface interface.
+func NewRoyaltyReportVersionRepository(db mongodb.SourceInterface) RoyaltyReportVersionRepositoryInterface {
+	s := &royaltyReportVersionRepository{db: db}
+	return s
+}
+
+func (r *royaltyReportVersionRepository) Insert(ctx context.Context, obj *internalPkg.RoyaltyReportVersion) error {
+	_, err := r.db.Collection(collectionRoyaltyReportVersion).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRoyaltyReportVersion),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldDocument, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *royaltyReportVersionRepository) GetLastByReportId(
+	ctx context.Context,
+	reportId string,
+) (*internalPkg.RoyaltyReportVersion, error) {
+	query := bson.M{"report_id": reportId}
+	opts := options.FindOne().SetSort(bson.M{"version": -1})
+	obj := &internalPkg.RoyaltyReportVersion{}
+	err := r.db.Collection(collectionRoyaltyReportVersion).FindOne(ctx, query, opts).Decode(obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionRoyaltyReportVersion),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+func (r *royaltyReportVersionRepository) GetByReportId(
+	ctx context.Context,
+	reportId string,
+) ([]*internalPkg.RoyaltyReportVersion, error) {
+	query := bson.M{"report_id": reportId}
+	opts := options.Find().SetSort(bson.M{"version": 1})
+	cursor, err := r.db.Collection(collectionRoyaltyReportVersion).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRoyaltyReportVersion),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*internalPkg.RoyaltyReportVersion
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRoyaltyReportVersion),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// RoyaltyReportVersionRepositoryInterface is abstraction layer for working with versions of royalty reports
+// and representation in database.
+type RoyaltyReportVersionRepositoryInterface interface {
+	// Insert adds the version of the royalty report.
+	Insert(context.Context, *pkg.RoyaltyReportVersion) error
+
+	// GetLastByReportId returns the latest version of the royalty report.
+	GetLastByReportId(context.Context, string) (*pkg.RoyaltyReportVersion, error)
+
+	// GetByReportId returns all versions of the royalty report ordered by the version number.
+	GetByReportId(context.Context, string) ([]*pkg.RoyaltyReportVersion, error)
+}
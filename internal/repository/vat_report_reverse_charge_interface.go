@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// VatReportReverseChargeRepositoryInterface is abstraction layer for working with summaries of the reverse charge
+// orders of VAT reports and representation in database.
+type VatReportReverseChargeRepositoryInterface interface {
+	// Upsert adds or updates the reverse charge summary of the VAT report.
+	Upsert(context.Context, *pkg.VatReportReverseCharge) error
+
+	// GetByVatReportId returns the reverse charge summary of the VAT report.
+	GetByVatReportId(context.Context, string) (*pkg.VatReportReverseCharge, error)
+}
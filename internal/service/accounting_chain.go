@@ -0,0 +1,542 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/internal/repository"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"strconv"
+	"time"
+)
+
+const (
+	accountingChainInsertAttempts = 5
+	accountingChainVerifyBatch    = 1000
+	accountingChainTimeFormat     = "2006-01-02T15:04:05.000Z"
+)
+
+var (
+	accountingChainErrorOperatingCompanyNotFound = newBillingServerErrorMsg("ch000001", "operating company not found")
+)
+
+// insertAccountingEntries saves the new accounting entries and appends them to the hash chain of the operating company.
+// All accounting entries must be saved using this method, the stored entries are never changed.
+func (s *Service) insertAccountingEntries(ctx context.Context, entries []*billingpb.AccountingEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	for _, entry := range entries {
+		if entry.Id == "" {
+			entry.Id = primitive.NewObjectID().Hex()
+		}
+
+		if entry.CreatedAt == nil {
+			entry.CreatedAt = ptypes.TimestampNow()
+		}
+	}
+
+	// the entries, the changes of the balance ledger and the chain links are saved in one transaction,
+	// so the entry is never saved without the link
+	return repository.WithTransaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.accountingRepository.MultipleInsert(ctx, entries); err != nil {
+			return err
+		}
+
+		if err := s.applyAccountingEntriesToBalanceLedger(ctx, entries); err != nil {
+			return err
+		}
+
+		return s.appendAccountingChainLinks(ctx, entries)
+	})
+}
+
+// appendAccountingChainLinks adds the links of the entries to the end of the operating company chains.
+// When the link with the same sequence was added by another process the link is recalculated from the new last link.
+// Inside the transaction the duplicate link aborts the transaction, so the whole transaction is retried by the driver.
+func (s *Service) appendAccountingChainLinks(ctx context.Context, entries []*billingpb.AccountingEntry) error {
+	lastLinks := make(map[string]*intPkg.AccountingChainLink)
+
+	for _, entry := range entries {
+		for attempt := 1; ; attempt++ {
+			last, ok := lastLinks[entry.OperatingCompanyId]
+
+			if !ok {
+				var err error
+				last, err = s.accountingChainRepository.GetLast(ctx, entry.OperatingCompanyId)
+
+				if err != nil && err != mongo.ErrNoDocuments {
+					return err
+				}
+			}
+
+			link := newAccountingChainLink(last, entry)
+			err := s.accountingChainRepository.Insert(ctx, link)
+
+			if err == nil {
+				lastLinks[entry.OperatingCompanyId] = link
+				break
+			}
+
+			delete(lastLinks, entry.OperatingCompanyId)
+
+			if !repository.IsDuplicateKeyError(err) || attempt >= accountingChainInsertAttempts {
+				zap.L().Error(
+					"Accounting entry not added to the chain",
+					zap.Error(err),
+					zap.String("entry_id", entry.Id),
+					zap.String("operating_company_id", entry.OperatingCompanyId),
+				)
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// reverseAccountingEntries saves the changes of the stored accounting entries. The stored entry isn't updated,
+// the reversal with the negated amounts of the stored entry and the replacement with the changed data are written
+// instead. The entries dated inside the closed accounting period are reversed in the current open period.
+func (s *Service) reverseAccountingEntries(ctx context.Context, list []*billingpb.AccountingEntry) error {
+	lock := s.newAccountingPeriodLock(ctx)
+	ids := make([]string, len(list))
+
+	for i, entry := range list {
+		ids[i] = entry.Id
+	}
+
+	reversed, err := s.getReversedAccountingEntryIds(ctx, ids)
+
+	if err != nil {
+		return err
+	}
+
+	var entries []*billingpb.AccountingEntry
+	var reversals []*intPkg.AccountingEntryReversal
+
+	for _, entry := range list {
+		original, err := s.accountingRepository.GetById(ctx, entry.Id)
+
+		if err != nil {
+			return err
+		}
+
+		if accountingChainHash("", entry) == accountingChainHash("", original) {
+			continue
+		}
+
+		if reversed[original.Id] {
+			zap.L().Warn(
+				"Accounting entry already reversed, change skipped",
+				zap.String("entry_id", original.Id),
+			)
+			continue
+		}
+
+		period, err := lock.getClosedPeriod(original)
+
+		if err != nil {
+			return err
+		}
+
+		reversal := protobuf.Clone(original).(*billingpb.AccountingEntry)
+		reversal.Id = primitive.NewObjectID().Hex()
+		reversal.Amount = -original.Amount
+		reversal.OriginalAmount = -original.OriginalAmount
+		reversal.LocalAmount = -original.LocalAmount
+		reversal.Reason = getAccountingReversalReason(intPkg.AccountingReversalReason, original)
+
+		replacement := protobuf.Clone(entry).(*billingpb.AccountingEntry)
+		replacement.Id = primitive.NewObjectID().Hex()
+		replacement.CreatedAt = original.CreatedAt
+		replacement.Reason = getAccountingReversalReason(intPkg.AccountingReplacementReason, entry)
+
+		if period != nil {
+			lock.moveToOpenPeriod(reversal, period)
+			lock.moveToOpenPeriod(replacement, period)
+		}
+
+		entries = append(entries, reversal, replacement)
+		reversals = append(reversals, &intPkg.AccountingEntryReversal{
+			Id:                 primitive.NewObjectID(),
+			EntryId:            original.Id,
+			ReversalEntryId:    reversal.Id,
+			ReplacementEntryId: replacement.Id,
+			CreatedAt:          time.Now(),
+		})
+		reversed[original.Id] = true
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	// the reversal is saved with the entries, the duplicate reversal of the entry rolls back the entries
+	return repository.WithTransaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.insertAccountingEntries(ctx, entries); err != nil {
+			return err
+		}
+
+		for _, reversal := range reversals {
+			if err := s.accountingEntryReversalRepository.Insert(ctx, reversal); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// getReversedAccountingEntryIds returns the ids of the accounting entries in the list which are reversed
+// or are the reversals of another entries.
+func (s *Service) getReversedAccountingEntryIds(ctx context.Context, ids []string) (map[string]bool, error) {
+	reversed := make(map[string]bool)
+
+	for start := 0; start < len(ids); start += accountingChainVerifyBatch {
+		end := start + accountingChainVerifyBatch
+
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		reversals, err := s.accountingEntryReversalRepository.FindByEntryIds(ctx, ids[start:end])
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, reversal := range reversals {
+			reversed[reversal.EntryId] = true
+			reversed[reversal.ReversalEntryId] = true
+		}
+	}
+
+	return reversed, nil
+}
+
+// getActiveAccountingEntries returns the entries excluding the reversals and the reversed entries,
+// so only the current state of the entries is processed.
+func (s *Service) getActiveAccountingEntries(
+	ctx context.Context,
+	list []*billingpb.AccountingEntry,
+) ([]*billingpb.AccountingEntry, error) {
+	ids := make([]string, len(list))
+
+	for i, entry := range list {
+		ids[i] = entry.Id
+	}
+
+	reversed, err := s.getReversedAccountingEntryIds(ctx, ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(reversed) == 0 {
+		return list, nil
+	}
+
+	var active []*billingpb.AccountingEntry
+
+	for _, entry := range list {
+		if !reversed[entry.Id] {
+			active = append(active, entry)
+		}
+	}
+
+	return active, nil
+}
+
+func getAccountingReversalReason(prefix string, entry *billingpb.AccountingEntry) string {
+	reason := prefix + " of " + entry.Id
+
+	if entry.Reason != "" {
+		reason += ": " + entry.Reason
+	}
+
+	return reason
+}
+
+// VerifyAccountingChain recomputes the hash chain of the operating company accounting entries
+// and returns the first broken link of the chain.
+func (s *Service) VerifyAccountingChain(
+	ctx context.Context,
+	req *intPkg.VerifyAccountingChainRequest,
+	res *intPkg.VerifyAccountingChainResponse,
+) error {
+	if _, err := s.operatingCompanyRepository.GetById(ctx, req.OperatingCompanyId); err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = accountingChainErrorOperatingCompanyNotFound
+		return nil
+	}
+
+	report, err := s.verifyAccountingChain(ctx, req.OperatingCompanyId)
+
+	if err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = report
+
+	return nil
+}
+
+// VerifyAccountingChains verifies the hash chains of all operating companies.
+func (s *Service) VerifyAccountingChains(ctx context.Context) ([]*intPkg.AccountingChainReport, error) {
+	operatingCompanies, err := s.operatingCompanyRepository.GetAll(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []*intPkg.AccountingChainReport
+
+	for _, oc := range operatingCompanies {
+		report, err := s.verifyAccountingChain(ctx, oc.Id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func (s *Service) verifyAccountingChain(ctx context.Context, operatingCompanyId string) (*intPkg.AccountingChainReport, error) {
+	report := &intPkg.AccountingChainReport{
+		OperatingCompanyId: operatingCompanyId,
+		CreatedAt:          time.Now(),
+	}
+	sequence := int64(1)
+	previousHash := ""
+	linked := make(map[string]bool)
+
+	for {
+		links, err := s.accountingChainRepository.FindByOperatingCompany(
+			ctx,
+			operatingCompanyId,
+			sequence,
+			accountingChainVerifyBatch,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(links) == 0 {
+			break
+		}
+
+		ids := make([]string, len(links))
+
+		for i, link := range links {
+			ids[i] = link.EntryId
+			linked[link.EntryId] = true
+		}
+
+		list, err := s.accountingRepository.FindByIds(ctx, ids)
+
+		if err != nil {
+			return nil, err
+		}
+
+		entries := make(map[string]*billingpb.AccountingEntry, len(list))
+
+		for _, entry := range list {
+			entries[entry.Id] = entry
+		}
+
+		for _, link := range links {
+			report.BrokenLink = verifyAccountingChainLink(link, sequence, previousHash, entries[link.EntryId])
+
+			if report.BrokenLink != nil {
+				return report, nil
+			}
+
+			report.CheckedLinks++
+			sequence++
+			previousHash = link.Hash
+		}
+	}
+
+	// the entries saved without the links aren't protected by the chain
+	brokenLink, err := s.findAccountingEntryNotLinked(ctx, operatingCompanyId, linked)
+
+	if err != nil {
+		return nil, err
+	}
+
+	report.BrokenLink = brokenLink
+
+	return report, nil
+}
+
+// findAccountingEntryNotLinked returns the first accounting entry of the operating company missing in the chain.
+// The entries saved after the chain was read are checked by their own links.
+func (s *Service) findAccountingEntryNotLinked(
+	ctx context.Context,
+	operatingCompanyId string,
+	linked map[string]bool,
+) (*intPkg.AccountingChainBrokenLink, error) {
+	afterId := ""
+
+	for {
+		ids, err := s.accountingRepository.FindIdsByOperatingCompany(
+			ctx,
+			operatingCompanyId,
+			afterId,
+			accountingChainVerifyBatch,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(ids) == 0 {
+			return nil, nil
+		}
+
+		for _, id := range ids {
+			if linked[id] {
+				continue
+			}
+
+			_, err = s.accountingChainRepository.GetByEntryId(ctx, id)
+
+			if err == nil {
+				continue
+			}
+
+			if err != mongo.ErrNoDocuments {
+				return nil, err
+			}
+
+			brokenLink := &intPkg.AccountingChainBrokenLink{
+				EntryId: id,
+				Reason:  intPkg.AccountingChainBrokenLinkEntryNotLinked,
+			}
+			return brokenLink, nil
+		}
+
+		afterId = ids[len(ids)-1]
+	}
+}
+
+func verifyAccountingChainLink(
+	link *intPkg.AccountingChainLink,
+	sequence int64,
+	previousHash string,
+	entry *billingpb.AccountingEntry,
+) *intPkg.AccountingChainBrokenLink {
+	brokenLink := &intPkg.AccountingChainBrokenLink{
+		Sequence: link.Sequence,
+		EntryId:  link.EntryId,
+	}
+
+	if link.Sequence != sequence {
+		brokenLink.Reason = intPkg.AccountingChainBrokenLinkSequenceGap
+		return brokenLink
+	}
+
+	if link.PreviousHash != previousHash {
+		brokenLink.Reason = intPkg.AccountingChainBrokenLinkPreviousHashMismatch
+		brokenLink.ExpectedHash = previousHash
+		brokenLink.ActualHash = link.PreviousHash
+		return brokenLink
+	}
+
+	if entry == nil {
+		brokenLink.Reason = intPkg.AccountingChainBrokenLinkEntryNotFound
+		brokenLink.ExpectedHash = link.Hash
+		return brokenLink
+	}
+
+	if hash := accountingChainHash(link.PreviousHash, entry); hash != link.Hash {
+		brokenLink.Reason = intPkg.AccountingChainBrokenLinkHashMismatch
+		brokenLink.ExpectedHash = link.Hash
+		brokenLink.ActualHash = hash
+		return brokenLink
+	}
+
+	return nil
+}
+
+func newAccountingChainLink(last *intPkg.AccountingChainLink, entry *billingpb.AccountingEntry) *intPkg.AccountingChainLink {
+	link := &intPkg.AccountingChainLink{
+		Id:                 primitive.NewObjectID(),
+		OperatingCompanyId: entry.OperatingCompanyId,
+		Sequence:           1,
+		EntryId:            entry.Id,
+		CreatedAt:          time.Now(),
+	}
+
+	if last != nil {
+		link.Sequence = last.Sequence + 1
+		link.PreviousHash = last.Hash
+	}
+
+	link.Hash = accountingChainHash(link.PreviousHash, entry)
+
+	return link
+}
+
+// accountingChainHash returns the SHA-256 hash of the previous link hash and the stored fields of the entry.
+// The dates are truncated to milliseconds as they are stored in the database.
+func accountingChainHash(previousHash string, entry *billingpb.AccountingEntry) string {
+	fields := []string{
+		previousHash,
+		entry.Id,
+		entry.Object,
+		entry.Type,
+		entry.GetSource().GetType(),
+		entry.GetSource().GetId(),
+		entry.MerchantId,
+		strconv.FormatFloat(entry.Amount, 'f', -1, 64),
+		entry.Currency,
+		strconv.FormatFloat(entry.OriginalAmount, 'f', -1, 64),
+		entry.OriginalCurrency,
+		strconv.FormatFloat(entry.LocalAmount, 'f', -1, 64),
+		entry.LocalCurrency,
+		entry.Reason,
+		entry.Status,
+		entry.Country,
+		entry.OperatingCompanyId,
+		formatAccountingChainTime(entry.CreatedAt),
+		formatAccountingChainTime(entry.AvailableOn),
+	}
+
+	// the fields are encoded as JSON array to avoid ambiguity of the separators inside the values
+	data, _ := json.Marshal(fields)
+	hash := sha256.Sum256(data)
+
+	return hex.EncodeToString(hash[:])
+}
+
+func formatAccountingChainTime(ts *timestamp.Timestamp) string {
+	t := time.Time{}
+
+	if ts != nil {
+		var err error
+		t, err = ptypes.Timestamp(ts)
+
+		if err != nil {
+			return fmt.Sprintf("%d.%d", ts.Seconds, ts.Nanos)
+		}
+	}
+
+	return t.UTC().Format(accountingChainTimeFormat)
+}
@@ -0,0 +1,462 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/paysuper/paysuper-billing-server/internal/helper"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const (
+	accountingCorrectionAppliedNotificationMessage = "Correction of %s %s was applied to your account and will be included into the next royalty report. Reason: %s"
+)
+
+var (
+	accountingCorrectionErrorMerchantNotFound   = newBillingServerErrorMsg("ac000001", "merchant not found")
+	accountingCorrectionErrorAmountZero         = newBillingServerErrorMsg("ac000002", "correction amount can't be zero")
+	accountingCorrectionErrorCurrencyNotSupport = newBillingServerErrorMsg("ac000003", "correction currency is not supported")
+	accountingCorrectionErrorReasonEmpty        = newBillingServerErrorMsg("ac000004", "correction reason can't be empty")
+	accountingCorrectionErrorTypeNotAllowed     = newBillingServerErrorMsg("ac000005", "only merchant royalty correction can be created manually, other accounting entries are written by the payment events")
+	accountingCorrectionErrorNotFound           = newBillingServerErrorMsg("ac000006", "correction not found")
+	accountingCorrectionErrorStatusInvalid      = newBillingServerErrorMsg("ac000007", "action is not allowed for the correction in the current status")
+	accountingCorrectionErrorAccessDenied       = newBillingServerErrorMsg("ac000008", "user is not allowed to perform this action")
+	accountingCorrectionErrorSameReviewer       = newBillingServerErrorMsg("ac000009", "correction can't be reviewed by its creator")
+
+	// accountingCorrectionAllowedTypes are the types of the entries written by the manual correction. The other
+	// entries are derived from the orders and refunds and are changed only by the reversal of the source entries.
+	accountingCorrectionAllowedTypes = map[string]bool{
+		pkg.AccountingEntryTypeMerchantRoyaltyCorrection: true,
+	}
+
+	accountingCorrectionReviewerRoles = []string{
+		billingpb.RoleSystemAdmin,
+		billingpb.RoleSystemFinancial,
+	}
+)
+
+// CreateAccountingCorrection creates the request for the manual correction of the merchant balance.
+// The accounting entry isn't written until the correction is approved by the financier.
+func (s *Service) CreateAccountingCorrection(
+	ctx context.Context,
+	req *intPkg.CreateAccountingCorrectionRequest,
+	res *intPkg.AccountingCorrectionResponse,
+) error {
+	if !s.hasAdminRole(ctx, req.UserId) {
+		res.Status = billingpb.ResponseStatusForbidden
+		res.Message = accountingCorrectionErrorAccessDenied
+		return nil
+	}
+
+	merchant, err := s.merchantRepository.GetById(ctx, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = accountingCorrectionErrorMerchantNotFound
+		return nil
+	}
+
+	if req.Type == "" {
+		req.Type = pkg.AccountingEntryTypeMerchantRoyaltyCorrection
+	}
+
+	if _, ok := accountingCorrectionAllowedTypes[req.Type]; !ok {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = accountingCorrectionErrorTypeNotAllowed
+		return nil
+	}
+
+	if req.Amount == 0 {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = accountingCorrectionErrorAmountZero
+		return nil
+	}
+
+	if !helper.Contains(s.supportedCurrencies, req.Currency) {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = accountingCorrectionErrorCurrencyNotSupport
+		return nil
+	}
+
+	if strings.TrimSpace(req.Reason) == "" {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = accountingCorrectionErrorReasonEmpty
+		return nil
+	}
+
+	merchantOid, _ := primitive.ObjectIDFromHex(merchant.Id)
+	now := time.Now()
+	correction := &intPkg.AccountingCorrection{
+		Id:          primitive.NewObjectID(),
+		MerchantId:  merchantOid,
+		Type:        req.Type,
+		Amount:      s.newMoney(req.Amount, req.Currency).Round().Float64(),
+		Currency:    req.Currency,
+		Reason:      req.Reason,
+		Attachments: req.Attachments,
+		Status:      intPkg.AccountingCorrectionStatusPending,
+		CreatedBy:   req.UserId,
+		History: []*intPkg.AccountingCorrectionHistoryItem{
+			{Action: intPkg.AccountingCorrectionActionCreate, UserId: req.UserId, CreatedAt: now},
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if correction.Attachments == nil {
+		correction.Attachments = []*intPkg.AccountingCorrectionAttachment{}
+	}
+
+	if err = s.accountingCorrectionRepository.Insert(ctx, correction); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = correction
+
+	return nil
+}
+
+// ApproveAccountingCorrection approves the pending correction, writes the correction accounting entry
+// and notifies the merchant about the change of the balance. The correction is moved to the approved status
+// before the entry is written, so the concurrent review can't approve or reject it again. The entry has the id
+// of the correction and is written only once, the approval of the correction which entry wasn't written
+// because of the failure is repeated to write it.
+func (s *Service) ApproveAccountingCorrection(
+	ctx context.Context,
+	req *intPkg.ReviewAccountingCorrectionRequest,
+	res *intPkg.AccountingCorrectionResponse,
+) error {
+	correction, msg := s.getAccountingCorrectionForReview(
+		ctx,
+		req,
+		intPkg.AccountingCorrectionStatusPending,
+		intPkg.AccountingCorrectionStatusApproved,
+	)
+
+	if msg != nil {
+		res.Status = msg.status
+		res.Message = msg.message
+		return nil
+	}
+
+	if correction.Status == intPkg.AccountingCorrectionStatusPending {
+		now := time.Now()
+		correction.Status = intPkg.AccountingCorrectionStatusApproved
+		correction.ReviewedBy = req.UserId
+		correction.ReviewedAt = now
+		correction.UpdatedAt = now
+		correction.History = append(correction.History, &intPkg.AccountingCorrectionHistoryItem{
+			Action:    intPkg.AccountingCorrectionActionApprove,
+			UserId:    req.UserId,
+			Comment:   req.Comment,
+			CreatedAt: now,
+		})
+
+		err := s.accountingCorrectionRepository.UpdateByStatus(ctx, correction, intPkg.AccountingCorrectionStatusPending)
+
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				res.Status = billingpb.ResponseStatusBadData
+				res.Message = accountingCorrectionErrorStatusInvalid
+				return nil
+			}
+
+			return err
+		}
+	}
+
+	entryRsp := &billingpb.CreateAccountingEntryResponse{}
+	err := s.createAccountingCorrectionEntry(ctx, correction, time.Now(), entryRsp)
+
+	if err != nil {
+		return err
+	}
+
+	if entryRsp.Status != billingpb.ResponseStatusOk {
+		res.Status = entryRsp.Status
+		res.Message = entryRsp.Message
+		return nil
+	}
+
+	correction.Status = intPkg.AccountingCorrectionStatusApplied
+	correction.AccountingEntryId = entryRsp.Item.Id
+	correction.UpdatedAt = time.Now()
+
+	err = s.accountingCorrectionRepository.UpdateByStatus(ctx, correction, intPkg.AccountingCorrectionStatusApproved)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = accountingCorrectionErrorStatusInvalid
+			return nil
+		}
+
+		return err
+	}
+
+	message := fmt.Sprintf(
+		accountingCorrectionAppliedNotificationMessage,
+		s.newMoney(correction.Amount, correction.Currency).String(),
+		correction.Currency,
+		correction.Reason,
+	)
+	_, err = s.addNotification(ctx, message, correction.MerchantId.Hex(), "", nil)
+
+	if err != nil {
+		zap.L().Error(
+			"Notification about applied accounting correction failed",
+			zap.Error(err),
+			zap.String("correction_id", correction.Id.Hex()),
+		)
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = correction
+
+	return nil
+}
+
+// RejectAccountingCorrection rejects the pending correction without writing any accounting entries.
+func (s *Service) RejectAccountingCorrection(
+	ctx context.Context,
+	req *intPkg.ReviewAccountingCorrectionRequest,
+	res *intPkg.AccountingCorrectionResponse,
+) error {
+	correction, msg := s.getAccountingCorrectionForReview(ctx, req, intPkg.AccountingCorrectionStatusPending)
+
+	if msg != nil {
+		res.Status = msg.status
+		res.Message = msg.message
+		return nil
+	}
+
+	now := time.Now()
+	correction.Status = intPkg.AccountingCorrectionStatusRejected
+	correction.ReviewedBy = req.UserId
+	correction.ReviewedAt = now
+	correction.UpdatedAt = now
+	correction.History = append(correction.History, &intPkg.AccountingCorrectionHistoryItem{
+		Action:    intPkg.AccountingCorrectionActionReject,
+		UserId:    req.UserId,
+		Comment:   req.Comment,
+		CreatedAt: now,
+	})
+
+	err := s.accountingCorrectionRepository.UpdateByStatus(ctx, correction, intPkg.AccountingCorrectionStatusPending)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = accountingCorrectionErrorStatusInvalid
+			return nil
+		}
+
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = correction
+
+	return nil
+}
+
+// ListAccountingCorrections returns the corrections of the merchant filtered by status.
+func (s *Service) ListAccountingCorrections(
+	ctx context.Context,
+	req *intPkg.ListAccountingCorrectionsRequest,
+	res *intPkg.ListAccountingCorrectionsResponse,
+) error {
+	count, err := s.accountingCorrectionRepository.FindCount(ctx, req.MerchantId, req.Status)
+
+	if err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Count = count
+	res.Items = []*intPkg.AccountingCorrection{}
+
+	if count <= 0 {
+		return nil
+	}
+
+	res.Items, err = s.accountingCorrectionRepository.Find(ctx, req.MerchantId, req.Status, req.Limit, req.Offset)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// createApprovedAccountingCorrection writes the correction initiated by the other workflow, for example
+// the resolution of the royalty report dispute or the debt repayment, on behalf of the financier approved it
+// in that workflow. The correction is stored in the applied status with the approver, so it's kept in the audit
+// trail the same way as the reviewed corrections.
+func (s *Service) createApprovedAccountingCorrection(
+	ctx context.Context,
+	merchantId, currency, reason string,
+	amount float64,
+	date time.Time,
+	createdBy, approvedBy string,
+) (*intPkg.AccountingCorrection, error) {
+	if approvedBy == "" {
+		return nil, accountingCorrectionErrorAccessDenied
+	}
+
+	merchantOid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		return nil, accountingCorrectionErrorMerchantNotFound
+	}
+
+	now := time.Now()
+	correction := &intPkg.AccountingCorrection{
+		Id:          primitive.NewObjectID(),
+		MerchantId:  merchantOid,
+		Type:        pkg.AccountingEntryTypeMerchantRoyaltyCorrection,
+		Amount:      amount,
+		Currency:    currency,
+		Reason:      reason,
+		Attachments: []*intPkg.AccountingCorrectionAttachment{},
+		Status:      intPkg.AccountingCorrectionStatusApplied,
+		CreatedBy:   createdBy,
+		ReviewedBy:  approvedBy,
+		ReviewedAt:  now,
+		History: []*intPkg.AccountingCorrectionHistoryItem{
+			{Action: intPkg.AccountingCorrectionActionCreate, UserId: createdBy, CreatedAt: now},
+			{Action: intPkg.AccountingCorrectionActionApprove, UserId: approvedBy, CreatedAt: now},
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	entryRsp := &billingpb.CreateAccountingEntryResponse{}
+
+	if err = s.createAccountingCorrectionEntry(ctx, correction, date, entryRsp); err != nil {
+		return nil, err
+	}
+
+	if entryRsp.Status != billingpb.ResponseStatusOk {
+		return nil, entryRsp.Message
+	}
+
+	correction.Amount = entryRsp.Item.Amount
+	correction.AccountingEntryId = entryRsp.Item.Id
+
+	if err = s.accountingCorrectionRepository.Insert(ctx, correction); err != nil {
+		return nil, err
+	}
+
+	return correction, nil
+}
+
+// createAccountingCorrectionEntry writes the accounting entry of the correction. The entry has the id
+// of the correction, so the entry which is already written for the correction is returned instead of the new one.
+func (s *Service) createAccountingCorrectionEntry(
+	ctx context.Context,
+	correction *intPkg.AccountingCorrection,
+	date time.Time,
+	rsp *billingpb.CreateAccountingEntryResponse,
+) error {
+	entry, err := s.accountingRepository.GetById(ctx, correction.Id.Hex())
+
+	if err == nil {
+		rsp.Status = billingpb.ResponseStatusOk
+		rsp.Item = entry
+		return nil
+	}
+
+	if err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	req := &billingpb.CreateAccountingEntryRequest{
+		Type:       correction.Type,
+		MerchantId: correction.MerchantId.Hex(),
+		Amount:     correction.Amount,
+		Currency:   correction.Currency,
+		Reason:     correction.Reason,
+		Date:       date.Unix(),
+		Status:     pkg.BalanceTransactionStatusAvailable,
+	}
+
+	if err = s.createAccountingEntryWithId(ctx, req, rsp, correction.Id.Hex()); err != nil {
+		return err
+	}
+
+	if rsp.Status == billingpb.ResponseStatusOk {
+		return nil
+	}
+
+	// the entry of the correction could be written concurrently, so the insert of the same entry failed
+	if entry, err = s.accountingRepository.GetById(ctx, correction.Id.Hex()); err == nil {
+		rsp.Status = billingpb.ResponseStatusOk
+		rsp.Message = nil
+		rsp.Item = entry
+	}
+
+	return nil
+}
+
+type accountingCorrectionReviewError struct {
+	status  int32
+	message *billingpb.ResponseErrorMessage
+}
+
+// getAccountingCorrectionForReview checks that the user can review the correction in the current status.
+func (s *Service) getAccountingCorrectionForReview(
+	ctx context.Context,
+	req *intPkg.ReviewAccountingCorrectionRequest,
+	statuses ...string,
+) (*intPkg.AccountingCorrection, *accountingCorrectionReviewError) {
+	if !s.hasAdminRole(ctx, req.UserId, accountingCorrectionReviewerRoles...) {
+		return nil, &accountingCorrectionReviewError{billingpb.ResponseStatusForbidden, accountingCorrectionErrorAccessDenied}
+	}
+
+	correction, err := s.accountingCorrectionRepository.GetById(ctx, req.Id)
+
+	if err != nil {
+		return nil, &accountingCorrectionReviewError{billingpb.ResponseStatusNotFound, accountingCorrectionErrorNotFound}
+	}
+
+	if !helper.Contains(statuses, correction.Status) {
+		return nil, &accountingCorrectionReviewError{billingpb.ResponseStatusBadData, accountingCorrectionErrorStatusInvalid}
+	}
+
+	if !accountingCorrectionAllowedTypes[correction.Type] {
+		return nil, &accountingCorrectionReviewError{billingpb.ResponseStatusBadData, accountingCorrectionErrorTypeNotAllowed}
+	}
+
+	if correction.CreatedBy == req.UserId {
+		return nil, &accountingCorrectionReviewError{billingpb.ResponseStatusForbidden, accountingCorrectionErrorSameReviewer}
+	}
+
+	return correction, nil
+}
+
+// hasAdminRole checks that the user is the admin user with one of the passed roles.
+// Any admin role is accepted if the roles aren't passed.
+func (s *Service) hasAdminRole(ctx context.Context, userId string, roles ...string) bool {
+	if userId == "" {
+		return false
+	}
+
+	role, err := s.userRoleRepository.GetAdminUserByUserId(ctx, userId)
+
+	if err != nil || role == nil {
+		return false
+	}
+
+	if len(roles) == 0 {
+		return true
+	}
+
+	return helper.Contains(roles, role.Role)
+}
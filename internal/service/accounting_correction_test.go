@@ -0,0 +1,496 @@
+package service
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/mongodb"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/internal/repository"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	mock2 "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	rabbitmq "gopkg.in/ProtocolONE/rabbitmq.v1/pkg"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"sync"
+	"testing"
+	"time"
+)
+
+type AccountingCorrectionTestSuite struct {
+	suite.Suite
+	service    *Service
+	cache      database.CacheInterface
+	centrifugo *mocks.CentrifugoInterface
+
+	merchant  *billingpb.Merchant
+	support   *billingpb.UserRole
+	financier *billingpb.UserRole
+}
+
+func Test_AccountingCorrection(t *testing.T) {
+	suite.Run(t, new(AccountingCorrectionTestSuite))
+}
+
+func (suite *AccountingCorrectionTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New(
+		"file://../../migrations/tests",
+		cfg.MongoDsn)
+	assert.NoError(suite.T(), err, "Migrate init failed")
+
+	err = m.Up()
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	broker, err := rabbitmq.NewBroker(cfg.BrokerAddress)
+
+	if err != nil {
+		suite.FailNow("Creating RabbitMQ publisher failed", "%v", err)
+	}
+
+	redisClient := database.NewRedis(
+		&redis.Options{
+			Addr:     cfg.RedisHost,
+			Password: cfg.RedisPassword,
+		},
+	)
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		broker,
+		redisClient,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	suite.centrifugo = &mocks.CentrifugoInterface{}
+	suite.centrifugo.On("Publish", mock2.Anything, mock2.Anything, mock2.Anything).Return(nil)
+	suite.service.centrifugoDashboard = suite.centrifugo
+
+	suite.merchant, _, _, _ = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+
+	suite.support = &billingpb.UserRole{
+		Id:     primitive.NewObjectID().Hex(),
+		UserId: primitive.NewObjectID().Hex(),
+		Role:   billingpb.RoleSystemSupport,
+	}
+	suite.financier = &billingpb.UserRole{
+		Id:     primitive.NewObjectID().Hex(),
+		UserId: primitive.NewObjectID().Hex(),
+		Role:   billingpb.RoleSystemFinancial,
+	}
+
+	for _, role := range []*billingpb.UserRole{suite.support, suite.financier} {
+		if err := suite.service.userRoleRepository.AddAdminUser(context.TODO(), role); err != nil {
+			suite.FailNow("Insert admin user failed", "%v", err)
+		}
+	}
+}
+
+func (suite *AccountingCorrectionTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *AccountingCorrectionTestSuite) createCorrection(amount float64) *intPkg.AccountingCorrection {
+	req := &intPkg.CreateAccountingCorrectionRequest{
+		MerchantId: suite.merchant.Id,
+		UserId:     suite.support.UserId,
+		Amount:     amount,
+		Currency:   "RUB",
+		Reason:     "compensation of the chargeback fee",
+		Attachments: []*intPkg.AccountingCorrectionAttachment{
+			{Name: "act.pdf", Url: "https://storage.local/act.pdf", MimeType: "application/pdf", Size: 1024},
+		},
+	}
+	res := &intPkg.AccountingCorrectionResponse{}
+	err := suite.service.CreateAccountingCorrection(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+
+	return res.Item
+}
+
+func (suite *AccountingCorrectionTestSuite) TestAccountingCorrection_Create_Ok() {
+	correction := suite.createCorrection(100.555)
+	assert.Equal(suite.T(), intPkg.AccountingCorrectionStatusPending, correction.Status)
+	assert.Equal(suite.T(), pkg.AccountingEntryTypeMerchantRoyaltyCorrection, correction.Type)
+	assert.Equal(suite.T(), 100.56, correction.Amount)
+	assert.Equal(suite.T(), suite.support.UserId, correction.CreatedBy)
+	assert.Empty(suite.T(), correction.AccountingEntryId)
+	assert.Len(suite.T(), correction.Attachments, 1)
+	assert.Len(suite.T(), correction.History, 1)
+	assert.Equal(suite.T(), intPkg.AccountingCorrectionActionCreate, correction.History[0].Action)
+
+	suite.centrifugo.AssertNotCalled(suite.T(), "Publish", mock2.Anything, mock2.Anything, mock2.Anything)
+}
+
+func (suite *AccountingCorrectionTestSuite) TestAccountingCorrection_Create_ValidationError() {
+	req := &intPkg.CreateAccountingCorrectionRequest{
+		MerchantId: suite.merchant.Id,
+		UserId:     primitive.NewObjectID().Hex(),
+		Amount:     100,
+		Currency:   "RUB",
+		Reason:     "reason",
+	}
+	res := &intPkg.AccountingCorrectionResponse{}
+	err := suite.service.CreateAccountingCorrection(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+	assert.Equal(suite.T(), accountingCorrectionErrorAccessDenied, res.Message)
+
+	req.UserId = suite.support.UserId
+	req.Amount = 0
+	res = &intPkg.AccountingCorrectionResponse{}
+	err = suite.service.CreateAccountingCorrection(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), accountingCorrectionErrorAmountZero, res.Message)
+
+	req.Amount = 100
+	req.Currency = "XXX"
+	res = &intPkg.AccountingCorrectionResponse{}
+	err = suite.service.CreateAccountingCorrection(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), accountingCorrectionErrorCurrencyNotSupport, res.Message)
+
+	req.Currency = "RUB"
+	req.Reason = " "
+	res = &intPkg.AccountingCorrectionResponse{}
+	err = suite.service.CreateAccountingCorrection(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), accountingCorrectionErrorReasonEmpty, res.Message)
+
+	req.Reason = "reason"
+	req.Type = pkg.AccountingEntryTypeRealGrossRevenue
+	res = &intPkg.AccountingCorrectionResponse{}
+	err = suite.service.CreateAccountingCorrection(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), accountingCorrectionErrorTypeNotAllowed, res.Message)
+}
+
+func (suite *AccountingCorrectionTestSuite) TestAccountingCorrection_Approve_Ok() {
+	correction := suite.createCorrection(-50)
+
+	req := &intPkg.ReviewAccountingCorrectionRequest{Id: correction.Id.Hex(), UserId: suite.financier.UserId, Comment: "ok"}
+	res := &intPkg.AccountingCorrectionResponse{}
+	err := suite.service.ApproveAccountingCorrection(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Equal(suite.T(), intPkg.AccountingCorrectionStatusApplied, res.Item.Status)
+	assert.Equal(suite.T(), suite.financier.UserId, res.Item.ReviewedBy)
+	assert.NotEmpty(suite.T(), res.Item.AccountingEntryId)
+	assert.Len(suite.T(), res.Item.History, 2)
+	assert.Equal(suite.T(), intPkg.AccountingCorrectionActionApprove, res.Item.History[1].Action)
+
+	assert.Equal(suite.T(), correction.Id.Hex(), res.Item.AccountingEntryId)
+
+	entry, err := suite.service.accountingRepository.GetById(context.TODO(), res.Item.AccountingEntryId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.AccountingEntryTypeMerchantRoyaltyCorrection, entry.Type)
+	assert.Equal(suite.T(), suite.merchant.Id, entry.MerchantId)
+	assert.Equal(suite.T(), float64(-50), entry.Amount)
+	assert.Equal(suite.T(), correction.Reason, entry.Reason)
+
+	notifications, err := suite.service.notificationRepository.Find(context.TODO(), suite.merchant.Id, "", 2, []string{}, 0, 10)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), notifications, 1)
+	suite.centrifugo.AssertCalled(suite.T(), "Publish", mock2.Anything, mock2.Anything, mock2.Anything)
+
+	res = &intPkg.AccountingCorrectionResponse{}
+	err = suite.service.ApproveAccountingCorrection(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), accountingCorrectionErrorStatusInvalid, res.Message)
+}
+
+func (suite *AccountingCorrectionTestSuite) TestAccountingCorrection_Approve_Concurrent_AppliedOnce() {
+	correction := suite.createCorrection(75)
+
+	count := 5
+	results := make(chan *intPkg.AccountingCorrectionResponse, count)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			req := &intPkg.ReviewAccountingCorrectionRequest{Id: correction.Id.Hex(), UserId: suite.financier.UserId}
+			res := &intPkg.AccountingCorrectionResponse{}
+			err := suite.service.ApproveAccountingCorrection(context.TODO(), req, res)
+			assert.NoError(suite.T(), err)
+			results <- res
+		}()
+	}
+
+	wg.Wait()
+	close(results)
+
+	applied := 0
+
+	for res := range results {
+		if res.Status == billingpb.ResponseStatusOk {
+			applied++
+			continue
+		}
+
+		assert.Equal(suite.T(), accountingCorrectionErrorStatusInvalid, res.Message)
+	}
+
+	assert.Equal(suite.T(), 1, applied)
+
+	entries, err := suite.service.accountingRepository.FindBySource(context.TODO(), suite.merchant.Id, repository.CollectionMerchant)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), entries, 1)
+	assert.Equal(suite.T(), correction.Id.Hex(), entries[0].Id)
+
+	stored, err := suite.service.accountingCorrectionRepository.GetById(context.TODO(), correction.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), intPkg.AccountingCorrectionStatusApplied, stored.Status)
+	assert.Len(suite.T(), stored.History, 2)
+}
+
+func (suite *AccountingCorrectionTestSuite) TestAccountingCorrection_Approve_Repeated_EntryWrittenOnce() {
+	correction := suite.createCorrection(30)
+
+	// the correction was approved and its entry was written, but the correction wasn't saved as applied
+	correction.Status = intPkg.AccountingCorrectionStatusApproved
+	err := suite.service.accountingCorrectionRepository.UpdateByStatus(
+		context.TODO(),
+		correction,
+		intPkg.AccountingCorrectionStatusPending,
+	)
+	assert.NoError(suite.T(), err)
+
+	entryRsp := &billingpb.CreateAccountingEntryResponse{}
+	err = suite.service.createAccountingCorrectionEntry(context.TODO(), correction, time.Now(), entryRsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, entryRsp.Status)
+
+	req := &intPkg.ReviewAccountingCorrectionRequest{Id: correction.Id.Hex(), UserId: suite.financier.UserId}
+	res := &intPkg.AccountingCorrectionResponse{}
+	err = suite.service.ApproveAccountingCorrection(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Equal(suite.T(), intPkg.AccountingCorrectionStatusApplied, res.Item.Status)
+	assert.Equal(suite.T(), entryRsp.Item.Id, res.Item.AccountingEntryId)
+
+	entries, err := suite.service.accountingRepository.FindBySource(context.TODO(), suite.merchant.Id, repository.CollectionMerchant)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), entries, 1)
+
+	// the rejection of the approved correction isn't allowed
+	res = &intPkg.AccountingCorrectionResponse{}
+	err = suite.service.RejectAccountingCorrection(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), accountingCorrectionErrorStatusInvalid, res.Message)
+}
+
+func (suite *AccountingCorrectionTestSuite) TestAccountingCorrection_Create_TypeNotAllowed() {
+	types := []string{
+		pkg.AccountingEntryTypeRealGrossRevenue,
+		pkg.AccountingEntryTypeMerchantTaxFee,
+		pkg.AccountingEntryTypeMerchantRollingReserveCreate,
+		pkg.AccountingEntryTypeMerchantRollingReserveRelease,
+		"unknown_entry_type",
+	}
+
+	for _, entryType := range types {
+		req := &intPkg.CreateAccountingCorrectionRequest{
+			MerchantId: suite.merchant.Id,
+			UserId:     suite.support.UserId,
+			Type:       entryType,
+			Amount:     100,
+			Currency:   "RUB",
+			Reason:     "reason",
+		}
+		res := &intPkg.AccountingCorrectionResponse{}
+		err := suite.service.CreateAccountingCorrection(context.TODO(), req, res)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status, entryType)
+		assert.Equal(suite.T(), accountingCorrectionErrorTypeNotAllowed, res.Message, entryType)
+		assert.Nil(suite.T(), res.Item)
+	}
+
+	count, err := suite.service.accountingCorrectionRepository.FindCount(context.TODO(), suite.merchant.Id, "")
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 0, count)
+}
+
+func (suite *AccountingCorrectionTestSuite) TestAccountingCorrection_Approve_AccessDenied() {
+	correction := suite.createCorrection(100)
+
+	req := &intPkg.ReviewAccountingCorrectionRequest{Id: correction.Id.Hex(), UserId: suite.support.UserId}
+	res := &intPkg.AccountingCorrectionResponse{}
+	err := suite.service.ApproveAccountingCorrection(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+	assert.Equal(suite.T(), accountingCorrectionErrorAccessDenied, res.Message)
+
+	suite.support.Role = billingpb.RoleSystemFinancial
+	err = suite.service.userRoleRepository.UpdateAdminUser(context.TODO(), suite.support)
+	assert.NoError(suite.T(), err)
+
+	res = &intPkg.AccountingCorrectionResponse{}
+	err = suite.service.ApproveAccountingCorrection(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+	assert.Equal(suite.T(), accountingCorrectionErrorSameReviewer, res.Message)
+
+	req.Id = primitive.NewObjectID().Hex()
+	req.UserId = suite.financier.UserId
+	res = &intPkg.AccountingCorrectionResponse{}
+	err = suite.service.ApproveAccountingCorrection(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, res.Status)
+	assert.Equal(suite.T(), accountingCorrectionErrorNotFound, res.Message)
+}
+
+func (suite *AccountingCorrectionTestSuite) TestAccountingCorrection_CreateAccountingEntry_CorrectionNotAllowed() {
+	req := &billingpb.CreateAccountingEntryRequest{
+		Type:       pkg.AccountingEntryTypeMerchantRoyaltyCorrection,
+		MerchantId: suite.merchant.Id,
+		Amount:     100,
+		Currency:   "RUB",
+		Reason:     "compensation",
+		Date:       time.Now().Unix(),
+		Status:     pkg.BalanceTransactionStatusAvailable,
+	}
+	rsp := &billingpb.CreateAccountingEntryResponse{}
+	err := suite.service.CreateAccountingEntry(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), accountingEntryErrorCorrectionNotAllowed, rsp.Message)
+	assert.Nil(suite.T(), rsp.Item)
+}
+
+func (suite *AccountingCorrectionTestSuite) TestAccountingCorrection_createApprovedAccountingCorrection_Ok() {
+	correction, err := suite.service.createApprovedAccountingCorrection(
+		context.TODO(),
+		suite.merchant.Id,
+		"RUB",
+		"merchant debt repayment",
+		-25,
+		time.Now(),
+		suite.support.UserId,
+		suite.financier.UserId,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), intPkg.AccountingCorrectionStatusApplied, correction.Status)
+	assert.Equal(suite.T(), suite.support.UserId, correction.CreatedBy)
+	assert.Equal(suite.T(), suite.financier.UserId, correction.ReviewedBy)
+	assert.Len(suite.T(), correction.History, 2)
+
+	stored, err := suite.service.accountingCorrectionRepository.GetById(context.TODO(), correction.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), correction.AccountingEntryId, stored.AccountingEntryId)
+
+	entry, err := suite.service.accountingRepository.GetById(context.TODO(), correction.AccountingEntryId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), float64(-25), entry.Amount)
+
+	_, err = suite.service.createApprovedAccountingCorrection(
+		context.TODO(), suite.merchant.Id, "RUB", "unit-test", 10, time.Now(), suite.support.UserId, "",
+	)
+	assert.Equal(suite.T(), accountingCorrectionErrorAccessDenied, err)
+}
+
+func (suite *AccountingCorrectionTestSuite) TestAccountingCorrection_Reject_Ok() {
+	correction := suite.createCorrection(100)
+
+	req := &intPkg.ReviewAccountingCorrectionRequest{Id: correction.Id.Hex(), UserId: suite.financier.UserId, Comment: "no documents"}
+	res := &intPkg.AccountingCorrectionResponse{}
+	err := suite.service.RejectAccountingCorrection(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Equal(suite.T(), intPkg.AccountingCorrectionStatusRejected, res.Item.Status)
+	assert.Empty(suite.T(), res.Item.AccountingEntryId)
+	assert.Equal(suite.T(), "no documents", res.Item.History[1].Comment)
+
+	suite.centrifugo.AssertNotCalled(suite.T(), "Publish", mock2.Anything, mock2.Anything, mock2.Anything)
+}
+
+func (suite *AccountingCorrectionTestSuite) TestAccountingCorrection_List_Ok() {
+	suite.createCorrection(100)
+	correction := suite.createCorrection(200)
+
+	req := &intPkg.ReviewAccountingCorrectionRequest{Id: correction.Id.Hex(), UserId: suite.financier.UserId}
+	err := suite.service.RejectAccountingCorrection(context.TODO(), req, &intPkg.AccountingCorrectionResponse{})
+	assert.NoError(suite.T(), err)
+
+	res := &intPkg.ListAccountingCorrectionsResponse{}
+	err = suite.service.ListAccountingCorrections(
+		context.TODO(),
+		&intPkg.ListAccountingCorrectionsRequest{MerchantId: suite.merchant.Id, Limit: 10},
+		res,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.EqualValues(suite.T(), 2, res.Count)
+	assert.Len(suite.T(), res.Items, 2)
+
+	res = &intPkg.ListAccountingCorrectionsResponse{}
+	err = suite.service.ListAccountingCorrections(
+		context.TODO(),
+		&intPkg.ListAccountingCorrectionsRequest{
+			MerchantId: suite.merchant.Id,
+			Status:     intPkg.AccountingCorrectionStatusPending,
+			Limit:      10,
+		},
+		res,
+	)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 1, res.Count)
+	assert.Equal(suite.T(), float64(100), res.Items[0].Amount)
+}
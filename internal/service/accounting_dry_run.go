@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/internal/repository"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+)
+
+const (
+	accountingDryRunMaxSources = 100
+)
+
+var (
+	accountingDryRunErrorSourcesEmpty   = newBillingServerErrorMsg("ad000001", "orders or refunds for recomputation not passed")
+	accountingDryRunErrorSourcesLimit   = newBillingServerErrorMsg("ad000002", "too many orders and refunds for recomputation in single request")
+	accountingDryRunErrorAccessDenied   = newBillingServerErrorMsg("ad000003", "user is not allowed to apply recomputation corrections")
+	accountingDryRunErrorReasonRequired = newBillingServerErrorMsg("ad000004", "reason is required to apply recomputation corrections")
+)
+
+// DryRunAccounting recomputes the accounting entries of the orders and refunds against the current tariffs
+// and currency rates without saving them and returns the difference with the stored entries by entry type.
+// If Apply is set, the differences are written as correction entries of the same types.
+func (s *Service) DryRunAccounting(
+	ctx context.Context,
+	req *intPkg.AccountingDryRunRequest,
+	res *intPkg.AccountingDryRunResponse,
+) error {
+	count := len(req.OrderIds) + len(req.RefundIds)
+
+	if count == 0 {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = accountingDryRunErrorSourcesEmpty
+		return nil
+	}
+
+	if count > accountingDryRunMaxSources {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = accountingDryRunErrorSourcesLimit
+		return nil
+	}
+
+	if req.Apply {
+		if !s.hasAdminRole(ctx, req.UserId, billingpb.RoleSystemAdmin, billingpb.RoleSystemFinancial) {
+			res.Status = billingpb.ResponseStatusForbidden
+			res.Message = accountingDryRunErrorAccessDenied
+			return nil
+		}
+
+		if req.Reason == "" {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = accountingDryRunErrorReasonRequired
+			return nil
+		}
+	}
+
+	res.Items = make([]*intPkg.AccountingDryRunResult, 0, count)
+
+	for _, id := range req.OrderIds {
+		result := &intPkg.AccountingDryRunResult{SourceType: repository.CollectionOrder, SourceId: id}
+		handler, err := s.newAccountingDryRunPaymentHandler(ctx, id)
+
+		if err == nil {
+			err = handler.processPaymentEvent()
+		}
+
+		if err == nil {
+			err = s.completeAccountingDryRun(ctx, req, handler, result, id)
+		}
+
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		res.Items = append(res.Items, result)
+	}
+
+	for _, id := range req.RefundIds {
+		result := &intPkg.AccountingDryRunResult{SourceType: repository.CollectionRefund, SourceId: id}
+		handler, err := s.newAccountingDryRunRefundHandler(ctx, id)
+
+		if err == nil {
+			result.SourceId = handler.refund.CreatedOrderId
+			err = handler.processRefundEvent()
+		}
+
+		if err == nil {
+			err = s.completeAccountingDryRun(ctx, req, handler, result, handler.refundOrder.Id)
+		}
+
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		res.Items = append(res.Items, result)
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+
+	return nil
+}
+
+func (s *Service) newAccountingDryRunPaymentHandler(ctx context.Context, orderId string) (*accountingEntry, error) {
+	order, err := s.getOrderById(ctx, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	country, err := s.country.GetByIsoCodeA2(ctx, order.GetCountry())
+
+	if err != nil {
+		return nil, err
+	}
+
+	merchant, err := s.merchantRepository.GetById(ctx, order.GetMerchantId())
+
+	if err != nil {
+		return nil, merchantErrorNotFound
+	}
+
+	handler := &accountingEntry{
+		Service:  s,
+		order:    order,
+		ctx:      ctx,
+		country:  country,
+		merchant: merchant,
+		dryRun:   true,
+	}
+
+	return handler, nil
+}
+
+func (s *Service) newAccountingDryRunRefundHandler(ctx context.Context, refundId string) (*accountingEntry, error) {
+	refund, err := s.refundRepository.GetById(ctx, refundId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.getOrderById(ctx, refund.OriginalOrder.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	refundOrder, err := s.getOrderById(ctx, refund.CreatedOrderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	country, err := s.country.GetByIsoCodeA2(ctx, order.GetCountry())
+
+	if err != nil {
+		return nil, err
+	}
+
+	merchant, err := s.merchantRepository.GetById(ctx, refundOrder.GetMerchantId())
+
+	if err != nil {
+		return nil, merchantErrorNotFound
+	}
+
+	handler := &accountingEntry{
+		Service:     s,
+		refund:      refund,
+		order:       order,
+		refundOrder: refundOrder,
+		ctx:         ctx,
+		country:     country,
+		merchant:    merchant,
+		dryRun:      true,
+	}
+
+	return handler, nil
+}
+
+// completeAccountingDryRun compares the recomputed entries of the handler with the stored ones
+// and writes the correction entries if it's requested.
+func (s *Service) completeAccountingDryRun(
+	ctx context.Context,
+	req *intPkg.AccountingDryRunRequest,
+	handler *accountingEntry,
+	result *intPkg.AccountingDryRunResult,
+	orderViewId string,
+) error {
+	stored, err := s.accountingRepository.FindBySource(ctx, result.SourceId, result.SourceType)
+
+	if err != nil {
+		return err
+	}
+
+	result.MerchantId = handler.merchant.Id
+	corrections := s.getAccountingDryRunCorrections(handler.accountingEntries, stored)
+	result.Diffs = make([]*intPkg.AccountingDryRunDiff, 0, len(corrections))
+
+	for _, correction := range corrections {
+		result.Diffs = append(result.Diffs, correction.diff)
+
+		if correction.entry != nil {
+			result.Changed = true
+		}
+	}
+
+	if !req.Apply || !result.Changed {
+		return nil
+	}
+
+	var entries []*billingpb.AccountingEntry
+
+	for _, correction := range corrections {
+		if correction.entry == nil {
+			continue
+		}
+
+		correction.entry.Reason = intPkg.AccountingRecomputationReason + ": " + req.Reason
+		correction.entry.CreatedAt = ptypes.TimestampNow()
+		entries = append(entries, correction.entry)
+		result.CorrectionEntryIds = append(result.CorrectionEntryIds, correction.entry.Id)
+	}
+
+	if err = s.insertAccountingEntries(ctx, entries); err != nil {
+		return err
+	}
+
+	return s.updateOrderView(ctx, []string{orderViewId})
+}
+
+type accountingDryRunCorrection struct {
+	diff *intPkg.AccountingDryRunDiff
+	// entry is the correction entry with the difference of amounts, nil if the amounts are equal
+	entry *billingpb.AccountingEntry
+}
+
+// getAccountingDryRunCorrections sums the recomputed and stored entries by type and returns the difference
+// for every entry type sorted by type.
+func (s *Service) getAccountingDryRunCorrections(
+	computed, stored []*billingpb.AccountingEntry,
+) []*accountingDryRunCorrection {
+	type amounts struct {
+		entry                         *billingpb.AccountingEntry
+		amount, originalAmount, local intPkg.Money
+	}
+
+	sum := func(list []*billingpb.AccountingEntry) map[string]*amounts {
+		result := make(map[string]*amounts)
+
+		for _, entry := range list {
+			a, ok := result[entry.Type]
+
+			if !ok {
+				a = &amounts{
+					entry:          entry,
+					amount:         s.newMoney(0, entry.Currency),
+					originalAmount: s.newMoney(0, entry.OriginalCurrency),
+					local:          s.newMoney(0, entry.LocalCurrency),
+				}
+				result[entry.Type] = a
+			}
+
+			a.amount = a.amount.Add(s.newMoney(entry.Amount, entry.Currency))
+			a.originalAmount = a.originalAmount.Add(s.newMoney(entry.OriginalAmount, entry.OriginalCurrency))
+			a.local = a.local.Add(s.newMoney(entry.LocalAmount, entry.LocalCurrency))
+		}
+
+		return result
+	}
+
+	computedByType := sum(computed)
+	storedByType := sum(stored)
+	types := make([]string, 0, len(computedByType))
+
+	for entryType := range computedByType {
+		types = append(types, entryType)
+	}
+
+	for entryType := range storedByType {
+		if _, ok := computedByType[entryType]; !ok {
+			types = append(types, entryType)
+		}
+	}
+
+	sort.Strings(types)
+	corrections := make([]*accountingDryRunCorrection, 0, len(types))
+
+	for _, entryType := range types {
+		c, cok := computedByType[entryType]
+		st, sok := storedByType[entryType]
+
+		if !cok {
+			c = &amounts{entry: st.entry}
+		}
+
+		if !sok {
+			st = &amounts{entry: c.entry}
+		}
+
+		currency := c.entry.Currency
+		difference := c.amount.Sub(st.amount).RoundTo(intPkg.MoneyAccountingPrecision)
+		originalDifference := c.originalAmount.Sub(st.originalAmount).RoundTo(intPkg.MoneyAccountingPrecision)
+		localDifference := c.local.Sub(st.local).RoundTo(intPkg.MoneyAccountingPrecision)
+
+		correction := &accountingDryRunCorrection{
+			diff: &intPkg.AccountingDryRunDiff{
+				EntryType:      entryType,
+				Currency:       currency,
+				StoredAmount:   s.newMoney(0, currency).Add(st.amount).Float64(),
+				ComputedAmount: s.newMoney(0, currency).Add(c.amount).Float64(),
+				Difference:     difference.Float64(),
+			},
+		}
+
+		if !difference.IsZero() || !originalDifference.IsZero() || !localDifference.IsZero() {
+			entry := protobuf.Clone(c.entry).(*billingpb.AccountingEntry)
+			entry.Id = primitive.NewObjectID().Hex()
+			entry.Amount = difference.Float64()
+			entry.OriginalAmount = originalDifference.Float64()
+			entry.LocalAmount = localDifference.Float64()
+			correction.entry = entry
+		}
+
+		corrections = append(corrections, correction)
+	}
+
+	return corrections
+}
@@ -4,11 +4,11 @@ import (
 	"context"
 	"errors"
 	"github.com/golang/protobuf/ptypes"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
 	"github.com/paysuper/paysuper-billing-server/internal/repository"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	"github.com/paysuper/paysuper-proto/go/currenciespb"
-	tools "github.com/paysuper/paysuper-tools/number"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
@@ -48,6 +48,7 @@ var (
 	accountingEntryBalanceUpdateFailed             = newBillingServerErrorMsg("ae00015", "balance update failed after create accounting entry")
 	accountingEntryOriginalTaxNotFound             = newBillingServerErrorMsg("ae00016", "real_tax_fee entry from original order not found, refund processing failed")
 	accountingEntryVatCurrencyNotSet               = newBillingServerErrorMsg("ae00017", "vat currency not set")
+	accountingEntryErrorCorrectionNotAllowed       = newBillingServerErrorMsg("ae00018", "manual correction must be created via the accounting correction approval")
 
 	availableAccountingEntries = map[string]bool{
 		pkg.AccountingEntryTypeRealGrossRevenue:                    true,
@@ -127,12 +128,46 @@ type accountingEntry struct {
 	country           *billingpb.Country
 	accountingEntries []*billingpb.AccountingEntry
 	req               *billingpb.CreateAccountingEntryRequest
+	periodLock        *accountingPeriodLock
+	// dryRun allows to recompute the entries of the source which already has stored entries
+	dryRun bool
+	// entryId is the predefined id of the manual entry, so the entry with the same id can't be written twice
+	entryId string
 }
 
+// CreateAccountingEntry creates the manual accounting entry. The manual corrections of the merchant balance
+// aren't accepted here, they are written only after the approval of the financier.
 func (s *Service) CreateAccountingEntry(
 	ctx context.Context,
 	req *billingpb.CreateAccountingEntryRequest,
 	rsp *billingpb.CreateAccountingEntryResponse,
+) error {
+	if accountingCorrectionAllowedTypes[req.Type] {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = accountingEntryErrorCorrectionNotAllowed
+
+		return nil
+	}
+
+	return s.createAccountingEntry(ctx, req, rsp)
+}
+
+// createAccountingEntry writes the manual accounting entry of any available type.
+func (s *Service) createAccountingEntry(
+	ctx context.Context,
+	req *billingpb.CreateAccountingEntryRequest,
+	rsp *billingpb.CreateAccountingEntryResponse,
+) error {
+	return s.createAccountingEntryWithId(ctx, req, rsp, "")
+}
+
+// createAccountingEntryWithId writes the manual accounting entry with the passed id. The insert of the entry
+// with the id which is already stored fails, so the entry of the same operation is written only once.
+func (s *Service) createAccountingEntryWithId(
+	ctx context.Context,
+	req *billingpb.CreateAccountingEntryRequest,
+	rsp *billingpb.CreateAccountingEntryResponse,
+	entryId string,
 ) error {
 	if _, ok := availableAccountingEntries[req.Type]; !ok {
 		rsp.Status = billingpb.ResponseStatusBadData
@@ -141,7 +176,7 @@ func (s *Service) CreateAccountingEntry(
 		return nil
 	}
 
-	handler := &accountingEntry{Service: s, req: req, ctx: ctx}
+	handler := &accountingEntry{Service: s, req: req, ctx: ctx, entryId: entryId}
 
 	countryCode := ""
 	_, err := primitive.ObjectIDFromHex(req.OrderId)
@@ -336,6 +371,10 @@ func (h *accountingEntry) processManualCorrectionEvent() error {
 
 	entry := h.newEntry(h.req.Type)
 
+	if h.entryId != "" {
+		entry.Id = h.entryId
+	}
+
 	entry.Amount = h.req.Amount
 	entry.Currency = h.req.Currency
 	entry.Reason = h.req.Reason
@@ -372,7 +411,7 @@ func (h *accountingEntry) processPaymentEvent() error {
 		repository.CollectionOrder,
 	)
 
-	if ae != nil {
+	if ae != nil && !h.dryRun {
 		zap.L().Error(
 			accountingEntryAlreadyCreated.Message,
 			zap.Error(err),
@@ -425,14 +464,18 @@ func (h *accountingEntry) processPaymentEvent() error {
 	if err != nil {
 		return err
 	}
-	psGrossRevenueFx.Amount = amount - realGrossRevenue.Amount
+	psGrossRevenueFx.Amount = h.newMoney(amount, psGrossRevenueFx.Currency).
+		Sub(h.newMoney(realGrossRevenue.Amount, realGrossRevenue.Currency)).
+		Float64()
 	if err = h.addEntry(psGrossRevenueFx); err != nil {
 		return err
 	}
 
 	// 6. psGrossRevenueFxTaxFee
 	psGrossRevenueFxTaxFee := h.newEntry(pkg.AccountingEntryTypePsGrossRevenueFxTaxFee)
-	psGrossRevenueFxTaxFee.Amount = tools.GetPercentPartFromAmount(psGrossRevenueFx.Amount, h.order.Tax.Rate)
+	psGrossRevenueFxTaxFee.Amount = h.newMoney(psGrossRevenueFx.Amount, psGrossRevenueFx.Currency).
+		PercentPart(h.order.Tax.Rate).
+		Float64()
 	if err = h.addEntry(psGrossRevenueFxTaxFee); err != nil {
 		return err
 	}
@@ -442,12 +485,16 @@ func (h *accountingEntry) processPaymentEvent() error {
 
 	// 8. merchantGrossRevenue
 	merchantGrossRevenue := h.newEntry(pkg.AccountingEntryTypeMerchantGrossRevenue)
-	merchantGrossRevenue.Amount = realGrossRevenue.Amount - psGrossRevenueFx.Amount
+	merchantGrossRevenue.Amount = h.newMoney(realGrossRevenue.Amount, realGrossRevenue.Currency).
+		Sub(h.newMoney(psGrossRevenueFx.Amount, psGrossRevenueFx.Currency)).
+		Float64()
 	// not store in DB - calculated in order_view, but used further in the method code
 
 	// 9. merchantTaxFeeCostValue
 	merchantTaxFeeCostValue := h.newEntry(pkg.AccountingEntryTypeMerchantTaxFeeCostValue)
-	merchantTaxFeeCostValue.Amount = tools.GetPercentPartFromAmount(merchantGrossRevenue.Amount, h.order.Tax.Rate)
+	merchantTaxFeeCostValue.Amount = h.newMoney(merchantGrossRevenue.Amount, merchantGrossRevenue.Currency).
+		PercentPart(h.order.Tax.Rate).
+		Float64()
 	if err = h.addEntry(merchantTaxFeeCostValue); err != nil {
 		return err
 	}
@@ -463,7 +510,9 @@ func (h *accountingEntry) processPaymentEvent() error {
 		if err != nil {
 			return err
 		}
-		merchantTaxFeeCentralBankFx.Amount = amount - merchantTaxFeeCostValue.Amount
+		merchantTaxFeeCentralBankFx.Amount = h.newMoney(amount, merchantTaxFeeCentralBankFx.Currency).
+			Sub(h.newMoney(merchantTaxFeeCostValue.Amount, merchantTaxFeeCostValue.Currency)).
+			Float64()
 	}
 	if err = h.addEntry(merchantTaxFeeCentralBankFx); err != nil {
 		return err
@@ -484,21 +533,27 @@ func (h *accountingEntry) processPaymentEvent() error {
 
 	// 12. psMethodFee
 	psMethodFee := h.newEntry(pkg.AccountingEntryTypePsMethodFee)
-	psMethodFee.Amount = merchantGrossRevenue.Amount * paymentChannelCostMerchant.PsPercent
+	psMethodFee.Amount = h.newMoney(merchantGrossRevenue.Amount, merchantGrossRevenue.Currency).
+		Mul(paymentChannelCostMerchant.PsPercent).
+		Float64()
 	if err = h.addEntry(psMethodFee); err != nil {
 		return err
 	}
 
 	// 13. merchantMethodFee
 	merchantMethodFee := h.newEntry(pkg.AccountingEntryTypeMerchantMethodFee)
-	merchantMethodFee.Amount = merchantGrossRevenue.Amount * paymentChannelCostMerchant.MethodPercent
+	merchantMethodFee.Amount = h.newMoney(merchantGrossRevenue.Amount, merchantGrossRevenue.Currency).
+		Mul(paymentChannelCostMerchant.MethodPercent).
+		Float64()
 	if err = h.addEntry(merchantMethodFee); err != nil {
 		return err
 	}
 
 	// 14. merchantMethodFeeCostValue
 	merchantMethodFeeCostValue := h.newEntry(pkg.AccountingEntryTypeMerchantMethodFeeCostValue)
-	merchantMethodFeeCostValue.Amount = realGrossRevenue.Amount * paymentChannelCostSystem.Percent
+	merchantMethodFeeCostValue.Amount = h.newMoney(realGrossRevenue.Amount, realGrossRevenue.Currency).
+		Mul(paymentChannelCostSystem.Percent).
+		Float64()
 	if err = h.addEntry(merchantMethodFeeCostValue); err != nil {
 		return err
 	}
@@ -595,7 +650,7 @@ func (h *accountingEntry) processRefundEvent() error {
 		return err
 	}
 
-	if aes != nil {
+	if aes != nil && !h.dryRun {
 		zap.L().Error(
 			accountingEntryAlreadyCreated.Message,
 			zap.Error(err),
@@ -660,15 +715,19 @@ func (h *accountingEntry) processRefundEvent() error {
 		return err
 	}
 	realRefundTaxFee := h.newEntry(pkg.AccountingEntryTypeRealRefundTaxFee)
-	realRefundTaxFee.Amount = realTaxFee.Amount * partialRefundCorrection
+	realRefundTaxFee.Amount = h.newMoney(realTaxFee.Amount, realTaxFee.Currency).Mul(partialRefundCorrection).Float64()
 	realRefundTaxFee.Currency = realTaxFee.Currency
-	realRefundTaxFee.OriginalAmount = realTaxFee.OriginalAmount * partialRefundCorrection
+	realRefundTaxFee.OriginalAmount = h.newMoney(realTaxFee.OriginalAmount, realTaxFee.OriginalCurrency).
+		Mul(partialRefundCorrection).
+		Float64()
 	realRefundTaxFee.OriginalCurrency = realTaxFee.OriginalCurrency
 
 	// fills with original values, if not deduction, to subtract the same vat amount that was added on payment
 	// otherwise local values will be automatically re-calculated with exchange rates for current vat period
 	if !h.refundOrder.IsVatDeduction {
-		realRefundTaxFee.LocalAmount = realTaxFee.LocalAmount * partialRefundCorrection
+		realRefundTaxFee.LocalAmount = h.newMoney(realTaxFee.LocalAmount, realTaxFee.LocalCurrency).
+			Mul(partialRefundCorrection).
+			Float64()
 		realRefundTaxFee.LocalCurrency = realTaxFee.LocalCurrency
 	}
 
@@ -678,7 +737,7 @@ func (h *accountingEntry) processRefundEvent() error {
 
 	// 3. realRefundFee
 	realRefundFee := h.newEntry(pkg.AccountingEntryTypeRealRefundFee)
-	realRefundFee.Amount = realRefund.Amount * moneyBackCostSystem.Percent
+	realRefundFee.Amount = h.newMoney(realRefund.Amount, realRefund.Currency).Mul(moneyBackCostSystem.Percent).Float64()
 	if err = h.addEntry(realRefundFee); err != nil {
 		return err
 	}
@@ -710,7 +769,9 @@ func (h *accountingEntry) processRefundEvent() error {
 	// 7. merchantRefundFee
 	merchantRefundFee := h.newEntry(pkg.AccountingEntryTypeMerchantRefundFee)
 	if moneyBackCostMerchant.IsPaidByMerchant {
-		merchantRefundFee.Amount = merchantRefund.Amount * moneyBackCostMerchant.Percent
+		merchantRefundFee.Amount = h.newMoney(merchantRefund.Amount, merchantRefund.Currency).
+			Mul(moneyBackCostMerchant.Percent).
+			Float64()
 	}
 	if err = h.addEntry(merchantRefundFee); err != nil {
 		return err
@@ -778,10 +839,31 @@ func (h *accountingEntry) processRefundEvent() error {
 			return err
 		}
 
-		reverseTaxFee.Amount = (merchantTaxFeeCostValue.Amount + merchantTaxFeeCentralBankFx.Amount) * partialRefundCorrection
-		reverseTaxFee.OriginalAmount = (merchantTaxFeeCostValue.OriginalAmount + merchantTaxFeeCentralBankFx.Amount) * partialRefundCorrection
+		// central bank fx is added in the currency of each amount, it's converted by the rate of the order
+		// taken from the cost value if the fx entry has no amount in that currency
+		reverseTaxFee.Amount = h.newMoney(merchantTaxFeeCostValue.Amount, merchantTaxFeeCostValue.Currency).
+			Add(h.newMoney(merchantTaxFeeCentralBankFx.Amount, merchantTaxFeeCentralBankFx.Currency)).
+			Mul(partialRefundCorrection).
+			Float64()
+		reverseTaxFee.OriginalAmount = h.newMoney(merchantTaxFeeCostValue.OriginalAmount, merchantTaxFeeCostValue.OriginalCurrency).
+			Add(h.getEntryAmountIn(
+				merchantTaxFeeCentralBankFx,
+				merchantTaxFeeCostValue.OriginalCurrency,
+				merchantTaxFeeCostValue.Amount,
+				merchantTaxFeeCostValue.OriginalAmount,
+			)).
+			Mul(partialRefundCorrection).
+			Float64()
 		reverseTaxFee.OriginalCurrency = merchantTaxFeeCostValue.OriginalCurrency
-		reverseTaxFee.LocalAmount = (merchantTaxFeeCostValue.LocalAmount + merchantTaxFeeCentralBankFx.Amount) * partialRefundCorrection
+		reverseTaxFee.LocalAmount = h.newMoney(merchantTaxFeeCostValue.LocalAmount, merchantTaxFeeCostValue.LocalCurrency).
+			Add(h.getEntryAmountIn(
+				merchantTaxFeeCentralBankFx,
+				merchantTaxFeeCostValue.LocalCurrency,
+				merchantTaxFeeCostValue.Amount,
+				merchantTaxFeeCostValue.LocalAmount,
+			)).
+			Mul(partialRefundCorrection).
+			Float64()
 		reverseTaxFee.LocalCurrency = merchantTaxFeeCostValue.LocalCurrency
 	}
 	if err = h.addEntry(reverseTaxFee); err != nil {
@@ -800,8 +882,8 @@ func (h *accountingEntry) processRefundEvent() error {
 		// after that converting it back from vat currency  to merchant currency by stock rate,
 		// next getting Centralbank fx for restored value as difference between converted and restored values,
 		// and finally getting difference between old merchantTaxFeeCentralBankFx amount and calculated new.
-		amountVatRestored := tools.GetPercentPartFromAmount(merchantRefund.Amount, h.order.Tax.Rate)
-		amountVatCb, err := h.GetExchangeCbCurrentCommon(h.order.GetMerchantRoyaltyCurrency(), amountVatRestored)
+		amountVatRestored := h.newMoney(merchantRefund.Amount, merchantRefund.Currency).PercentPart(h.order.Tax.Rate)
+		amountVatCb, err := h.GetExchangeCbCurrentCommon(h.order.GetMerchantRoyaltyCurrency(), amountVatRestored.Float64())
 		if err != nil {
 			return err
 		}
@@ -809,13 +891,14 @@ func (h *accountingEntry) processRefundEvent() error {
 		if err != nil {
 			return err
 		}
-		amountFxRestored := amountMerchantStock - amountVatRestored
-		amountResult := merchantTaxFeeCentralBankFx.Amount - amountFxRestored
+		amountFxRestored := h.newMoney(amountMerchantStock, merchantRefund.Currency).Sub(amountVatRestored)
+		amountResult := h.newMoney(merchantTaxFeeCentralBankFx.Amount, merchantTaxFeeCentralBankFx.Currency).
+			Sub(amountFxRestored)
 
-		if amountResult < 0 {
-			psReverseTaxFeeDelta.Amount = -1 * amountResult
+		if amountResult.Sign() < 0 {
+			psReverseTaxFeeDelta.Amount = amountResult.Neg().Float64()
 		} else {
-			reverseTaxFeeDelta.Amount = amountResult
+			reverseTaxFeeDelta.Amount = amountResult.Float64()
 		}
 	}
 
@@ -1025,9 +1108,27 @@ func (h *accountingEntry) addEntry(entry *billingpb.AccountingEntry) error {
 		}
 	}
 
-	entry.Amount = tools.ToPrecise(entry.Amount)
-	entry.OriginalAmount = tools.ToPrecise(entry.OriginalAmount)
-	entry.LocalAmount = tools.ToPrecise(entry.LocalAmount)
+	entry.Amount = h.newMoney(entry.Amount, entry.Currency).RoundTo(intPkg.MoneyAccountingPrecision).Float64()
+	entry.OriginalAmount = h.newMoney(entry.OriginalAmount, entry.OriginalCurrency).
+		RoundTo(intPkg.MoneyAccountingPrecision).
+		Float64()
+	entry.LocalAmount = h.newMoney(entry.LocalAmount, entry.LocalCurrency).
+		RoundTo(intPkg.MoneyAccountingPrecision).
+		Float64()
+
+	if h.periodLock == nil {
+		h.periodLock = h.newAccountingPeriodLock(h.ctx)
+	}
+
+	period, err := h.periodLock.getClosedPeriod(entry)
+
+	if err != nil {
+		return err
+	}
+
+	if period != nil {
+		h.periodLock.moveToOpenPeriod(entry, period)
+	}
 
 	h.accountingEntries = append(h.accountingEntries, entry)
 
@@ -1039,7 +1140,7 @@ func (h *accountingEntry) saveAccountingEntries(
 	plr repository.PaylinkRepositoryInterface,
 	plvr repository.PaylinkVisitRepositoryInterface,
 ) error {
-	err := h.accountingRepository.MultipleInsert(h.ctx, h.accountingEntries)
+	err := h.insertAccountingEntries(h.ctx, h.accountingEntries)
 
 	if err != nil {
 		return err
@@ -1105,6 +1206,26 @@ func (h *accountingEntry) saveAccountingEntries(
 	return nil
 }
 
+// getEntryAmountIn returns the amount of the entry in the currency. The original or the local amount of the entry
+// is used if it's in that currency, otherwise the amount of the entry is converted by the rate between rateFrom
+// in the currency of the entry and rateTo in the requested currency.
+func (h *accountingEntry) getEntryAmountIn(
+	entry *billingpb.AccountingEntry,
+	currency string,
+	rateFrom, rateTo float64,
+) intPkg.Money {
+	switch currency {
+	case entry.Currency:
+		return h.newMoney(entry.Amount, currency)
+	case entry.OriginalCurrency:
+		return h.newMoney(entry.OriginalAmount, currency)
+	case entry.LocalCurrency:
+		return h.newMoney(entry.LocalAmount, currency)
+	}
+
+	return h.newMoney(entry.Amount, currency).Mul(rateTo).Div(rateFrom)
+}
+
 func (h *accountingEntry) newEntry(entryType string) *billingpb.AccountingEntry {
 
 	var (
@@ -1305,6 +1426,12 @@ func (s *Service) FixTaxes(ctx context.Context) error {
 		return err
 	}
 
+	aes, err = s.getActiveAccountingEntries(ctx, aes)
+
+	if err != nil {
+		return err
+	}
+
 	count := len(aes)
 
 	if count == 0 {
@@ -1410,7 +1537,7 @@ func (s *Service) FixTaxes(ctx context.Context) error {
 		return nil
 	}
 
-	if err = s.accountingRepository.BulkWrite(ctx, aes); err != nil {
+	if err = s.reverseAccountingEntries(ctx, aes); err != nil {
 		return err
 	}
 
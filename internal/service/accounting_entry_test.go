@@ -7,11 +7,14 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/mongodb"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	protobuf "github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/paysuper/paysuper-billing-server/internal/config"
 	"github.com/paysuper/paysuper-billing-server/internal/database"
 	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
 	"github.com/paysuper/paysuper-billing-server/internal/repository"
+	"github.com/paysuper/paysuper-billing-server/internal/repository/models"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
@@ -19,6 +22,7 @@ import (
 	tools "github.com/paysuper/paysuper-tools/number"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
 	rabbitmq "gopkg.in/ProtocolONE/rabbitmq.v1/pkg"
@@ -587,6 +591,58 @@ func (suite *AccountingEntryTestSuite) TestAccountingEntry_Ok_RUB_USD_EUR_VatPay
 	suite.helperCheckRefundView(refund.CreatedOrderId, orderCurrency, merchantRoyaltyCurrency, country.VatCurrency, refundControlResults)
 }
 
+func (suite *AccountingEntryTestSuite) TestAccountingEntry_Refund_ReverseTaxFee_CurrenciesDiffer() {
+	// Order currency RUB
+	// Royalty currency USD
+	// VAT currency EUR
+	order := HelperCreateAndPayOrder(suite.Suite, suite.service, 650, "RUB", "FI", suite.projectFixedAmount, suite.paymentMethod)
+	assert.NotNil(suite.T(), order)
+	assert.Equal(suite.T(), "USD", order.GetMerchantRoyaltyCurrency())
+
+	suite.paymentSystem.Handler = "mock_ok"
+	err := suite.service.paymentSystemRepository.Update(ctx, suite.paymentSystem)
+	assert.NoError(suite.T(), err)
+
+	refund := HelperMakeRefund(suite.Suite, suite.service, order, order.ChargeAmount, false)
+	assert.NotNil(suite.T(), refund)
+
+	orderEntries := make(map[string]*billingpb.AccountingEntry)
+
+	for _, entry := range suite.helperGetAccountingEntries(order.Id, repository.CollectionOrder) {
+		orderEntries[entry.Type] = entry
+	}
+
+	costValue := orderEntries[pkg.AccountingEntryTypeMerchantTaxFeeCostValue]
+	centralBankFx := orderEntries[pkg.AccountingEntryTypeMerchantTaxFeeCentralBankFx]
+	assert.NotNil(suite.T(), costValue)
+	assert.NotNil(suite.T(), centralBankFx)
+	assert.Equal(suite.T(), "EUR", costValue.LocalCurrency)
+	assert.Equal(suite.T(), "EUR", centralBankFx.LocalCurrency)
+
+	var reverseTaxFee *billingpb.AccountingEntry
+
+	for _, entry := range suite.helperGetAccountingEntries(refund.CreatedOrderId, repository.CollectionRefund) {
+		if entry.Type == pkg.AccountingEntryTypeReverseTaxFee {
+			reverseTaxFee = entry
+		}
+	}
+
+	assert.NotNil(suite.T(), reverseTaxFee)
+	assert.Equal(suite.T(), "USD", reverseTaxFee.Currency)
+	assert.Equal(suite.T(), costValue.OriginalCurrency, reverseTaxFee.OriginalCurrency)
+	assert.Equal(suite.T(), "EUR", reverseTaxFee.LocalCurrency)
+	assert.Equal(
+		suite.T(),
+		tools.ToPrecise(costValue.Amount+centralBankFx.Amount),
+		tools.ToPrecise(reverseTaxFee.Amount),
+	)
+	assert.Equal(
+		suite.T(),
+		tools.ToPrecise(costValue.LocalAmount+centralBankFx.LocalAmount),
+		tools.ToPrecise(reverseTaxFee.LocalAmount),
+	)
+}
+
 func (suite *AccountingEntryTestSuite) TestAccountingEntry_Ok_RUB_USD_EUR_VatPayer_Seller() {
 	project := HelperCreateProject(suite.Suite, suite.service, suite.merchant.Id, billingpb.VatPayerSeller)
 
@@ -1297,6 +1353,19 @@ func (suite *AccountingEntryTestSuite) helperGetAccountingEntries(orderId, colle
 	return accountingEntries
 }
 
+// helperReplaceAccountingEntry changes the stored accounting entry bypassing the service,
+// the service never changes the stored entries.
+func (suite *AccountingEntryTestSuite) helperReplaceAccountingEntry(entry *billingpb.AccountingEntry) {
+	oid, err := primitive.ObjectIDFromHex(entry.Id)
+	assert.NoError(suite.T(), err)
+
+	mgo, err := models.NewAccountingEntryMapper().MapObjectToMgo(entry)
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.service.db.Collection(repository.CollectionAccountingEntry).ReplaceOne(ctx, bson.M{"_id": oid}, mgo)
+	assert.NoError(suite.T(), err)
+}
+
 func (suite *AccountingEntryTestSuite) helperCheckOrderView(orderId, orderCurrency, royaltyCurrency, vatCurrency string, orderControlResults map[string]float64) {
 	orderView, err := suite.service.orderViewRepository.GetPrivateOrderBy(ctx, orderId, "", "")
 
@@ -1469,3 +1538,417 @@ func (suite *AccountingEntryTestSuite) TestAccountingEntry_Ok_USD_EUR_None() {
 	orderAccountingEntries := suite.helperGetAccountingEntries(order.Id, repository.CollectionOrder)
 	assert.Equal(suite.T(), len(orderAccountingEntries), 15)
 }
+
+func (suite *AccountingEntryTestSuite) TestAccountingEntry_CheckAccountingIntegrity_Ok() {
+	order := HelperCreateAndPayOrder(suite.Suite, suite.service, 100, "RUB", "RU", suite.projectFixedAmount, suite.paymentMethod)
+	assert.NotNil(suite.T(), order)
+
+	suite.paymentSystem.Handler = "mock_ok"
+	err := suite.service.paymentSystemRepository.Update(ctx, suite.paymentSystem)
+	assert.NoError(suite.T(), err)
+
+	refund := HelperMakeRefund(suite.Suite, suite.service, order, order.ChargeAmount, false)
+	assert.NotNil(suite.T(), refund)
+
+	req := &intPkg.CheckAccountingIntegrityRequest{
+		DateFrom: time.Now().Add(-time.Hour),
+		DateTo:   time.Now().Add(time.Hour),
+	}
+	rsp := &intPkg.CheckAccountingIntegrityResponse{}
+	err = suite.service.CheckAccountingIntegrity(ctx, req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.NotNil(suite.T(), rsp.Item)
+	assert.EqualValues(suite.T(), 1, rsp.Item.CheckedOrders)
+	assert.EqualValues(suite.T(), 1, rsp.Item.CheckedRefunds)
+	assert.EqualValues(suite.T(), 0, rsp.Item.ViolationsCount)
+	assert.Empty(suite.T(), rsp.Item.Violations)
+}
+
+func (suite *AccountingEntryTestSuite) TestAccountingEntry_CheckAccountingIntegrity_Violations() {
+	order := HelperCreateAndPayOrder(suite.Suite, suite.service, 100, "RUB", "RU", suite.projectFixedAmount, suite.paymentMethod)
+	assert.NotNil(suite.T(), order)
+
+	var (
+		realTaxFee  *billingpb.AccountingEntry
+		psMethodFee *billingpb.AccountingEntry
+	)
+
+	for _, entry := range suite.helperGetAccountingEntries(order.Id, repository.CollectionOrder) {
+		switch entry.Type {
+		case pkg.AccountingEntryTypeRealTaxFee:
+			realTaxFee = entry
+		case pkg.AccountingEntryTypePsMethodFee:
+			psMethodFee = entry
+		}
+	}
+
+	assert.NotNil(suite.T(), realTaxFee)
+	assert.NotNil(suite.T(), psMethodFee)
+
+	realTaxFee.OriginalAmount += 5
+	suite.helperReplaceAccountingEntry(realTaxFee)
+
+	psMethodFee.Id = primitive.NewObjectID().Hex()
+	err := suite.service.accountingRepository.MultipleInsert(ctx, []*billingpb.AccountingEntry{psMethodFee})
+	assert.NoError(suite.T(), err)
+
+	req := &intPkg.CheckAccountingIntegrityRequest{
+		DateFrom: time.Now().Add(-time.Hour),
+		DateTo:   time.Now().Add(time.Hour),
+	}
+	rsp := &intPkg.CheckAccountingIntegrityResponse{}
+	err = suite.service.CheckAccountingIntegrity(ctx, req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.EqualValues(suite.T(), 1, rsp.Item.CheckedOrders)
+	assert.EqualValues(suite.T(), 2, rsp.Item.ViolationsCount)
+	assert.Len(suite.T(), rsp.Item.Violations, 2)
+
+	for _, violation := range rsp.Item.Violations {
+		assert.Equal(suite.T(), order.Id, violation.SourceId)
+		assert.Equal(suite.T(), repository.CollectionOrder, violation.SourceType)
+
+		switch violation.Type {
+		case intPkg.AccountingIntegrityViolationDuplicateEntry:
+			assert.Equal(suite.T(), pkg.AccountingEntryTypePsMethodFee, violation.EntryType)
+			assert.EqualValues(suite.T(), 2, violation.Actual)
+		case intPkg.AccountingIntegrityViolationIdentityMismatch:
+			assert.Equal(suite.T(), accountingIntegrityIdentityRealTaxFee, violation.Identity)
+			assert.Equal(suite.T(), realTaxFee.OriginalAmount, violation.Actual)
+		default:
+			assert.Fail(suite.T(), "unexpected violation type", violation.Type)
+		}
+	}
+}
+
+func (suite *AccountingEntryTestSuite) TestAccountingEntry_CheckAccountingIntegrity_SourceStraddlesPeriod() {
+	order := HelperCreateAndPayOrder(suite.Suite, suite.service, 100, "RUB", "RU", suite.projectFixedAmount, suite.paymentMethod)
+	assert.NotNil(suite.T(), order)
+
+	var psMethodFee *billingpb.AccountingEntry
+
+	for _, entry := range suite.helperGetAccountingEntries(order.Id, repository.CollectionOrder) {
+		if entry.Type == pkg.AccountingEntryTypePsMethodFee {
+			psMethodFee = entry
+		}
+	}
+
+	assert.NotNil(suite.T(), psMethodFee)
+
+	createdAt, err := ptypes.TimestampProto(time.Now().Add(-2 * time.Hour))
+	assert.NoError(suite.T(), err)
+
+	psMethodFee.CreatedAt = createdAt
+	suite.helperReplaceAccountingEntry(psMethodFee)
+
+	req := &intPkg.CheckAccountingIntegrityRequest{
+		DateFrom: time.Now().Add(-time.Hour),
+		DateTo:   time.Now().Add(time.Hour),
+	}
+	rsp := &intPkg.CheckAccountingIntegrityResponse{}
+	err = suite.service.CheckAccountingIntegrity(ctx, req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.EqualValues(suite.T(), 1, rsp.Item.CheckedOrders)
+	assert.EqualValues(suite.T(), 0, rsp.Item.ViolationsCount)
+	assert.Empty(suite.T(), rsp.Item.Violations)
+}
+
+func (suite *AccountingEntryTestSuite) TestAccountingEntry_CheckAccountingIntegrity_PeriodInvalid_Error() {
+	req := &intPkg.CheckAccountingIntegrityRequest{
+		DateFrom: time.Now(),
+		DateTo:   time.Now().Add(-time.Hour),
+	}
+	rsp := &intPkg.CheckAccountingIntegrityResponse{}
+	err := suite.service.CheckAccountingIntegrity(ctx, req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), accountingIntegrityErrorPeriodInvalid, rsp.Message)
+	assert.Nil(suite.T(), rsp.Item)
+}
+
+func (suite *AccountingEntryTestSuite) TestAccountingEntry_DryRunAccounting_NoChanges() {
+	order := HelperCreateAndPayOrder(suite.Suite, suite.service, 100, "RUB", "RU", suite.projectFixedAmount, suite.paymentMethod)
+	assert.NotNil(suite.T(), order)
+
+	suite.paymentSystem.Handler = "mock_ok"
+	err := suite.service.paymentSystemRepository.Update(ctx, suite.paymentSystem)
+	assert.NoError(suite.T(), err)
+
+	refund := HelperMakeRefund(suite.Suite, suite.service, order, order.ChargeAmount, false)
+	assert.NotNil(suite.T(), refund)
+
+	req := &intPkg.AccountingDryRunRequest{OrderIds: []string{order.Id}, RefundIds: []string{refund.Id}}
+	rsp := &intPkg.AccountingDryRunResponse{}
+	err = suite.service.DryRunAccounting(ctx, req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Len(suite.T(), rsp.Items, 2)
+
+	for _, item := range rsp.Items {
+		assert.Empty(suite.T(), item.Error)
+		assert.False(suite.T(), item.Changed)
+		assert.NotEmpty(suite.T(), item.Diffs)
+		assert.Empty(suite.T(), item.CorrectionEntryIds)
+
+		for _, diff := range item.Diffs {
+			assert.Zero(suite.T(), diff.Difference)
+			assert.Equal(suite.T(), diff.StoredAmount, diff.ComputedAmount)
+		}
+	}
+
+	assert.Equal(suite.T(), refund.CreatedOrderId, rsp.Items[1].SourceId)
+	assert.Len(suite.T(), suite.helperGetAccountingEntries(order.Id, repository.CollectionOrder), len(rsp.Items[0].Diffs))
+}
+
+func (suite *AccountingEntryTestSuite) TestAccountingEntry_DryRunAccounting_Apply() {
+	order := HelperCreateAndPayOrder(suite.Suite, suite.service, 100, "RUB", "RU", suite.projectFixedAmount, suite.paymentMethod)
+	assert.NotNil(suite.T(), order)
+
+	var psMethodFee *billingpb.AccountingEntry
+
+	for _, entry := range suite.helperGetAccountingEntries(order.Id, repository.CollectionOrder) {
+		if entry.Type == pkg.AccountingEntryTypePsMethodFee {
+			psMethodFee = entry
+		}
+	}
+
+	assert.NotNil(suite.T(), psMethodFee)
+	expected := psMethodFee.Amount
+	psMethodFee.Amount += 5
+	suite.helperReplaceAccountingEntry(psMethodFee)
+
+	req := &intPkg.AccountingDryRunRequest{OrderIds: []string{order.Id}}
+	rsp := &intPkg.AccountingDryRunResponse{}
+	err := suite.service.DryRunAccounting(ctx, req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.True(suite.T(), rsp.Items[0].Changed)
+
+	for _, diff := range rsp.Items[0].Diffs {
+		if diff.EntryType != pkg.AccountingEntryTypePsMethodFee {
+			assert.Zero(suite.T(), diff.Difference)
+			continue
+		}
+
+		assert.Equal(suite.T(), float64(-5), diff.Difference)
+		assert.Equal(suite.T(), expected, diff.ComputedAmount)
+	}
+
+	// nothing is written without apply
+	entriesCount := len(suite.helperGetAccountingEntries(order.Id, repository.CollectionOrder))
+	assert.Len(suite.T(), rsp.Items[0].Diffs, entriesCount)
+
+	admin := &billingpb.UserRole{
+		Id:     primitive.NewObjectID().Hex(),
+		UserId: primitive.NewObjectID().Hex(),
+		Role:   billingpb.RoleSystemFinancial,
+	}
+	err = suite.service.userRoleRepository.AddAdminUser(ctx, admin)
+	assert.NoError(suite.T(), err)
+
+	req.Apply = true
+	req.UserId = admin.UserId
+	req.Reason = "payment method fee fixed"
+	rsp = &intPkg.AccountingDryRunResponse{}
+	err = suite.service.DryRunAccounting(ctx, req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Len(suite.T(), rsp.Items[0].CorrectionEntryIds, 1)
+
+	correction, err := suite.service.accountingRepository.GetById(ctx, rsp.Items[0].CorrectionEntryIds[0])
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.AccountingEntryTypePsMethodFee, correction.Type)
+	assert.Equal(suite.T(), float64(-5), correction.Amount)
+	assert.True(suite.T(), intPkg.IsAccountingAdjustment(correction))
+	assert.Len(suite.T(), suite.helperGetAccountingEntries(order.Id, repository.CollectionOrder), entriesCount+1)
+
+	req.Apply = false
+	rsp = &intPkg.AccountingDryRunResponse{}
+	err = suite.service.DryRunAccounting(ctx, req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), rsp.Items[0].Changed)
+}
+
+func (suite *AccountingEntryTestSuite) TestAccountingEntry_DryRunAccounting_Error() {
+	rsp := &intPkg.AccountingDryRunResponse{}
+	err := suite.service.DryRunAccounting(ctx, &intPkg.AccountingDryRunRequest{}, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), accountingDryRunErrorSourcesEmpty, rsp.Message)
+
+	req := &intPkg.AccountingDryRunRequest{
+		OrderIds: []string{primitive.NewObjectID().Hex()},
+		Apply:    true,
+		UserId:   primitive.NewObjectID().Hex(),
+		Reason:   "reason",
+	}
+	rsp = &intPkg.AccountingDryRunResponse{}
+	err = suite.service.DryRunAccounting(ctx, req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, rsp.Status)
+	assert.Equal(suite.T(), accountingDryRunErrorAccessDenied, rsp.Message)
+
+	req.Apply = false
+	rsp = &intPkg.AccountingDryRunResponse{}
+	err = suite.service.DryRunAccounting(ctx, req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.NotEmpty(suite.T(), rsp.Items[0].Error)
+}
+
+func (suite *AccountingEntryTestSuite) TestAccountingEntry_VerifyAccountingChain_Ok() {
+	order := HelperCreateAndPayOrder(suite.Suite, suite.service, 100, "RUB", "RU", suite.projectFixedAmount, suite.paymentMethod)
+	assert.NotNil(suite.T(), order)
+
+	entries := suite.helperGetAccountingEntries(order.Id, repository.CollectionOrder)
+	assert.NotEmpty(suite.T(), entries)
+
+	var expected *billingpb.AccountingEntry
+
+	for _, entry := range entries {
+		if entry.Type == pkg.AccountingEntryTypePsMethodFee {
+			expected = protobuf.Clone(entry).(*billingpb.AccountingEntry)
+			entry.Amount += 5
+		}
+	}
+
+	err := suite.service.reverseAccountingEntries(ctx, entries)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), suite.helperGetAccountingEntries(order.Id, repository.CollectionOrder), len(entries)+2)
+
+	stored, err := suite.service.accountingRepository.GetById(ctx, expected.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), expected.Amount, stored.Amount)
+
+	req := &intPkg.VerifyAccountingChainRequest{OperatingCompanyId: entries[0].OperatingCompanyId}
+	rsp := &intPkg.VerifyAccountingChainResponse{}
+	err = suite.service.VerifyAccountingChain(ctx, req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Nil(suite.T(), rsp.Item.BrokenLink)
+	assert.EqualValues(suite.T(), len(entries)+2, rsp.Item.CheckedLinks)
+
+	last, err := suite.service.accountingChainRepository.GetLast(ctx, req.OperatingCompanyId)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), len(entries)+2, last.Sequence)
+
+	replacement, err := suite.service.accountingRepository.GetById(ctx, last.EntryId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), expected.Amount+5, replacement.Amount)
+	assert.True(suite.T(), intPkg.IsAccountingAdjustment(replacement))
+
+	// the already reversed entry isn't reversed again
+	err = suite.service.reverseAccountingEntries(ctx, []*billingpb.AccountingEntry{expected})
+	assert.NoError(suite.T(), err)
+	expected.Amount += 10
+	err = suite.service.reverseAccountingEntries(ctx, []*billingpb.AccountingEntry{expected})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), suite.helperGetAccountingEntries(order.Id, repository.CollectionOrder), len(entries)+2)
+}
+
+func (suite *AccountingEntryTestSuite) TestAccountingEntry_getActiveAccountingEntries_Ok() {
+	order := HelperCreateAndPayOrder(suite.Suite, suite.service, 100, "RUB", "RU", suite.projectFixedAmount, suite.paymentMethod)
+	assert.NotNil(suite.T(), order)
+
+	entries := suite.helperGetAccountingEntries(order.Id, repository.CollectionOrder)
+	assert.NotEmpty(suite.T(), entries)
+
+	// the reason text of the replacement doesn't mark another entry as reversed
+	entries[0].Amount += 5
+	entries[0].Reason = intPkg.AccountingReversalReason + " of " + entries[1].Id
+	err := suite.service.reverseAccountingEntries(ctx, entries[:1])
+	assert.NoError(suite.T(), err)
+
+	reversals, err := suite.service.accountingEntryReversalRepository.FindByEntryIds(ctx, []string{entries[1].Id})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), reversals)
+
+	reversals, err = suite.service.accountingEntryReversalRepository.FindByEntryIds(ctx, []string{entries[0].Id})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), reversals, 1)
+
+	all := suite.helperGetAccountingEntries(order.Id, repository.CollectionOrder)
+	assert.Len(suite.T(), all, len(entries)+2)
+
+	active, err := suite.service.getActiveAccountingEntries(ctx, all)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), active, len(entries))
+
+	ids := make(map[string]bool, len(active))
+
+	for _, entry := range active {
+		ids[entry.Id] = true
+	}
+
+	assert.False(suite.T(), ids[entries[0].Id])
+	assert.False(suite.T(), ids[reversals[0].ReversalEntryId])
+	assert.True(suite.T(), ids[reversals[0].ReplacementEntryId])
+	assert.True(suite.T(), ids[entries[1].Id])
+}
+
+func (suite *AccountingEntryTestSuite) TestAccountingEntry_VerifyAccountingChain_EntryNotLinked() {
+	order := HelperCreateAndPayOrder(suite.Suite, suite.service, 100, "RUB", "RU", suite.projectFixedAmount, suite.paymentMethod)
+	assert.NotNil(suite.T(), order)
+
+	entries := suite.helperGetAccountingEntries(order.Id, repository.CollectionOrder)
+	assert.NotEmpty(suite.T(), entries)
+
+	// the entry saved bypassing the chain
+	unlinked := protobuf.Clone(entries[0]).(*billingpb.AccountingEntry)
+	unlinked.Id = primitive.NewObjectID().Hex()
+	err := suite.service.accountingRepository.MultipleInsert(ctx, []*billingpb.AccountingEntry{unlinked})
+	assert.NoError(suite.T(), err)
+
+	req := &intPkg.VerifyAccountingChainRequest{OperatingCompanyId: entries[0].OperatingCompanyId}
+	rsp := &intPkg.VerifyAccountingChainResponse{}
+	err = suite.service.VerifyAccountingChain(ctx, req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.EqualValues(suite.T(), len(entries), rsp.Item.CheckedLinks)
+	assert.NotNil(suite.T(), rsp.Item.BrokenLink)
+	assert.Zero(suite.T(), rsp.Item.BrokenLink.Sequence)
+	assert.Equal(suite.T(), unlinked.Id, rsp.Item.BrokenLink.EntryId)
+	assert.Equal(suite.T(), intPkg.AccountingChainBrokenLinkEntryNotLinked, rsp.Item.BrokenLink.Reason)
+}
+
+func (suite *AccountingEntryTestSuite) TestAccountingEntry_VerifyAccountingChain_BrokenLink() {
+	order := HelperCreateAndPayOrder(suite.Suite, suite.service, 100, "RUB", "RU", suite.projectFixedAmount, suite.paymentMethod)
+	assert.NotNil(suite.T(), order)
+
+	entries := suite.helperGetAccountingEntries(order.Id, repository.CollectionOrder)
+	assert.NotEmpty(suite.T(), entries)
+
+	links, err := suite.service.accountingChainRepository.FindByOperatingCompany(ctx, entries[0].OperatingCompanyId, 1, 0)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), links, len(entries))
+
+	tampered, err := suite.service.accountingRepository.GetById(ctx, links[2].EntryId)
+	assert.NoError(suite.T(), err)
+	tampered.Amount += 1
+	suite.helperReplaceAccountingEntry(tampered)
+
+	req := &intPkg.VerifyAccountingChainRequest{OperatingCompanyId: entries[0].OperatingCompanyId}
+	rsp := &intPkg.VerifyAccountingChainResponse{}
+	err = suite.service.VerifyAccountingChain(ctx, req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.EqualValues(suite.T(), 2, rsp.Item.CheckedLinks)
+	assert.NotNil(suite.T(), rsp.Item.BrokenLink)
+	assert.EqualValues(suite.T(), 3, rsp.Item.BrokenLink.Sequence)
+	assert.Equal(suite.T(), tampered.Id, rsp.Item.BrokenLink.EntryId)
+	assert.Equal(suite.T(), intPkg.AccountingChainBrokenLinkHashMismatch, rsp.Item.BrokenLink.Reason)
+	assert.Equal(suite.T(), links[2].Hash, rsp.Item.BrokenLink.ExpectedHash)
+	assert.NotEqual(suite.T(), links[2].Hash, rsp.Item.BrokenLink.ActualHash)
+
+	reports, err := suite.service.VerifyAccountingChains(ctx)
+	assert.NoError(suite.T(), err)
+	assert.NotEmpty(suite.T(), reports)
+
+	rsp = &intPkg.VerifyAccountingChainResponse{}
+	err = suite.service.VerifyAccountingChain(ctx, &intPkg.VerifyAccountingChainRequest{OperatingCompanyId: primitive.NewObjectID().Hex()}, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rsp.Status)
+	assert.Equal(suite.T(), accountingChainErrorOperatingCompanyNotFound, rsp.Message)
+}
@@ -0,0 +1,449 @@
+package service
+
+import (
+	"context"
+	protobuf "github.com/golang/protobuf/proto"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/internal/repository"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"math"
+	"time"
+)
+
+const (
+	accountingIntegrityMaxPeriod = 31 * 24 * time.Hour
+
+	accountingIntegrityIdentityGrossRevenue           = "gross_revenue"
+	accountingIntegrityIdentityRealTaxFee             = "real_tax_fee"
+	accountingIntegrityIdentityCentralBankTaxFee      = "central_bank_tax_fee"
+	accountingIntegrityIdentityPsGrossRevenueFxTaxFee = "ps_gross_revenue_fx_tax_fee"
+	accountingIntegrityIdentityMerchantTaxFee         = "merchant_tax_fee_cost_value"
+	accountingIntegrityIdentityRefundAmount           = "refund_amount"
+	accountingIntegrityIdentityRealRefundTaxFee       = "real_refund_tax_fee"
+	accountingIntegrityIdentityReverseTaxFeeDelta     = "reverse_tax_fee_delta"
+)
+
+var (
+	accountingIntegrityErrorPeriodInvalid = newBillingServerErrorMsg("ai000001", "accounting integrity check period is invalid")
+
+	// accounting entries stored by processPaymentEvent, other entry types of payment are calculated in order_view
+	paymentAccountingEntriesRequired = []string{
+		pkg.AccountingEntryTypeRealGrossRevenue,
+		pkg.AccountingEntryTypeRealTaxFee,
+		pkg.AccountingEntryTypeCentralBankTaxFee,
+		pkg.AccountingEntryTypePsGrossRevenueFx,
+		pkg.AccountingEntryTypePsGrossRevenueFxTaxFee,
+		pkg.AccountingEntryTypeMerchantTaxFeeCostValue,
+		pkg.AccountingEntryTypeMerchantTaxFeeCentralBankFx,
+		pkg.AccountingEntryTypePsMethodFee,
+		pkg.AccountingEntryTypeMerchantMethodFee,
+		pkg.AccountingEntryTypeMerchantMethodFeeCostValue,
+		pkg.AccountingEntryTypeMerchantMethodFixedFee,
+		pkg.AccountingEntryTypeRealMerchantMethodFixedFee,
+		pkg.AccountingEntryTypeRealMerchantMethodFixedFeeCostValue,
+		pkg.AccountingEntryTypeMerchantPsFixedFee,
+		pkg.AccountingEntryTypeRealMerchantPsFixedFee,
+	}
+
+	// accounting entries stored by processRefundEvent, other entry types of refund are calculated in order_view
+	refundAccountingEntriesRequired = []string{
+		pkg.AccountingEntryTypeRealRefund,
+		pkg.AccountingEntryTypeRealRefundTaxFee,
+		pkg.AccountingEntryTypeRealRefundFee,
+		pkg.AccountingEntryTypeRealRefundFixedFee,
+		pkg.AccountingEntryTypeMerchantRefund,
+		pkg.AccountingEntryTypeMerchantRefundFee,
+		pkg.AccountingEntryTypeMerchantRefundFixedFeeCostValue,
+		pkg.AccountingEntryTypeMerchantRefundFixedFee,
+		pkg.AccountingEntryTypeReverseTaxFee,
+		pkg.AccountingEntryTypeReverseTaxFeeDelta,
+		pkg.AccountingEntryTypePsReverseTaxFeeDelta,
+	}
+)
+
+type accountingIntegrityChecker struct {
+	*Service
+	ctx    context.Context
+	report *intPkg.AccountingIntegrityReport
+}
+
+// CheckAccountingIntegrity checks the accounting entries of orders and refunds for the date range
+// and returns the report of the found violations.
+func (s *Service) CheckAccountingIntegrity(
+	ctx context.Context,
+	req *intPkg.CheckAccountingIntegrityRequest,
+	res *intPkg.CheckAccountingIntegrityResponse,
+) error {
+	if !req.DateFrom.Before(req.DateTo) || req.DateTo.Sub(req.DateFrom) > accountingIntegrityMaxPeriod {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = accountingIntegrityErrorPeriodInvalid
+		return nil
+	}
+
+	report, err := s.checkAccountingIntegrity(ctx, req.DateFrom, req.DateTo)
+
+	if err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = report
+
+	return nil
+}
+
+func (s *Service) checkAccountingIntegrity(
+	ctx context.Context,
+	from, to time.Time,
+) (*intPkg.AccountingIntegrityReport, error) {
+	h := &accountingIntegrityChecker{
+		Service: s,
+		ctx:     ctx,
+		report: &intPkg.AccountingIntegrityReport{
+			DateFrom:   from,
+			DateTo:     to,
+			Violations: []*intPkg.AccountingIntegrityViolation{},
+			CreatedAt:  time.Now(),
+		},
+	}
+
+	entries, err := s.accountingRepository.FindBySourceTypeDates(ctx, repository.CollectionOrder, from, to)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, list := range h.groupBySource(entries) {
+		h.report.CheckedOrders++
+
+		if list, err = h.getSourceEntries(list, repository.CollectionOrder); err != nil {
+			return nil, err
+		}
+
+		if err = h.checkPayment(list); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err = s.accountingRepository.FindBySourceTypeDates(ctx, repository.CollectionRefund, from, to)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, list := range h.groupBySource(entries) {
+		h.report.CheckedRefunds++
+
+		if list, err = h.getSourceEntries(list, repository.CollectionRefund); err != nil {
+			return nil, err
+		}
+
+		if err = h.checkRefund(list); err != nil {
+			return nil, err
+		}
+	}
+
+	h.report.ViolationsCount = int32(len(h.report.Violations))
+
+	return h.report, nil
+}
+
+// groupBySource splits the entries sorted by source id to the lists of entries of the same source.
+func (h *accountingIntegrityChecker) groupBySource(entries []*billingpb.AccountingEntry) [][]*billingpb.AccountingEntry {
+	var groups [][]*billingpb.AccountingEntry
+
+	for i, entry := range entries {
+		if i == 0 || entries[i-1].Source.Id != entry.Source.Id {
+			groups = append(groups, []*billingpb.AccountingEntry{})
+		}
+
+		groups[len(groups)-1] = append(groups[len(groups)-1], entry)
+	}
+
+	return groups
+}
+
+// getSourceEntries returns all accounting entries of the source found in the checked period. The entries of the source
+// can be created on the both sides of the period boundary, so the entries found by dates only aren't complete.
+func (h *accountingIntegrityChecker) getSourceEntries(
+	list []*billingpb.AccountingEntry,
+	sourceType string,
+) ([]*billingpb.AccountingEntry, error) {
+	entries, err := h.accountingRepository.FindBySource(h.ctx, list[0].Source.Id, sourceType)
+
+	if err != nil || len(entries) <= 0 {
+		return list, err
+	}
+
+	return entries, nil
+}
+
+func (h *accountingIntegrityChecker) checkPayment(list []*billingpb.AccountingEntry) error {
+	source := list[0].Source
+	entries := h.checkEntryTypes(list, paymentAccountingEntriesRequired)
+
+	order, err := h.orderRepository.GetById(h.ctx, source.Id)
+
+	if err != nil {
+		h.addViolation(source, intPkg.AccountingIntegrityViolationSourceNotFound, "", "", 0, 0, "")
+		return nil
+	}
+
+	rate := order.GetTax().GetRate()
+	currency := list[0].Currency
+	realGrossRevenue := entries[pkg.AccountingEntryTypeRealGrossRevenue]
+	originalCurrency := realGrossRevenue.GetOriginalCurrency()
+	psGrossRevenueFx := h.newMoney(entries[pkg.AccountingEntryTypePsGrossRevenueFx].GetAmount(), currency)
+	merchantGrossRevenue := h.newMoney(realGrossRevenue.GetAmount(), currency).Sub(psGrossRevenueFx)
+
+	h.checkIdentity(
+		source,
+		accountingIntegrityIdentityRealTaxFee,
+		h.newMoney(realGrossRevenue.GetOriginalAmount(), originalCurrency).PercentPart(rate),
+		h.newMoney(entries[pkg.AccountingEntryTypeRealTaxFee].GetOriginalAmount(), originalCurrency),
+	)
+
+	h.checkIdentity(
+		source,
+		accountingIntegrityIdentityCentralBankTaxFee,
+		h.newMoney(0, currency),
+		h.newMoney(entries[pkg.AccountingEntryTypeCentralBankTaxFee].GetAmount(), currency),
+	)
+
+	h.checkIdentity(
+		source,
+		accountingIntegrityIdentityPsGrossRevenueFxTaxFee,
+		psGrossRevenueFx.PercentPart(rate),
+		h.newMoney(entries[pkg.AccountingEntryTypePsGrossRevenueFxTaxFee].GetAmount(), currency),
+	)
+
+	h.checkIdentity(
+		source,
+		accountingIntegrityIdentityMerchantTaxFee,
+		merchantGrossRevenue.PercentPart(rate),
+		h.newMoney(entries[pkg.AccountingEntryTypeMerchantTaxFeeCostValue].GetAmount(), currency),
+	)
+
+	// gross revenue of merchant must be equal to the sum of net revenue, fees and taxes calculated in order_view
+	view, err := h.orderViewRepository.GetPrivateOrderBy(h.ctx, source.Id, "", "")
+
+	if err != nil || view.NetRevenue == nil {
+		return nil
+	}
+
+	h.checkIdentity(
+		source,
+		accountingIntegrityIdentityGrossRevenue,
+		h.newMoney(view.NetRevenue.Amount, currency).Add(
+			h.newMoney(view.GetFeesTotal().GetAmount(), currency),
+			h.newMoney(view.GetTaxFeeTotal().GetAmount(), currency),
+		),
+		merchantGrossRevenue,
+	)
+
+	return nil
+}
+
+func (h *accountingIntegrityChecker) checkRefund(list []*billingpb.AccountingEntry) error {
+	source := list[0].Source
+	entries := h.checkEntryTypes(list, refundAccountingEntriesRequired)
+
+	refundOrder, err := h.orderRepository.GetById(h.ctx, source.Id)
+
+	if err != nil || refundOrder.ParentOrder == nil {
+		h.addViolation(source, intPkg.AccountingIntegrityViolationSourceNotFound, "", "", 0, 0, "")
+		return nil
+	}
+
+	reverseTaxFeeDelta := entries[pkg.AccountingEntryTypeReverseTaxFeeDelta].GetAmount()
+	psReverseTaxFeeDelta := entries[pkg.AccountingEntryTypePsReverseTaxFeeDelta].GetAmount()
+
+	// only one of the tax fee deltas can be set for refund
+	h.checkIdentity(
+		source,
+		accountingIntegrityIdentityReverseTaxFeeDelta,
+		h.newMoney(0, list[0].Currency),
+		h.newMoney(math.Min(reverseTaxFeeDelta, psReverseTaxFeeDelta), list[0].Currency),
+	)
+
+	paymentEntries, err := h.accountingRepository.FindBySource(h.ctx, refundOrder.ParentOrder.Id, repository.CollectionOrder)
+
+	if err != nil {
+		return err
+	}
+
+	var realGrossRevenue, realTaxFee *billingpb.AccountingEntry
+
+	for _, entry := range paymentEntries {
+		switch entry.Type {
+		case pkg.AccountingEntryTypeRealGrossRevenue:
+			realGrossRevenue = entry
+		case pkg.AccountingEntryTypeRealTaxFee:
+			realTaxFee = entry
+		}
+	}
+
+	realRefund := entries[pkg.AccountingEntryTypeRealRefund]
+
+	if realGrossRevenue == nil || realRefund == nil || realGrossRevenue.OriginalAmount == 0 ||
+		realGrossRevenue.OriginalCurrency != realRefund.OriginalCurrency {
+		return nil
+	}
+
+	correction := realRefund.OriginalAmount / realGrossRevenue.OriginalAmount
+
+	if correction > 1 {
+		h.checkIdentity(
+			source,
+			accountingIntegrityIdentityRefundAmount,
+			h.newMoney(realGrossRevenue.OriginalAmount, realRefund.OriginalCurrency),
+			h.newMoney(realRefund.OriginalAmount, realRefund.OriginalCurrency),
+		)
+	}
+
+	h.checkIdentity(
+		source,
+		accountingIntegrityIdentityRealRefundTaxFee,
+		h.newMoney(realTaxFee.GetAmount(), realTaxFee.GetCurrency()).Mul(correction),
+		h.newMoney(entries[pkg.AccountingEntryTypeRealRefundTaxFee].GetAmount(), realTaxFee.GetCurrency()),
+	)
+
+	return nil
+}
+
+// checkEntryTypes adds violations for missing and duplicate required entry types and different currencies
+// of the source entries. Returns the entries of the source by type, the adjustments of the closed accounting
+// periods and the recomputation corrections are added to the adjusted entries.
+func (h *accountingIntegrityChecker) checkEntryTypes(
+	list []*billingpb.AccountingEntry,
+	required []string,
+) map[string]*billingpb.AccountingEntry {
+	source := list[0].Source
+	entries := make(map[string]*billingpb.AccountingEntry, len(list))
+	counts := make(map[string]int, len(list))
+
+	var adjustments []*billingpb.AccountingEntry
+
+	for _, entry := range list {
+		if intPkg.IsAccountingAdjustment(entry) {
+			adjustments = append(adjustments, entry)
+			continue
+		}
+
+		counts[entry.Type]++
+
+		if _, ok := entries[entry.Type]; !ok {
+			entries[entry.Type] = entry
+		}
+
+		if entry.Currency != list[0].Currency {
+			h.addViolation(
+				source,
+				intPkg.AccountingIntegrityViolationCurrencyMismatch,
+				entry.Type,
+				"",
+				0,
+				entry.Amount,
+				entry.Currency,
+			)
+		}
+	}
+
+	for _, adjustment := range adjustments {
+		entry, ok := entries[adjustment.Type]
+
+		if !ok {
+			counts[adjustment.Type]++
+			entries[adjustment.Type] = adjustment
+			continue
+		}
+
+		entries[adjustment.Type] = h.addAccountingAdjustment(entry, adjustment)
+	}
+
+	for _, entryType := range required {
+		switch {
+		case counts[entryType] == 0:
+			h.addViolation(source, intPkg.AccountingIntegrityViolationMissingEntry, entryType, "", 1, 0, "")
+		case counts[entryType] > 1:
+			h.addViolation(
+				source,
+				intPkg.AccountingIntegrityViolationDuplicateEntry,
+				entryType,
+				"",
+				1,
+				float64(counts[entryType]),
+				"",
+			)
+		}
+	}
+
+	return entries
+}
+
+// addAccountingAdjustment returns the copy of the entry with amounts of the adjustment added.
+func (h *accountingIntegrityChecker) addAccountingAdjustment(
+	entry, adjustment *billingpb.AccountingEntry,
+) *billingpb.AccountingEntry {
+	adjusted := protobuf.Clone(entry).(*billingpb.AccountingEntry)
+	adjusted.Amount = h.newMoney(entry.Amount, entry.Currency).
+		Add(h.newMoney(adjustment.Amount, entry.Currency)).
+		Float64()
+	adjusted.OriginalAmount = h.newMoney(entry.OriginalAmount, entry.OriginalCurrency).
+		Add(h.newMoney(adjustment.OriginalAmount, entry.OriginalCurrency)).
+		Float64()
+	adjusted.LocalAmount = h.newMoney(entry.LocalAmount, entry.LocalCurrency).
+		Add(h.newMoney(adjustment.LocalAmount, entry.LocalCurrency)).
+		Float64()
+
+	return adjusted
+}
+
+// checkIdentity adds violation if the difference of the expected and actual amounts
+// is greater than the minor unit of the currency.
+func (h *accountingIntegrityChecker) checkIdentity(
+	source *billingpb.AccountingEntrySource,
+	identity string,
+	expected, actual intPkg.Money,
+) {
+	diff := expected.Sub(actual)
+
+	if diff.Sign() < 0 {
+		diff = diff.Neg()
+	}
+
+	tolerance := h.newMoney(math.Pow10(-int(expected.Precision())), expected.Currency())
+
+	if diff.Cmp(tolerance) <= 0 {
+		return
+	}
+
+	h.addViolation(
+		source,
+		intPkg.AccountingIntegrityViolationIdentityMismatch,
+		"",
+		identity,
+		expected.RoundTo(intPkg.MoneyAccountingPrecision).Float64(),
+		actual.RoundTo(intPkg.MoneyAccountingPrecision).Float64(),
+		expected.Currency(),
+	)
+}
+
+func (h *accountingIntegrityChecker) addViolation(
+	source *billingpb.AccountingEntrySource,
+	violationType, entryType, identity string,
+	expected, actual float64,
+	currency string,
+) {
+	h.report.Violations = append(h.report.Violations, &intPkg.AccountingIntegrityViolation{
+		SourceId:   source.Id,
+		SourceType: source.Type,
+		Type:       violationType,
+		EntryType:  entryType,
+		Identity:   identity,
+		Expected:   expected,
+		Actual:     actual,
+		Currency:   currency,
+	})
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	accountingPeriodDateFormat = "2006-01-02"
+)
+
+var (
+	accountingPeriodErrorOperatingCompanyNotFound = newBillingServerErrorMsg("ap000001", "operating company not found")
+	accountingPeriodErrorDatesInvalid             = newBillingServerErrorMsg("ap000002", "accounting period dates are invalid, closing of the future dates is not allowed")
+	accountingPeriodErrorOverlaps                 = newBillingServerErrorMsg("ap000003", "accounting period overlaps with the already closed period")
+	accountingPeriodErrorNotFound                 = newBillingServerErrorMsg("ap000004", "accounting period not found")
+	accountingPeriodErrorStatusInvalid            = newBillingServerErrorMsg("ap000005", "action is not allowed for the accounting period in the current status")
+	accountingPeriodErrorAccessDenied             = newBillingServerErrorMsg("ap000006", "only admin can close or reopen the accounting period")
+)
+
+// CloseAccountingPeriod closes the accounting period of the operating company. After closing the accounting entries
+// dated inside the period can't be added or changed, all late adjustments are written into the current open period.
+func (s *Service) CloseAccountingPeriod(
+	ctx context.Context,
+	req *intPkg.CloseAccountingPeriodRequest,
+	res *intPkg.AccountingPeriodResponse,
+) error {
+	if !s.hasAdminRole(ctx, req.UserId, billingpb.RoleSystemAdmin) {
+		res.Status = billingpb.ResponseStatusForbidden
+		res.Message = accountingPeriodErrorAccessDenied
+		return nil
+	}
+
+	if _, err := s.operatingCompanyRepository.GetById(ctx, req.OperatingCompanyId); err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = accountingPeriodErrorOperatingCompanyNotFound
+		return nil
+	}
+
+	if !req.DateFrom.Before(req.DateTo) || req.DateTo.After(time.Now()) {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = accountingPeriodErrorDatesInvalid
+		return nil
+	}
+
+	periods, err := s.accountingPeriodRepository.FindByOperatingCompany(
+		ctx,
+		req.OperatingCompanyId,
+		intPkg.AccountingPeriodStatusClosed,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	for _, period := range periods {
+		if period.Overlaps(req.DateFrom, req.DateTo) {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = accountingPeriodErrorOverlaps
+			return nil
+		}
+	}
+
+	now := time.Now()
+	period := &intPkg.AccountingPeriod{
+		Id:                 primitive.NewObjectID(),
+		OperatingCompanyId: req.OperatingCompanyId,
+		DateFrom:           req.DateFrom,
+		DateTo:             req.DateTo,
+		Status:             intPkg.AccountingPeriodStatusClosed,
+		History: []*intPkg.AccountingPeriodHistoryItem{
+			{Action: intPkg.AccountingPeriodActionClose, UserId: req.UserId, Comment: req.Comment, CreatedAt: now},
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err = s.accountingPeriodRepository.Insert(ctx, period); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = period
+
+	return nil
+}
+
+// ReopenAccountingPeriod opens the closed accounting period, so the accounting entries dated inside it
+// can be added and changed again.
+func (s *Service) ReopenAccountingPeriod(
+	ctx context.Context,
+	req *intPkg.ReopenAccountingPeriodRequest,
+	res *intPkg.AccountingPeriodResponse,
+) error {
+	if !s.hasAdminRole(ctx, req.UserId, billingpb.RoleSystemAdmin) {
+		res.Status = billingpb.ResponseStatusForbidden
+		res.Message = accountingPeriodErrorAccessDenied
+		return nil
+	}
+
+	period, err := s.accountingPeriodRepository.GetById(ctx, req.Id)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = accountingPeriodErrorNotFound
+		return nil
+	}
+
+	if period.Status != intPkg.AccountingPeriodStatusClosed {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = accountingPeriodErrorStatusInvalid
+		return nil
+	}
+
+	now := time.Now()
+	period.Status = intPkg.AccountingPeriodStatusReopened
+	period.UpdatedAt = now
+	period.History = append(period.History, &intPkg.AccountingPeriodHistoryItem{
+		Action:    intPkg.AccountingPeriodActionReopen,
+		UserId:    req.UserId,
+		Comment:   req.Comment,
+		CreatedAt: now,
+	})
+
+	if err = s.accountingPeriodRepository.Update(ctx, period); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = period
+
+	return nil
+}
+
+// ListAccountingPeriods returns the accounting periods of the operating company filtered by status.
+func (s *Service) ListAccountingPeriods(
+	ctx context.Context,
+	req *intPkg.ListAccountingPeriodsRequest,
+	res *intPkg.ListAccountingPeriodsResponse,
+) error {
+	periods, err := s.accountingPeriodRepository.FindByOperatingCompany(ctx, req.OperatingCompanyId, req.Status)
+
+	if err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Items = periods
+
+	if res.Items == nil {
+		res.Items = []*intPkg.AccountingPeriod{}
+	}
+
+	return nil
+}
+
+// accountingPeriodLock looks up the closed accounting periods for the accounting entries.
+// The periods of the operating company are loaded once per lock.
+type accountingPeriodLock struct {
+	*Service
+	ctx     context.Context
+	periods map[string][]*intPkg.AccountingPeriod
+}
+
+func (s *Service) newAccountingPeriodLock(ctx context.Context) *accountingPeriodLock {
+	return &accountingPeriodLock{
+		Service: s,
+		ctx:     ctx,
+		periods: make(map[string][]*intPkg.AccountingPeriod),
+	}
+}
+
+// getClosedPeriod returns the closed accounting period containing the entry date or nil if the date is open.
+func (l *accountingPeriodLock) getClosedPeriod(entry *billingpb.AccountingEntry) (*intPkg.AccountingPeriod, error) {
+	if entry.OperatingCompanyId == "" || entry.CreatedAt == nil {
+		return nil, nil
+	}
+
+	periods, ok := l.periods[entry.OperatingCompanyId]
+
+	if !ok {
+		var err error
+		periods, err = l.accountingPeriodRepository.FindByOperatingCompany(
+			l.ctx,
+			entry.OperatingCompanyId,
+			intPkg.AccountingPeriodStatusClosed,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		l.periods[entry.OperatingCompanyId] = periods
+	}
+
+	if len(periods) == 0 {
+		return nil, nil
+	}
+
+	date, err := ptypes.Timestamp(entry.CreatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, period := range periods {
+		if period.Contains(date) {
+			return period, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// moveToOpenPeriod dates the entry into the current open period and marks it as the closed period adjustment.
+func (l *accountingPeriodLock) moveToOpenPeriod(entry *billingpb.AccountingEntry, period *intPkg.AccountingPeriod) {
+	reason := fmt.Sprintf(
+		"%s %s - %s",
+		intPkg.AccountingPeriodAdjustmentReason,
+		period.DateFrom.Format(accountingPeriodDateFormat),
+		period.DateTo.Format(accountingPeriodDateFormat),
+	)
+
+	if entry.Reason != "" {
+		reason += ": " + entry.Reason
+	}
+
+	entry.CreatedAt = ptypes.TimestampNow()
+	entry.Reason = reason
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/mongodb"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/internal/repository"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	rabbitmq "gopkg.in/ProtocolONE/rabbitmq.v1/pkg"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"strings"
+	"testing"
+	"time"
+)
+
+type AccountingPeriodTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant  *billingpb.Merchant
+	admin     *billingpb.UserRole
+	financier *billingpb.UserRole
+}
+
+func Test_AccountingPeriod(t *testing.T) {
+	suite.Run(t, new(AccountingPeriodTestSuite))
+}
+
+func (suite *AccountingPeriodTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New(
+		"file://../../migrations/tests",
+		cfg.MongoDsn)
+	assert.NoError(suite.T(), err, "Migrate init failed")
+
+	err = m.Up()
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	broker, err := rabbitmq.NewBroker(cfg.BrokerAddress)
+
+	if err != nil {
+		suite.FailNow("Creating RabbitMQ publisher failed", "%v", err)
+	}
+
+	redisClient := database.NewRedis(
+		&redis.Options{
+			Addr:     cfg.RedisHost,
+			Password: cfg.RedisPassword,
+		},
+	)
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		broker,
+		redisClient,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	suite.merchant, _, _, _ = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+
+	suite.admin = &billingpb.UserRole{
+		Id:     primitive.NewObjectID().Hex(),
+		UserId: primitive.NewObjectID().Hex(),
+		Role:   billingpb.RoleSystemAdmin,
+	}
+	suite.financier = &billingpb.UserRole{
+		Id:     primitive.NewObjectID().Hex(),
+		UserId: primitive.NewObjectID().Hex(),
+		Role:   billingpb.RoleSystemFinancial,
+	}
+
+	for _, role := range []*billingpb.UserRole{suite.admin, suite.financier} {
+		if err := suite.service.userRoleRepository.AddAdminUser(context.TODO(), role); err != nil {
+			suite.FailNow("Insert admin user failed", "%v", err)
+		}
+	}
+}
+
+func (suite *AccountingPeriodTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *AccountingPeriodTestSuite) closePeriod(from, to time.Time) *intPkg.AccountingPeriod {
+	res := &intPkg.AccountingPeriodResponse{}
+	err := suite.service.CloseAccountingPeriod(
+		context.TODO(),
+		&intPkg.CloseAccountingPeriodRequest{
+			OperatingCompanyId: suite.merchant.OperatingCompanyId,
+			UserId:             suite.admin.UserId,
+			DateFrom:           from,
+			DateTo:             to,
+		},
+		res,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+
+	return res.Item
+}
+
+func (suite *AccountingPeriodTestSuite) TestAccountingPeriod_Close_Ok() {
+	to := time.Now().AddDate(0, 0, -1)
+	period := suite.closePeriod(to.AddDate(0, -1, 0), to)
+	assert.Equal(suite.T(), intPkg.AccountingPeriodStatusClosed, period.Status)
+	assert.Len(suite.T(), period.History, 1)
+
+	res := &intPkg.ListAccountingPeriodsResponse{}
+	err := suite.service.ListAccountingPeriods(
+		context.TODO(),
+		&intPkg.ListAccountingPeriodsRequest{OperatingCompanyId: suite.merchant.OperatingCompanyId},
+		res,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Len(suite.T(), res.Items, 1)
+}
+
+func (suite *AccountingPeriodTestSuite) TestAccountingPeriod_Close_Error() {
+	to := time.Now().AddDate(0, 0, -1)
+	suite.closePeriod(to.AddDate(0, -1, 0), to)
+
+	req := &intPkg.CloseAccountingPeriodRequest{
+		OperatingCompanyId: suite.merchant.OperatingCompanyId,
+		UserId:             suite.financier.UserId,
+		DateFrom:           to.AddDate(0, 0, -5),
+		DateTo:             to.AddDate(0, 0, 5),
+	}
+	res := &intPkg.AccountingPeriodResponse{}
+	err := suite.service.CloseAccountingPeriod(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+	assert.Equal(suite.T(), accountingPeriodErrorAccessDenied, res.Message)
+
+	req.UserId = suite.admin.UserId
+	res = &intPkg.AccountingPeriodResponse{}
+	err = suite.service.CloseAccountingPeriod(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), accountingPeriodErrorDatesInvalid, res.Message)
+
+	req.DateTo = to.AddDate(0, 0, -1)
+	res = &intPkg.AccountingPeriodResponse{}
+	err = suite.service.CloseAccountingPeriod(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), accountingPeriodErrorOverlaps, res.Message)
+
+	req.OperatingCompanyId = primitive.NewObjectID().Hex()
+	res = &intPkg.AccountingPeriodResponse{}
+	err = suite.service.CloseAccountingPeriod(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, res.Status)
+	assert.Equal(suite.T(), accountingPeriodErrorOperatingCompanyNotFound, res.Message)
+}
+
+func (suite *AccountingPeriodTestSuite) TestAccountingPeriod_Reopen_Ok() {
+	to := time.Now().AddDate(0, 0, -1)
+	period := suite.closePeriod(to.AddDate(0, -1, 0), to)
+
+	req := &intPkg.ReopenAccountingPeriodRequest{Id: period.Id.Hex(), UserId: suite.financier.UserId}
+	res := &intPkg.AccountingPeriodResponse{}
+	err := suite.service.ReopenAccountingPeriod(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+
+	req.UserId = suite.admin.UserId
+	req.Comment = "late chargebacks"
+	res = &intPkg.AccountingPeriodResponse{}
+	err = suite.service.ReopenAccountingPeriod(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Equal(suite.T(), intPkg.AccountingPeriodStatusReopened, res.Item.Status)
+	assert.Len(suite.T(), res.Item.History, 2)
+
+	res = &intPkg.AccountingPeriodResponse{}
+	err = suite.service.ReopenAccountingPeriod(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), accountingPeriodErrorStatusInvalid, res.Message)
+
+	// the dates of the reopened period can be closed again
+	suite.closePeriod(to.AddDate(0, -1, 0), to)
+}
+
+func (suite *AccountingPeriodTestSuite) TestAccountingPeriod_AddEntry_MovedToOpenPeriod() {
+	to := time.Now().AddDate(0, 0, -1)
+	suite.closePeriod(to.AddDate(0, -1, 0), to)
+
+	req := &billingpb.CreateAccountingEntryRequest{
+		Type:       pkg.AccountingEntryTypeMerchantRoyaltyCorrection,
+		MerchantId: suite.merchant.Id,
+		Amount:     10,
+		Currency:   "RUB",
+		Reason:     "compensation",
+		Date:       to.AddDate(0, 0, -3).Unix(),
+		Status:     pkg.BalanceTransactionStatusAvailable,
+	}
+	rsp := &billingpb.CreateAccountingEntryResponse{}
+	err := suite.service.createAccountingEntry(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	entry, err := suite.service.accountingRepository.GetById(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), intPkg.IsAccountingPeriodAdjustment(entry))
+	assert.True(suite.T(), strings.HasSuffix(entry.Reason, ": compensation"))
+
+	createdAt, err := ptypes.Timestamp(entry.CreatedAt)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), createdAt.After(to))
+
+	req.Date = time.Now().Unix()
+	rsp = &billingpb.CreateAccountingEntryResponse{}
+	err = suite.service.createAccountingEntry(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), "compensation", rsp.Item.Reason)
+}
+
+func (suite *AccountingPeriodTestSuite) TestAccountingPeriod_ReverseEntries_Adjustment() {
+	to := time.Now().AddDate(0, 0, -1)
+	closedDate, _ := ptypes.TimestampProto(to.AddDate(0, 0, -3))
+	source := &billingpb.AccountingEntrySource{Id: primitive.NewObjectID().Hex(), Type: repository.CollectionOrder}
+
+	locked := &billingpb.AccountingEntry{
+		Id:                 primitive.NewObjectID().Hex(),
+		Object:             pkg.ObjectTypeBalanceTransaction,
+		Type:               pkg.AccountingEntryTypeRealTaxFee,
+		Source:             source,
+		MerchantId:         suite.merchant.Id,
+		Amount:             10,
+		Currency:           "RUB",
+		OriginalAmount:     10,
+		OriginalCurrency:   "RUB",
+		LocalAmount:        10,
+		LocalCurrency:      "RUB",
+		Status:             pkg.BalanceTransactionStatusAvailable,
+		CreatedAt:          closedDate,
+		OperatingCompanyId: suite.merchant.OperatingCompanyId,
+	}
+	open := &billingpb.AccountingEntry{
+		Id:                 primitive.NewObjectID().Hex(),
+		Object:             pkg.ObjectTypeBalanceTransaction,
+		Type:               pkg.AccountingEntryTypeRealRefundTaxFee,
+		Source:             source,
+		MerchantId:         suite.merchant.Id,
+		Amount:             5,
+		Currency:           "RUB",
+		Status:             pkg.BalanceTransactionStatusAvailable,
+		CreatedAt:          ptypes.TimestampNow(),
+		OperatingCompanyId: suite.merchant.OperatingCompanyId,
+	}
+	err := suite.service.accountingRepository.MultipleInsert(context.TODO(), []*billingpb.AccountingEntry{locked, open})
+	assert.NoError(suite.T(), err)
+
+	suite.closePeriod(to.AddDate(0, -1, 0), to)
+
+	locked.Amount = 12.5
+	locked.OriginalAmount = 12.5
+	locked.LocalAmount = 12.5
+	open.Amount = 6
+	err = suite.service.reverseAccountingEntries(context.TODO(), []*billingpb.AccountingEntry{locked, open})
+	assert.NoError(suite.T(), err)
+
+	stored, err := suite.service.accountingRepository.GetById(context.TODO(), locked.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), float64(10), stored.Amount)
+
+	stored, err = suite.service.accountingRepository.GetById(context.TODO(), open.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), float64(5), stored.Amount)
+
+	entries, err := suite.service.accountingRepository.FindBySource(context.TODO(), source.Id, source.Type)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), entries, 6)
+
+	amounts := map[string]float64{}
+
+	for _, entry := range entries {
+		amounts[entry.Type] += entry.Amount
+
+		if entry.Id == locked.Id || entry.Id == open.Id {
+			continue
+		}
+
+		assert.True(suite.T(), intPkg.IsAccountingAdjustment(entry))
+
+		if entry.Type == pkg.AccountingEntryTypeRealTaxFee {
+			assert.True(suite.T(), intPkg.IsAccountingPeriodAdjustment(entry))
+		} else {
+			assert.False(suite.T(), intPkg.IsAccountingPeriodAdjustment(entry))
+			assert.Equal(suite.T(), open.CreatedAt.Seconds, entry.CreatedAt.Seconds)
+		}
+	}
+
+	assert.Equal(suite.T(), 12.5, amounts[pkg.AccountingEntryTypeRealTaxFee])
+	assert.Equal(suite.T(), float64(6), amounts[pkg.AccountingEntryTypeRealRefundTaxFee])
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+)
+
+type ibanRule struct {
+	length int
+	sepa   bool
+}
+
+var (
+	bankAccountBicFormat        = regexp.MustCompile(`^[A-Z]{6}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+	bankAccountIbanFormat       = regexp.MustCompile(`^[A-Z]{2}\d{2}[A-Z0-9]+$`)
+	bankAccountAbaFormat        = regexp.MustCompile(`^\d{9}$`)
+	bankAccountAchAccountFormat = regexp.MustCompile(`^[0-9A-Z-]{1,17}$`)
+	bankAccountNumberFormat     = regexp.MustCompile(`^[0-9A-Z-]{4,34}$`)
+
+	ibanRules = map[string]*ibanRule{
+		"AD": {length: 24, sepa: true},
+		"AT": {length: 20, sepa: true},
+		"BE": {length: 16, sepa: true},
+		"BG": {length: 22, sepa: true},
+		"CH": {length: 21, sepa: true},
+		"CY": {length: 28, sepa: true},
+		"CZ": {length: 24, sepa: true},
+		"DE": {length: 22, sepa: true},
+		"DK": {length: 18, sepa: true},
+		"EE": {length: 20, sepa: true},
+		"ES": {length: 24, sepa: true},
+		"FI": {length: 18, sepa: true},
+		"FR": {length: 27, sepa: true},
+		"GB": {length: 22, sepa: true},
+		"GI": {length: 23, sepa: true},
+		"GR": {length: 27, sepa: true},
+		"HR": {length: 21, sepa: true},
+		"HU": {length: 28, sepa: true},
+		"IE": {length: 22, sepa: true},
+		"IS": {length: 26, sepa: true},
+		"IT": {length: 27, sepa: true},
+		"LI": {length: 21, sepa: true},
+		"LT": {length: 20, sepa: true},
+		"LU": {length: 20, sepa: true},
+		"LV": {length: 21, sepa: true},
+		"MC": {length: 27, sepa: true},
+		"MT": {length: 31, sepa: true},
+		"NL": {length: 18, sepa: true},
+		"NO": {length: 15, sepa: true},
+		"PL": {length: 28, sepa: true},
+		"PT": {length: 25, sepa: true},
+		"RO": {length: 24, sepa: true},
+		"SE": {length: 24, sepa: true},
+		"SI": {length: 19, sepa: true},
+		"SK": {length: 24, sepa: true},
+		"SM": {length: 27, sepa: true},
+		"VA": {length: 22, sepa: true},
+		"AE": {length: 23},
+		"AZ": {length: 28},
+		"BH": {length: 22},
+		"BR": {length: 29},
+		"GE": {length: 22},
+		"IL": {length: 23},
+		"JO": {length: 30},
+		"KW": {length: 30},
+		"KZ": {length: 20},
+		"LB": {length: 28},
+		"MD": {length: 24},
+		"QA": {length: 29},
+		"RS": {length: 22},
+		"SA": {length: 24},
+		"TR": {length: 26},
+		"UA": {length: 29},
+	}
+)
+
+// normalizeBankAccount removes the spaces from the bank account identifier and converts it to upper case.
+func normalizeBankAccount(value string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(value), ""))
+}
+
+// isIbanLike checks that the account number looks like IBAN, so it must be validated as IBAN.
+func isIbanLike(value string) bool {
+	value = normalizeBankAccount(value)
+	return bankAccountIbanFormat.MatchString(value) && ibanRules[value[:2]] != nil
+}
+
+// validateIban checks the country length and the ISO 7064 mod 97-10 check digits of the IBAN.
+func validateIban(value string) bool {
+	value = normalizeBankAccount(value)
+
+	if !bankAccountIbanFormat.MatchString(value) {
+		return false
+	}
+
+	rule, ok := ibanRules[value[:2]]
+
+	if !ok || len(value) != rule.length {
+		return false
+	}
+
+	remainder := 0
+
+	for _, r := range value[4:] + value[:4] {
+		var digits int
+
+		if r >= 'A' && r <= 'Z' {
+			digits = int(r-'A') + 10
+			remainder = (remainder*100 + digits) % 97
+		} else {
+			digits = int(r - '0')
+			remainder = (remainder*10 + digits) % 97
+		}
+	}
+
+	return remainder == 1
+}
+
+// isSepaIban checks that the IBAN is valid and belongs to the country of the SEPA scheme.
+func isSepaIban(value string) bool {
+	if !validateIban(value) {
+		return false
+	}
+
+	return ibanRules[normalizeBankAccount(value)[:2]].sepa
+}
+
+// validateBic checks the format of the ISO 9362 bank identifier code.
+func validateBic(value string) bool {
+	return bankAccountBicFormat.MatchString(normalizeBankAccount(value))
+}
+
+// validateAbaRoutingNumber checks the format and the check digit of the ABA routing number of the US bank.
+func validateAbaRoutingNumber(value string) bool {
+	value = normalizeBankAccount(value)
+
+	if !bankAccountAbaFormat.MatchString(value) {
+		return false
+	}
+
+	weights := []int{3, 7, 1}
+	sum := 0
+
+	for i, r := range value {
+		sum += int(r-'0') * weights[i%3]
+	}
+
+	return sum%10 == 0
+}
+
+// validateAchAccountNumber checks that the account number fits the DFI account number field of the NACHA entry.
+func validateAchAccountNumber(value string) bool {
+	return bankAccountAchAccountFormat.MatchString(normalizeBankAccount(value))
+}
+
+// validateBankAccountNumber checks the account number of the SWIFT transfer, the IBAN-like numbers are validated as IBAN.
+func validateBankAccountNumber(value string) bool {
+	if isIbanLike(value) {
+		return validateIban(value)
+	}
+
+	return bankAccountNumberFormat.MatchString(normalizeBankAccount(value))
+}
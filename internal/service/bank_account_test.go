@@ -0,0 +1,98 @@
+package service
+
+import (
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_BankAccountIbanValidation(t *testing.T) {
+	cases := []struct {
+		iban  string
+		valid bool
+		sepa  bool
+	}{
+		{"DE89370400440532013000", true, true},
+		{"de89 3704 0044 0532 0130 00", true, true},
+		{"DE89370400440532013001", false, false},
+		{"DE8937040044053201300", false, false},
+		{"GB82WEST12345698765432", true, true},
+		{"FR1420041010050500013M02606", true, true},
+		{"AE070331234567890123456", true, false},
+		{"XX89370400440532013000", false, false},
+		{"1234567890", false, false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.valid, validateIban(c.iban), c.iban)
+		assert.Equal(t, c.sepa, isSepaIban(c.iban), c.iban)
+	}
+}
+
+func Test_BankAccountBicValidation(t *testing.T) {
+	assert.True(t, validateBic("DEUTDEFF"))
+	assert.True(t, validateBic("deutdeff500"))
+	assert.False(t, validateBic("DEUT1EFF"))
+	assert.False(t, validateBic("DEUTDEF"))
+	assert.False(t, validateBic(""))
+}
+
+func Test_BankAccountAbaRoutingNumberValidation(t *testing.T) {
+	assert.True(t, validateAbaRoutingNumber("021000021"))
+	assert.True(t, validateAbaRoutingNumber("011000015"))
+	assert.False(t, validateAbaRoutingNumber("021000022"))
+	assert.False(t, validateAbaRoutingNumber("02100002"))
+	assert.False(t, validateAbaRoutingNumber("02100002A"))
+}
+
+func Test_PayoutBatchNachaFile(t *testing.T) {
+	service := &Service{}
+	batch := &intPkg.PayoutBatch{
+		Id:       primitive.NewObjectID(),
+		Format:   intPkg.PayoutBatchFormatNacha,
+		Currency: "USD",
+		Debtor: &intPkg.PayoutBatchDebtor{
+			Name:          "PaySuper Inc",
+			BankName:      "Chase",
+			RoutingNumber: "021000021",
+			CompanyId:     "1234567890",
+		},
+		ExecutionDate: time.Now().Add(24 * time.Hour),
+		CreatedAt:     time.Now(),
+	}
+	items := []*payoutBatchItem{
+		{
+			payout:  &billingpb.PayoutDocument{Id: primitive.NewObjectID().Hex(), Currency: "USD"},
+			banking: &billingpb.MerchantBanking{AccountNumber: "123456789", CorrespondentAccount: "011000015"},
+			name:    "Merchant One",
+			amount:  service.newMoney(100.5, "USD"),
+		},
+		{
+			payout:  &billingpb.PayoutDocument{Id: primitive.NewObjectID().Hex(), Currency: "USD"},
+			banking: &billingpb.MerchantBanking{AccountNumber: "987654321", CorrespondentAccount: "021000021"},
+			name:    "Merchant Two",
+			amount:  service.newMoney(20, "USD"),
+		},
+	}
+
+	content, err := buildNachaPayoutFile(batch, items)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	assert.Len(t, lines, payoutBatchNachaBlockingSize)
+
+	for _, line := range lines {
+		assert.Len(t, line, payoutBatchNachaLineLength)
+	}
+
+	assert.Equal(t, "6", lines[2][:1])
+	assert.Equal(t, "0000010050", lines[2][29:39])
+	assert.Equal(t, "8", lines[4][:1])
+	assert.Equal(t, "0003200003", lines[4][10:20])
+	assert.Equal(t, "000000012050", lines[4][32:44])
+	assert.Equal(t, "9", lines[5][:1])
+}
@@ -0,0 +1,391 @@
+package service
+
+import (
+	"context"
+	"github.com/golang/protobuf/ptypes"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/currenciespb"
+	"sort"
+	"time"
+)
+
+var (
+	fxRevaluationErrorOperatingCompanyNotFound = newBillingServerErrorMsg("fx000001", "operating company not found")
+	fxRevaluationErrorPeriodInvalid            = newBillingServerErrorMsg("fx000002", "fx revaluation report period is invalid")
+	fxRevaluationErrorValuationDateInvalid     = newBillingServerErrorMsg("fx000003", "valuation date must be between the end of the period and the current date")
+
+	// fxRevaluationExposureEntries contains the sign of the FX exposure of the entries with original amount
+	// in the payment currency: payments increase the exposure and refunds decrease it
+	fxRevaluationExposureEntries = map[string]int{
+		pkg.AccountingEntryTypeRealGrossRevenue: 1,
+		pkg.AccountingEntryTypeRealRefund:       -1,
+	}
+	fxRevaluationMarginEntries = map[string]bool{
+		pkg.AccountingEntryTypePsGrossRevenueFx:   true,
+		pkg.AccountingEntryTypePsMerchantRefundFx: true,
+	}
+)
+
+// GetFxRevaluationReport returns the realized and unrealized FX gains and losses of the operating company
+// for the payments and refunds booked in the period.
+func (s *Service) GetFxRevaluationReport(
+	ctx context.Context,
+	req *intPkg.GetFxRevaluationReportRequest,
+	res *intPkg.GetFxRevaluationReportResponse,
+) error {
+	if !req.DateFrom.Before(req.DateTo) {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = fxRevaluationErrorPeriodInvalid
+		return nil
+	}
+
+	now := time.Now()
+	valuationDate := req.ValuationDate
+
+	if valuationDate.IsZero() {
+		valuationDate = now
+	}
+
+	if valuationDate.Before(req.DateTo) || valuationDate.After(now) {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = fxRevaluationErrorValuationDateInvalid
+		return nil
+	}
+
+	if _, err := s.operatingCompanyRepository.GetById(ctx, req.OperatingCompanyId); err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = fxRevaluationErrorOperatingCompanyNotFound
+		return nil
+	}
+
+	entries, err := s.accountingRepository.FindByOperatingCompanyDates(ctx, req.OperatingCompanyId, req.DateFrom, req.DateTo)
+
+	if err != nil {
+		return err
+	}
+
+	builder := s.newFxRevaluationReportBuilder(ctx, valuationDate)
+
+	if err = builder.addEntries(entries); err != nil {
+		return err
+	}
+
+	report, err := builder.build()
+
+	if err != nil {
+		return err
+	}
+
+	report.OperatingCompanyId = req.OperatingCompanyId
+	report.DateFrom = req.DateFrom
+	report.DateTo = req.DateTo
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = report
+
+	return nil
+}
+
+// fxRevaluationPosition accumulates the amounts of the entries with the same original and booked currencies.
+type fxRevaluationPosition struct {
+	originalCurrency string
+	currency         string
+	// realizedOriginal contains the original amounts paid to merchants by the payout date
+	realizedOriginal   map[time.Time]intPkg.Money
+	realizedBooked     intPkg.Money
+	unrealizedOriginal intPkg.Money
+	unrealizedBooked   intPkg.Money
+	margin             intPkg.Money
+}
+
+type fxRevaluationReportBuilder struct {
+	*Service
+	ctx           context.Context
+	valuationDate time.Time
+	entriesCount  int32
+	positions     map[string]*fxRevaluationPosition
+	// sourceCurrencies contains the original currency of the payment or refund by source
+	sourceCurrencies map[string]string
+	royaltyReports   map[string][]*billingpb.RoyaltyReport
+	payouts          map[string]*billingpb.PayoutDocument
+}
+
+func (s *Service) newFxRevaluationReportBuilder(ctx context.Context, valuationDate time.Time) *fxRevaluationReportBuilder {
+	return &fxRevaluationReportBuilder{
+		Service:          s,
+		ctx:              ctx,
+		valuationDate:    valuationDate,
+		positions:        make(map[string]*fxRevaluationPosition),
+		sourceCurrencies: make(map[string]string),
+		royaltyReports:   make(map[string][]*billingpb.RoyaltyReport),
+		payouts:          make(map[string]*billingpb.PayoutDocument),
+	}
+}
+
+func (b *fxRevaluationReportBuilder) addEntries(entries []*billingpb.AccountingEntry) error {
+	for _, entry := range entries {
+		sign, ok := fxRevaluationExposureEntries[entry.Type]
+
+		if !ok || entry.OriginalCurrency == "" || entry.OriginalCurrency == entry.Currency {
+			continue
+		}
+
+		b.sourceCurrencies[entry.Source.Type+entry.Source.Id] = entry.OriginalCurrency
+
+		paidAt, err := b.getPayoutDate(entry)
+
+		if err != nil {
+			return err
+		}
+
+		position := b.getPosition(entry.OriginalCurrency, entry.Currency)
+		original := b.newMoney(entry.OriginalAmount, entry.OriginalCurrency)
+		booked := b.newMoney(entry.Amount, entry.Currency)
+
+		if sign < 0 {
+			original = original.Neg()
+			booked = booked.Neg()
+		}
+
+		if paidAt.IsZero() {
+			position.unrealizedOriginal = position.unrealizedOriginal.Add(original)
+			position.unrealizedBooked = position.unrealizedBooked.Add(booked)
+		} else {
+			amount, ok := position.realizedOriginal[paidAt]
+
+			if !ok {
+				amount = b.newMoney(0, entry.OriginalCurrency)
+			}
+
+			position.realizedOriginal[paidAt] = amount.Add(original)
+			position.realizedBooked = position.realizedBooked.Add(booked)
+		}
+
+		b.entriesCount++
+	}
+
+	for _, entry := range entries {
+		if !fxRevaluationMarginEntries[entry.Type] {
+			continue
+		}
+
+		originalCurrency, ok := b.sourceCurrencies[entry.Source.Type+entry.Source.Id]
+
+		if !ok {
+			continue
+		}
+
+		position := b.getPosition(originalCurrency, entry.Currency)
+		position.margin = position.margin.Add(b.newMoney(entry.Amount, entry.Currency))
+	}
+
+	return nil
+}
+
+func (b *fxRevaluationReportBuilder) getPosition(originalCurrency, currency string) *fxRevaluationPosition {
+	key := originalCurrency + currency
+	position, ok := b.positions[key]
+
+	if !ok {
+		position = &fxRevaluationPosition{
+			originalCurrency:   originalCurrency,
+			currency:           currency,
+			realizedOriginal:   make(map[time.Time]intPkg.Money),
+			realizedBooked:     b.newMoney(0, currency),
+			unrealizedOriginal: b.newMoney(0, originalCurrency),
+			unrealizedBooked:   b.newMoney(0, currency),
+			margin:             b.newMoney(0, currency),
+		}
+		b.positions[key] = position
+	}
+
+	return position
+}
+
+// getPayoutDate returns the date of the payout containing the entry or zero time if the entry amount
+// wasn't paid to the merchant on the valuation date.
+func (b *fxRevaluationReportBuilder) getPayoutDate(entry *billingpb.AccountingEntry) (time.Time, error) {
+	reports, ok := b.royaltyReports[entry.MerchantId]
+
+	if !ok {
+		var err error
+		reports, err = b.royaltyReportRepository.FindByMerchantStatusDates(b.ctx, entry.MerchantId, nil, 0, 0, 0, 0)
+
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		b.royaltyReports[entry.MerchantId] = reports
+	}
+
+	date, err := ptypes.Timestamp(entry.CreatedAt)
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, report := range reports {
+		if report.PayoutDocumentId == "" || report.Currency != entry.Currency {
+			continue
+		}
+
+		from, err := ptypes.Timestamp(report.PeriodFrom)
+
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		to, err := ptypes.Timestamp(report.PeriodTo)
+
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if date.Before(from) || date.After(to) {
+			continue
+		}
+
+		payout, ok := b.payouts[report.PayoutDocumentId]
+
+		if !ok {
+			payout, err = b.payoutRepository.GetById(b.ctx, report.PayoutDocumentId)
+
+			if err != nil {
+				return time.Time{}, err
+			}
+
+			b.payouts[report.PayoutDocumentId] = payout
+		}
+
+		if payout.Status != pkg.PayoutDocumentStatusPaid || payout.PaidAt == nil {
+			return time.Time{}, nil
+		}
+
+		paidAt, err := ptypes.Timestamp(payout.PaidAt)
+
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if paidAt.After(b.valuationDate) {
+			return time.Time{}, nil
+		}
+
+		return paidAt.Truncate(24 * time.Hour), nil
+	}
+
+	return time.Time{}, nil
+}
+
+func (b *fxRevaluationReportBuilder) build() (*intPkg.FxRevaluationReport, error) {
+	report := &intPkg.FxRevaluationReport{
+		ValuationDate: b.valuationDate,
+		EntriesCount:  b.entriesCount,
+		Items:         []*intPkg.FxRevaluationReportItem{},
+		Totals:        []*intPkg.FxRevaluationReportTotal{},
+		CreatedAt:     time.Now(),
+	}
+	totals := make(map[string]*intPkg.FxRevaluationReportTotal)
+
+	for _, position := range b.positions {
+		realizedOriginal := b.newMoney(0, position.originalCurrency)
+		realized := b.newMoney(0, position.currency)
+
+		for date, amount := range position.realizedOriginal {
+			exchanged, err := b.exchangeFxRevaluationAmount(amount, position.currency, date)
+
+			if err != nil {
+				return nil, err
+			}
+
+			realizedOriginal = realizedOriginal.Add(amount)
+			realized = realized.Add(exchanged)
+		}
+
+		unrealized, err := b.exchangeFxRevaluationAmount(position.unrealizedOriginal, position.currency, b.valuationDate)
+
+		if err != nil {
+			return nil, err
+		}
+
+		realizedGainLoss := realized.Sub(position.realizedBooked).Round()
+		unrealizedGainLoss := unrealized.Sub(position.unrealizedBooked).Round()
+
+		report.Items = append(report.Items, &intPkg.FxRevaluationReportItem{
+			OriginalCurrency:         position.originalCurrency,
+			Currency:                 position.currency,
+			RealizedOriginalAmount:   realizedOriginal.Round().Float64(),
+			RealizedBookedAmount:     position.realizedBooked.Round().Float64(),
+			RealizedAmount:           realized.Round().Float64(),
+			RealizedGainLoss:         realizedGainLoss.Float64(),
+			UnrealizedOriginalAmount: position.unrealizedOriginal.Round().Float64(),
+			UnrealizedBookedAmount:   position.unrealizedBooked.Round().Float64(),
+			UnrealizedAmount:         unrealized.Round().Float64(),
+			UnrealizedGainLoss:       unrealizedGainLoss.Float64(),
+			ConversionMargin:         position.margin.Round().Float64(),
+		})
+
+		total, ok := totals[position.currency]
+
+		if !ok {
+			total = &intPkg.FxRevaluationReportTotal{Currency: position.currency}
+			totals[position.currency] = total
+			report.Totals = append(report.Totals, total)
+		}
+
+		total.RealizedGainLoss = b.newMoney(total.RealizedGainLoss, total.Currency).Add(realizedGainLoss).Float64()
+		total.UnrealizedGainLoss = b.newMoney(total.UnrealizedGainLoss, total.Currency).Add(unrealizedGainLoss).Float64()
+		total.ConversionMargin = b.newMoney(total.ConversionMargin, total.Currency).Add(position.margin.Round()).Float64()
+		total.Total = b.newMoney(total.RealizedGainLoss, total.Currency).
+			Add(b.newMoney(total.UnrealizedGainLoss, total.Currency), b.newMoney(total.ConversionMargin, total.Currency)).
+			Float64()
+	}
+
+	sort.Slice(report.Items, func(i, j int) bool {
+		if report.Items[i].Currency != report.Items[j].Currency {
+			return report.Items[i].Currency < report.Items[j].Currency
+		}
+
+		return report.Items[i].OriginalCurrency < report.Items[j].OriginalCurrency
+	})
+	sort.Slice(report.Totals, func(i, j int) bool {
+		return report.Totals[i].Currency < report.Totals[j].Currency
+	})
+
+	return report, nil
+}
+
+// exchangeFxRevaluationAmount converts the original amount into the currency with the PaySuper rate on the date.
+func (b *fxRevaluationReportBuilder) exchangeFxRevaluationAmount(
+	amount intPkg.Money,
+	currency string,
+	date time.Time,
+) (intPkg.Money, error) {
+	if amount.IsZero() {
+		return b.newMoney(0, currency), nil
+	}
+
+	datetime, err := ptypes.TimestampProto(date)
+
+	if err != nil {
+		return intPkg.Money{}, err
+	}
+
+	// the rates service converts the positive amounts, the sign of net refunds is restored after conversion
+	exchanged, err := b.exchangeCurrencyByDateCommon(b.ctx, &currenciespb.ExchangeCurrencyByDateCommonRequest{
+		From:              amount.Currency(),
+		To:                currency,
+		RateType:          currenciespb.RateTypePaysuper,
+		ExchangeDirection: currenciespb.ExchangeDirectionBuy,
+		Amount:            amount.RoundTo(intPkg.MoneyAccountingPrecision).Float64() * float64(amount.Sign()),
+		Datetime:          datetime,
+	})
+
+	if err != nil {
+		return intPkg.Money{}, err
+	}
+
+	return b.newMoney(exchanged, currency).Mul(float64(amount.Sign())), nil
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/mongodb"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/internal/repository"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	rabbitmq "gopkg.in/ProtocolONE/rabbitmq.v1/pkg"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+	"time"
+)
+
+type FxRevaluationTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant *billingpb.Merchant
+}
+
+func Test_FxRevaluation(t *testing.T) {
+	suite.Run(t, new(FxRevaluationTestSuite))
+}
+
+func (suite *FxRevaluationTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New(
+		"file://../../migrations/tests",
+		cfg.MongoDsn)
+	assert.NoError(suite.T(), err, "Migrate init failed")
+
+	err = m.Up()
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	broker, err := rabbitmq.NewBroker(cfg.BrokerAddress)
+
+	if err != nil {
+		suite.FailNow("Creating RabbitMQ publisher failed", "%v", err)
+	}
+
+	redisClient := database.NewRedis(
+		&redis.Options{
+			Addr:     cfg.RedisHost,
+			Password: cfg.RedisPassword,
+		},
+	)
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		broker,
+		redisClient,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	suite.merchant, _, _, _ = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+}
+
+func (suite *FxRevaluationTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *FxRevaluationTestSuite) insertEntry(
+	entryType string,
+	source *billingpb.AccountingEntrySource,
+	amount, originalAmount float64,
+	date time.Time,
+) {
+	createdAt, err := ptypes.TimestampProto(date)
+	assert.NoError(suite.T(), err)
+
+	entry := &billingpb.AccountingEntry{
+		Id:                 primitive.NewObjectID().Hex(),
+		Object:             pkg.ObjectTypeBalanceTransaction,
+		Type:               entryType,
+		Source:             source,
+		MerchantId:         suite.merchant.Id,
+		Amount:             amount,
+		Currency:           "RUB",
+		OriginalAmount:     originalAmount,
+		OriginalCurrency:   "USD",
+		Status:             pkg.BalanceTransactionStatusAvailable,
+		CreatedAt:          createdAt,
+		OperatingCompanyId: suite.merchant.OperatingCompanyId,
+	}
+
+	if originalAmount == 0 {
+		entry.OriginalCurrency = ""
+	}
+
+	err = suite.service.insertAccountingEntries(context.TODO(), []*billingpb.AccountingEntry{entry})
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *FxRevaluationTestSuite) TestFxRevaluation_GetFxRevaluationReport_Ok() {
+	now := time.Now()
+	paid := &billingpb.AccountingEntrySource{Id: primitive.NewObjectID().Hex(), Type: repository.CollectionOrder}
+	unpaid := &billingpb.AccountingEntrySource{Id: primitive.NewObjectID().Hex(), Type: repository.CollectionOrder}
+	refund := &billingpb.AccountingEntrySource{Id: primitive.NewObjectID().Hex(), Type: repository.CollectionRefund}
+
+	suite.insertEntry(pkg.AccountingEntryTypeRealGrossRevenue, paid, 6000, 100, now.AddDate(0, 0, -10))
+	suite.insertEntry(pkg.AccountingEntryTypePsGrossRevenueFx, paid, 30, 0, now.AddDate(0, 0, -10))
+	suite.insertEntry(pkg.AccountingEntryTypeRealGrossRevenue, unpaid, 3000, 50, now.AddDate(0, 0, -3))
+	suite.insertEntry(pkg.AccountingEntryTypeRealRefund, refund, 620, 10, now.AddDate(0, 0, -2))
+
+	periodFrom, _ := ptypes.TimestampProto(now.AddDate(0, 0, -14))
+	periodTo, _ := ptypes.TimestampProto(now.AddDate(0, 0, -7))
+	paidAt, _ := ptypes.TimestampProto(now.AddDate(0, 0, -1))
+
+	payout := &billingpb.PayoutDocument{
+		Id:          primitive.NewObjectID().Hex(),
+		MerchantId:  suite.merchant.Id,
+		Balance:     6000,
+		Currency:    "RUB",
+		Status:      pkg.PayoutDocumentStatusPaid,
+		Description: "test payout document",
+		Destination: suite.merchant.Banking,
+		CreatedAt:   paidAt,
+		UpdatedAt:   paidAt,
+		ArrivalDate: paidAt,
+		PaidAt:      paidAt,
+	}
+	report := &billingpb.RoyaltyReport{
+		Id:                 primitive.NewObjectID().Hex(),
+		MerchantId:         suite.merchant.Id,
+		Totals:             &billingpb.RoyaltyReportTotals{PayoutAmount: 6000},
+		Status:             billingpb.RoyaltyReportStatusAccepted,
+		CreatedAt:          periodTo,
+		PeriodFrom:         periodFrom,
+		PeriodTo:           periodTo,
+		AcceptExpireAt:     periodTo,
+		Currency:           "RUB",
+		OperatingCompanyId: suite.merchant.OperatingCompanyId,
+		PayoutDocumentId:   payout.Id,
+	}
+	payout.SourceId = []string{report.Id}
+
+	err := suite.service.royaltyReportRepository.Insert(context.TODO(), report, "", pkg.RoyaltyReportChangeSourceAuto)
+	assert.NoError(suite.T(), err)
+	err = suite.service.payoutRepository.Insert(context.TODO(), payout, "127.0.0.1", payoutChangeSourceAdmin)
+	assert.NoError(suite.T(), err)
+
+	req := &intPkg.GetFxRevaluationReportRequest{
+		OperatingCompanyId: suite.merchant.OperatingCompanyId,
+		DateFrom:           now.AddDate(0, 0, -30),
+		DateTo:             now.Add(-time.Minute),
+	}
+	res := &intPkg.GetFxRevaluationReportResponse{}
+	err = suite.service.GetFxRevaluationReport(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.EqualValues(suite.T(), 3, res.Item.EntriesCount)
+	assert.Len(suite.T(), res.Item.Items, 1)
+
+	// the mock converts USD into RUB with the rate 63, the booked rates are 60 and 62
+	item := res.Item.Items[0]
+	assert.Equal(suite.T(), "USD", item.OriginalCurrency)
+	assert.Equal(suite.T(), "RUB", item.Currency)
+	assert.Equal(suite.T(), float64(100), item.RealizedOriginalAmount)
+	assert.Equal(suite.T(), float64(6000), item.RealizedBookedAmount)
+	assert.Equal(suite.T(), float64(6300), item.RealizedAmount)
+	assert.Equal(suite.T(), float64(300), item.RealizedGainLoss)
+	assert.Equal(suite.T(), float64(40), item.UnrealizedOriginalAmount)
+	assert.Equal(suite.T(), float64(2380), item.UnrealizedBookedAmount)
+	assert.Equal(suite.T(), float64(2520), item.UnrealizedAmount)
+	assert.Equal(suite.T(), float64(140), item.UnrealizedGainLoss)
+	assert.Equal(suite.T(), float64(30), item.ConversionMargin)
+
+	assert.Len(suite.T(), res.Item.Totals, 1)
+	assert.Equal(suite.T(), "RUB", res.Item.Totals[0].Currency)
+	assert.Equal(suite.T(), float64(470), res.Item.Totals[0].Total)
+}
+
+func (suite *FxRevaluationTestSuite) TestFxRevaluation_GetFxRevaluationReport_Error() {
+	now := time.Now()
+	req := &intPkg.GetFxRevaluationReportRequest{
+		OperatingCompanyId: suite.merchant.OperatingCompanyId,
+		DateFrom:           now,
+		DateTo:             now.AddDate(0, 0, -1),
+	}
+	res := &intPkg.GetFxRevaluationReportResponse{}
+	err := suite.service.GetFxRevaluationReport(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), fxRevaluationErrorPeriodInvalid, res.Message)
+
+	req.DateFrom = now.AddDate(0, 0, -2)
+	req.ValuationDate = now.AddDate(0, 0, -3)
+	res = &intPkg.GetFxRevaluationReportResponse{}
+	err = suite.service.GetFxRevaluationReport(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), fxRevaluationErrorValuationDateInvalid, res.Message)
+
+	req.OperatingCompanyId = primitive.NewObjectID().Hex()
+	req.ValuationDate = time.Time{}
+	res = &intPkg.GetFxRevaluationReportResponse{}
+	err = suite.service.GetFxRevaluationReport(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, res.Status)
+	assert.Equal(suite.T(), fxRevaluationErrorOperatingCompanyNotFound, res.Message)
+}
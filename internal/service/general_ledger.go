@@ -0,0 +1,643 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	generalLedgerDateFormat         = "2006-01-02"
+	generalLedgerFileNameFormat     = "general_ledger_%s_%s_%s.%s"
+	generalLedgerSaftVersion        = "2.00"
+	generalLedgerSaftNamespace      = "urn:StandardAuditFile-Taxation-Financial:2.00"
+	generalLedgerSaftSoftwareId     = "paysuper-billing-server"
+	generalLedgerSaftSoftwareName   = "PaySuper"
+	generalLedgerSaftJournalType    = "GL"
+	generalLedgerContentTypeCsv     = "text/csv"
+	generalLedgerContentTypeXml     = "application/xml"
+	generalLedgerCsvTotalLineMarker = "total"
+)
+
+var (
+	generalLedgerErrorOperatingCompanyNotFound = newBillingServerErrorMsg("gl000001", "operating company not found")
+	generalLedgerErrorAccessDenied             = newBillingServerErrorMsg("gl000002", "user is not allowed to change the chart of accounts")
+	generalLedgerErrorAccountsEmpty            = newBillingServerErrorMsg("gl000003", "chart of accounts must contain at least one entry type mapping")
+	generalLedgerErrorAccountsItemInvalid      = newBillingServerErrorMsg("gl000004", "chart of accounts item must have known entry type and different debit and credit accounts")
+	generalLedgerErrorAccountsItemDuplicate    = newBillingServerErrorMsg("gl000005", "entry type is mapped more than once")
+	generalLedgerErrorAccountsNotFound         = newBillingServerErrorMsg("gl000006", "chart of accounts of the operating company not found")
+	generalLedgerErrorPeriodInvalid            = newBillingServerErrorMsg("gl000007", "general ledger export period is invalid")
+	generalLedgerErrorFormatInvalid            = newBillingServerErrorMsg("gl000008", "general ledger export format is not supported")
+	generalLedgerErrorEntryTypeNotMapped       = newBillingServerErrorMsg("gl000009", "accounting entry type is not mapped to the general ledger accounts")
+
+	generalLedgerCsvHeader = []string{
+		"line_number",
+		"date",
+		"entry_id",
+		"entry_type",
+		"account",
+		"debit",
+		"credit",
+		"currency",
+		"source_type",
+		"source_id",
+		"merchant_id",
+		"description",
+	}
+)
+
+// SetChartOfAccounts replaces the general ledger accounts mapping of the operating company.
+func (s *Service) SetChartOfAccounts(
+	ctx context.Context,
+	req *intPkg.SetChartOfAccountsRequest,
+	res *intPkg.ChartOfAccountsResponse,
+) error {
+	if !s.hasAdminRole(ctx, req.UserId, billingpb.RoleSystemAdmin, billingpb.RoleSystemFinancial) {
+		res.Status = billingpb.ResponseStatusForbidden
+		res.Message = generalLedgerErrorAccessDenied
+		return nil
+	}
+
+	if _, err := s.operatingCompanyRepository.GetById(ctx, req.OperatingCompanyId); err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = generalLedgerErrorOperatingCompanyNotFound
+		return nil
+	}
+
+	if len(req.Items) == 0 {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = generalLedgerErrorAccountsEmpty
+		return nil
+	}
+
+	types := make(map[string]bool, len(req.Items))
+
+	for _, item := range req.Items {
+		if _, ok := availableAccountingEntries[item.EntryType]; !ok ||
+			item.DebitAccount == "" || item.CreditAccount == "" || item.DebitAccount == item.CreditAccount {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = newBillingServerErrorMsg(
+				generalLedgerErrorAccountsItemInvalid.Code,
+				generalLedgerErrorAccountsItemInvalid.Message,
+				item.EntryType,
+			)
+			return nil
+		}
+
+		if types[item.EntryType] {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = newBillingServerErrorMsg(
+				generalLedgerErrorAccountsItemDuplicate.Code,
+				generalLedgerErrorAccountsItemDuplicate.Message,
+				item.EntryType,
+			)
+			return nil
+		}
+
+		types[item.EntryType] = true
+	}
+
+	chart, err := s.chartOfAccountsRepository.GetByOperatingCompanyId(ctx, req.OperatingCompanyId)
+
+	if err != nil {
+		chart = &intPkg.ChartOfAccounts{
+			Id:                 primitive.NewObjectID(),
+			OperatingCompanyId: req.OperatingCompanyId,
+			CreatedAt:          time.Now(),
+		}
+	}
+
+	chart.Items = req.Items
+	chart.UpdatedBy = req.UserId
+	chart.UpdatedAt = time.Now()
+
+	if err = s.chartOfAccountsRepository.Upsert(ctx, chart); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = chart
+
+	return nil
+}
+
+// GetChartOfAccounts returns the general ledger accounts mapping of the operating company.
+func (s *Service) GetChartOfAccounts(
+	ctx context.Context,
+	req *intPkg.GetChartOfAccountsRequest,
+	res *intPkg.ChartOfAccountsResponse,
+) error {
+	chart, err := s.chartOfAccountsRepository.GetByOperatingCompanyId(ctx, req.OperatingCompanyId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = generalLedgerErrorAccountsNotFound
+		return nil
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = chart
+
+	return nil
+}
+
+// ExportGeneralLedger returns the double-entry journal of the operating company accounting entries
+// for the period as CSV or SAF-T style XML file.
+func (s *Service) ExportGeneralLedger(
+	ctx context.Context,
+	req *intPkg.ExportGeneralLedgerRequest,
+	res *intPkg.ExportGeneralLedgerResponse,
+) error {
+	if req.Format != intPkg.GeneralLedgerExportFormatCsv && req.Format != intPkg.GeneralLedgerExportFormatXml {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = generalLedgerErrorFormatInvalid
+		return nil
+	}
+
+	if !req.DateFrom.Before(req.DateTo) {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = generalLedgerErrorPeriodInvalid
+		return nil
+	}
+
+	operatingCompany, err := s.operatingCompanyRepository.GetById(ctx, req.OperatingCompanyId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = generalLedgerErrorOperatingCompanyNotFound
+		return nil
+	}
+
+	chart, err := s.chartOfAccountsRepository.GetByOperatingCompanyId(ctx, req.OperatingCompanyId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = generalLedgerErrorAccountsNotFound
+		return nil
+	}
+
+	entries, err := s.accountingRepository.FindByOperatingCompanyDates(ctx, req.OperatingCompanyId, req.DateFrom, req.DateTo)
+
+	if err != nil {
+		return err
+	}
+
+	journal, msg, err := s.buildGeneralLedgerJournal(operatingCompany, chart, entries, req.DateFrom, req.DateTo)
+
+	if err != nil {
+		return err
+	}
+
+	if msg != nil {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = msg
+		return nil
+	}
+
+	export := &intPkg.GeneralLedgerExport{
+		Format: req.Format,
+		FileName: fmt.Sprintf(
+			generalLedgerFileNameFormat,
+			operatingCompany.Id,
+			req.DateFrom.Format(generalLedgerDateFormat),
+			req.DateTo.Format(generalLedgerDateFormat),
+			req.Format,
+		),
+		EntriesCount: journal.EntriesCount,
+		Totals:       journal.Totals,
+	}
+
+	if req.Format == intPkg.GeneralLedgerExportFormatCsv {
+		export.ContentType = generalLedgerContentTypeCsv
+		export.Content, err = s.renderGeneralLedgerCsv(journal)
+	} else {
+		export.ContentType = generalLedgerContentTypeXml
+		export.Content, err = s.renderGeneralLedgerXml(journal)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = export
+
+	return nil
+}
+
+// buildGeneralLedgerJournal converts the accounting entries to the debit and credit lines of the journal.
+// Returns error message if the entry type isn't mapped in the chart of accounts.
+func (s *Service) buildGeneralLedgerJournal(
+	operatingCompany *billingpb.OperatingCompany,
+	chart *intPkg.ChartOfAccounts,
+	entries []*billingpb.AccountingEntry,
+	from, to time.Time,
+) (*intPkg.GeneralLedgerJournal, *billingpb.ResponseErrorMessage, error) {
+	journal := &intPkg.GeneralLedgerJournal{
+		OperatingCompany: operatingCompany,
+		Accounts:         chart.Items,
+		DateFrom:         from,
+		DateTo:           to,
+		Lines:            []*intPkg.GeneralLedgerJournalLine{},
+		CreatedAt:        time.Now(),
+	}
+
+	for _, entry := range entries {
+		item := chart.GetItem(entry.Type)
+
+		if item == nil {
+			return nil, newBillingServerErrorMsg(
+				generalLedgerErrorEntryTypeNotMapped.Code,
+				generalLedgerErrorEntryTypeNotMapped.Message,
+				entry.Type,
+			), nil
+		}
+
+		amount := s.newMoney(entry.Amount, entry.Currency).Round()
+
+		if amount.IsZero() {
+			continue
+		}
+
+		debitAccount, creditAccount := item.DebitAccount, item.CreditAccount
+
+		if amount.Sign() < 0 {
+			amount = amount.Neg()
+			debitAccount, creditAccount = creditAccount, debitAccount
+		}
+
+		date, err := ptypes.Timestamp(entry.CreatedAt)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		description := entry.Reason
+
+		if description == "" {
+			description = item.Description
+		}
+
+		line := &intPkg.GeneralLedgerJournalLine{
+			EntryId:     entry.Id,
+			EntryType:   entry.Type,
+			Date:        date,
+			Currency:    entry.Currency,
+			SourceType:  entry.GetSource().GetType(),
+			SourceId:    entry.GetSource().GetId(),
+			MerchantId:  entry.MerchantId,
+			Description: description,
+		}
+
+		debit := *line
+		debit.Account = debitAccount
+		debit.Debit = amount.Float64()
+
+		credit := *line
+		credit.Account = creditAccount
+		credit.Credit = amount.Float64()
+
+		debit.LineNumber = int32(len(journal.Lines) + 1)
+		credit.LineNumber = debit.LineNumber + 1
+		journal.Lines = append(journal.Lines, &debit, &credit)
+		journal.EntriesCount++
+	}
+
+	journal.Totals = s.getGeneralLedgerJournalTotals(journal.Lines)
+
+	for _, total := range journal.Totals {
+		if total.Difference != 0 {
+			zap.L().Warn(
+				"General ledger journal isn't balanced",
+				zap.String("operating_company_id", operatingCompany.Id),
+				zap.String("currency", total.Currency),
+				zap.Float64("debit", total.Debit),
+				zap.Float64("credit", total.Credit),
+				zap.Float64("difference", total.Difference),
+			)
+		}
+	}
+
+	return journal, nil, nil
+}
+
+// getGeneralLedgerJournalTotals sums the debit and credit of the journal lines by the currency independently
+// and returns the difference of the sums, so the totals show the imbalance of the journal.
+func (s *Service) getGeneralLedgerJournalTotals(lines []*intPkg.GeneralLedgerJournalLine) []*intPkg.GeneralLedgerJournalTotal {
+	debits := make(map[string]intPkg.Money)
+	credits := make(map[string]intPkg.Money)
+	var currencies []string
+
+	for _, line := range lines {
+		if _, ok := debits[line.Currency]; !ok {
+			debits[line.Currency] = s.newMoney(0, line.Currency)
+			credits[line.Currency] = s.newMoney(0, line.Currency)
+			currencies = append(currencies, line.Currency)
+		}
+
+		debits[line.Currency] = debits[line.Currency].Add(s.newMoney(line.Debit, line.Currency))
+		credits[line.Currency] = credits[line.Currency].Add(s.newMoney(line.Credit, line.Currency))
+	}
+
+	sort.Strings(currencies)
+	totals := make([]*intPkg.GeneralLedgerJournalTotal, 0, len(currencies))
+
+	for _, currency := range currencies {
+		totals = append(totals, &intPkg.GeneralLedgerJournalTotal{
+			Currency:   currency,
+			Debit:      debits[currency].Round().Float64(),
+			Credit:     credits[currency].Round().Float64(),
+			Difference: debits[currency].Sub(credits[currency]).Round().Float64(),
+		})
+	}
+
+	return totals
+}
+
+func (s *Service) renderGeneralLedgerCsv(journal *intPkg.GeneralLedgerJournal) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+
+	if err := w.Write(generalLedgerCsvHeader); err != nil {
+		return nil, err
+	}
+
+	for _, line := range journal.Lines {
+		record := []string{
+			strconv.Itoa(int(line.LineNumber)),
+			line.Date.Format(generalLedgerDateFormat),
+			line.EntryId,
+			line.EntryType,
+			line.Account,
+			s.formatGeneralLedgerAmount(line.Debit, line.Currency),
+			s.formatGeneralLedgerAmount(line.Credit, line.Currency),
+			line.Currency,
+			line.SourceType,
+			line.SourceId,
+			line.MerchantId,
+			line.Description,
+		}
+
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	// the total line contains the difference of the debit and credit in the description column
+	for _, total := range journal.Totals {
+		record := make([]string, len(generalLedgerCsvHeader))
+		record[0] = generalLedgerCsvTotalLineMarker
+		record[5] = s.formatGeneralLedgerAmount(total.Debit, total.Currency)
+		record[6] = s.formatGeneralLedgerAmount(total.Credit, total.Currency)
+		record[7] = total.Currency
+		record[11] = s.formatGeneralLedgerAmount(total.Difference, total.Currency)
+
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+
+	return buf.Bytes(), w.Error()
+}
+
+type saftAuditFile struct {
+	XMLName              xml.Name                  `xml:"AuditFile"`
+	Xmlns                string                    `xml:"xmlns,attr"`
+	Header               *saftHeader               `xml:"Header"`
+	MasterFiles          *saftMasterFiles          `xml:"MasterFiles"`
+	GeneralLedgerEntries *saftGeneralLedgerEntries `xml:"GeneralLedgerEntries"`
+}
+
+type saftHeader struct {
+	AuditFileVersion     string                 `xml:"AuditFileVersion"`
+	AuditFileCountry     string                 `xml:"AuditFileCountry"`
+	AuditFileDateCreated string                 `xml:"AuditFileDateCreated"`
+	SoftwareCompanyName  string                 `xml:"SoftwareCompanyName"`
+	SoftwareID           string                 `xml:"SoftwareID"`
+	Company              *saftCompany           `xml:"Company"`
+	SelectionCriteria    *saftSelectionCriteria `xml:"SelectionCriteria"`
+}
+
+type saftCompany struct {
+	RegistrationNumber string `xml:"RegistrationNumber"`
+	Name               string `xml:"Name"`
+	TaxRegistration    string `xml:"TaxRegistration>TaxRegistrationNumber"`
+}
+
+type saftSelectionCriteria struct {
+	SelectionStartDate string `xml:"SelectionStartDate"`
+	SelectionEndDate   string `xml:"SelectionEndDate"`
+}
+
+type saftMasterFiles struct {
+	Accounts []*saftAccount `xml:"GeneralLedgerAccounts>Account"`
+}
+
+type saftAccount struct {
+	AccountID          string `xml:"AccountID"`
+	AccountDescription string `xml:"AccountDescription"`
+}
+
+type saftGeneralLedgerEntries struct {
+	NumberOfEntries int32          `xml:"NumberOfEntries"`
+	Journals        []*saftJournal `xml:"Journal"`
+}
+
+// saftJournal contains the transactions in the single currency, the totals of the journal are balanced.
+type saftJournal struct {
+	JournalID    string             `xml:"JournalID"`
+	Description  string             `xml:"Description"`
+	Type         string             `xml:"Type"`
+	CurrencyCode string             `xml:"CurrencyCode"`
+	TotalDebit   string             `xml:"TotalDebit"`
+	TotalCredit  string             `xml:"TotalCredit"`
+	Transactions []*saftTransaction `xml:"Transaction"`
+}
+
+type saftTransaction struct {
+	TransactionID   string      `xml:"TransactionID"`
+	Period          int         `xml:"Period"`
+	PeriodYear      int         `xml:"PeriodYear"`
+	TransactionDate string      `xml:"TransactionDate"`
+	SourceID        string      `xml:"SourceID"`
+	Description     string      `xml:"Description"`
+	SystemEntryDate string      `xml:"SystemEntryDate"`
+	GLPostingDate   string      `xml:"GLPostingDate"`
+	Lines           []*saftLine `xml:"Line"`
+}
+
+type saftLine struct {
+	RecordID         string      `xml:"RecordID"`
+	AccountID        string      `xml:"AccountID"`
+	SourceDocumentID string      `xml:"SourceDocumentID"`
+	CustomerID       string      `xml:"CustomerID,omitempty"`
+	Description      string      `xml:"Description"`
+	DebitAmount      *saftAmount `xml:"DebitAmount,omitempty"`
+	CreditAmount     *saftAmount `xml:"CreditAmount,omitempty"`
+}
+
+type saftAmount struct {
+	Amount       string `xml:"Amount"`
+	CurrencyCode string `xml:"CurrencyCode"`
+}
+
+func (s *Service) renderGeneralLedgerXml(journal *intPkg.GeneralLedgerJournal) ([]byte, error) {
+	oc := journal.OperatingCompany
+	file := &saftAuditFile{
+		Xmlns: generalLedgerSaftNamespace,
+		Header: &saftHeader{
+			AuditFileVersion:     generalLedgerSaftVersion,
+			AuditFileCountry:     oc.Country,
+			AuditFileDateCreated: journal.CreatedAt.Format(generalLedgerDateFormat),
+			SoftwareCompanyName:  generalLedgerSaftSoftwareName,
+			SoftwareID:           generalLedgerSaftSoftwareId,
+			Company: &saftCompany{
+				RegistrationNumber: oc.RegistrationNumber,
+				Name:               oc.Name,
+				TaxRegistration:    oc.VatNumber,
+			},
+			SelectionCriteria: &saftSelectionCriteria{
+				SelectionStartDate: journal.DateFrom.Format(generalLedgerDateFormat),
+				SelectionEndDate:   journal.DateTo.Format(generalLedgerDateFormat),
+			},
+		},
+		MasterFiles: &saftMasterFiles{},
+		GeneralLedgerEntries: &saftGeneralLedgerEntries{
+			NumberOfEntries: journal.EntriesCount,
+		},
+	}
+
+	accounts := make(map[string]bool)
+
+	for _, item := range journal.Accounts {
+		for _, account := range []string{item.DebitAccount, item.CreditAccount} {
+			if accounts[account] {
+				continue
+			}
+
+			accounts[account] = true
+			file.MasterFiles.Accounts = append(file.MasterFiles.Accounts, &saftAccount{
+				AccountID:          account,
+				AccountDescription: item.Description,
+			})
+		}
+	}
+
+	journals := make(map[string]*saftJournal)
+
+	for _, total := range journal.Totals {
+		j := &saftJournal{
+			JournalID:    generalLedgerSaftJournalType + "-" + total.Currency,
+			Description:  "Accounting entries in " + total.Currency,
+			Type:         generalLedgerSaftJournalType,
+			CurrencyCode: total.Currency,
+			TotalDebit:   s.formatGeneralLedgerAmount(total.Debit, total.Currency),
+			TotalCredit:  s.formatGeneralLedgerAmount(total.Credit, total.Currency),
+		}
+		journals[total.Currency] = j
+		file.GeneralLedgerEntries.Journals = append(file.GeneralLedgerEntries.Journals, j)
+	}
+
+	var transaction *saftTransaction
+
+	for _, line := range journal.Lines {
+		j := journals[line.Currency]
+
+		if transaction == nil || transaction.TransactionID != line.EntryId {
+			date := line.Date.Format(generalLedgerDateFormat)
+			transaction = &saftTransaction{
+				TransactionID:   line.EntryId,
+				Period:          int(line.Date.Month()),
+				PeriodYear:      line.Date.Year(),
+				TransactionDate: date,
+				SourceID:        line.SourceType + "/" + line.SourceId,
+				Description:     line.EntryType,
+				SystemEntryDate: date,
+				GLPostingDate:   date,
+			}
+			j.Transactions = append(j.Transactions, transaction)
+		}
+
+		l := &saftLine{
+			RecordID:         strconv.Itoa(int(line.LineNumber)),
+			AccountID:        line.Account,
+			SourceDocumentID: line.SourceType + "/" + line.SourceId,
+			CustomerID:       line.MerchantId,
+			Description:      line.Description,
+		}
+
+		if line.Debit != 0 {
+			l.DebitAmount = &saftAmount{
+				Amount:       s.formatGeneralLedgerAmount(line.Debit, line.Currency),
+				CurrencyCode: line.Currency,
+			}
+		} else {
+			l.CreditAmount = &saftAmount{
+				Amount:       s.formatGeneralLedgerAmount(line.Credit, line.Currency),
+				CurrencyCode: line.Currency,
+			}
+		}
+
+		transaction.Lines = append(transaction.Lines, l)
+	}
+
+	out, err := xml.MarshalIndent(file, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), out...), nil
+}
+
+func (s *Service) formatGeneralLedgerAmount(amount float64, currency string) string {
+	return s.newMoney(amount, currency).String()
+}
+
+// ExportGeneralLedgers returns the journal files in all supported formats for every operating company
+// which has the chart of accounts. Used by the general ledger export task.
+func (s *Service) ExportGeneralLedgers(ctx context.Context, from, to time.Time) ([]*intPkg.GeneralLedgerExport, error) {
+	operatingCompanies, err := s.operatingCompanyRepository.GetAll(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var exports []*intPkg.GeneralLedgerExport
+
+	for _, oc := range operatingCompanies {
+		if _, err := s.chartOfAccountsRepository.GetByOperatingCompanyId(ctx, oc.Id); err != nil {
+			zap.L().Info("Chart of accounts of operating company not found, export skipped", zap.String("operating_company_id", oc.Id))
+			continue
+		}
+
+		for _, format := range []string{intPkg.GeneralLedgerExportFormatCsv, intPkg.GeneralLedgerExportFormatXml} {
+			req := &intPkg.ExportGeneralLedgerRequest{OperatingCompanyId: oc.Id, DateFrom: from, DateTo: to, Format: format}
+			res := &intPkg.ExportGeneralLedgerResponse{}
+
+			if err = s.ExportGeneralLedger(ctx, req, res); err != nil {
+				return nil, err
+			}
+
+			if res.Status != billingpb.ResponseStatusOk {
+				return nil, res.Message
+			}
+
+			exports = append(exports, res.Item)
+		}
+	}
+
+	return exports, nil
+}
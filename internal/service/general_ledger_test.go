@@ -0,0 +1,360 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"github.com/go-redis/redis"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/mongodb"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/internal/repository"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	rabbitmq "gopkg.in/ProtocolONE/rabbitmq.v1/pkg"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+	"time"
+)
+
+type GeneralLedgerTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant  *billingpb.Merchant
+	admin     *billingpb.UserRole
+	financier *billingpb.UserRole
+	support   *billingpb.UserRole
+}
+
+func Test_GeneralLedger(t *testing.T) {
+	suite.Run(t, new(GeneralLedgerTestSuite))
+}
+
+func (suite *GeneralLedgerTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New(
+		"file://../../migrations/tests",
+		cfg.MongoDsn)
+	assert.NoError(suite.T(), err, "Migrate init failed")
+
+	err = m.Up()
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	broker, err := rabbitmq.NewBroker(cfg.BrokerAddress)
+
+	if err != nil {
+		suite.FailNow("Creating RabbitMQ publisher failed", "%v", err)
+	}
+
+	redisClient := database.NewRedis(
+		&redis.Options{
+			Addr:     cfg.RedisHost,
+			Password: cfg.RedisPassword,
+		},
+	)
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		broker,
+		redisClient,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	suite.merchant, _, _, _ = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+
+	suite.admin = &billingpb.UserRole{
+		Id:     primitive.NewObjectID().Hex(),
+		UserId: primitive.NewObjectID().Hex(),
+		Role:   billingpb.RoleSystemAdmin,
+	}
+	suite.financier = &billingpb.UserRole{
+		Id:     primitive.NewObjectID().Hex(),
+		UserId: primitive.NewObjectID().Hex(),
+		Role:   billingpb.RoleSystemFinancial,
+	}
+
+	suite.support = &billingpb.UserRole{
+		Id:     primitive.NewObjectID().Hex(),
+		UserId: primitive.NewObjectID().Hex(),
+		Role:   billingpb.RoleSystemSupport,
+	}
+
+	for _, role := range []*billingpb.UserRole{suite.admin, suite.financier, suite.support} {
+		if err := suite.service.userRoleRepository.AddAdminUser(context.TODO(), role); err != nil {
+			suite.FailNow("Insert admin user failed", "%v", err)
+		}
+	}
+}
+
+func (suite *GeneralLedgerTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *GeneralLedgerTestSuite) setChartOfAccounts() {
+	req := &intPkg.SetChartOfAccountsRequest{
+		OperatingCompanyId: suite.merchant.OperatingCompanyId,
+		UserId:             suite.financier.UserId,
+		Items: []*intPkg.ChartOfAccountsItem{
+			{
+				EntryType:     pkg.AccountingEntryTypeRealGrossRevenue,
+				DebitAccount:  "1200",
+				CreditAccount: "4000",
+				Description:   "Gross revenue",
+			},
+			{
+				EntryType:     pkg.AccountingEntryTypeMerchantRoyaltyCorrection,
+				DebitAccount:  "6100",
+				CreditAccount: "2100",
+				Description:   "Merchant royalty correction",
+			},
+		},
+	}
+	res := &intPkg.ChartOfAccountsResponse{}
+	err := suite.service.SetChartOfAccounts(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+}
+
+func (suite *GeneralLedgerTestSuite) insertEntry(entryType string, amount float64, currency string) *billingpb.AccountingEntry {
+	entry := &billingpb.AccountingEntry{
+		Id:                 primitive.NewObjectID().Hex(),
+		Object:             pkg.ObjectTypeBalanceTransaction,
+		Type:               entryType,
+		Source:             &billingpb.AccountingEntrySource{Id: primitive.NewObjectID().Hex(), Type: repository.CollectionOrder},
+		MerchantId:         suite.merchant.Id,
+		Amount:             amount,
+		Currency:           currency,
+		Status:             pkg.BalanceTransactionStatusAvailable,
+		CreatedAt:          ptypes.TimestampNow(),
+		OperatingCompanyId: suite.merchant.OperatingCompanyId,
+	}
+	err := suite.service.accountingRepository.MultipleInsert(context.TODO(), []*billingpb.AccountingEntry{entry})
+	assert.NoError(suite.T(), err)
+
+	return entry
+}
+
+func (suite *GeneralLedgerTestSuite) exportRequest(format string) *intPkg.ExportGeneralLedgerRequest {
+	return &intPkg.ExportGeneralLedgerRequest{
+		OperatingCompanyId: suite.merchant.OperatingCompanyId,
+		DateFrom:           time.Now().Add(-time.Hour),
+		DateTo:             time.Now().Add(time.Hour),
+		Format:             format,
+	}
+}
+
+func (suite *GeneralLedgerTestSuite) TestGeneralLedger_SetChartOfAccounts_Error() {
+	req := &intPkg.SetChartOfAccountsRequest{
+		OperatingCompanyId: suite.merchant.OperatingCompanyId,
+		UserId:             suite.support.UserId,
+		Items: []*intPkg.ChartOfAccountsItem{
+			{EntryType: pkg.AccountingEntryTypeRealGrossRevenue, DebitAccount: "1200", CreditAccount: "4000"},
+		},
+	}
+	res := &intPkg.ChartOfAccountsResponse{}
+	err := suite.service.SetChartOfAccounts(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+	assert.Equal(suite.T(), generalLedgerErrorAccessDenied, res.Message)
+
+	req.UserId = suite.admin.UserId
+	req.Items = append(req.Items, &intPkg.ChartOfAccountsItem{
+		EntryType:     pkg.AccountingEntryTypeRealGrossRevenue,
+		DebitAccount:  "1300",
+		CreditAccount: "4000",
+	})
+	res = &intPkg.ChartOfAccountsResponse{}
+	err = suite.service.SetChartOfAccounts(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), generalLedgerErrorAccountsItemDuplicate.Code, res.Message.Code)
+
+	req.Items = []*intPkg.ChartOfAccountsItem{
+		{EntryType: pkg.AccountingEntryTypeRealGrossRevenue, DebitAccount: "1200", CreditAccount: "1200"},
+	}
+	res = &intPkg.ChartOfAccountsResponse{}
+	err = suite.service.SetChartOfAccounts(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), generalLedgerErrorAccountsItemInvalid.Code, res.Message.Code)
+	assert.Equal(suite.T(), pkg.AccountingEntryTypeRealGrossRevenue, res.Message.Details)
+}
+
+func (suite *GeneralLedgerTestSuite) TestGeneralLedger_ExportCsv_Ok() {
+	suite.setChartOfAccounts()
+	revenue := suite.insertEntry(pkg.AccountingEntryTypeRealGrossRevenue, 100.125, "RUB")
+	suite.insertEntry(pkg.AccountingEntryTypeMerchantRoyaltyCorrection, -15, "RUB")
+	suite.insertEntry(pkg.AccountingEntryTypeRealGrossRevenue, 20, "USD")
+
+	res := &intPkg.ExportGeneralLedgerResponse{}
+	err := suite.service.ExportGeneralLedger(context.TODO(), suite.exportRequest(intPkg.GeneralLedgerExportFormatCsv), res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.EqualValues(suite.T(), 3, res.Item.EntriesCount)
+	assert.Len(suite.T(), res.Item.Totals, 2)
+
+	for _, total := range res.Item.Totals {
+		assert.Equal(suite.T(), total.Debit, total.Credit)
+		assert.Zero(suite.T(), total.Difference)
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(res.Item.Content)).ReadAll()
+	assert.NoError(suite.T(), err)
+	// header, two lines for each entry and total line for each currency
+	assert.Len(suite.T(), records, 1+6+2)
+	assert.Equal(suite.T(), generalLedgerCsvHeader, records[0])
+
+	for _, record := range records[1:7] {
+		if record[2] != revenue.Id {
+			continue
+		}
+
+		assert.Equal(suite.T(), repository.CollectionOrder, record[8])
+		assert.Equal(suite.T(), revenue.Source.Id, record[9])
+		assert.Equal(suite.T(), suite.merchant.Id, record[10])
+
+		if record[4] == "1200" {
+			assert.Equal(suite.T(), "100.13", record[5])
+			assert.Equal(suite.T(), "0.00", record[6])
+		} else {
+			assert.Equal(suite.T(), "4000", record[4])
+			assert.Equal(suite.T(), "100.13", record[6])
+		}
+	}
+
+	for _, record := range records[1:7] {
+		// the negative correction is posted with swapped accounts
+		if record[3] == pkg.AccountingEntryTypeMerchantRoyaltyCorrection && record[4] == "2100" {
+			assert.Equal(suite.T(), "15.00", record[5])
+		}
+	}
+
+	assert.Equal(suite.T(), generalLedgerCsvTotalLineMarker, records[7][0])
+	assert.Equal(suite.T(), "RUB", records[7][7])
+	assert.Equal(suite.T(), "115.13", records[7][5])
+	assert.Equal(suite.T(), records[7][5], records[7][6])
+	assert.Equal(suite.T(), "0.00", records[7][11])
+}
+
+func (suite *GeneralLedgerTestSuite) TestGeneralLedger_getGeneralLedgerJournalTotals_Imbalance() {
+	lines := []*intPkg.GeneralLedgerJournalLine{
+		{Account: "1200", Debit: 100.5, Currency: "RUB"},
+		{Account: "4000", Credit: 100.5, Currency: "RUB"},
+		{Account: "1200", Debit: 20, Currency: "USD"},
+		{Account: "4000", Credit: 15, Currency: "USD"},
+	}
+
+	totals := suite.service.getGeneralLedgerJournalTotals(lines)
+	assert.Len(suite.T(), totals, 2)
+	assert.Equal(suite.T(), "RUB", totals[0].Currency)
+	assert.Equal(suite.T(), 100.5, totals[0].Debit)
+	assert.Equal(suite.T(), 100.5, totals[0].Credit)
+	assert.Zero(suite.T(), totals[0].Difference)
+	assert.Equal(suite.T(), "USD", totals[1].Currency)
+	assert.EqualValues(suite.T(), 20, totals[1].Debit)
+	assert.EqualValues(suite.T(), 15, totals[1].Credit)
+	assert.EqualValues(suite.T(), 5, totals[1].Difference)
+}
+
+func (suite *GeneralLedgerTestSuite) TestGeneralLedger_ExportXml_Ok() {
+	suite.setChartOfAccounts()
+	suite.insertEntry(pkg.AccountingEntryTypeRealGrossRevenue, 100, "RUB")
+	suite.insertEntry(pkg.AccountingEntryTypeMerchantRoyaltyCorrection, 10, "RUB")
+
+	res := &intPkg.ExportGeneralLedgerResponse{}
+	err := suite.service.ExportGeneralLedger(context.TODO(), suite.exportRequest(intPkg.GeneralLedgerExportFormatXml), res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Equal(suite.T(), generalLedgerContentTypeXml, res.Item.ContentType)
+
+	file := &saftAuditFile{}
+	err = xml.Unmarshal(res.Item.Content, file)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 2, file.GeneralLedgerEntries.NumberOfEntries)
+	assert.Len(suite.T(), file.MasterFiles.Accounts, 4)
+	assert.Len(suite.T(), file.GeneralLedgerEntries.Journals, 1)
+
+	journal := file.GeneralLedgerEntries.Journals[0]
+	assert.Equal(suite.T(), "RUB", journal.CurrencyCode)
+	assert.Equal(suite.T(), "110.00", journal.TotalDebit)
+	assert.Equal(suite.T(), journal.TotalDebit, journal.TotalCredit)
+	assert.Len(suite.T(), journal.Transactions, 2)
+	assert.Len(suite.T(), journal.Transactions[0].Lines, 2)
+	assert.NotNil(suite.T(), journal.Transactions[0].Lines[0].DebitAmount)
+	assert.NotNil(suite.T(), journal.Transactions[0].Lines[1].CreditAmount)
+}
+
+func (suite *GeneralLedgerTestSuite) TestGeneralLedger_Export_Error() {
+	res := &intPkg.ExportGeneralLedgerResponse{}
+	err := suite.service.ExportGeneralLedger(context.TODO(), suite.exportRequest(intPkg.GeneralLedgerExportFormatCsv), res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, res.Status)
+	assert.Equal(suite.T(), generalLedgerErrorAccountsNotFound, res.Message)
+
+	res = &intPkg.ExportGeneralLedgerResponse{}
+	err = suite.service.ExportGeneralLedger(context.TODO(), suite.exportRequest("pdf"), res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), generalLedgerErrorFormatInvalid, res.Message)
+
+	suite.setChartOfAccounts()
+	suite.insertEntry(pkg.AccountingEntryTypePsGrossRevenueFxTaxFee, 1, "RUB")
+
+	res = &intPkg.ExportGeneralLedgerResponse{}
+	err = suite.service.ExportGeneralLedger(context.TODO(), suite.exportRequest(intPkg.GeneralLedgerExportFormatCsv), res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), generalLedgerErrorEntryTypeNotMapped.Code, res.Message.Code)
+	assert.Equal(suite.T(), pkg.AccountingEntryTypePsGrossRevenueFxTaxFee, res.Message.Details)
+}
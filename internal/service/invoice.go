@@ -0,0 +1,626 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/uuid"
+	"github.com/paysuper/paysuper-billing-server/internal/helper"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/postmarkpb"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"net/mail"
+	"strconv"
+	"time"
+)
+
+const (
+	// invoiceCheckoutReservationTimeout is the time after which the reservation of the checkout without
+	// the created order is ignored
+	invoiceCheckoutReservationTimeout = time.Minute
+
+	invoiceDuplicatePaymentRefundReason        = "duplicate payment of the invoice %s"
+	invoiceDuplicatePaymentNotificationMessage = "Invoice %s is paid again by the order %s, automatic refund failed. " +
+		"Please refund the payment manually."
+)
+
+var (
+	invoiceErrorNotFound            = newBillingServerErrorMsg("iv000001", "invoice not found")
+	invoiceErrorStatusInvalid       = newBillingServerErrorMsg("iv000002", "action is not allowed for the invoice in the current status")
+	invoiceErrorItemsEmpty          = newBillingServerErrorMsg("iv000003", "invoice must contain at least one line item")
+	invoiceErrorItemInvalid         = newBillingServerErrorMsg("iv000004", "invoice line item must have name, positive quantity and price")
+	invoiceErrorDueDateInPast       = newBillingServerErrorMsg("iv000005", "invoice due date in past")
+	invoiceErrorCustomerEmail       = newBillingServerErrorMsg("iv000006", "invoice customer email is invalid")
+	invoiceErrorProjectMismatch     = newBillingServerErrorMsg("iv000007", "project is not belongs to merchant")
+	invoiceErrorCurrencyUnsupported = newBillingServerErrorMsg("iv000008", "invoice currency is not supported")
+	invoiceErrorNotPayable          = newBillingServerErrorMsg("iv000009", "invoice can not be paid")
+	invoiceErrorOrderProcessing     = newBillingServerErrorMsg("iv000010", "payment of the invoice is already in progress")
+)
+
+// CreateOrUpdateInvoice creates the invoice draft or updates the existing one.
+// Only invoices in the draft status can be changed.
+func (s *Service) CreateOrUpdateInvoice(
+	ctx context.Context,
+	req *intPkg.CreateOrUpdateInvoiceRequest,
+	res *intPkg.InvoiceResponse,
+) error {
+	invoice := &intPkg.Invoice{
+		Id:        primitive.NewObjectID(),
+		Uuid:      uuid.New().String(),
+		Status:    intPkg.InvoiceStatusDraft,
+		CreatedAt: time.Now(),
+	}
+
+	if req.Id != "" {
+		var err error
+		invoice, err = s.invoiceRepository.GetByIdAndMerchant(ctx, req.Id, req.MerchantId)
+
+		if err != nil {
+			res.Status = billingpb.ResponseStatusNotFound
+			res.Message = invoiceErrorNotFound
+			return nil
+		}
+
+		if invoice.Status != intPkg.InvoiceStatusDraft {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = invoiceErrorStatusInvalid
+			return nil
+		}
+	}
+
+	if msg := s.validateInvoiceRequest(ctx, req); msg != nil {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = msg
+		return nil
+	}
+
+	invoice.MerchantId, _ = primitive.ObjectIDFromHex(req.MerchantId)
+	invoice.ProjectId, _ = primitive.ObjectIDFromHex(req.ProjectId)
+	invoice.CustomerEmail = req.CustomerEmail
+	invoice.Currency = req.Currency
+	invoice.DueDate = req.DueDate
+	invoice.Memo = req.Memo
+	invoice.Items = make([]*intPkg.InvoiceItem, len(req.Items))
+	invoice.TotalAmount = 0
+	invoice.UpdatedAt = time.Now()
+
+	for i, item := range req.Items {
+		amount := s.FormatAmount(item.Price*float64(item.Quantity), req.Currency)
+		invoice.Items[i] = &intPkg.InvoiceItem{
+			Name:     item.Name,
+			Quantity: item.Quantity,
+			Price:    item.Price,
+			Amount:   amount,
+		}
+		invoice.TotalAmount += amount
+	}
+
+	invoice.TotalAmount = s.FormatAmount(invoice.TotalAmount, req.Currency)
+
+	var err error
+
+	if req.Id != "" {
+		err = s.invoiceRepository.Update(ctx, invoice)
+	} else {
+		err = s.invoiceRepository.Insert(ctx, invoice)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = invoice
+
+	return nil
+}
+
+// GetInvoice returns the merchant invoice.
+func (s *Service) GetInvoice(
+	ctx context.Context,
+	req *intPkg.InvoiceRequest,
+	res *intPkg.InvoiceResponse,
+) error {
+	invoice, err := s.invoiceRepository.GetByIdAndMerchant(ctx, req.Id, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = invoiceErrorNotFound
+		return nil
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = invoice
+
+	return nil
+}
+
+// ListInvoices returns the merchant invoices filtered by status with pagination.
+func (s *Service) ListInvoices(
+	ctx context.Context,
+	req *intPkg.ListInvoicesRequest,
+	res *intPkg.ListInvoicesResponse,
+) error {
+	count, err := s.invoiceRepository.FindCount(ctx, req.MerchantId, req.Status)
+
+	if err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Count = count
+	res.Items = []*intPkg.Invoice{}
+
+	if count <= 0 {
+		return nil
+	}
+
+	res.Items, err = s.invoiceRepository.Find(ctx, req.MerchantId, req.Status, req.Limit, req.Offset)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SendInvoice sends the invoice to the customer email. The draft invoice is moved to the sent status,
+// the already sent or overdue invoice is delivered to the customer once again.
+func (s *Service) SendInvoice(
+	ctx context.Context,
+	req *intPkg.InvoiceRequest,
+	res *intPkg.InvoiceResponse,
+) error {
+	invoice, err := s.invoiceRepository.GetByIdAndMerchant(ctx, req.Id, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = invoiceErrorNotFound
+		return nil
+	}
+
+	if invoice.Status != intPkg.InvoiceStatusDraft && !invoice.IsPayable() {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = invoiceErrorStatusInvalid
+		return nil
+	}
+
+	if invoice.Status == intPkg.InvoiceStatusDraft {
+		if invoice.DueDate.Before(time.Now()) {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = invoiceErrorDueDateInPast
+			return nil
+		}
+
+		invoice.Status = intPkg.InvoiceStatusSent
+	}
+
+	invoice.SentAt = time.Now()
+	invoice.UpdatedAt = time.Now()
+
+	if err = s.invoiceRepository.Update(ctx, invoice); err != nil {
+		return err
+	}
+
+	if err = s.sendInvoiceEmail(ctx, invoice, s.cfg.EmailTemplates.InvoiceDelivery); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = invoice
+
+	return nil
+}
+
+// VoidInvoice cancels the unpaid invoice.
+func (s *Service) VoidInvoice(
+	ctx context.Context,
+	req *intPkg.InvoiceRequest,
+	res *intPkg.InvoiceResponse,
+) error {
+	invoice, err := s.invoiceRepository.GetByIdAndMerchant(ctx, req.Id, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = invoiceErrorNotFound
+		return nil
+	}
+
+	if invoice.Status != intPkg.InvoiceStatusDraft && !invoice.IsPayable() {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = invoiceErrorStatusInvalid
+		return nil
+	}
+
+	invoice.Status = intPkg.InvoiceStatusVoid
+	invoice.UpdatedAt = time.Now()
+
+	if err = s.invoiceRepository.Update(ctx, invoice); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = invoice
+
+	return nil
+}
+
+// GetInvoiceUrl returns the checkout url of the invoice.
+func (s *Service) GetInvoiceUrl(
+	ctx context.Context,
+	req *intPkg.InvoiceRequest,
+	res *intPkg.GetInvoiceUrlResponse,
+) error {
+	invoice, err := s.invoiceRepository.GetByIdAndMerchant(ctx, req.Id, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = invoiceErrorNotFound
+		return nil
+	}
+
+	if !invoice.IsPayable() {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = invoiceErrorNotPayable
+		return nil
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Url = s.cfg.GetInvoiceCheckoutUrl(invoice.Uuid)
+
+	return nil
+}
+
+// OrderCreateByInvoice creates the order for payment of the invoice opened by the customer by the checkout url.
+func (s *Service) OrderCreateByInvoice(
+	ctx context.Context,
+	req *intPkg.OrderCreateByInvoiceRequest,
+	rsp *billingpb.OrderCreateProcessResponse,
+) error {
+	invoice, err := s.invoiceRepository.GetByUuid(ctx, req.InvoiceUuid)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = invoiceErrorNotFound
+		return nil
+	}
+
+	if !invoice.IsPayable() {
+		rsp.Status = billingpb.ResponseStatusGone
+		rsp.Message = invoiceErrorNotPayable
+		return nil
+	}
+
+	if s.isInvoiceOrderProcessing(ctx, invoice) {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = invoiceErrorOrderProcessing
+		return nil
+	}
+
+	// the checkout of the invoice is reserved before the order is created, so only one of the concurrent
+	// requests can create the order
+	reservationId := primitive.NewObjectID().Hex()
+	err = s.invoiceRepository.SetProcessingOrderId(ctx, invoice.Id, invoice.ProcessingOrderId, reservationId)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = invoiceErrorOrderProcessing
+			return nil
+		}
+
+		return err
+	}
+
+	oReq := &billingpb.OrderCreateRequest{
+		ProjectId: invoice.ProjectId.Hex(),
+		Type:      pkg.OrderType_simple,
+		Amount:    invoice.TotalAmount,
+		Currency:  invoice.Currency,
+		User: &billingpb.OrderUser{
+			Ip:    req.PayerIp,
+			Email: invoice.CustomerEmail,
+		},
+		Description: invoice.Memo,
+		PrivateMetadata: map[string]string{
+			"InvoiceId": invoice.Id.Hex(),
+		},
+		IssuerUrl:           req.IssuerUrl,
+		IsEmbedded:          req.IsEmbedded,
+		IssuerReferenceType: pkg.OrderIssuerReferenceTypeInvoice,
+		IssuerReference:     invoice.Id.Hex(),
+		Cookie:              req.Cookie,
+	}
+
+	err = s.OrderCreateProcess(ctx, oReq, rsp)
+
+	if err != nil || rsp.Status != billingpb.ResponseStatusOk || rsp.Item == nil {
+		s.releaseInvoiceCheckout(ctx, invoice, reservationId)
+
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = e
+			return nil
+		}
+
+		return err
+	}
+
+	return s.invoiceRepository.SetProcessingOrderId(ctx, invoice.Id, reservationId, rsp.Item.Id)
+}
+
+// releaseInvoiceCheckout removes the reservation of the invoice checkout when the order wasn't created.
+func (s *Service) releaseInvoiceCheckout(ctx context.Context, invoice *intPkg.Invoice, reservationId string) {
+	err := s.invoiceRepository.SetProcessingOrderId(ctx, invoice.Id, reservationId, "")
+
+	if err != nil {
+		zap.L().Error(
+			"Invoice checkout reservation release failed",
+			zap.Error(err),
+			zap.String("invoice_id", invoice.Id.Hex()),
+		)
+	}
+}
+
+// isInvoiceOrderProcessing checks that the order created on the invoice checkout before is waiting for the payment.
+// The invoice which processing order isn't found is reserved by the checkout creating the order right now.
+func (s *Service) isInvoiceOrderProcessing(ctx context.Context, invoice *intPkg.Invoice) bool {
+	if invoice.ProcessingOrderId == "" {
+		return false
+	}
+
+	order, err := s.orderRepository.GetById(ctx, invoice.ProcessingOrderId)
+
+	if err != nil {
+		return invoice.ProcessingStartedAt.Add(invoiceCheckoutReservationTimeout).After(time.Now())
+	}
+
+	switch order.PrivateStatus {
+	case recurringpb.OrderStatusPaymentSystemCreate:
+		return true
+	case recurringpb.OrderStatusNew:
+		expireAt, err := ptypes.Timestamp(order.ExpireDateToFormInput)
+		return err == nil && expireAt.After(time.Now())
+	}
+
+	return false
+}
+
+// ProcessInvoices marks the sent invoices with expired due date as overdue and sends payment reminders
+// to customers of the invoices which due date is coming soon.
+func (s *Service) ProcessInvoices(ctx context.Context) error {
+	now := time.Now()
+	invoices, err := s.invoiceRepository.FindByStatusDueBefore(ctx, intPkg.InvoiceStatusSent, now)
+
+	if err != nil {
+		return err
+	}
+
+	for _, invoice := range invoices {
+		invoice.Status = intPkg.InvoiceStatusOverdue
+		invoice.RemindedAt = now
+		invoice.UpdatedAt = now
+
+		if err = s.invoiceRepository.Update(ctx, invoice); err != nil {
+			zap.L().Error(
+				pkg.MethodFinishedWithError,
+				zap.String("Method", "ProcessInvoices"),
+				zap.Error(err),
+				zap.String("invoice_id", invoice.Id.Hex()),
+			)
+			continue
+		}
+
+		if err = s.sendInvoiceEmail(ctx, invoice, s.cfg.EmailTemplates.InvoiceReminder); err != nil {
+			zap.L().Error(
+				pkg.MethodFinishedWithError,
+				zap.String("Method", "ProcessInvoices"),
+				zap.Error(err),
+				zap.String("invoice_id", invoice.Id.Hex()),
+			)
+		}
+	}
+
+	remindDate := now.AddDate(0, 0, s.cfg.InvoiceReminderDays)
+	invoices, err = s.invoiceRepository.FindByStatusDueBefore(ctx, intPkg.InvoiceStatusSent, remindDate)
+
+	if err != nil {
+		return err
+	}
+
+	for _, invoice := range invoices {
+		if !invoice.RemindedAt.IsZero() {
+			continue
+		}
+
+		invoice.RemindedAt = now
+		invoice.UpdatedAt = now
+
+		if err = s.invoiceRepository.Update(ctx, invoice); err != nil {
+			zap.L().Error(
+				pkg.MethodFinishedWithError,
+				zap.String("Method", "ProcessInvoices"),
+				zap.Error(err),
+				zap.String("invoice_id", invoice.Id.Hex()),
+			)
+			continue
+		}
+
+		if err = s.sendInvoiceEmail(ctx, invoice, s.cfg.EmailTemplates.InvoiceReminder); err != nil {
+			zap.L().Error(
+				pkg.MethodFinishedWithError,
+				zap.String("Method", "ProcessInvoices"),
+				zap.Error(err),
+				zap.String("invoice_id", invoice.Id.Hex()),
+			)
+		}
+	}
+
+	return nil
+}
+
+// onInvoicePaymentNotify marks the invoice as paid and links it to the successfully paid order.
+func (s *Service) onInvoicePaymentNotify(ctx context.Context, order *billingpb.Order) error {
+	if order.Issuer == nil || order.Issuer.ReferenceType != pkg.OrderIssuerReferenceTypeInvoice {
+		return nil
+	}
+
+	invoice, err := s.invoiceRepository.GetById(ctx, order.Issuer.Reference)
+
+	if err != nil {
+		return invoiceErrorNotFound
+	}
+
+	if invoice.Status == intPkg.InvoiceStatusPaid {
+		if invoice.OrderId == order.Id {
+			return nil
+		}
+
+		return s.onInvoiceDuplicatePayment(ctx, invoice, order)
+	}
+
+	invoice.Status = intPkg.InvoiceStatusPaid
+	invoice.OrderId = order.Id
+	invoice.ProcessingOrderId = ""
+	invoice.PaidAt = time.Now()
+	invoice.UpdatedAt = time.Now()
+
+	return s.invoiceRepository.Update(ctx, invoice)
+}
+
+// onInvoiceDuplicatePayment refunds the payment of the already paid invoice. The payment is saved in the invoice
+// and the merchant is notified to process the payment manually when the automatic refund failed.
+func (s *Service) onInvoiceDuplicatePayment(ctx context.Context, invoice *intPkg.Invoice, order *billingpb.Order) error {
+	for _, payment := range invoice.DuplicatePayments {
+		if payment.OrderId == order.Id {
+			return nil
+		}
+	}
+
+	payment := &intPkg.InvoiceDuplicatePayment{
+		OrderId:   order.Id,
+		Amount:    order.ChargeAmount,
+		Currency:  order.ChargeCurrency,
+		CreatedAt: time.Now(),
+	}
+
+	// the refund is created by the system, so the creator is empty
+	req := &billingpb.CreateRefundRequest{
+		OrderId:    order.Uuid,
+		Amount:     order.ChargeAmount,
+		CreatorId:  primitive.NilObjectID.Hex(),
+		Reason:     fmt.Sprintf(invoiceDuplicatePaymentRefundReason, invoice.Id.Hex()),
+		MerchantId: order.GetMerchantId(),
+	}
+	rsp := &billingpb.CreateRefundResponse{}
+	err := s.CreateRefund(ctx, req, rsp)
+
+	if err == nil && rsp.Status == billingpb.ResponseStatusOk {
+		payment.RefundId = rsp.Item.Id
+	} else {
+		zap.L().Error(
+			"Refund of the duplicate invoice payment failed",
+			zap.Error(err),
+			zap.Any("response", rsp),
+			zap.String("invoice_id", invoice.Id.Hex()),
+			zap.String("invoice_order_id", invoice.OrderId),
+			zap.String("order_id", order.Id),
+		)
+
+		message := fmt.Sprintf(invoiceDuplicatePaymentNotificationMessage, invoice.Id.Hex(), order.Uuid)
+		_, err = s.addNotification(ctx, message, invoice.MerchantId.Hex(), "", nil)
+
+		if err != nil {
+			zap.L().Error(
+				"Notification about duplicate invoice payment failed",
+				zap.Error(err),
+				zap.String("invoice_id", invoice.Id.Hex()),
+				zap.String("order_id", order.Id),
+			)
+		}
+	}
+
+	invoice.DuplicatePayments = append(invoice.DuplicatePayments, payment)
+	invoice.UpdatedAt = time.Now()
+
+	return s.invoiceRepository.Update(ctx, invoice)
+}
+
+func (s *Service) validateInvoiceRequest(
+	ctx context.Context,
+	req *intPkg.CreateOrUpdateInvoiceRequest,
+) *billingpb.ResponseErrorMessage {
+	if _, err := mail.ParseAddress(req.CustomerEmail); err != nil {
+		return invoiceErrorCustomerEmail
+	}
+
+	if !helper.Contains(s.supportedCurrencies, req.Currency) {
+		return invoiceErrorCurrencyUnsupported
+	}
+
+	if req.DueDate.Before(time.Now()) {
+		return invoiceErrorDueDateInPast
+	}
+
+	if len(req.Items) <= 0 {
+		return invoiceErrorItemsEmpty
+	}
+
+	for _, item := range req.Items {
+		if item.Name == "" || item.Quantity <= 0 || item.Price <= 0 {
+			return invoiceErrorItemInvalid
+		}
+	}
+
+	project, err := s.project.GetById(ctx, req.ProjectId)
+
+	if err != nil || project.MerchantId != req.MerchantId {
+		return invoiceErrorProjectMismatch
+	}
+
+	return nil
+}
+
+func (s *Service) sendInvoiceEmail(ctx context.Context, invoice *intPkg.Invoice, template string) error {
+	merchant, err := s.merchantRepository.GetById(ctx, invoice.MerchantId.Hex())
+
+	if err != nil {
+		return merchantErrorNotFound
+	}
+
+	payload := &postmarkpb.Payload{
+		TemplateAlias: template,
+		TemplateModel: map[string]string{
+			"merchant_name":  merchant.GetCompany().GetName(),
+			"invoice_id":     invoice.Id.Hex(),
+			"total_amount":   strconv.FormatFloat(invoice.TotalAmount, 'f', int(s.getCurrencyPrecision(invoice.Currency)), 64),
+			"currency":       invoice.Currency,
+			"due_date":       invoice.DueDate.Format(time.RFC822),
+			"memo":           invoice.Memo,
+			"status":         invoice.Status,
+			"invoice_url":    s.cfg.GetInvoiceCheckoutUrl(invoice.Uuid),
+			"customer_email": invoice.CustomerEmail,
+		},
+		To: invoice.CustomerEmail,
+	}
+
+	err = s.postmarkBroker.Publish(postmarkpb.PostmarkSenderTopicName, payload, amqp.Table{})
+
+	if err != nil {
+		zap.L().Error(
+			"Publication message with invoice to customer email queue failed",
+			zap.Error(err),
+			zap.String("invoice_id", invoice.Id.Hex()),
+			zap.String("template", template),
+		)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,490 @@
+package service
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/mongodb"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/uuid"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	rabbitmq "gopkg.in/ProtocolONE/rabbitmq.v1/pkg"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"sync"
+	"testing"
+	"time"
+)
+
+type InvoiceTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant      *billingpb.Merchant
+	project       *billingpb.Project
+	paymentMethod *billingpb.PaymentMethod
+}
+
+func Test_Invoice(t *testing.T) {
+	suite.Run(t, new(InvoiceTestSuite))
+}
+
+func (suite *InvoiceTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+	cfg.CardPayApiUrl = "https://sandbox.cardpay.com"
+
+	m, err := migrate.New(
+		"file://../../migrations/tests",
+		cfg.MongoDsn)
+	assert.NoError(suite.T(), err, "Migrate init failed")
+
+	err = m.Up()
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	broker, err := rabbitmq.NewBroker(cfg.BrokerAddress)
+
+	if err != nil {
+		suite.FailNow("Creating RabbitMQ publisher failed", "%v", err)
+	}
+
+	redisClient := database.NewRedis(
+		&redis.Options{
+			Addr:     cfg.RedisHost,
+			Password: cfg.RedisPassword,
+		},
+	)
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		broker,
+		redisClient,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	suite.merchant, suite.project, suite.paymentMethod, _ = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+}
+
+func (suite *InvoiceTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *InvoiceTestSuite) getInvoiceRequest() *intPkg.CreateOrUpdateInvoiceRequest {
+	return &intPkg.CreateOrUpdateInvoiceRequest{
+		MerchantId:    suite.merchant.Id,
+		ProjectId:     suite.project.Id,
+		CustomerEmail: "customer@unit.test",
+		Currency:      "USD",
+		DueDate:       time.Now().AddDate(0, 0, 14),
+		Memo:          "Consulting services",
+		Items: []*intPkg.InvoiceItem{
+			{Name: "Consulting", Quantity: 3, Price: 100.5},
+			{Name: "Support", Quantity: 1, Price: 49.99},
+		},
+	}
+}
+
+func (suite *InvoiceTestSuite) createInvoice(status string) *intPkg.Invoice {
+	res := &intPkg.InvoiceResponse{}
+	err := suite.service.CreateOrUpdateInvoice(context.TODO(), suite.getInvoiceRequest(), res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+
+	if status != intPkg.InvoiceStatusDraft {
+		res.Item.Status = status
+		err = suite.service.invoiceRepository.Update(context.TODO(), res.Item)
+		assert.NoError(suite.T(), err)
+	}
+
+	return res.Item
+}
+
+func (suite *InvoiceTestSuite) TestInvoice_CreateOrUpdateInvoice_Ok() {
+	res := &intPkg.InvoiceResponse{}
+	err := suite.service.CreateOrUpdateInvoice(context.TODO(), suite.getInvoiceRequest(), res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Equal(suite.T(), intPkg.InvoiceStatusDraft, res.Item.Status)
+	assert.Equal(suite.T(), 351.49, res.Item.TotalAmount)
+	assert.Equal(suite.T(), 301.5, res.Item.Items[0].Amount)
+	assert.NotEmpty(suite.T(), res.Item.Uuid)
+
+	req := suite.getInvoiceRequest()
+	req.Id = res.Item.Id.Hex()
+	req.Items = req.Items[1:]
+	res2 := &intPkg.InvoiceResponse{}
+	err = suite.service.CreateOrUpdateInvoice(context.TODO(), req, res2)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res2.Status)
+	assert.Equal(suite.T(), res.Item.Uuid, res2.Item.Uuid)
+	assert.Equal(suite.T(), 49.99, res2.Item.TotalAmount)
+}
+
+func (suite *InvoiceTestSuite) TestInvoice_CreateOrUpdateInvoice_ValidationError() {
+	req := suite.getInvoiceRequest()
+	req.CustomerEmail = "customer"
+	res := &intPkg.InvoiceResponse{}
+	err := suite.service.CreateOrUpdateInvoice(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), invoiceErrorCustomerEmail, res.Message)
+
+	req = suite.getInvoiceRequest()
+	req.Items = nil
+	err = suite.service.CreateOrUpdateInvoice(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), invoiceErrorItemsEmpty, res.Message)
+
+	req = suite.getInvoiceRequest()
+	req.Items[0].Quantity = 0
+	err = suite.service.CreateOrUpdateInvoice(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), invoiceErrorItemInvalid, res.Message)
+
+	req = suite.getInvoiceRequest()
+	req.DueDate = time.Now().AddDate(0, 0, -1)
+	err = suite.service.CreateOrUpdateInvoice(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), invoiceErrorDueDateInPast, res.Message)
+
+	req = suite.getInvoiceRequest()
+	req.MerchantId = primitive.NewObjectID().Hex()
+	err = suite.service.CreateOrUpdateInvoice(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), invoiceErrorProjectMismatch, res.Message)
+}
+
+func (suite *InvoiceTestSuite) TestInvoice_CreateOrUpdateInvoice_NotDraft() {
+	invoice := suite.createInvoice(intPkg.InvoiceStatusSent)
+
+	req := suite.getInvoiceRequest()
+	req.Id = invoice.Id.Hex()
+	res := &intPkg.InvoiceResponse{}
+	err := suite.service.CreateOrUpdateInvoice(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), invoiceErrorStatusInvalid, res.Message)
+}
+
+func (suite *InvoiceTestSuite) TestInvoice_SendInvoice_Ok() {
+	invoice := suite.createInvoice(intPkg.InvoiceStatusDraft)
+
+	urlRes := &intPkg.GetInvoiceUrlResponse{}
+	req := &intPkg.InvoiceRequest{Id: invoice.Id.Hex(), MerchantId: suite.merchant.Id}
+	err := suite.service.GetInvoiceUrl(context.TODO(), req, urlRes)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, urlRes.Status)
+	assert.Equal(suite.T(), invoiceErrorNotPayable, urlRes.Message)
+
+	res := &intPkg.InvoiceResponse{}
+	err = suite.service.SendInvoice(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Equal(suite.T(), intPkg.InvoiceStatusSent, res.Item.Status)
+	assert.False(suite.T(), res.Item.SentAt.IsZero())
+
+	err = suite.service.GetInvoiceUrl(context.TODO(), req, urlRes)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, urlRes.Status)
+	assert.Equal(suite.T(), suite.service.cfg.GetInvoiceCheckoutUrl(invoice.Uuid), urlRes.Url)
+}
+
+func (suite *InvoiceTestSuite) TestInvoice_VoidInvoice() {
+	invoice := suite.createInvoice(intPkg.InvoiceStatusSent)
+	req := &intPkg.InvoiceRequest{Id: invoice.Id.Hex(), MerchantId: suite.merchant.Id}
+
+	res := &intPkg.InvoiceResponse{}
+	err := suite.service.VoidInvoice(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Equal(suite.T(), intPkg.InvoiceStatusVoid, res.Item.Status)
+
+	err = suite.service.SendInvoice(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), invoiceErrorStatusInvalid, res.Message)
+}
+
+func (suite *InvoiceTestSuite) TestInvoice_ListInvoices() {
+	suite.createInvoice(intPkg.InvoiceStatusDraft)
+	suite.createInvoice(intPkg.InvoiceStatusSent)
+
+	res := &intPkg.ListInvoicesResponse{}
+	err := suite.service.ListInvoices(context.TODO(), &intPkg.ListInvoicesRequest{MerchantId: suite.merchant.Id}, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.EqualValues(suite.T(), 2, res.Count)
+	assert.Len(suite.T(), res.Items, 2)
+
+	req := &intPkg.ListInvoicesRequest{MerchantId: suite.merchant.Id, Status: intPkg.InvoiceStatusSent}
+	err = suite.service.ListInvoices(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 1, res.Count)
+	assert.Equal(suite.T(), intPkg.InvoiceStatusSent, res.Items[0].Status)
+}
+
+func (suite *InvoiceTestSuite) TestInvoice_ProcessInvoices() {
+	overdue := suite.createInvoice(intPkg.InvoiceStatusSent)
+	overdue.DueDate = time.Now().Add(-time.Hour)
+	err := suite.service.invoiceRepository.Update(context.TODO(), overdue)
+	assert.NoError(suite.T(), err)
+
+	upcoming := suite.createInvoice(intPkg.InvoiceStatusSent)
+	upcoming.DueDate = time.Now().Add(time.Hour)
+	err = suite.service.invoiceRepository.Update(context.TODO(), upcoming)
+	assert.NoError(suite.T(), err)
+
+	later := suite.createInvoice(intPkg.InvoiceStatusSent)
+
+	err = suite.service.ProcessInvoices(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	overdue, err = suite.service.invoiceRepository.GetById(context.TODO(), overdue.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), intPkg.InvoiceStatusOverdue, overdue.Status)
+
+	upcoming, err = suite.service.invoiceRepository.GetById(context.TODO(), upcoming.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), intPkg.InvoiceStatusSent, upcoming.Status)
+	assert.False(suite.T(), upcoming.RemindedAt.IsZero())
+
+	later, err = suite.service.invoiceRepository.GetById(context.TODO(), later.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), later.RemindedAt.IsZero())
+}
+
+func (suite *InvoiceTestSuite) TestInvoice_OrderCreateByInvoice_NotPayable() {
+	invoice := suite.createInvoice(intPkg.InvoiceStatusDraft)
+
+	req := &intPkg.OrderCreateByInvoiceRequest{InvoiceUuid: invoice.Uuid, PayerIp: "127.0.0.1"}
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateByInvoice(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusGone, rsp.Status)
+	assert.Equal(suite.T(), invoiceErrorNotPayable, rsp.Message)
+
+	req.InvoiceUuid = "unknown"
+	err = suite.service.OrderCreateByInvoice(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rsp.Status)
+	assert.Equal(suite.T(), invoiceErrorNotFound, rsp.Message)
+}
+
+func (suite *InvoiceTestSuite) TestInvoice_OrderCreateByInvoice_Paid() {
+	invoice := suite.createInvoice(intPkg.InvoiceStatusSent)
+
+	req := &intPkg.OrderCreateByInvoiceRequest{InvoiceUuid: invoice.Uuid, PayerIp: "127.0.0.1"}
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateByInvoice(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), pkg.OrderIssuerReferenceTypeInvoice, rsp.Item.Issuer.ReferenceType)
+	assert.Equal(suite.T(), invoice.Id.Hex(), rsp.Item.Issuer.Reference)
+	assert.Equal(suite.T(), invoice.TotalAmount, rsp.Item.OrderAmount)
+
+	req1 := &billingpb.ProcessBillingAddressRequest{
+		OrderId: rsp.Item.Uuid,
+		Country: "RU",
+		Zip:     "123345",
+	}
+	rsp1 := &billingpb.ProcessBillingAddressResponse{}
+	err = suite.service.ProcessBillingAddress(context.TODO(), req1, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp1.Status)
+
+	order, err := suite.service.orderRepository.GetById(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+
+	order = HelperPayOrder(suite.Suite, suite.service, order, suite.paymentMethod, "RU")
+
+	invoice, err = suite.service.invoiceRepository.GetById(context.TODO(), invoice.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), intPkg.InvoiceStatusPaid, invoice.Status)
+	assert.Equal(suite.T(), order.Id, invoice.OrderId)
+	assert.False(suite.T(), invoice.PaidAt.IsZero())
+}
+
+func (suite *InvoiceTestSuite) TestInvoice_OrderCreateByInvoice_OrderProcessing() {
+	invoice := suite.createInvoice(intPkg.InvoiceStatusSent)
+
+	req := &intPkg.OrderCreateByInvoiceRequest{InvoiceUuid: invoice.Uuid, PayerIp: "127.0.0.1"}
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateByInvoice(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	invoice, err = suite.service.invoiceRepository.GetById(context.TODO(), invoice.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), rsp.Item.Id, invoice.ProcessingOrderId)
+
+	rsp1 := &billingpb.OrderCreateProcessResponse{}
+	err = suite.service.OrderCreateByInvoice(context.TODO(), req, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp1.Status)
+	assert.Equal(suite.T(), invoiceErrorOrderProcessing, rsp1.Message)
+
+	order, err := suite.service.orderRepository.GetById(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+	order.ExpireDateToFormInput, _ = ptypes.TimestampProto(time.Now().Add(-time.Minute))
+	err = suite.service.orderRepository.Update(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	rsp2 := &billingpb.OrderCreateProcessResponse{}
+	err = suite.service.OrderCreateByInvoice(context.TODO(), req, rsp2)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp2.Status)
+	assert.NotEqual(suite.T(), rsp.Item.Id, rsp2.Item.Id)
+}
+
+func (suite *InvoiceTestSuite) TestInvoice_OrderCreateByInvoice_Concurrent_OneOrder() {
+	invoice := suite.createInvoice(intPkg.InvoiceStatusSent)
+
+	count := 5
+	results := make(chan *billingpb.OrderCreateProcessResponse, count)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			req := &intPkg.OrderCreateByInvoiceRequest{InvoiceUuid: invoice.Uuid, PayerIp: "127.0.0.1"}
+			rsp := &billingpb.OrderCreateProcessResponse{}
+			err := suite.service.OrderCreateByInvoice(context.TODO(), req, rsp)
+			assert.NoError(suite.T(), err)
+			results <- rsp
+		}()
+	}
+
+	wg.Wait()
+	close(results)
+
+	var created []*billingpb.Order
+
+	for rsp := range results {
+		if rsp.Status == billingpb.ResponseStatusOk {
+			created = append(created, rsp.Item)
+			continue
+		}
+
+		assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+		assert.Equal(suite.T(), invoiceErrorOrderProcessing, rsp.Message)
+	}
+
+	assert.Len(suite.T(), created, 1)
+
+	invoice, err := suite.service.invoiceRepository.GetById(context.TODO(), invoice.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), created[0].Id, invoice.ProcessingOrderId)
+}
+
+func (suite *InvoiceTestSuite) TestInvoice_OrderCreateByInvoice_ReservedCheckout() {
+	invoice := suite.createInvoice(intPkg.InvoiceStatusSent)
+
+	// the other checkout reserved the invoice and is creating the order
+	reservationId := primitive.NewObjectID().Hex()
+	err := suite.service.invoiceRepository.SetProcessingOrderId(context.TODO(), invoice.Id, "", reservationId)
+	assert.NoError(suite.T(), err)
+
+	req := &intPkg.OrderCreateByInvoiceRequest{InvoiceUuid: invoice.Uuid, PayerIp: "127.0.0.1"}
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err = suite.service.OrderCreateByInvoice(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), invoiceErrorOrderProcessing, rsp.Message)
+
+	// the reservation of the failed checkout is ignored after the timeout
+	invoice, err = suite.service.invoiceRepository.GetById(context.TODO(), invoice.Id.Hex())
+	assert.NoError(suite.T(), err)
+	invoice.ProcessingStartedAt = time.Now().Add(-invoiceCheckoutReservationTimeout)
+	err = suite.service.invoiceRepository.Update(context.TODO(), invoice)
+	assert.NoError(suite.T(), err)
+
+	rsp = &billingpb.OrderCreateProcessResponse{}
+	err = suite.service.OrderCreateByInvoice(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+}
+
+func (suite *InvoiceTestSuite) TestInvoice_onInvoicePaymentNotify_DuplicatePayment() {
+	invoice := suite.createInvoice(intPkg.InvoiceStatusPaid)
+	invoice.OrderId = primitive.NewObjectID().Hex()
+	err := suite.service.invoiceRepository.Update(context.TODO(), invoice)
+	assert.NoError(suite.T(), err)
+
+	order := &billingpb.Order{
+		Id:             primitive.NewObjectID().Hex(),
+		Uuid:           uuid.New().String(),
+		ChargeAmount:   invoice.TotalAmount,
+		ChargeCurrency: invoice.Currency,
+		Project:        &billingpb.ProjectOrder{Id: suite.project.Id, MerchantId: suite.merchant.Id},
+		Issuer: &billingpb.OrderIssuer{
+			ReferenceType: pkg.OrderIssuerReferenceTypeInvoice,
+			Reference:     invoice.Id.Hex(),
+		},
+	}
+	err = suite.service.onInvoicePaymentNotify(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	// the second notification about the same order doesn't duplicate the payment
+	err = suite.service.onInvoicePaymentNotify(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	invoice2, err := suite.service.invoiceRepository.GetById(context.TODO(), invoice.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), intPkg.InvoiceStatusPaid, invoice2.Status)
+	assert.Equal(suite.T(), invoice.OrderId, invoice2.OrderId)
+	assert.Len(suite.T(), invoice2.DuplicatePayments, 1)
+	assert.Equal(suite.T(), order.Id, invoice2.DuplicatePayments[0].OrderId)
+	assert.Equal(suite.T(), order.ChargeAmount, invoice2.DuplicatePayments[0].Amount)
+	// the order isn't in the database, so the refund failed and the payment is flagged for the merchant
+	assert.Empty(suite.T(), invoice2.DuplicatePayments[0].RefundId)
+}
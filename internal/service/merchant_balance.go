@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"github.com/golang/protobuf/ptypes"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -55,6 +56,52 @@ func (s *Service) GetMerchantBalance(
 	return nil
 }
 
+// GetMerchantBalances returns the balances of the merchant in all payout currencies,
+// the balance in the main payout currency goes first.
+func (s *Service) GetMerchantBalances(
+	ctx context.Context,
+	req *billingpb.GetMerchantBalanceRequest,
+	res *intPkg.GetMerchantBalancesResponse,
+) error {
+	merchant, err := s.merchantRepository.GetById(ctx, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = merchantErrorNotFound
+		return nil
+	}
+
+	if merchant.GetPayoutCurrency() == "" {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = errorMerchantPayoutCurrencyNotSet
+		return nil
+	}
+
+	currencies, err := s.getMerchantPayoutCurrencies(ctx, merchant)
+
+	if err != nil {
+		return err
+	}
+
+	for _, currency := range currencies {
+		balance, err := s.merchantBalanceRepository.GetByIdAndCurrency(ctx, merchant.Id, currency)
+
+		if err == mongo.ErrNoDocuments {
+			balance, err = s.updateMerchantCurrencyBalance(ctx, merchant, currency)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		res.Items = append(res.Items, balance)
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+
+	return nil
+}
+
 func (s *Service) getMerchantBalance(ctx context.Context, merchantId string) (*billingpb.MerchantBalance, error) {
 	merchant, err := s.merchantRepository.GetById(ctx, merchantId)
 	if err != nil {
@@ -69,6 +116,8 @@ func (s *Service) getMerchantBalance(ctx context.Context, merchantId string) (*b
 	return s.merchantBalanceRepository.GetByIdAndCurrency(ctx, merchant.Id, merchant.GetPayoutCurrency())
 }
 
+// updateMerchantBalance recalculates the balances of the merchant in all payout currencies
+// and returns the balance in the main payout currency.
 func (s *Service) updateMerchantBalance(ctx context.Context, merchantId string) (*billingpb.MerchantBalance, error) {
 	merchant, err := s.merchantRepository.GetById(ctx, merchantId)
 	if err != nil {
@@ -80,39 +129,72 @@ func (s *Service) updateMerchantBalance(ctx context.Context, merchantId string)
 		return nil, errorMerchantPayoutCurrencyNotSet
 	}
 
-	debit, err := s.royaltyReportRepository.GetBalanceAmount(ctx, merchant.Id, merchant.GetPayoutCurrency())
+	currencies, err := s.getMerchantPayoutCurrencies(ctx, merchant)
+	if err != nil {
+		return nil, err
+	}
+
+	var balance *billingpb.MerchantBalance
+
+	for _, currency := range currencies {
+		currencyBalance, err := s.updateMerchantCurrencyBalance(ctx, merchant, currency)
+		if err != nil {
+			return nil, err
+		}
+
+		if currency == merchant.GetPayoutCurrency() {
+			balance = currencyBalance
+		}
+	}
+
+	return balance, nil
+}
+
+func (s *Service) updateMerchantCurrencyBalance(
+	ctx context.Context,
+	merchant *billingpb.Merchant,
+	currency string,
+) (*billingpb.MerchantBalance, error) {
+	debit, err := s.royaltyReportRepository.GetBalanceAmount(ctx, merchant.Id, currency)
 	if err != nil {
 		return nil, err
 	}
 
-	credit, err := s.payoutRepository.GetBalanceAmount(ctx, merchant.Id, merchant.GetPayoutCurrency())
+	credit, err := s.payoutRepository.GetBalanceAmount(ctx, merchant.Id, currency)
 	if err != nil {
 		return nil, err
 	}
 
-	rr, err := s.getRollingReserveForBalance(ctx, merchantId, merchant.GetPayoutCurrency())
+	rr, err := s.getRollingReserveForBalance(ctx, merchant.Id, currency)
 	if err != nil {
 		return nil, err
 	}
 
+	debitAmount := s.newMoney(debit, currency).Round()
+	creditAmount := s.newMoney(credit, currency).Round()
+	rollingReserveAmount := s.newMoney(rr, currency).Round()
+
 	balance := &billingpb.MerchantBalance{
 		Id:             primitive.NewObjectID().Hex(),
-		MerchantId:     merchantId,
-		Currency:       merchant.GetPayoutCurrency(),
-		Debit:          debit,
-		Credit:         credit,
-		RollingReserve: rr,
+		MerchantId:     merchant.Id,
+		Currency:       currency,
+		Debit:          debitAmount.Float64(),
+		Credit:         creditAmount.Float64(),
+		RollingReserve: rollingReserveAmount.Float64(),
+		Total:          debitAmount.Sub(creditAmount, rollingReserveAmount).Float64(),
 		CreatedAt:      ptypes.TimestampNow(),
 	}
 
-	balance.Total = balance.Debit - balance.Credit - balance.RollingReserve
-
 	err = s.merchantBalanceRepository.Insert(ctx, balance)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if err = s.syncMerchantDebt(ctx, balance); err != nil {
+		return nil, err
+	}
+
 	return balance, nil
 }
 
@@ -142,16 +224,21 @@ func (s *Service) getRollingReserveForBalance(ctx context.Context, merchantId, c
 		return 0, nil
 	}
 
-	result := float64(0)
+	return s.sumRollingReserveEntries(items, currency).Round().Float64(), nil
+}
+
+// sumRollingReserveEntries returns the rolling reserve amount of the created reserves minus the released ones.
+func (s *Service) sumRollingReserveEntries(items []*intPkg.ReserveQueryResItem, currency string) intPkg.Money {
+	result := s.newMoney(0, currency)
 
 	for _, i := range items {
 		// in case of rolling reserve release, result will have negative amount, it is ok
 		if i.Type == pkg.AccountingEntryTypeMerchantRollingReserveRelease {
-			result -= i.Amount
+			result = result.Sub(s.newMoney(i.Amount, currency))
 		} else {
-			result += i.Amount
+			result = result.Add(s.newMoney(i.Amount, currency))
 		}
 	}
 
-	return result, nil
+	return result
 }
@@ -0,0 +1,388 @@
+package service
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"time"
+)
+
+const (
+	merchantBalanceLedgerAccountingKeyPrefix = "accounting:"
+)
+
+var (
+	merchantBalanceLedgerErrorMerchantNotFound = newBillingServerErrorMsg("bl000001", "merchant not found")
+	merchantBalanceLedgerErrorAccessDenied     = newBillingServerErrorMsg("bl000002", "only admin can fix the merchant balance ledger")
+
+	// merchantBalanceLedgerPendingSigns contains the accounting entries forming the merchant revenue
+	// with the sign of the entry amount in the revenue
+	merchantBalanceLedgerPendingSigns = map[string]float64{
+		pkg.AccountingEntryTypeRealGrossRevenue:            1,
+		pkg.AccountingEntryTypeReverseTaxFee:               1,
+		pkg.AccountingEntryTypePsGrossRevenueFx:            -1,
+		pkg.AccountingEntryTypeMerchantTaxFeeCentralBankFx: -1,
+		pkg.AccountingEntryTypeMerchantTaxFeeCostValue:     -1,
+		pkg.AccountingEntryTypePsMethodFee:                 -1,
+		pkg.AccountingEntryTypeMerchantPsFixedFee:          -1,
+		pkg.AccountingEntryTypeMerchantRefund:              -1,
+		pkg.AccountingEntryTypeMerchantRefundFee:           -1,
+		pkg.AccountingEntryTypeMerchantRefundFixedFee:      -1,
+		pkg.AccountingEntryTypeReverseTaxFeeDelta:          -1,
+		pkg.AccountingEntryTypeMerchantRoyaltyCorrection:   -1,
+	}
+
+	merchantBalanceLedgerReservedSigns = map[string]float64{
+		pkg.AccountingEntryTypeMerchantRollingReserveCreate:  1,
+		pkg.AccountingEntryTypeMerchantRollingReserveRelease: -1,
+	}
+
+	royaltyReportStatusesForBalance = map[string]bool{
+		billingpb.RoyaltyReportStatusAccepted:       true,
+		billingpb.RoyaltyReportStatusWaitForPayment: true,
+		billingpb.RoyaltyReportStatusPaid:           true,
+	}
+
+	payoutDocumentStatusesForBalance = map[string]bool{
+		pkg.PayoutDocumentStatusPending: true,
+		pkg.PayoutDocumentStatusPaid:    true,
+	}
+)
+
+// GetMerchantBalanceLedger returns the incrementally updated balances of the merchant in all currencies.
+func (s *Service) GetMerchantBalanceLedger(
+	ctx context.Context,
+	req *intPkg.GetMerchantBalanceLedgerRequest,
+	res *intPkg.GetMerchantBalanceLedgerResponse,
+) error {
+	if _, err := s.merchantRepository.GetById(ctx, req.MerchantId); err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = merchantBalanceLedgerErrorMerchantNotFound
+		return nil
+	}
+
+	items, err := s.merchantBalanceLedgerRepository.GetByMerchantId(ctx, req.MerchantId)
+
+	if err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Items = items
+
+	if res.Items == nil {
+		res.Items = []*intPkg.MerchantBalanceLedger{}
+	}
+
+	return nil
+}
+
+// ReconcileMerchantBalanceLedger compares the ledger balances with the balances recomputed from the royalty reports,
+// payouts and rolling reserve accounting entries. The differences are written into the ledger if the fix is requested.
+func (s *Service) ReconcileMerchantBalanceLedger(
+	ctx context.Context,
+	req *intPkg.ReconcileMerchantBalanceLedgerRequest,
+	res *intPkg.ReconcileMerchantBalanceLedgerResponse,
+) error {
+	if req.Fix && !s.hasAdminRole(ctx, req.UserId, billingpb.RoleSystemAdmin, billingpb.RoleSystemFinancial) {
+		res.Status = billingpb.ResponseStatusForbidden
+		res.Message = merchantBalanceLedgerErrorAccessDenied
+		return nil
+	}
+
+	var merchants []*billingpb.Merchant
+
+	if req.MerchantId != "" {
+		merchant, err := s.merchantRepository.GetById(ctx, req.MerchantId)
+
+		if err != nil {
+			res.Status = billingpb.ResponseStatusNotFound
+			res.Message = merchantBalanceLedgerErrorMerchantNotFound
+			return nil
+		}
+
+		merchants = append(merchants, merchant)
+	} else {
+		var err error
+		merchants, err = s.merchantRepository.GetAll(ctx)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	res.Items = []*intPkg.MerchantBalanceReconciliationItem{}
+
+	for _, merchant := range merchants {
+		items, err := s.reconcileMerchantBalanceLedger(ctx, merchant, req.Fix)
+
+		if err != nil {
+			return err
+		}
+
+		res.Items = append(res.Items, items...)
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+
+	return nil
+}
+
+func (s *Service) reconcileMerchantBalanceLedger(
+	ctx context.Context,
+	merchant *billingpb.Merchant,
+	fix bool,
+) ([]*intPkg.MerchantBalanceReconciliationItem, error) {
+	ledgers, err := s.merchantBalanceLedgerRepository.GetByMerchantId(ctx, merchant.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	byCurrency := make(map[string]*intPkg.MerchantBalanceLedger)
+	var currencies []string
+
+	if merchant.GetPayoutCurrency() != "" {
+		currencies = append(currencies, merchant.GetPayoutCurrency())
+	}
+
+	for _, ledger := range ledgers {
+		byCurrency[ledger.Currency] = ledger
+
+		if ledger.Currency != merchant.GetPayoutCurrency() {
+			currencies = append(currencies, ledger.Currency)
+		}
+	}
+
+	var items []*intPkg.MerchantBalanceReconciliationItem
+
+	for _, currency := range currencies {
+		debit, err := s.royaltyReportRepository.GetBalanceAmount(ctx, merchant.Id, currency)
+
+		if err != nil {
+			return nil, err
+		}
+
+		credit, err := s.payoutRepository.GetBalanceAmount(ctx, merchant.Id, currency)
+
+		if err != nil {
+			return nil, err
+		}
+
+		entries, err := s.accountingRepository.GetRollingReserveForBalance(
+			ctx, merchant.Id, currency, accountingEntriesForRollingReserve, time.Time{},
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		ledgerAvailable := s.newMoney(0, currency)
+		ledgerReserved := s.newMoney(0, currency)
+
+		if ledger, ok := byCurrency[currency]; ok {
+			ledgerAvailable = s.newMoney(ledger.Available, currency).Round()
+			ledgerReserved = s.newMoney(ledger.Reserved, currency).Round()
+		}
+
+		computedAvailable := s.newMoney(debit, currency).Sub(s.newMoney(credit, currency)).Round()
+		computedReserved := s.sumRollingReserveEntries(entries, currency).Round()
+		availableDifference := computedAvailable.Sub(ledgerAvailable).Round()
+		reservedDifference := computedReserved.Sub(ledgerReserved).Round()
+
+		item := &intPkg.MerchantBalanceReconciliationItem{
+			MerchantId:          merchant.Id,
+			Currency:            currency,
+			LedgerAvailable:     ledgerAvailable.Float64(),
+			ComputedAvailable:   computedAvailable.Float64(),
+			AvailableDifference: availableDifference.Float64(),
+			LedgerReserved:      ledgerReserved.Float64(),
+			ComputedReserved:    computedReserved.Float64(),
+			ReservedDifference:  reservedDifference.Float64(),
+			Matched:             availableDifference.IsZero() && reservedDifference.IsZero(),
+		}
+
+		if !item.Matched {
+			zap.L().Warn(
+				"Merchant balance ledger doesn't match the recomputed balance",
+				zap.String("merchant_id", merchant.Id),
+				zap.String("currency", currency),
+				zap.Float64("available_difference", item.AvailableDifference),
+				zap.Float64("reserved_difference", item.ReservedDifference),
+			)
+
+			if fix {
+				entry := &intPkg.MerchantBalanceLedgerEntry{
+					Event:     intPkg.MerchantBalanceLedgerEventReconciliation,
+					Available: item.AvailableDifference,
+					Reserved:  item.ReservedDifference,
+				}
+
+				if err = s.applyMerchantBalanceLedgerEntry(ctx, merchant.Id, currency, entry); err != nil {
+					return nil, err
+				}
+
+				item.Fixed = true
+			}
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// applyAccountingEntriesToBalanceLedger updates the merchant balances by the saved accounting entries.
+// Entries are grouped by the merchant, currency and source, the key of the group is the id of its first entry,
+// so the repeated apply of the same entries is ignored.
+func (s *Service) applyAccountingEntriesToBalanceLedger(ctx context.Context, entries []*billingpb.AccountingEntry) error {
+	type ledgerGroup struct {
+		merchantId string
+		currency   string
+		sourceId   string
+		pending    intPkg.Money
+		reserved   intPkg.Money
+		key        string
+	}
+
+	var groups []*ledgerGroup
+	index := make(map[string]*ledgerGroup)
+
+	for _, entry := range entries {
+		pendingSign, isPending := merchantBalanceLedgerPendingSigns[entry.Type]
+		reservedSign, isReserved := merchantBalanceLedgerReservedSigns[entry.Type]
+
+		if entry.MerchantId == "" || entry.Currency == "" || (!isPending && !isReserved) {
+			continue
+		}
+
+		sourceId := ""
+
+		if entry.Source != nil {
+			sourceId = entry.Source.Id
+		}
+
+		groupKey := entry.MerchantId + entry.Currency + sourceId
+		group, ok := index[groupKey]
+
+		if !ok {
+			group = &ledgerGroup{
+				merchantId: entry.MerchantId,
+				currency:   entry.Currency,
+				sourceId:   sourceId,
+				pending:    s.newMoney(0, entry.Currency),
+				reserved:   s.newMoney(0, entry.Currency),
+				key:        merchantBalanceLedgerAccountingKeyPrefix + entry.Id,
+			}
+			index[groupKey] = group
+			groups = append(groups, group)
+		}
+
+		amount := s.newMoney(entry.Amount, entry.Currency)
+
+		if isPending {
+			group.pending = group.pending.Add(amount.Mul(pendingSign))
+		}
+
+		if isReserved {
+			group.reserved = group.reserved.Add(amount.Mul(reservedSign))
+		}
+	}
+
+	for _, group := range groups {
+		ledgerEntry := &intPkg.MerchantBalanceLedgerEntry{
+			Key:      group.key,
+			Event:    intPkg.MerchantBalanceLedgerEventAccounting,
+			SourceId: group.sourceId,
+			Pending:  group.pending.Round().Float64(),
+			Reserved: group.reserved.Round().Float64(),
+		}
+
+		if err := s.applyMerchantBalanceLedgerEntry(ctx, group.merchantId, group.currency, ledgerEntry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyRoyaltyReportToBalanceLedger moves the royalty report amount from the pending to the available balance
+// when the report becomes accepted and back when the report leaves the accepted statuses.
+func (s *Service) applyRoyaltyReportToBalanceLedger(
+	ctx context.Context,
+	report *billingpb.RoyaltyReport,
+	previousStatus string,
+) error {
+	if royaltyReportStatusesForBalance[previousStatus] == royaltyReportStatusesForBalance[report.Status] ||
+		report.Totals == nil {
+		return nil
+	}
+
+	amount := s.newMoney(report.Totals.PayoutAmount, report.Currency).
+		Sub(s.newMoney(report.Totals.CorrectionAmount, report.Currency))
+
+	if !royaltyReportStatusesForBalance[report.Status] {
+		amount = amount.Neg()
+	}
+
+	entry := &intPkg.MerchantBalanceLedgerEntry{
+		Event:     intPkg.MerchantBalanceLedgerEventRoyaltyReport,
+		SourceId:  report.Id,
+		Pending:   amount.Neg().Round().Float64(),
+		Available: amount.Round().Float64(),
+	}
+
+	return s.applyMerchantBalanceLedgerEntry(ctx, report.MerchantId, report.Currency, entry)
+}
+
+// applyPayoutDocumentToBalanceLedger decreases the available balance by the payout amount when the payout
+// document becomes active and returns the amount when the payout is failed or canceled.
+func (s *Service) applyPayoutDocumentToBalanceLedger(
+	ctx context.Context,
+	pd *billingpb.PayoutDocument,
+	previousStatus string,
+) error {
+	if payoutDocumentStatusesForBalance[previousStatus] == payoutDocumentStatusesForBalance[pd.Status] {
+		return nil
+	}
+
+	amount := s.newMoney(pd.TotalFees, pd.Currency)
+
+	if payoutDocumentStatusesForBalance[pd.Status] {
+		amount = amount.Neg()
+	}
+
+	entry := &intPkg.MerchantBalanceLedgerEntry{
+		Event:     intPkg.MerchantBalanceLedgerEventPayout,
+		SourceId:  pd.Id,
+		Available: amount.Round().Float64(),
+	}
+
+	return s.applyMerchantBalanceLedgerEntry(ctx, pd.MerchantId, pd.Currency, entry)
+}
+
+func (s *Service) applyMerchantBalanceLedgerEntry(
+	ctx context.Context,
+	merchantId, currency string,
+	entry *intPkg.MerchantBalanceLedgerEntry,
+) error {
+	oid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		return err
+	}
+
+	entry.Id = primitive.NewObjectID()
+	entry.MerchantId = oid
+	entry.Currency = currency
+	entry.CreatedAt = time.Now()
+
+	if entry.Key == "" {
+		entry.Key = entry.Event + ":" + entry.Id.Hex()
+	}
+
+	return s.merchantBalanceLedgerRepository.Apply(ctx, entry)
+}
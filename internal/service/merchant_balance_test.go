@@ -10,6 +10,7 @@ import (
 	"github.com/paysuper/paysuper-billing-server/internal/config"
 	"github.com/paysuper/paysuper-billing-server/internal/database"
 	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
@@ -24,6 +25,7 @@ import (
 	rabbitmq "gopkg.in/ProtocolONE/rabbitmq.v1/pkg"
 	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
 	"net/http"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -494,6 +496,19 @@ func (suite *MerchantBalanceTestSuite) TestMerchantBalance_UpdateBalanceTriggeri
 	err = suite.service.payoutRepository.Insert(ctx, payout, "127.0.0.1", payoutChangeSourceAdmin)
 	assert.NoError(suite.T(), err)
 
+	approval := &intPkg.PayoutApproval{
+		Id:                primitive.NewObjectID(),
+		PayoutDocumentId:  payout.Id,
+		MerchantId:        payout.MerchantId,
+		Amount:            payout.Balance,
+		Currency:          payout.Currency,
+		Status:            intPkg.PayoutApprovalStatusApproved,
+		RequiredApprovals: 1,
+		ApprovedBy:        []string{primitive.NewObjectID().Hex()},
+	}
+	err = suite.service.payoutApprovalRepository.Insert(ctx, approval)
+	assert.NoError(suite.T(), err)
+
 	req3 := &billingpb.UpdatePayoutDocumentRequest{
 		PayoutDocumentId: payout.Id,
 		Status:           pkg.PayoutDocumentStatusPaid,
@@ -714,6 +729,452 @@ func (suite *MerchantBalanceTestSuite) TestMerchantBalance_UpdateBalanceTriggeri
 	assert.Equal(suite.T(), mbRes.Item.Total, float64(500))
 }
 
+func (suite *MerchantBalanceTestSuite) TestMerchantBalance_BalanceLedger_AccountingEntries_Ok() {
+	source := &billingpb.AccountingEntrySource{Type: "order", Id: primitive.NewObjectID().Hex()}
+	entries := []*billingpb.AccountingEntry{
+		{
+			Type:       pkg.AccountingEntryTypeRealGrossRevenue,
+			Object:     pkg.ObjectTypeBalanceTransaction,
+			Source:     source,
+			MerchantId: suite.merchant.Id,
+			Amount:     100,
+			Currency:   "RUB",
+		},
+		{
+			Type:       pkg.AccountingEntryTypePsMethodFee,
+			Object:     pkg.ObjectTypeBalanceTransaction,
+			Source:     source,
+			MerchantId: suite.merchant.Id,
+			Amount:     10,
+			Currency:   "RUB",
+		},
+		{
+			Type:       pkg.AccountingEntryTypeMerchantRollingReserveCreate,
+			Object:     pkg.ObjectTypeBalanceTransaction,
+			Source:     source,
+			MerchantId: suite.merchant.Id,
+			Amount:     20,
+			Currency:   "RUB",
+		},
+	}
+	err := suite.service.insertAccountingEntries(ctx, entries)
+	assert.NoError(suite.T(), err)
+
+	// the repeated apply of the same entries must be ignored
+	err = suite.service.applyAccountingEntriesToBalanceLedger(ctx, entries)
+	assert.NoError(suite.T(), err)
+
+	req := &intPkg.GetMerchantBalanceLedgerRequest{MerchantId: suite.merchant.Id}
+	res := &intPkg.GetMerchantBalanceLedgerResponse{}
+	err = suite.service.GetMerchantBalanceLedger(ctx, req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Len(suite.T(), res.Items, 1)
+	assert.Equal(suite.T(), "RUB", res.Items[0].Currency)
+	assert.EqualValues(suite.T(), 90, res.Items[0].Pending)
+	assert.EqualValues(suite.T(), 0, res.Items[0].Available)
+	assert.EqualValues(suite.T(), 20, res.Items[0].Reserved)
+}
+
+func (suite *MerchantBalanceTestSuite) TestMerchantBalance_BalanceLedger_RoyaltyReportAndPayout_Ok() {
+	date, err := ptypes.TimestampProto(time.Now().Add(time.Hour * -480))
+	assert.NoError(suite.T(), err)
+
+	report := &billingpb.RoyaltyReport{
+		Id:         primitive.NewObjectID().Hex(),
+		MerchantId: suite.merchant.Id,
+		Totals: &billingpb.RoyaltyReportTotals{
+			TransactionsCount: 10,
+			PayoutAmount:      1000,
+		},
+		Status:         billingpb.RoyaltyReportStatusPending,
+		CreatedAt:      ptypes.TimestampNow(),
+		PeriodFrom:     ptypes.TimestampNow(),
+		PeriodTo:       ptypes.TimestampNow(),
+		AcceptExpireAt: date,
+		Currency:       suite.merchant.GetPayoutCurrency(),
+	}
+	err = suite.service.royaltyReportRepository.Insert(ctx, report, "", pkg.RoyaltyReportChangeSourceAuto)
+	assert.NoError(suite.T(), err)
+
+	err = suite.service.AutoAcceptRoyaltyReports(ctx, &billingpb.EmptyRequest{}, &billingpb.EmptyResponse{})
+	assert.NoError(suite.T(), err)
+
+	ledger, err := suite.service.merchantBalanceLedgerRepository.GetByMerchantIdAndCurrency(ctx, suite.merchant.Id, "RUB")
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), -1000, ledger.Pending)
+	assert.EqualValues(suite.T(), 1000, ledger.Available)
+
+	payout := &billingpb.PayoutDocument{
+		Id:         primitive.NewObjectID().Hex(),
+		MerchantId: suite.merchant.Id,
+		SourceId:   []string{report.Id},
+		TotalFees:  600,
+		Balance:    600,
+		Currency:   "RUB",
+		Status:     pkg.PayoutDocumentStatusPending,
+		CreatedAt:  ptypes.TimestampNow(),
+	}
+	err = suite.service.payoutRepository.Insert(ctx, payout, "127.0.0.1", payoutChangeSourceAdmin)
+	assert.NoError(suite.T(), err)
+	err = suite.service.applyPayoutDocumentToBalanceLedger(ctx, payout, "")
+	assert.NoError(suite.T(), err)
+
+	ledger, err = suite.service.merchantBalanceLedgerRepository.GetByMerchantIdAndCurrency(ctx, suite.merchant.Id, "RUB")
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 400, ledger.Available)
+
+	req := &billingpb.UpdatePayoutDocumentRequest{
+		PayoutDocumentId: payout.Id,
+		Status:           pkg.PayoutDocumentStatusFailed,
+		Ip:               "127.0.0.1",
+	}
+	res := &billingpb.PayoutDocumentResponse{}
+	err = suite.service.UpdatePayoutDocument(ctx, req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+
+	ledger, err = suite.service.merchantBalanceLedgerRepository.GetByMerchantIdAndCurrency(ctx, suite.merchant.Id, "RUB")
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 1000, ledger.Available)
+}
+
+func (suite *MerchantBalanceTestSuite) TestMerchantBalance_BalanceLedger_ApplyOncePerKeyWithoutDrift() {
+	for i := 0; i < 3; i++ {
+		entry := &intPkg.MerchantBalanceLedgerEntry{
+			Key:       "unit_test:" + strconv.Itoa(i),
+			Event:     intPkg.MerchantBalanceLedgerEventAccounting,
+			Available: 0.1,
+		}
+		err := suite.service.applyMerchantBalanceLedgerEntry(ctx, suite.merchant.Id, "RUB", entry)
+		assert.NoError(suite.T(), err)
+
+		// the repeated apply of the same key doesn't change the balance
+		err = suite.service.applyMerchantBalanceLedgerEntry(ctx, suite.merchant.Id, "RUB", entry)
+		assert.NoError(suite.T(), err)
+	}
+
+	ledger, err := suite.service.merchantBalanceLedgerRepository.GetByMerchantIdAndCurrency(ctx, suite.merchant.Id, "RUB")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0.3, ledger.Available)
+	assert.Zero(suite.T(), ledger.Pending)
+	assert.False(suite.T(), ledger.Id.IsZero())
+	assert.False(suite.T(), ledger.CreatedAt.IsZero())
+}
+
+func (suite *MerchantBalanceTestSuite) TestMerchantBalance_ReconcileMerchantBalanceLedger_Ok() {
+	report := &billingpb.RoyaltyReport{
+		Id:         primitive.NewObjectID().Hex(),
+		MerchantId: suite.merchant.Id,
+		Totals: &billingpb.RoyaltyReportTotals{
+			TransactionsCount: 10,
+			PayoutAmount:      1234.5,
+		},
+		Status:         billingpb.RoyaltyReportStatusAccepted,
+		CreatedAt:      ptypes.TimestampNow(),
+		PeriodFrom:     ptypes.TimestampNow(),
+		PeriodTo:       ptypes.TimestampNow(),
+		AcceptExpireAt: ptypes.TimestampNow(),
+		Currency:       suite.merchant.GetPayoutCurrency(),
+	}
+	err := suite.service.royaltyReportRepository.Insert(ctx, report, "", pkg.RoyaltyReportChangeSourceAuto)
+	assert.NoError(suite.T(), err)
+
+	req := &intPkg.ReconcileMerchantBalanceLedgerRequest{MerchantId: suite.merchant.Id}
+	res := &intPkg.ReconcileMerchantBalanceLedgerResponse{}
+	err = suite.service.ReconcileMerchantBalanceLedger(ctx, req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Len(suite.T(), res.Items, 1)
+	assert.False(suite.T(), res.Items[0].Matched)
+	assert.False(suite.T(), res.Items[0].Fixed)
+	assert.EqualValues(suite.T(), 0, res.Items[0].LedgerAvailable)
+	assert.EqualValues(suite.T(), 1234.5, res.Items[0].ComputedAvailable)
+	assert.EqualValues(suite.T(), 1234.5, res.Items[0].AvailableDifference)
+
+	req.Fix = true
+	req.UserId = primitive.NewObjectID().Hex()
+	res = &intPkg.ReconcileMerchantBalanceLedgerResponse{}
+	err = suite.service.ReconcileMerchantBalanceLedger(ctx, req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+	assert.Equal(suite.T(), merchantBalanceLedgerErrorAccessDenied, res.Message)
+
+	admin := &billingpb.UserRole{
+		Id:     primitive.NewObjectID().Hex(),
+		UserId: req.UserId,
+		Role:   billingpb.RoleSystemAdmin,
+	}
+	err = suite.service.userRoleRepository.AddAdminUser(ctx, admin)
+	assert.NoError(suite.T(), err)
+
+	res = &intPkg.ReconcileMerchantBalanceLedgerResponse{}
+	err = suite.service.ReconcileMerchantBalanceLedger(ctx, req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.True(suite.T(), res.Items[0].Fixed)
+
+	req.Fix = false
+	res = &intPkg.ReconcileMerchantBalanceLedgerResponse{}
+	err = suite.service.ReconcileMerchantBalanceLedger(ctx, req, res)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), res.Items[0].Matched)
+	assert.EqualValues(suite.T(), 1234.5, res.Items[0].LedgerAvailable)
+}
+
+func (suite *MerchantBalanceTestSuite) TestMerchantBalance_MerchantDebt_Ok() {
+	report := &billingpb.RoyaltyReport{
+		Id:         primitive.NewObjectID().Hex(),
+		MerchantId: suite.merchant.Id,
+		Totals: &billingpb.RoyaltyReportTotals{
+			TransactionsCount: 1,
+			PayoutAmount:      100,
+		},
+		Status:         billingpb.RoyaltyReportStatusAccepted,
+		CreatedAt:      ptypes.TimestampNow(),
+		PeriodFrom:     ptypes.TimestampNow(),
+		PeriodTo:       ptypes.TimestampNow(),
+		AcceptExpireAt: ptypes.TimestampNow(),
+		Currency:       suite.merchant.GetPayoutCurrency(),
+	}
+	err := suite.service.royaltyReportRepository.Insert(ctx, report, "", pkg.RoyaltyReportChangeSourceAuto)
+	assert.NoError(suite.T(), err)
+
+	payout := &billingpb.PayoutDocument{
+		Id:         primitive.NewObjectID().Hex(),
+		MerchantId: suite.merchant.Id,
+		SourceId:   []string{report.Id},
+		TotalFees:  300,
+		Balance:    300,
+		Currency:   "RUB",
+		Status:     pkg.PayoutDocumentStatusPaid,
+		CreatedAt:  ptypes.TimestampNow(),
+	}
+	err = suite.service.payoutRepository.Insert(ctx, payout, "127.0.0.1", payoutChangeSourceAdmin)
+	assert.NoError(suite.T(), err)
+
+	balance, err := suite.service.updateMerchantBalance(ctx, suite.merchant.Id)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), -200, balance.Total)
+
+	debt, err := suite.service.getOpenMerchantDebt(ctx, suite.merchant.Id, "RUB")
+	assert.NoError(suite.T(), err)
+	assert.NotNil(suite.T(), debt)
+	assert.EqualValues(suite.T(), 200, debt.Outstanding)
+	assert.Equal(suite.T(), intPkg.MerchantDebtEscalationNone, debt.EscalationLevel)
+
+	debt.OpenedAt = time.Now().Add(-time.Hour * 24 * time.Duration(suite.service.cfg.MerchantDebtSuspendPayoutsDays))
+	err = suite.service.merchantDebtRepository.Update(ctx, debt)
+	assert.NoError(suite.T(), err)
+
+	err = suite.service.EscalateMerchantDebts(ctx, &billingpb.EmptyRequest{}, &billingpb.EmptyResponse{})
+	assert.NoError(suite.T(), err)
+
+	debt, err = suite.service.getOpenMerchantDebt(ctx, suite.merchant.Id, "RUB")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), intPkg.MerchantDebtEscalationPayoutsSuspended, debt.EscalationLevel)
+	assert.True(suite.T(), debt.IsPayoutsSuspended())
+	assert.False(suite.T(), debt.IsPaymentsSuspended())
+
+	user := &billingpb.UserRole{
+		Id:     primitive.NewObjectID().Hex(),
+		UserId: primitive.NewObjectID().Hex(),
+		Role:   billingpb.RoleSystemFinancial,
+	}
+	err = suite.service.userRoleRepository.AddAdminUser(ctx, user)
+	assert.NoError(suite.T(), err)
+
+	req := &intPkg.RecordMerchantRepaymentRequest{
+		MerchantId: suite.merchant.Id,
+		Amount:     250,
+		Currency:   "RUB",
+		Reference:  "bank transfer",
+		UserId:     user.UserId,
+	}
+	res := &intPkg.MerchantDebtResponse{}
+	err = suite.service.RecordMerchantRepayment(ctx, req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), merchantDebtErrorAmountInvalid, res.Message)
+
+	req.Amount = 200
+	res = &intPkg.MerchantDebtResponse{}
+	err = suite.service.RecordMerchantRepayment(ctx, req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Equal(suite.T(), intPkg.MerchantDebtStatusRecovered, res.Item.Status)
+	assert.Equal(suite.T(), intPkg.MerchantDebtEscalationNone, res.Item.EscalationLevel)
+	assert.Len(suite.T(), res.Item.Repayments, 1)
+	assert.False(suite.T(), res.Item.Repayments[0].Settled)
+
+	// the pending repayment isn't included into the balance yet, so the debt must not be reopened
+	_, err = suite.service.updateMerchantBalance(ctx, suite.merchant.Id)
+	assert.NoError(suite.T(), err)
+
+	debt, err = suite.service.getOpenMerchantDebt(ctx, suite.merchant.Id, "RUB")
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), debt)
+}
+
+func (suite *MerchantBalanceTestSuite) TestMerchantBalance_PaymentsSuspended_AnyPayoutCurrency() {
+	suite.service.supportedCurrencies = []string{"RUB", "USD"}
+
+	req := &intPkg.SetMerchantPayoutRoutingRequest{
+		MerchantId: suite.merchant.Id,
+		Accounts: []*intPkg.MerchantPayoutAccount{
+			{Currency: "USD", Name: "Bank name", AccountNumber: "0000002", Swift: "swift"},
+		},
+		Routes: map[string]string{"USD": "USD"},
+		UserId: suite.helperAddAdminUser(billingpb.RoleSystemAdmin),
+	}
+	res := &intPkg.MerchantPayoutRoutingResponse{}
+	err := suite.service.SetMerchantPayoutRouting(ctx, req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+
+	suspended, err := suite.service.isMerchantPaymentsSuspended(ctx, suite.merchant)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), suspended)
+
+	merchantId, _ := primitive.ObjectIDFromHex(suite.merchant.Id)
+	debt := &intPkg.MerchantDebt{
+		Id:              primitive.NewObjectID(),
+		MerchantId:      merchantId,
+		Currency:        "USD",
+		Outstanding:     100,
+		Status:          intPkg.MerchantDebtStatusOpen,
+		EscalationLevel: intPkg.MerchantDebtEscalationPaymentsSuspended,
+		OpenedAt:        time.Now(),
+	}
+	err = suite.service.merchantDebtRepository.Insert(ctx, debt)
+	assert.NoError(suite.T(), err)
+
+	suspended, err = suite.service.isMerchantPaymentsSuspended(ctx, suite.merchant)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), suspended)
+}
+
+func (suite *MerchantBalanceTestSuite) TestMerchantBalance_RecordMerchantRepayment_Failed_AccessDenied() {
+	req := &intPkg.RecordMerchantRepaymentRequest{
+		MerchantId: suite.merchant.Id,
+		Amount:     100,
+		Currency:   "RUB",
+		UserId:     primitive.NewObjectID().Hex(),
+	}
+	res := &intPkg.MerchantDebtResponse{}
+	err := suite.service.RecordMerchantRepayment(ctx, req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+	assert.Equal(suite.T(), merchantDebtErrorAccessDenied, res.Message)
+}
+
+func (suite *MerchantBalanceTestSuite) TestMerchantBalance_SetMerchantPayoutRouting_Failed_AccessDenied() {
+	suite.service.supportedCurrencies = []string{"RUB", "USD"}
+
+	req := &intPkg.SetMerchantPayoutRoutingRequest{
+		MerchantId: suite.merchant.Id,
+		Accounts: []*intPkg.MerchantPayoutAccount{
+			{Currency: "USD", Name: "Bank name", AccountNumber: "0000002", Swift: "swift"},
+		},
+		Routes: map[string]string{"USD": "USD"},
+		UserId: primitive.NewObjectID().Hex(),
+	}
+	res := &intPkg.MerchantPayoutRoutingResponse{}
+	err := suite.service.SetMerchantPayoutRouting(ctx, req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+	assert.Equal(suite.T(), payoutRoutingErrorAccessDenied, res.Message)
+
+	_, err = suite.service.merchantPayoutRoutingRepository.GetByMerchantId(ctx, suite.merchant.Id)
+	assert.Equal(suite.T(), mongo.ErrNoDocuments, err)
+}
+
+func (suite *MerchantBalanceTestSuite) TestMerchantBalance_MultiCurrency_Ok() {
+	suite.service.supportedCurrencies = []string{"RUB", "USD", "EUR"}
+
+	req := &intPkg.SetMerchantPayoutRoutingRequest{
+		MerchantId: suite.merchant.Id,
+		Accounts: []*intPkg.MerchantPayoutAccount{
+			{Currency: "usd", Name: "Bank name", AccountNumber: "0000002", Swift: "swift"},
+		},
+		Routes: map[string]string{"EUR": "GBP"},
+		UserId: suite.helperAddAdminUser(billingpb.RoleSystemFinancial),
+	}
+	res := &intPkg.MerchantPayoutRoutingResponse{}
+	err := suite.service.SetMerchantPayoutRouting(ctx, req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), payoutRoutingErrorRouteInvalid, res.Message)
+
+	req.Routes = map[string]string{"USD": "USD", "EUR": "USD"}
+	res = &intPkg.MerchantPayoutRoutingResponse{}
+	err = suite.service.SetMerchantPayoutRouting(ctx, req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Equal(suite.T(), "USD", res.Item.Accounts[0].Currency)
+
+	order := &billingpb.Order{
+		Currency: "EUR",
+		Project:  &billingpb.ProjectOrder{MerchantId: suite.merchant.Id, MerchantRoyaltyCurrency: "RUB"},
+	}
+	err = suite.service.setOrderMerchantRoyaltyCurrency(ctx, order)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "USD", order.Project.MerchantRoyaltyCurrency)
+
+	order.Currency = "RUB"
+	err = suite.service.setOrderMerchantRoyaltyCurrency(ctx, order)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "RUB", order.Project.MerchantRoyaltyCurrency)
+
+	report := &billingpb.RoyaltyReport{
+		Id:         primitive.NewObjectID().Hex(),
+		MerchantId: suite.merchant.Id,
+		Totals: &billingpb.RoyaltyReportTotals{
+			TransactionsCount: 1,
+			PayoutAmount:      100,
+		},
+		Status:         billingpb.RoyaltyReportStatusAccepted,
+		CreatedAt:      ptypes.TimestampNow(),
+		PeriodFrom:     ptypes.TimestampNow(),
+		PeriodTo:       ptypes.TimestampNow(),
+		AcceptExpireAt: ptypes.TimestampNow(),
+		Currency:       "USD",
+	}
+	err = suite.service.royaltyReportRepository.Insert(ctx, report, "", pkg.RoyaltyReportChangeSourceAuto)
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.service.updateMerchantBalance(ctx, suite.merchant.Id)
+	assert.NoError(suite.T(), err)
+
+	balancesRes := &intPkg.GetMerchantBalancesResponse{}
+	err = suite.service.GetMerchantBalances(ctx, &billingpb.GetMerchantBalanceRequest{MerchantId: suite.merchant.Id}, balancesRes)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, balancesRes.Status)
+	assert.Len(suite.T(), balancesRes.Items, 2)
+	assert.Equal(suite.T(), "RUB", balancesRes.Items[0].Currency)
+	assert.EqualValues(suite.T(), 0, balancesRes.Items[0].Total)
+	assert.Equal(suite.T(), "USD", balancesRes.Items[1].Currency)
+	assert.EqualValues(suite.T(), 100, balancesRes.Items[1].Total)
+
+	banking, err := suite.service.getMerchantPayoutBanking(ctx, suite.merchant, "USD")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "0000002", banking.AccountNumber)
+}
+
+func (suite *MerchantBalanceTestSuite) helperAddAdminUser(role string) string {
+	user := &billingpb.UserRole{
+		Id:     primitive.NewObjectID().Hex(),
+		UserId: primitive.NewObjectID().Hex(),
+		Role:   role,
+	}
+
+	if err := suite.service.userRoleRepository.AddAdminUser(ctx, user); err != nil {
+		suite.FailNow("Insert admin user failed", "%v", err)
+	}
+
+	return user.UserId
+}
+
 func (suite *MerchantBalanceTestSuite) mbRecordsCount(merchantId, currency string) int64 {
 	count, err := suite.service.merchantBalanceRepository.CountByIdAndCurrency(ctx, merchantId, currency)
 
@@ -0,0 +1,466 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const (
+	merchantDebtRepaymentReason = "merchant debt repayment"
+
+	merchantDebtOpenedNotificationMessage    = "Your balance is negative, the debt of %s %s will be netted against your next royalty reports."
+	merchantDebtRecoveredNotificationMessage = "Your debt of %s %s is fully recovered."
+	merchantDebtRepaymentNotificationMessage = "Repayment of %s %s is received, the outstanding debt is %s %s."
+)
+
+var (
+	merchantDebtErrorMerchantNotFound = newBillingServerErrorMsg("md000001", "merchant not found")
+	merchantDebtErrorAccessDenied     = newBillingServerErrorMsg("md000002", "only admin or financier can record the merchant repayment")
+	merchantDebtErrorAmountInvalid    = newBillingServerErrorMsg("md000003", "repayment amount must be positive and can't exceed the outstanding debt")
+	merchantDebtErrorNoOpenDebt       = newBillingServerErrorMsg("md000004", "merchant has no open debt in the currency")
+	merchantDebtErrorNotFound         = newBillingServerErrorMsg("md000005", "merchant debt not found")
+
+	merchantDebtEscalationRanks = map[string]int{
+		intPkg.MerchantDebtEscalationNone:              0,
+		intPkg.MerchantDebtEscalationNotified:          1,
+		intPkg.MerchantDebtEscalationPayoutsSuspended:  2,
+		intPkg.MerchantDebtEscalationPaymentsSuspended: 3,
+	}
+
+	merchantDebtEscalationMessages = map[string]string{
+		intPkg.MerchantDebtEscalationNotified:          "Your debt of %s %s is not recovered yet, please make a repayment.",
+		intPkg.MerchantDebtEscalationPayoutsSuspended:  "Your payouts are suspended until the debt of %s %s is recovered.",
+		intPkg.MerchantDebtEscalationPaymentsSuspended: "Your payments are suspended until the debt of %s %s is recovered.",
+	}
+)
+
+// GetMerchantDebt returns the open or the last recovered debt of the merchant.
+func (s *Service) GetMerchantDebt(
+	ctx context.Context,
+	req *intPkg.GetMerchantDebtRequest,
+	res *intPkg.MerchantDebtResponse,
+) error {
+	debt, err := s.merchantDebtRepository.GetLastByMerchantId(ctx, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = merchantDebtErrorNotFound
+		return nil
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = debt
+
+	return nil
+}
+
+// RecordMerchantRepayment records the incoming transfer from the merchant as the accounting correction.
+// The correction is included into the next royalty report and reduces the outstanding debt immediately.
+func (s *Service) RecordMerchantRepayment(
+	ctx context.Context,
+	req *intPkg.RecordMerchantRepaymentRequest,
+	res *intPkg.MerchantDebtResponse,
+) error {
+	if !s.hasAdminRole(ctx, req.UserId, billingpb.RoleSystemAdmin, billingpb.RoleSystemFinancial) {
+		res.Status = billingpb.ResponseStatusForbidden
+		res.Message = merchantDebtErrorAccessDenied
+		return nil
+	}
+
+	merchant, err := s.merchantRepository.GetById(ctx, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = merchantDebtErrorMerchantNotFound
+		return nil
+	}
+
+	debt, err := s.getOpenMerchantDebt(ctx, merchant.Id, req.Currency)
+
+	if err != nil {
+		return err
+	}
+
+	if debt == nil {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = merchantDebtErrorNoOpenDebt
+		return nil
+	}
+
+	amount := s.newMoney(req.Amount, debt.Currency).Round()
+	outstanding := s.newMoney(debt.Outstanding, debt.Currency)
+
+	if amount.Sign() <= 0 || amount.Cmp(outstanding) > 0 {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = merchantDebtErrorAmountInvalid
+		return nil
+	}
+
+	reason := merchantDebtRepaymentReason
+
+	if strings.TrimSpace(req.Reference) != "" {
+		reason += ": " + req.Reference
+	}
+
+	// the correction amount is deducted from the merchant balance, so the repayment is negative correction
+	correction, err := s.createApprovedAccountingCorrection(
+		ctx,
+		merchant.Id,
+		debt.Currency,
+		reason,
+		amount.Neg().Float64(),
+		time.Now(),
+		req.UserId,
+		req.UserId,
+	)
+
+	if err != nil {
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			res.Status = billingpb.ResponseStatusSystemError
+			res.Message = e
+			return nil
+		}
+
+		return err
+	}
+
+	now := time.Now()
+	outstanding = outstanding.Sub(amount).Round()
+	debt.Repayments = append(debt.Repayments, &intPkg.MerchantDebtRepayment{
+		AccountingEntryId: correction.AccountingEntryId,
+		Amount:            amount.Float64(),
+		Reference:         req.Reference,
+		UserId:            req.UserId,
+		CreatedAt:         now,
+	})
+	s.setMerchantDebtOutstanding(debt, outstanding, intPkg.MerchantDebtActionRepayment, req.Reference, req.UserId, now)
+
+	if err = s.merchantDebtRepository.Update(ctx, debt); err != nil {
+		return err
+	}
+
+	s.notifyMerchantDebt(ctx, debt, fmt.Sprintf(
+		merchantDebtRepaymentNotificationMessage,
+		amount.String(),
+		debt.Currency,
+		outstanding.String(),
+		debt.Currency,
+	))
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = debt
+
+	return nil
+}
+
+// EscalateMerchantDebts applies the escalation steps to the open merchant debts by the number of days
+// since the debt was opened.
+func (s *Service) EscalateMerchantDebts(
+	ctx context.Context,
+	_ *billingpb.EmptyRequest,
+	_ *billingpb.EmptyResponse,
+) error {
+	debts, err := s.merchantDebtRepository.FindOpen(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	for _, debt := range debts {
+		level := s.getMerchantDebtEscalationLevel(debt, now)
+
+		if merchantDebtEscalationRanks[level] <= merchantDebtEscalationRanks[debt.EscalationLevel] {
+			continue
+		}
+
+		debt.EscalationLevel = level
+		debt.UpdatedAt = now
+		debt.History = append(debt.History, &intPkg.MerchantDebtHistoryItem{
+			Action:      intPkg.MerchantDebtActionEscalate,
+			Outstanding: debt.Outstanding,
+			Comment:     level,
+			CreatedAt:   now,
+		})
+
+		if err = s.merchantDebtRepository.Update(ctx, debt); err != nil {
+			return err
+		}
+
+		s.notifyMerchantDebt(ctx, debt, fmt.Sprintf(
+			merchantDebtEscalationMessages[level],
+			s.newMoney(debt.Outstanding, debt.Currency).String(),
+			debt.Currency,
+		))
+	}
+
+	return nil
+}
+
+func (s *Service) getMerchantDebtEscalationLevel(debt *intPkg.MerchantDebt, now time.Time) string {
+	days := int(now.Sub(debt.OpenedAt).Hours() / 24)
+	steps := []struct {
+		days  int
+		level string
+	}{
+		{s.cfg.MerchantDebtSuspendPaymentsDays, intPkg.MerchantDebtEscalationPaymentsSuspended},
+		{s.cfg.MerchantDebtSuspendPayoutsDays, intPkg.MerchantDebtEscalationPayoutsSuspended},
+		{s.cfg.MerchantDebtNotifyDays, intPkg.MerchantDebtEscalationNotified},
+	}
+
+	for _, step := range steps {
+		if step.days > 0 && days >= step.days {
+			return step.level
+		}
+	}
+
+	return intPkg.MerchantDebtEscalationNone
+}
+
+// getOpenMerchantDebt returns the open debt of the merchant in the currency or nil if the merchant has no debt.
+func (s *Service) getOpenMerchantDebt(ctx context.Context, merchantId, currency string) (*intPkg.MerchantDebt, error) {
+	debt, err := s.merchantDebtRepository.GetOpen(ctx, merchantId, currency)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	return debt, err
+}
+
+// isMerchantPayoutsSuspended checks that the payouts of the merchant in the currency are suspended by the debt.
+func (s *Service) isMerchantPayoutsSuspended(ctx context.Context, merchantId, currency string) (bool, error) {
+	debt, err := s.getOpenMerchantDebt(ctx, merchantId, currency)
+
+	if err != nil {
+		return false, err
+	}
+
+	return debt != nil && debt.IsPayoutsSuspended(), nil
+}
+
+// isMerchantPaymentsSuspended checks that the payments of the merchant are suspended by the debt
+// in any payout currency of the merchant.
+func (s *Service) isMerchantPaymentsSuspended(ctx context.Context, merchant *billingpb.Merchant) (bool, error) {
+	currencies, err := s.getMerchantPayoutCurrencies(ctx, merchant)
+
+	if err != nil {
+		return false, err
+	}
+
+	for _, currency := range currencies {
+		debt, err := s.getOpenMerchantDebt(ctx, merchant.Id, currency)
+
+		if err != nil {
+			return false, err
+		}
+
+		if debt != nil && debt.IsPaymentsSuspended() {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// syncMerchantDebt opens, nets or recovers the merchant debt by the recalculated merchant balance. The repayments
+// are pending until their corrections are included into the balance, so they aren't counted twice.
+func (s *Service) syncMerchantDebt(ctx context.Context, balance *billingpb.MerchantBalance) error {
+	debt, err := s.merchantDebtRepository.GetLastByMerchantIdAndCurrency(ctx, balance.MerchantId, balance.Currency)
+
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	if debt != nil && debt.Status == intPkg.MerchantDebtStatusRecovered && !hasPendingMerchantRepayments(debt) {
+		debt = nil
+	}
+
+	pending := s.newMoney(0, balance.Currency)
+	settled := false
+
+	if debt != nil {
+		settled, err = s.settleMerchantRepayments(ctx, debt)
+
+		if err != nil {
+			return err
+		}
+
+		for _, repayment := range debt.Repayments {
+			if !repayment.Settled {
+				pending = pending.Add(s.newMoney(repayment.Amount, debt.Currency))
+			}
+		}
+	}
+
+	owed := s.newMoney(balance.Total, balance.Currency).Neg().Sub(pending).Round()
+
+	if owed.Sign() < 0 {
+		owed = s.newMoney(0, balance.Currency)
+	}
+
+	now := time.Now()
+
+	if debt == nil {
+		if owed.Sign() <= 0 {
+			return nil
+		}
+
+		merchantOid, _ := primitive.ObjectIDFromHex(balance.MerchantId)
+		debt = &intPkg.MerchantDebt{
+			Id:              primitive.NewObjectID(),
+			MerchantId:      merchantOid,
+			Currency:        balance.Currency,
+			Outstanding:     owed.Float64(),
+			MaxOutstanding:  owed.Float64(),
+			Status:          intPkg.MerchantDebtStatusOpen,
+			EscalationLevel: intPkg.MerchantDebtEscalationNone,
+			Repayments:      []*intPkg.MerchantDebtRepayment{},
+			History: []*intPkg.MerchantDebtHistoryItem{
+				{Action: intPkg.MerchantDebtActionOpen, Outstanding: owed.Float64(), CreatedAt: now},
+			},
+			OpenedAt:  now,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+
+		if err = s.merchantDebtRepository.Insert(ctx, debt); err != nil {
+			return err
+		}
+
+		s.notifyMerchantDebt(ctx, debt, fmt.Sprintf(merchantDebtOpenedNotificationMessage, owed.String(), debt.Currency))
+		return nil
+	}
+
+	if !settled && owed.Cmp(s.newMoney(debt.Outstanding, debt.Currency)) == 0 {
+		return nil
+	}
+
+	wasOpen := debt.Status == intPkg.MerchantDebtStatusOpen
+	s.setMerchantDebtOutstanding(debt, owed, intPkg.MerchantDebtActionChange, "", "", now)
+
+	if err = s.merchantDebtRepository.Update(ctx, debt); err != nil {
+		return err
+	}
+
+	if wasOpen && debt.Status == intPkg.MerchantDebtStatusRecovered {
+		s.notifyMerchantDebt(ctx, debt, fmt.Sprintf(
+			merchantDebtRecoveredNotificationMessage,
+			s.newMoney(debt.MaxOutstanding, debt.Currency).String(),
+			debt.Currency,
+		))
+	}
+
+	return nil
+}
+
+// setMerchantDebtOutstanding changes the outstanding amount of the debt, the debt is recovered when nothing
+// is outstanding and reopened when the outstanding amount grows after the recovery.
+func (s *Service) setMerchantDebtOutstanding(
+	debt *intPkg.MerchantDebt,
+	outstanding intPkg.Money,
+	action, comment, userId string,
+	now time.Time,
+) {
+	debt.Outstanding = outstanding.Float64()
+	debt.UpdatedAt = now
+
+	if outstanding.Cmp(s.newMoney(debt.MaxOutstanding, debt.Currency)) > 0 {
+		debt.MaxOutstanding = outstanding.Float64()
+	}
+
+	debt.History = append(debt.History, &intPkg.MerchantDebtHistoryItem{
+		Action:      action,
+		Outstanding: debt.Outstanding,
+		Comment:     comment,
+		UserId:      userId,
+		CreatedAt:   now,
+	})
+
+	if outstanding.Sign() <= 0 && debt.Status == intPkg.MerchantDebtStatusOpen {
+		debt.Status = intPkg.MerchantDebtStatusRecovered
+		debt.EscalationLevel = intPkg.MerchantDebtEscalationNone
+		debt.RecoveredAt = now
+		debt.History = append(debt.History, &intPkg.MerchantDebtHistoryItem{
+			Action:    intPkg.MerchantDebtActionRecover,
+			UserId:    userId,
+			CreatedAt: now,
+		})
+	}
+
+	if outstanding.Sign() > 0 && debt.Status == intPkg.MerchantDebtStatusRecovered {
+		debt.Status = intPkg.MerchantDebtStatusOpen
+		debt.RecoveredAt = time.Time{}
+		debt.History = append(debt.History, &intPkg.MerchantDebtHistoryItem{
+			Action:      intPkg.MerchantDebtActionOpen,
+			Outstanding: debt.Outstanding,
+			CreatedAt:   now,
+		})
+	}
+}
+
+// settleMerchantRepayments marks the repayments which corrections are included into the merchant balance.
+func (s *Service) settleMerchantRepayments(ctx context.Context, debt *intPkg.MerchantDebt) (bool, error) {
+	var ids []string
+
+	for _, repayment := range debt.Repayments {
+		if !repayment.Settled {
+			ids = append(ids, repayment.AccountingEntryId)
+		}
+	}
+
+	if len(ids) == 0 {
+		return false, nil
+	}
+
+	settledIds, err := s.royaltyReportRepository.GetBalanceCorrectionEntryIds(ctx, debt.MerchantId.Hex(), debt.Currency, ids)
+
+	if err != nil || len(settledIds) == 0 {
+		return false, err
+	}
+
+	settled := make(map[string]bool, len(settledIds))
+
+	for _, id := range settledIds {
+		settled[id] = true
+	}
+
+	for _, repayment := range debt.Repayments {
+		if settled[repayment.AccountingEntryId] {
+			repayment.Settled = true
+		}
+	}
+
+	return true, nil
+}
+
+func hasPendingMerchantRepayments(debt *intPkg.MerchantDebt) bool {
+	for _, repayment := range debt.Repayments {
+		if !repayment.Settled {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Service) notifyMerchantDebt(ctx context.Context, debt *intPkg.MerchantDebt, message string) {
+	_, err := s.addNotification(ctx, message, debt.MerchantId.Hex(), "", nil)
+
+	if err != nil {
+		zap.L().Error(
+			"Notification about merchant debt failed",
+			zap.Error(err),
+			zap.String("debt_id", debt.Id.Hex()),
+			zap.String("status", debt.Status),
+		)
+	}
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/helper"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"strings"
+	"time"
+)
+
+var (
+	payoutRoutingErrorMerchantNotFound     = newBillingServerErrorMsg("mp000001", "merchant not found")
+	payoutRoutingErrorCurrencyNotSupport   = newBillingServerErrorMsg("mp000002", "payout currency is not supported")
+	payoutRoutingErrorAccountIncomplete    = newBillingServerErrorMsg("mp000003", "bank account name, account number and swift are required for the payout currency")
+	payoutRoutingErrorAccountDuplicate     = newBillingServerErrorMsg("mp000004", "payout currency can have only one bank account")
+	payoutRoutingErrorRouteInvalid         = newBillingServerErrorMsg("mp000005", "sales currency can be routed only to the payout currency with the bank account")
+	payoutRoutingErrorNotFound             = newBillingServerErrorMsg("mp000006", "payout routing not found")
+	payoutRoutingErrorPayoutCurrencyNotSet = newBillingServerErrorMsg("mp000007", "main payout currency of merchant not set")
+	payoutRoutingErrorAccessDenied         = newBillingServerErrorMsg("mp000008", "only admin or financier can change the payout routing")
+)
+
+// SetMerchantPayoutRouting creates or replaces the multi-currency payout settings of the merchant.
+// The main payout currency of the merchant is paid out to the merchant banking and doesn't need the bank account.
+func (s *Service) SetMerchantPayoutRouting(
+	ctx context.Context,
+	req *intPkg.SetMerchantPayoutRoutingRequest,
+	res *intPkg.MerchantPayoutRoutingResponse,
+) error {
+	if !s.hasAdminRole(ctx, req.UserId, billingpb.RoleSystemAdmin, billingpb.RoleSystemFinancial) {
+		res.Status = billingpb.ResponseStatusForbidden
+		res.Message = payoutRoutingErrorAccessDenied
+		return nil
+	}
+
+	merchant, err := s.merchantRepository.GetById(ctx, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = payoutRoutingErrorMerchantNotFound
+		return nil
+	}
+
+	if merchant.GetPayoutCurrency() == "" {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = payoutRoutingErrorPayoutCurrencyNotSet
+		return nil
+	}
+
+	accounts := make([]*intPkg.MerchantPayoutAccount, 0, len(req.Accounts))
+	payoutCurrencies := map[string]bool{merchant.GetPayoutCurrency(): true}
+
+	for _, account := range req.Accounts {
+		account.Currency = strings.ToUpper(account.Currency)
+
+		if !helper.Contains(s.supportedCurrencies, account.Currency) {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = payoutRoutingErrorCurrencyNotSupport
+			return nil
+		}
+
+		if payoutCurrencies[account.Currency] {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = payoutRoutingErrorAccountDuplicate
+			return nil
+		}
+
+		if account.Name == "" || account.AccountNumber == "" || account.Swift == "" {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = payoutRoutingErrorAccountIncomplete
+			return nil
+		}
+
+		payoutCurrencies[account.Currency] = true
+		accounts = append(accounts, account)
+	}
+
+	routes := make(map[string]string, len(req.Routes))
+
+	for salesCurrency, payoutCurrency := range req.Routes {
+		salesCurrency = strings.ToUpper(salesCurrency)
+		payoutCurrency = strings.ToUpper(payoutCurrency)
+
+		if !helper.Contains(s.supportedCurrencies, salesCurrency) || !payoutCurrencies[payoutCurrency] {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = payoutRoutingErrorRouteInvalid
+			return nil
+		}
+
+		routes[salesCurrency] = payoutCurrency
+	}
+
+	now := time.Now()
+	routing, err := s.merchantPayoutRoutingRepository.GetByMerchantId(ctx, merchant.Id)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		oid, _ := primitive.ObjectIDFromHex(merchant.Id)
+		routing = &intPkg.MerchantPayoutRouting{
+			Id:         primitive.NewObjectID(),
+			MerchantId: oid,
+			CreatedAt:  now,
+		}
+	}
+
+	routing.Accounts = accounts
+	routing.Routes = routes
+	routing.UpdatedBy = req.UserId
+	routing.UpdatedAt = now
+
+	if err = s.merchantPayoutRoutingRepository.Upsert(ctx, routing); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = routing
+
+	return nil
+}
+
+// GetMerchantPayoutRouting returns the multi-currency payout settings of the merchant.
+func (s *Service) GetMerchantPayoutRouting(
+	ctx context.Context,
+	req *intPkg.GetMerchantPayoutRoutingRequest,
+	res *intPkg.MerchantPayoutRoutingResponse,
+) error {
+	routing, err := s.merchantPayoutRoutingRepository.GetByMerchantId(ctx, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = payoutRoutingErrorNotFound
+		return nil
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = routing
+
+	return nil
+}
+
+// getMerchantPayoutRouting returns the payout routing of the merchant or nil if the merchant has only
+// the main payout currency.
+func (s *Service) getMerchantPayoutRouting(ctx context.Context, merchantId string) (*intPkg.MerchantPayoutRouting, error) {
+	routing, err := s.merchantPayoutRoutingRepository.GetByMerchantId(ctx, merchantId)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	return routing, err
+}
+
+// getMerchantPayoutCurrencies returns all payout currencies of the merchant, the main payout currency goes first.
+func (s *Service) getMerchantPayoutCurrencies(ctx context.Context, merchant *billingpb.Merchant) ([]string, error) {
+	routing, err := s.getMerchantPayoutRouting(ctx, merchant.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return routing.GetCurrencies(merchant.GetPayoutCurrency()), nil
+}
+
+// getMerchantPayoutBanking returns the bank account for the payouts of the merchant in the currency.
+func (s *Service) getMerchantPayoutBanking(
+	ctx context.Context,
+	merchant *billingpb.Merchant,
+	currency string,
+) (*billingpb.MerchantBanking, error) {
+	if currency == merchant.GetPayoutCurrency() {
+		return merchant.Banking, nil
+	}
+
+	routing, err := s.getMerchantPayoutRouting(ctx, merchant.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if account := routing.GetAccount(currency); account != nil {
+		return account.ToBanking(), nil
+	}
+
+	return merchant.Banking, nil
+}
+
+// setOrderMerchantRoyaltyCurrency routes the order to the payout currency of the merchant by the sales currency.
+// The royalty currency must be set after the order currency is final, because the accounting entries
+// of the order are converted into it.
+func (s *Service) setOrderMerchantRoyaltyCurrency(ctx context.Context, order *billingpb.Order) error {
+	routing, err := s.getMerchantPayoutRouting(ctx, order.GetMerchantId())
+
+	if err != nil || routing == nil {
+		return err
+	}
+
+	// the order currency can be changed after the previous payment attempt, so the route is taken from scratch
+	merchant, err := s.merchantRepository.GetById(ctx, order.GetMerchantId())
+
+	if err != nil {
+		return err
+	}
+
+	order.Project.MerchantRoyaltyCurrency = routing.GetPayoutCurrency(order.Currency, merchant.GetPayoutCurrency())
+
+	return nil
+}
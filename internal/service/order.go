@@ -133,12 +133,31 @@ var (
 	orderErrorWrongPrivateStatus                              = newBillingServerErrorMsg("fm000077", "order has wrong private status and cannot be recreated")
 	orderCountryChangeRestrictedError                         = newBillingServerErrorMsg("fm000078", "change country is not allowed")
 	orderErrorVatPayerUnknown                                 = newBillingServerErrorMsg("fm000079", "vat payer unknown")
+	orderErrorMerchantPaymentsSuspended                       = newBillingServerErrorMsg("fm000080", "payments to merchant are suspended until the merchant debt is recovered")
+	orderErrorPrivateMetadataGiftKeys                         = newBillingServerErrorMsg("fm000081", "gift of order can't be set by private metadata, use the order gift request instead")
 
 	virtualCurrencyPayoutCurrencyMissed = newBillingServerErrorMsg("vc000001", "virtual currency don't have price in merchant payout currency")
 
 	paymentSystemPaymentProcessingSuccessStatus = "PAYMENT_SYSTEM_PROCESSING_SUCCESS"
 
 	possiblePaymentFormOpeningModes = map[string]bool{"embed": true, "iframe": true, "standalone": true}
+
+	// orderPrivateMetadataServerKeys are the keys of the private metadata filled by the server only
+	orderPrivateMetadataServerKeys = map[string]bool{
+		pkg.OrderPrivateMetadataVatId:                 true,
+		pkg.OrderPrivateMetadataVatIdCountry:          true,
+		pkg.OrderPrivateMetadataVatIdValid:            true,
+		pkg.OrderPrivateMetadataVatIdValidationSource: true,
+		pkg.OrderPrivateMetadataVatIdValidatedAt:      true,
+		pkg.OrderPrivateMetadataReverseCharge:         true,
+	}
+
+	// orderPrivateMetadataGiftKeys are the keys of the private metadata filled by the order gift request only
+	orderPrivateMetadataGiftKeys = map[string]bool{
+		pkg.OrderPrivateMetadataGiftRecipientEmail: true,
+		pkg.OrderPrivateMetadataGiftMessage:        true,
+		pkg.OrderPrivateMetadataGiftDeliveryDate:   true,
+	}
 )
 
 type orderCreateRequestProcessorChecked struct {
@@ -515,7 +534,16 @@ func (s *Service) OrderCreateProcess(
 	}
 
 	processor.processMetadata()
-	processor.processPrivateMetadata()
+
+	if err := processor.processPrivateMetadata(); err != nil {
+		zap.S().Errorw(pkg.MethodFinishedWithError, "err", err.Error())
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = e
+			return nil
+		}
+		return err
+	}
 
 	order, err := processor.prepareOrder()
 
@@ -974,6 +1002,14 @@ func (s *Service) PaymentCreateProcess(
 		return err
 	}
 
+	err = s.setOrderMerchantRoyaltyCurrency(ctx, order)
+	if err != nil {
+		zap.S().Errorw(pkg.MethodFinishedWithError, "err", err.Error(), "method", "setOrderMerchantRoyaltyCurrency")
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = orderErrorUnknown
+		return nil
+	}
+
 	methodName, err := order.GetCostPaymentMethodName()
 	if err != nil {
 		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
@@ -1237,6 +1273,30 @@ func (s *Service) PaymentCallbackProcess(
 		}
 
 		if order.PrivateStatus == recurringpb.OrderStatusPaymentSystemComplete {
+			err = s.onInvoicePaymentNotify(ctx, order)
+
+			if err != nil {
+				zap.L().Error(
+					pkg.MethodFinishedWithError,
+					zap.String("Method", "onInvoicePaymentNotify"),
+					zap.Error(err),
+					zap.String("orderId", order.Id),
+					zap.String("orderUuid", order.Uuid),
+				)
+			}
+
+			err = s.onGiftPaymentNotify(ctx, order)
+
+			if err != nil {
+				zap.L().Error(
+					pkg.MethodFinishedWithError,
+					zap.String("Method", "onGiftPaymentNotify"),
+					zap.Error(err),
+					zap.String("orderId", order.Id),
+					zap.String("orderUuid", order.Uuid),
+				)
+			}
+
 			s.sendMailWithReceipt(ctx, order)
 		}
 
@@ -1937,7 +1997,33 @@ func (s *Service) getPayloadForReceipt(ctx context.Context, order *billingpb.Ord
 	return payload, nil
 }
 
-func (s *Service) sendMailWithCode(_ context.Context, order *billingpb.Order, key *billingpb.Key) {
+func (s *Service) sendMailWithCode(ctx context.Context, order *billingpb.Order, key *billingpb.Key) {
+	gift, err := s.getOrderGift(ctx, order)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.Error(err),
+			zap.String("order_id", order.Id),
+			zap.String("key_id", key.Id),
+		)
+		return
+	}
+
+	if gift != nil {
+		zap.S().Infow("Activation code of gift order will be sent to recipient at delivery date", "order_id", order.Id, "key_id", key.Id)
+		return
+	}
+
+	s.publishMailWithCode(order, key, order.ReceiptEmail, nil)
+}
+
+func (s *Service) publishMailWithCode(
+	order *billingpb.Order,
+	key *billingpb.Key,
+	to string,
+	extra map[string]string,
+) error {
 	platformIconUrl := ""
 	activationInstructionUrl := ""
 	platformName := ""
@@ -1961,27 +2047,33 @@ func (s *Service) sendMailWithCode(_ context.Context, order *billingpb.Order, ke
 					"platform_name":              platformName,
 					"receipt_url":                order.ReceiptUrl,
 				},
-				To: order.ReceiptEmail,
+				To: to,
 			}
 
 			if len(item.Images) > 0 {
 				payload.TemplateModel["product_image"] = item.Images[0]
 			}
 
+			for k, v := range extra {
+				payload.TemplateModel[k] = v
+			}
+
 			err := s.postmarkBroker.Publish(postmarkpb.PostmarkSenderTopicName, payload, amqp.Table{})
 			if err != nil {
 				zap.S().Errorw(
 					"Publication activation code to user email queue is failed",
-					"err", err, "email", order.ReceiptEmail, "order_id", order.Id, "key_id", key.Id)
+					"err", err, "email", to, "order_id", order.Id, "key_id", key.Id)
 
 			} else {
-				zap.S().Infow("Sent payload to broker", "email", order.ReceiptEmail, "order_id", order.Id, "key_id", key.Id, "topic", postmarkpb.PostmarkSenderTopicName)
+				zap.S().Infow("Sent payload to broker", "email", to, "order_id", order.Id, "key_id", key.Id, "topic", postmarkpb.PostmarkSenderTopicName)
 			}
-			return
+			return err
 		}
 	}
 
-	zap.S().Errorw("Mail not sent because no items found for key", "order_id", order.Id, "key_id", key.Id, "email", order.ReceiptEmail)
+	zap.S().Errorw("Mail not sent because no items found for key", "order_id", order.Id, "key_id", key.Id, "email", to)
+
+	return nil
 }
 
 func (s *Service) orderNotifyMerchant(ctx context.Context, order *billingpb.Order) {
@@ -2245,6 +2337,17 @@ func (v *OrderCreateRequestProcessor) processMerchant() error {
 		return orderErrorMerchantBadTariffs
 	}
 
+	suspended, err := v.isMerchantPaymentsSuspended(v.ctx, v.checked.merchant)
+
+	if err != nil {
+		zap.L().Error("Order create get merchant debt error", zap.Error(err), zap.String("merchant_id", v.checked.merchant.Id))
+		return orderErrorUnknown
+	}
+
+	if suspended {
+		return orderErrorMerchantPaymentsSuspended
+	}
+
 	return nil
 }
 
@@ -2355,8 +2458,30 @@ func (v *OrderCreateRequestProcessor) processMetadata() {
 	v.checked.metadata = v.request.Metadata
 }
 
-func (v *OrderCreateRequestProcessor) processPrivateMetadata() {
-	v.checked.privateMetadata = v.request.PrivateMetadata
+// processPrivateMetadata copies the private metadata of the request to the order. The keys filled by the server,
+// for example the VAT ID and the reverse charge flag, are removed from the request. The request with the gift keys
+// is rejected, because the gift is set by the order gift request only.
+func (v *OrderCreateRequestProcessor) processPrivateMetadata() error {
+	if v.request.PrivateMetadata == nil {
+		v.checked.privateMetadata = nil
+		return nil
+	}
+
+	v.checked.privateMetadata = make(map[string]string, len(v.request.PrivateMetadata))
+
+	for key, value := range v.request.PrivateMetadata {
+		if _, ok := orderPrivateMetadataGiftKeys[key]; ok {
+			return orderErrorPrivateMetadataGiftKeys
+		}
+
+		if _, ok := orderPrivateMetadataServerKeys[key]; ok {
+			continue
+		}
+
+		v.checked.privateMetadata[key] = value
+	}
+
+	return nil
 }
 
 func (v *OrderCreateRequestProcessor) getCountry() string {
@@ -2585,6 +2710,7 @@ func (v *OrderCreateRequestProcessor) processOrderVat(order *billingpb.Order) er
 	order.TotalPaymentAmount = order.OrderAmount
 	order.ChargeAmount = order.TotalPaymentAmount
 	order.ChargeCurrency = order.Currency
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataReverseCharge)
 
 	countryCode := order.GetCountry()
 
@@ -2610,6 +2736,22 @@ func (v *OrderCreateRequestProcessor) processOrderVat(order *billingpb.Order) er
 		}
 	}
 
+	// business customer with the valid VAT ID accounts the VAT itself under the reverse charge
+	reverseCharge, err := v.isOrderReverseCharge(v.ctx, order)
+
+	if err != nil {
+		return err
+	}
+
+	if reverseCharge {
+		if order.PrivateMetadata == nil {
+			order.PrivateMetadata = make(map[string]string)
+		}
+
+		order.PrivateMetadata[pkg.OrderPrivateMetadataReverseCharge] = "true"
+		return nil
+	}
+
 	req := &taxpb.GeoIdentity{
 		Country: countryCode,
 	}
@@ -2618,7 +2760,7 @@ func (v *OrderCreateRequestProcessor) processOrderVat(order *billingpb.Order) er
 		req.Zip = order.GetPostalCode()
 	}
 
-	rsp, err := v.tax.GetRate(v.ctx, req)
+	rsp, err := v.getTaxRate(v.ctx, req, order.OperatingCompanyId)
 
 	if err != nil {
 		v.logError("Tax service return error", []interface{}{"error", err.Error(), "request", req})
@@ -0,0 +1,344 @@
+package service
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/helper"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/postmarkpb"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+const (
+	orderGiftMessageMaxLength = 500
+)
+
+var (
+	orderGiftErrorNotFound            = newBillingServerErrorMsg("og000001", "gift of order not found")
+	orderGiftErrorOrderPaid           = newBillingServerErrorMsg("og000002", "gift can be set only for the order which is not paid yet")
+	orderGiftErrorOrderType           = newBillingServerErrorMsg("og000003", "gift can be set only for the order with products or keys")
+	orderGiftErrorRecipientEmail      = newBillingServerErrorMsg("og000004", "gift recipient email is invalid")
+	orderGiftErrorMessageTooLong      = newBillingServerErrorMsg("og000005", "gift message is too long")
+	orderGiftErrorDeliveryDateInPast  = newBillingServerErrorMsg("og000006", "gift delivery date in past")
+	orderGiftErrorMerchantNotEqual    = newBillingServerErrorMsg("og000007", "order is not belongs to merchant")
+	orderGiftErrorDeliveryKeyNotFound = newBillingServerErrorMsg("og000008", "key of gift order not found")
+)
+
+// SetOrderGift marks the unpaid order as a gift for another recipient.
+func (s *Service) SetOrderGift(
+	ctx context.Context,
+	req *intPkg.SetOrderGiftRequest,
+	res *intPkg.OrderGiftResponse,
+) error {
+	order, err := s.getOrderByUuid(ctx, req.OrderId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = orderErrorNotFound
+		return nil
+	}
+
+	if order.PrivateStatus != recurringpb.OrderStatusNew {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = orderGiftErrorOrderPaid
+		return nil
+	}
+
+	if order.ProductType != pkg.OrderType_product && order.ProductType != pkg.OrderType_key {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = orderGiftErrorOrderType
+		return nil
+	}
+
+	if _, err := mail.ParseAddress(req.RecipientEmail); err != nil {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = orderGiftErrorRecipientEmail
+		return nil
+	}
+
+	if len([]rune(req.Message)) > orderGiftMessageMaxLength {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = orderGiftErrorMessageTooLong
+		return nil
+	}
+
+	deliveryDate := req.DeliveryDate
+
+	if deliveryDate.IsZero() {
+		deliveryDate = time.Now()
+	} else if deliveryDate.Before(time.Now().Add(-time.Minute)) {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = orderGiftErrorDeliveryDateInPast
+		return nil
+	}
+
+	gift, err := s.orderGiftRepository.GetByOrderId(ctx, order.Id)
+
+	if err != nil {
+		gift = &intPkg.OrderGift{
+			Id:        primitive.NewObjectID(),
+			CreatedAt: time.Now(),
+		}
+		gift.OrderId, _ = primitive.ObjectIDFromHex(order.Id)
+		gift.MerchantId, _ = primitive.ObjectIDFromHex(order.GetMerchantId())
+	}
+
+	gift.RecipientEmail = req.RecipientEmail
+	gift.Message = strings.TrimSpace(req.Message)
+	gift.DeliveryDate = deliveryDate.UTC()
+	gift.Status = intPkg.OrderGiftStatusPending
+	gift.UpdatedAt = time.Now()
+
+	if err = s.orderGiftRepository.Upsert(ctx, gift); err != nil {
+		return err
+	}
+
+	if order.PrivateMetadata == nil {
+		order.PrivateMetadata = make(map[string]string)
+	}
+
+	order.PrivateMetadata[pkg.OrderPrivateMetadataGiftRecipientEmail] = gift.RecipientEmail
+	order.PrivateMetadata[pkg.OrderPrivateMetadataGiftMessage] = gift.Message
+	order.PrivateMetadata[pkg.OrderPrivateMetadataGiftDeliveryDate] = gift.DeliveryDate.Format(time.RFC3339)
+
+	if err = s.orderRepository.Update(ctx, order); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = gift
+
+	return nil
+}
+
+// GetOrderGift returns the gift of the merchant order.
+func (s *Service) GetOrderGift(
+	ctx context.Context,
+	req *intPkg.OrderGiftRequest,
+	res *intPkg.OrderGiftResponse,
+) error {
+	gift, err := s.orderGiftRepository.GetByOrderId(ctx, req.OrderId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = orderGiftErrorNotFound
+		return nil
+	}
+
+	if gift.MerchantId.Hex() != req.MerchantId {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = orderGiftErrorMerchantNotEqual
+		return nil
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = gift
+
+	return nil
+}
+
+// DeliverScheduledGifts sends the keys and activation emails of the paid gift orders
+// to the recipients which delivery date has come.
+func (s *Service) DeliverScheduledGifts(ctx context.Context) error {
+	gifts, err := s.orderGiftRepository.FindScheduled(ctx, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	for _, gift := range gifts {
+		order, err := s.getOrderById(ctx, gift.OrderId.Hex())
+
+		if err == nil {
+			err = s.deliverOrderGift(ctx, order, gift)
+		}
+
+		if err != nil {
+			zap.L().Error(
+				pkg.MethodFinishedWithError,
+				zap.String("Method", "DeliverScheduledGifts"),
+				zap.Error(err),
+				zap.String("gift_id", gift.Id.Hex()),
+				zap.String("order_id", gift.OrderId.Hex()),
+			)
+		}
+	}
+
+	return nil
+}
+
+// onGiftPaymentNotify schedules the delivery of the gift after the successful payment of the order
+// and delivers it immediately if the delivery date has already come.
+func (s *Service) onGiftPaymentNotify(ctx context.Context, order *billingpb.Order) error {
+	gift, err := s.getOrderGift(ctx, order)
+
+	if err != nil || gift == nil {
+		return err
+	}
+
+	if gift.Status != intPkg.OrderGiftStatusPending {
+		return nil
+	}
+
+	gift.Status = intPkg.OrderGiftStatusScheduled
+	gift.UpdatedAt = time.Now()
+
+	if err = s.orderGiftRepository.Upsert(ctx, gift); err != nil {
+		return err
+	}
+
+	if gift.DeliveryDate.After(time.Now()) {
+		return nil
+	}
+
+	return s.deliverOrderGift(ctx, order, gift)
+}
+
+// onGiftRefundNotify revokes the gift of the fully refunded order. If the gift was already delivered
+// then the recipient is notified about the revocation, otherwise the scheduled delivery is cancelled.
+func (s *Service) onGiftRefundNotify(ctx context.Context, order *billingpb.Order) error {
+	gift, err := s.getOrderGift(ctx, order)
+
+	if err != nil || gift == nil {
+		return err
+	}
+
+	if gift.Status == intPkg.OrderGiftStatusRevoked {
+		return nil
+	}
+
+	isDelivered := gift.Status == intPkg.OrderGiftStatusDelivered
+
+	gift.Status = intPkg.OrderGiftStatusRevoked
+	gift.RevokedAt = time.Now()
+	gift.UpdatedAt = time.Now()
+
+	if err = s.orderGiftRepository.Upsert(ctx, gift); err != nil {
+		return err
+	}
+
+	if !isDelivered {
+		return nil
+	}
+
+	payload := &postmarkpb.Payload{
+		TemplateAlias: s.cfg.EmailTemplates.GiftRevoked,
+		TemplateModel: map[string]string{
+			"product_names": s.getOrderItemsNames(order),
+			"project_name":  order.Project.Name[DefaultLanguage],
+		},
+		To: gift.RecipientEmail,
+	}
+
+	err = s.postmarkBroker.Publish(postmarkpb.PostmarkSenderTopicName, payload, amqp.Table{})
+
+	if err != nil {
+		zap.L().Error(
+			"Publication gift revocation to recipient email queue is failed",
+			zap.Error(err),
+			zap.String("order_id", order.Id),
+		)
+	}
+
+	return nil
+}
+
+// deliverOrderGift sends the gift to the recipient and marks it delivered only after all emails are queued.
+// The keys which emails are already queued are kept in the gift, so the next attempt doesn't send them again.
+func (s *Service) deliverOrderGift(ctx context.Context, order *billingpb.Order, gift *intPkg.OrderGift) error {
+	giftModel := map[string]string{
+		"gift_message":      gift.Message,
+		"gift_sender_email": order.ReceiptEmail,
+	}
+
+	if order.ProductType == pkg.OrderType_key {
+		for _, keyId := range order.Keys {
+			if helper.Contains(gift.DeliveredKeys, keyId) {
+				continue
+			}
+
+			key, err := s.keyRepository.GetById(ctx, keyId)
+
+			if err != nil {
+				zap.L().Error(
+					pkg.MethodFinishedWithError,
+					zap.Error(err),
+					zap.String("order_id", order.Id),
+					zap.String("key_id", keyId),
+				)
+				return orderGiftErrorDeliveryKeyNotFound
+			}
+
+			if err = s.publishMailWithCode(order, key, gift.RecipientEmail, giftModel); err != nil {
+				return err
+			}
+
+			gift.DeliveredKeys = append(gift.DeliveredKeys, keyId)
+			gift.UpdatedAt = time.Now()
+
+			if err = s.orderGiftRepository.Upsert(ctx, gift); err != nil {
+				return err
+			}
+		}
+	} else {
+		giftModel["product_names"] = s.getOrderItemsNames(order)
+		giftModel["project_name"] = order.Project.Name[DefaultLanguage]
+
+		payload := &postmarkpb.Payload{
+			TemplateAlias: s.cfg.EmailTemplates.GiftDelivery,
+			TemplateModel: giftModel,
+			To:            gift.RecipientEmail,
+		}
+
+		err := s.postmarkBroker.Publish(postmarkpb.PostmarkSenderTopicName, payload, amqp.Table{})
+
+		if err != nil {
+			zap.L().Error(
+				"Publication gift to recipient email queue is failed",
+				zap.Error(err),
+				zap.String("order_id", order.Id),
+			)
+			return err
+		}
+	}
+
+	gift.Status = intPkg.OrderGiftStatusDelivered
+	gift.DeliveredAt = time.Now()
+	gift.UpdatedAt = time.Now()
+
+	return s.orderGiftRepository.Upsert(ctx, gift)
+}
+
+// getOrderGift returns the gift of the order set by the order gift request, nil is returned if the order isn't a gift.
+// The metadata of the order is never used to detect the gift.
+func (s *Service) getOrderGift(ctx context.Context, order *billingpb.Order) (*intPkg.OrderGift, error) {
+	gift, err := s.orderGiftRepository.GetByOrderId(ctx, order.Id)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return gift, nil
+}
+
+func (s *Service) getOrderItemsNames(order *billingpb.Order) string {
+	names := make([]string, 0, len(order.Items))
+
+	for _, item := range order.Items {
+		names = append(names, item.Name)
+	}
+
+	return strings.Join(names, ", ")
+}
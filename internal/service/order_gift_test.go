@@ -0,0 +1,395 @@
+package service
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/mongodb"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	rabbitmq "gopkg.in/ProtocolONE/rabbitmq.v1/pkg"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"strings"
+	"testing"
+	"time"
+)
+
+type OrderGiftTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant      *billingpb.Merchant
+	project       *billingpb.Project
+	paymentMethod *billingpb.PaymentMethod
+	products      []*billingpb.Product
+}
+
+func Test_OrderGift(t *testing.T) {
+	suite.Run(t, new(OrderGiftTestSuite))
+}
+
+func (suite *OrderGiftTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+	cfg.CardPayApiUrl = "https://sandbox.cardpay.com"
+
+	m, err := migrate.New(
+		"file://../../migrations/tests",
+		cfg.MongoDsn)
+	assert.NoError(suite.T(), err, "Migrate init failed")
+
+	err = m.Up()
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	broker, err := rabbitmq.NewBroker(cfg.BrokerAddress)
+
+	if err != nil {
+		suite.FailNow("Creating RabbitMQ publisher failed", "%v", err)
+	}
+
+	redisClient := database.NewRedis(
+		&redis.Options{
+			Addr:     cfg.RedisHost,
+			Password: cfg.RedisPassword,
+		},
+	)
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		broker,
+		redisClient,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	suite.merchant, suite.project, suite.paymentMethod, _ = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+	suite.products = CreateProductsForProject(suite.Suite, suite.service, suite.project, 1)
+}
+
+func (suite *OrderGiftTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *OrderGiftTestSuite) createOrder() *billingpb.Order {
+	req := &billingpb.OrderCreateRequest{
+		Type:      pkg.OrderType_product,
+		ProjectId: suite.project.Id,
+		Products:  []string{suite.products[0].Id},
+		User: &billingpb.OrderUser{
+			Email: "buyer@unit.test",
+			Ip:    "127.0.0.1",
+			Address: &billingpb.OrderBillingAddress{
+				Country: "RU",
+			},
+		},
+	}
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateProcess(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	return rsp.Item
+}
+
+func (suite *OrderGiftTestSuite) TestOrderGift_SetOrderGift_Ok() {
+	order := suite.createOrder()
+
+	req := &intPkg.SetOrderGiftRequest{
+		OrderId:        order.Uuid,
+		RecipientEmail: "recipient@unit.test",
+		Message:        " Happy birthday! ",
+		DeliveryDate:   time.Now().AddDate(0, 0, 1),
+	}
+	res := &intPkg.OrderGiftResponse{}
+	err := suite.service.SetOrderGift(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Equal(suite.T(), intPkg.OrderGiftStatusPending, res.Item.Status)
+	assert.Equal(suite.T(), "Happy birthday!", res.Item.Message)
+
+	order, err = suite.service.orderRepository.GetById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), req.RecipientEmail, order.PrivateMetadata[pkg.OrderPrivateMetadataGiftRecipientEmail])
+	assert.Equal(suite.T(), "Happy birthday!", order.PrivateMetadata[pkg.OrderPrivateMetadataGiftMessage])
+	assert.NotEmpty(suite.T(), order.PrivateMetadata[pkg.OrderPrivateMetadataGiftDeliveryDate])
+
+	res2 := &intPkg.OrderGiftResponse{}
+	err = suite.service.GetOrderGift(context.TODO(), &intPkg.OrderGiftRequest{OrderId: order.Id, MerchantId: suite.merchant.Id}, res2)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res2.Status)
+	assert.Equal(suite.T(), res.Item.Id, res2.Item.Id)
+
+	err = suite.service.GetOrderGift(context.TODO(), &intPkg.OrderGiftRequest{OrderId: order.Id, MerchantId: primitive.NewObjectID().Hex()}, res2)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res2.Status)
+	assert.Equal(suite.T(), orderGiftErrorMerchantNotEqual, res2.Message)
+}
+
+func (suite *OrderGiftTestSuite) TestOrderGift_SetOrderGift_ValidationError() {
+	order := suite.createOrder()
+	res := &intPkg.OrderGiftResponse{}
+
+	req := &intPkg.SetOrderGiftRequest{OrderId: order.Uuid, RecipientEmail: "recipient"}
+	err := suite.service.SetOrderGift(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), orderGiftErrorRecipientEmail, res.Message)
+
+	req = &intPkg.SetOrderGiftRequest{
+		OrderId:        order.Uuid,
+		RecipientEmail: "recipient@unit.test",
+		Message:        strings.Repeat("a", orderGiftMessageMaxLength+1),
+	}
+	err = suite.service.SetOrderGift(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), orderGiftErrorMessageTooLong, res.Message)
+
+	req = &intPkg.SetOrderGiftRequest{
+		OrderId:        order.Uuid,
+		RecipientEmail: "recipient@unit.test",
+		DeliveryDate:   time.Now().AddDate(0, 0, -1),
+	}
+	err = suite.service.SetOrderGift(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), orderGiftErrorDeliveryDateInPast, res.Message)
+
+	req = &intPkg.SetOrderGiftRequest{OrderId: "unknown", RecipientEmail: "recipient@unit.test"}
+	err = suite.service.SetOrderGift(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, res.Status)
+}
+
+func (suite *OrderGiftTestSuite) TestOrderGift_Payment_DeliveredImmediately() {
+	order := suite.createOrder()
+
+	req := &intPkg.SetOrderGiftRequest{OrderId: order.Uuid, RecipientEmail: "recipient@unit.test"}
+	res := &intPkg.OrderGiftResponse{}
+	err := suite.service.SetOrderGift(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+
+	order, err = suite.service.orderRepository.GetById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	HelperPayOrder(suite.Suite, suite.service, order, suite.paymentMethod, "RU")
+
+	gift, err := suite.service.orderGiftRepository.GetByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), intPkg.OrderGiftStatusDelivered, gift.Status)
+	assert.False(suite.T(), gift.DeliveredAt.IsZero())
+}
+
+func (suite *OrderGiftTestSuite) TestOrderGift_Payment_ScheduledAndRevoked() {
+	order := suite.createOrder()
+
+	req := &intPkg.SetOrderGiftRequest{
+		OrderId:        order.Uuid,
+		RecipientEmail: "recipient@unit.test",
+		DeliveryDate:   time.Now().Add(time.Hour),
+	}
+	res := &intPkg.OrderGiftResponse{}
+	err := suite.service.SetOrderGift(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+
+	order, err = suite.service.orderRepository.GetById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	order = HelperPayOrder(suite.Suite, suite.service, order, suite.paymentMethod, "RU")
+
+	gift, err := suite.service.orderGiftRepository.GetByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), intPkg.OrderGiftStatusScheduled, gift.Status)
+
+	err = suite.service.DeliverScheduledGifts(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	gift, err = suite.service.orderGiftRepository.GetByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), intPkg.OrderGiftStatusScheduled, gift.Status)
+
+	gift.DeliveryDate = time.Now().Add(-time.Minute)
+	err = suite.service.orderGiftRepository.Upsert(context.TODO(), gift)
+	assert.NoError(suite.T(), err)
+
+	err = suite.service.DeliverScheduledGifts(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	gift, err = suite.service.orderGiftRepository.GetByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), intPkg.OrderGiftStatusDelivered, gift.Status)
+
+	err = suite.service.onGiftRefundNotify(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	gift, err = suite.service.orderGiftRepository.GetByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), intPkg.OrderGiftStatusRevoked, gift.Status)
+	assert.False(suite.T(), gift.RevokedAt.IsZero())
+}
+
+func (suite *OrderGiftTestSuite) TestOrderGift_sendMailWithCode_SkippedForGift() {
+	postmarkBroker := &mocks.BrokerInterface{}
+	suite.service.postmarkBroker = postmarkBroker
+
+	order := &billingpb.Order{
+		Id:    primitive.NewObjectID().Hex(),
+		Items: []*billingpb.OrderItem{{Id: "key_product_id", Name: "Game"}},
+	}
+	gift := &intPkg.OrderGift{
+		Id:             primitive.NewObjectID(),
+		RecipientEmail: "recipient@unit.test",
+		Status:         intPkg.OrderGiftStatusScheduled,
+	}
+	gift.OrderId, _ = primitive.ObjectIDFromHex(order.Id)
+	err := suite.service.orderGiftRepository.Upsert(context.TODO(), gift)
+	assert.NoError(suite.T(), err)
+
+	suite.service.sendMailWithCode(context.TODO(), order, &billingpb.Key{KeyProductId: "key_product_id", Code: "code"})
+	postmarkBroker.AssertNumberOfCalls(suite.T(), "Publish", 0)
+}
+
+func (suite *OrderGiftTestSuite) TestOrderGift_sendMailWithCode_GiftMetadataIgnored() {
+	postmarkBroker := &mocks.BrokerInterface{}
+	postmarkBroker.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.service.postmarkBroker = postmarkBroker
+
+	order := &billingpb.Order{
+		Id:           primitive.NewObjectID().Hex(),
+		ReceiptEmail: "buyer@unit.test",
+		PrivateMetadata: map[string]string{
+			pkg.OrderPrivateMetadataGiftRecipientEmail: "recipient@unit.test",
+		},
+		Items: []*billingpb.OrderItem{{Id: "key_product_id", Name: "Game"}},
+	}
+	suite.service.sendMailWithCode(context.TODO(), order, &billingpb.Key{KeyProductId: "key_product_id", Code: "code"})
+	postmarkBroker.AssertNumberOfCalls(suite.T(), "Publish", 1)
+
+	err := suite.service.onGiftPaymentNotify(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+	postmarkBroker.AssertNumberOfCalls(suite.T(), "Publish", 1)
+}
+
+func (suite *OrderGiftTestSuite) TestOrderGift_OrderCreate_GiftMetadataRejected() {
+	req := &billingpb.OrderCreateRequest{
+		Type:      pkg.OrderType_product,
+		ProjectId: suite.project.Id,
+		Products:  []string{suite.products[0].Id},
+		User: &billingpb.OrderUser{
+			Email: "buyer@unit.test",
+			Ip:    "127.0.0.1",
+			Address: &billingpb.OrderBillingAddress{
+				Country: "RU",
+			},
+		},
+		PrivateMetadata: map[string]string{
+			pkg.OrderPrivateMetadataGiftRecipientEmail: "recipient@unit.test",
+		},
+	}
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateProcess(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), orderErrorPrivateMetadataGiftKeys, rsp.Message)
+}
+
+func (suite *OrderGiftTestSuite) TestOrderGift_DeliverScheduledGifts_DeliveredOnlyAfterEmailQueued() {
+	order := suite.createOrder()
+
+	req := &intPkg.SetOrderGiftRequest{
+		OrderId:        order.Uuid,
+		RecipientEmail: "recipient@unit.test",
+		DeliveryDate:   time.Now().Add(time.Hour),
+	}
+	res := &intPkg.OrderGiftResponse{}
+	err := suite.service.SetOrderGift(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+
+	order, err = suite.service.orderRepository.GetById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	HelperPayOrder(suite.Suite, suite.service, order, suite.paymentMethod, "RU")
+
+	gift, err := suite.service.orderGiftRepository.GetByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	gift.DeliveryDate = time.Now().Add(-time.Minute)
+	err = suite.service.orderGiftRepository.Upsert(context.TODO(), gift)
+	assert.NoError(suite.T(), err)
+
+	orphan := &intPkg.OrderGift{
+		Id:             primitive.NewObjectID(),
+		OrderId:        primitive.NewObjectID(),
+		MerchantId:     gift.MerchantId,
+		RecipientEmail: "orphan@unit.test",
+		DeliveryDate:   time.Now().Add(-2 * time.Minute),
+		Status:         intPkg.OrderGiftStatusScheduled,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	err = suite.service.orderGiftRepository.Upsert(context.TODO(), orphan)
+	assert.NoError(suite.T(), err)
+
+	suite.service.postmarkBroker = mocks.NewBrokerMockError()
+
+	err = suite.service.DeliverScheduledGifts(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	gift, err = suite.service.orderGiftRepository.GetByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), intPkg.OrderGiftStatusScheduled, gift.Status)
+	assert.True(suite.T(), gift.DeliveredAt.IsZero())
+
+	suite.service.postmarkBroker = mocks.NewBrokerMockOk()
+
+	err = suite.service.DeliverScheduledGifts(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	gift, err = suite.service.orderGiftRepository.GetByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), intPkg.OrderGiftStatusDelivered, gift.Status)
+}
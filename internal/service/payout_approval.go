@@ -0,0 +1,437 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const (
+	payoutApprovalCreatedNotificationMessage  = "Payout %s of %s %s is created and waiting for approval."
+	payoutApprovalApprovedNotificationMessage = "Payout %s of %s %s is approved (%d of %d approvals)."
+	payoutApprovalRejectedNotificationMessage = "Payout %s of %s %s is rejected: %s"
+	payoutApprovalPaidNotificationMessage     = "Payout %s of %s %s is paid."
+)
+
+var (
+	payoutApprovalErrorAccessDenied       = newBillingServerErrorMsg("pa000001", "only admin or financier can approve, reject or mark the payout as paid")
+	payoutApprovalErrorNotFound           = newBillingServerErrorMsg("pa000002", "payout approval not found")
+	payoutApprovalErrorStatusInvalid      = newBillingServerErrorMsg("pa000003", "action is not allowed for the payout in the current approval status")
+	payoutApprovalErrorSameUser           = newBillingServerErrorMsg("pa000004", "payout must be created, approved and marked as paid by different users")
+	payoutApprovalErrorLimitExceeded      = newBillingServerErrorMsg("pa000005", "payout amount exceeds the approval limit of the user")
+	payoutApprovalErrorLimitsInvalid      = newBillingServerErrorMsg("pa000006", "approval limit must be set for the currency code and can't be negative")
+	payoutApprovalErrorApproverNotAllowed = newBillingServerErrorMsg("pa000007", "approver must be admin or financier")
+	payoutApprovalErrorLimitsAccessDenied = newBillingServerErrorMsg("pa000008", "only admin can change the approval limits")
+)
+
+// SetPayoutApprover creates or replaces the payout approval limits of the user.
+func (s *Service) SetPayoutApprover(
+	ctx context.Context,
+	req *intPkg.SetPayoutApproverRequest,
+	res *intPkg.PayoutApproverResponse,
+) error {
+	if !s.hasAdminRole(ctx, req.UserId, billingpb.RoleSystemAdmin) {
+		res.Status = billingpb.ResponseStatusForbidden
+		res.Message = payoutApprovalErrorLimitsAccessDenied
+		return nil
+	}
+
+	if !s.hasAdminRole(ctx, req.ApproverId, billingpb.RoleSystemAdmin, billingpb.RoleSystemFinancial) {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = payoutApprovalErrorApproverNotAllowed
+		return nil
+	}
+
+	limits := make(map[string]float64, len(req.Limits))
+
+	for currency, amount := range req.Limits {
+		if len(currency) != 3 || amount < 0 {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = payoutApprovalErrorLimitsInvalid
+			return nil
+		}
+
+		currency = strings.ToUpper(currency)
+		limits[currency] = s.FormatAmount(amount, currency)
+	}
+
+	now := time.Now()
+	approver, err := s.payoutApproverRepository.GetByUserId(ctx, req.ApproverId)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		approver = &intPkg.PayoutApprover{
+			Id:        primitive.NewObjectID(),
+			UserId:    req.ApproverId,
+			CreatedAt: now,
+		}
+	}
+
+	approver.Limits = limits
+	approver.UpdatedBy = req.UserId
+	approver.UpdatedAt = now
+
+	if err = s.payoutApproverRepository.Upsert(ctx, approver); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = approver
+
+	return nil
+}
+
+// GetPayoutApproval returns the approval state of the payout document with the audit trail.
+func (s *Service) GetPayoutApproval(
+	ctx context.Context,
+	req *intPkg.GetPayoutApprovalRequest,
+	res *intPkg.PayoutApprovalResponse,
+) error {
+	approval, err := s.payoutApprovalRepository.GetByPayoutDocumentId(ctx, req.PayoutDocumentId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = payoutApprovalErrorNotFound
+		return nil
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = approval
+
+	return nil
+}
+
+// ApprovePayoutDocument adds the approval of the user to the pending payout. The approver must differ from
+// the creator of the payout and from the other approver, the payout amount must fit the approval limit of the user.
+// The approval is saved only if it wasn't changed by the concurrent action, so two concurrent approvals can't
+// be counted as the one or overwrite each other.
+func (s *Service) ApprovePayoutDocument(
+	ctx context.Context,
+	req *intPkg.PayoutApprovalActionRequest,
+	res *intPkg.PayoutApprovalResponse,
+) error {
+	approval, pd, message := s.getPayoutApprovalForAction(ctx, req, intPkg.PayoutApprovalStatusAwaiting)
+
+	if message != nil {
+		res.Status, res.Message = payoutApprovalStatusByError(message), message
+		return nil
+	}
+
+	approver, err := s.payoutApproverRepository.GetByUserId(ctx, req.UserId)
+
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	if approver == nil || !approver.CanApprove(approval.Amount, approval.Currency) {
+		res.Status = billingpb.ResponseStatusForbidden
+		res.Message = payoutApprovalErrorLimitExceeded
+		return nil
+	}
+
+	status, approvedBy := approval.Status, approval.ApprovedBy
+	approval.ApprovedBy = append(approval.ApprovedBy, req.UserId)
+
+	if int32(len(approval.ApprovedBy)) >= approval.RequiredApprovals {
+		approval.Status = intPkg.PayoutApprovalStatusApproved
+	}
+
+	err = s.updatePayoutApproval(ctx, approval, status, approvedBy, intPkg.PayoutApprovalActionApprove, req)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = payoutApprovalErrorStatusInvalid
+			return nil
+		}
+
+		return err
+	}
+
+	s.notifyPayoutApproval(ctx, approval, pd, fmt.Sprintf(
+		payoutApprovalApprovedNotificationMessage,
+		pd.Id,
+		s.newMoney(approval.Amount, approval.Currency).String(),
+		approval.Currency,
+		len(approval.ApprovedBy),
+		approval.RequiredApprovals,
+	))
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = approval
+
+	return nil
+}
+
+// RejectPayoutDocument cancels the payout which isn't paid yet.
+func (s *Service) RejectPayoutDocument(
+	ctx context.Context,
+	req *intPkg.PayoutApprovalActionRequest,
+	res *intPkg.PayoutApprovalResponse,
+) error {
+	approval, pd, message := s.getPayoutApprovalForAction(
+		ctx,
+		req,
+		intPkg.PayoutApprovalStatusAwaiting,
+		intPkg.PayoutApprovalStatusApproved,
+	)
+
+	if message != nil && message != payoutApprovalErrorSameUser {
+		res.Status, res.Message = payoutApprovalStatusByError(message), message
+		return nil
+	}
+
+	updateRes := &billingpb.PayoutDocumentResponse{}
+	err := s.updatePayoutDocument(
+		ctx,
+		&billingpb.UpdatePayoutDocumentRequest{
+			PayoutDocumentId: pd.Id,
+			Status:           pkg.PayoutDocumentStatusCanceled,
+			FailureMessage:   req.Comment,
+			Ip:               req.Ip,
+		},
+		updateRes,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if updateRes.Status != billingpb.ResponseStatusOk {
+		res.Status, res.Message = updateRes.Status, updateRes.Message
+		return nil
+	}
+
+	status, approvedBy := approval.Status, approval.ApprovedBy
+	approval.Status = intPkg.PayoutApprovalStatusRejected
+
+	err = s.updatePayoutApproval(ctx, approval, status, approvedBy, intPkg.PayoutApprovalActionReject, req)
+
+	if err != nil {
+		return err
+	}
+
+	s.notifyPayoutApproval(ctx, approval, pd, fmt.Sprintf(
+		payoutApprovalRejectedNotificationMessage,
+		pd.Id,
+		s.newMoney(approval.Amount, approval.Currency).String(),
+		approval.Currency,
+		req.Comment,
+	))
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = approval
+
+	return nil
+}
+
+// MarkPayoutDocumentPaid marks the approved payout as paid. The user must differ from the creator and the approvers.
+func (s *Service) MarkPayoutDocumentPaid(
+	ctx context.Context,
+	req *intPkg.PayoutApprovalActionRequest,
+	res *intPkg.PayoutApprovalResponse,
+) error {
+	approval, pd, message := s.getPayoutApprovalForAction(ctx, req, intPkg.PayoutApprovalStatusApproved)
+
+	if message != nil {
+		res.Status, res.Message = payoutApprovalStatusByError(message), message
+		return nil
+	}
+
+	updateRes := &billingpb.PayoutDocumentResponse{}
+	err := s.updatePayoutDocument(
+		ctx,
+		&billingpb.UpdatePayoutDocumentRequest{
+			PayoutDocumentId: pd.Id,
+			Status:           pkg.PayoutDocumentStatusPaid,
+			Transaction:      req.Transaction,
+			Ip:               req.Ip,
+		},
+		updateRes,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if updateRes.Status != billingpb.ResponseStatusOk {
+		res.Status, res.Message = updateRes.Status, updateRes.Message
+		return nil
+	}
+
+	status, approvedBy := approval.Status, approval.ApprovedBy
+	approval.Status = intPkg.PayoutApprovalStatusPaid
+	approval.PaidBy = req.UserId
+
+	err = s.updatePayoutApproval(ctx, approval, status, approvedBy, intPkg.PayoutApprovalActionPaid, req)
+
+	if err != nil {
+		return err
+	}
+
+	s.notifyPayoutApproval(ctx, approval, pd, fmt.Sprintf(
+		payoutApprovalPaidNotificationMessage,
+		pd.Id,
+		s.newMoney(approval.Amount, approval.Currency).String(),
+		approval.Currency,
+	))
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = approval
+
+	return nil
+}
+
+// createPayoutApproval starts the approval of the pending payout document created by the user. The automatic
+// payouts are created by the system.
+func (s *Service) createPayoutApproval(
+	ctx context.Context,
+	pd *billingpb.PayoutDocument,
+	createdBy string,
+) error {
+	requiredApprovals := int32(1)
+	dualApprovalAmount, ok := s.cfg.PayoutDualApprovalAmounts[pd.Currency]
+
+	if ok && pd.Balance > dualApprovalAmount {
+		requiredApprovals = 2
+	}
+
+	now := time.Now()
+	approval := &intPkg.PayoutApproval{
+		Id:                primitive.NewObjectID(),
+		PayoutDocumentId:  pd.Id,
+		MerchantId:        pd.MerchantId,
+		Amount:            pd.Balance,
+		Currency:          pd.Currency,
+		Status:            intPkg.PayoutApprovalStatusAwaiting,
+		RequiredApprovals: requiredApprovals,
+		CreatedBy:         createdBy,
+		ApprovedBy:        []string{},
+		History: []*intPkg.PayoutApprovalHistoryItem{
+			{Action: intPkg.PayoutApprovalActionCreate, UserId: createdBy, CreatedAt: now},
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.payoutApprovalRepository.Insert(ctx, approval); err != nil {
+		return err
+	}
+
+	s.notifyPayoutApproval(ctx, approval, pd, fmt.Sprintf(
+		payoutApprovalCreatedNotificationMessage,
+		pd.Id,
+		s.newMoney(approval.Amount, approval.Currency).String(),
+		approval.Currency,
+	))
+
+	return nil
+}
+
+// getPayoutApprovalForAction checks that the user can make the action with the payout in the current status.
+// The user who initiated the payout document can't approve or pay it.
+func (s *Service) getPayoutApprovalForAction(
+	ctx context.Context,
+	req *intPkg.PayoutApprovalActionRequest,
+	statuses ...string,
+) (*intPkg.PayoutApproval, *billingpb.PayoutDocument, *billingpb.ResponseErrorMessage) {
+	if !s.hasAdminRole(ctx, req.UserId, billingpb.RoleSystemAdmin, billingpb.RoleSystemFinancial) {
+		return nil, nil, payoutApprovalErrorAccessDenied
+	}
+
+	approval, err := s.payoutApprovalRepository.GetByPayoutDocumentId(ctx, req.PayoutDocumentId)
+
+	if err != nil {
+		return nil, nil, payoutApprovalErrorNotFound
+	}
+
+	pd, err := s.payoutRepository.GetById(ctx, req.PayoutDocumentId)
+
+	if err != nil {
+		return nil, nil, errorPayoutNotFound
+	}
+
+	allowed := pd.Status == pkg.PayoutDocumentStatusPending
+
+	if allowed {
+		allowed = false
+
+		for _, status := range statuses {
+			if approval.Status == status {
+				allowed = true
+				break
+			}
+		}
+	}
+
+	if !allowed {
+		return nil, nil, payoutApprovalErrorStatusInvalid
+	}
+
+	if approval.IsParticipant(req.UserId) {
+		return approval, pd, payoutApprovalErrorSameUser
+	}
+
+	return approval, pd, nil
+}
+
+// updatePayoutApproval saves the action of the user with the approval. The approval is saved only if its status
+// and approvers are still equal to the read ones, otherwise mongo.ErrNoDocuments is returned.
+func (s *Service) updatePayoutApproval(
+	ctx context.Context,
+	approval *intPkg.PayoutApproval,
+	status string,
+	approvedBy []string,
+	action string,
+	req *intPkg.PayoutApprovalActionRequest,
+) error {
+	now := time.Now()
+	approval.UpdatedAt = now
+	approval.History = append(approval.History, &intPkg.PayoutApprovalHistoryItem{
+		Action:    action,
+		UserId:    req.UserId,
+		Comment:   req.Comment,
+		CreatedAt: now,
+	})
+
+	return s.payoutApprovalRepository.UpdateByState(ctx, approval, status, approvedBy)
+}
+
+func (s *Service) notifyPayoutApproval(
+	ctx context.Context,
+	approval *intPkg.PayoutApproval,
+	pd *billingpb.PayoutDocument,
+	message string,
+) {
+	_, err := s.addNotification(ctx, message, pd.MerchantId, "", nil)
+
+	if err != nil {
+		zap.L().Error(
+			"Notification about payout approval failed",
+			zap.Error(err),
+			zap.String("payout_document_id", pd.Id),
+			zap.String("status", approval.Status),
+		)
+	}
+}
+
+func payoutApprovalStatusByError(message *billingpb.ResponseErrorMessage) int32 {
+	switch message {
+	case payoutApprovalErrorNotFound, errorPayoutNotFound:
+		return billingpb.ResponseStatusNotFound
+	case payoutApprovalErrorStatusInvalid:
+		return billingpb.ResponseStatusBadData
+	}
+
+	return billingpb.ResponseStatusForbidden
+}
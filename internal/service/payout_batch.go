@@ -0,0 +1,751 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	payoutBatchSepaNamespace     = "urn:iso:std:iso:20022:tech:xsd:pain.001.001.03"
+	payoutBatchDateFormat        = "2006-01-02"
+	payoutBatchDateTimeFormat    = "2006-01-02T15:04:05"
+	payoutBatchFileNameFormat    = "payouts_%s_%s_%s.%s"
+	payoutBatchRemittanceFormat  = "Payout %s"
+	payoutBatchNachaLineLength   = 94
+	payoutBatchNachaBlockingSize = 10
+	payoutBatchNachaCompanyEntry = "PAYOUT"
+
+	payoutBatchRejectNotFound  = "payout document not found"
+	payoutBatchRejectStatus    = "payout document is not pending"
+	payoutBatchRejectExported  = "payout document is already included into the batch %s"
+	payoutBatchRejectApproval  = "payout document is not approved"
+	payoutBatchRejectBanking   = "merchant banking data is not set"
+	payoutBatchRejectIban      = "merchant IBAN is invalid"
+	payoutBatchRejectBic       = "merchant BIC is invalid"
+	payoutBatchRejectAccount   = "merchant account number is invalid"
+	payoutBatchRejectAchRouter = "merchant ABA routing number is invalid"
+)
+
+var (
+	payoutBatchErrorAccessDenied   = newBillingServerErrorMsg("pb000001", "only admin or financier can export payout documents")
+	payoutBatchErrorEmptyList      = newBillingServerErrorMsg("pb000002", "payout documents for export are not specified")
+	payoutBatchErrorDebtorSepa     = newBillingServerErrorMsg("pb000003", "debtor name, IBAN and BIC are required for SEPA transfers")
+	payoutBatchErrorDebtorNacha    = newBillingServerErrorMsg("pb000004", "debtor name, ABA routing number and company identification are required for ACH transfers")
+	payoutBatchErrorNotFound       = newBillingServerErrorMsg("pb000005", "payout batch not found")
+	payoutBatchErrorDebtorRequired = newBillingServerErrorMsg("pb000006", "debtor bank account is required")
+
+	payoutBatchFileExtensions = map[string]string{
+		intPkg.PayoutBatchFormatSepa:     "xml",
+		intPkg.PayoutBatchFormatNacha:    "ach",
+		intPkg.PayoutBatchFormatSwiftCsv: "csv",
+	}
+
+	payoutBatchSwiftCsvHeader = []string{
+		"payout_document_id",
+		"merchant_id",
+		"beneficiary_name",
+		"beneficiary_address",
+		"beneficiary_account",
+		"beneficiary_bank_name",
+		"beneficiary_bank_address",
+		"beneficiary_bic",
+		"amount",
+		"currency",
+		"execution_date",
+		"reference",
+		"details",
+	}
+)
+
+type payoutBatchItem struct {
+	payout  *billingpb.PayoutDocument
+	banking *billingpb.MerchantBanking
+	name    string
+	address string
+	amount  intPkg.Money
+}
+
+type payoutBatchGroup struct {
+	format   string
+	currency string
+	items    []*payoutBatchItem
+}
+
+// ExportPayoutBatches generates the bank transfer files for the approved payout documents. The documents are grouped
+// by the transfer scheme: EUR payouts to SEPA accounts are exported as pain.001, USD payouts to the US accounts
+// as NACHA and all other payouts as the generic SWIFT CSV. The documents which can't be paid by the merchant banking
+// data are returned as rejected and aren't included into the batches. The documents are claimed by the batch
+// atomically, so the document included into the batch by the concurrent export is rejected too.
+func (s *Service) ExportPayoutBatches(
+	ctx context.Context,
+	req *intPkg.ExportPayoutBatchesRequest,
+	res *intPkg.ExportPayoutBatchesResponse,
+) error {
+	if !s.hasAdminRole(ctx, req.UserId, billingpb.RoleSystemAdmin, billingpb.RoleSystemFinancial) {
+		res.Status = billingpb.ResponseStatusForbidden
+		res.Message = payoutBatchErrorAccessDenied
+		return nil
+	}
+
+	if len(req.PayoutDocumentIds) == 0 {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = payoutBatchErrorEmptyList
+		return nil
+	}
+
+	if req.Debtor == nil {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = payoutBatchErrorDebtorRequired
+		return nil
+	}
+
+	batchIds, err := s.payoutRepository.GetBatchIds(ctx, req.PayoutDocumentIds)
+
+	if err != nil {
+		return err
+	}
+
+	res.Items = []*intPkg.PayoutBatch{}
+	res.Rejected = []*intPkg.PayoutBatchRejectedItem{}
+
+	var groups []*payoutBatchGroup
+	index := make(map[string]*payoutBatchGroup)
+
+	for _, id := range req.PayoutDocumentIds {
+		if batchId, ok := batchIds[id]; ok {
+			res.Rejected = append(res.Rejected, newPayoutBatchRejectedItem(id, fmt.Sprintf(payoutBatchRejectExported, batchId)))
+			continue
+		}
+
+		pd, err := s.payoutRepository.GetById(ctx, id)
+
+		if err != nil {
+			res.Rejected = append(res.Rejected, newPayoutBatchRejectedItem(id, payoutBatchRejectNotFound))
+			continue
+		}
+
+		if pd.Status != pkg.PayoutDocumentStatusPending {
+			res.Rejected = append(res.Rejected, newPayoutBatchRejectedItem(id, payoutBatchRejectStatus))
+			continue
+		}
+
+		approval, err := s.payoutApprovalRepository.GetByPayoutDocumentId(ctx, id)
+
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		if approval == nil || approval.Status != intPkg.PayoutApprovalStatusApproved {
+			res.Rejected = append(res.Rejected, newPayoutBatchRejectedItem(id, payoutBatchRejectApproval))
+			continue
+		}
+
+		item, format, reason := s.newPayoutBatchItem(ctx, pd)
+
+		if reason != "" {
+			res.Rejected = append(res.Rejected, newPayoutBatchRejectedItem(id, reason))
+			continue
+		}
+
+		key := format + pd.Currency
+		group, ok := index[key]
+
+		if !ok {
+			group = &payoutBatchGroup{format: format, currency: pd.Currency}
+			index[key] = group
+			groups = append(groups, group)
+		}
+
+		group.items = append(group.items, item)
+	}
+
+	for _, group := range groups {
+		if message := validatePayoutBatchDebtor(group.format, req.Debtor); message != nil {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = message
+			res.Items = []*intPkg.PayoutBatch{}
+			return nil
+		}
+	}
+
+	executionDate := req.ExecutionDate
+
+	if executionDate.IsZero() {
+		executionDate = time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	}
+
+	for _, group := range groups {
+		batch, unclaimed, err := s.createPayoutBatch(ctx, group, req.Debtor, executionDate, req.UserId)
+
+		if err != nil {
+			return err
+		}
+
+		if len(unclaimed) > 0 {
+			rejected, err := s.getPayoutBatchUnclaimedItems(ctx, unclaimed)
+
+			if err != nil {
+				return err
+			}
+
+			res.Rejected = append(res.Rejected, rejected...)
+		}
+
+		if batch != nil {
+			res.Items = append(res.Items, batch)
+		}
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+
+	return nil
+}
+
+// GetPayoutBatch returns the bank transfer batch with the file content.
+func (s *Service) GetPayoutBatch(
+	ctx context.Context,
+	req *intPkg.GetPayoutBatchRequest,
+	res *intPkg.PayoutBatchResponse,
+) error {
+	batch, err := s.payoutBatchRepository.GetById(ctx, req.Id)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = payoutBatchErrorNotFound
+		return nil
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = batch
+
+	return nil
+}
+
+// newPayoutBatchItem selects the transfer scheme of the payout document and validates the merchant banking data
+// for it. The banking data is taken from the payout document and from the merchant if the document has no destination.
+func (s *Service) newPayoutBatchItem(
+	ctx context.Context,
+	pd *billingpb.PayoutDocument,
+) (*payoutBatchItem, string, string) {
+	item := &payoutBatchItem{
+		payout:  pd,
+		banking: pd.Destination,
+		amount:  s.newMoney(pd.Balance, pd.Currency).Round(),
+	}
+
+	if pd.Company != nil {
+		item.name = pd.Company.Name
+		item.address = strings.TrimSpace(strings.Join([]string{pd.Company.Address, pd.Company.City, pd.Company.Zip, pd.Company.Country}, " "))
+	}
+
+	if item.banking == nil || item.name == "" {
+		merchant, err := s.merchantRepository.GetById(ctx, pd.MerchantId)
+
+		if err == nil {
+			if item.banking == nil {
+				item.banking = merchant.Banking
+			}
+
+			if item.name == "" && merchant.Company != nil {
+				item.name = merchant.Company.Name
+			}
+		}
+	}
+
+	if item.banking == nil || item.banking.AccountNumber == "" {
+		return nil, "", payoutBatchRejectBanking
+	}
+
+	account := normalizeBankAccount(item.banking.AccountNumber)
+
+	switch {
+	case pd.Currency == "EUR" && isSepaIban(account):
+		if item.banking.Swift != "" && !validateBic(item.banking.Swift) {
+			return nil, "", payoutBatchRejectBic
+		}
+
+		return item, intPkg.PayoutBatchFormatSepa, ""
+
+	case pd.Currency == "USD" && item.banking.CorrespondentAccount != "" && !isIbanLike(account):
+		// for the US bank accounts the ABA routing number is collected as the correspondent account at onboarding
+		if !validateAbaRoutingNumber(item.banking.CorrespondentAccount) {
+			return nil, "", payoutBatchRejectAchRouter
+		}
+
+		if !validateAchAccountNumber(account) {
+			return nil, "", payoutBatchRejectAccount
+		}
+
+		return item, intPkg.PayoutBatchFormatNacha, ""
+	}
+
+	if isIbanLike(account) && !validateIban(account) {
+		return nil, "", payoutBatchRejectIban
+	}
+
+	if !validateBankAccountNumber(account) {
+		return nil, "", payoutBatchRejectAccount
+	}
+
+	if !validateBic(item.banking.Swift) {
+		return nil, "", payoutBatchRejectBic
+	}
+
+	return item, intPkg.PayoutBatchFormatSwiftCsv, ""
+}
+
+func validatePayoutBatchDebtor(format string, debtor *intPkg.PayoutBatchDebtor) *billingpb.ResponseErrorMessage {
+	switch format {
+	case intPkg.PayoutBatchFormatSepa:
+		if debtor.Name == "" || !isSepaIban(debtor.Iban) || !validateBic(debtor.Bic) {
+			return payoutBatchErrorDebtorSepa
+		}
+	case intPkg.PayoutBatchFormatNacha:
+		if debtor.Name == "" || !validateAbaRoutingNumber(debtor.RoutingNumber) ||
+			debtor.CompanyId == "" || len(debtor.CompanyId) > 10 {
+			return payoutBatchErrorDebtorNacha
+		}
+	}
+
+	return nil
+}
+
+// createPayoutBatch claims the payout documents of the group by the new batch and generates the batch file
+// for the claimed documents only. The ids of the documents claimed by another batch or changed concurrently
+// are returned as unclaimed, the batch isn't created if no document is claimed.
+func (s *Service) createPayoutBatch(
+	ctx context.Context,
+	group *payoutBatchGroup,
+	debtor *intPkg.PayoutBatchDebtor,
+	executionDate time.Time,
+	userId string,
+) (*intPkg.PayoutBatch, []string, error) {
+	batchId := primitive.NewObjectID()
+	ids := make([]string, 0, len(group.items))
+
+	for _, item := range group.items {
+		ids = append(ids, item.payout.Id)
+	}
+
+	claimedIds, err := s.payoutRepository.ClaimForBatch(ctx, ids, pkg.PayoutDocumentStatusPending, batchId.Hex())
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	claimed := make(map[string]bool, len(claimedIds))
+
+	for _, id := range claimedIds {
+		claimed[id] = true
+	}
+
+	total := s.newMoney(0, group.currency)
+	items := make([]*payoutBatchItem, 0, len(claimedIds))
+	ids = make([]string, 0, len(claimedIds))
+	var unclaimed []string
+
+	for _, item := range group.items {
+		if !claimed[item.payout.Id] {
+			unclaimed = append(unclaimed, item.payout.Id)
+			continue
+		}
+
+		total = total.Add(item.amount)
+		items = append(items, item)
+		ids = append(ids, item.payout.Id)
+	}
+
+	if len(items) == 0 {
+		return nil, unclaimed, nil
+	}
+
+	now := time.Now().UTC()
+	batch := &intPkg.PayoutBatch{
+		Id:                batchId,
+		Format:            group.format,
+		Currency:          group.currency,
+		PayoutDocumentIds: ids,
+		ItemsCount:        int32(len(items)),
+		TotalAmount:       total.Round().Float64(),
+		Debtor:            debtor,
+		ExecutionDate:     executionDate,
+		CreatedBy:         userId,
+		CreatedAt:         now,
+	}
+	batch.FileName = fmt.Sprintf(
+		payoutBatchFileNameFormat,
+		group.format,
+		strings.ToLower(group.currency),
+		batch.Id.Hex(),
+		payoutBatchFileExtensions[group.format],
+	)
+
+	switch group.format {
+	case intPkg.PayoutBatchFormatSepa:
+		batch.Content, err = s.buildSepaPayoutFile(batch, items)
+	case intPkg.PayoutBatchFormatNacha:
+		batch.Content, err = buildNachaPayoutFile(batch, items)
+	default:
+		batch.Content, err = buildSwiftCsvPayoutFile(batch, items)
+	}
+
+	if err != nil {
+		zap.L().Error(
+			"Payout batch file generation failed",
+			zap.Error(err),
+			zap.String("format", group.format),
+			zap.String("batch_id", batch.Id.Hex()),
+		)
+	} else {
+		err = s.payoutBatchRepository.Insert(ctx, batch)
+	}
+
+	if err != nil {
+		// the documents are returned to the export if the batch isn't saved
+		if releaseErr := s.payoutRepository.ReleaseBatch(ctx, batch.Id.Hex()); releaseErr != nil {
+			zap.L().Error(
+				"Payout batch documents release failed",
+				zap.Error(releaseErr),
+				zap.String("batch_id", batch.Id.Hex()),
+			)
+		}
+
+		return nil, nil, err
+	}
+
+	return batch, unclaimed, nil
+}
+
+// getPayoutBatchUnclaimedItems returns the reasons of the rejection of the payout documents which weren't claimed
+// by the batch: the document is included into the concurrent batch or its status is changed.
+func (s *Service) getPayoutBatchUnclaimedItems(
+	ctx context.Context,
+	ids []string,
+) ([]*intPkg.PayoutBatchRejectedItem, error) {
+	batchIds, err := s.payoutRepository.GetBatchIds(ctx, ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rejected := make([]*intPkg.PayoutBatchRejectedItem, 0, len(ids))
+
+	for _, id := range ids {
+		if batchId, ok := batchIds[id]; ok {
+			rejected = append(rejected, newPayoutBatchRejectedItem(id, fmt.Sprintf(payoutBatchRejectExported, batchId)))
+			continue
+		}
+
+		rejected = append(rejected, newPayoutBatchRejectedItem(id, payoutBatchRejectStatus))
+	}
+
+	return rejected, nil
+}
+
+type sepaDocument struct {
+	XMLName  xml.Name         `xml:"Document"`
+	Xmlns    string           `xml:"xmlns,attr"`
+	Initiate sepaCreditTrfIni `xml:"CstmrCdtTrfInitn"`
+}
+
+type sepaCreditTrfIni struct {
+	GroupHeader sepaGroupHeader `xml:"GrpHdr"`
+	PaymentInfo sepaPaymentInfo `xml:"PmtInf"`
+}
+
+type sepaGroupHeader struct {
+	MsgId       string    `xml:"MsgId"`
+	CreatedAt   string    `xml:"CreDtTm"`
+	TxsCount    int32     `xml:"NbOfTxs"`
+	ControlSum  string    `xml:"CtrlSum"`
+	InitiatorNm sepaParty `xml:"InitgPty"`
+}
+
+type sepaParty struct {
+	Name string `xml:"Nm"`
+}
+
+type sepaAccount struct {
+	Iban string `xml:"Id>IBAN"`
+}
+
+type sepaAgent struct {
+	Bic string `xml:"FinInstnId>BIC,omitempty"`
+}
+
+type sepaAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type sepaPaymentInfo struct {
+	PaymentInfoId string           `xml:"PmtInfId"`
+	Method        string           `xml:"PmtMtd"`
+	BatchBooking  bool             `xml:"BtchBookg"`
+	TxsCount      int32            `xml:"NbOfTxs"`
+	ControlSum    string           `xml:"CtrlSum"`
+	ServiceLevel  string           `xml:"PmtTpInf>SvcLvl>Cd"`
+	ExecutionDate string           `xml:"ReqdExctnDt"`
+	Debtor        sepaParty        `xml:"Dbtr"`
+	DebtorAccount sepaAccount      `xml:"DbtrAcct"`
+	DebtorAgent   sepaAgent        `xml:"DbtrAgt"`
+	ChargeBearer  string           `xml:"ChrgBr"`
+	Transfers     []*sepaCreditTrf `xml:"CdtTrfTxInf"`
+}
+
+type sepaCreditTrf struct {
+	EndToEndId      string      `xml:"PmtId>EndToEndId"`
+	Amount          sepaAmount  `xml:"Amt>InstdAmt"`
+	CreditorAgent   *sepaAgent  `xml:"CdtrAgt,omitempty"`
+	Creditor        sepaParty   `xml:"Cdtr"`
+	CreditorAccount sepaAccount `xml:"CdtrAcct"`
+	Remittance      string      `xml:"RmtInf>Ustrd"`
+}
+
+// buildSepaPayoutFile returns the ISO 20022 pain.001.001.03 customer credit transfer initiation message.
+func (s *Service) buildSepaPayoutFile(batch *intPkg.PayoutBatch, items []*payoutBatchItem) ([]byte, error) {
+	controlSum := strconv.FormatFloat(batch.TotalAmount, 'f', 2, 64)
+	doc := &sepaDocument{
+		Xmlns: payoutBatchSepaNamespace,
+		Initiate: sepaCreditTrfIni{
+			GroupHeader: sepaGroupHeader{
+				MsgId:       batch.Id.Hex(),
+				CreatedAt:   batch.CreatedAt.Format(payoutBatchDateTimeFormat),
+				TxsCount:    batch.ItemsCount,
+				ControlSum:  controlSum,
+				InitiatorNm: sepaParty{Name: truncatePayoutBatchText(batch.Debtor.Name, 70)},
+			},
+			PaymentInfo: sepaPaymentInfo{
+				PaymentInfoId: batch.Id.Hex(),
+				Method:        "TRF",
+				BatchBooking:  true,
+				TxsCount:      batch.ItemsCount,
+				ControlSum:    controlSum,
+				ServiceLevel:  "SEPA",
+				ExecutionDate: batch.ExecutionDate.Format(payoutBatchDateFormat),
+				Debtor:        sepaParty{Name: truncatePayoutBatchText(batch.Debtor.Name, 70)},
+				DebtorAccount: sepaAccount{Iban: normalizeBankAccount(batch.Debtor.Iban)},
+				DebtorAgent:   sepaAgent{Bic: normalizeBankAccount(batch.Debtor.Bic)},
+				ChargeBearer:  "SLEV",
+			},
+		},
+	}
+
+	for _, item := range items {
+		transfer := &sepaCreditTrf{
+			EndToEndId:      item.payout.Id,
+			Amount:          sepaAmount{Currency: item.payout.Currency, Value: strconv.FormatFloat(item.amount.Float64(), 'f', 2, 64)},
+			Creditor:        sepaParty{Name: truncatePayoutBatchText(item.name, 70)},
+			CreditorAccount: sepaAccount{Iban: normalizeBankAccount(item.banking.AccountNumber)},
+			Remittance:      truncatePayoutBatchText(fmt.Sprintf(payoutBatchRemittanceFormat, item.payout.Id), 140),
+		}
+
+		if item.banking.Swift != "" {
+			transfer.CreditorAgent = &sepaAgent{Bic: normalizeBankAccount(item.banking.Swift)}
+		}
+
+		doc.Initiate.PaymentInfo.Transfers = append(doc.Initiate.PaymentInfo.Transfers, transfer)
+	}
+
+	content, err := xml.MarshalIndent(doc, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), content...), nil
+}
+
+// buildNachaPayoutFile returns the NACHA file with the single CCD batch of ACH credits to the merchant checking accounts.
+func buildNachaPayoutFile(batch *intPkg.PayoutBatch, items []*payoutBatchItem) ([]byte, error) {
+	debtor := batch.Debtor
+	routing := normalizeBankAccount(debtor.RoutingNumber)
+	odfi := routing[:8]
+	companyId := fmt.Sprintf("%-10s", debtor.CompanyId)
+	now := batch.CreatedAt
+
+	var lines []string
+	lines = append(lines, "1"+
+		"01"+
+		" "+routing+
+		" "+routing+
+		now.Format("060102")+
+		now.Format("1504")+
+		"A"+
+		"094"+
+		"10"+
+		"1"+
+		nachaAlpha(debtor.BankName, 23)+
+		nachaAlpha(debtor.Name, 23)+
+		nachaAlpha(batch.Id.Hex(), 8),
+	)
+	lines = append(lines, "5"+
+		"220"+
+		nachaAlpha(debtor.Name, 16)+
+		nachaAlpha("", 20)+
+		companyId+
+		"CCD"+
+		nachaAlpha(payoutBatchNachaCompanyEntry, 10)+
+		batch.ExecutionDate.Format("060102")+
+		batch.ExecutionDate.Format("060102")+
+		"   "+
+		"1"+
+		odfi+
+		nachaNumeric(1, 7),
+	)
+
+	hash := int64(0)
+	totalCents := int64(0)
+
+	for i, item := range items {
+		rdfi := normalizeBankAccount(item.banking.CorrespondentAccount)
+		cents := nachaCents(item.amount)
+		rdfiHash, err := strconv.ParseInt(rdfi[:8], 10, 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		hash += rdfiHash
+		totalCents += cents
+
+		lines = append(lines, "6"+
+			"22"+
+			rdfi+
+			nachaAlpha(normalizeBankAccount(item.banking.AccountNumber), 17)+
+			nachaNumeric(cents, 10)+
+			nachaAlpha(item.payout.Id, 15)+
+			nachaAlpha(item.name, 22)+
+			"  "+
+			"0"+
+			odfi+nachaNumeric(int64(i+1), 7),
+		)
+	}
+
+	hash = hash % 10000000000
+	count := int64(len(items))
+
+	lines = append(lines, "8"+
+		"220"+
+		nachaNumeric(count, 6)+
+		nachaNumeric(hash, 10)+
+		nachaNumeric(0, 12)+
+		nachaNumeric(totalCents, 12)+
+		companyId+
+		nachaAlpha("", 19)+
+		nachaAlpha("", 6)+
+		odfi+
+		nachaNumeric(1, 7),
+	)
+
+	recordsCount := int64(len(lines) + 1)
+	blocks := (recordsCount + payoutBatchNachaBlockingSize - 1) / payoutBatchNachaBlockingSize
+
+	lines = append(lines, "9"+
+		nachaNumeric(1, 6)+
+		nachaNumeric(blocks, 6)+
+		nachaNumeric(count, 8)+
+		nachaNumeric(hash, 10)+
+		nachaNumeric(0, 12)+
+		nachaNumeric(totalCents, 12)+
+		nachaAlpha("", 39),
+	)
+
+	for int64(len(lines)) < blocks*payoutBatchNachaBlockingSize {
+		lines = append(lines, strings.Repeat("9", payoutBatchNachaLineLength))
+	}
+
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// buildSwiftCsvPayoutFile returns the generic CSV of the SWIFT transfers to upload into the bank client.
+func buildSwiftCsvPayoutFile(batch *intPkg.PayoutBatch, items []*payoutBatchItem) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writer := csv.NewWriter(buf)
+
+	if err := writer.Write(payoutBatchSwiftCsvHeader); err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		row := []string{
+			item.payout.Id,
+			item.payout.MerchantId,
+			item.name,
+			item.address,
+			normalizeBankAccount(item.banking.AccountNumber),
+			item.banking.Name,
+			item.banking.Address,
+			normalizeBankAccount(item.banking.Swift),
+			strconv.FormatFloat(item.amount.Float64(), 'f', -1, 64),
+			item.payout.Currency,
+			batch.ExecutionDate.Format(payoutBatchDateFormat),
+			fmt.Sprintf(payoutBatchRemittanceFormat, item.payout.Id),
+			item.banking.Details,
+		}
+
+		if err := writer.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func newPayoutBatchRejectedItem(id, reason string) *intPkg.PayoutBatchRejectedItem {
+	return &intPkg.PayoutBatchRejectedItem{PayoutDocumentId: id, Reason: reason}
+}
+
+func truncatePayoutBatchText(value string, length int) string {
+	runes := []rune(strings.TrimSpace(value))
+
+	if len(runes) > length {
+		runes = runes[:length]
+	}
+
+	return string(runes)
+}
+
+// nachaAlpha returns the left justified upper case alphanumeric field of the NACHA record.
+func nachaAlpha(value string, length int) string {
+	value = strings.ToUpper(truncatePayoutBatchText(value, length))
+	clean := make([]rune, 0, len(value))
+
+	for _, r := range value {
+		if r < 0x20 || r > 0x7e {
+			r = ' '
+		}
+
+		clean = append(clean, r)
+	}
+
+	return fmt.Sprintf("%-*s", length, string(clean))
+}
+
+// nachaNumeric returns the right justified zero filled numeric field of the NACHA record.
+func nachaNumeric(value int64, length int) string {
+	return fmt.Sprintf("%0*d", length, value)
+}
+
+func nachaCents(amount intPkg.Money) int64 {
+	return int64(math.Round(amount.Round().Float64() * 100))
+}
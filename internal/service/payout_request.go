@@ -0,0 +1,457 @@
+package service
+
+import (
+	"context"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/jinzhu/now"
+	"github.com/paysuper/paysuper-billing-server/internal/helper"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	payoutRequestErrorMerchantNotFound  = newBillingServerErrorMsg("pq000001", "merchant not found")
+	payoutRequestErrorManualPayoutsOnly = newBillingServerErrorMsg("pq000002", "payout can be requested only by the merchant with manual payouts")
+	payoutRequestErrorCurrencyInvalid   = newBillingServerErrorMsg("pq000003", "currency is not the payout currency of the merchant")
+	payoutRequestErrorLimitExceeded     = newBillingServerErrorMsg("pq000004", "limit of payout requests for the period is exceeded")
+	payoutRequestErrorAmountInvalid     = newBillingServerErrorMsg("pq000005", "payout amount must be positive")
+	payoutRequestErrorNotEnoughBalance  = newBillingServerErrorMsg("pq000006", "payout amount exceeds the available balance after rolling reserve")
+	payoutRequestErrorBelowMinAmount    = newBillingServerErrorMsg("pq000007", "payout amount is below the minimum payout amount")
+	payoutRequestErrorLimitInvalid      = newBillingServerErrorMsg("pq000008", "maximum number of requests and period in days must be positive")
+	payoutRequestErrorLimitNotFound     = newBillingServerErrorMsg("pq000009", "payout request limit not found")
+	payoutRequestErrorAccessDenied      = newBillingServerErrorMsg("pq000010", "only admin or financier can change the payout request limit")
+	payoutRequestErrorInProgress        = newBillingServerErrorMsg("pq000011", "previous payout request of the merchant is in progress, try again later")
+)
+
+const (
+	// payoutRequestReservationTimeout is the time after which the reservation of the balance by the failed request
+	// is released for the following requests
+	payoutRequestReservationTimeout = time.Minute
+)
+
+// RequestPayout creates the pending payout document by the request of the merchant with manual payouts.
+//
+// The merchant can request the whole available balance or its part. The accepted royalty reports without payout
+// paid off by the requested amount are linked to the document, the rest of the balance remains available
+// for the following requests. The balance of the merchant is reserved by the request, so the concurrent requests
+// can't exceed the limit of requests or spend the same balance.
+func (s *Service) RequestPayout(
+	ctx context.Context,
+	req *intPkg.RequestPayoutRequest,
+	res *intPkg.RequestPayoutResponse,
+) error {
+	merchant, err := s.merchantRepository.GetById(ctx, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = payoutRequestErrorMerchantNotFound
+		return nil
+	}
+
+	if !merchant.ManualPayoutsEnabled {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = payoutRequestErrorManualPayoutsOnly
+		return nil
+	}
+
+	currency := strings.ToUpper(req.Currency)
+
+	if currency == "" {
+		currency = merchant.GetPayoutCurrency()
+	}
+
+	currencies, err := s.getMerchantPayoutCurrencies(ctx, merchant)
+
+	if err != nil {
+		return err
+	}
+
+	if currency == "" || !helper.Contains(currencies, currency) {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = payoutRequestErrorCurrencyInvalid
+		return nil
+	}
+
+	merchantOid, _ := primitive.ObjectIDFromHex(merchant.Id)
+	reservation := &intPkg.PayoutRequestReservation{
+		MerchantId:    merchantOid,
+		ReservationId: primitive.NewObjectID().Hex(),
+		Currency:      currency,
+		Amount:        req.Amount,
+		ReservedAt:    time.Now(),
+	}
+	err = s.payoutRequestRepository.Reserve(ctx, reservation, reservation.ReservedAt.Add(-payoutRequestReservationTimeout))
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = payoutRequestErrorInProgress
+		return nil
+	}
+
+	defer func() {
+		// the failed release is expired by the timeout
+		_ = s.payoutRequestRepository.Release(ctx, reservation)
+	}()
+
+	exceeded, err := s.isPayoutRequestLimitExceeded(ctx, merchant.Id)
+
+	if err != nil {
+		return err
+	}
+
+	if exceeded {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = payoutRequestErrorLimitExceeded
+		return nil
+	}
+
+	suspended, err := s.isMerchantPayoutsSuspended(ctx, merchant.Id, currency)
+
+	if err != nil {
+		return err
+	}
+
+	if suspended {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = errorPayoutSuspendedByDebt
+		return nil
+	}
+
+	reports, err := s.royaltyReportRepository.GetNonPayoutReports(ctx, merchant.Id, currency)
+
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	var sources []*billingpb.RoyaltyReport
+
+	for _, report := range reports {
+		if report.Status == billingpb.RoyaltyReportStatusDispute {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = errorPayoutSourcesDispute
+			return nil
+		}
+
+		// the pending reports aren't included into the balance yet
+		if report.Status == billingpb.RoyaltyReportStatusAccepted {
+			sources = append(sources, report)
+		}
+	}
+
+	balance, err := s.updateMerchantCurrencyBalance(ctx, merchant, currency)
+
+	if err != nil {
+		return err
+	}
+
+	available := s.newMoney(balance.Total, currency).Round()
+	amount := available
+
+	if req.Amount != 0 {
+		amount = s.newMoney(req.Amount, currency).Round()
+	}
+
+	if amount.Sign() <= 0 {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = payoutRequestErrorAmountInvalid
+		return nil
+	}
+
+	if amount.Cmp(available) > 0 {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = payoutRequestErrorNotEnoughBalance
+		return nil
+	}
+
+	minAmount, err := s.getPayoutRequestMinAmount(ctx, merchant, currency)
+
+	if err != nil {
+		return err
+	}
+
+	if amount.Cmp(minAmount) < 0 {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = payoutRequestErrorBelowMinAmount
+		return nil
+	}
+
+	destination, err := s.getMerchantPayoutBanking(ctx, merchant, currency)
+
+	if err != nil {
+		return err
+	}
+
+	arrivalDate, err := ptypes.TimestampProto(now.EndOfDay().Add(time.Hour * 24 * payoutArrivalInDays))
+
+	if err != nil {
+		return err
+	}
+
+	pd := &billingpb.PayoutDocument{
+		Id:                      primitive.NewObjectID().Hex(),
+		Status:                  pkg.PayoutDocumentStatusPending,
+		SourceId:                []string{},
+		Description:             req.Description,
+		Currency:                currency,
+		CreatedAt:               ptypes.TimestampNow(),
+		UpdatedAt:               ptypes.TimestampNow(),
+		ArrivalDate:             arrivalDate,
+		MerchantId:              merchant.Id,
+		Destination:             destination,
+		Company:                 merchant.Company,
+		MerchantAgreementNumber: merchant.AgreementNumber,
+		OperatingCompanyId:      merchant.OperatingCompanyId,
+	}
+
+	sources = s.getPayoutRequestSources(sources, available, amount)
+	_, times, err := s.setPayoutDocumentSources(pd, sources)
+
+	if err != nil {
+		return err
+	}
+
+	if len(times) == 0 {
+		// the request of the balance carried over from the previous partial payouts
+		times = []time.Time{time.Now()}
+	}
+
+	// the document pays the requested amount only, the reports linked to the document can be paid partially
+	// by the previous requests
+	pd.TotalFees = amount.Float64()
+	pd.Balance = amount.Float64()
+
+	createReq := &billingpb.CreatePayoutDocumentRequest{
+		MerchantId:  merchant.Id,
+		Description: req.Description,
+		Ip:          req.Ip,
+		Initiator:   pkg.RoyaltyReportChangeSourceMerchant,
+	}
+	createRes := &billingpb.CreatePayoutDocumentResponse{}
+	createdBy := req.UserId
+
+	if createdBy == "" {
+		createdBy = getPayoutDocumentInitiator(merchant, createReq)
+	}
+
+	if err = s.savePayoutDocument(ctx, merchant, pd, times, createdBy, createReq, createRes); err != nil {
+		return err
+	}
+
+	if createRes.Status != billingpb.ResponseStatusOk {
+		res.Status = createRes.Status
+		res.Message = createRes.Message
+		return nil
+	}
+
+	request := &intPkg.PayoutRequest{
+		Id:               primitive.NewObjectID(),
+		MerchantId:       merchantOid,
+		PayoutDocumentId: pd.Id,
+		Currency:         currency,
+		Amount:           pd.Balance,
+		UserId:           req.UserId,
+		Ip:               req.Ip,
+		CreatedAt:        time.Now(),
+	}
+
+	if err = s.payoutRequestRepository.Insert(ctx, request); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = pd
+
+	return nil
+}
+
+// getPayoutRequestSources returns the accepted royalty reports paid off by the requested amount. The reports are paid
+// in the order of their periods, the report paid partially remains without payout until the following request
+// pays the rest of it. The part of the reports paid by the previous partial requests is the difference between
+// the reports amount and the available balance.
+func (s *Service) getPayoutRequestSources(
+	reports []*billingpb.RoyaltyReport,
+	available, amount intPkg.Money,
+) []*billingpb.RoyaltyReport {
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].PeriodFrom.Seconds < reports[j].PeriodFrom.Seconds
+	})
+
+	reportsAmount := s.newMoney(0, amount.Currency())
+
+	for _, r := range reports {
+		reportsAmount = reportsAmount.Add(s.getPayoutRequestSourceAmount(r, amount.Currency()))
+	}
+
+	paid := reportsAmount.Sub(available)
+
+	if paid.Sign() < 0 {
+		paid = s.newMoney(0, amount.Currency())
+	}
+
+	covered := paid.Add(amount).Round()
+	sum := s.newMoney(0, amount.Currency())
+
+	var sources []*billingpb.RoyaltyReport
+
+	for _, r := range reports {
+		sum = sum.Add(s.getPayoutRequestSourceAmount(r, amount.Currency()))
+
+		if sum.Round().Cmp(covered) > 0 {
+			break
+		}
+
+		sources = append(sources, r)
+	}
+
+	return sources
+}
+
+func (s *Service) getPayoutRequestSourceAmount(report *billingpb.RoyaltyReport, currency string) intPkg.Money {
+	return s.newMoney(report.Totals.PayoutAmount, currency).
+		Sub(s.newMoney(report.Totals.CorrectionAmount, currency), s.newMoney(report.Totals.RollingReserveAmount, currency))
+}
+
+// SetPayoutRequestLimit creates or replaces the limit of the on-demand payout requests of the merchant.
+func (s *Service) SetPayoutRequestLimit(
+	ctx context.Context,
+	req *intPkg.SetPayoutRequestLimitRequest,
+	res *intPkg.PayoutRequestLimitResponse,
+) error {
+	if !s.hasAdminRole(ctx, req.UserId, billingpb.RoleSystemAdmin, billingpb.RoleSystemFinancial) {
+		res.Status = billingpb.ResponseStatusForbidden
+		res.Message = payoutRequestErrorAccessDenied
+		return nil
+	}
+
+	merchant, err := s.merchantRepository.GetById(ctx, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = payoutRequestErrorMerchantNotFound
+		return nil
+	}
+
+	if req.MaxRequests <= 0 || req.PeriodDays <= 0 {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = payoutRequestErrorLimitInvalid
+		return nil
+	}
+
+	updatedAt := time.Now()
+	limit, err := s.payoutRequestLimitRepository.GetByMerchantId(ctx, merchant.Id)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		oid, _ := primitive.ObjectIDFromHex(merchant.Id)
+		limit = &intPkg.PayoutRequestLimit{
+			Id:         primitive.NewObjectID(),
+			MerchantId: oid,
+			CreatedAt:  updatedAt,
+		}
+	}
+
+	limit.MaxRequests = req.MaxRequests
+	limit.PeriodDays = req.PeriodDays
+	limit.UpdatedBy = req.UserId
+	limit.UpdatedAt = updatedAt
+
+	if err = s.payoutRequestLimitRepository.Upsert(ctx, limit); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = limit
+
+	return nil
+}
+
+// GetPayoutRequestLimit returns the limit of the on-demand payout requests of the merchant.
+func (s *Service) GetPayoutRequestLimit(
+	ctx context.Context,
+	req *intPkg.GetPayoutRequestLimitRequest,
+	res *intPkg.PayoutRequestLimitResponse,
+) error {
+	limit, err := s.payoutRequestLimitRepository.GetByMerchantId(ctx, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = payoutRequestErrorLimitNotFound
+		return nil
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = limit
+
+	return nil
+}
+
+// isPayoutRequestLimitExceeded checks the number of the payout requests of the merchant in the rolling period,
+// the default limit from the config is used for the merchants without own limit.
+func (s *Service) isPayoutRequestLimitExceeded(ctx context.Context, merchantId string) (bool, error) {
+	maxRequests, periodDays := s.cfg.PayoutRequestsMax, s.cfg.PayoutRequestsPeriodDays
+	limit, err := s.payoutRequestLimitRepository.GetByMerchantId(ctx, merchantId)
+
+	if err != nil && err != mongo.ErrNoDocuments {
+		return false, err
+	}
+
+	if limit != nil {
+		maxRequests, periodDays = limit.MaxRequests, limit.PeriodDays
+	}
+
+	if maxRequests <= 0 {
+		return false, nil
+	}
+
+	since := time.Now().AddDate(0, 0, -int(periodDays))
+	count, err := s.payoutRequestRepository.CountByMerchantId(ctx, merchantId, since)
+
+	if err != nil {
+		return false, err
+	}
+
+	return count >= int64(maxRequests), nil
+}
+
+// getPayoutRequestMinAmount returns the minimum amount of the payout request in the currency: the minimum payout
+// amount of the merchant for the main payout currency or the minimum amount of the payout schedule if it's greater.
+func (s *Service) getPayoutRequestMinAmount(
+	ctx context.Context,
+	merchant *billingpb.Merchant,
+	currency string,
+) (intPkg.Money, error) {
+	minAmount := s.newMoney(0, currency)
+
+	if currency == merchant.GetPayoutCurrency() {
+		minAmount = s.newMoney(merchant.MinPayoutAmount, currency)
+	}
+
+	schedule, err := s.getPayoutSchedule(ctx, merchant.Id)
+
+	if err != nil {
+		return minAmount, err
+	}
+
+	if schedule != nil {
+		scheduleMinAmount := s.newMoney(schedule.GetMinAmount(currency), currency)
+
+		if scheduleMinAmount.Cmp(minAmount) > 0 {
+			minAmount = scheduleMinAmount
+		}
+	}
+
+	return minAmount, nil
+}
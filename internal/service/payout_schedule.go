@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"strings"
+	"time"
+)
+
+var (
+	payoutScheduleErrorMerchantNotFound = newBillingServerErrorMsg("ps000001", "merchant not found")
+	payoutScheduleErrorPeriodInvalid    = newBillingServerErrorMsg("ps000002", "payout schedule period or payout day is invalid")
+	payoutScheduleErrorMinAmountInvalid = newBillingServerErrorMsg("ps000003", "minimum payout amount must be set for the currency code and can't be negative")
+	payoutScheduleErrorNotFound         = newBillingServerErrorMsg("ps000004", "payout schedule not found")
+	payoutScheduleErrorAccessDenied     = newBillingServerErrorMsg("ps000005", "only admin or financier can change the payout schedule")
+
+	payoutSchedulePeriods = map[string]bool{
+		intPkg.PayoutSchedulePeriodWeekly:   true,
+		intPkg.PayoutSchedulePeriodBiWeekly: true,
+		intPkg.PayoutSchedulePeriodMonthly:  true,
+	}
+)
+
+// SetPayoutSchedule creates or replaces the automatic payout schedule of the merchant.
+// The weekly and bi-weekly schedules start from the nearest payout day of week.
+func (s *Service) SetPayoutSchedule(
+	ctx context.Context,
+	req *intPkg.SetPayoutScheduleRequest,
+	res *intPkg.PayoutScheduleResponse,
+) error {
+	if !s.hasAdminRole(ctx, req.UserId, billingpb.RoleSystemAdmin, billingpb.RoleSystemFinancial) {
+		res.Status = billingpb.ResponseStatusForbidden
+		res.Message = payoutScheduleErrorAccessDenied
+		return nil
+	}
+
+	merchant, err := s.merchantRepository.GetById(ctx, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = payoutScheduleErrorMerchantNotFound
+		return nil
+	}
+
+	if !payoutSchedulePeriods[req.Period] ||
+		(req.Period == intPkg.PayoutSchedulePeriodMonthly &&
+			(req.DayOfMonth < 1 || req.DayOfMonth > intPkg.PayoutScheduleMaxDayOfMonth)) ||
+		(req.Period != intPkg.PayoutSchedulePeriodMonthly && (req.DayOfWeek < 0 || req.DayOfWeek > 6)) {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = payoutScheduleErrorPeriodInvalid
+		return nil
+	}
+
+	minAmounts := make(map[string]float64, len(req.MinAmounts))
+
+	for currency, amount := range req.MinAmounts {
+		if len(currency) != 3 || amount < 0 {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = payoutScheduleErrorMinAmountInvalid
+			return nil
+		}
+
+		currency = strings.ToUpper(currency)
+		minAmounts[currency] = s.FormatAmount(amount, currency)
+	}
+
+	now := time.Now()
+	schedule, err := s.payoutScheduleRepository.GetByMerchantId(ctx, merchant.Id)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		oid, _ := primitive.ObjectIDFromHex(merchant.Id)
+		schedule = &intPkg.PayoutSchedule{
+			Id:         primitive.NewObjectID(),
+			MerchantId: oid,
+			CreatedAt:  now,
+		}
+	}
+
+	schedule.Period = req.Period
+	schedule.DayOfWeek = 0
+	schedule.DayOfMonth = 0
+	schedule.AnchorDate = time.Time{}
+	schedule.MinAmounts = minAmounts
+	schedule.UpdatedBy = req.UserId
+	schedule.UpdatedAt = now
+
+	if req.Period == intPkg.PayoutSchedulePeriodMonthly {
+		schedule.DayOfMonth = req.DayOfMonth
+	} else {
+		schedule.DayOfWeek = req.DayOfWeek
+		schedule.AnchorDate = intPkg.GetPayoutScheduleAnchorDate(now, req.DayOfWeek)
+	}
+
+	if err = s.payoutScheduleRepository.Upsert(ctx, schedule); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = schedule
+
+	return nil
+}
+
+// GetPayoutSchedule returns the automatic payout schedule of the merchant.
+func (s *Service) GetPayoutSchedule(
+	ctx context.Context,
+	req *intPkg.GetPayoutScheduleRequest,
+	res *intPkg.PayoutScheduleResponse,
+) error {
+	schedule, err := s.payoutScheduleRepository.GetByMerchantId(ctx, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = payoutScheduleErrorNotFound
+		return nil
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = schedule
+
+	return nil
+}
+
+// getPayoutSchedule returns the payout schedule of the merchant or nil if the merchant has no schedule.
+func (s *Service) getPayoutSchedule(ctx context.Context, merchantId string) (*intPkg.PayoutSchedule, error) {
+	schedule, err := s.payoutScheduleRepository.GetByMerchantId(ctx, merchantId)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	return schedule, err
+}
+
+// getMerchantNextPayout returns the expected automatic payout of the merchant with the balance,
+// nil is returned for the merchants with the manual payouts or without the payout schedule.
+func (s *Service) getMerchantNextPayout(
+	ctx context.Context,
+	merchant *billingpb.Merchant,
+	balance *billingpb.MerchantBalance,
+) (*intPkg.MerchantNextPayout, error) {
+	if merchant.ManualPayoutsEnabled {
+		return nil, nil
+	}
+
+	schedule, err := s.getPayoutSchedule(ctx, merchant.Id)
+
+	if err != nil || schedule == nil {
+		return nil, err
+	}
+
+	date := schedule.NextPayoutDate()
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	if date.Before(today) {
+		// the due payout is made by the next run of the task
+		date = today
+	}
+
+	amount := s.newMoney(balance.Total, balance.Currency).Round()
+	minAmount := s.newMoney(schedule.GetMinAmount(balance.Currency), balance.Currency)
+	next := &intPkg.MerchantNextPayout{
+		Date:      date,
+		Amount:    amount.Float64(),
+		MinAmount: minAmount.Float64(),
+	}
+
+	if amount.Sign() <= 0 || amount.Cmp(minAmount) < 0 {
+		next.Held = true
+		next.Amount = 0
+	}
+
+	return next, nil
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/jinzhu/now"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	"github.com/paysuper/paysuper-proto/go/postmarkpb"
@@ -41,6 +42,9 @@ var (
 	errorPayoutManualPayoutsDisabled   = newBillingServerErrorMsg("po000015", "manual payouts disabled")
 	errorPayoutAutoPayoutsDisabled     = newBillingServerErrorMsg("po000016", "auto payouts disabled")
 	errorPayoutAutoPayoutsWithErrors   = newBillingServerErrorMsg("po000017", "auto payouts creation finished with errors")
+	errorPayoutBelowMinAmount          = newBillingServerErrorMsg("po000018", "payout amount is below the minimum amount of the payout schedule")
+	errorPayoutApprovalRequired        = newBillingServerErrorMsg("po000019", "status of the payout document can be changed only by the approval workflow")
+	errorPayoutSuspendedByDebt         = newBillingServerErrorMsg("po000020", "payouts are suspended until the merchant debt is recovered")
 
 	statusForUpdateBalance = map[string]bool{
 		pkg.PayoutDocumentStatusPending: true,
@@ -81,12 +85,74 @@ func (s *Service) CreatePayoutDocument(
 	return s.createPayoutDocument(ctx, merchant, req, res)
 }
 
+// createPayoutDocument creates the payout documents of the merchant in every payout currency with the sources.
+// The response is successful when at least one document is created, otherwise it contains the error
+// of the first failed currency. The response contains the documents created by this call only.
 func (s *Service) createPayoutDocument(
 	ctx context.Context,
 	merchant *billingpb.Merchant,
 	req *billingpb.CreatePayoutDocumentRequest,
 	res *billingpb.CreatePayoutDocumentResponse,
 ) error {
+	currencies, err := s.getMerchantPayoutCurrencies(ctx, merchant)
+
+	if err != nil {
+		return err
+	}
+
+	var failed *billingpb.CreatePayoutDocumentResponse
+	res.Items = nil
+
+	for _, currency := range currencies {
+		rsp := &billingpb.CreatePayoutDocumentResponse{}
+		err = s.createCurrencyPayoutDocument(ctx, merchant, currency, req, rsp)
+
+		if err != nil {
+			return err
+		}
+
+		if rsp.Status == billingpb.ResponseStatusOk {
+			res.Items = append(res.Items, rsp.Items...)
+			continue
+		}
+
+		if failed == nil || failed.Message == errorPayoutSourcesNotFound {
+			failed = rsp
+		}
+
+		if rsp.Message != errorPayoutSourcesNotFound {
+			zap.L().Info(
+				"payout document for currency is not created",
+				zap.String("merchant_id", merchant.Id),
+				zap.String("currency", currency),
+				zap.Any("message", rsp.Message),
+			)
+		}
+	}
+
+	if len(res.Items) == 0 && failed != nil {
+		res.Status = failed.Status
+		res.Message = failed.Message
+		return nil
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+
+	return nil
+}
+
+func (s *Service) createCurrencyPayoutDocument(
+	ctx context.Context,
+	merchant *billingpb.Merchant,
+	currency string,
+	req *billingpb.CreatePayoutDocumentRequest,
+	res *billingpb.CreatePayoutDocumentResponse,
+) error {
+	destination, err := s.getMerchantPayoutBanking(ctx, merchant, currency)
+
+	if err != nil {
+		return err
+	}
 
 	arrivalDate, err := ptypes.TimestampProto(now.EndOfDay().Add(time.Hour * 24 * payoutArrivalInDays))
 	if err != nil {
@@ -102,13 +168,13 @@ func (s *Service) createPayoutDocument(
 		UpdatedAt:               ptypes.TimestampNow(),
 		ArrivalDate:             arrivalDate,
 		MerchantId:              merchant.Id,
-		Destination:             merchant.Banking,
+		Destination:             destination,
 		Company:                 merchant.Company,
 		MerchantAgreementNumber: merchant.AgreementNumber,
 		OperatingCompanyId:      merchant.OperatingCompanyId,
 	}
 
-	reports, err := s.getPayoutDocumentSources(ctx, merchant)
+	reports, err := s.getPayoutDocumentSources(ctx, merchant, currency)
 
 	if err != nil {
 		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
@@ -121,11 +187,103 @@ func (s *Service) createPayoutDocument(
 
 	pd.Currency = reports[0].Currency
 
+	pdBalance, times, err := s.setPayoutDocumentSources(pd, reports)
+
+	if err != nil {
+		return err
+	}
+
+	pd.Balance = pdBalance.Float64()
+
+	if pd.Balance <= 0 {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = errorPayoutAmountInvalid
+		return nil
+	}
+
+	suspended, err := s.isMerchantPayoutsSuspended(ctx, merchant.Id, pd.Currency)
+
+	if err != nil {
+		return err
+	}
+
+	if suspended {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = errorPayoutSuspendedByDebt
+		return nil
+	}
+
+	if req.IsAutoGeneration {
+		schedule, err := s.getPayoutSchedule(ctx, merchant.Id)
+
+		if err != nil {
+			return err
+		}
+
+		if schedule != nil && pdBalance.Cmp(s.newMoney(schedule.GetMinAmount(pd.Currency), pd.Currency)) < 0 {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = errorPayoutBelowMinAmount
+			return nil
+		}
+	}
+
+	balance, err := s.merchantBalanceRepository.GetByIdAndCurrency(ctx, merchant.Id, pd.Currency)
+	if err != nil {
+		res.Status = billingpb.ResponseStatusSystemError
+		res.Message = errorPayoutBalanceError
+		return nil
+	}
+
+	available := s.newMoney(balance.Debit, pd.Currency).Sub(s.newMoney(balance.Credit, pd.Currency)).Round()
+
+	if pdBalance.Cmp(available) > 0 {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = errorPayoutNotEnoughBalance
+		return nil
+	}
+
+	if pd.Balance < merchant.MinPayoutAmount {
+		pd.Status = pkg.PayoutDocumentStatusSkip
+	}
+
+	return s.savePayoutDocument(ctx, merchant, pd, times, getPayoutDocumentInitiator(merchant, req), req, res)
+}
+
+// getPayoutDocumentInitiator returns the user who initiated the payout document. The initiator of the request is
+// the id of the user, the automatic payouts are initiated by the system and the payouts requested by the merchant
+// without the user id by the merchant owner.
+func getPayoutDocumentInitiator(merchant *billingpb.Merchant, req *billingpb.CreatePayoutDocumentRequest) string {
+	if req.IsAutoGeneration {
+		return pkg.RoyaltyReportChangeSourceAuto
+	}
+
+	switch req.Initiator {
+	case "", pkg.RoyaltyReportChangeSourceMerchant, pkg.RoyaltyReportChangeSourceAuto:
+		if merchant.User != nil && merchant.User.Id != "" {
+			return merchant.User.Id
+		}
+
+		return payoutChangeSourceMerchant
+	}
+
+	return req.Initiator
+}
+
+// setPayoutDocumentSources links the royalty reports to the payout document and returns the payout amount
+// of the reports with the bounds of their periods.
+func (s *Service) setPayoutDocumentSources(
+	pd *billingpb.PayoutDocument,
+	reports []*billingpb.RoyaltyReport,
+) (intPkg.Money, []time.Time, error) {
 	var times []time.Time
 
+	totalFees := s.newMoney(0, pd.Currency)
+	pdBalance := s.newMoney(0, pd.Currency)
+
 	for _, r := range reports {
-		pd.TotalFees += r.Totals.PayoutAmount - r.Totals.CorrectionAmount
-		pd.Balance += r.Totals.PayoutAmount - r.Totals.CorrectionAmount - r.Totals.RollingReserveAmount
+		payoutAmount := s.newMoney(r.Totals.PayoutAmount, pd.Currency).Sub(s.newMoney(r.Totals.CorrectionAmount, pd.Currency))
+		totalFees = totalFees.Add(payoutAmount)
+		pdBalance = pdBalance.Add(payoutAmount.Sub(s.newMoney(r.Totals.RollingReserveAmount, pd.Currency)))
 		pd.TotalTransactions += r.Totals.TransactionsCount
 		pd.SourceId = append(pd.SourceId, r.Id)
 
@@ -136,7 +294,7 @@ func (s *Service) createPayoutDocument(
 				"Time conversion error",
 				zap.Error(err),
 			)
-			return err
+			return pdBalance, nil, err
 		}
 
 		to, err := ptypes.Timestamp(r.PeriodTo)
@@ -145,34 +303,27 @@ func (s *Service) createPayoutDocument(
 				"Payout source time conversion error",
 				zap.Error(err),
 			)
-			return err
+			return pdBalance, nil, err
 		}
 		times = append(times, from, to)
 	}
 
-	if pd.Balance <= 0 {
-		res.Status = billingpb.ResponseStatusBadData
-		res.Message = errorPayoutAmountInvalid
-		return nil
-	}
-
-	balance, err := s.getMerchantBalance(ctx, merchant.Id)
-	if err != nil {
-		res.Status = billingpb.ResponseStatusSystemError
-		res.Message = errorPayoutBalanceError
-		return nil
-	}
-
-	if pd.Balance > (balance.Debit - balance.Credit) {
-		res.Status = billingpb.ResponseStatusBadData
-		res.Message = errorPayoutNotEnoughBalance
-		return nil
-	}
+	pd.TotalFees = totalFees.Round().Float64()
 
-	if pd.Balance < merchant.MinPayoutAmount {
-		pd.Status = pkg.PayoutDocumentStatusSkip
-	}
+	return pdBalance.Round(), times, nil
+}
 
+// savePayoutDocument stores the new payout document with the period of the source royalty reports,
+// links the reports to the document and starts the approval of the pending document created by the user.
+func (s *Service) savePayoutDocument(
+	ctx context.Context,
+	merchant *billingpb.Merchant,
+	pd *billingpb.PayoutDocument,
+	times []time.Time,
+	createdBy string,
+	req *billingpb.CreatePayoutDocumentRequest,
+	res *billingpb.CreatePayoutDocumentResponse,
+) (err error) {
 	sort.Slice(times, func(i, j int) bool {
 		return times[i].Before(times[j])
 	})
@@ -208,6 +359,10 @@ func (s *Service) createPayoutDocument(
 		return err
 	}
 
+	if err = s.applyPayoutDocumentToBalanceLedger(ctx, pd, ""); err != nil {
+		return err
+	}
+
 	err = s.royaltyReportSetPayoutDocumentId(ctx, pd.SourceId, pd.Id, req.Ip, req.Initiator)
 
 	if err != nil {
@@ -219,6 +374,12 @@ func (s *Service) createPayoutDocument(
 		return err
 	}
 
+	if pd.Status == pkg.PayoutDocumentStatusPending {
+		if err = s.createPayoutApproval(ctx, pd, createdBy); err != nil {
+			return err
+		}
+	}
+
 	err = s.renderPayoutDocument(ctx, pd, merchant)
 	if err != nil {
 		return err
@@ -314,16 +475,34 @@ func (s *Service) AutoCreatePayoutDocuments(
 		Initiator:        pkg.RoyaltyReportChangeSourceAuto,
 		IsAutoGeneration: true,
 	}
-	res := &billingpb.CreatePayoutDocumentResponse{}
 
 	wasErrors := false
+	runAt := time.Now()
 
 	for _, m := range merchants {
+		schedule, err := s.getPayoutSchedule(ctx, m.Id)
+
+		if err != nil {
+			zap.L().Error(
+				"auto createPayoutDocument failed on getting payout schedule",
+				zap.Error(err),
+				zap.String("merchantId", m.Id),
+			)
+			wasErrors = true
+			continue
+		}
+
+		if schedule != nil && !schedule.IsDue(runAt) {
+			continue
+		}
+
 		req1.MerchantId = m.Id
+		res := &billingpb.CreatePayoutDocumentResponse{}
 		err = s.createPayoutDocument(ctx, m, req1, res)
 
 		if err != nil {
 			if err == errorPayoutSourcesNotFound {
+				wasErrors = s.completeScheduledPayout(ctx, schedule, runAt) != nil || wasErrors
 				continue
 			}
 			zap.L().Error(
@@ -335,7 +514,14 @@ func (s *Service) AutoCreatePayoutDocuments(
 			continue
 		}
 		if res.Status != billingpb.ResponseStatusOk {
-			if res.Message == errorPayoutAmountInvalid || res.Message == errorPayoutSourcesNotFound {
+			// the balance below the minimum amount is held until the next scheduled payout date
+			if res.Message == errorPayoutAmountInvalid || res.Message == errorPayoutSourcesNotFound ||
+				res.Message == errorPayoutBelowMinAmount {
+				wasErrors = s.completeScheduledPayout(ctx, schedule, runAt) != nil || wasErrors
+				continue
+			}
+			// the payout is retried on the next run after the merchant debt is recovered
+			if res.Message == errorPayoutSuspendedByDebt {
 				continue
 			}
 			zap.L().Error(
@@ -347,6 +533,18 @@ func (s *Service) AutoCreatePayoutDocuments(
 			wasErrors = true
 			continue
 		}
+
+		// the schedule is moved to the next payout date only when the documents of the merchant are created
+		if len(res.Items) == 0 {
+			zap.L().Error(
+				"auto createPayoutDocument returned no payout documents",
+				zap.String("merchantId", m.Id),
+			)
+			wasErrors = true
+			continue
+		}
+
+		wasErrors = s.completeScheduledPayout(ctx, schedule, runAt) != nil || wasErrors
 	}
 
 	if wasErrors {
@@ -360,6 +558,17 @@ func (s *Service) AutoCreatePayoutDocuments(
 	return nil
 }
 
+// completeScheduledPayout moves the payout schedule of the merchant to the next payout date.
+func (s *Service) completeScheduledPayout(ctx context.Context, schedule *intPkg.PayoutSchedule, runAt time.Time) error {
+	if schedule == nil {
+		return nil
+	}
+
+	schedule.LastPayoutAt = runAt
+
+	return s.payoutScheduleRepository.Upsert(ctx, schedule)
+}
+
 func (s *Service) renderPayoutDocument(
 	ctx context.Context,
 	pd *billingpb.PayoutDocument,
@@ -393,12 +602,65 @@ func (s *Service) renderPayoutDocument(
 	return nil
 }
 
+// UpdatePayoutDocument changes the status and the transaction details of the payout document.
+// The status of the payout under the approval is changed by the approval workflow only, the payout
+// is marked as paid only after the approval.
 func (s *Service) UpdatePayoutDocument(
 	ctx context.Context,
 	req *billingpb.UpdatePayoutDocumentRequest,
 	res *billingpb.PayoutDocumentResponse,
 ) error {
 	pd, err := s.payoutRepository.GetById(ctx, req.PayoutDocumentId)
+
+	if err != nil || req.Status == "" || req.Status == pd.Status {
+		return s.updatePayoutDocument(ctx, req, res)
+	}
+
+	approval, err := s.payoutApprovalRepository.GetByPayoutDocumentId(ctx, pd.Id)
+
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	if req.Status != pkg.PayoutDocumentStatusPaid {
+		if approval != nil {
+			res.Status = billingpb.ResponseStatusForbidden
+			res.Message = errorPayoutApprovalRequired
+			return nil
+		}
+
+		return s.updatePayoutDocument(ctx, req, res)
+	}
+
+	if approval == nil || approval.Status != intPkg.PayoutApprovalStatusApproved {
+		res.Status = billingpb.ResponseStatusForbidden
+		res.Message = errorPayoutApprovalRequired
+		return nil
+	}
+
+	status, approvedBy := approval.Status, approval.ApprovedBy
+	err = s.updatePayoutDocument(ctx, req, res)
+
+	if err != nil || res.Status != billingpb.ResponseStatusOk {
+		return err
+	}
+
+	approval.Status = intPkg.PayoutApprovalStatusPaid
+	actionReq := &intPkg.PayoutApprovalActionRequest{
+		PayoutDocumentId: pd.Id,
+		Transaction:      req.Transaction,
+		Ip:               req.Ip,
+	}
+
+	return s.updatePayoutApproval(ctx, approval, status, approvedBy, intPkg.PayoutApprovalActionPaid, actionReq)
+}
+
+func (s *Service) updatePayoutDocument(
+	ctx context.Context,
+	req *billingpb.UpdatePayoutDocumentRequest,
+	res *billingpb.PayoutDocumentResponse,
+) error {
+	pd, err := s.payoutRepository.GetById(ctx, req.PayoutDocumentId)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			res.Status = billingpb.ResponseStatusNotFound
@@ -410,6 +672,7 @@ func (s *Service) UpdatePayoutDocument(
 
 	isChanged := false
 	needBalanceUpdate := false
+	previousStatus := pd.Status
 
 	_, isReqStatusForBecomePaid := statusForBecomePaid[req.Status]
 	becomePaid := isReqStatusForBecomePaid && pd.Status != req.Status
@@ -468,6 +731,10 @@ func (s *Service) UpdatePayoutDocument(
 			return err
 		}
 
+		if err = s.applyPayoutDocumentToBalanceLedger(ctx, pd, previousStatus); err != nil {
+			return err
+		}
+
 		if becomePaid == true {
 			err = s.royaltyReportSetPaid(ctx, pd.SourceId, pd.Id, req.Ip, pkg.RoyaltyReportChangeSourceAdmin)
 			if err != nil {
@@ -663,8 +930,9 @@ func (s *Service) PayoutDocumentPdfUploaded(
 func (s *Service) getPayoutDocumentSources(
 	ctx context.Context,
 	merchant *billingpb.Merchant,
+	currency string,
 ) ([]*billingpb.RoyaltyReport, error) {
-	result, err := s.royaltyReportRepository.GetNonPayoutReports(ctx, merchant.Id, merchant.GetPayoutCurrency())
+	result, err := s.royaltyReportRepository.GetNonPayoutReports(ctx, merchant.Id, currency)
 
 	if err != nil && err != mongo.ErrNoDocuments {
 		return nil, err
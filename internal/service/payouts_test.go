@@ -3,12 +3,14 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/google/uuid"
 	"github.com/paysuper/paysuper-billing-server/internal/config"
 	"github.com/paysuper/paysuper-billing-server/internal/database"
 	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
@@ -17,10 +19,12 @@ import (
 	mock2 "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest/observer"
 	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"sync"
 	"testing"
 	"time"
 )
@@ -514,7 +518,7 @@ func (suite *PayoutsTestSuite) helperInsertPayoutDocuments(data []*billingpb.Pay
 func (suite *PayoutsTestSuite) TestPayouts_getPayoutDocumentSources_Ok_NoPayoutsYet() {
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report6})
 
-	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant)
+	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant, suite.merchant.GetPayoutCurrency())
 	assert.NoError(suite.T(), err)
 	assert.Len(suite.T(), reports, 2)
 }
@@ -522,20 +526,20 @@ func (suite *PayoutsTestSuite) TestPayouts_getPayoutDocumentSources_Ok_NoPayouts
 func (suite *PayoutsTestSuite) TestPayouts_getPayoutDocumentSources_Ok_FilteringByCurrency() {
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report5, suite.report6})
 
-	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant)
+	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant, suite.merchant.GetPayoutCurrency())
 	assert.NoError(suite.T(), err)
 	assert.Len(suite.T(), reports, 2)
 }
 
 func (suite *PayoutsTestSuite) TestPayouts_getPayoutDocumentSources_Fail_NotFound() {
-	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant)
+	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant, suite.merchant.GetPayoutCurrency())
 	assert.EqualError(suite.T(), err, errorPayoutSourcesNotFound.Error())
 	assert.Len(suite.T(), reports, 0)
 }
 
 func (suite *PayoutsTestSuite) TestPayouts_getPayoutDocumentSources_Fail_MerchantNotFound() {
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report6})
-	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), &billingpb.Merchant{Id: primitive.NewObjectID().Hex()})
+	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), &billingpb.Merchant{Id: primitive.NewObjectID().Hex()}, "")
 	assert.EqualError(suite.T(), err, errorPayoutSourcesNotFound.Error())
 	assert.Len(suite.T(), reports, 0)
 }
@@ -543,7 +547,7 @@ func (suite *PayoutsTestSuite) TestPayouts_getPayoutDocumentSources_Fail_Merchan
 func (suite *PayoutsTestSuite) TestPayouts_getPayoutDocumentSources_Fail_HasPendingReports() {
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report3})
 
-	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant)
+	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant, suite.merchant.GetPayoutCurrency())
 	assert.EqualError(suite.T(), err, errorPayoutSourcesPending.Error())
 	assert.Len(suite.T(), reports, 0)
 }
@@ -551,7 +555,7 @@ func (suite *PayoutsTestSuite) TestPayouts_getPayoutDocumentSources_Fail_HasPend
 func (suite *PayoutsTestSuite) TestPayouts_getPayoutDocumentSources_Fail_HasDisputingReports() {
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report7})
 
-	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant)
+	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant, suite.merchant.GetPayoutCurrency())
 	assert.EqualError(suite.T(), err, errorPayoutSourcesDispute.Error())
 	assert.Len(suite.T(), reports, 0)
 }
@@ -668,6 +672,24 @@ func (suite *PayoutsTestSuite) TestPayouts_CreatePayoutDocument_Failed_NoSources
 	assert.Equal(suite.T(), res.Message, errorPayoutSourcesNotFound)
 }
 
+func (suite *PayoutsTestSuite) TestPayouts_createPayoutDocument_Failed_ResponseOfPreviousMerchantIgnored() {
+	req := &billingpb.CreatePayoutDocumentRequest{
+		MerchantId:       suite.merchant.Id,
+		Ip:               "127.0.0.1",
+		IsAutoGeneration: true,
+	}
+	res := &billingpb.CreatePayoutDocumentResponse{
+		Status: billingpb.ResponseStatusOk,
+		Items:  []*billingpb.PayoutDocument{{Id: primitive.NewObjectID().Hex()}},
+	}
+
+	err := suite.service.createPayoutDocument(context.TODO(), suite.merchant, req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), errorPayoutSourcesNotFound, res.Message)
+	assert.Empty(suite.T(), res.Items)
+}
+
 func (suite *PayoutsTestSuite) TestPayouts_CreatePayoutDocument_Failed_MerchantNotFound() {
 
 	req := &billingpb.CreatePayoutDocumentRequest{
@@ -703,6 +725,77 @@ func (suite *PayoutsTestSuite) TestPayouts_CreatePayoutDocument_Failed_ZeroAmoun
 	assert.Equal(suite.T(), res.Message, errorPayoutAmountInvalid)
 }
 
+func (suite *PayoutsTestSuite) TestPayouts_CreatePayoutDocument_Failed_BelowScheduleMinAmount() {
+	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report2})
+
+	_, err := suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id)
+	assert.NoError(suite.T(), err)
+
+	scheduleRes := &intPkg.PayoutScheduleResponse{}
+	err = suite.service.SetPayoutSchedule(
+		context.TODO(),
+		&intPkg.SetPayoutScheduleRequest{
+			MerchantId: suite.merchant.Id,
+			Period:     intPkg.PayoutSchedulePeriodMonthly,
+			DayOfMonth: 1,
+			MinAmounts: map[string]float64{"RUB": 20000},
+			UserId:     suite.helperAddPayoutAdmin(billingpb.RoleSystemAdmin),
+		},
+		scheduleRes,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, scheduleRes.Status)
+
+	req := &billingpb.CreatePayoutDocumentRequest{
+		MerchantId:       suite.merchant.Id,
+		Ip:               "127.0.0.1",
+		IsAutoGeneration: true,
+	}
+	res := &billingpb.CreatePayoutDocumentResponse{}
+
+	err = suite.service.createPayoutDocument(context.TODO(), suite.merchant, req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), errorPayoutBelowMinAmount, res.Message)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_SetPayoutSchedule_Failed_PeriodInvalid() {
+	res := &intPkg.PayoutScheduleResponse{}
+	err := suite.service.SetPayoutSchedule(
+		context.TODO(),
+		&intPkg.SetPayoutScheduleRequest{
+			MerchantId: suite.merchant.Id,
+			Period:     intPkg.PayoutSchedulePeriodMonthly,
+			DayOfMonth: 31,
+			UserId:     suite.helperAddPayoutAdmin(billingpb.RoleSystemAdmin),
+		},
+		res,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), payoutScheduleErrorPeriodInvalid, res.Message)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_SetPayoutSchedule_Failed_AccessDenied() {
+	res := &intPkg.PayoutScheduleResponse{}
+	err := suite.service.SetPayoutSchedule(
+		context.TODO(),
+		&intPkg.SetPayoutScheduleRequest{
+			MerchantId: suite.merchant.Id,
+			Period:     intPkg.PayoutSchedulePeriodMonthly,
+			DayOfMonth: 1,
+			UserId:     suite.merchant.User.Id,
+		},
+		res,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+	assert.Equal(suite.T(), payoutScheduleErrorAccessDenied, res.Message)
+
+	_, err = suite.service.payoutScheduleRepository.GetByMerchantId(context.TODO(), suite.merchant.Id)
+	assert.Equal(suite.T(), mongo.ErrNoDocuments, err)
+}
+
 func (suite *PayoutsTestSuite) TestPayouts_CreatePayoutDocument_Failed_NoBalance() {
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1})
 
@@ -812,6 +905,7 @@ func (suite *PayoutsTestSuite) TestPayouts_UpdatePayoutDocument_Ok() {
 
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report6})
 	suite.helperInsertPayoutDocuments([]*billingpb.PayoutDocument{suite.payout2})
+	suite.helperApprovePayoutDocument(suite.payout2)
 
 	req := &billingpb.UpdatePayoutDocumentRequest{
 		PayoutDocumentId:   suite.payout2.Id,
@@ -834,12 +928,18 @@ func (suite *PayoutsTestSuite) TestPayouts_UpdatePayoutDocument_Ok() {
 	assert.Equal(suite.T(), res.Item.FailureTransaction, "failure456")
 	assert.Equal(suite.T(), res.Item.FailureMessage, "bla-bla-bla")
 	assert.Equal(suite.T(), res.Item.FailureCode, "999")
+
+	approval, err := suite.service.payoutApprovalRepository.GetByPayoutDocumentId(context.TODO(), suite.payout2.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), intPkg.PayoutApprovalStatusPaid, approval.Status)
+	assert.Equal(suite.T(), intPkg.PayoutApprovalActionPaid, approval.History[len(approval.History)-1].Action)
 }
 
 func (suite *PayoutsTestSuite) TestPayouts_UpdatePayoutDocument_Ok_PaidOk() {
 
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report6})
 	suite.helperInsertPayoutDocuments([]*billingpb.PayoutDocument{suite.payout2})
+	suite.helperApprovePayoutDocument(suite.payout2)
 
 	req := &billingpb.UpdatePayoutDocumentRequest{
 		PayoutDocumentId: suite.payout2.Id,
@@ -922,6 +1022,7 @@ func (suite *PayoutsTestSuite) TestPayouts_UpdatePayoutDocument_Failed_NotFound(
 func (suite *PayoutsTestSuite) TestPayouts_UpdatePayoutDocument_Failed_UpdateError() {
 
 	suite.helperInsertPayoutDocuments([]*billingpb.PayoutDocument{suite.payout1})
+	suite.helperApprovePayoutDocument(suite.payout2)
 
 	pds := &mocks.PayoutRepositoryInterface{}
 	pds.On("Update", mock2.Anything, mock2.Anything, mock2.Anything, mock2.Anything).Return(errors.New(mocks.SomeError))
@@ -944,6 +1045,651 @@ func (suite *PayoutsTestSuite) TestPayouts_UpdatePayoutDocument_Failed_UpdateErr
 	assert.Error(suite.T(), err)
 }
 
+func (suite *PayoutsTestSuite) TestPayouts_UpdatePayoutDocument_Failed_PaidWithoutApproval() {
+	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report6})
+	suite.helperInsertPayoutDocuments([]*billingpb.PayoutDocument{suite.payout2})
+
+	req := &billingpb.UpdatePayoutDocumentRequest{
+		PayoutDocumentId: suite.payout2.Id,
+		Status:           pkg.PayoutDocumentStatusPaid,
+		Ip:               "192.168.1.1",
+	}
+	res := &billingpb.PayoutDocumentResponse{}
+
+	err := suite.service.UpdatePayoutDocument(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+	assert.Equal(suite.T(), errorPayoutApprovalRequired, res.Message)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_UpdatePayoutDocument_Failed_PaidAwaitingApproval() {
+	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report6})
+	suite.helperInsertPayoutDocuments([]*billingpb.PayoutDocument{suite.payout2})
+
+	err := suite.service.createPayoutApproval(context.TODO(), suite.payout2, suite.merchant.User.Id)
+	assert.NoError(suite.T(), err)
+
+	req := &billingpb.UpdatePayoutDocumentRequest{
+		PayoutDocumentId: suite.payout2.Id,
+		Status:           pkg.PayoutDocumentStatusPaid,
+		Ip:               "192.168.1.1",
+	}
+	res := &billingpb.PayoutDocumentResponse{}
+
+	err = suite.service.UpdatePayoutDocument(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+	assert.Equal(suite.T(), errorPayoutApprovalRequired, res.Message)
+
+	pd, err := suite.service.payoutRepository.GetById(context.TODO(), suite.payout2.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.PayoutDocumentStatusPending, pd.Status)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_UpdatePayoutDocument_Failed_CancelApproved() {
+	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report6})
+	suite.helperInsertPayoutDocuments([]*billingpb.PayoutDocument{suite.payout2})
+	suite.helperApprovePayoutDocument(suite.payout2)
+
+	req := &billingpb.UpdatePayoutDocumentRequest{
+		PayoutDocumentId: suite.payout2.Id,
+		Status:           pkg.PayoutDocumentStatusCanceled,
+		Ip:               "192.168.1.1",
+	}
+	res := &billingpb.PayoutDocumentResponse{}
+
+	err := suite.service.UpdatePayoutDocument(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+	assert.Equal(suite.T(), errorPayoutApprovalRequired, res.Message)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_PayoutApproval_Failed_ConcurrentApproval() {
+	suite.helperInsertPayoutDocuments([]*billingpb.PayoutDocument{suite.payout2})
+	suite.service.cfg.PayoutDualApprovalAmounts = map[string]float64{"RUB": 100}
+
+	admin := suite.helperAddPayoutAdmin(billingpb.RoleSystemAdmin)
+	approver1 := suite.helperAddPayoutApprover(admin, 1000000000)
+	approver2 := suite.helperAddPayoutApprover(admin, 1000000000)
+
+	err := suite.service.createPayoutApproval(context.TODO(), suite.payout2, suite.merchant.User.Id)
+	assert.NoError(suite.T(), err)
+
+	// the second approver has read the approval before the first one saved the approval
+	stale, err := suite.service.payoutApprovalRepository.GetByPayoutDocumentId(context.TODO(), suite.payout2.Id)
+	assert.NoError(suite.T(), err)
+
+	res := &intPkg.PayoutApprovalResponse{}
+	req := &intPkg.PayoutApprovalActionRequest{PayoutDocumentId: suite.payout2.Id, UserId: approver1}
+	err = suite.service.ApprovePayoutDocument(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+
+	status, approvedBy := stale.Status, stale.ApprovedBy
+	stale.ApprovedBy = append(stale.ApprovedBy, approver2)
+	stale.Status = intPkg.PayoutApprovalStatusApproved
+	req = &intPkg.PayoutApprovalActionRequest{PayoutDocumentId: suite.payout2.Id, UserId: approver2}
+	err = suite.service.updatePayoutApproval(context.TODO(), stale, status, approvedBy, intPkg.PayoutApprovalActionApprove, req)
+	assert.Equal(suite.T(), mongo.ErrNoDocuments, err)
+
+	approval, err := suite.service.payoutApprovalRepository.GetByPayoutDocumentId(context.TODO(), suite.payout2.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), intPkg.PayoutApprovalStatusAwaiting, approval.Status)
+	assert.Equal(suite.T(), []string{approver1}, approval.ApprovedBy)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_PayoutApproval_DualApproval_Ok() {
+	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report6})
+	suite.helperInsertPayoutDocuments([]*billingpb.PayoutDocument{suite.payout2})
+	suite.service.cfg.PayoutDualApprovalAmounts = map[string]float64{"RUB": 100}
+
+	admin := suite.helperAddPayoutAdmin(billingpb.RoleSystemAdmin)
+	approver1 := suite.helperAddPayoutApprover(admin, 1000000000)
+	approver2 := suite.helperAddPayoutApprover(admin, 1000000000)
+	payer := suite.helperAddPayoutAdmin(billingpb.RoleSystemFinancial)
+
+	err := suite.service.createPayoutApproval(context.TODO(), suite.payout2, suite.merchant.User.Id)
+	assert.NoError(suite.T(), err)
+
+	req := &intPkg.PayoutApprovalActionRequest{PayoutDocumentId: suite.payout2.Id, UserId: approver1}
+	res := &intPkg.PayoutApprovalResponse{}
+	err = suite.service.ApprovePayoutDocument(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.EqualValues(suite.T(), 2, res.Item.RequiredApprovals)
+	assert.Equal(suite.T(), intPkg.PayoutApprovalStatusAwaiting, res.Item.Status)
+
+	res = &intPkg.PayoutApprovalResponse{}
+	err = suite.service.ApprovePayoutDocument(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), payoutApprovalErrorSameUser, res.Message)
+
+	res = &intPkg.PayoutApprovalResponse{}
+	err = suite.service.MarkPayoutDocumentPaid(context.TODO(), &intPkg.PayoutApprovalActionRequest{PayoutDocumentId: suite.payout2.Id, UserId: payer}, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), payoutApprovalErrorStatusInvalid, res.Message)
+
+	res = &intPkg.PayoutApprovalResponse{}
+	err = suite.service.ApprovePayoutDocument(context.TODO(), &intPkg.PayoutApprovalActionRequest{PayoutDocumentId: suite.payout2.Id, UserId: approver2}, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Equal(suite.T(), intPkg.PayoutApprovalStatusApproved, res.Item.Status)
+
+	res = &intPkg.PayoutApprovalResponse{}
+	err = suite.service.MarkPayoutDocumentPaid(context.TODO(), &intPkg.PayoutApprovalActionRequest{PayoutDocumentId: suite.payout2.Id, UserId: approver2}, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), payoutApprovalErrorSameUser, res.Message)
+
+	res = &intPkg.PayoutApprovalResponse{}
+	req = &intPkg.PayoutApprovalActionRequest{PayoutDocumentId: suite.payout2.Id, UserId: payer, Transaction: "transaction123"}
+	err = suite.service.MarkPayoutDocumentPaid(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Equal(suite.T(), intPkg.PayoutApprovalStatusPaid, res.Item.Status)
+	assert.Equal(suite.T(), payer, res.Item.PaidBy)
+	assert.Len(suite.T(), res.Item.History, 4)
+
+	pd, err := suite.service.payoutRepository.GetById(context.TODO(), suite.payout2.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.PayoutDocumentStatusPaid, pd.Status)
+	assert.Equal(suite.T(), "transaction123", pd.Transaction)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_PayoutApproval_Failed_CreatorCantApprove() {
+	reporting := &reportingMocks.ReporterService{}
+	reporting.On("CreateFile", mock2.Anything, mock2.Anything).Return(nil, nil)
+	suite.service.reporterService = reporting
+
+	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report2})
+
+	admin := suite.helperAddPayoutAdmin(billingpb.RoleSystemAdmin)
+	creator := suite.helperAddPayoutApprover(admin, 1000000000)
+
+	req := &billingpb.CreatePayoutDocumentRequest{
+		MerchantId:  suite.merchant.Id,
+		Description: "test payout",
+		Ip:          "127.0.0.1",
+		Initiator:   creator,
+	}
+	res := &billingpb.CreatePayoutDocumentResponse{}
+	err := suite.service.CreatePayoutDocument(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+
+	approval, err := suite.service.payoutApprovalRepository.GetByPayoutDocumentId(context.TODO(), res.Items[0].Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), creator, approval.CreatedBy)
+
+	approvalReq := &intPkg.PayoutApprovalActionRequest{PayoutDocumentId: res.Items[0].Id, UserId: creator}
+	approvalRes := &intPkg.PayoutApprovalResponse{}
+	err = suite.service.ApprovePayoutDocument(context.TODO(), approvalReq, approvalRes)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, approvalRes.Status)
+	assert.Equal(suite.T(), payoutApprovalErrorSameUser, approvalRes.Message)
+
+	// the approval workflow can't be bypassed by the direct change of the payout document status
+	updateReq := &billingpb.UpdatePayoutDocumentRequest{
+		PayoutDocumentId: res.Items[0].Id,
+		Status:           pkg.PayoutDocumentStatusCanceled,
+		Ip:               "127.0.0.1",
+	}
+	updateRes := &billingpb.PayoutDocumentResponse{}
+	err = suite.service.UpdatePayoutDocument(context.TODO(), updateReq, updateRes)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, updateRes.Status)
+	assert.Equal(suite.T(), errorPayoutApprovalRequired, updateRes.Message)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_PayoutApproval_LimitExceeded() {
+	suite.helperInsertPayoutDocuments([]*billingpb.PayoutDocument{suite.payout2})
+
+	admin := suite.helperAddPayoutAdmin(billingpb.RoleSystemAdmin)
+	approver := suite.helperAddPayoutApprover(admin, 1)
+
+	err := suite.service.createPayoutApproval(context.TODO(), suite.payout2, pkg.RoyaltyReportChangeSourceAuto)
+	assert.NoError(suite.T(), err)
+
+	res := &intPkg.PayoutApprovalResponse{}
+	err = suite.service.ApprovePayoutDocument(context.TODO(), &intPkg.PayoutApprovalActionRequest{PayoutDocumentId: suite.payout2.Id, UserId: approver}, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+	assert.Equal(suite.T(), payoutApprovalErrorLimitExceeded, res.Message)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_RequestPayout_Ok_PartialAmount() {
+	reporting := &reportingMocks.ReporterService{}
+	reporting.On("CreateFile", mock2.Anything, mock2.Anything).Return(nil, nil)
+	suite.service.reporterService = reporting
+
+	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report2})
+
+	req := &intPkg.RequestPayoutRequest{
+		MerchantId:  suite.merchant.Id,
+		Amount:      13100,
+		Description: "payout by request",
+		UserId:      suite.merchant.User.Id,
+		Ip:          "127.0.0.1",
+	}
+	res := &intPkg.RequestPayoutResponse{}
+
+	err := suite.service.RequestPayout(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.EqualValues(suite.T(), 13100, res.Item.Balance)
+	assert.EqualValues(suite.T(), 13100, res.Item.TotalFees)
+	assert.Equal(suite.T(), pkg.PayoutDocumentStatusPending, res.Item.Status)
+	assert.Equal(suite.T(), suite.merchant.GetPayoutCurrency(), res.Item.Currency)
+	// the second report is paid partially, so it remains without payout
+	assert.Equal(suite.T(), []string{suite.report1.Id}, res.Item.SourceId)
+
+	ledger, err := suite.service.merchantBalanceLedgerRepository.GetByMerchantIdAndCurrency(
+		context.TODO(),
+		suite.merchant.Id,
+		suite.merchant.GetPayoutCurrency(),
+	)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), -13100, ledger.Available)
+
+	approval, err := suite.service.payoutApprovalRepository.GetByPayoutDocumentId(context.TODO(), res.Item.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), intPkg.PayoutApprovalStatusAwaiting, approval.Status)
+
+	balance, err := suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 479.5, balance.Total)
+
+	// the rest of the balance is below the minimum payout amount
+	req.Amount = 0
+	res = &intPkg.RequestPayoutResponse{}
+	err = suite.service.RequestPayout(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), payoutRequestErrorBelowMinAmount, res.Message)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_RequestPayout_Failed_NotEnoughBalance() {
+	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report2})
+
+	req := &intPkg.RequestPayoutRequest{
+		MerchantId: suite.merchant.Id,
+		Amount:     20000,
+		Ip:         "127.0.0.1",
+	}
+	res := &intPkg.RequestPayoutResponse{}
+
+	err := suite.service.RequestPayout(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), payoutRequestErrorNotEnoughBalance, res.Message)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_RequestPayout_Failed_Dispute() {
+	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report7})
+
+	req := &intPkg.RequestPayoutRequest{MerchantId: suite.merchant.Id, Ip: "127.0.0.1"}
+	res := &intPkg.RequestPayoutResponse{}
+
+	err := suite.service.RequestPayout(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), errorPayoutSourcesDispute, res.Message)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_RequestPayout_Failed_LimitExceeded() {
+	reporting := &reportingMocks.ReporterService{}
+	reporting.On("CreateFile", mock2.Anything, mock2.Anything).Return(nil, nil)
+	suite.service.reporterService = reporting
+
+	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report2})
+
+	limitReq := &intPkg.SetPayoutRequestLimitRequest{
+		MerchantId:  suite.merchant.Id,
+		MaxRequests: 1,
+		PeriodDays:  7,
+		UserId:      suite.helperAddPayoutAdmin(billingpb.RoleSystemAdmin),
+	}
+	limitRes := &intPkg.PayoutRequestLimitResponse{}
+	err := suite.service.SetPayoutRequestLimit(context.TODO(), limitReq, limitRes)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, limitRes.Status)
+
+	req := &intPkg.RequestPayoutRequest{MerchantId: suite.merchant.Id, Amount: 13000, Ip: "127.0.0.1"}
+	res := &intPkg.RequestPayoutResponse{}
+	err = suite.service.RequestPayout(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+
+	res = &intPkg.RequestPayoutResponse{}
+	err = suite.service.RequestPayout(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), payoutRequestErrorLimitExceeded, res.Message)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_RequestPayout_Concurrent_OneRequest() {
+	reporting := &reportingMocks.ReporterService{}
+	reporting.On("CreateFile", mock2.Anything, mock2.Anything).Return(nil, nil)
+	suite.service.reporterService = reporting
+
+	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report2})
+
+	// each request is within the balance, but the balance isn't enough for both of them
+	var wg sync.WaitGroup
+	results := make(chan *intPkg.RequestPayoutResponse, 2)
+
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			req := &intPkg.RequestPayoutRequest{MerchantId: suite.merchant.Id, Amount: 13000, Ip: "127.0.0.1"}
+			res := &intPkg.RequestPayoutResponse{}
+			err := suite.service.RequestPayout(context.TODO(), req, res)
+			assert.NoError(suite.T(), err)
+			results <- res
+		}()
+	}
+
+	wg.Wait()
+	close(results)
+
+	succeeded := 0
+
+	for res := range results {
+		if res.Status == billingpb.ResponseStatusOk {
+			succeeded++
+			continue
+		}
+
+		assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+		assert.Contains(
+			suite.T(),
+			[]*billingpb.ResponseErrorMessage{payoutRequestErrorInProgress, payoutRequestErrorNotEnoughBalance},
+			res.Message,
+		)
+	}
+
+	assert.Equal(suite.T(), 1, succeeded)
+
+	count, err := suite.service.payoutRequestRepository.CountByMerchantId(
+		context.TODO(),
+		suite.merchant.Id,
+		time.Now().Add(-time.Hour),
+	)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 1, count)
+
+	// the reservation is released after the request
+	req := &intPkg.RequestPayoutRequest{MerchantId: suite.merchant.Id, Amount: 13000, Ip: "127.0.0.1"}
+	res := &intPkg.RequestPayoutResponse{}
+	err = suite.service.RequestPayout(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), payoutRequestErrorNotEnoughBalance, res.Message)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_RequestPayout_Failed_InProgress() {
+	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report2})
+
+	merchantOid, _ := primitive.ObjectIDFromHex(suite.merchant.Id)
+	reservation := &intPkg.PayoutRequestReservation{
+		MerchantId:    merchantOid,
+		ReservationId: primitive.NewObjectID().Hex(),
+		Currency:      suite.merchant.GetPayoutCurrency(),
+		ReservedAt:    time.Now(),
+	}
+	err := suite.service.payoutRequestRepository.Reserve(context.TODO(), reservation, reservation.ReservedAt)
+	assert.NoError(suite.T(), err)
+
+	req := &intPkg.RequestPayoutRequest{MerchantId: suite.merchant.Id, Amount: 13000, Ip: "127.0.0.1"}
+	res := &intPkg.RequestPayoutResponse{}
+	err = suite.service.RequestPayout(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), payoutRequestErrorInProgress, res.Message)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_SetPayoutRequestLimit_Failed_AccessDenied() {
+	req := &intPkg.SetPayoutRequestLimitRequest{
+		MerchantId:  suite.merchant.Id,
+		MaxRequests: 100,
+		PeriodDays:  1,
+		UserId:      suite.merchant.User.Id,
+	}
+	res := &intPkg.PayoutRequestLimitResponse{}
+
+	err := suite.service.SetPayoutRequestLimit(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+	assert.Equal(suite.T(), payoutRequestErrorAccessDenied, res.Message)
+
+	_, err = suite.service.payoutRequestLimitRepository.GetByMerchantId(context.TODO(), suite.merchant.Id)
+	assert.Equal(suite.T(), mongo.ErrNoDocuments, err)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_SetPayoutRequestLimit_Failed_LimitInvalid() {
+	req := &intPkg.SetPayoutRequestLimitRequest{
+		MerchantId:  suite.merchant.Id,
+		MaxRequests: 1,
+		UserId:      suite.helperAddPayoutAdmin(billingpb.RoleSystemAdmin),
+	}
+	res := &intPkg.PayoutRequestLimitResponse{}
+
+	err := suite.service.SetPayoutRequestLimit(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), payoutRequestErrorLimitInvalid, res.Message)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_ExportPayoutBatches_Failed_AccessDenied() {
+	suite.helperInsertPayoutDocuments([]*billingpb.PayoutDocument{suite.payout1})
+
+	req := &intPkg.ExportPayoutBatchesRequest{
+		PayoutDocumentIds: []string{suite.payout1.Id},
+		Debtor:            &intPkg.PayoutBatchDebtor{Name: "Debtor"},
+		UserId:            primitive.NewObjectID().Hex(),
+	}
+	res := &intPkg.ExportPayoutBatchesResponse{}
+
+	err := suite.service.ExportPayoutBatches(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+	assert.Equal(suite.T(), payoutBatchErrorAccessDenied, res.Message)
+
+	batchIds, err := suite.service.payoutRepository.GetBatchIds(context.TODO(), req.PayoutDocumentIds)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), batchIds)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_ExportPayoutBatches_Ok_BalanceExported() {
+	suite.payout1.Destination = suite.helperPayoutBatchBanking()
+	suite.payout1.Balance = 700000
+	suite.payout4.Destination = suite.helperPayoutBatchBanking()
+	suite.payout4.Balance = 50000
+	suite.helperInsertPayoutDocuments([]*billingpb.PayoutDocument{suite.payout1, suite.payout4})
+	suite.helperApprovePayoutDocument(suite.payout1)
+	suite.helperApprovePayoutDocument(suite.payout4)
+
+	req := &intPkg.ExportPayoutBatchesRequest{
+		PayoutDocumentIds: []string{suite.payout1.Id, suite.payout4.Id},
+		Debtor:            &intPkg.PayoutBatchDebtor{Name: "Debtor"},
+		UserId:            suite.helperAddPayoutAdmin(billingpb.RoleSystemFinancial),
+	}
+	res := &intPkg.ExportPayoutBatchesResponse{}
+
+	err := suite.service.ExportPayoutBatches(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Empty(suite.T(), res.Rejected)
+	assert.Len(suite.T(), res.Items, 1)
+
+	batch := res.Items[0]
+	assert.Equal(suite.T(), intPkg.PayoutBatchFormatSwiftCsv, batch.Format)
+	assert.EqualValues(suite.T(), 2, batch.ItemsCount)
+	assert.EqualValues(suite.T(), 750000, batch.TotalAmount)
+	assert.Contains(suite.T(), string(batch.Content), ",700000,RUB,")
+	assert.Contains(suite.T(), string(batch.Content), ",50000,RUB,")
+	assert.NotContains(suite.T(), string(batch.Content), ",765000,RUB,")
+
+	batchIds, err := suite.service.payoutRepository.GetBatchIds(context.TODO(), req.PayoutDocumentIds)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), batch.Id.Hex(), batchIds[suite.payout1.Id])
+	assert.Equal(suite.T(), batch.Id.Hex(), batchIds[suite.payout4.Id])
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_ExportPayoutBatches_Ok_NotApprovedRejected() {
+	suite.payout1.Destination = suite.helperPayoutBatchBanking()
+	suite.payout4.Destination = suite.helperPayoutBatchBanking()
+	suite.payout5.Destination = suite.helperPayoutBatchBanking()
+	suite.helperInsertPayoutDocuments([]*billingpb.PayoutDocument{suite.payout1, suite.payout4, suite.payout5})
+	suite.helperApprovePayoutDocument(suite.payout1)
+
+	err := suite.service.createPayoutApproval(context.TODO(), suite.payout4, suite.merchant.User.Id)
+	assert.NoError(suite.T(), err)
+
+	req := &intPkg.ExportPayoutBatchesRequest{
+		PayoutDocumentIds: []string{suite.payout1.Id, suite.payout4.Id, suite.payout5.Id},
+		Debtor:            &intPkg.PayoutBatchDebtor{Name: "Debtor"},
+		UserId:            suite.helperAddPayoutAdmin(billingpb.RoleSystemFinancial),
+	}
+	res := &intPkg.ExportPayoutBatchesResponse{}
+
+	err = suite.service.ExportPayoutBatches(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Len(suite.T(), res.Items, 1)
+	assert.Equal(suite.T(), []string{suite.payout1.Id}, res.Items[0].PayoutDocumentIds)
+	assert.Len(suite.T(), res.Rejected, 2)
+
+	for _, item := range res.Rejected {
+		assert.Equal(suite.T(), payoutBatchRejectApproval, item.Reason)
+	}
+
+	batchIds, err := suite.service.payoutRepository.GetBatchIds(context.TODO(), []string{suite.payout4.Id, suite.payout5.Id})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), batchIds)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_ExportPayoutBatches_Ok_AlreadyExportedRejected() {
+	suite.payout1.Destination = suite.helperPayoutBatchBanking()
+	suite.helperInsertPayoutDocuments([]*billingpb.PayoutDocument{suite.payout1})
+	suite.helperApprovePayoutDocument(suite.payout1)
+
+	req := &intPkg.ExportPayoutBatchesRequest{
+		PayoutDocumentIds: []string{suite.payout1.Id},
+		Debtor:            &intPkg.PayoutBatchDebtor{Name: "Debtor"},
+		UserId:            suite.helperAddPayoutAdmin(billingpb.RoleSystemFinancial),
+	}
+	res := &intPkg.ExportPayoutBatchesResponse{}
+
+	err := suite.service.ExportPayoutBatches(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Len(suite.T(), res.Items, 1)
+
+	res2 := &intPkg.ExportPayoutBatchesResponse{}
+	err = suite.service.ExportPayoutBatches(context.TODO(), req, res2)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res2.Status)
+	assert.Empty(suite.T(), res2.Items)
+	assert.Len(suite.T(), res2.Rejected, 1)
+	assert.Equal(suite.T(), fmt.Sprintf(payoutBatchRejectExported, res.Items[0].Id.Hex()), res2.Rejected[0].Reason)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_createPayoutBatch_Ok_ClaimedByConcurrentBatch() {
+	suite.payout1.Destination = suite.helperPayoutBatchBanking()
+	suite.payout4.Destination = suite.helperPayoutBatchBanking()
+	suite.helperInsertPayoutDocuments([]*billingpb.PayoutDocument{suite.payout1, suite.payout4})
+
+	// the first document is claimed by the concurrent export after the documents are read by this one
+	concurrentBatchId := primitive.NewObjectID().Hex()
+	claimed, err := suite.service.payoutRepository.ClaimForBatch(
+		context.TODO(),
+		[]string{suite.payout1.Id},
+		pkg.PayoutDocumentStatusPending,
+		concurrentBatchId,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{suite.payout1.Id}, claimed)
+
+	group := &payoutBatchGroup{format: intPkg.PayoutBatchFormatSwiftCsv, currency: "RUB"}
+
+	for _, pd := range []*billingpb.PayoutDocument{suite.payout1, suite.payout4} {
+		item, _, reason := suite.service.newPayoutBatchItem(context.TODO(), pd)
+		assert.Empty(suite.T(), reason)
+		group.items = append(group.items, item)
+	}
+
+	debtor := &intPkg.PayoutBatchDebtor{Name: "Debtor"}
+	batch, unclaimed, err := suite.service.createPayoutBatch(context.TODO(), group, debtor, time.Now(), "")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{suite.payout1.Id}, unclaimed)
+	assert.NotNil(suite.T(), batch)
+	assert.Equal(suite.T(), []string{suite.payout4.Id}, batch.PayoutDocumentIds)
+	assert.EqualValues(suite.T(), suite.payout4.Balance, batch.TotalAmount)
+	assert.NotContains(suite.T(), string(batch.Content), suite.payout1.Id)
+
+	batchIds, err := suite.service.payoutRepository.GetBatchIds(context.TODO(), []string{suite.payout1.Id, suite.payout4.Id})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), concurrentBatchId, batchIds[suite.payout1.Id])
+	assert.Equal(suite.T(), batch.Id.Hex(), batchIds[suite.payout4.Id])
+}
+
+func (suite *PayoutsTestSuite) helperApprovePayoutDocument(pd *billingpb.PayoutDocument) {
+	approval := &intPkg.PayoutApproval{
+		Id:                primitive.NewObjectID(),
+		PayoutDocumentId:  pd.Id,
+		MerchantId:        pd.MerchantId,
+		Amount:            pd.Balance,
+		Currency:          pd.Currency,
+		Status:            intPkg.PayoutApprovalStatusApproved,
+		RequiredApprovals: 1,
+		CreatedBy:         pkg.RoyaltyReportChangeSourceAuto,
+		ApprovedBy:        []string{primitive.NewObjectID().Hex()},
+		History:           []*intPkg.PayoutApprovalHistoryItem{},
+	}
+
+	if err := suite.service.payoutApprovalRepository.Insert(context.TODO(), approval); err != nil {
+		suite.FailNow("Insert payout approval failed", "%v", err)
+	}
+}
+
+func (suite *PayoutsTestSuite) helperPayoutBatchBanking() *billingpb.MerchantBanking {
+	return &billingpb.MerchantBanking{
+		Currency:      "RUB",
+		Name:          "Bank name",
+		AccountNumber: "40702810900000000001",
+		Swift:         "SABRRUMM",
+	}
+}
+
+func (suite *PayoutsTestSuite) helperAddPayoutAdmin(role string) string {
+	user := &billingpb.UserRole{
+		Id:     primitive.NewObjectID().Hex(),
+		UserId: primitive.NewObjectID().Hex(),
+		Role:   role,
+	}
+
+	if err := suite.service.userRoleRepository.AddAdminUser(context.TODO(), user); err != nil {
+		suite.FailNow("Insert admin user failed", "%v", err)
+	}
+
+	return user.UserId
+}
+
+func (suite *PayoutsTestSuite) helperAddPayoutApprover(admin string, limit float64) string {
+	approver := suite.helperAddPayoutAdmin(billingpb.RoleSystemFinancial)
+	req := &intPkg.SetPayoutApproverRequest{ApproverId: approver, Limits: map[string]float64{"RUB": limit}, UserId: admin}
+	res := &intPkg.PayoutApproverResponse{}
+
+	if err := suite.service.SetPayoutApprover(context.TODO(), req, res); err != nil || res.Status != billingpb.ResponseStatusOk {
+		suite.FailNow("Set payout approver failed", "%v", err)
+	}
+
+	return approver
+}
+
 func (suite *PayoutsTestSuite) TestPayouts_GetPayoutDocument_ById_Ok() {
 	suite.helperInsertPayoutDocuments([]*billingpb.PayoutDocument{suite.payout7})
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/golang/protobuf/ptypes"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
 	our "github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	"github.com/paysuper/paysuper-proto/go/currenciespb"
@@ -179,10 +180,17 @@ func (s *Service) GetRecommendedPriceByPriceGroup(
 		return err
 	}
 
+	rules, err := s.getPricingRules(ctx)
+
+	if err != nil {
+		zap.S().Errorw("Unable to get pricing rules", "err", err, "req", req)
+		return err
+	}
+
 	priceRange := s.getPriceTableRange(priceTable, req.Amount)
 
 	for _, region := range regions {
-		price, err := s.getRecommendedPriceForRegion(ctx, region, priceRange, req.Amount)
+		price, err := s.getRecommendedPriceForRegion(ctx, region, rules, priceRange, req.Amount)
 
 		if err != nil {
 			zap.S().Errorw("Unable to get recommended price for region", "err", err, "region", region)
@@ -228,6 +236,7 @@ func (s *Service) getPriceTableRange(pt *billingpb.PriceTable, amount float64) *
 func (s *Service) getRecommendedPriceForRegion(
 	ctx context.Context,
 	region *billingpb.PriceGroup,
+	rules map[string]*intPkg.PriceGroupPricingRules,
 	rng *billingpb.PriceTableRange,
 	amount float64,
 ) (float64, error) {
@@ -260,9 +269,8 @@ func (s *Service) getRecommendedPriceForRegion(
 	}
 
 	price := regionRange.From + (regionRange.To-regionRange.From)*ratio
-	priceFrac := s.calculatePriceWithFraction(region.Fraction, price)
 
-	return priceFrac, nil
+	return s.applyPriceGroupRules(region, rules, price), nil
 }
 
 func (s *Service) GetRecommendedPriceByConversion(
@@ -277,6 +285,13 @@ func (s *Service) GetRecommendedPriceByConversion(
 		return err
 	}
 
+	rules, err := s.getPricingRules(ctx)
+
+	if err != nil {
+		zap.S().Errorw("Unable to get pricing rules", "err", err, "req", req)
+		return err
+	}
+
 	for _, region := range regions {
 		amount, err := s.getPriceInCurrencyByAmount(ctx, region.Currency, req.Currency, req.Amount)
 
@@ -286,7 +301,7 @@ func (s *Service) GetRecommendedPriceByConversion(
 		}
 
 		res.RecommendedPrice = append(res.RecommendedPrice, &billingpb.RecommendedPrice{
-			Amount:   s.calculatePriceWithFraction(region.Fraction, amount),
+			Amount:   s.applyPriceGroupRules(region, rules, amount),
 			Region:   region.Region,
 			Currency: region.Currency,
 		})
@@ -288,16 +288,16 @@ func (suite *PriceGroupTestSuite) TestPriceGroup_getPriceTableRange_NotExistRang
 }
 
 func (suite *PriceGroupTestSuite) TestPriceGroup_getRecommendedPriceForRegion_Error_RegionNotFound() {
-	rep := &mocks.PriceTableServiceInterface{}
+	rep := &mocks.PriceTableRepositoryInterface{}
 	rep.On("GetByRegion", mock2.Anything, mock2.Anything).Return(nil, errors.New("error"))
 	suite.service.priceTableRepository = rep
 
-	_, err := suite.service.getRecommendedPriceForRegion(context.TODO(), &billingpb.PriceGroup{}, &billingpb.PriceTableRange{}, 1)
+	_, err := suite.service.getRecommendedPriceForRegion(context.TODO(), &billingpb.PriceGroup{}, nil, &billingpb.PriceTableRange{}, 1)
 	assert.Error(suite.T(), err)
 }
 
 func (suite *PriceGroupTestSuite) TestPriceGroup_getRecommendedPriceForRegion_Ok_ExistRange() {
-	rep := &mocks.PriceTableServiceInterface{}
+	rep := &mocks.PriceTableRepositoryInterface{}
 	rep.
 		On("GetByRegion", mock2.Anything, mock2.Anything).
 		Return(&billingpb.PriceTable{Ranges: []*billingpb.PriceTableRange{
@@ -308,13 +308,13 @@ func (suite *PriceGroupTestSuite) TestPriceGroup_getRecommendedPriceForRegion_Ok
 	amount := float64(1)
 	pg := &billingpb.PriceGroup{Fraction: 0}
 	pt := &billingpb.PriceTableRange{From: 0, To: 2, Position: 0}
-	price, err := suite.service.getRecommendedPriceForRegion(context.TODO(), pg, pt, amount)
+	price, err := suite.service.getRecommendedPriceForRegion(context.TODO(), pg, nil, pt, amount)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), float64(8), price)
 }
 
 func (suite *PriceGroupTestSuite) TestPriceGroup_getRecommendedPriceForRegion_Ok_NotExistRange() {
-	rep := &mocks.PriceTableServiceInterface{}
+	rep := &mocks.PriceTableRepositoryInterface{}
 	rep.
 		On("GetByRegion", mock2.Anything, mock2.Anything).
 		Return(&billingpb.PriceTable{Ranges: []*billingpb.PriceTableRange{
@@ -325,7 +325,7 @@ func (suite *PriceGroupTestSuite) TestPriceGroup_getRecommendedPriceForRegion_Ok
 	amount := float64(1)
 	pg := &billingpb.PriceGroup{Fraction: 0}
 	pt := &billingpb.PriceTableRange{From: 0, To: 2, Position: 1}
-	price, err := suite.service.getRecommendedPriceForRegion(context.TODO(), pg, pt, amount)
+	price, err := suite.service.getRecommendedPriceForRegion(context.TODO(), pg, nil, pt, amount)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), float64(12), price)
 }
@@ -346,7 +346,7 @@ func (suite *PriceGroupTestSuite) TestPriceGroup_GetRecommendedPriceByPriceGroup
 	pg.On("GetAll", mock2.Anything).Return([]*billingpb.PriceGroup{}, nil)
 	suite.service.priceGroupRepository = pg
 
-	pt := &mocks.PriceTableServiceInterface{}
+	pt := &mocks.PriceTableRepositoryInterface{}
 	pt.On("GetByRegion", mock2.Anything, mock2.Anything).Return(nil, errors.New("price table not exists"))
 	suite.service.priceTableRepository = pt
 
@@ -362,7 +362,7 @@ func (suite *PriceGroupTestSuite) TestPriceGroup_GetRecommendedPriceByPriceGroup
 	pg.On("CalculatePriceWithFraction", mock2.Anything, mock2.Anything, mock2.Anything).Return(float64(1))
 	suite.service.priceGroupRepository = pg
 
-	pt := &mocks.PriceTableServiceInterface{}
+	pt := &mocks.PriceTableRepositoryInterface{}
 	pt.
 		On("GetByRegion", mock2.Anything, mock2.Anything).
 		Return(&billingpb.PriceTable{Ranges: []*billingpb.PriceTableRange{{From: 0, To: 2, Position: 0}}}, nil)
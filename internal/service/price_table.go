@@ -2,8 +2,24 @@ package service
 
 import (
 	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/internal/repository/models"
+	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
+	tools "github.com/paysuper/paysuper-tools/number"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	priceTableErrorBaseTableNotFound       = newBillingServerErrorMsg("pt000001", "price table of the base region not found")
+	priceTableErrorBaseGroupNotFound       = newBillingServerErrorMsg("pt000002", "price group of the base region not found")
+	priceTableErrorExchangeFailed          = newBillingServerErrorMsg("pt000003", "currency exchange failed")
+	priceTableErrorVersionNotFound         = newBillingServerErrorMsg("pt000004", "price table version not found")
+	priceTableErrorVersionNotDraft         = newBillingServerErrorMsg("pt000005", "only draft version of price table can be activated")
+	priceTableErrorPreviousVersionNotFound = newBillingServerErrorMsg("pt000006", "previous version of price table not found")
 )
 
 func (s *Service) GetRecommendedPriceTable(
@@ -22,3 +38,415 @@ func (s *Service) GetRecommendedPriceTable(
 
 	return nil
 }
+
+// GeneratePriceTable creates a new draft version of the price tables for all price groups regions.
+// Ranges of every region are calculated from the base region ranges using the current exchange rates and
+// the purchasing power coefficients of the regions. The draft must be activated with ActivatePriceTable.
+func (s *Service) GeneratePriceTable(
+	ctx context.Context,
+	_ *billingpb.EmptyRequest,
+	res *intPkg.PriceTableVersionResponse,
+) error {
+	baseTable, err := s.priceTableRepository.GetByRegion(ctx, s.cfg.PriceTableBaseRegion)
+
+	if err != nil || len(baseTable.Ranges) <= 0 {
+		zap.L().Error(priceTableErrorBaseTableNotFound.Message, zap.Error(err), zap.String("region", s.cfg.PriceTableBaseRegion))
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = priceTableErrorBaseTableNotFound
+		return nil
+	}
+
+	groups, err := s.priceGroupRepository.GetAll(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	var baseGroup *billingpb.PriceGroup
+
+	for _, group := range groups {
+		if group.Region == s.cfg.PriceTableBaseRegion {
+			baseGroup = group
+			break
+		}
+	}
+
+	if baseGroup == nil {
+		zap.L().Error(priceTableErrorBaseGroupNotFound.Message, zap.String("region", s.cfg.PriceTableBaseRegion))
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = priceTableErrorBaseGroupNotFound
+		return nil
+	}
+
+	// current content of the price table collection must be kept as version to be able to rollback to it
+	if _, err = s.getActivePriceTableVersion(ctx); err != nil {
+		return err
+	}
+
+	number, err := s.priceTableVersionRepository.GetLastVersionNumber(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	version := &intPkg.PriceTableVersion{
+		Id:           primitive.NewObjectID(),
+		Version:      number + 1,
+		Status:       intPkg.PriceTableVersionStatusDraft,
+		BaseRegion:   baseGroup.Region,
+		Rates:        make(map[string]float64),
+		Coefficients: make(map[string]float64),
+		CreatedAt:    time.Now(),
+	}
+
+	for _, group := range groups {
+		rate := float64(1)
+
+		if group.Currency != baseGroup.Currency {
+			rate, err = s.getPriceInCurrencyByAmount(ctx, group.Currency, baseGroup.Currency, 1)
+
+			if err != nil {
+				zap.L().Error(
+					pkg.ErrorGrpcServiceCallFailed,
+					zap.Error(err),
+					zap.String(errorFieldService, "CurrencyRatesService"),
+					zap.String(errorFieldMethod, "ExchangeCurrencyCurrentCommon"),
+					zap.String("currency", group.Currency),
+				)
+				res.Status = billingpb.ResponseStatusSystemError
+				res.Message = priceTableErrorExchangeFailed
+				return nil
+			}
+		}
+
+		coefficient := s.getPurchasingPowerCoefficient(group.Region)
+		version.Rates[group.Region] = rate
+		version.Coefficients[group.Region] = coefficient
+		version.Tables = append(
+			version.Tables,
+			s.makeRegionPriceTable(baseTable, group, rate*coefficient),
+		)
+	}
+
+	if err = s.priceTableVersionRepository.Insert(ctx, version); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = version
+
+	return nil
+}
+
+// GetPriceTableDiff returns the difference between the current price tables and the passed version.
+// If version identifier not passed then the last draft version is used.
+func (s *Service) GetPriceTableDiff(
+	ctx context.Context,
+	req *intPkg.PriceTableVersionRequest,
+	res *intPkg.PriceTableDiffResponse,
+) error {
+	version, msg := s.getPriceTableVersionForRequest(ctx, req)
+
+	if msg != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = msg
+		return nil
+	}
+
+	current, err := s.priceTableRepository.GetAll(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Version = version
+	res.Regions = s.getPriceTableDiff(current, version.Tables)
+
+	return nil
+}
+
+// ActivatePriceTable replaces the current price tables by the passed version.
+// If version identifier not passed then the last draft version is activated.
+func (s *Service) ActivatePriceTable(
+	ctx context.Context,
+	req *intPkg.PriceTableVersionRequest,
+	res *intPkg.PriceTableVersionResponse,
+) error {
+	version, msg := s.getPriceTableVersionForRequest(ctx, req)
+
+	if msg != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = msg
+		return nil
+	}
+
+	if version.Status != intPkg.PriceTableVersionStatusDraft {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = priceTableErrorVersionNotDraft
+		return nil
+	}
+
+	if err := s.switchPriceTableVersion(ctx, version); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = version
+
+	return nil
+}
+
+// RollbackPriceTable restores the price tables from the version that was active before the current one.
+func (s *Service) RollbackPriceTable(
+	ctx context.Context,
+	_ *billingpb.EmptyRequest,
+	res *intPkg.PriceTableVersionResponse,
+) error {
+	active, err := s.getActivePriceTableVersion(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	previous, err := s.priceTableVersionRepository.GetPreviousArchived(ctx, active.Version)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			res.Status = billingpb.ResponseStatusNotFound
+			res.Message = priceTableErrorPreviousVersionNotFound
+			return nil
+		}
+		return err
+	}
+
+	if err = s.switchPriceTableVersion(ctx, previous); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = previous
+
+	return nil
+}
+
+func (s *Service) getPriceTableVersionForRequest(
+	ctx context.Context,
+	req *intPkg.PriceTableVersionRequest,
+) (*intPkg.PriceTableVersion, *billingpb.ResponseErrorMessage) {
+	var (
+		version *intPkg.PriceTableVersion
+		err     error
+	)
+
+	if req.VersionId != "" {
+		version, err = s.priceTableVersionRepository.GetById(ctx, req.VersionId)
+	} else {
+		version, err = s.priceTableVersionRepository.GetLastByStatus(ctx, intPkg.PriceTableVersionStatusDraft)
+	}
+
+	if err != nil {
+		return nil, priceTableErrorVersionNotFound
+	}
+
+	return version, nil
+}
+
+// getActivePriceTableVersion returns the active version of the price tables.
+// If the price tables were never versioned (loaded by migrations) then the current content of the price table
+// collection is saved as the active version. If the previous switch of the version was interrupted
+// then it's completed before the version is returned.
+func (s *Service) getActivePriceTableVersion(ctx context.Context) (*intPkg.PriceTableVersion, error) {
+	version, err := s.priceTableVersionRepository.GetLastByStatus(ctx, intPkg.PriceTableVersionStatusActive)
+
+	if err == nil {
+		count, err := s.priceTableVersionRepository.CountByStatus(ctx, intPkg.PriceTableVersionStatusActive)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if count > 1 {
+			if err = s.applyPriceTableVersion(ctx, version); err != nil {
+				return nil, err
+			}
+		}
+
+		return version, nil
+	}
+
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	tables, err := s.priceTableRepository.GetAll(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	number, err := s.priceTableVersionRepository.GetLastVersionNumber(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	version = &intPkg.PriceTableVersion{
+		Id:          primitive.NewObjectID(),
+		Version:     number + 1,
+		Status:      intPkg.PriceTableVersionStatusActive,
+		BaseRegion:  s.cfg.PriceTableBaseRegion,
+		CreatedAt:   time.Now(),
+		ActivatedAt: time.Now(),
+	}
+
+	for _, table := range tables {
+		version.Tables = append(version.Tables, s.mapPriceTableToVersionTable(table))
+	}
+
+	if err = s.priceTableVersionRepository.Insert(ctx, version); err != nil {
+		return nil, err
+	}
+
+	return version, nil
+}
+
+// switchPriceTableVersion makes the passed version active. The status of the version is changed first by the single
+// document write, the price tables and the statuses of other versions are written after that by the idempotent
+// writes. If they fail then the switch is completed by the next call of getActivePriceTableVersion.
+func (s *Service) switchPriceTableVersion(ctx context.Context, version *intPkg.PriceTableVersion) error {
+	if _, err := s.getActivePriceTableVersion(ctx); err != nil {
+		return err
+	}
+
+	version.Status = intPkg.PriceTableVersionStatusActive
+	version.ActivatedAt = time.Now()
+
+	if err := s.priceTableVersionRepository.Update(ctx, version); err != nil {
+		return err
+	}
+
+	return s.applyPriceTableVersion(ctx, version)
+}
+
+// applyPriceTableVersion replaces the price tables by the tables of the active version
+// and archives the previously active versions.
+func (s *Service) applyPriceTableVersion(ctx context.Context, version *intPkg.PriceTableVersion) error {
+	tables := make([]*billingpb.PriceTable, len(version.Tables))
+
+	for i, table := range version.Tables {
+		tables[i] = s.mapVersionTableToPriceTable(table)
+	}
+
+	if err := s.priceTableRepository.ReplaceAll(ctx, tables); err != nil {
+		return err
+	}
+
+	return s.priceTableVersionRepository.ArchiveActive(ctx, version.Id)
+}
+
+func (s *Service) getPurchasingPowerCoefficient(region string) float64 {
+	coefficient, ok := s.cfg.PriceTablePurchasingPowerCoefficients[region]
+
+	if !ok || coefficient <= 0 {
+		return 1
+	}
+
+	return coefficient
+}
+
+func (s *Service) makeRegionPriceTable(
+	base *billingpb.PriceTable,
+	group *billingpb.PriceGroup,
+	multiplier float64,
+) *models.MgoPriceTable {
+	table := &models.MgoPriceTable{
+		Id:       primitive.NewObjectID(),
+		Currency: group.Region,
+		Ranges:   make([]*models.MgoPriceTableRange, len(base.Ranges)),
+	}
+
+	for i, rng := range base.Ranges {
+		table.Ranges[i] = &models.MgoPriceTableRange{
+			From:     s.FormatAmount(rng.From*multiplier, group.Currency),
+			To:       s.FormatAmount(rng.To*multiplier, group.Currency),
+			Position: rng.Position,
+		}
+	}
+
+	return table
+}
+
+func (s *Service) getPriceTableDiff(
+	current []*billingpb.PriceTable,
+	next []*models.MgoPriceTable,
+) []*intPkg.PriceTableRegionDiff {
+	var diff []*intPkg.PriceTableRegionDiff
+	currentByRegion := make(map[string]*billingpb.PriceTable, len(current))
+
+	for _, table := range current {
+		currentByRegion[table.Currency] = table
+	}
+
+	for _, table := range next {
+		item := &intPkg.PriceTableRegionDiff{Region: table.Currency}
+		currentTable, ok := currentByRegion[table.Currency]
+
+		if !ok {
+			item.Added = true
+			currentTable = &billingpb.PriceTable{}
+		}
+
+		delete(currentByRegion, table.Currency)
+
+		for i, rng := range table.Ranges {
+			rngDiff := &intPkg.PriceTableRangeDiff{
+				Position: rng.Position,
+				NewFrom:  rng.From,
+				NewTo:    rng.To,
+			}
+
+			if i < len(currentTable.Ranges) {
+				rngDiff.CurrentFrom = currentTable.Ranges[i].From
+				rngDiff.CurrentTo = currentTable.Ranges[i].To
+			}
+
+			if rngDiff.CurrentTo > 0 {
+				rngDiff.ChangePercent = tools.ToPrecise((rngDiff.NewTo - rngDiff.CurrentTo) / rngDiff.CurrentTo * 100)
+			}
+
+			item.Ranges = append(item.Ranges, rngDiff)
+		}
+
+		diff = append(diff, item)
+	}
+
+	for region := range currentByRegion {
+		diff = append(diff, &intPkg.PriceTableRegionDiff{Region: region, Removed: true})
+	}
+
+	return diff
+}
+
+func (s *Service) mapPriceTableToVersionTable(table *billingpb.PriceTable) *models.MgoPriceTable {
+	out := &models.MgoPriceTable{
+		Id:       primitive.NewObjectID(),
+		Currency: table.Currency,
+	}
+
+	for _, rng := range table.Ranges {
+		out.Ranges = append(out.Ranges, &models.MgoPriceTableRange{From: rng.From, To: rng.To, Position: rng.Position})
+	}
+
+	return out
+}
+
+func (s *Service) mapVersionTableToPriceTable(table *models.MgoPriceTable) *billingpb.PriceTable {
+	out := &billingpb.PriceTable{Currency: table.Currency}
+
+	for _, rng := range table.Ranges {
+		out.Ranges = append(out.Ranges, &billingpb.PriceTableRange{From: rng.From, To: rng.To, Position: rng.Position})
+	}
+
+	return out
+}
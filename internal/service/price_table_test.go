@@ -5,6 +5,8 @@ import (
 	"github.com/paysuper/paysuper-billing-server/internal/config"
 	"github.com/paysuper/paysuper-billing-server/internal/database"
 	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/internal/repository/models"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
 	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
@@ -15,6 +17,7 @@ import (
 	"go.uber.org/zap"
 	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
 	"testing"
+	"time"
 )
 
 type PriceTableTestSuite struct {
@@ -109,7 +112,7 @@ func (suite *PriceTableTestSuite) TestPriceTable_GetByRegion_Error_NotFound() {
 }
 
 func (suite *PriceTableTestSuite) TestPriceTable_GetRecommendedPriceTable_Ok() {
-	rep := &mocks.PriceTableServiceInterface{}
+	rep := &mocks.PriceTableRepositoryInterface{}
 	rep.
 		On("GetByRegion", mock.Anything, mock.Anything).
 		Return(&billingpb.PriceTable{Ranges: []*billingpb.PriceTableRange{{From: 0, To: 0, Position: 0}}}, nil)
@@ -127,3 +130,211 @@ func (suite *PriceTableTestSuite) TestPriceTable_GetRecommendedPriceTable_Ok_Emp
 	assert.NoError(suite.T(), err)
 	assert.Len(suite.T(), res.Ranges, 0)
 }
+
+func (suite *PriceTableTestSuite) TestPriceTable_GeneratePriceTable_Ok() {
+	suite.createPriceTableFixtures()
+
+	res := &intPkg.PriceTableVersionResponse{}
+	err := suite.service.GeneratePriceTable(context.TODO(), &billingpb.EmptyRequest{}, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Equal(suite.T(), intPkg.PriceTableVersionStatusDraft, res.Item.Status)
+	assert.EqualValues(suite.T(), 2, res.Item.Version)
+	assert.Len(suite.T(), res.Item.Tables, 2)
+
+	for _, table := range res.Item.Tables {
+		assert.Len(suite.T(), table.Ranges, 2)
+
+		if table.Currency == "EUR" {
+			assert.InDelta(suite.T(), res.Item.Rates["EUR"]*0.5, table.Ranges[1].From, 0.01)
+			assert.Equal(suite.T(), table.Ranges[0].To, table.Ranges[1].From)
+		}
+	}
+
+	active, err := suite.service.priceTableVersionRepository.GetLastByStatus(context.TODO(), intPkg.PriceTableVersionStatusActive)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 1, active.Version)
+	assert.Len(suite.T(), active.Tables, 2)
+}
+
+func (suite *PriceTableTestSuite) TestPriceTable_GeneratePriceTable_BaseTableNotFound() {
+	res := &intPkg.PriceTableVersionResponse{}
+	err := suite.service.GeneratePriceTable(context.TODO(), &billingpb.EmptyRequest{}, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, res.Status)
+	assert.Equal(suite.T(), priceTableErrorBaseTableNotFound, res.Message)
+}
+
+func (suite *PriceTableTestSuite) TestPriceTable_GetPriceTableDiff_Ok() {
+	suite.createPriceTableFixtures()
+
+	res := &intPkg.PriceTableVersionResponse{}
+	err := suite.service.GeneratePriceTable(context.TODO(), &billingpb.EmptyRequest{}, res)
+	assert.NoError(suite.T(), err)
+
+	diff := &intPkg.PriceTableDiffResponse{}
+	err = suite.service.GetPriceTableDiff(context.TODO(), &intPkg.PriceTableVersionRequest{}, diff)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, diff.Status)
+	assert.Equal(suite.T(), res.Item.Id, diff.Version.Id)
+	assert.Len(suite.T(), diff.Regions, 2)
+
+	for _, region := range diff.Regions {
+		assert.False(suite.T(), region.Added)
+		assert.False(suite.T(), region.Removed)
+		assert.Len(suite.T(), region.Ranges, 2)
+
+		if region.Region == "USD" {
+			assert.Zero(suite.T(), region.Ranges[1].ChangePercent)
+		}
+	}
+}
+
+func (suite *PriceTableTestSuite) TestPriceTable_GetPriceTableDiff_VersionNotFound() {
+	diff := &intPkg.PriceTableDiffResponse{}
+	err := suite.service.GetPriceTableDiff(context.TODO(), &intPkg.PriceTableVersionRequest{}, diff)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, diff.Status)
+	assert.Equal(suite.T(), priceTableErrorVersionNotFound, diff.Message)
+}
+
+func (suite *PriceTableTestSuite) TestPriceTable_ActivateAndRollbackPriceTable_Ok() {
+	suite.createPriceTableFixtures()
+
+	generated := &intPkg.PriceTableVersionResponse{}
+	err := suite.service.GeneratePriceTable(context.TODO(), &billingpb.EmptyRequest{}, generated)
+	assert.NoError(suite.T(), err)
+
+	res := &intPkg.PriceTableVersionResponse{}
+	err = suite.service.ActivatePriceTable(context.TODO(), &intPkg.PriceTableVersionRequest{VersionId: generated.Item.Id.Hex()}, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Equal(suite.T(), intPkg.PriceTableVersionStatusActive, res.Item.Status)
+
+	table, err := suite.service.priceTableRepository.GetByRegion(context.TODO(), "EUR")
+	assert.NoError(suite.T(), err)
+
+	for _, generatedTable := range generated.Item.Tables {
+		if generatedTable.Currency == "EUR" {
+			assert.Equal(suite.T(), generatedTable.Ranges[1].To, table.Ranges[1].To)
+		}
+	}
+
+	res = &intPkg.PriceTableVersionResponse{}
+	err = suite.service.ActivatePriceTable(context.TODO(), &intPkg.PriceTableVersionRequest{VersionId: generated.Item.Id.Hex()}, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), priceTableErrorVersionNotDraft, res.Message)
+
+	res = &intPkg.PriceTableVersionResponse{}
+	err = suite.service.RollbackPriceTable(context.TODO(), &billingpb.EmptyRequest{}, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.EqualValues(suite.T(), 1, res.Item.Version)
+
+	table, err = suite.service.priceTableRepository.GetByRegion(context.TODO(), "EUR")
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 20, table.Ranges[1].To)
+
+	res = &intPkg.PriceTableVersionResponse{}
+	err = suite.service.RollbackPriceTable(context.TODO(), &billingpb.EmptyRequest{}, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, res.Status)
+	assert.Equal(suite.T(), priceTableErrorPreviousVersionNotFound, res.Message)
+}
+
+func (suite *PriceTableTestSuite) TestPriceTable_ActivatePriceTable_RemovesMissingRegions() {
+	suite.createPriceTableFixtures()
+
+	generated := &intPkg.PriceTableVersionResponse{}
+	err := suite.service.GeneratePriceTable(context.TODO(), &billingpb.EmptyRequest{}, generated)
+	assert.NoError(suite.T(), err)
+
+	for _, table := range generated.Item.Tables {
+		if table.Currency == "USD" {
+			generated.Item.Tables = []*models.MgoPriceTable{table}
+			break
+		}
+	}
+
+	err = suite.service.priceTableVersionRepository.Update(context.TODO(), generated.Item)
+	assert.NoError(suite.T(), err)
+
+	res := &intPkg.PriceTableVersionResponse{}
+	err = suite.service.ActivatePriceTable(context.TODO(), &intPkg.PriceTableVersionRequest{VersionId: generated.Item.Id.Hex()}, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+
+	tables, err := suite.service.priceTableRepository.GetAll(context.TODO())
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), tables, 1)
+	assert.Equal(suite.T(), "USD", tables[0].Currency)
+
+	res = &intPkg.PriceTableVersionResponse{}
+	err = suite.service.RollbackPriceTable(context.TODO(), &billingpb.EmptyRequest{}, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+
+	tables, err = suite.service.priceTableRepository.GetAll(context.TODO())
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), tables, 2)
+}
+
+func (suite *PriceTableTestSuite) TestPriceTable_getActivePriceTableVersion_CompletesInterruptedSwitch() {
+	suite.createPriceTableFixtures()
+
+	generated := &intPkg.PriceTableVersionResponse{}
+	err := suite.service.GeneratePriceTable(context.TODO(), &billingpb.EmptyRequest{}, generated)
+	assert.NoError(suite.T(), err)
+
+	// the switch is interrupted after the status of the version is changed
+	generated.Item.Status = intPkg.PriceTableVersionStatusActive
+	generated.Item.ActivatedAt = time.Now()
+	err = suite.service.priceTableVersionRepository.Update(context.TODO(), generated.Item)
+	assert.NoError(suite.T(), err)
+
+	active, err := suite.service.getActivePriceTableVersion(context.TODO())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), generated.Item.Id, active.Id)
+
+	count, err := suite.service.priceTableVersionRepository.CountByStatus(context.TODO(), intPkg.PriceTableVersionStatusActive)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 1, count)
+
+	table, err := suite.service.priceTableRepository.GetByRegion(context.TODO(), "EUR")
+	assert.NoError(suite.T(), err)
+
+	for _, generatedTable := range generated.Item.Tables {
+		if generatedTable.Currency == "EUR" {
+			assert.Equal(suite.T(), generatedTable.Ranges[1].To, table.Ranges[1].To)
+		}
+	}
+}
+
+func (suite *PriceTableTestSuite) createPriceTableFixtures() {
+	suite.service.cfg.PriceTableBaseRegion = "USD"
+	suite.service.cfg.PriceTablePurchasingPowerCoefficients = map[string]float64{"EUR": 0.5}
+
+	groups := []*billingpb.PriceGroup{
+		{Id: primitive.NewObjectID().Hex(), Region: "USD", Currency: "USD", IsActive: true},
+		{Id: primitive.NewObjectID().Hex(), Region: "EUR", Currency: "EUR", IsActive: true},
+	}
+	assert.NoError(suite.T(), suite.service.priceGroupRepository.MultipleInsert(context.TODO(), groups))
+
+	tables := []*billingpb.PriceTable{
+		{
+			Id:       primitive.NewObjectID().Hex(),
+			Currency: "USD",
+			Ranges:   []*billingpb.PriceTableRange{{From: 0, To: 1, Position: 0}, {From: 1, To: 2, Position: 1}},
+		},
+		{
+			Id:       primitive.NewObjectID().Hex(),
+			Currency: "EUR",
+			Ranges:   []*billingpb.PriceTableRange{{From: 0, To: 10, Position: 0}, {From: 10, To: 20, Position: 1}},
+		},
+	}
+
+	for _, table := range tables {
+		assert.NoError(suite.T(), suite.service.priceTableRepository.Insert(context.TODO(), table))
+	}
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"github.com/golang/protobuf/ptypes"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	tools "github.com/paysuper/paysuper-tools/number"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	pricingRuleThresholdDefaultTolerance = 0.05
+)
+
+var (
+	pricingRuleErrorPriceGroupNotFound = newBillingServerErrorMsg("pr000001", "price group not found")
+	pricingRuleErrorUnknownType        = newBillingServerErrorMsg("pr000002", "unknown pricing rule type")
+	pricingRuleErrorInvalidValue       = newBillingServerErrorMsg("pr000003", "pricing rule value is invalid")
+	pricingRuleErrorThresholdsEmpty    = newBillingServerErrorMsg("pr000004", "thresholds of pricing rule must be set")
+	pricingRuleErrorNotFound           = newBillingServerErrorMsg("pr000005", "pricing rules for price group not found")
+	pricingRuleErrorFillMethodUnknown  = newBillingServerErrorMsg("pr000006", "unknown method of product prices filling")
+	pricingRuleErrorFillAmountInvalid  = newBillingServerErrorMsg("pr000007", "amount for product prices filling must be greater than zero")
+)
+
+// GetPriceGroupPricingRules returns the chain of the pricing rules of the price group.
+func (s *Service) GetPriceGroupPricingRules(
+	ctx context.Context,
+	req *intPkg.GetPricingRulesRequest,
+	res *intPkg.PricingRulesResponse,
+) error {
+	rules, err := s.pricingRuleRepository.GetByPriceGroupId(ctx, req.PriceGroupId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = pricingRuleErrorNotFound
+		return nil
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = rules
+
+	return nil
+}
+
+// SetPriceGroupPricingRules replaces the chain of the pricing rules of the price group.
+// An empty chain restores the default price group behaviour based on the price group fraction.
+func (s *Service) SetPriceGroupPricingRules(
+	ctx context.Context,
+	req *intPkg.SetPricingRulesRequest,
+	res *intPkg.PricingRulesResponse,
+) error {
+	group, err := s.priceGroupRepository.GetById(ctx, req.PriceGroupId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = pricingRuleErrorPriceGroupNotFound
+		return nil
+	}
+
+	for _, rule := range req.Rules {
+		if msg := s.validatePricingRule(rule); msg != nil {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = msg
+			return nil
+		}
+	}
+
+	oid, _ := primitive.ObjectIDFromHex(group.Id)
+	rules, err := s.pricingRuleRepository.GetByPriceGroupId(ctx, group.Id)
+
+	if err != nil {
+		rules = &intPkg.PriceGroupPricingRules{
+			Id:           primitive.NewObjectID(),
+			PriceGroupId: oid,
+			CreatedAt:    time.Now(),
+		}
+	}
+
+	rules.Rules = req.Rules
+	rules.UpdatedAt = time.Now()
+
+	if err = s.pricingRuleRepository.Upsert(ctx, rules); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = rules
+
+	return nil
+}
+
+// FillProductPrices calculates the product prices for all price groups regions by the passed amount
+// using the recommended prices and saves them to the product.
+func (s *Service) FillProductPrices(
+	ctx context.Context,
+	req *intPkg.FillProductPricesRequest,
+	res *intPkg.FillProductPricesResponse,
+) error {
+	if req.Amount <= 0 {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = pricingRuleErrorFillAmountInvalid
+		return nil
+	}
+
+	product, err := s.productRepository.GetById(ctx, req.ProductId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = productErrorNotFound
+		return nil
+	}
+
+	if req.MerchantId != product.MerchantId {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = productErrorMerchantNotEqual
+		return nil
+	}
+
+	recommended := &billingpb.RecommendedPriceResponse{}
+	recommendedReq := &billingpb.RecommendedPriceRequest{Currency: req.Currency, Amount: req.Amount}
+
+	switch req.Method {
+	case intPkg.ProductPricesFillMethodPriceGroup, "":
+		err = s.GetRecommendedPriceByPriceGroup(ctx, recommendedReq, recommended)
+	case intPkg.ProductPricesFillMethodConversion:
+		err = s.GetRecommendedPriceByConversion(ctx, recommendedReq, recommended)
+	default:
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = pricingRuleErrorFillMethodUnknown
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool)
+	filled := make(map[string]bool)
+
+	for _, price := range product.Prices {
+		existing[price.Region] = true
+	}
+
+	for _, price := range recommended.RecommendedPrice {
+		filled[price.Region] = true
+	}
+
+	var prices []*billingpb.ProductPrice
+
+	// the overwrite replaces the prices of the filled regions only, the prices of other regions are kept
+	for _, price := range product.Prices {
+		if req.Overwrite && price.IsVirtualCurrency == false && filled[price.Region] {
+			continue
+		}
+
+		prices = append(prices, price)
+	}
+
+	for _, price := range recommended.RecommendedPrice {
+		if existing[price.Region] && req.Overwrite == false {
+			continue
+		}
+
+		prices = append(prices, &billingpb.ProductPrice{
+			Amount:   price.Amount,
+			Region:   price.Region,
+			Currency: price.Currency,
+		})
+	}
+
+	product.Prices = prices
+	product.UpdatedAt = ptypes.TimestampNow()
+
+	if err = s.productRepository.Upsert(ctx, product); err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String("product_id", product.Id),
+		)
+		return productErrorPricesUpdate
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Prices = prices
+
+	return nil
+}
+
+// getPricingRules returns the pricing rules of all price groups mapped by the price group identity.
+func (s *Service) getPricingRules(ctx context.Context) (map[string]*intPkg.PriceGroupPricingRules, error) {
+	list, err := s.pricingRuleRepository.GetAll(ctx)
+
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	rules := make(map[string]*intPkg.PriceGroupPricingRules, len(list))
+
+	for _, item := range list {
+		rules[item.PriceGroupId.Hex()] = item
+	}
+
+	return rules, nil
+}
+
+// applyPriceGroupRules applies the chain of the pricing rules of the price group to the price.
+// If the price group has no pricing rules then the price group fraction is applied.
+// The result is clamped once after the whole chain: the price moved to the threshold never exceeds it, even if
+// the following rules of the chain raise the price, and the price is never less than the minimum of the chain.
+// The minimum wins if it is above the threshold.
+func (s *Service) applyPriceGroupRules(
+	group *billingpb.PriceGroup,
+	rules map[string]*intPkg.PriceGroupPricingRules,
+	price float64,
+) float64 {
+	groupRules, ok := rules[group.Id]
+
+	if !ok || len(groupRules.Rules) <= 0 {
+		return s.calculatePriceWithFraction(group.Fraction, price)
+	}
+
+	ceiling := math.Inf(1)
+	floor := float64(0)
+
+	for _, rule := range groupRules.Rules {
+		if threshold, ok := s.getPricingRuleThreshold(rule, price); ok {
+			ceiling = math.Min(ceiling, threshold)
+		}
+
+		if rule.Type == intPkg.PricingRuleTypeMinimum {
+			floor = math.Max(floor, rule.Value)
+		}
+
+		price = s.applyPricingRule(rule, price)
+	}
+
+	return math.Max(math.Min(s.FormatAmount(price, group.Currency), ceiling), floor)
+}
+
+func (s *Service) applyPricingRule(rule *intPkg.PricingRule, price float64) float64 {
+	switch rule.Type {
+	case intPkg.PricingRuleTypeFraction:
+		i := math.Floor(price)
+		result := tools.ToPrecise(i + rule.Value)
+
+		if result < tools.ToPrecise(price) {
+			result = tools.ToPrecise(i + 1 + rule.Value)
+		}
+
+		return result
+	case intPkg.PricingRuleTypeRoundTo:
+		return math.Ceil(tools.ToPrecise(price/rule.Value)) * rule.Value
+	case intPkg.PricingRuleTypeThreshold:
+		if threshold, ok := s.getPricingRuleThreshold(rule, price); ok {
+			return threshold
+		}
+
+		return price
+	case intPkg.PricingRuleTypeMinimum:
+		return math.Max(price, rule.Value)
+	}
+
+	return price
+}
+
+// getPricingRuleThreshold returns the threshold of the threshold pricing rule which the price is moved down to.
+func (s *Service) getPricingRuleThreshold(rule *intPkg.PricingRule, price float64) (float64, bool) {
+	if rule.Type != intPkg.PricingRuleTypeThreshold {
+		return 0, false
+	}
+
+	tolerance := rule.Value
+
+	if tolerance <= 0 {
+		tolerance = pricingRuleThresholdDefaultTolerance
+	}
+
+	thresholds := make([]float64, len(rule.Thresholds))
+	copy(thresholds, rule.Thresholds)
+	sort.Float64s(thresholds)
+
+	for _, threshold := range thresholds {
+		if price >= threshold && price <= threshold*(1+tolerance) {
+			return threshold, true
+		}
+	}
+
+	return 0, false
+}
+
+func (s *Service) validatePricingRule(rule *intPkg.PricingRule) *billingpb.ResponseErrorMessage {
+	switch rule.Type {
+	case intPkg.PricingRuleTypeFraction:
+		if rule.Value < 0 || rule.Value >= 1 {
+			return pricingRuleErrorInvalidValue
+		}
+	case intPkg.PricingRuleTypeRoundTo:
+		if rule.Value <= 0 {
+			return pricingRuleErrorInvalidValue
+		}
+	case intPkg.PricingRuleTypeThreshold:
+		if rule.Value < 0 || rule.Value >= 1 {
+			return pricingRuleErrorInvalidValue
+		}
+
+		if len(rule.Thresholds) <= 0 {
+			return pricingRuleErrorThresholdsEmpty
+		}
+	case intPkg.PricingRuleTypeMinimum:
+		if rule.Value < 0 {
+			return pricingRuleErrorInvalidValue
+		}
+	default:
+		return pricingRuleErrorUnknownType
+	}
+
+	return nil
+}
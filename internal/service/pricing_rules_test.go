@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+)
+
+type PricingRulesTestSuite struct {
+	suite.Suite
+	service    *Service
+	cache      database.CacheInterface
+	priceGroup *billingpb.PriceGroup
+}
+
+func Test_PricingRules(t *testing.T) {
+	suite.Run(t, new(PricingRulesTestSuite))
+}
+
+func (suite *PricingRulesTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	suite.priceGroup = &billingpb.PriceGroup{
+		Id:       primitive.NewObjectID().Hex(),
+		Currency: "JPY",
+		Region:   "JPY",
+		Fraction: 0,
+		IsActive: true,
+	}
+
+	if err := suite.service.priceGroupRepository.Insert(context.TODO(), suite.priceGroup); err != nil {
+		suite.FailNow("Insert price group test data failed", "%v", err)
+	}
+}
+
+func (suite *PricingRulesTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *PricingRulesTestSuite) TestPricingRules_applyPricingRule_Fraction() {
+	rule := &intPkg.PricingRule{Type: intPkg.PricingRuleTypeFraction, Value: 0.99}
+	assert.Equal(suite.T(), 1.99, suite.service.applyPricingRule(rule, 1.01))
+	assert.Equal(suite.T(), 1.99, suite.service.applyPricingRule(rule, 1.99))
+	assert.Equal(suite.T(), 4.99, suite.service.applyPricingRule(rule, 3.999))
+}
+
+func (suite *PricingRulesTestSuite) TestPricingRules_applyPricingRule_RoundTo() {
+	rule := &intPkg.PricingRule{Type: intPkg.PricingRuleTypeRoundTo, Value: 100}
+	assert.Equal(suite.T(), float64(1300), suite.service.applyPricingRule(rule, 1201))
+	assert.Equal(suite.T(), float64(1200), suite.service.applyPricingRule(rule, 1200))
+}
+
+func (suite *PricingRulesTestSuite) TestPricingRules_applyPricingRule_Threshold() {
+	rule := &intPkg.PricingRule{Type: intPkg.PricingRuleTypeThreshold, Value: 0.1, Thresholds: []float64{1999, 999}}
+	assert.Equal(suite.T(), float64(999), suite.service.applyPricingRule(rule, 1000))
+	assert.Equal(suite.T(), float64(1999), suite.service.applyPricingRule(rule, 2100))
+	assert.Equal(suite.T(), float64(1500), suite.service.applyPricingRule(rule, 1500))
+	assert.Equal(suite.T(), float64(950), suite.service.applyPricingRule(rule, 950))
+}
+
+func (suite *PricingRulesTestSuite) TestPricingRules_applyPricingRule_Minimum() {
+	rule := &intPkg.PricingRule{Type: intPkg.PricingRuleTypeMinimum, Value: 100}
+	assert.Equal(suite.T(), float64(100), suite.service.applyPricingRule(rule, 10))
+	assert.Equal(suite.T(), float64(110), suite.service.applyPricingRule(rule, 110))
+}
+
+func (suite *PricingRulesTestSuite) TestPricingRules_applyPriceGroupRules_WithoutRules() {
+	price := suite.service.applyPriceGroupRules(suite.priceGroup, map[string]*intPkg.PriceGroupPricingRules{}, 1.21)
+	assert.Equal(suite.T(), float64(2), price)
+}
+
+func (suite *PricingRulesTestSuite) TestPricingRules_SetPriceGroupPricingRules_Ok() {
+	req := &intPkg.SetPricingRulesRequest{
+		PriceGroupId: suite.priceGroup.Id,
+		Rules: []*intPkg.PricingRule{
+			{Type: intPkg.PricingRuleTypeRoundTo, Value: 100},
+			{Type: intPkg.PricingRuleTypeThreshold, Thresholds: []float64{999, 1999}},
+			{Type: intPkg.PricingRuleTypeMinimum, Value: 300},
+		},
+	}
+	res := &intPkg.PricingRulesResponse{}
+	err := suite.service.SetPriceGroupPricingRules(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Len(suite.T(), res.Item.Rules, 3)
+
+	rules, err := suite.service.getPricingRules(context.TODO())
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), rules, 1)
+	assert.Equal(suite.T(), float64(999), suite.service.applyPriceGroupRules(suite.priceGroup, rules, 950))
+	assert.Equal(suite.T(), float64(300), suite.service.applyPriceGroupRules(suite.priceGroup, rules, 120))
+	assert.Equal(suite.T(), float64(1500), suite.service.applyPriceGroupRules(suite.priceGroup, rules, 1401))
+
+	res2 := &intPkg.PricingRulesResponse{}
+	err = suite.service.GetPriceGroupPricingRules(context.TODO(), &intPkg.GetPricingRulesRequest{PriceGroupId: suite.priceGroup.Id}, res2)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res2.Status)
+	assert.Equal(suite.T(), res.Item.Id, res2.Item.Id)
+}
+
+func (suite *PricingRulesTestSuite) TestPricingRules_applyPriceGroupRules_ThresholdNeverExceeded() {
+	rules := map[string]*intPkg.PriceGroupPricingRules{
+		suite.priceGroup.Id: {
+			Rules: []*intPkg.PricingRule{
+				{Type: intPkg.PricingRuleTypeThreshold, Thresholds: []float64{999, 1999}},
+				{Type: intPkg.PricingRuleTypeRoundTo, Value: 100},
+				{Type: intPkg.PricingRuleTypeFraction, Value: 0.5},
+				{Type: intPkg.PricingRuleTypeMinimum, Value: 500},
+			},
+		},
+	}
+
+	for _, price := range []float64{999, 1000, 1020, 1048} {
+		assert.Equal(suite.T(), float64(999), suite.service.applyPriceGroupRules(suite.priceGroup, rules, price))
+	}
+
+	assert.Equal(suite.T(), float64(1999), suite.service.applyPriceGroupRules(suite.priceGroup, rules, 2010))
+	assert.Equal(suite.T(), float64(500), suite.service.applyPriceGroupRules(suite.priceGroup, rules, 300))
+}
+
+func (suite *PricingRulesTestSuite) TestPricingRules_applyPriceGroupRules_MinimumAboveThreshold() {
+	rules := map[string]*intPkg.PriceGroupPricingRules{
+		suite.priceGroup.Id: {
+			Rules: []*intPkg.PricingRule{
+				{Type: intPkg.PricingRuleTypeThreshold, Thresholds: []float64{999, 1999}},
+				{Type: intPkg.PricingRuleTypeRoundTo, Value: 100},
+				{Type: intPkg.PricingRuleTypeMinimum, Value: 1200},
+			},
+		},
+	}
+
+	// the price is moved to the threshold bucket below the minimum, the minimum wins
+	for _, price := range []float64{999, 1000, 1020, 1048} {
+		assert.Equal(suite.T(), float64(1200), suite.service.applyPriceGroupRules(suite.priceGroup, rules, price))
+	}
+
+	assert.Equal(suite.T(), float64(1999), suite.service.applyPriceGroupRules(suite.priceGroup, rules, 2010))
+	assert.Equal(suite.T(), float64(1200), suite.service.applyPriceGroupRules(suite.priceGroup, rules, 300))
+}
+
+func (suite *PricingRulesTestSuite) TestPricingRules_SetPriceGroupPricingRules_InvalidRule() {
+	req := &intPkg.SetPricingRulesRequest{
+		PriceGroupId: suite.priceGroup.Id,
+		Rules:        []*intPkg.PricingRule{{Type: intPkg.PricingRuleTypeFraction, Value: 1.5}},
+	}
+	res := &intPkg.PricingRulesResponse{}
+	err := suite.service.SetPriceGroupPricingRules(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), pricingRuleErrorInvalidValue, res.Message)
+
+	req.Rules = []*intPkg.PricingRule{{Type: "unknown"}}
+	err = suite.service.SetPriceGroupPricingRules(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pricingRuleErrorUnknownType, res.Message)
+}
+
+func (suite *PricingRulesTestSuite) TestPricingRules_SetPriceGroupPricingRules_PriceGroupNotFound() {
+	req := &intPkg.SetPricingRulesRequest{PriceGroupId: primitive.NewObjectID().Hex()}
+	res := &intPkg.PricingRulesResponse{}
+	err := suite.service.SetPriceGroupPricingRules(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, res.Status)
+	assert.Equal(suite.T(), pricingRuleErrorPriceGroupNotFound, res.Message)
+}
+
+func (suite *PricingRulesTestSuite) TestPricingRules_FillProductPrices_AmountInvalid() {
+	res := &intPkg.FillProductPricesResponse{}
+	err := suite.service.FillProductPrices(context.TODO(), &intPkg.FillProductPricesRequest{Currency: "USD"}, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), pricingRuleErrorFillAmountInvalid, res.Message)
+}
+
+func (suite *PricingRulesTestSuite) TestPricingRules_FillProductPrices_ProductNotFound() {
+	req := &intPkg.FillProductPricesRequest{ProductId: primitive.NewObjectID().Hex(), Currency: "USD", Amount: 10}
+	res := &intPkg.FillProductPricesResponse{}
+	err := suite.service.FillProductPrices(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, res.Status)
+	assert.Equal(suite.T(), productErrorNotFound, res.Message)
+}
+
+func (suite *PricingRulesTestSuite) TestPricingRules_FillProductPrices_OverwriteKeepsOtherRegions() {
+	table := &billingpb.PriceTable{
+		Id:       primitive.NewObjectID().Hex(),
+		Currency: "JPY",
+		Ranges:   []*billingpb.PriceTableRange{{From: 0, To: 100, Position: 0}, {From: 100, To: 200, Position: 1}},
+	}
+	err := suite.service.priceTableRepository.Insert(context.TODO(), table)
+	assert.NoError(suite.T(), err)
+
+	product := &billingpb.Product{
+		Id:         primitive.NewObjectID().Hex(),
+		MerchantId: primitive.NewObjectID().Hex(),
+		ProjectId:  primitive.NewObjectID().Hex(),
+		Sku:        "fill_prices_sku",
+		Name:       map[string]string{"en": "Product"},
+		Enabled:    true,
+		Prices: []*billingpb.ProductPrice{
+			{Amount: 10, Region: "USD", Currency: "USD"},
+			{Amount: 1, Region: "JPY", Currency: "JPY"},
+		},
+	}
+	err = suite.service.productRepository.Upsert(context.TODO(), product)
+	assert.NoError(suite.T(), err)
+
+	req := &intPkg.FillProductPricesRequest{
+		MerchantId: product.MerchantId,
+		ProductId:  product.Id,
+		Currency:   "JPY",
+		Amount:     50,
+		Overwrite:  true,
+	}
+	res := &intPkg.FillProductPricesResponse{}
+	err = suite.service.FillProductPrices(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Len(suite.T(), res.Prices, 2)
+
+	for _, price := range res.Prices {
+		switch price.Region {
+		case "USD":
+			assert.EqualValues(suite.T(), 10, price.Amount)
+		case "JPY":
+			assert.NotEqual(suite.T(), float64(1), price.Amount)
+		default:
+			assert.Fail(suite.T(), "unexpected region of price", price.Region)
+		}
+	}
+}
@@ -313,6 +313,18 @@ func (s *Service) ProcessRefundCallback(
 			if err != nil {
 				zap.S().Errorf("Update order data failed", "err", err.Error(), "order", order)
 			}
+
+			err = s.onGiftRefundNotify(ctx, order)
+
+			if err != nil {
+				zap.L().Error(
+					pkg.MethodFinishedWithError,
+					zap.String("method", "onGiftRefundNotify"),
+					zap.Error(err),
+					zap.String("refundId", refund.Id),
+					zap.String("orderId", order.Id),
+				)
+			}
 		}
 
 		err = s.onRefundNotify(ctx, refund, order)
@@ -0,0 +1,368 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/internal/repository"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"time"
+)
+
+const (
+	rollingReserveDateFormat    = "2006-01-02"
+	rollingReserveCreateReason  = "rolling reserve held until %s"
+	rollingReserveReleaseReason = "release of rolling reserve held for period %s - %s"
+)
+
+var (
+	rollingReserveErrorMerchantNotFound = newBillingServerErrorMsg("rv000001", "merchant not found")
+	rollingReserveErrorAccessDenied     = newBillingServerErrorMsg("rv000002", "only admin or financier can change the rolling reserve policy")
+	rollingReserveErrorPolicyInvalid    = newBillingServerErrorMsg("rv000003", "rolling reserve policy requires hold days and percent from 0 to 100 or cap")
+	rollingReserveErrorPolicyNotFound   = newBillingServerErrorMsg("rv000004", "rolling reserve policy not found")
+)
+
+// SetRollingReservePolicy creates or replaces the rolling reserve policy of the merchant.
+// The policy is applied to the royalty reports generated after the change.
+func (s *Service) SetRollingReservePolicy(
+	ctx context.Context,
+	req *intPkg.SetRollingReservePolicyRequest,
+	res *intPkg.RollingReservePolicyResponse,
+) error {
+	if !s.hasAdminRole(ctx, req.UserId, billingpb.RoleSystemAdmin, billingpb.RoleSystemFinancial) {
+		res.Status = billingpb.ResponseStatusForbidden
+		res.Message = rollingReserveErrorAccessDenied
+		return nil
+	}
+
+	merchant, err := s.merchantRepository.GetById(ctx, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = rollingReserveErrorMerchantNotFound
+		return nil
+	}
+
+	if req.Percent < 0 || req.Percent > 100 || req.Cap < 0 || req.HoldDays <= 0 || (req.Percent == 0 && req.Cap == 0) {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = rollingReserveErrorPolicyInvalid
+		return nil
+	}
+
+	now := time.Now()
+	policy, err := s.rollingReservePolicyRepository.GetByMerchantId(ctx, merchant.Id)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		oid, _ := primitive.ObjectIDFromHex(merchant.Id)
+		policy = &intPkg.RollingReservePolicy{
+			Id:         primitive.NewObjectID(),
+			MerchantId: oid,
+			CreatedAt:  now,
+		}
+	}
+
+	policy.Percent = req.Percent
+	policy.HoldDays = req.HoldDays
+	policy.Cap = s.FormatAmount(req.Cap, merchant.GetPayoutCurrency())
+	policy.Enabled = req.Enabled
+	policy.UpdatedBy = req.UserId
+	policy.UpdatedAt = now
+
+	if err = s.rollingReservePolicyRepository.Upsert(ctx, policy); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = policy
+
+	return nil
+}
+
+// GetRollingReservePolicy returns the rolling reserve policy of the merchant.
+func (s *Service) GetRollingReservePolicy(
+	ctx context.Context,
+	req *intPkg.GetRollingReservePolicyRequest,
+	res *intPkg.RollingReservePolicyResponse,
+) error {
+	policy, err := s.rollingReservePolicyRepository.GetByMerchantId(ctx, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = rollingReserveErrorPolicyNotFound
+		return nil
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = policy
+
+	return nil
+}
+
+// GetMerchantBalanceDetails returns the merchant balance with the release schedule of the held rolling reserves
+// and the next expected payout by the payout schedule.
+func (s *Service) GetMerchantBalanceDetails(
+	ctx context.Context,
+	req *billingpb.GetMerchantBalanceRequest,
+	res *intPkg.GetMerchantBalanceDetailsResponse,
+) error {
+	balanceRes := &billingpb.GetMerchantBalanceResponse{}
+
+	if err := s.GetMerchantBalance(ctx, req, balanceRes); err != nil {
+		return err
+	}
+
+	if balanceRes.Status != billingpb.ResponseStatusOk {
+		res.Status = balanceRes.Status
+		res.Message = balanceRes.Message
+		return nil
+	}
+
+	schedule, err := s.rollingReserveRepository.FindByMerchantId(
+		ctx,
+		req.MerchantId,
+		balanceRes.Item.Currency,
+		intPkg.RollingReserveStatusHeld,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if schedule == nil {
+		schedule = []*intPkg.RollingReserve{}
+	}
+
+	merchant, err := s.merchantRepository.GetById(ctx, req.MerchantId)
+
+	if err != nil {
+		return err
+	}
+
+	nextPayout, err := s.getMerchantNextPayout(ctx, merchant, balanceRes.Item)
+
+	if err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = &intPkg.MerchantBalanceDetails{
+		Balance:                balanceRes.Item,
+		RollingReserveSchedule: schedule,
+		NextPayout:             nextPayout,
+	}
+
+	return nil
+}
+
+// ReleaseRollingReserves releases the held rolling reserves which hold period is over.
+// The failed release of the reserve doesn't stop the release of the other reserves, it is repeated by the next run.
+func (s *Service) ReleaseRollingReserves(
+	ctx context.Context,
+	_ *billingpb.EmptyRequest,
+	_ *billingpb.EmptyResponse,
+) error {
+	reserves, err := s.rollingReserveRepository.FindToRelease(ctx, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	for _, reserve := range reserves {
+		if err = s.releaseRollingReserve(ctx, reserve); err != nil {
+			zap.L().Error(
+				"Rolling reserve release failed",
+				zap.Error(err),
+				zap.String("rolling_reserve_id", reserve.Id.Hex()),
+				zap.String("merchant_id", reserve.MerchantId.Hex()),
+			)
+		}
+	}
+
+	return nil
+}
+
+// releaseRollingReserve claims the held reserve by the conditional update before the release entry is written,
+// so the reserve released concurrently by the other run is skipped instead of the second release entry.
+func (s *Service) releaseRollingReserve(ctx context.Context, reserve *intPkg.RollingReserve) error {
+	reason := fmt.Sprintf(
+		rollingReserveReleaseReason,
+		reserve.PeriodFrom.Format(rollingReserveDateFormat),
+		reserve.PeriodTo.Format(rollingReserveDateFormat),
+	)
+
+	return repository.WithTransaction(ctx, s.db, func(ctx context.Context) error {
+		now := time.Now()
+		reserve.Status = intPkg.RollingReserveStatusReleased
+		reserve.ReleaseEntryId = primitive.NewObjectID().Hex()
+		reserve.ReleasedAt = now
+		reserve.UpdatedAt = now
+
+		if err := s.rollingReserveRepository.Release(ctx, reserve); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil
+			}
+			return err
+		}
+
+		_, err := s.createRollingReserveEntry(
+			ctx,
+			pkg.AccountingEntryTypeMerchantRollingReserveRelease,
+			reserve.ReleaseEntryId,
+			reserve.MerchantId.Hex(),
+			reserve.Currency,
+			reserve.Amount,
+			reason,
+			now,
+		)
+
+		return err
+	})
+}
+
+// createRollingReserve holds the rolling reserve of the royalty period by the merchant policy.
+// The reserve is held once per period, so the regenerated report keeps the reserve of the first generation.
+// The cap of the policy is set in the main payout currency, so it applies to the reserves of that currency only.
+func (h *royaltyHandler) createRollingReserve(
+	ctx context.Context,
+	merchant *billingpb.Merchant,
+	currency string,
+	reportId string,
+	grossRevenue float64,
+) error {
+	policy, err := h.rollingReservePolicyRepository.GetByMerchantId(ctx, merchant.Id)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return err
+	}
+
+	if !policy.Enabled {
+		return nil
+	}
+
+	_, err = h.rollingReserveRepository.GetByMerchantPeriod(ctx, merchant.Id, currency, h.from)
+
+	if err == nil {
+		return nil
+	}
+
+	if err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	held, err := h.rollingReserveRepository.FindByMerchantId(ctx, merchant.Id, currency, intPkg.RollingReserveStatusHeld)
+
+	if err != nil {
+		return err
+	}
+
+	if currency != merchant.GetPayoutCurrency() {
+		policy.Cap = 0
+	}
+
+	amount := h.getRollingReserveAmount(policy, grossRevenue, held, currency)
+
+	if amount.Sign() <= 0 {
+		return nil
+	}
+
+	releaseAt := h.to.AddDate(0, 0, int(policy.HoldDays))
+	entryId, err := h.createRollingReserveEntry(
+		ctx,
+		pkg.AccountingEntryTypeMerchantRollingReserveCreate,
+		"",
+		merchant.Id,
+		currency,
+		amount.Float64(),
+		fmt.Sprintf(rollingReserveCreateReason, releaseAt.Format(rollingReserveDateFormat)),
+		h.to.Add(-1*time.Second),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	reserve := &intPkg.RollingReserve{
+		Id:              primitive.NewObjectID(),
+		MerchantId:      policy.MerchantId,
+		Currency:        currency,
+		RoyaltyReportId: reportId,
+		PeriodFrom:      h.from,
+		PeriodTo:        h.to,
+		Amount:          amount.Float64(),
+		Status:          intPkg.RollingReserveStatusHeld,
+		CreateEntryId:   entryId,
+		ReleaseAt:       releaseAt,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	return h.rollingReserveRepository.Insert(ctx, reserve)
+}
+
+// getRollingReserveAmount returns the percent of the gross revenue limited by the cap minus already held reserves.
+// The policy without percent holds the whole cap.
+func (s *Service) getRollingReserveAmount(
+	policy *intPkg.RollingReservePolicy,
+	grossRevenue float64,
+	held []*intPkg.RollingReserve,
+	currency string,
+) intPkg.Money {
+	amount := s.newMoney(grossRevenue, currency).Mul(policy.Percent / 100)
+
+	if policy.Cap <= 0 {
+		return amount.Round()
+	}
+
+	available := s.newMoney(policy.Cap, currency)
+
+	for _, reserve := range held {
+		available = available.Sub(s.newMoney(reserve.Amount, currency))
+	}
+
+	if policy.Percent == 0 || amount.Cmp(available) > 0 {
+		amount = available
+	}
+
+	return amount.Round()
+}
+
+func (s *Service) createRollingReserveEntry(
+	ctx context.Context,
+	entryType, entryId, merchantId, currency string,
+	amount float64,
+	reason string,
+	date time.Time,
+) (string, error) {
+	req := &billingpb.CreateAccountingEntryRequest{
+		Type:       entryType,
+		MerchantId: merchantId,
+		Amount:     amount,
+		Currency:   currency,
+		Reason:     reason,
+		Date:       date.Unix(),
+		Status:     pkg.BalanceTransactionStatusAvailable,
+	}
+	res := &billingpb.CreateAccountingEntryResponse{}
+
+	if err := s.createAccountingEntryWithId(ctx, req, res, entryId); err != nil {
+		return "", err
+	}
+
+	if res.Status != billingpb.ResponseStatusOk {
+		return "", res.Message
+	}
+
+	return res.Item.Id, nil
+}
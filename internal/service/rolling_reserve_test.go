@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/mongodb"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	rabbitmq "gopkg.in/ProtocolONE/rabbitmq.v1/pkg"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type RollingReserveTestSuite struct {
+	suite.Suite
+	service    *Service
+	log        *zap.Logger
+	cache      database.CacheInterface
+	httpClient *http.Client
+
+	merchant *billingpb.Merchant
+	admin    *billingpb.UserRole
+}
+
+func Test_RollingReserve(t *testing.T) {
+	suite.Run(t, new(RollingReserveTestSuite))
+}
+
+func (suite *RollingReserveTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+	cfg.CardPayApiUrl = "https://sandbox.cardpay.com"
+
+	m, err := migrate.New(
+		"file://../../migrations/tests",
+		cfg.MongoDsn)
+	assert.NoError(suite.T(), err, "Migrate init failed")
+
+	err = m.Up()
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	suite.log, err = zap.NewProduction()
+
+	if err != nil {
+		suite.FailNow("Logger initialization failed", "%v", err)
+	}
+
+	broker, err := rabbitmq.NewBroker(cfg.BrokerAddress)
+
+	if err != nil {
+		suite.FailNow("Creating RabbitMQ publisher failed", "%v", err)
+	}
+
+	redisClient := database.NewRedis(
+		&redis.Options{
+			Addr:     cfg.RedisHost,
+			Password: cfg.RedisPassword,
+		},
+	)
+
+	redisdb := mocks.NewTestRedis()
+	suite.httpClient = mocks.NewClientStatusOk()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		broker,
+		redisClient,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	operatingCompany := HelperOperatingCompany(suite.Suite, suite.service)
+
+	suite.merchant = HelperCreateMerchant(suite.Suite, suite.service, "RUB", "RU", nil, 13000, operatingCompany.Id)
+
+	suite.admin = &billingpb.UserRole{
+		Id:     primitive.NewObjectID().Hex(),
+		UserId: primitive.NewObjectID().Hex(),
+		Role:   billingpb.RoleSystemFinancial,
+	}
+
+	if err := suite.service.userRoleRepository.AddAdminUser(context.TODO(), suite.admin); err != nil {
+		suite.FailNow("Insert admin user failed", "%v", err)
+	}
+}
+
+func (suite *RollingReserveTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *RollingReserveTestSuite) setPolicy(percent, cap float64) {
+	req := &intPkg.SetRollingReservePolicyRequest{
+		MerchantId: suite.merchant.Id,
+		Percent:    percent,
+		HoldDays:   30,
+		Cap:        cap,
+		Enabled:    true,
+		UserId:     suite.admin.UserId,
+	}
+	res := &intPkg.RollingReservePolicyResponse{}
+	err := suite.service.SetRollingReservePolicy(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+}
+
+func (suite *RollingReserveTestSuite) newRoyaltyHandler(to time.Time) *royaltyHandler {
+	return &royaltyHandler{
+		Service: suite.service,
+		from:    to.AddDate(0, 0, -7),
+		to:      to,
+	}
+}
+
+func (suite *RollingReserveTestSuite) TestRollingReserve_SetRollingReservePolicy_AccessDenied() {
+	req := &intPkg.SetRollingReservePolicyRequest{
+		MerchantId: suite.merchant.Id,
+		Percent:    10,
+		HoldDays:   30,
+		UserId:     primitive.NewObjectID().Hex(),
+	}
+	res := &intPkg.RollingReservePolicyResponse{}
+	err := suite.service.SetRollingReservePolicy(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+	assert.Equal(suite.T(), rollingReserveErrorAccessDenied, res.Message)
+}
+
+func (suite *RollingReserveTestSuite) TestRollingReserve_SetRollingReservePolicy_Invalid() {
+	req := &intPkg.SetRollingReservePolicyRequest{
+		MerchantId: suite.merchant.Id,
+		Percent:    110,
+		HoldDays:   30,
+		UserId:     suite.admin.UserId,
+	}
+	res := &intPkg.RollingReservePolicyResponse{}
+	err := suite.service.SetRollingReservePolicy(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), rollingReserveErrorPolicyInvalid, res.Message)
+}
+
+func (suite *RollingReserveTestSuite) TestRollingReserve_CreateRollingReserve_Percent() {
+	suite.setPolicy(10, 0)
+
+	handler := suite.newRoyaltyHandler(time.Now().Add(-time.Hour))
+	err := handler.createRollingReserve(context.TODO(), suite.merchant, suite.merchant.GetPayoutCurrency(), primitive.NewObjectID().Hex(), 1000)
+	assert.NoError(suite.T(), err)
+
+	// the reserve of the period is held once
+	err = handler.createRollingReserve(context.TODO(), suite.merchant, suite.merchant.GetPayoutCurrency(), primitive.NewObjectID().Hex(), 2000)
+	assert.NoError(suite.T(), err)
+
+	reserves, err := suite.service.rollingReserveRepository.FindByMerchantId(
+		context.TODO(), suite.merchant.Id, "RUB", intPkg.RollingReserveStatusHeld,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), reserves, 1)
+	assert.EqualValues(suite.T(), 100, reserves[0].Amount)
+	assert.Equal(suite.T(), handler.to.AddDate(0, 0, 30).Unix(), reserves[0].ReleaseAt.Unix())
+	assert.NotEmpty(suite.T(), reserves[0].CreateEntryId)
+
+	entries, err := suite.service.accountingRepository.FindByIds(context.TODO(), []string{reserves[0].CreateEntryId})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), entries, 1)
+	assert.Equal(suite.T(), pkg.AccountingEntryTypeMerchantRollingReserveCreate, entries[0].Type)
+	assert.EqualValues(suite.T(), 100, entries[0].Amount)
+}
+
+func (suite *RollingReserveTestSuite) TestRollingReserve_CreateRollingReserve_Cap() {
+	suite.setPolicy(10, 150)
+
+	handler := suite.newRoyaltyHandler(time.Now().AddDate(0, 0, -7))
+	err := handler.createRollingReserve(context.TODO(), suite.merchant, suite.merchant.GetPayoutCurrency(), primitive.NewObjectID().Hex(), 1000)
+	assert.NoError(suite.T(), err)
+
+	handler = suite.newRoyaltyHandler(time.Now().Add(-time.Hour))
+	err = handler.createRollingReserve(context.TODO(), suite.merchant, suite.merchant.GetPayoutCurrency(), primitive.NewObjectID().Hex(), 1000)
+	assert.NoError(suite.T(), err)
+
+	reserves, err := suite.service.rollingReserveRepository.FindByMerchantId(
+		context.TODO(), suite.merchant.Id, "RUB", intPkg.RollingReserveStatusHeld,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), reserves, 2)
+	assert.EqualValues(suite.T(), 100, reserves[0].Amount)
+	assert.EqualValues(suite.T(), 50, reserves[1].Amount)
+}
+
+func (suite *RollingReserveTestSuite) TestRollingReserve_ReleaseRollingReserves_Ok() {
+	suite.setPolicy(10, 0)
+
+	handler := suite.newRoyaltyHandler(time.Now().AddDate(0, 0, -31))
+	err := handler.createRollingReserve(context.TODO(), suite.merchant, suite.merchant.GetPayoutCurrency(), primitive.NewObjectID().Hex(), 1000)
+	assert.NoError(suite.T(), err)
+
+	req := &billingpb.GetMerchantBalanceRequest{MerchantId: suite.merchant.Id}
+	res := &intPkg.GetMerchantBalanceDetailsResponse{}
+	err = suite.service.GetMerchantBalanceDetails(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.EqualValues(suite.T(), 100, res.Item.Balance.RollingReserve)
+	assert.Len(suite.T(), res.Item.RollingReserveSchedule, 1)
+
+	err = suite.service.ReleaseRollingReserves(context.TODO(), &billingpb.EmptyRequest{}, &billingpb.EmptyResponse{})
+	assert.NoError(suite.T(), err)
+
+	reserves, err := suite.service.rollingReserveRepository.FindByMerchantId(
+		context.TODO(), suite.merchant.Id, "RUB", intPkg.RollingReserveStatusReleased,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), reserves, 1)
+	assert.NotEmpty(suite.T(), reserves[0].ReleaseEntryId)
+
+	res = &intPkg.GetMerchantBalanceDetailsResponse{}
+	err = suite.service.GetMerchantBalanceDetails(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.EqualValues(suite.T(), 0, res.Item.Balance.RollingReserve)
+	assert.Empty(suite.T(), res.Item.RollingReserveSchedule)
+}
+
+func (suite *RollingReserveTestSuite) TestRollingReserve_ReleaseRollingReserve_ReleasedOnce() {
+	suite.setPolicy(10, 0)
+
+	handler := suite.newRoyaltyHandler(time.Now().AddDate(0, 0, -31))
+	err := handler.createRollingReserve(context.TODO(), suite.merchant, suite.merchant.GetPayoutCurrency(), primitive.NewObjectID().Hex(), 1000)
+	assert.NoError(suite.T(), err)
+
+	reserves, err := suite.service.rollingReserveRepository.FindToRelease(context.TODO(), time.Now())
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), reserves, 1)
+
+	stale := *reserves[0]
+	err = suite.service.releaseRollingReserve(context.TODO(), reserves[0])
+	assert.NoError(suite.T(), err)
+
+	// the reserve released by the concurrent run is skipped
+	err = suite.service.releaseRollingReserve(context.TODO(), &stale)
+	assert.NoError(suite.T(), err)
+
+	released, err := suite.service.rollingReserveRepository.FindByMerchantId(
+		context.TODO(), suite.merchant.Id, "RUB", intPkg.RollingReserveStatusReleased,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), released, 1)
+	assert.Equal(suite.T(), reserves[0].ReleaseEntryId, released[0].ReleaseEntryId)
+
+	req := &billingpb.GetMerchantBalanceRequest{MerchantId: suite.merchant.Id}
+	res := &intPkg.GetMerchantBalanceDetailsResponse{}
+	err = suite.service.GetMerchantBalanceDetails(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.EqualValues(suite.T(), 0, res.Item.Balance.RollingReserve)
+}
+
+func (suite *RollingReserveTestSuite) TestRollingReserve_ReleaseRollingReserves_ContinueAfterFailure() {
+	suite.setPolicy(10, 0)
+
+	handler := suite.newRoyaltyHandler(time.Now().AddDate(0, 0, -31))
+	err := handler.createRollingReserve(context.TODO(), suite.merchant, suite.merchant.GetPayoutCurrency(), primitive.NewObjectID().Hex(), 1000)
+	assert.NoError(suite.T(), err)
+
+	now := time.Now()
+	unknown := &intPkg.RollingReserve{
+		Id:         primitive.NewObjectID(),
+		MerchantId: primitive.NewObjectID(),
+		Currency:   "RUB",
+		PeriodFrom: now.AddDate(0, -3, 0),
+		PeriodTo:   now.AddDate(0, -2, 0),
+		Amount:     10,
+		Status:     intPkg.RollingReserveStatusHeld,
+		ReleaseAt:  now.AddDate(0, -1, 0),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	err = suite.service.rollingReserveRepository.Insert(context.TODO(), unknown)
+	assert.NoError(suite.T(), err)
+
+	err = suite.service.ReleaseRollingReserves(context.TODO(), &billingpb.EmptyRequest{}, &billingpb.EmptyResponse{})
+	assert.NoError(suite.T(), err)
+
+	reserves, err := suite.service.rollingReserveRepository.FindByMerchantId(
+		context.TODO(), suite.merchant.Id, "RUB", intPkg.RollingReserveStatusReleased,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), reserves, 1)
+	assert.NotEmpty(suite.T(), reserves[0].ReleaseEntryId)
+}
@@ -9,14 +9,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/golang/protobuf/ptypes"
-	"github.com/jinzhu/now"
 	pkg2 "github.com/paysuper/paysuper-billing-server/internal/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	"github.com/paysuper/paysuper-proto/go/postmarkpb"
 	"github.com/paysuper/paysuper-proto/go/recurringpb"
 	"github.com/paysuper/paysuper-proto/go/reporterpb"
-	tools "github.com/paysuper/paysuper-tools/number"
 	"github.com/streadway/amqp"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -36,7 +34,6 @@ var (
 	royaltyReportErrorCorrectionReasonRequired        = newBillingServerErrorMsg("rr00003", "correction reason required")
 	royaltyReportEntryErrorUnknown                    = newBillingServerErrorMsg("rr00004", "unknown error. try request later")
 	royaltyReportUpdateBalanceError                   = newBillingServerErrorMsg("rr00005", "update balance failed")
-	royaltyReportErrorEndOfPeriodIsInFuture           = newBillingServerErrorMsg("rr00006", "end of royalty report period is in future")
 	royaltyReportErrorTimezoneIncorrect               = newBillingServerErrorMsg("rr00007", "incorrect time zone")
 	royaltyReportErrorAlreadyExistsAndCannotBeUpdated = newBillingServerErrorMsg("rr00008", "report for this merchant and period already exists and can not be updated")
 	royaltyReportErrorCorrectionAmountRequired        = newBillingServerErrorMsg("rr00009", "correction amount required and must be not zero")
@@ -53,10 +50,15 @@ var (
 
 type royaltyHandler struct {
 	*Service
-	from time.Time
-	to   time.Time
+	from      time.Time
+	to        time.Time
+	merchants []primitive.ObjectID
+	// regeneration is set when the royalty report is regenerated on demand
+	regeneration *royaltyReportRegeneration
 }
 
+// CreateRoyaltyReport generates the royalty reports of the merchants whose royalty report period is closed.
+// Every merchant has own period, so the merchants are grouped by the periods.
 func (s *Service) CreateRoyaltyReport(
 	ctx context.Context,
 	req *billingpb.CreateRoyaltyReportRequest,
@@ -64,19 +66,20 @@ func (s *Service) CreateRoyaltyReport(
 ) error {
 	zap.L().Info("start royalty reports processing")
 
-	loc, err := time.LoadLocation(s.cfg.RoyaltyReportTimeZone)
+	_, err := time.LoadLocation(s.cfg.RoyaltyReportTimeZone)
 
 	if err != nil {
 		zap.L().Error(royaltyReportErrorTimezoneIncorrect.Error(), zap.Error(err))
 		return royaltyReportErrorTimezoneIncorrect
 	}
 
-	to := now.Monday().In(loc).Add(time.Duration(s.cfg.RoyaltyReportPeriodEndHour) * time.Hour)
-	if to.After(time.Now().In(loc)) {
-		return royaltyReportErrorEndOfPeriodIsInFuture
-	}
+	reportTime := time.Now()
+	merchantsSince, err := s.getRoyaltyReportMerchantsSince(reportTime)
 
-	from := to.Add(-time.Duration(s.cfg.RoyaltyReportPeriod) * time.Second).Add(1 * time.Millisecond).In(loc)
+	if err != nil {
+		zap.L().Error(royaltyReportErrorTimezoneIncorrect.Error(), zap.Error(err))
+		return err
+	}
 
 	var merchants []*pkg2.RoyaltyReportMerchant
 
@@ -91,7 +94,12 @@ func (s *Service) CreateRoyaltyReport(
 			merchants = append(merchants, &pkg2.RoyaltyReportMerchant{Id: oid})
 		}
 	} else {
-		merchants, _ = s.orderViewRepository.GetRoyaltyForMerchants(ctx, orderStatusForRoyaltyReports, from, to)
+		merchants, _ = s.orderViewRepository.GetRoyaltyForMerchants(
+			ctx,
+			orderStatusForRoyaltyReports,
+			merchantsSince,
+			reportTime,
+		)
 	}
 
 	if len(merchants) <= 0 {
@@ -99,25 +107,69 @@ func (s *Service) CreateRoyaltyReport(
 		return nil
 	}
 
-	handler := &royaltyHandler{
-		Service: s,
-		from:    from,
-		to:      to,
-	}
+	var handlers []*royaltyHandler
+	periods := make(map[string]*royaltyHandler)
 
 	for _, v := range merchants {
-		err := handler.createMerchantRoyaltyReport(ctx, v.Id)
+		from, to, ok, err := s.getMerchantRoyaltyReportPeriod(ctx, v.Id.Hex(), reportTime)
 
-		if err == nil {
-			rsp.Merchants = append(rsp.Merchants, v.Id.Hex())
-		} else {
+		if err != nil {
 			zap.L().Error(
 				pkg.ErrorRoyaltyReportGenerationFailed,
 				zap.Error(err),
 				zap.String(pkg.ErrorRoyaltyReportFieldMerchantId, v.Id.Hex()),
-				zap.Any(pkg.ErrorRoyaltyReportFieldFrom, from),
-				zap.Any(pkg.ErrorRoyaltyReportFieldTo, to),
 			)
+			continue
+		}
+
+		if !ok {
+			continue
+		}
+
+		key := fmt.Sprintf("%d-%d", from.UnixNano(), to.UnixNano())
+		handler, ok := periods[key]
+
+		if !ok {
+			handler = &royaltyHandler{
+				Service: s,
+				from:    from,
+				to:      to,
+			}
+			periods[key] = handler
+			handlers = append(handlers, handler)
+		}
+
+		handler.merchants = append(handler.merchants, v.Id)
+	}
+
+	for _, handler := range handlers {
+		if len(req.Merchants) <= 0 {
+			// the reports are generated only for the merchants with the transactions in the period
+			if err = handler.filterMerchantsWithTransactions(ctx); err != nil {
+				zap.L().Error(
+					pkg.ErrorRoyaltyReportGenerationFailed,
+					zap.Error(err),
+					zap.Any(pkg.ErrorRoyaltyReportFieldFrom, handler.from),
+					zap.Any(pkg.ErrorRoyaltyReportFieldTo, handler.to),
+				)
+				continue
+			}
+		}
+
+		for _, merchantId := range handler.merchants {
+			err := handler.createMerchantRoyaltyReport(ctx, merchantId)
+
+			if err == nil {
+				rsp.Merchants = append(rsp.Merchants, merchantId.Hex())
+			} else {
+				zap.L().Error(
+					pkg.ErrorRoyaltyReportGenerationFailed,
+					zap.Error(err),
+					zap.String(pkg.ErrorRoyaltyReportFieldMerchantId, merchantId.Hex()),
+					zap.Any(pkg.ErrorRoyaltyReportFieldFrom, handler.from),
+					zap.Any(pkg.ErrorRoyaltyReportFieldTo, handler.to),
+				)
+			}
 		}
 	}
 
@@ -138,6 +190,17 @@ func (s *Service) AutoAcceptRoyaltyReports(
 	}
 
 	for _, report := range reports {
+		hasDispute, err := s.hasOpenRoyaltyReportDispute(ctx, report.Id)
+
+		if err != nil {
+			return err
+		}
+
+		if hasDispute {
+			continue
+		}
+
+		previousStatus := report.Status
 		report.Status = billingpb.RoyaltyReportStatusAccepted
 		report.AcceptedAt = ptypes.TimestampNow()
 		report.UpdatedAt = ptypes.TimestampNow()
@@ -148,6 +211,11 @@ func (s *Service) AutoAcceptRoyaltyReports(
 			return err
 		}
 
+		err = s.applyRoyaltyReportToBalanceLedger(ctx, report, previousStatus)
+		if err != nil {
+			return err
+		}
+
 		_, err = s.updateMerchantBalance(ctx, report.MerchantId)
 		if err != nil {
 			return err
@@ -237,6 +305,8 @@ func (s *Service) MerchantReviewRoyaltyReport(
 		return nil
 	}
 
+	previousStatus := report.Status
+
 	if req.IsAccepted == true {
 		report.Status = billingpb.RoyaltyReportStatusAccepted
 		report.AcceptedAt = ptypes.TimestampNow()
@@ -259,6 +329,22 @@ func (s *Service) MerchantReviewRoyaltyReport(
 		return err
 	}
 
+	if err = s.applyRoyaltyReportToBalanceLedger(ctx, report, previousStatus); err != nil {
+		return err
+	}
+
+	if !req.IsAccepted {
+		lines := []*pkg2.RoyaltyReportDisputeLine{{
+			Type:   pkg2.RoyaltyReportDisputeLineTypeReport,
+			Reason: req.DisputeReason,
+		}}
+		dispute := s.newRoyaltyReportDispute(report, "", lines)
+
+		if err = s.royaltyReportDisputeRepository.Insert(ctx, dispute); err != nil {
+			return err
+		}
+	}
+
 	if req.IsAccepted {
 		_, err = s.updateMerchantBalance(ctx, report.MerchantId)
 		if err != nil {
@@ -346,6 +432,7 @@ func (s *Service) ChangeRoyaltyReport(
 	}
 
 	hasChanges := false
+	previousStatus := report.Status
 
 	if report.Status == billingpb.RoyaltyReportStatusDispute && req.Correction != nil {
 
@@ -363,59 +450,17 @@ func (s *Service) ChangeRoyaltyReport(
 			return nil
 		}
 
-		from, err := ptypes.Timestamp(report.PeriodFrom)
-		if err != nil {
-			zap.L().Error("time conversion error", zap.Error(err))
-			rsp.Status = billingpb.ResponseStatusSystemError
-			rsp.Message = royaltyReportEntryErrorUnknown
-			return nil
-		}
-		to, err := ptypes.Timestamp(report.PeriodTo)
-		if err != nil {
-			zap.L().Error("time conversion error", zap.Error(err))
-			rsp.Status = billingpb.ResponseStatusSystemError
-			rsp.Message = royaltyReportEntryErrorUnknown
-			return nil
-		}
-
-		reqAe := &billingpb.CreateAccountingEntryRequest{
-			MerchantId: report.MerchantId,
-			Amount:     req.Correction.Amount,
-			Currency:   report.Currency,
-			Reason:     req.Correction.Reason,
-			Date:       to.Add(-1 * time.Second).Unix(),
-			Type:       pkg.AccountingEntryTypeMerchantRoyaltyCorrection,
-		}
-		resAe := &billingpb.CreateAccountingEntryResponse{}
-		err = s.CreateAccountingEntry(ctx, reqAe, resAe)
-		if err != nil {
-			zap.L().Error("create correction accounting entry failed", zap.Error(err))
-			rsp.Status = billingpb.ResponseStatusSystemError
-			rsp.Message = royaltyReportEntryErrorUnknown
-			return nil
-		}
-		if resAe.Status != billingpb.ResponseStatusOk {
-			zap.L().Error("create correction accounting entry failed")
-			rsp.Status = billingpb.ResponseStatusSystemError
-			rsp.Message = royaltyReportEntryErrorUnknown
-			return nil
-		}
-
-		if report.Totals == nil {
-			report.Totals = &billingpb.RoyaltyReportTotals{}
-		}
-		if report.Summary == nil {
-			report.Summary = &billingpb.RoyaltyReportSummary{}
-		}
+		// the request of the admin console has no user, so the correction is approved on behalf of the admin source
+		_, err = s.addRoyaltyReportCorrection(
+			ctx,
+			report,
+			req.Correction.Amount,
+			req.Correction.Reason,
+			pkg.RoyaltyReportChangeSourceAdmin,
+			pkg.RoyaltyReportChangeSourceAdmin,
+		)
 
-		handler := &royaltyHandler{
-			Service: s,
-			from:    from,
-			to:      to,
-		}
-		report.Summary.Corrections, report.Totals.CorrectionAmount, err = handler.getRoyaltyReportCorrections(ctx, report.MerchantId, report.Currency)
 		if err != nil {
-			zap.L().Error("get royalty report corrections error", zap.Error(err))
 			rsp.Status = billingpb.ResponseStatusSystemError
 			rsp.Message = royaltyReportEntryErrorUnknown
 			return nil
@@ -427,6 +472,10 @@ func (s *Service) ChangeRoyaltyReport(
 	if req.Status != "" && req.Status != report.Status {
 		if report.Status == billingpb.RoyaltyReportStatusDispute {
 			report.DisputeClosedAt = ptypes.TimestampNow()
+
+			if err = s.closeRoyaltyReportDispute(ctx, report.Id); err != nil {
+				return err
+			}
 		}
 
 		if req.Status == billingpb.RoyaltyReportStatusAccepted {
@@ -455,6 +504,10 @@ func (s *Service) ChangeRoyaltyReport(
 		return err
 	}
 
+	if err = s.applyRoyaltyReportToBalanceLedger(ctx, report, previousStatus); err != nil {
+		return err
+	}
+
 	s.sendRoyaltyReportNotification(ctx, report)
 
 	_, err = s.updateMerchantBalance(ctx, report.MerchantId)
@@ -467,6 +520,61 @@ func (s *Service) ChangeRoyaltyReport(
 	return nil
 }
 
+// addRoyaltyReportCorrection creates the approved correction at the end of the royalty report period
+// and recalculates the corrections of the report. The identifier of the created accounting entry is returned.
+func (s *Service) addRoyaltyReportCorrection(
+	ctx context.Context,
+	report *billingpb.RoyaltyReport,
+	amount float64,
+	reason, createdBy, approvedBy string,
+) (string, error) {
+	from, err := ptypes.Timestamp(report.PeriodFrom)
+	if err != nil {
+		zap.L().Error("time conversion error", zap.Error(err))
+		return "", err
+	}
+	to, err := ptypes.Timestamp(report.PeriodTo)
+	if err != nil {
+		zap.L().Error("time conversion error", zap.Error(err))
+		return "", err
+	}
+
+	correction, err := s.createApprovedAccountingCorrection(
+		ctx,
+		report.MerchantId,
+		report.Currency,
+		reason,
+		amount,
+		to.Add(-1*time.Second),
+		createdBy,
+		approvedBy,
+	)
+	if err != nil {
+		zap.L().Error("create correction accounting entry failed", zap.Error(err))
+		return "", err
+	}
+
+	if report.Totals == nil {
+		report.Totals = &billingpb.RoyaltyReportTotals{}
+	}
+	if report.Summary == nil {
+		report.Summary = &billingpb.RoyaltyReportSummary{}
+	}
+
+	handler := &royaltyHandler{
+		Service: s,
+		from:    from,
+		to:      to,
+	}
+	report.Summary.Corrections, report.Totals.CorrectionAmount, err = handler.getRoyaltyReportCorrections(ctx, report.MerchantId, report.Currency)
+	if err != nil {
+		zap.L().Error("get royalty report corrections error", zap.Error(err))
+		return "", err
+	}
+
+	return correction.AccountingEntryId, nil
+}
+
 func (s *Service) ListRoyaltyReportOrders(
 	ctx context.Context,
 	req *billingpb.ListRoyaltyReportOrdersRequest,
@@ -530,6 +638,8 @@ func (h *royaltyHandler) getRoyaltyReportCorrections(ctx context.Context, mercha
 		return
 	}
 
+	sum := h.newMoney(0, currency)
+
 	for _, e := range accountingEntries {
 		entries = append(entries, &billingpb.RoyaltyReportCorrectionItem{
 			AccountingEntryId: e.Id,
@@ -537,9 +647,11 @@ func (h *royaltyHandler) getRoyaltyReportCorrections(ctx context.Context, mercha
 			Reason:            e.Reason,
 			EntryDate:         e.CreatedAt,
 		})
-		total += e.Amount
+		sum = sum.Add(h.newMoney(e.Amount, currency))
 	}
 
+	total = sum.Round().Float64()
+
 	return
 }
 
@@ -559,6 +671,8 @@ func (h *royaltyHandler) getRoyaltyReportRollingReserves(
 		return
 	}
 
+	sum := h.newMoney(0, currency)
+
 	for _, e := range accountingEntries {
 		entries = append(entries, &billingpb.RoyaltyReportCorrectionItem{
 			AccountingEntryId: e.Id,
@@ -566,12 +680,41 @@ func (h *royaltyHandler) getRoyaltyReportRollingReserves(
 			Reason:            e.Reason,
 			EntryDate:         e.CreatedAt,
 		})
-		total += e.Amount
+		sum = sum.Add(h.newMoney(e.Amount, currency))
 	}
 
+	total = sum.Round().Float64()
+
 	return
 }
 
+// filterMerchantsWithTransactions leaves only the merchants with the transactions in the period of the handler.
+func (h *royaltyHandler) filterMerchantsWithTransactions(ctx context.Context) error {
+	merchants, err := h.orderViewRepository.GetRoyaltyForMerchants(ctx, orderStatusForRoyaltyReports, h.from, h.to)
+
+	if err != nil {
+		return err
+	}
+
+	active := make(map[primitive.ObjectID]bool, len(merchants))
+
+	for _, v := range merchants {
+		active[v.Id] = true
+	}
+
+	var filtered []primitive.ObjectID
+
+	for _, merchantId := range h.merchants {
+		if active[merchantId] {
+			filtered = append(filtered, merchantId)
+		}
+	}
+
+	h.merchants = filtered
+
+	return nil
+}
+
 func (h *royaltyHandler) createMerchantRoyaltyReport(ctx context.Context, merchantId primitive.ObjectID) error {
 	zap.L().Info("start generating royalty reports for merchant", zap.String("merchant_id", merchantId.Hex()))
 
@@ -580,31 +723,82 @@ func (h *royaltyHandler) createMerchantRoyaltyReport(ctx context.Context, mercha
 		return merchantErrorNotFound
 	}
 
-	existingReport := h.royaltyReportRepository.GetReportExists(ctx, merchant.Id, merchant.GetPayoutCurrency(), h.from, h.to)
-	if existingReport != nil && existingReport.Status != billingpb.RoyaltyReportStatusPending {
+	currencies, err := h.getMerchantPayoutCurrencies(ctx, merchant)
+	if err != nil {
+		return err
+	}
+
+	for _, currency := range currencies {
+		err = h.createMerchantCurrencyRoyaltyReport(ctx, merchant, currency, currency == merchant.GetPayoutCurrency())
+		if err != nil {
+			return err
+		}
+	}
+
+	zap.L().Info("generating royalty reports for merchant finished", zap.String("merchant_id", merchantId.Hex()))
+
+	return nil
+}
+
+// createMerchantCurrencyRoyaltyReport creates or updates the royalty report of the merchant in the payout currency.
+// The report in the main payout currency is always created, the reports in the additional payout currencies
+// are created only for the periods with the transactions or corrections. Every generation of the report is saved
+// as the new version and rendered to the own PDF file.
+func (h *royaltyHandler) createMerchantCurrencyRoyaltyReport(
+	ctx context.Context,
+	merchant *billingpb.Merchant,
+	currency string,
+	isMainCurrency bool,
+) error {
+	existingReport := h.royaltyReportRepository.GetReportExists(ctx, merchant.Id, currency, h.from, h.to)
+	if existingReport != nil && !h.canUpdateRoyaltyReport(existingReport) {
+		if !isMainCurrency {
+			return nil
+		}
 		return royaltyReportErrorAlreadyExistsAndCannotBeUpdated
 	}
 
-	summaryItems, summaryTotal, err := h.orderViewRepository.GetRoyaltySummary(ctx, merchant.Id, merchant.GetPayoutCurrency(), h.from, h.to)
+	summaryItems, summaryTotal, err := h.orderViewRepository.GetRoyaltySummary(ctx, merchant.Id, currency, h.from, h.to)
+	if err != nil {
+		return err
+	}
+
+	corrections, correctionsTotal, err := h.getRoyaltyReportCorrections(ctx, merchant.Id, currency)
 	if err != nil {
 		return err
 	}
 
-	corrections, correctionsTotal, err := h.getRoyaltyReportCorrections(ctx, merchant.Id, merchant.GetPayoutCurrency())
+	if !isMainCurrency && existingReport == nil && summaryTotal.TotalTransactions == 0 && len(corrections) == 0 {
+		return nil
+	}
+
+	reportId := primitive.NewObjectID().Hex()
+
+	if existingReport != nil {
+		reportId = existingReport.Id
+	}
+
+	err = h.createRollingReserve(ctx, merchant, currency, reportId, summaryTotal.GrossTotalAmount)
 	if err != nil {
 		return err
 	}
 
-	reserves, reservesTotal, err := h.getRoyaltyReportRollingReserves(ctx, merchant.Id, merchant.GetPayoutCurrency())
+	reserves, reservesTotal, err := h.getRoyaltyReportRollingReserves(ctx, merchant.Id, currency)
 	if err != nil {
 		return err
 	}
 
+	for _, item := range summaryItems {
+		h.royaltySummaryItemRound(item, currency)
+	}
+
+	h.royaltySummaryItemRound(summaryTotal, currency)
+
 	newReport := &billingpb.RoyaltyReport{
-		Id:                 primitive.NewObjectID().Hex(),
-		MerchantId:         merchantId.Hex(),
+		Id:                 reportId,
+		MerchantId:         merchant.Id,
 		OperatingCompanyId: merchant.OperatingCompanyId,
-		Currency:           merchant.GetPayoutCurrency(),
+		Currency:           currency,
 		Status:             billingpb.RoyaltyReportStatusPending,
 		CreatedAt:          ptypes.TimestampNow(),
 		UpdatedAt:          ptypes.TimestampNow(),
@@ -613,8 +807,8 @@ func (h *royaltyHandler) createMerchantRoyaltyReport(ctx context.Context, mercha
 			FeeAmount:            summaryTotal.TotalFees,
 			VatAmount:            summaryTotal.TotalVat,
 			PayoutAmount:         summaryTotal.PayoutAmount,
-			CorrectionAmount:     tools.ToPrecise(correctionsTotal),
-			RollingReserveAmount: tools.ToPrecise(reservesTotal),
+			CorrectionAmount:     correctionsTotal,
+			RollingReserveAmount: reservesTotal,
 		},
 		Summary: &billingpb.RoyaltyReportSummary{
 			ProductsItems:   summaryItems,
@@ -668,7 +862,13 @@ func (h *royaltyHandler) createMerchantRoyaltyReport(ctx context.Context, mercha
 			zap.String("operating_company_id", newReport.OperatingCompanyId),
 		)
 
-		err = h.royaltyReportRepository.Update(ctx, newReport, "", pkg.RoyaltyReportChangeSourceAuto)
+		ip, source := "", pkg.RoyaltyReportChangeSourceAuto
+
+		if h.regeneration != nil {
+			ip, source = h.regeneration.ip, pkg.RoyaltyReportChangeSourceAdmin
+		}
+
+		err = h.royaltyReportRepository.Update(ctx, newReport, ip, source)
 		if err != nil {
 			return err
 		}
@@ -685,22 +885,25 @@ func (h *royaltyHandler) createMerchantRoyaltyReport(ctx context.Context, mercha
 		}
 	}
 
-	err = h.Service.renderRoyaltyReport(ctx, newReport, merchant)
+	version, err := h.addRoyaltyReportVersion(ctx, newReport, existingReport)
 	if err != nil {
 		return err
 	}
 
-	zap.L().Info("generating royalty reports for merchant finished", zap.String("merchant_id", merchantId.Hex()))
-
-	return nil
+	return h.Service.renderRoyaltyReport(ctx, newReport, merchant, version.Version)
 }
 
+// renderRoyaltyReport requests the PDF file of the royalty report version from the reporting service.
 func (s *Service) renderRoyaltyReport(
 	ctx context.Context,
 	report *billingpb.RoyaltyReport,
 	merchant *billingpb.Merchant,
+	version int32,
 ) error {
-	params, err := json.Marshal(map[string]interface{}{reporterpb.ParamsFieldId: report.Id})
+	params, err := json.Marshal(map[string]interface{}{
+		reporterpb.ParamsFieldId:        report.Id,
+		royaltyReportParamsFieldVersion: version,
+	})
 	if err != nil {
 		zap.L().Error(
 			"Unable to marshal the params of royalty report for the reporting service.",
@@ -1009,3 +1212,21 @@ func (s *Service) royaltyReportChangedEmail(ctx context.Context, report *billing
 
 	return nil
 }
+
+// royaltySummaryItemRound rounds the amounts of the royalty report summary to the precision of the currency.
+// Totals are calculated from the rounded amounts, so the payout amount is always equal to the gross total
+// without fees and VAT.
+func (h *royaltyHandler) royaltySummaryItemRound(item *billingpb.RoyaltyReportProductSummaryItem, currency string) {
+	grossSales := h.newMoney(item.GrossSalesAmount, currency).Round()
+	grossReturns := h.newMoney(item.GrossReturnsAmount, currency).Round()
+	grossTotal := grossSales.Sub(grossReturns)
+	fees := h.newMoney(item.TotalFees, currency).Round()
+	vat := h.newMoney(item.TotalVat, currency).Round()
+
+	item.GrossSalesAmount = grossSales.Float64()
+	item.GrossReturnsAmount = grossReturns.Float64()
+	item.GrossTotalAmount = grossTotal.Float64()
+	item.TotalFees = fees.Float64()
+	item.TotalVat = vat.Float64()
+	item.PayoutAmount = grossTotal.Sub(fees, vat).Float64()
+}
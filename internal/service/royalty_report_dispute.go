@@ -0,0 +1,559 @@
+package service
+
+import (
+	"context"
+	"github.com/golang/protobuf/ptypes"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const (
+	royaltyReportDisputeCorrectionReason           = "royalty report dispute"
+	royaltyReportDisputeCommentNotificationMessage = "New comment on the dispute of your royalty report."
+)
+
+var (
+	royaltyReportDisputeErrorReportNotFound      = newBillingServerErrorMsg("rd000001", "royalty report not found")
+	royaltyReportDisputeErrorNotOwnedByMerchant  = newBillingServerErrorMsg("rd000002", "royalty report is not owned by merchant")
+	royaltyReportDisputeErrorReportStatusInvalid = newBillingServerErrorMsg("rd000003", "only pending royalty report can be disputed")
+	royaltyReportDisputeErrorLinesRequired       = newBillingServerErrorMsg("rd000004", "at least one disputed line required")
+	royaltyReportDisputeErrorLineInvalid         = newBillingServerErrorMsg("rd000005", "disputed line type is unknown or line reason is empty")
+	royaltyReportDisputeErrorOrderNotFound       = newBillingServerErrorMsg("rd000006", "disputed order not found in royalty report")
+	royaltyReportDisputeErrorSummaryNotFound     = newBillingServerErrorMsg("rd000007", "disputed summary line not found in royalty report")
+	royaltyReportDisputeErrorNotFound            = newBillingServerErrorMsg("rd000008", "royalty report dispute not found")
+	royaltyReportDisputeErrorClosed              = newBillingServerErrorMsg("rd000009", "royalty report dispute is closed")
+	royaltyReportDisputeErrorAccessDenied        = newBillingServerErrorMsg("rd000010", "only admin or financier can resolve the dispute and comment on behalf of financiers")
+	royaltyReportDisputeErrorLineNotFound        = newBillingServerErrorMsg("rd000011", "disputed line not found")
+	royaltyReportDisputeErrorLineResolved        = newBillingServerErrorMsg("rd000012", "disputed line is already resolved")
+	royaltyReportDisputeErrorResolutionInvalid   = newBillingServerErrorMsg("rd000013", "resolution of disputed line must be corrected or rejected")
+	royaltyReportDisputeErrorCorrectionRequired  = newBillingServerErrorMsg("rd000014", "correction amount required and must be not zero")
+	royaltyReportDisputeErrorCommentRequired     = newBillingServerErrorMsg("rd000015", "comment text or attachment required")
+
+	royaltyReportDisputeResolutions = map[string]bool{
+		intPkg.RoyaltyReportDisputeLineStatusCorrected: true,
+		intPkg.RoyaltyReportDisputeLineStatusRejected:  true,
+	}
+)
+
+// OpenRoyaltyReportDispute disputes the orders and the summary lines of the pending royalty report by the merchant.
+func (s *Service) OpenRoyaltyReportDispute(
+	ctx context.Context,
+	req *intPkg.OpenRoyaltyReportDisputeRequest,
+	res *intPkg.RoyaltyReportDisputeResponse,
+) error {
+	report, err := s.royaltyReportRepository.GetById(ctx, req.ReportId)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			res.Status = billingpb.ResponseStatusNotFound
+			res.Message = royaltyReportDisputeErrorReportNotFound
+			return nil
+		}
+
+		return err
+	}
+
+	if report.MerchantId != req.MerchantId {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = royaltyReportDisputeErrorNotOwnedByMerchant
+		return nil
+	}
+
+	if report.Status != billingpb.RoyaltyReportStatusPending {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = royaltyReportDisputeErrorReportStatusInvalid
+		return nil
+	}
+
+	if len(req.Lines) == 0 {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = royaltyReportDisputeErrorLinesRequired
+		return nil
+	}
+
+	for _, line := range req.Lines {
+		msg, err := s.validateRoyaltyReportDisputeLine(ctx, report, line)
+
+		if err != nil {
+			return err
+		}
+
+		if msg != nil {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = msg
+			return nil
+		}
+	}
+
+	dispute := s.newRoyaltyReportDispute(report, req.UserId, req.Lines)
+
+	if strings.TrimSpace(req.Comment) != "" || len(req.Attachments) > 0 {
+		dispute.Comments = append(dispute.Comments, &intPkg.RoyaltyReportDisputeComment{
+			Id:          primitive.NewObjectID().Hex(),
+			Author:      intPkg.RoyaltyReportDisputeAuthorMerchant,
+			UserId:      req.UserId,
+			Text:        req.Comment,
+			Attachments: req.Attachments,
+			CreatedAt:   dispute.CreatedAt,
+		})
+	}
+
+	previousStatus := report.Status
+	report.Status = billingpb.RoyaltyReportStatusDispute
+	report.DisputeReason = req.Comment
+	report.DisputeStartedAt = ptypes.TimestampNow()
+	report.UpdatedAt = ptypes.TimestampNow()
+
+	err = s.royaltyReportRepository.Update(ctx, report, req.Ip, pkg.RoyaltyReportChangeSourceMerchant)
+
+	if err != nil {
+		return err
+	}
+
+	if err = s.royaltyReportDisputeRepository.Insert(ctx, dispute); err != nil {
+		return err
+	}
+
+	if err = s.applyRoyaltyReportToBalanceLedger(ctx, report, previousStatus); err != nil {
+		return err
+	}
+
+	if err = s.royaltyReportChangedEmail(ctx, report); err != nil {
+		res.Status = billingpb.ResponseStatusSystemError
+		res.Message = err.(*billingpb.ResponseErrorMessage)
+		return nil
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = dispute
+
+	return nil
+}
+
+// AddRoyaltyReportDisputeComment adds the comment of the merchant or the financier to the thread of the dispute
+// or of the disputed line.
+func (s *Service) AddRoyaltyReportDisputeComment(
+	ctx context.Context,
+	req *intPkg.AddRoyaltyReportDisputeCommentRequest,
+	res *intPkg.RoyaltyReportDisputeResponse,
+) error {
+	dispute, err := s.royaltyReportDisputeRepository.GetById(ctx, req.DisputeId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = royaltyReportDisputeErrorNotFound
+		return nil
+	}
+
+	author := intPkg.RoyaltyReportDisputeAuthorFinancier
+
+	if req.MerchantId != "" {
+		if req.MerchantId != dispute.MerchantId {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = royaltyReportDisputeErrorNotOwnedByMerchant
+			return nil
+		}
+
+		author = intPkg.RoyaltyReportDisputeAuthorMerchant
+	} else if !s.hasAdminRole(ctx, req.UserId, billingpb.RoleSystemAdmin, billingpb.RoleSystemFinancial) {
+		res.Status = billingpb.ResponseStatusForbidden
+		res.Message = royaltyReportDisputeErrorAccessDenied
+		return nil
+	}
+
+	if dispute.Status != intPkg.RoyaltyReportDisputeStatusOpen {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = royaltyReportDisputeErrorClosed
+		return nil
+	}
+
+	if strings.TrimSpace(req.Text) == "" && len(req.Attachments) == 0 {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = royaltyReportDisputeErrorCommentRequired
+		return nil
+	}
+
+	if req.LineId != "" && dispute.GetLine(req.LineId) == nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = royaltyReportDisputeErrorLineNotFound
+		return nil
+	}
+
+	dispute.UpdatedAt = time.Now()
+	dispute.Comments = append(dispute.Comments, &intPkg.RoyaltyReportDisputeComment{
+		Id:          primitive.NewObjectID().Hex(),
+		LineId:      req.LineId,
+		Author:      author,
+		UserId:      req.UserId,
+		Text:        req.Text,
+		Attachments: req.Attachments,
+		CreatedAt:   dispute.UpdatedAt,
+	})
+
+	if err = s.royaltyReportDisputeRepository.Update(ctx, dispute); err != nil {
+		return err
+	}
+
+	s.notifyRoyaltyReportDisputeComment(ctx, dispute, author)
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = dispute
+
+	return nil
+}
+
+// ResolveRoyaltyReportDisputeLine resolves the disputed line by the correction of the royalty report
+// or the rejection. The dispute is closed and the report returns to the merchant for the review
+// when the last line is resolved.
+func (s *Service) ResolveRoyaltyReportDisputeLine(
+	ctx context.Context,
+	req *intPkg.ResolveRoyaltyReportDisputeLineRequest,
+	res *intPkg.RoyaltyReportDisputeResponse,
+) error {
+	if !s.hasAdminRole(ctx, req.UserId, billingpb.RoleSystemAdmin, billingpb.RoleSystemFinancial) {
+		res.Status = billingpb.ResponseStatusForbidden
+		res.Message = royaltyReportDisputeErrorAccessDenied
+		return nil
+	}
+
+	dispute, err := s.royaltyReportDisputeRepository.GetById(ctx, req.DisputeId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = royaltyReportDisputeErrorNotFound
+		return nil
+	}
+
+	if dispute.Status != intPkg.RoyaltyReportDisputeStatusOpen {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = royaltyReportDisputeErrorClosed
+		return nil
+	}
+
+	line := dispute.GetLine(req.LineId)
+
+	if line == nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = royaltyReportDisputeErrorLineNotFound
+		return nil
+	}
+
+	if line.Status != intPkg.RoyaltyReportDisputeLineStatusOpen {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = royaltyReportDisputeErrorLineResolved
+		return nil
+	}
+
+	if !royaltyReportDisputeResolutions[req.Resolution] {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = royaltyReportDisputeErrorResolutionInvalid
+		return nil
+	}
+
+	if req.Resolution == intPkg.RoyaltyReportDisputeLineStatusCorrected && req.CorrectionAmount == 0 {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = royaltyReportDisputeErrorCorrectionRequired
+		return nil
+	}
+
+	report, err := s.royaltyReportRepository.GetById(ctx, dispute.ReportId)
+
+	if err != nil {
+		return err
+	}
+
+	previousStatus := report.Status
+	isReportChanged := false
+
+	if req.Resolution == intPkg.RoyaltyReportDisputeLineStatusCorrected {
+		reason := royaltyReportDisputeCorrectionReason
+
+		if line.Reason != "" {
+			reason += ": " + line.Reason
+		}
+
+		line.AccountingEntryId, err = s.addRoyaltyReportCorrection(
+			ctx,
+			report,
+			req.CorrectionAmount,
+			reason,
+			dispute.CreatedBy,
+			req.UserId,
+		)
+
+		if err != nil {
+			return err
+		}
+
+		line.CorrectionAmount = req.CorrectionAmount
+		isReportChanged = true
+	}
+
+	resolvedAt := time.Now()
+	line.Status = req.Resolution
+	line.ResolutionComment = req.Comment
+	line.ResolvedBy = req.UserId
+	line.ResolvedAt = resolvedAt
+	dispute.UpdatedAt = resolvedAt
+
+	if strings.TrimSpace(req.Comment) != "" {
+		dispute.Comments = append(dispute.Comments, &intPkg.RoyaltyReportDisputeComment{
+			Id:        primitive.NewObjectID().Hex(),
+			LineId:    line.Id,
+			Author:    intPkg.RoyaltyReportDisputeAuthorFinancier,
+			UserId:    req.UserId,
+			Text:      req.Comment,
+			CreatedAt: resolvedAt,
+		})
+	}
+
+	if dispute.IsResolved() {
+		dispute.Status = intPkg.RoyaltyReportDisputeStatusClosed
+		dispute.ClosedAt = resolvedAt
+
+		if report.Status == billingpb.RoyaltyReportStatusDispute {
+			report.Status = billingpb.RoyaltyReportStatusPending
+			report.DisputeClosedAt = ptypes.TimestampNow()
+			report.AcceptExpireAt, err = ptypes.TimestampProto(
+				resolvedAt.Add(time.Duration(s.cfg.RoyaltyReportAcceptTimeout) * time.Second),
+			)
+
+			if err != nil {
+				return err
+			}
+
+			isReportChanged = true
+		}
+	}
+
+	if err = s.royaltyReportDisputeRepository.Update(ctx, dispute); err != nil {
+		return err
+	}
+
+	if isReportChanged {
+		report.UpdatedAt = ptypes.TimestampNow()
+
+		err = s.royaltyReportRepository.Update(ctx, report, req.Ip, pkg.RoyaltyReportChangeSourceAdmin)
+
+		if err != nil {
+			return err
+		}
+
+		if err = s.applyRoyaltyReportToBalanceLedger(ctx, report, previousStatus); err != nil {
+			return err
+		}
+
+		s.sendRoyaltyReportNotification(ctx, report)
+
+		if _, err = s.updateMerchantBalance(ctx, report.MerchantId); err != nil {
+			return err
+		}
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = dispute
+
+	return nil
+}
+
+// GetRoyaltyReportDisputes returns all disputes of the royalty report.
+func (s *Service) GetRoyaltyReportDisputes(
+	ctx context.Context,
+	req *intPkg.GetRoyaltyReportDisputesRequest,
+	res *intPkg.RoyaltyReportDisputesResponse,
+) error {
+	report, err := s.royaltyReportRepository.GetById(ctx, req.ReportId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = royaltyReportDisputeErrorReportNotFound
+		return nil
+	}
+
+	if req.MerchantId != "" && report.MerchantId != req.MerchantId {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = royaltyReportDisputeErrorNotOwnedByMerchant
+		return nil
+	}
+
+	res.Items, err = s.royaltyReportDisputeRepository.GetByReportId(ctx, report.Id)
+
+	if err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+
+	return nil
+}
+
+// validateRoyaltyReportDisputeLine checks that the disputed order or summary line belongs to the royalty report.
+// The disputed order must be the order of the report merchant paid in the report period in the report currency.
+func (s *Service) validateRoyaltyReportDisputeLine(
+	ctx context.Context,
+	report *billingpb.RoyaltyReport,
+	line *intPkg.RoyaltyReportDisputeLine,
+) (*billingpb.ResponseErrorMessage, error) {
+	if strings.TrimSpace(line.Reason) == "" {
+		return royaltyReportDisputeErrorLineInvalid, nil
+	}
+
+	switch line.Type {
+	case intPkg.RoyaltyReportDisputeLineTypeReport:
+		return nil, nil
+
+	case intPkg.RoyaltyReportDisputeLineTypeOrder:
+		if line.OrderId == "" {
+			return royaltyReportDisputeErrorOrderNotFound, nil
+		}
+
+		order, err := s.orderRepository.GetByUuid(ctx, line.OrderId)
+
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return royaltyReportDisputeErrorOrderNotFound, nil
+			}
+
+			return nil, err
+		}
+
+		if order.GetMerchantId() != report.MerchantId || order.GetMerchantRoyaltyCurrency() != report.Currency {
+			return royaltyReportDisputeErrorOrderNotFound, nil
+		}
+
+		closedAt, err := ptypes.Timestamp(order.PaymentMethodOrderClosedAt)
+
+		if err != nil {
+			return royaltyReportDisputeErrorOrderNotFound, nil
+		}
+
+		from, err := ptypes.Timestamp(report.PeriodFrom)
+
+		if err != nil {
+			return nil, err
+		}
+
+		to, err := ptypes.Timestamp(report.PeriodTo)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if closedAt.Before(from) || closedAt.After(to) {
+			return royaltyReportDisputeErrorOrderNotFound, nil
+		}
+
+		return nil, nil
+
+	case intPkg.RoyaltyReportDisputeLineTypeSummary:
+		for _, item := range report.GetSummary().GetProductsItems() {
+			if item.Product == line.Product && item.Region == line.Region {
+				return nil, nil
+			}
+		}
+
+		return royaltyReportDisputeErrorSummaryNotFound, nil
+	}
+
+	return royaltyReportDisputeErrorLineInvalid, nil
+}
+
+// newRoyaltyReportDispute returns the open dispute of the royalty report with the disputed lines.
+func (s *Service) newRoyaltyReportDispute(
+	report *billingpb.RoyaltyReport,
+	userId string,
+	lines []*intPkg.RoyaltyReportDisputeLine,
+) *intPkg.RoyaltyReportDispute {
+	createdAt := time.Now()
+
+	if userId == "" {
+		userId = pkg.RoyaltyReportChangeSourceMerchant
+	}
+
+	dispute := &intPkg.RoyaltyReportDispute{
+		Id:         primitive.NewObjectID(),
+		ReportId:   report.Id,
+		MerchantId: report.MerchantId,
+		Status:     intPkg.RoyaltyReportDisputeStatusOpen,
+		Lines:      make([]*intPkg.RoyaltyReportDisputeLine, 0, len(lines)),
+		Comments:   []*intPkg.RoyaltyReportDisputeComment{},
+		CreatedBy:  userId,
+		CreatedAt:  createdAt,
+		UpdatedAt:  createdAt,
+	}
+
+	for _, line := range lines {
+		dispute.Lines = append(dispute.Lines, &intPkg.RoyaltyReportDisputeLine{
+			Id:      primitive.NewObjectID().Hex(),
+			Type:    line.Type,
+			OrderId: line.OrderId,
+			Product: line.Product,
+			Region:  line.Region,
+			Amount:  line.Amount,
+			Reason:  line.Reason,
+			Status:  intPkg.RoyaltyReportDisputeLineStatusOpen,
+		})
+	}
+
+	return dispute
+}
+
+// hasOpenRoyaltyReportDispute checks that the royalty report has the dispute with the unresolved lines.
+func (s *Service) hasOpenRoyaltyReportDispute(ctx context.Context, reportId string) (bool, error) {
+	_, err := s.royaltyReportDisputeRepository.GetOpenByReportId(ctx, reportId)
+
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// closeRoyaltyReportDispute closes the open dispute of the royalty report when the financier
+// changes the status of the disputed report manually.
+func (s *Service) closeRoyaltyReportDispute(ctx context.Context, reportId string) error {
+	dispute, err := s.royaltyReportDisputeRepository.GetOpenByReportId(ctx, reportId)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+
+		return err
+	}
+
+	dispute.Status = intPkg.RoyaltyReportDisputeStatusClosed
+	dispute.ClosedAt = time.Now()
+	dispute.UpdatedAt = dispute.ClosedAt
+
+	return s.royaltyReportDisputeRepository.Update(ctx, dispute)
+}
+
+func (s *Service) notifyRoyaltyReportDisputeComment(
+	ctx context.Context,
+	dispute *intPkg.RoyaltyReportDispute,
+	author string,
+) {
+	var err error
+
+	if author == intPkg.RoyaltyReportDisputeAuthorFinancier {
+		_, err = s.addNotification(ctx, royaltyReportDisputeCommentNotificationMessage, dispute.MerchantId, "", nil)
+	} else {
+		err = s.centrifugoDashboard.Publish(ctx, s.cfg.CentrifugoFinancierChannel, dispute)
+	}
+
+	if err != nil {
+		zap.L().Error(
+			"Notification about royalty report dispute comment failed",
+			zap.Error(err),
+			zap.String("dispute_id", dispute.Id.Hex()),
+			zap.String("author", author),
+		)
+	}
+}
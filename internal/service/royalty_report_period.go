@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/jinzhu/now"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+var (
+	royaltyReportPeriodErrorMerchantNotFound = newBillingServerErrorMsg("rt000001", "merchant not found")
+	royaltyReportPeriodErrorPeriodInvalid    = newBillingServerErrorMsg("rt000002", "royalty report period or last day of period is invalid")
+	royaltyReportPeriodErrorTimezoneInvalid  = newBillingServerErrorMsg("rt000003", "incorrect time zone")
+	royaltyReportPeriodErrorCutOffInvalid    = newBillingServerErrorMsg("rt000004", "cut-off hour must be between 0 and 23")
+	royaltyReportPeriodErrorNotFound         = newBillingServerErrorMsg("rt000005", "royalty report period not found")
+	royaltyReportPeriodErrorAccessDenied     = newBillingServerErrorMsg("rt000006", "only admin or financier can change the royalty report period")
+
+	royaltyReportPeriods = map[string]bool{
+		intPkg.RoyaltyReportPeriodWeekly:   true,
+		intPkg.RoyaltyReportPeriodBiWeekly: true,
+		intPkg.RoyaltyReportPeriodMonthly:  true,
+	}
+)
+
+// SetRoyaltyReportPeriod creates or replaces the royalty report period of the merchant.
+// The new settings are applied starting from the next royalty report of the merchant.
+func (s *Service) SetRoyaltyReportPeriod(
+	ctx context.Context,
+	req *intPkg.SetRoyaltyReportPeriodRequest,
+	res *intPkg.RoyaltyReportPeriodResponse,
+) error {
+	if !s.hasAdminRole(ctx, req.UserId, billingpb.RoleSystemAdmin, billingpb.RoleSystemFinancial) {
+		res.Status = billingpb.ResponseStatusForbidden
+		res.Message = royaltyReportPeriodErrorAccessDenied
+		return nil
+	}
+
+	merchant, err := s.merchantRepository.GetById(ctx, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = royaltyReportPeriodErrorMerchantNotFound
+		return nil
+	}
+
+	if !royaltyReportPeriods[req.Period] ||
+		(req.Period == intPkg.RoyaltyReportPeriodMonthly &&
+			(req.DayOfMonth < 1 || req.DayOfMonth > intPkg.RoyaltyReportMaxDayOfMonth)) ||
+		(req.Period != intPkg.RoyaltyReportPeriodMonthly && (req.DayOfWeek < 0 || req.DayOfWeek > 6)) {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = royaltyReportPeriodErrorPeriodInvalid
+		return nil
+	}
+
+	if req.CutOffHour < 0 || req.CutOffHour > 23 {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = royaltyReportPeriodErrorCutOffInvalid
+		return nil
+	}
+
+	timeZone := req.TimeZone
+
+	if timeZone == "" {
+		timeZone = s.cfg.RoyaltyReportTimeZone
+	}
+
+	if _, err = time.LoadLocation(timeZone); err != nil {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = royaltyReportPeriodErrorTimezoneInvalid
+		return nil
+	}
+
+	updatedAt := time.Now()
+	period, err := s.royaltyReportPeriodRepository.GetByMerchantId(ctx, merchant.Id)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		oid, _ := primitive.ObjectIDFromHex(merchant.Id)
+		period = &intPkg.RoyaltyReportPeriod{
+			Id:         primitive.NewObjectID(),
+			MerchantId: oid,
+			CreatedAt:  updatedAt,
+		}
+	}
+
+	period.Period = req.Period
+	period.TimeZone = timeZone
+	period.DayOfWeek = 0
+	period.DayOfMonth = 0
+	period.CutOffHour = req.CutOffHour
+	period.UpdatedBy = req.UserId
+	period.UpdatedAt = updatedAt
+
+	if req.Period == intPkg.RoyaltyReportPeriodMonthly {
+		period.DayOfMonth = req.DayOfMonth
+	} else {
+		period.DayOfWeek = req.DayOfWeek
+	}
+
+	if err = s.royaltyReportPeriodRepository.Upsert(ctx, period); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = period
+
+	return nil
+}
+
+// GetRoyaltyReportPeriod returns the royalty report period of the merchant.
+func (s *Service) GetRoyaltyReportPeriod(
+	ctx context.Context,
+	req *intPkg.GetRoyaltyReportPeriodRequest,
+	res *intPkg.RoyaltyReportPeriodResponse,
+) error {
+	period, err := s.royaltyReportPeriodRepository.GetByMerchantId(ctx, req.MerchantId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = royaltyReportPeriodErrorNotFound
+		return nil
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = period
+
+	return nil
+}
+
+// getMerchantRoyaltyReportPeriod returns the period of the royalty report of the merchant to generate at the time.
+//
+// The period starts right after the end of the last royalty report of the merchant in any currency and ends
+// at the last cut-off closing it. While the next period isn't closed, the last reports are recalculated for their
+// own period when any of them is pending. False is returned when there is nothing to generate.
+func (s *Service) getMerchantRoyaltyReportPeriod(
+	ctx context.Context,
+	merchantId string,
+	at time.Time,
+) (from, to time.Time, ok bool, err error) {
+	merchant, err := s.merchantRepository.GetById(ctx, merchantId)
+
+	if err != nil {
+		return from, to, false, merchantErrorNotFound
+	}
+
+	period, err := s.royaltyReportPeriodRepository.GetByMerchantId(ctx, merchant.Id)
+
+	if err != nil && err != mongo.ErrNoDocuments {
+		return from, to, false, err
+	}
+
+	if period != nil {
+		if to, err = period.GetLastCutOff(at); err != nil {
+			return from, to, false, royaltyReportErrorTimezoneIncorrect
+		}
+
+		from = period.GetPeriodStart(to)
+	} else if from, to, err = s.getDefaultRoyaltyReportPeriod(at); err != nil {
+		return from, to, false, err
+	}
+
+	// the reports of the merchant are generated for the same periods in every currency
+	reports, err := s.royaltyReportRepository.GetLastPeriodByMerchantId(ctx, merchant.Id)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return from, to, true, nil
+		}
+
+		return from, to, false, err
+	}
+
+	last := reports[0]
+	lastFrom, err := ptypes.Timestamp(last.PeriodFrom)
+
+	if err != nil {
+		return from, to, false, err
+	}
+
+	lastTo, err := ptypes.Timestamp(last.PeriodTo)
+
+	if err != nil {
+		return from, to, false, err
+	}
+
+	isClosed := to.After(lastTo)
+
+	if period != nil {
+		isClosed = period.IsClosed(lastTo, to)
+	}
+
+	if isClosed {
+		return lastTo.Add(1 * time.Millisecond).In(to.Location()), to, true, nil
+	}
+
+	for _, report := range reports {
+		if report.Status == billingpb.RoyaltyReportStatusPending {
+			return lastFrom.In(to.Location()), lastTo.In(to.Location()), true, nil
+		}
+	}
+
+	return from, to, false, nil
+}
+
+// getDefaultRoyaltyReportPeriod returns the last closed royalty report period at the time for the merchants
+// without own settings: the period of the configured length ending on Monday at the configured hour.
+func (s *Service) getDefaultRoyaltyReportPeriod(at time.Time) (from, to time.Time, err error) {
+	loc, err := time.LoadLocation(s.cfg.RoyaltyReportTimeZone)
+
+	if err != nil {
+		return from, to, royaltyReportErrorTimezoneIncorrect
+	}
+
+	length := time.Duration(s.cfg.RoyaltyReportPeriod) * time.Second
+	to = now.With(at).Monday().In(loc).Add(time.Duration(s.cfg.RoyaltyReportPeriodEndHour) * time.Hour)
+
+	if to.After(at) {
+		to = to.Add(-length)
+	}
+
+	from = to.Add(-length).Add(1 * time.Millisecond).In(loc)
+
+	return from, to, nil
+}
+
+// getRoyaltyReportMerchantsSince returns the start of the search of the merchants with the transactions for the royalty
+// reports at the time. The search covers two periods of the longest of the configured default period and the monthly
+// merchant period, so the first period after the change of the merchant settings is found too.
+func (s *Service) getRoyaltyReportMerchantsSince(at time.Time) (time.Time, error) {
+	from, _, err := s.getDefaultRoyaltyReportPeriod(at)
+
+	if err != nil {
+		return from, err
+	}
+
+	since := from.Add(-time.Duration(s.cfg.RoyaltyReportPeriod) * time.Second)
+	monthly := &intPkg.RoyaltyReportPeriod{
+		Period:     intPkg.RoyaltyReportPeriodMonthly,
+		TimeZone:   s.cfg.RoyaltyReportTimeZone,
+		DayOfMonth: 1,
+	}
+	cutOff, err := monthly.GetLastCutOff(at)
+
+	if err != nil {
+		return since, royaltyReportErrorTimezoneIncorrect
+	}
+
+	if monthlySince := monthly.GetPeriodStart(monthly.GetPeriodStart(cutOff)); monthlySince.Before(since) {
+		since = monthlySince
+	}
+
+	return since, nil
+}
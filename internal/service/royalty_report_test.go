@@ -14,6 +14,7 @@ import (
 	"github.com/paysuper/paysuper-billing-server/internal/config"
 	"github.com/paysuper/paysuper-billing-server/internal/database"
 	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
@@ -26,6 +27,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest/observer"
@@ -1073,7 +1075,7 @@ func (suite *RoyaltyReportTestSuite) createOrder(project *billingpb.Project) *bi
 	return order
 }
 
-func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_CreateRoyaltyReport_Fail_EndOfPeriodInFuture() {
+func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_CreateRoyaltyReport_Ok_EndOfPeriodInFuture() {
 
 	loc, err := time.LoadLocation(suite.service.cfg.RoyaltyReportTimeZone)
 	if !assert.NoError(suite.T(), err) {
@@ -1084,10 +1086,16 @@ func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_CreateRoyaltyReport_Fail_
 	monday := now.Monday().In(loc)
 	suite.service.cfg.RoyaltyReportPeriodEndHour = int64(math.Ceil(currentTime.Sub(monday).Hours()))
 
+	from, to, err := suite.service.getDefaultRoyaltyReportPeriod(time.Now())
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), to.Before(time.Now()))
+	assert.Equal(suite.T(), monday.Add(time.Duration(suite.service.cfg.RoyaltyReportPeriodEndHour)*time.Hour).Add(-7*24*time.Hour), to)
+	assert.Equal(suite.T(), to.Add(-7*24*time.Hour).Add(time.Millisecond), from)
+
 	req := &billingpb.CreateRoyaltyReportRequest{}
 	rsp := &billingpb.CreateRoyaltyReportRequest{}
 	err = suite.service.CreateRoyaltyReport(context.TODO(), req, rsp)
-	assert.Error(suite.T(), err)
+	assert.NoError(suite.T(), err)
 }
 
 func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_CreateRoyaltyReport_Ok_MerchantWithCorrectionAndReserve() {
@@ -1118,7 +1126,7 @@ func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_CreateRoyaltyReport_Ok_Me
 		Reason:     "unit test",
 	}
 	rsp := &billingpb.CreateAccountingEntryResponse{}
-	err = suite.service.CreateAccountingEntry(context.TODO(), req, rsp)
+	err = suite.service.createAccountingEntry(context.TODO(), req, rsp)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
 	assert.Empty(suite.T(), rsp.Message)
@@ -1305,3 +1313,466 @@ func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_ChangeRoyaltyReport_Dispu
 	assert.Equal(suite.T(), billingpb.ResponseStatusSystemError, rsp1.Status)
 	assert.Equal(suite.T(), rsp1.Message, royaltyReportEntryErrorUnknown)
 }
+
+func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_getMerchantRoyaltyReportPeriod_SettingsChanged_Ok() {
+	req := &intPkg.SetRoyaltyReportPeriodRequest{
+		MerchantId: suite.merchant.Id,
+		Period:     intPkg.RoyaltyReportPeriodMonthly,
+		TimeZone:   "America/New_York",
+		DayOfMonth: 1,
+		UserId:     suite.helperAddRoyaltyReportFinancier().UserId,
+	}
+	res := &intPkg.RoyaltyReportPeriodResponse{}
+	err := suite.service.SetRoyaltyReportPeriod(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+
+	reportTime := time.Now()
+	cutOff, err := res.Item.GetLastCutOff(reportTime)
+	assert.NoError(suite.T(), err)
+
+	from, to, ok, err := suite.service.getMerchantRoyaltyReportPeriod(context.TODO(), suite.merchant.Id, reportTime)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), ok)
+	assert.True(suite.T(), cutOff.Equal(to))
+	assert.True(suite.T(), cutOff.AddDate(0, -1, 0).Add(time.Millisecond).Equal(from))
+
+	// the last weekly report before the change of the settings ended in the middle of the monthly period
+	lastTo := cutOff.AddDate(0, 0, -10)
+	suite.helperInsertRoyaltyReport(lastTo.AddDate(0, 0, -7).Add(time.Millisecond), lastTo, billingpb.RoyaltyReportStatusAccepted)
+
+	from, to, ok, err = suite.service.getMerchantRoyaltyReportPeriod(context.TODO(), suite.merchant.Id, reportTime)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), ok)
+	assert.True(suite.T(), cutOff.Equal(to))
+	assert.True(suite.T(), lastTo.Add(time.Millisecond).Equal(from))
+
+	// the pending report of the last closed period is recalculated
+	suite.helperInsertRoyaltyReport(from, to, billingpb.RoyaltyReportStatusPending)
+
+	from1, to1, ok, err := suite.service.getMerchantRoyaltyReportPeriod(context.TODO(), suite.merchant.Id, reportTime)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), ok)
+	assert.True(suite.T(), from.Equal(from1))
+	assert.True(suite.T(), to.Equal(to1))
+
+	// the next monthly period isn't closed yet
+	oid, _ := primitive.ObjectIDFromHex(suite.merchant.Id)
+	query := bson.M{"merchant_id": oid}
+	set := bson.M{"$set": bson.M{"status": billingpb.RoyaltyReportStatusAccepted}}
+	err = suite.service.royaltyReportRepository.UpdateMany(context.TODO(), query, set)
+	assert.NoError(suite.T(), err)
+
+	_, _, ok, err = suite.service.getMerchantRoyaltyReportPeriod(context.TODO(), suite.merchant.Id, reportTime)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), ok)
+}
+
+func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_getMerchantRoyaltyReportPeriod_Ok_AdditionalCurrency() {
+	reportTime := time.Now()
+	from, to, ok, err := suite.service.getMerchantRoyaltyReportPeriod(context.TODO(), suite.merchant.Id, reportTime)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), ok)
+
+	// the main currency report is accepted, the report of the same period in the additional currency is pending
+	suite.helperInsertRoyaltyReport(from, to, billingpb.RoyaltyReportStatusAccepted)
+	report := suite.helperInsertRoyaltyReport(from, to, billingpb.RoyaltyReportStatusPending)
+	report.Currency = "EUR"
+	err = suite.service.royaltyReportRepository.Update(context.TODO(), report, "", pkg.RoyaltyReportChangeSourceAuto)
+	assert.NoError(suite.T(), err)
+
+	from1, to1, ok, err := suite.service.getMerchantRoyaltyReportPeriod(context.TODO(), suite.merchant.Id, reportTime)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), ok)
+	assert.True(suite.T(), from.Equal(from1))
+	assert.True(suite.T(), to.Equal(to1))
+
+	// the next period starts after the latest report in any currency
+	report.Status = billingpb.RoyaltyReportStatusAccepted
+	report.PeriodTo, _ = ptypes.TimestampProto(to.Add(time.Hour))
+	err = suite.service.royaltyReportRepository.Update(context.TODO(), report, "", pkg.RoyaltyReportChangeSourceAuto)
+	assert.NoError(suite.T(), err)
+
+	_, _, ok, err = suite.service.getMerchantRoyaltyReportPeriod(context.TODO(), suite.merchant.Id, reportTime)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), ok)
+}
+
+func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_SetRoyaltyReportPeriod_Failed_TimezoneInvalid() {
+	req := &intPkg.SetRoyaltyReportPeriodRequest{
+		MerchantId: suite.merchant.Id,
+		Period:     intPkg.RoyaltyReportPeriodWeekly,
+		TimeZone:   "incorrect_timezone",
+		DayOfWeek:  int32(time.Friday),
+		UserId:     suite.helperAddRoyaltyReportFinancier().UserId,
+	}
+	res := &intPkg.RoyaltyReportPeriodResponse{}
+	err := suite.service.SetRoyaltyReportPeriod(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), royaltyReportPeriodErrorTimezoneInvalid, res.Message)
+}
+
+func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_SetRoyaltyReportPeriod_Failed_PeriodInvalid() {
+	req := &intPkg.SetRoyaltyReportPeriodRequest{
+		MerchantId: suite.merchant.Id,
+		Period:     intPkg.RoyaltyReportPeriodMonthly,
+		DayOfMonth: 31,
+		UserId:     suite.helperAddRoyaltyReportFinancier().UserId,
+	}
+	res := &intPkg.RoyaltyReportPeriodResponse{}
+	err := suite.service.SetRoyaltyReportPeriod(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), royaltyReportPeriodErrorPeriodInvalid, res.Message)
+}
+
+func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_SetRoyaltyReportPeriod_Failed_AccessDenied() {
+	req := &intPkg.SetRoyaltyReportPeriodRequest{
+		MerchantId: suite.merchant.Id,
+		Period:     intPkg.RoyaltyReportPeriodMonthly,
+		DayOfMonth: 1,
+		UserId:     primitive.NewObjectID().Hex(),
+	}
+	res := &intPkg.RoyaltyReportPeriodResponse{}
+	err := suite.service.SetRoyaltyReportPeriod(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+	assert.Equal(suite.T(), royaltyReportPeriodErrorAccessDenied, res.Message)
+
+	_, err = suite.service.royaltyReportPeriodRepository.GetByMerchantId(context.TODO(), suite.merchant.Id)
+	assert.Equal(suite.T(), mongo.ErrNoDocuments, err)
+}
+
+func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_getRoyaltyReportMerchantsSince_Ok() {
+	reportTime := time.Now()
+
+	// the default weekly period is shorter than the monthly period of the merchant settings
+	since, err := suite.service.getRoyaltyReportMerchantsSince(reportTime)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), since.Before(reportTime.AddDate(0, -1, 0)))
+	assert.True(suite.T(), since.After(reportTime.AddDate(0, -3, 0)))
+
+	// the search covers two periods of the longer configured default period
+	period := suite.service.cfg.RoyaltyReportPeriod
+	suite.service.cfg.RoyaltyReportPeriod = 90 * 24 * 60 * 60
+	defer func() { suite.service.cfg.RoyaltyReportPeriod = period }()
+
+	since, err = suite.service.getRoyaltyReportMerchantsSince(reportTime)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), since.Before(reportTime.AddDate(0, 0, -180)))
+}
+
+func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_OpenRoyaltyReportDispute_Ok_ClosedOnResolvedLines() {
+	order := suite.createOrder(suite.project)
+	closedAt, err := ptypes.Timestamp(order.PaymentMethodOrderClosedAt)
+	assert.NoError(suite.T(), err)
+	report := suite.helperInsertRoyaltyReport(closedAt.AddDate(0, 0, -1), closedAt.AddDate(0, 0, 1), billingpb.RoyaltyReportStatusPending)
+	financier := suite.helperAddRoyaltyReportFinancier()
+
+	req := &intPkg.OpenRoyaltyReportDisputeRequest{
+		ReportId:   report.Id,
+		MerchantId: report.MerchantId,
+		Ip:         "127.0.0.1",
+		Lines: []*intPkg.RoyaltyReportDisputeLine{
+			{Type: intPkg.RoyaltyReportDisputeLineTypeOrder, OrderId: order.Uuid, Reason: "order refunded"},
+			{Type: intPkg.RoyaltyReportDisputeLineTypeReport, Reason: "fee is too high"},
+		},
+		Comment: "unit-test",
+		Attachments: []*intPkg.RoyaltyReportDisputeAttachment{
+			{Name: "statement.pdf", ContentType: "application/pdf", Size: 1024, Url: "http://localhost/statement.pdf"},
+		},
+	}
+	res := &intPkg.RoyaltyReportDisputeResponse{}
+	err = suite.service.OpenRoyaltyReportDispute(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Equal(suite.T(), intPkg.RoyaltyReportDisputeStatusOpen, res.Item.Status)
+	assert.Len(suite.T(), res.Item.Lines, 2)
+	assert.Len(suite.T(), res.Item.Comments, 1)
+	assert.Len(suite.T(), res.Item.Comments[0].Attachments, 1)
+
+	report, err = suite.service.royaltyReportRepository.GetById(context.TODO(), report.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.RoyaltyReportStatusDispute, report.Status)
+
+	dispute := res.Item
+	req1 := &intPkg.AddRoyaltyReportDisputeCommentRequest{
+		DisputeId: dispute.Id.Hex(),
+		LineId:    dispute.Lines[0].Id,
+		UserId:    financier.UserId,
+		Text:      "order is refunded after the end of the period",
+	}
+	err = suite.service.AddRoyaltyReportDisputeComment(context.TODO(), req1, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Len(suite.T(), res.Item.Comments, 2)
+	assert.Equal(suite.T(), intPkg.RoyaltyReportDisputeAuthorFinancier, res.Item.Comments[1].Author)
+
+	req2 := &intPkg.ResolveRoyaltyReportDisputeLineRequest{
+		DisputeId:  dispute.Id.Hex(),
+		LineId:     dispute.Lines[0].Id,
+		Resolution: intPkg.RoyaltyReportDisputeLineStatusRejected,
+		UserId:     financier.UserId,
+	}
+	err = suite.service.ResolveRoyaltyReportDisputeLine(context.TODO(), req2, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Equal(suite.T(), intPkg.RoyaltyReportDisputeStatusOpen, res.Item.Status)
+
+	report, err = suite.service.royaltyReportRepository.GetById(context.TODO(), report.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.RoyaltyReportStatusDispute, report.Status)
+
+	err = suite.service.ResolveRoyaltyReportDisputeLine(context.TODO(), req2, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), royaltyReportDisputeErrorLineResolved, res.Message)
+
+	req2.LineId = dispute.Lines[1].Id
+	req2.Resolution = intPkg.RoyaltyReportDisputeLineStatusCorrected
+	req2.CorrectionAmount = -10
+	res = &intPkg.RoyaltyReportDisputeResponse{}
+	err = suite.service.ResolveRoyaltyReportDisputeLine(context.TODO(), req2, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.Equal(suite.T(), intPkg.RoyaltyReportDisputeStatusClosed, res.Item.Status)
+	assert.NotEmpty(suite.T(), res.Item.Lines[1].AccountingEntryId)
+
+	report, err = suite.service.royaltyReportRepository.GetById(context.TODO(), report.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.RoyaltyReportStatusPending, report.Status)
+	assert.EqualValues(suite.T(), -10, report.Totals.CorrectionAmount)
+	assert.Len(suite.T(), report.Summary.Corrections, 1)
+}
+
+func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_OpenRoyaltyReportDispute_Failed_OrderNotInReport() {
+	order := suite.createOrder(suite.project)
+	closedAt, err := ptypes.Timestamp(order.PaymentMethodOrderClosedAt)
+	assert.NoError(suite.T(), err)
+
+	// the order is paid after the report period
+	report := suite.helperInsertRoyaltyReport(closedAt.AddDate(0, 0, -8), closedAt.AddDate(0, 0, -1), billingpb.RoyaltyReportStatusPending)
+	req := &intPkg.OpenRoyaltyReportDisputeRequest{
+		ReportId:   report.Id,
+		MerchantId: report.MerchantId,
+		Lines: []*intPkg.RoyaltyReportDisputeLine{
+			{Type: intPkg.RoyaltyReportDisputeLineTypeOrder, OrderId: order.Uuid, Reason: "unit-test"},
+		},
+	}
+	res := &intPkg.RoyaltyReportDisputeResponse{}
+	err = suite.service.OpenRoyaltyReportDispute(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), royaltyReportDisputeErrorOrderNotFound, res.Message)
+
+	// the report of the period is in the other currency
+	report = suite.helperInsertRoyaltyReport(closedAt.AddDate(0, 0, -1), closedAt.AddDate(0, 0, 1), billingpb.RoyaltyReportStatusPending)
+	report.Currency = "EUR"
+	err = suite.service.royaltyReportRepository.Update(context.TODO(), report, "", pkg.RoyaltyReportChangeSourceAuto)
+	assert.NoError(suite.T(), err)
+
+	req.ReportId = report.Id
+	res = &intPkg.RoyaltyReportDisputeResponse{}
+	err = suite.service.OpenRoyaltyReportDispute(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), royaltyReportDisputeErrorOrderNotFound, res.Message)
+}
+
+func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_OpenRoyaltyReportDispute_Failed_SummaryLineNotFound() {
+	to := time.Now().Add(-time.Hour)
+	report := suite.helperInsertRoyaltyReport(to.AddDate(0, 0, -7), to, billingpb.RoyaltyReportStatusPending)
+
+	req := &intPkg.OpenRoyaltyReportDisputeRequest{
+		ReportId:   report.Id,
+		MerchantId: report.MerchantId,
+		Lines: []*intPkg.RoyaltyReportDisputeLine{
+			{Type: intPkg.RoyaltyReportDisputeLineTypeSummary, Product: "unknown", Region: "RU", Reason: "unit-test"},
+		},
+	}
+	res := &intPkg.RoyaltyReportDisputeResponse{}
+	err := suite.service.OpenRoyaltyReportDispute(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), royaltyReportDisputeErrorSummaryNotFound, res.Message)
+
+	report, err = suite.service.royaltyReportRepository.GetById(context.TODO(), report.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.RoyaltyReportStatusPending, report.Status)
+}
+
+func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_ResolveRoyaltyReportDisputeLine_Failed_AccessDenied() {
+	to := time.Now().Add(-time.Hour)
+	report := suite.helperInsertRoyaltyReport(to.AddDate(0, 0, -7), to, billingpb.RoyaltyReportStatusPending)
+
+	req := &intPkg.OpenRoyaltyReportDisputeRequest{
+		ReportId:   report.Id,
+		MerchantId: report.MerchantId,
+		Lines:      []*intPkg.RoyaltyReportDisputeLine{{Type: intPkg.RoyaltyReportDisputeLineTypeReport, Reason: "unit-test"}},
+	}
+	res := &intPkg.RoyaltyReportDisputeResponse{}
+	err := suite.service.OpenRoyaltyReportDispute(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+
+	req1 := &intPkg.ResolveRoyaltyReportDisputeLineRequest{
+		DisputeId:  res.Item.Id.Hex(),
+		LineId:     res.Item.Lines[0].Id,
+		Resolution: intPkg.RoyaltyReportDisputeLineStatusRejected,
+		UserId:     primitive.NewObjectID().Hex(),
+	}
+	res1 := &intPkg.RoyaltyReportDisputeResponse{}
+	err = suite.service.ResolveRoyaltyReportDisputeLine(context.TODO(), req1, res1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res1.Status)
+	assert.Equal(suite.T(), royaltyReportDisputeErrorAccessDenied, res1.Message)
+}
+
+func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_AutoAcceptRoyaltyReports_Ok_OpenDisputeSkipped() {
+	to := time.Now().Add(-time.Hour)
+	report := suite.helperInsertRoyaltyReport(to.AddDate(0, 0, -7), to, billingpb.RoyaltyReportStatusPending)
+
+	dispute := suite.service.newRoyaltyReportDispute(
+		report,
+		"",
+		[]*intPkg.RoyaltyReportDisputeLine{{Type: intPkg.RoyaltyReportDisputeLineTypeReport, Reason: "unit-test"}},
+	)
+	err := suite.service.royaltyReportDisputeRepository.Insert(context.TODO(), dispute)
+	assert.NoError(suite.T(), err)
+
+	err = suite.service.AutoAcceptRoyaltyReports(context.TODO(), &billingpb.EmptyRequest{}, &billingpb.EmptyResponse{})
+	assert.NoError(suite.T(), err)
+
+	report, err = suite.service.royaltyReportRepository.GetById(context.TODO(), report.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.RoyaltyReportStatusPending, report.Status)
+	assert.False(suite.T(), report.IsAutoAccepted)
+}
+
+func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_CreateRoyaltyReport_Ok_FirstVersion() {
+	req := &billingpb.CreateRoyaltyReportRequest{}
+	rsp := &billingpb.CreateRoyaltyReportRequest{}
+	err := suite.service.CreateRoyaltyReport(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+
+	reports, err := suite.service.royaltyReportRepository.GetAll(context.TODO())
+	assert.NoError(suite.T(), err)
+	assert.NotEmpty(suite.T(), reports)
+
+	for _, report := range reports {
+		req1 := &intPkg.GetRoyaltyReportVersionsRequest{ReportId: report.Id, MerchantId: report.MerchantId}
+		res1 := &intPkg.RoyaltyReportVersionsResponse{}
+		err = suite.service.GetRoyaltyReportVersions(context.TODO(), req1, res1)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), billingpb.ResponseStatusOk, res1.Status)
+		assert.Len(suite.T(), res1.Items, 1)
+		assert.EqualValues(suite.T(), 1, res1.Items[0].Version)
+		assert.True(suite.T(), res1.Items[0].Diff.IsZero())
+	}
+}
+
+func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_RegenerateRoyaltyReport_Ok() {
+	suite.createOrder(suite.project)
+	err := suite.service.updateOrderView(context.TODO(), []string{})
+	assert.NoError(suite.T(), err)
+
+	report := suite.helperInsertRoyaltyReport(
+		time.Now().AddDate(0, 0, -1),
+		time.Now().Add(time.Hour),
+		billingpb.RoyaltyReportStatusDispute,
+	)
+	financier := suite.helperAddRoyaltyReportFinancier()
+
+	req := &intPkg.RegenerateRoyaltyReportRequest{
+		ReportId: report.Id,
+		Reason:   "late refund",
+		UserId:   financier.UserId,
+		Ip:       "127.0.0.1",
+	}
+	res := &intPkg.RoyaltyReportVersionResponse{}
+	err = suite.service.RegenerateRoyaltyReport(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+	assert.EqualValues(suite.T(), 2, res.Item.Version)
+	assert.Equal(suite.T(), req.Reason, res.Item.Reason)
+	assert.Equal(suite.T(), financier.UserId, res.Item.CreatedBy)
+	assert.EqualValues(suite.T(), 1, res.Item.Diff.TransactionsCount)
+
+	report, err = suite.service.royaltyReportRepository.GetById(context.TODO(), report.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.RoyaltyReportStatusDispute, report.Status)
+	assert.EqualValues(suite.T(), 1, report.Totals.TransactionsCount)
+
+	changes, err := suite.service.royaltyReportRepository.GetRoyaltyHistoryById(context.TODO(), report.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), changes, 2)
+	assert.Equal(suite.T(), req.Ip, changes[1].Ip)
+	assert.Equal(suite.T(), pkg.RoyaltyReportChangeSourceAdmin, changes[1].Source)
+
+	versions, err := suite.service.royaltyReportVersionRepository.GetByReportId(context.TODO(), report.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), versions, 2)
+	assert.EqualValues(suite.T(), 1, versions[0].Version)
+	assert.Zero(suite.T(), versions[0].Totals.TransactionsCount)
+}
+
+func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_RegenerateRoyaltyReport_Failed_StatusInvalid() {
+	to := time.Now().Add(-time.Hour)
+	report := suite.helperInsertRoyaltyReport(to.AddDate(0, 0, -7), to, billingpb.RoyaltyReportStatusAccepted)
+	financier := suite.helperAddRoyaltyReportFinancier()
+
+	req := &intPkg.RegenerateRoyaltyReportRequest{ReportId: report.Id, Reason: "unit-test", UserId: financier.UserId}
+	res := &intPkg.RoyaltyReportVersionResponse{}
+	err := suite.service.RegenerateRoyaltyReport(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), royaltyReportVersionErrorStatusInvalid, res.Message)
+}
+
+func (suite *RoyaltyReportTestSuite) TestRoyaltyReport_RegenerateRoyaltyReport_Failed_AccessDenied() {
+	to := time.Now().Add(-time.Hour)
+	report := suite.helperInsertRoyaltyReport(to.AddDate(0, 0, -7), to, billingpb.RoyaltyReportStatusPending)
+
+	req := &intPkg.RegenerateRoyaltyReportRequest{ReportId: report.Id, Reason: "unit-test", UserId: primitive.NewObjectID().Hex()}
+	res := &intPkg.RoyaltyReportVersionResponse{}
+	err := suite.service.RegenerateRoyaltyReport(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, res.Status)
+	assert.Equal(suite.T(), royaltyReportVersionErrorAccessDenied, res.Message)
+}
+
+func (suite *RoyaltyReportTestSuite) helperInsertRoyaltyReport(from, to time.Time, status string) *billingpb.RoyaltyReport {
+	report := &billingpb.RoyaltyReport{
+		Id:                 primitive.NewObjectID().Hex(),
+		MerchantId:         suite.merchant.Id,
+		OperatingCompanyId: suite.merchant.OperatingCompanyId,
+		Currency:           suite.merchant.GetPayoutCurrency(),
+		Status:             status,
+		Totals:             &billingpb.RoyaltyReportTotals{},
+		CreatedAt:          ptypes.TimestampNow(),
+		AcceptExpireAt:     ptypes.TimestampNow(),
+	}
+	report.PeriodFrom, _ = ptypes.TimestampProto(from)
+	report.PeriodTo, _ = ptypes.TimestampProto(to)
+
+	if err := suite.service.royaltyReportRepository.Insert(context.TODO(), report, "", pkg.RoyaltyReportChangeSourceAuto); err != nil {
+		suite.FailNow("Insert royalty report test data failed", "%v", err)
+	}
+
+	return report
+}
+
+func (suite *RoyaltyReportTestSuite) helperAddRoyaltyReportFinancier() *billingpb.UserRole {
+	role := &billingpb.UserRole{
+		Id:     primitive.NewObjectID().Hex(),
+		UserId: primitive.NewObjectID().Hex(),
+		Role:   billingpb.RoleSystemFinancial,
+	}
+
+	if err := suite.service.userRoleRepository.AddAdminUser(context.TODO(), role); err != nil {
+		suite.FailNow("Insert admin user failed", "%v", err)
+	}
+
+	return role
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const (
+	royaltyReportParamsFieldVersion = "version"
+
+	royaltyReportVersionReasonScheduled     = "scheduled recalculation"
+	royaltyReportVersionNotificationMessage = "Royalty report for the period %s - %s is regenerated as version %d (%s). " +
+		"Payout amount changed by %s %s, transactions count changed by %d."
+)
+
+var (
+	royaltyReportVersionErrorReportNotFound     = newBillingServerErrorMsg("rg000001", "royalty report not found")
+	royaltyReportVersionErrorAccessDenied       = newBillingServerErrorMsg("rg000002", "only admin or financier can regenerate the royalty report")
+	royaltyReportVersionErrorStatusInvalid      = newBillingServerErrorMsg("rg000003", "only pending or disputed royalty report can be regenerated")
+	royaltyReportVersionErrorReasonRequired     = newBillingServerErrorMsg("rg000004", "reason of the royalty report regeneration required")
+	royaltyReportVersionErrorNotOwnedByMerchant = newBillingServerErrorMsg("rg000005", "royalty report is not owned by merchant")
+
+	royaltyReportStatusesForRegeneration = map[string]bool{
+		billingpb.RoyaltyReportStatusPending: true,
+		billingpb.RoyaltyReportStatusDispute: true,
+	}
+)
+
+// royaltyReportRegeneration is the on demand regeneration of the royalty report.
+type royaltyReportRegeneration struct {
+	reason string
+	userId string
+	ip     string
+	// version is the version created by the regeneration, it's empty when the report is unchanged
+	version *intPkg.RoyaltyReportVersion
+}
+
+// RegenerateRoyaltyReport regenerates the pending or disputed royalty report as the new version, for example
+// after the late refund or the fix of the tariffs. The merchant is notified about the changes of the totals.
+func (s *Service) RegenerateRoyaltyReport(
+	ctx context.Context,
+	req *intPkg.RegenerateRoyaltyReportRequest,
+	res *intPkg.RoyaltyReportVersionResponse,
+) error {
+	if !s.hasAdminRole(ctx, req.UserId, billingpb.RoleSystemAdmin, billingpb.RoleSystemFinancial) {
+		res.Status = billingpb.ResponseStatusForbidden
+		res.Message = royaltyReportVersionErrorAccessDenied
+		return nil
+	}
+
+	if strings.TrimSpace(req.Reason) == "" {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = royaltyReportVersionErrorReasonRequired
+		return nil
+	}
+
+	report, err := s.royaltyReportRepository.GetById(ctx, req.ReportId)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			res.Status = billingpb.ResponseStatusNotFound
+			res.Message = royaltyReportVersionErrorReportNotFound
+			return nil
+		}
+
+		return err
+	}
+
+	if !royaltyReportStatusesForRegeneration[report.Status] {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = royaltyReportVersionErrorStatusInvalid
+		return nil
+	}
+
+	merchant, err := s.merchantRepository.GetById(ctx, report.MerchantId)
+
+	if err != nil {
+		return merchantErrorNotFound
+	}
+
+	from, err := ptypes.Timestamp(report.PeriodFrom)
+
+	if err != nil {
+		return err
+	}
+
+	to, err := ptypes.Timestamp(report.PeriodTo)
+
+	if err != nil {
+		return err
+	}
+
+	handler := &royaltyHandler{
+		Service: s,
+		from:    from,
+		to:      to,
+		regeneration: &royaltyReportRegeneration{
+			reason: req.Reason,
+			userId: req.UserId,
+			ip:     req.Ip,
+		},
+	}
+
+	isMainCurrency := report.Currency == merchant.GetPayoutCurrency()
+
+	if err = handler.createMerchantCurrencyRoyaltyReport(ctx, merchant, report.Currency, isMainCurrency); err != nil {
+		return err
+	}
+
+	if handler.regeneration.version == nil {
+		res.Status = billingpb.ResponseStatusNotModified
+		return nil
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = handler.regeneration.version
+
+	return nil
+}
+
+// GetRoyaltyReportVersions returns all versions of the royalty report.
+func (s *Service) GetRoyaltyReportVersions(
+	ctx context.Context,
+	req *intPkg.GetRoyaltyReportVersionsRequest,
+	res *intPkg.RoyaltyReportVersionsResponse,
+) error {
+	report, err := s.royaltyReportRepository.GetById(ctx, req.ReportId)
+
+	if err != nil {
+		res.Status = billingpb.ResponseStatusNotFound
+		res.Message = royaltyReportVersionErrorReportNotFound
+		return nil
+	}
+
+	if req.MerchantId != "" && report.MerchantId != req.MerchantId {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = royaltyReportVersionErrorNotOwnedByMerchant
+		return nil
+	}
+
+	res.Items, err = s.royaltyReportVersionRepository.GetByReportId(ctx, report.Id)
+
+	if err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+
+	return nil
+}
+
+// canUpdateRoyaltyReport checks that the existing royalty report can be generated again. The scheduled generation
+// updates the pending reports only, the disputed reports are regenerated on demand.
+func (h *royaltyHandler) canUpdateRoyaltyReport(report *billingpb.RoyaltyReport) bool {
+	if h.regeneration != nil {
+		return royaltyReportStatusesForRegeneration[report.Status]
+	}
+
+	return report.Status == billingpb.RoyaltyReportStatusPending
+}
+
+// addRoyaltyReportVersion saves the generated royalty report as the new version with the changes of the totals
+// against the previous version and notifies the merchant about the new version of the existing report.
+// The scheduled recalculation keeping the totals unchanged doesn't create the new version.
+func (h *royaltyHandler) addRoyaltyReportVersion(
+	ctx context.Context,
+	report, previous *billingpb.RoyaltyReport,
+) (*intPkg.RoyaltyReportVersion, error) {
+	createdBy := pkg.RoyaltyReportChangeSourceAuto
+	reason := ""
+
+	if h.regeneration != nil {
+		createdBy = h.regeneration.userId
+		reason = h.regeneration.reason
+	} else if previous != nil {
+		reason = royaltyReportVersionReasonScheduled
+	}
+
+	version := newRoyaltyReportVersion(report, 1, createdBy, reason)
+
+	if previous != nil {
+		last, err := h.royaltyReportVersionRepository.GetLastByReportId(ctx, report.Id)
+
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				return nil, err
+			}
+
+			// the report generated before the versioning becomes the first version
+			last = newRoyaltyReportVersion(previous, 1, pkg.RoyaltyReportChangeSourceAuto, "")
+
+			if createdAt, err := ptypes.Timestamp(previous.CreatedAt); err == nil {
+				last.CreatedAt = createdAt
+			}
+
+			if err = h.royaltyReportVersionRepository.Insert(ctx, last); err != nil {
+				return nil, err
+			}
+		}
+
+		version.Version = last.Version + 1
+		version.Diff = version.Totals.Sub(last.Totals)
+
+		if h.regeneration == nil && version.Diff.IsZero() {
+			return last, nil
+		}
+	}
+
+	if err := h.royaltyReportVersionRepository.Insert(ctx, version); err != nil {
+		return nil, err
+	}
+
+	if h.regeneration != nil {
+		h.regeneration.version = version
+	}
+
+	if previous != nil {
+		h.Service.notifyRoyaltyReportVersion(ctx, report, version)
+	}
+
+	return version, nil
+}
+
+func newRoyaltyReportVersion(
+	report *billingpb.RoyaltyReport,
+	number int32,
+	createdBy, reason string,
+) *intPkg.RoyaltyReportVersion {
+	return &intPkg.RoyaltyReportVersion{
+		Id:         primitive.NewObjectID(),
+		ReportId:   report.Id,
+		MerchantId: report.MerchantId,
+		Version:    number,
+		Currency:   report.Currency,
+		Status:     report.Status,
+		Totals:     intPkg.NewRoyaltyReportVersionTotals(report.Totals),
+		Diff:       &intPkg.RoyaltyReportVersionTotals{},
+		Reason:     reason,
+		CreatedBy:  createdBy,
+		CreatedAt:  time.Now(),
+	}
+}
+
+func (s *Service) notifyRoyaltyReportVersion(
+	ctx context.Context,
+	report *billingpb.RoyaltyReport,
+	version *intPkg.RoyaltyReportVersion,
+) {
+	periodFrom, _ := ptypes.Timestamp(report.PeriodFrom)
+	periodTo, _ := ptypes.Timestamp(report.PeriodTo)
+
+	message := fmt.Sprintf(
+		royaltyReportVersionNotificationMessage,
+		periodFrom.Format("2006-01-02"),
+		periodTo.Format("2006-01-02"),
+		version.Version,
+		version.Reason,
+		s.newMoney(version.Diff.PayoutAmount, report.Currency).String(),
+		report.Currency,
+		version.Diff.TransactionsCount,
+	)
+	_, err := s.addNotification(ctx, message, report.MerchantId, "", nil)
+
+	if err != nil {
+		zap.L().Error(
+			"Notification about royalty report version failed",
+			zap.Error(err),
+			zap.String("report_id", report.Id),
+			zap.Int32("version", version.Version),
+		)
+	}
+}
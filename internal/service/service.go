@@ -7,6 +7,7 @@ import (
 	"github.com/go-redis/redis"
 	"github.com/paysuper/paysuper-billing-server/internal/config"
 	"github.com/paysuper/paysuper-billing-server/internal/database"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
 	"github.com/paysuper/paysuper-billing-server/internal/repository"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-i18n"
@@ -79,12 +80,14 @@ type Service struct {
 	userProfileRepository                  repository.UserProfileRepositoryInterface
 	turnoverRepository                     repository.TurnoverRepositoryInterface
 	priceGroupRepository                   repository.PriceGroupRepositoryInterface
+	pricingRuleRepository                  repository.PricingRuleRepositoryInterface
 	merchantRepository                     repository.MerchantRepositoryInterface
 	merchantBalanceRepository              repository.MerchantBalanceRepositoryInterface
 	moneyBackCostMerchantRepository        repository.MoneyBackCostMerchantRepositoryInterface
 	moneyBackCostSystemRepository          repository.MoneyBackCostSystemRepositoryInterface
 	project                                repository.ProjectRepositoryInterface
 	priceTableRepository                   repository.PriceTableRepositoryInterface
+	priceTableVersionRepository            repository.PriceTableVersionRepositoryInterface
 	notificationRepository                 repository.NotificationRepositoryInterface
 	operatingCompanyRepository             repository.OperatingCompanyRepositoryInterface
 	bankBinRepository                      repository.BankBinRepositoryInterface
@@ -100,6 +103,7 @@ type Service struct {
 	productRepository                      repository.ProductRepositoryInterface
 	paylinkRepository                      repository.PaylinkRepositoryInterface
 	paylinkVisitsRepository                repository.PaylinkVisitRepositoryInterface
+	invoiceRepository                      repository.InvoiceRepositoryInterface
 	royaltyReportRepository                repository.RoyaltyReportRepositoryInterface
 	vatReportRepository                    repository.VatReportRepositoryInterface
 	payoutRepository                       repository.PayoutRepositoryInterface
@@ -108,6 +112,30 @@ type Service struct {
 	merchantTariffsSettingsRepository      repository.MerchantTariffsSettingsInterface
 	merchantPaymentTariffsRepository       repository.MerchantPaymentTariffsInterface
 	orderViewRepository                    repository.OrderViewRepositoryInterface
+	orderGiftRepository                    repository.OrderGiftRepositoryInterface
+	orderVatIdRepository                   repository.OrderVatIdRepositoryInterface
+	localTaxRateRepository                 repository.LocalTaxRateRepositoryInterface
+	vatReportReverseChargeRepository       repository.VatReportReverseChargeRepositoryInterface
+	vatIdValidator                         VatIdValidatorInterface
+	accountingCorrectionRepository         repository.AccountingCorrectionRepositoryInterface
+	accountingPeriodRepository             repository.AccountingPeriodRepositoryInterface
+	chartOfAccountsRepository              repository.ChartOfAccountsRepositoryInterface
+	accountingChainRepository              repository.AccountingChainRepositoryInterface
+	accountingEntryReversalRepository      repository.AccountingEntryReversalRepositoryInterface
+	merchantBalanceLedgerRepository        repository.MerchantBalanceLedgerRepositoryInterface
+	rollingReservePolicyRepository         repository.RollingReservePolicyRepositoryInterface
+	rollingReserveRepository               repository.RollingReserveRepositoryInterface
+	payoutBatchRepository                  repository.PayoutBatchRepositoryInterface
+	payoutScheduleRepository               repository.PayoutScheduleRepositoryInterface
+	payoutApproverRepository               repository.PayoutApproverRepositoryInterface
+	payoutApprovalRepository               repository.PayoutApprovalRepositoryInterface
+	merchantDebtRepository                 repository.MerchantDebtRepositoryInterface
+	merchantPayoutRoutingRepository        repository.MerchantPayoutRoutingRepositoryInterface
+	payoutRequestLimitRepository           repository.PayoutRequestLimitRepositoryInterface
+	payoutRequestRepository                repository.PayoutRequestRepositoryInterface
+	royaltyReportPeriodRepository          repository.RoyaltyReportPeriodRepositoryInterface
+	royaltyReportDisputeRepository         repository.RoyaltyReportDisputeRepositoryInterface
+	royaltyReportVersionRepository         repository.RoyaltyReportVersionRepositoryInterface
 	merchantPaymentMethodHistoryRepository repository.MerchantPaymentMethodHistoryRepositoryInterface
 	feedbackRepository                     repository.FeedbackRepositoryInterface
 	dashboardRepository                    repository.DashboardRepositoryInterface
@@ -179,12 +207,14 @@ func (s *Service) Init() (err error) {
 	s.userProfileRepository = repository.NewUserProfileRepository(s.db)
 	s.turnoverRepository = repository.NewTurnoverRepository(s.db, s.cacher)
 	s.priceGroupRepository = repository.NewPriceGroupRepository(s.db, s.cacher)
+	s.pricingRuleRepository = repository.NewPricingRuleRepository(s.db, s.cacher)
 	s.merchantRepository = repository.NewMerchantRepository(s.db, s.cacher)
 	s.merchantBalanceRepository = repository.NewMerchantBalanceRepository(s.db, s.cacher)
 	s.moneyBackCostMerchantRepository = repository.NewMoneyBackCostMerchantRepository(s.db, s.cacher)
 	s.moneyBackCostSystemRepository = repository.NewMoneyBackCostSystemRepository(s.db, s.cacher)
 	s.project = repository.NewProjectRepository(s.db, s.cacher)
 	s.priceTableRepository = repository.NewPriceTableRepository(s.db)
+	s.priceTableVersionRepository = repository.NewPriceTableVersionRepository(s.db)
 	s.notificationRepository = repository.NewNotificationRepository(s.db)
 	s.operatingCompanyRepository = repository.NewOperatingCompanyRepository(s.db, s.cacher)
 	s.bankBinRepository = repository.NewBankBinRepository(s.db)
@@ -200,6 +230,7 @@ func (s *Service) Init() (err error) {
 	s.productRepository = repository.NewProductRepository(s.db, s.cacher)
 	s.paylinkRepository = repository.NewPaylinkRepository(s.db, s.cacher)
 	s.paylinkVisitsRepository = repository.NewPaylinkVisitRepository(s.db)
+	s.invoiceRepository = repository.NewInvoiceRepository(s.db)
 	s.royaltyReportRepository = repository.NewRoyaltyReportRepository(s.db, s.cacher)
 	s.vatReportRepository = repository.NewVatReportRepository(s.db)
 	s.payoutRepository = repository.NewPayoutRepository(s.db, s.cacher)
@@ -208,6 +239,29 @@ func (s *Service) Init() (err error) {
 	s.merchantTariffsSettingsRepository = repository.NewMerchantTariffsSettingsRepository(s.db, s.cacher)
 	s.merchantPaymentTariffsRepository = repository.NewMerchantPaymentTariffsRepository(s.db, s.cacher)
 	s.orderViewRepository = repository.NewOrderViewRepository(s.db)
+	s.orderGiftRepository = repository.NewOrderGiftRepository(s.db)
+	s.orderVatIdRepository = repository.NewOrderVatIdRepository(s.db)
+	s.localTaxRateRepository = repository.NewLocalTaxRateRepository(s.db)
+	s.vatReportReverseChargeRepository = repository.NewVatReportReverseChargeRepository(s.db)
+	s.accountingCorrectionRepository = repository.NewAccountingCorrectionRepository(s.db)
+	s.accountingPeriodRepository = repository.NewAccountingPeriodRepository(s.db)
+	s.chartOfAccountsRepository = repository.NewChartOfAccountsRepository(s.db)
+	s.accountingChainRepository = repository.NewAccountingChainRepository(s.db)
+	s.accountingEntryReversalRepository = repository.NewAccountingEntryReversalRepository(s.db)
+	s.merchantBalanceLedgerRepository = repository.NewMerchantBalanceLedgerRepository(s.db)
+	s.rollingReservePolicyRepository = repository.NewRollingReservePolicyRepository(s.db)
+	s.rollingReserveRepository = repository.NewRollingReserveRepository(s.db)
+	s.payoutBatchRepository = repository.NewPayoutBatchRepository(s.db)
+	s.payoutScheduleRepository = repository.NewPayoutScheduleRepository(s.db)
+	s.payoutApproverRepository = repository.NewPayoutApproverRepository(s.db)
+	s.payoutApprovalRepository = repository.NewPayoutApprovalRepository(s.db)
+	s.merchantDebtRepository = repository.NewMerchantDebtRepository(s.db)
+	s.merchantPayoutRoutingRepository = repository.NewMerchantPayoutRoutingRepository(s.db)
+	s.payoutRequestLimitRepository = repository.NewPayoutRequestLimitRepository(s.db)
+	s.payoutRequestRepository = repository.NewPayoutRequestRepository(s.db)
+	s.royaltyReportPeriodRepository = repository.NewRoyaltyReportPeriodRepository(s.db)
+	s.royaltyReportDisputeRepository = repository.NewRoyaltyReportDisputeRepository(s.db)
+	s.royaltyReportVersionRepository = repository.NewRoyaltyReportVersionRepository(s.db)
 	s.merchantPaymentMethodHistoryRepository = repository.NewMerchantPaymentMethodHistoryRepository(s.db)
 	s.feedbackRepository = repository.NewFeedbackRepository(s.db)
 	s.dashboardRepository = repository.NewDashboardRepository(s.db, s.cacher)
@@ -246,11 +300,18 @@ func (s *Service) Init() (err error) {
 func (s *Service) getCurrencyPrecision(currency string) int32 {
 	p, ok := s.currenciesPrecision[currency]
 	if !ok {
-		return 2
+		return intPkg.MoneyDefaultPrecision
 	}
 	return p
 }
 
+// newMoney returns the fixed-point amount of money with the precision of the currency.
+func (s *Service) newMoney(amount float64, currency string) intPkg.Money {
+	return intPkg.NewMoney(amount, currency, s.getCurrencyPrecision(currency))
+}
+
+// FormatAmount rounds the amount of order, tax or refund to the precision of the currency.
+// The accounting amounts are rounded half away from zero by the money type instead.
 func (s *Service) FormatAmount(amount float64, currency string) float64 {
 	p := s.getCurrencyPrecision(currency)
 	return tools.ToFixed(amount, int(p))
@@ -9,6 +9,7 @@ import (
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
 	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	tools "github.com/paysuper/paysuper-tools/number"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -429,3 +430,14 @@ func (suite *BillingServiceTestSuite) TestBillingService_CheckProjectRequestSign
 	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
 	assert.Equal(suite.T(), orderErrorSignatureInvalid, rsp.Message)
 }
+
+func (suite *BillingServiceTestSuite) TestBillingService_FormatAmount_OrderRoundingUnchanged() {
+	precision := int(suite.service.getCurrencyPrecision("USD"))
+
+	for _, amount := range []float64{1.005, 2.345, -2.345, 10.125, 0.285} {
+		assert.Equal(suite.T(), tools.ToFixed(amount, precision), suite.service.FormatAmount(amount, "USD"))
+	}
+
+	assert.Equal(suite.T(), 2.35, suite.service.newMoney(2.345, "USD").Round().Float64())
+	assert.Equal(suite.T(), -2.35, suite.service.newMoney(-2.345, "USD").Round().Float64())
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/currenciespb"
+	"github.com/paysuper/paysuper-proto/go/taxpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"math"
+	"time"
+)
+
+const (
+	taxEngineShadowRateTolerance = 0.0001
+)
+
+var (
+	localTaxRateErrorNotFound       = newBillingServerErrorMsg("tx000001", "tax rate not found in local tax engine")
+	localTaxRateErrorRateInvalid    = newBillingServerErrorMsg("tx000002", "tax rate must be greater or equal 0 and less than 1")
+	localTaxRateErrorCountryInvalid = newBillingServerErrorMsg("tx000003", "country for tax rate not found")
+	localTaxRateErrorZipInvalid     = newBillingServerErrorMsg("tx000004", "zip code for tax rate not found")
+	localTaxRateErrorStateNotAllow  = newBillingServerErrorMsg("tx000005", "state and zip code can be set only for US tax rates")
+)
+
+// SetLocalTaxRate adds or updates the rate of the local tax engine. The rate of the country is stored in the country,
+// the local tax rates are kept for US states and zip codes only. For US rates the state is taken from the zip code
+// if zip code is passed.
+func (s *Service) SetLocalTaxRate(
+	ctx context.Context,
+	req *intPkg.LocalTaxRate,
+	res *intPkg.LocalTaxRateResponse,
+) error {
+	if req.Rate < 0 || req.Rate >= 1 {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = localTaxRateErrorRateInvalid
+		return nil
+	}
+
+	if _, err := s.country.GetByIsoCodeA2(ctx, req.Country); err != nil {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = localTaxRateErrorCountryInvalid
+		return nil
+	}
+
+	if req.Country != CountryCodeUSA && (req.State != "" || req.Zip != "") {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = localTaxRateErrorStateNotAllow
+		return nil
+	}
+
+	if req.State == "" && req.Zip == "" {
+		if err := s.country.SetVatRate(ctx, req.Country, req.Rate); err != nil {
+			return err
+		}
+
+		res.Status = billingpb.ResponseStatusOk
+		res.Item = &intPkg.LocalTaxRate{
+			Country:   req.Country,
+			Rate:      req.Rate,
+			UpdatedAt: time.Now(),
+		}
+
+		return nil
+	}
+
+	if req.Zip != "" {
+		zip, err := s.zipCodeRepository.GetByZipAndCountry(ctx, req.Zip, req.Country)
+
+		if err != nil {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = localTaxRateErrorZipInvalid
+			return nil
+		}
+
+		req.State = zip.GetState().GetCode()
+	}
+
+	rate, err := s.localTaxRateRepository.Get(ctx, req.Country, req.State, req.Zip)
+
+	if err != nil {
+		rate = &intPkg.LocalTaxRate{
+			Id:        primitive.NewObjectID(),
+			Country:   req.Country,
+			State:     req.State,
+			Zip:       req.Zip,
+			CreatedAt: time.Now(),
+		}
+	}
+
+	rate.Rate = req.Rate
+	rate.UpdatedAt = time.Now()
+
+	if err = s.localTaxRateRepository.Upsert(ctx, rate); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Item = rate
+
+	return nil
+}
+
+// ListLocalTaxRates returns the US state and zip code rates of the local tax engine for the country.
+func (s *Service) ListLocalTaxRates(
+	ctx context.Context,
+	req *intPkg.ListLocalTaxRatesRequest,
+	res *intPkg.ListLocalTaxRatesResponse,
+) error {
+	rates, err := s.localTaxRateRepository.FindByCountry(ctx, req.Country)
+
+	if err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Items = rates
+
+	return nil
+}
+
+// getTaxRate returns the tax rate for geo identity using the tax engine selected in configuration.
+// In shadow mode the rate is also calculated by the secondary engine and mismatches are logged.
+// The VAT threshold is applied by the local tax engine for the passed operating company only.
+func (s *Service) getTaxRate(
+	ctx context.Context,
+	req *taxpb.GeoIdentity,
+	operatingCompanyId string,
+) (*taxpb.TaxRate, error) {
+	switch s.cfg.TaxEngineMode {
+	case intPkg.TaxEngineModeLocal:
+		rate, err := s.getLocalTaxRate(ctx, req, operatingCompanyId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if s.cfg.TaxEngineShadowMode {
+			shadow, err := s.tax.GetRate(ctx, req)
+			s.compareTaxRates(req, rate, shadow, err)
+		}
+
+		return rate, nil
+	case intPkg.TaxEngineModeFallback:
+		rate, err := s.tax.GetRate(ctx, req)
+
+		if err != nil {
+			zap.L().Warn(
+				"Tax service return error, local tax engine used",
+				zap.Error(err),
+				zap.Any("request", req),
+			)
+			return s.getLocalTaxRate(ctx, req, operatingCompanyId)
+		}
+
+		if s.cfg.TaxEngineShadowMode {
+			shadow, err := s.getLocalTaxRate(ctx, req, operatingCompanyId)
+			s.compareTaxRates(req, rate, shadow, err)
+		}
+
+		return rate, nil
+	default:
+		rate, err := s.tax.GetRate(ctx, req)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if s.cfg.TaxEngineShadowMode {
+			shadow, err := s.getLocalTaxRate(ctx, req, operatingCompanyId)
+			s.compareTaxRates(req, rate, shadow, err)
+		}
+
+		return rate, nil
+	}
+}
+
+// getLocalTaxRate calculates the tax rate using the VAT rate of the country and the local rates of US states
+// and zip codes. For US the most specific rate is used: rate of zip code, then rate of state, then rate of country.
+// The tax isn't charged in the countries with disabled VAT and in the countries which VAT threshold isn't reached
+// by the operating company yet.
+func (s *Service) getLocalTaxRate(
+	ctx context.Context,
+	req *taxpb.GeoIdentity,
+	operatingCompanyId string,
+) (*taxpb.TaxRate, error) {
+	country, err := s.country.GetByIsoCodeA2(ctx, req.Country)
+
+	if err != nil {
+		return nil, localTaxRateErrorNotFound
+	}
+
+	rsp := &taxpb.TaxRate{
+		Country: req.Country,
+	}
+
+	if !country.VatEnabled {
+		return rsp, nil
+	}
+
+	if operatingCompanyId != "" && !s.isVatThresholdReached(ctx, country, operatingCompanyId) {
+		return rsp, nil
+	}
+
+	if req.Country == CountryCodeUSA && req.Zip != "" {
+		rsp.Zip = req.Zip
+
+		if zip, err := s.zipCodeRepository.GetByZipAndCountry(ctx, req.Zip, req.Country); err == nil {
+			rsp.State = zip.GetState().GetCode()
+			rsp.City = zip.City
+		}
+
+		rate, err := s.localTaxRateRepository.Get(ctx, req.Country, rsp.State, req.Zip)
+
+		if err != nil && rsp.State != "" {
+			rate, err = s.localTaxRateRepository.Get(ctx, req.Country, rsp.State, "")
+		}
+
+		if err == nil {
+			rsp.Rate = rate.Rate
+			return rsp, nil
+		}
+	}
+
+	rsp.Rate, err = s.country.GetVatRate(ctx, req.Country)
+
+	if err != nil {
+		return nil, localTaxRateErrorNotFound
+	}
+
+	return rsp, nil
+}
+
+// isVatThresholdReached checks the annual turnover of the operating company in the country and in the world
+// against the VAT threshold of the country. The threshold is considered reached if the turnover is unknown,
+// so the VAT is charged when it isn't possible to prove that it's not required.
+func (s *Service) isVatThresholdReached(
+	ctx context.Context,
+	country *billingpb.Country,
+	operatingCompanyId string,
+) bool {
+	threshold := country.GetVatThreshold()
+
+	if threshold.GetYear() <= 0 && threshold.GetWorld() <= 0 {
+		return true
+	}
+
+	year := time.Now().Year()
+
+	if threshold.GetYear() > 0 {
+		turnover, err := s.turnoverRepository.Get(ctx, operatingCompanyId, country.IsoCodeA2, year)
+
+		if err != nil || turnover.Amount >= threshold.GetYear() {
+			return true
+		}
+	}
+
+	if threshold.GetWorld() > 0 {
+		turnover, err := s.turnoverRepository.Get(ctx, operatingCompanyId, "", year)
+
+		if err != nil {
+			return true
+		}
+
+		currency := country.VatCurrency
+
+		if currency == "" {
+			currency = country.Currency
+		}
+
+		amount := turnover.Amount
+
+		if turnover.Currency != currency && amount > 0 {
+			req := &currenciespb.ExchangeCurrencyCurrentCommonRequest{
+				From:              turnover.Currency,
+				To:                currency,
+				RateType:          currenciespb.RateTypeCentralbanks,
+				ExchangeDirection: currenciespb.ExchangeDirectionBuy,
+				Source:            country.VatCurrencyRatesSource,
+				Amount:            amount,
+			}
+			rsp, err := s.curService.ExchangeCurrencyCurrentCommon(ctx, req)
+
+			if err != nil {
+				zap.L().Error(
+					pkg.ErrorGrpcServiceCallFailed,
+					zap.Error(err),
+					zap.String(errorFieldService, "CurrencyRatesService"),
+					zap.String(errorFieldMethod, "ExchangeCurrencyCurrentCommon"),
+					zap.Any(errorFieldRequest, req),
+				)
+				return true
+			}
+
+			amount = rsp.ExchangedAmount
+		}
+
+		if amount >= threshold.GetWorld() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Service) compareTaxRates(req *taxpb.GeoIdentity, rate, shadow *taxpb.TaxRate, err error) {
+	if err != nil {
+		zap.L().Warn(
+			"Shadow tax engine return error",
+			zap.Error(err),
+			zap.String("engine_mode", s.cfg.TaxEngineMode),
+			zap.Any("request", req),
+		)
+		return
+	}
+
+	if math.Abs(rate.Rate-shadow.Rate) <= taxEngineShadowRateTolerance {
+		return
+	}
+
+	zap.L().Warn(
+		"Tax rates of primary and shadow tax engines are not equal",
+		zap.String("engine_mode", s.cfg.TaxEngineMode),
+		zap.Any("request", req),
+		zap.Float64("rate", rate.Rate),
+		zap.Float64("shadow_rate", shadow.Rate),
+	)
+}
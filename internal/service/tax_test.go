@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/mongodb"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/paysuper/paysuper-proto/go/taxpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	rabbitmq "gopkg.in/ProtocolONE/rabbitmq.v1/pkg"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+	"time"
+)
+
+type TaxTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+}
+
+func Test_Tax(t *testing.T) {
+	suite.Run(t, new(TaxTestSuite))
+}
+
+func (suite *TaxTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+	cfg.CardPayApiUrl = "https://sandbox.cardpay.com"
+
+	m, err := migrate.New(
+		"file://../../migrations/tests",
+		cfg.MongoDsn)
+	assert.NoError(suite.T(), err, "Migrate init failed")
+
+	err = m.Up()
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	broker, err := rabbitmq.NewBroker(cfg.BrokerAddress)
+
+	if err != nil {
+		suite.FailNow("Creating RabbitMQ publisher failed", "%v", err)
+	}
+
+	redisClient := database.NewRedis(
+		&redis.Options{
+			Addr:     cfg.RedisHost,
+			Password: cfg.RedisPassword,
+		},
+	)
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		broker,
+		redisClient,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	country := &billingpb.Country{
+		IsoCodeA2:       "US",
+		Region:          "North America",
+		Currency:        "USD",
+		PaymentsAllowed: true,
+		ChangeAllowed:   true,
+		VatEnabled:      true,
+		PriceGroupId:    "",
+		VatCurrency:     "USD",
+	}
+	err = suite.service.country.Insert(context.TODO(), country)
+	assert.NoError(suite.T(), err)
+
+	zipCode := &billingpb.ZipCode{
+		Zip:     "98001",
+		Country: "US",
+		City:    "Washington",
+		State: &billingpb.ZipCodeState{
+			Code: "NJ",
+			Name: "New Jersey",
+		},
+	}
+	err = suite.service.zipCodeRepository.Insert(context.TODO(), zipCode)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *TaxTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *TaxTestSuite) setRate(country, zip string, rate float64) {
+	req := &intPkg.LocalTaxRate{Country: country, Zip: zip, Rate: rate}
+	res := &intPkg.LocalTaxRateResponse{}
+	err := suite.service.SetLocalTaxRate(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, res.Status)
+}
+
+func (suite *TaxTestSuite) TestTax_SetLocalTaxRate_Ok() {
+	suite.setRate("US", "98001", 0.1)
+
+	rates := &intPkg.ListLocalTaxRatesResponse{}
+	err := suite.service.ListLocalTaxRates(context.TODO(), &intPkg.ListLocalTaxRatesRequest{Country: "US"}, rates)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), rates.Items, 1)
+	assert.Equal(suite.T(), "NJ", rates.Items[0].State)
+
+	suite.setRate("US", "98001", 0.12)
+
+	err = suite.service.ListLocalTaxRates(context.TODO(), &intPkg.ListLocalTaxRatesRequest{Country: "US"}, rates)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), rates.Items, 1)
+	assert.Equal(suite.T(), 0.12, rates.Items[0].Rate)
+}
+
+func (suite *TaxTestSuite) TestTax_SetLocalTaxRate_Error() {
+	res := &intPkg.LocalTaxRateResponse{}
+	err := suite.service.SetLocalTaxRate(context.TODO(), &intPkg.LocalTaxRate{Country: "US", Rate: 1}, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, res.Status)
+	assert.Equal(suite.T(), localTaxRateErrorRateInvalid, res.Message)
+
+	res = &intPkg.LocalTaxRateResponse{}
+	err = suite.service.SetLocalTaxRate(context.TODO(), &intPkg.LocalTaxRate{Country: "XX", Rate: 0.1}, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), localTaxRateErrorCountryInvalid, res.Message)
+
+	res = &intPkg.LocalTaxRateResponse{}
+	err = suite.service.SetLocalTaxRate(context.TODO(), &intPkg.LocalTaxRate{Country: "US", Zip: "00000", Rate: 0.1}, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), localTaxRateErrorZipInvalid, res.Message)
+}
+
+func (suite *TaxTestSuite) TestTax_GetLocalTaxRate_MostSpecificRate() {
+	suite.setRate("US", "", 0.05)
+
+	rate, err := suite.service.getLocalTaxRate(context.TODO(), &taxpb.GeoIdentity{Country: "US", Zip: "98001"}, "")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0.05, rate.Rate)
+	assert.Equal(suite.T(), "NJ", rate.State)
+
+	suite.setRate("US", "98001", 0.08)
+
+	rate, err = suite.service.getLocalTaxRate(context.TODO(), &taxpb.GeoIdentity{Country: "US", Zip: "98001"}, "")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0.08, rate.Rate)
+
+	_, err = suite.service.getLocalTaxRate(context.TODO(), &taxpb.GeoIdentity{Country: "XA"}, "")
+	assert.Equal(suite.T(), localTaxRateErrorNotFound, err)
+}
+
+func (suite *TaxTestSuite) TestTax_SetLocalTaxRate_CountryRateStoredInCountry() {
+	country := &billingpb.Country{
+		Id:          primitive.NewObjectID().Hex(),
+		IsoCodeA2:   "XA",
+		Currency:    "EUR",
+		VatEnabled:  true,
+		VatCurrency: "EUR",
+	}
+	err := suite.service.country.Insert(context.TODO(), country)
+	assert.NoError(suite.T(), err)
+
+	suite.setRate("XA", "", 0.2)
+
+	rate, err := suite.service.country.GetVatRate(context.TODO(), "XA")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0.2, rate)
+
+	rates := &intPkg.ListLocalTaxRatesResponse{}
+	err = suite.service.ListLocalTaxRates(context.TODO(), &intPkg.ListLocalTaxRatesRequest{Country: "XA"}, rates)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), rates.Items)
+
+	country.VatThreshold = &billingpb.CountryVatThreshold{}
+	err = suite.service.country.Update(context.TODO(), country)
+	assert.NoError(suite.T(), err)
+
+	taxRate, err := suite.service.getLocalTaxRate(context.TODO(), &taxpb.GeoIdentity{Country: "XA"}, "")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0.2, taxRate.Rate)
+}
+
+func (suite *TaxTestSuite) TestTax_GetLocalTaxRate_VatThreshold() {
+	country := &billingpb.Country{
+		Id:           primitive.NewObjectID().Hex(),
+		IsoCodeA2:    "XC",
+		Currency:     "EUR",
+		VatEnabled:   true,
+		VatCurrency:  "EUR",
+		VatThreshold: &billingpb.CountryVatThreshold{Year: 1000},
+	}
+	err := suite.service.country.Insert(context.TODO(), country)
+	assert.NoError(suite.T(), err)
+
+	suite.setRate("XC", "", 0.2)
+	operatingCompanyId := primitive.NewObjectID().Hex()
+
+	// the turnover isn't calculated yet, so the VAT is charged
+	rate, err := suite.service.getLocalTaxRate(context.TODO(), &taxpb.GeoIdentity{Country: "XC"}, operatingCompanyId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0.2, rate.Rate)
+
+	turnover := &billingpb.AnnualTurnover{
+		Year:               int32(time.Now().Year()),
+		Country:            "XC",
+		Amount:             999,
+		Currency:           "EUR",
+		OperatingCompanyId: operatingCompanyId,
+	}
+	err = suite.service.turnoverRepository.Upsert(context.TODO(), turnover)
+	assert.NoError(suite.T(), err)
+
+	rate, err = suite.service.getLocalTaxRate(context.TODO(), &taxpb.GeoIdentity{Country: "XC"}, operatingCompanyId)
+	assert.NoError(suite.T(), err)
+	assert.Zero(suite.T(), rate.Rate)
+
+	// the rate of the country is returned without the operating company
+	rate, err = suite.service.getLocalTaxRate(context.TODO(), &taxpb.GeoIdentity{Country: "XC"}, "")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0.2, rate.Rate)
+
+	turnover.Amount = 1000
+	err = suite.service.turnoverRepository.Upsert(context.TODO(), turnover)
+	assert.NoError(suite.T(), err)
+
+	rate, err = suite.service.getLocalTaxRate(context.TODO(), &taxpb.GeoIdentity{Country: "XC"}, operatingCompanyId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0.2, rate.Rate)
+}
+
+func (suite *TaxTestSuite) TestTax_GetLocalTaxRate_VatDisabled() {
+	country := &billingpb.Country{IsoCodeA2: "XB", Currency: "EUR", VatEnabled: false, VatCurrency: "EUR"}
+	err := suite.service.country.Insert(context.TODO(), country)
+	assert.NoError(suite.T(), err)
+
+	suite.setRate("XB", "", 0.2)
+
+	rate, err := suite.service.getLocalTaxRate(context.TODO(), &taxpb.GeoIdentity{Country: "XB"}, "")
+	assert.NoError(suite.T(), err)
+	assert.Zero(suite.T(), rate.Rate)
+}
+
+func (suite *TaxTestSuite) TestTax_GetTaxRate_Fallback() {
+	suite.setRate("US", "98001", 0.08)
+	suite.service.cfg.TaxEngineMode = intPkg.TaxEngineModeFallback
+
+	rate, err := suite.service.getTaxRate(context.TODO(), &taxpb.GeoIdentity{Country: "US", Zip: "98001"}, "")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0.19, rate.Rate)
+
+	suite.service.tax = mocks.NewTaxServiceErrorMock()
+
+	rate, err = suite.service.getTaxRate(context.TODO(), &taxpb.GeoIdentity{Country: "US", Zip: "98001"}, "")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0.08, rate.Rate)
+
+	suite.service.cfg.TaxEngineMode = intPkg.TaxEngineModeRemote
+
+	_, err = suite.service.getTaxRate(context.TODO(), &taxpb.GeoIdentity{Country: "US", Zip: "98001"}, "")
+	assert.Error(suite.T(), err)
+}
+
+func (suite *TaxTestSuite) TestTax_GetTaxRate_LocalWithShadow() {
+	suite.setRate("US", "98001", 0.08)
+	suite.service.cfg.TaxEngineMode = intPkg.TaxEngineModeLocal
+	suite.service.cfg.TaxEngineShadowMode = true
+
+	rate, err := suite.service.getTaxRate(context.TODO(), &taxpb.GeoIdentity{Country: "US", Zip: "98001"}, "")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0.08, rate.Rate)
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	"github.com/paysuper/paysuper-proto/go/currenciespb"
-	tools "github.com/paysuper/paysuper-tools/number"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 	"time"
@@ -125,7 +124,7 @@ func (s *Service) calcAnnualTurnover(ctx context.Context, countryCode, operating
 			if err != nil {
 				return err
 			}
-			amount += amnt
+			amount = s.newMoney(amount, targetCurrency).Add(s.newMoney(amnt, targetCurrency)).Float64()
 			count++
 			from, to, err = s.getVatReportTimeForDate(VatPeriodMonth, from.AddDate(0, 0, -1))
 			if err != nil {
@@ -141,7 +140,7 @@ func (s *Service) calcAnnualTurnover(ctx context.Context, countryCode, operating
 	at := &billingpb.AnnualTurnover{
 		Year:               int32(year.Year()),
 		Country:            countryCode,
-		Amount:             tools.FormatAmount(amount),
+		Amount:             s.newMoney(amount, targetCurrency).Round().Float64(),
 		Currency:           targetCurrency,
 		OperatingCompanyId: operatingCompanyId,
 	}
@@ -0,0 +1,404 @@
+package service
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	vatIdErrorCountryNotSupported = newBillingServerErrorMsg("vi000001", "vat id is not supported for country of order")
+)
+
+// VatIdValidatorInterface is the external validator of the VAT identification numbers, for example VIES.
+// Validate must return an error if the validator is unavailable, in this case the result
+// of the offline validation is used.
+type VatIdValidatorInterface interface {
+	Validate(ctx context.Context, country, vatId string) (bool, error)
+}
+
+type vatIdRule struct {
+	prefix   string
+	format   *regexp.Regexp
+	checksum func(string) bool
+}
+
+var vatIdRules = map[string]*vatIdRule{
+	"AT": {prefix: "AT", format: regexp.MustCompile(`^U\d{8}$`), checksum: vatIdChecksumAT},
+	"BE": {prefix: "BE", format: regexp.MustCompile(`^[01]\d{9}$`), checksum: vatIdChecksumBE},
+	"BG": {prefix: "BG", format: regexp.MustCompile(`^\d{9,10}$`)},
+	"CY": {prefix: "CY", format: regexp.MustCompile(`^\d{8}[A-Z]$`)},
+	"CZ": {prefix: "CZ", format: regexp.MustCompile(`^\d{8,10}$`)},
+	"DE": {prefix: "DE", format: regexp.MustCompile(`^\d{9}$`), checksum: vatIdChecksumDE},
+	"DK": {prefix: "DK", format: regexp.MustCompile(`^\d{8}$`), checksum: vatIdChecksumDK},
+	"EE": {prefix: "EE", format: regexp.MustCompile(`^\d{9}$`)},
+	"ES": {prefix: "ES", format: regexp.MustCompile(`^[0-9A-Z]\d{7}[0-9A-Z]$`)},
+	"FI": {prefix: "FI", format: regexp.MustCompile(`^\d{8}$`), checksum: vatIdChecksumFI},
+	"FR": {prefix: "FR", format: regexp.MustCompile(`^[0-9A-Z]{2}\d{9}$`), checksum: vatIdChecksumFR},
+	"GR": {prefix: "EL", format: regexp.MustCompile(`^\d{9}$`)},
+	"HR": {prefix: "HR", format: regexp.MustCompile(`^\d{11}$`)},
+	"HU": {prefix: "HU", format: regexp.MustCompile(`^\d{8}$`)},
+	"IE": {prefix: "IE", format: regexp.MustCompile(`^(\d{7}[A-W][A-I]?|\d[A-Z+*]\d{5}[A-W])$`)},
+	"IT": {prefix: "IT", format: regexp.MustCompile(`^\d{11}$`), checksum: vatIdChecksumLuhn},
+	"LT": {prefix: "LT", format: regexp.MustCompile(`^(\d{9}|\d{12})$`)},
+	"LU": {prefix: "LU", format: regexp.MustCompile(`^\d{8}$`), checksum: vatIdChecksumLU},
+	"LV": {prefix: "LV", format: regexp.MustCompile(`^\d{11}$`)},
+	"MT": {prefix: "MT", format: regexp.MustCompile(`^\d{8}$`)},
+	"NL": {prefix: "NL", format: regexp.MustCompile(`^\d{9}B\d{2}$`), checksum: vatIdChecksumNL},
+	"PL": {prefix: "PL", format: regexp.MustCompile(`^\d{10}$`), checksum: vatIdChecksumPL},
+	"PT": {prefix: "PT", format: regexp.MustCompile(`^\d{9}$`), checksum: vatIdChecksumPT},
+	"RO": {prefix: "RO", format: regexp.MustCompile(`^\d{2,10}$`)},
+	"SE": {prefix: "SE", format: regexp.MustCompile(`^\d{10}01$`), checksum: vatIdChecksumSE},
+	"SI": {prefix: "SI", format: regexp.MustCompile(`^\d{8}$`)},
+	"SK": {prefix: "SK", format: regexp.MustCompile(`^\d{10}$`)},
+}
+
+var vatIdCleanRegexp = regexp.MustCompile(`[^0-9A-Z+*]`)
+
+// SetVatIdValidator sets the external validator which is used to confirm the VAT IDs passed the offline validation.
+func (s *Service) SetVatIdValidator(validator VatIdValidatorInterface) {
+	s.vatIdValidator = validator
+}
+
+// SetOrderVatId sets the VAT ID of the business customer to the order and recalculates the VAT of the order.
+// The valid VAT ID of the billing country zeroes the VAT of the order under the reverse charge,
+// empty VAT ID removes the previously set one.
+func (s *Service) SetOrderVatId(
+	ctx context.Context,
+	req *intPkg.SetOrderVatIdRequest,
+	res *intPkg.SetOrderVatIdResponse,
+) error {
+	order, err := s.getOrderByUuidToForm(ctx, req.OrderId)
+
+	if err != nil {
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = e
+			return nil
+		}
+		return err
+	}
+
+	if order.PrivateMetadata == nil {
+		order.PrivateMetadata = make(map[string]string)
+	}
+
+	if req.VatId == "" {
+		delete(order.PrivateMetadata, pkg.OrderPrivateMetadataVatId)
+
+		if err = s.orderVatIdRepository.DeleteByOrderId(ctx, order.Id); err != nil {
+			return err
+		}
+	} else {
+		country := order.GetCountry()
+
+		if _, ok := vatIdRules[country]; !ok {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = vatIdErrorCountryNotSupported
+			return nil
+		}
+
+		vatId := s.validateVatId(ctx, country, req.VatId)
+		vatId.Id = primitive.NewObjectID()
+		vatId.OrderId, _ = primitive.ObjectIDFromHex(order.Id)
+
+		if current, err := s.orderVatIdRepository.GetByOrderId(ctx, order.Id); err == nil {
+			vatId.Id = current.Id
+		}
+
+		if err = s.orderVatIdRepository.Upsert(ctx, vatId); err != nil {
+			return err
+		}
+
+		order.PrivateMetadata[pkg.OrderPrivateMetadataVatId] = vatId.VatId
+	}
+
+	processor := &OrderCreateRequestProcessor{Service: s, ctx: ctx}
+	err = processor.processOrderVat(order)
+
+	if err != nil {
+		zap.S().Errorw(pkg.MethodFinishedWithError, "err", err.Error(), "method", "processOrderVat")
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			res.Status = billingpb.ResponseStatusSystemError
+			res.Message = e
+			return nil
+		}
+		return err
+	}
+
+	err = s.setOrderChargeAmountAndCurrency(ctx, order)
+
+	if err != nil {
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = e
+			return nil
+		}
+		return err
+	}
+
+	if err = s.updateOrder(ctx, order); err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.VatId, err = s.getOrderVatId(ctx, order)
+
+	if err != nil {
+		return err
+	}
+
+	res.Item = &billingpb.ProcessBillingAddressResponseItem{
+		HasVat:               order.Tax.Rate > 0,
+		VatRate:              order.Tax.Rate,
+		Vat:                  order.Tax.Amount,
+		VatInChargeCurrency:  s.FormatAmount(order.GetTaxAmountInChargeCurrency(), order.Currency),
+		Amount:               order.OrderAmount,
+		TotalAmount:          order.TotalPaymentAmount,
+		Currency:             order.Currency,
+		ChargeCurrency:       order.ChargeCurrency,
+		ChargeAmount:         order.ChargeAmount,
+		Items:                order.Items,
+		CountryChangeAllowed: order.CountryChangeAllowed(),
+	}
+
+	return nil
+}
+
+// validateVatId checks the VAT ID with the format and checksum rules of the country and confirms
+// the valid VAT ID with the external validator if it is set.
+func (s *Service) validateVatId(ctx context.Context, country, vatId string) *intPkg.OrderVatId {
+	result := &intPkg.OrderVatId{
+		Country:          country,
+		ValidationSource: intPkg.VatIdValidationSourceOffline,
+		ValidatedAt:      time.Now().UTC(),
+	}
+
+	rule := vatIdRules[country]
+	value := vatIdCleanRegexp.ReplaceAllString(strings.ToUpper(vatId), "")
+	value = strings.TrimPrefix(value, rule.prefix)
+	result.VatId = rule.prefix + value
+
+	if !rule.format.MatchString(value) || (rule.checksum != nil && !rule.checksum(value)) {
+		return result
+	}
+
+	result.IsValid = true
+
+	if s.vatIdValidator == nil {
+		return result
+	}
+
+	isValid, err := s.vatIdValidator.Validate(ctx, country, result.VatId)
+
+	if err != nil {
+		zap.L().Warn(
+			"External vat id validator return error, offline validation result used",
+			zap.Error(err),
+			zap.String("vat_id", result.VatId),
+		)
+		return result
+	}
+
+	result.IsValid = isValid
+	result.ValidationSource = intPkg.VatIdValidationSourceExternal
+
+	return result
+}
+
+// getOrderVatId returns the VAT ID of the order stored by the server, nil is returned if the VAT ID isn't set.
+func (s *Service) getOrderVatId(ctx context.Context, order *billingpb.Order) (*intPkg.OrderVatId, error) {
+	vatId, err := s.orderVatIdRepository.GetByOrderId(ctx, order.Id)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	vatId.IsReverseCharge = order.PrivateMetadata[pkg.OrderPrivateMetadataReverseCharge] == "true"
+
+	return vatId, nil
+}
+
+// isOrderReverseCharge checks that the order has the valid VAT ID of the billing country of the order.
+// Only the validation result stored by the server is used, the metadata of the order is never trusted.
+func (s *Service) isOrderReverseCharge(ctx context.Context, order *billingpb.Order) (bool, error) {
+	vatId, err := s.getOrderVatId(ctx, order)
+
+	if err != nil || vatId == nil {
+		return false, err
+	}
+
+	return vatId.IsValid && vatId.Country == order.GetCountry(), nil
+}
+
+func vatIdDigits(value string) []int {
+	digits := make([]int, len(value))
+
+	for i, c := range value {
+		digits[i] = int(c - '0')
+	}
+
+	return digits
+}
+
+func vatIdWeightedSum(digits, weights []int) int {
+	sum := 0
+
+	for i, w := range weights {
+		sum += digits[i] * w
+	}
+
+	return sum
+}
+
+func vatIdChecksumAT(value string) bool {
+	d := vatIdDigits(value[1:])
+	sum := d[0] + d[2] + d[4] + d[6]
+
+	for _, i := range []int{1, 3, 5} {
+		sum += d[i]*2/10 + d[i]*2%10
+	}
+
+	return (10-(sum+4)%10)%10 == d[7]
+}
+
+func vatIdChecksumBE(value string) bool {
+	base, _ := strconv.Atoi(value[:8])
+	check, _ := strconv.Atoi(value[8:])
+
+	return 97-base%97 == check
+}
+
+func vatIdChecksumDE(value string) bool {
+	d := vatIdDigits(value)
+	product := 10
+
+	for _, digit := range d[:8] {
+		sum := (digit + product) % 10
+
+		if sum == 0 {
+			sum = 10
+		}
+
+		product = (2 * sum) % 11
+	}
+
+	check := 11 - product
+
+	if check == 10 {
+		check = 0
+	}
+
+	return check == d[8]
+}
+
+func vatIdChecksumDK(value string) bool {
+	return vatIdWeightedSum(vatIdDigits(value), []int{2, 7, 6, 5, 4, 3, 2, 1})%11 == 0
+}
+
+func vatIdChecksumFI(value string) bool {
+	d := vatIdDigits(value)
+	rest := vatIdWeightedSum(d, []int{7, 9, 10, 5, 8, 4, 2}) % 11
+
+	if rest == 1 {
+		return false
+	}
+
+	if rest == 0 {
+		return d[7] == 0
+	}
+
+	return 11-rest == d[7]
+}
+
+func vatIdChecksumFR(value string) bool {
+	key, err := strconv.Atoi(value[:2])
+
+	// new format of the key contains letters and can't be checked offline
+	if err != nil {
+		return true
+	}
+
+	siren, _ := strconv.Atoi(value[2:])
+
+	return key == (12+3*(siren%97))%97
+}
+
+func vatIdChecksumLU(value string) bool {
+	base, _ := strconv.Atoi(value[:6])
+	check, _ := strconv.Atoi(value[6:])
+
+	return base%89 == check
+}
+
+func vatIdChecksumNL(value string) bool {
+	d := vatIdDigits(value[:9])
+	rest := vatIdWeightedSum(d, []int{9, 8, 7, 6, 5, 4, 3, 2}) % 11
+
+	if rest != 10 && rest == d[8] {
+		return true
+	}
+
+	// VAT IDs of sole proprietors issued since 2020 are checked by mod 97 of the full VAT ID
+	// where letters are replaced with numbers (N=23, L=21, B=11)
+	full := "2321" + value[:9] + "11" + value[10:]
+	rest = 0
+
+	for _, c := range full {
+		rest = (rest*10 + int(c-'0')) % 97
+	}
+
+	return rest == 1
+}
+
+func vatIdChecksumPL(value string) bool {
+	d := vatIdDigits(value)
+	rest := vatIdWeightedSum(d, []int{6, 5, 7, 2, 3, 4, 5, 6, 7}) % 11
+
+	return rest != 10 && rest == d[9]
+}
+
+func vatIdChecksumPT(value string) bool {
+	d := vatIdDigits(value)
+	check := 11 - vatIdWeightedSum(d, []int{9, 8, 7, 6, 5, 4, 3, 2})%11
+
+	if check > 9 {
+		check = 0
+	}
+
+	return check == d[8]
+}
+
+func vatIdChecksumSE(value string) bool {
+	return vatIdChecksumLuhn(value[:10])
+}
+
+func vatIdChecksumLuhn(value string) bool {
+	sum := 0
+
+	for i, digit := range vatIdDigits(value) {
+		if (len(value)-i)%2 == 0 {
+			digit *= 2
+
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+
+		sum += digit
+	}
+
+	return sum%10 == 0
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func Test_VatIdOfflineValidation(t *testing.T) {
+	service := &Service{}
+
+	cases := []struct {
+		country string
+		vatId   string
+		valid   bool
+	}{
+		{"AT", "ATU13585627", true},
+		{"AT", "ATU13585628", false},
+		{"BE", "BE 0403.170.701", true},
+		{"BE", "BE0403170702", false},
+		{"DE", "de136695976", true},
+		{"DE", "DE136695977", false},
+		{"DK", "DK13585628", true},
+		{"FI", "FI20774740", true},
+		{"FI", "20774741", false},
+		{"FR", "FR40303265045", true},
+		{"FR", "FR41303265045", false},
+		{"GR", "EL123456789", true},
+		{"IT", "IT00743110157", true},
+		{"IT", "IT00743110158", false},
+		{"LU", "LU15027442", true},
+		{"NL", "NL004495445B01", true},
+		{"NL", "NL000099998B57", true},
+		{"NL", "NL004495446B01", false},
+		{"PL", "PL5260250274", true},
+		{"PT", "PT501964843", true},
+		{"SE", "SE556188840401", true},
+		{"SE", "SE556188840402", false},
+		{"DE", "DE1234", false},
+	}
+
+	for _, c := range cases {
+		res := service.validateVatId(context.TODO(), c.country, c.vatId)
+		assert.Equal(t, c.valid, res.IsValid, c.vatId)
+		assert.Equal(t, intPkg.VatIdValidationSourceOffline, res.ValidationSource)
+	}
+
+	res := service.validateVatId(context.TODO(), "GR", "123456789")
+	assert.Equal(t, "EL123456789", res.VatId)
+}
+
+func Test_VatIdExternalValidation(t *testing.T) {
+	validator := &mocks.VatIdValidatorInterface{}
+	validator.On("Validate", mock.Anything, "DE", "DE136695976").Return(false, nil)
+	validator.On("Validate", mock.Anything, "FI", "FI20774740").Return(false, errors.New(mocks.SomeError))
+
+	service := &Service{}
+	service.SetVatIdValidator(validator)
+
+	res := service.validateVatId(context.TODO(), "DE", "DE136695976")
+	assert.False(t, res.IsValid)
+	assert.Equal(t, intPkg.VatIdValidationSourceExternal, res.ValidationSource)
+
+	res = service.validateVatId(context.TODO(), "FI", "FI20774740")
+	assert.True(t, res.IsValid)
+	assert.Equal(t, intPkg.VatIdValidationSourceOffline, res.ValidationSource)
+
+	res = service.validateVatId(context.TODO(), "DE", "DE136695977")
+	assert.False(t, res.IsValid)
+	validator.AssertNumberOfCalls(t, "Validate", 2)
+}
@@ -6,12 +6,12 @@ import (
 	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/jinzhu/now"
 	"github.com/paysuper/paysuper-billing-server/internal/helper"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	"github.com/paysuper/paysuper-proto/go/currenciespb"
 	"github.com/paysuper/paysuper-proto/go/postmarkpb"
 	"github.com/paysuper/paysuper-proto/go/taxpb"
-	tools "github.com/paysuper/paysuper-tools/number"
 	"github.com/streadway/amqp"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -262,6 +262,79 @@ func (s *Service) GetVatReportTransactions(
 	return nil
 }
 
+// GetVatReportReverseCharges returns the summary and the list of the reverse charge orders of the VAT report.
+func (s *Service) GetVatReportReverseCharges(
+	ctx context.Context,
+	req *billingpb.VatTransactionsRequest,
+	res *intPkg.VatReportReverseChargesResponse,
+) error {
+	vr, err := s.vatReportRepository.GetById(ctx, req.VatReportId)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			res.Status = billingpb.ResponseStatusNotFound
+			res.Message = errorVatReportNotFound
+			return nil
+		}
+
+		res.Status = billingpb.ResponseStatusSystemError
+		res.Message = errorVatReportQueryError
+		return nil
+	}
+
+	from, err := ptypes.Timestamp(vr.DateFrom)
+	if err != nil {
+		res.Status = billingpb.ResponseStatusSystemError
+		res.Message = errorVatReportInternal
+		return nil
+	}
+	to, err := ptypes.Timestamp(vr.DateTo)
+	if err != nil {
+		res.Status = billingpb.ResponseStatusSystemError
+		res.Message = errorVatReportInternal
+		return nil
+	}
+
+	res.Summary, err = s.vatReportReverseChargeRepository.GetByVatReportId(ctx, vr.Id)
+
+	if err != nil && err != mongo.ErrNoDocuments {
+		res.Status = billingpb.ResponseStatusSystemError
+		res.Message = errorVatReportQueryError
+		return nil
+	}
+
+	match := bson.M{
+		"pm_order_close_date": bson.M{
+			"$gte": now.New(from).BeginningOfDay(),
+			"$lte": now.New(to).EndOfDay(),
+		},
+		"country_code":         vr.Country,
+		"operating_company_id": vr.OperatingCompanyId,
+		"is_production":        true,
+		"is_reverse_charge":    true,
+	}
+
+	n, err := s.orderViewRepository.CountTransactions(ctx, match)
+
+	if err != nil {
+		return err
+	}
+
+	vts, err := s.orderViewRepository.GetTransactionsPrivate(ctx, match, req.Limit, req.Offset)
+
+	if err != nil {
+		return err
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+	res.Data = &billingpb.PrivateTransactionsPaginate{
+		Count: int32(n),
+		Items: vts,
+	}
+
+	return nil
+}
+
 func (s *Service) ProcessVatReports(
 	ctx context.Context,
 	req *billingpb.ProcessVatReportsRequest,
@@ -584,7 +657,8 @@ func (h *vatReportProcessor) processVatReportForPeriod(ctx context.Context, coun
 		Country: country.IsoCodeA2,
 	}
 
-	rsp, err := h.Service.tax.GetRate(ctx, req)
+	// the threshold is checked by the report status, so the report keeps the rate of the country
+	rsp, err := h.Service.getTaxRate(ctx, req, "")
 	if err != nil {
 		zap.L().Error(errorMsgVatReportTaxServiceGetRateFailed, zap.Error(err))
 		return err
@@ -628,7 +702,7 @@ func (h *vatReportProcessor) processVatReportForPeriod(ctx context.Context, coun
 		)
 		return nil
 	}
-	report.CountryAnnualTurnover = tools.FormatAmount(countryTurnover.Amount)
+	report.CountryAnnualTurnover = h.newMoney(countryTurnover.Amount, countryTurnover.Currency).Round().Float64()
 
 	worldTurnover, err := h.Service.turnoverRepository.Get(ctx, operatingCompanyId, "", from.Year())
 
@@ -658,7 +732,7 @@ func (h *vatReportProcessor) processVatReportForPeriod(ctx context.Context, coun
 		}
 	}
 
-	report.WorldAnnualTurnover = tools.FormatAmount(report.WorldAnnualTurnover)
+	report.WorldAnnualTurnover = h.newMoney(report.WorldAnnualTurnover, targetCurrency).Round().Float64()
 
 	isLastDayOfPeriod := h.date.Unix() == to.Unix()
 	isCurrencyRatesPolicyOnDay := country.VatCurrencyRatesPolicy == pkg.VatCurrencyRatesPolicyOnDay
@@ -670,11 +744,22 @@ func (h *vatReportProcessor) processVatReportForPeriod(ctx context.Context, coun
 		return err
 	}
 
+	feesAmount := h.newMoney(0, targetCurrency)
+
 	if len(res) == 1 {
 		report.TransactionsCount = res[0].Count
-		report.GrossRevenue = tools.FormatAmount(res[0].PaymentGrossRevenueLocal - res[0].PaymentRefundGrossRevenueLocal)
-		report.VatAmount = tools.FormatAmount(res[0].PaymentTaxFeeLocal - res[0].PaymentRefundTaxFeeLocal)
-		report.FeesAmount = res[0].PaymentFeesTotal + res[0].PaymentRefundFeesTotal
+		report.GrossRevenue = h.newMoney(res[0].PaymentGrossRevenueLocal, targetCurrency).
+			Sub(h.newMoney(res[0].PaymentRefundGrossRevenueLocal, targetCurrency)).
+			Round().
+			Float64()
+		report.VatAmount = h.newMoney(res[0].PaymentTaxFeeLocal, targetCurrency).
+			Sub(h.newMoney(res[0].PaymentRefundTaxFeeLocal, targetCurrency)).
+			Round().
+			Float64()
+		feesAmount = feesAmount.Add(
+			h.newMoney(res[0].PaymentFeesTotal, targetCurrency),
+			h.newMoney(res[0].PaymentRefundFeesTotal, targetCurrency),
+		)
 	}
 
 	res, err = h.orderViewRepository.GetVatSummary(h.ctx, operatingCompanyId, country.IsoCodeA2, true, from, to)
@@ -685,26 +770,81 @@ func (h *vatReportProcessor) processVatReportForPeriod(ctx context.Context, coun
 
 	if len(res) == 1 {
 		report.TransactionsCount += res[0].Count
-		report.DeductionAmount = tools.FormatAmount(res[0].PaymentRefundTaxFeeLocal)
-		report.FeesAmount += res[0].PaymentFeesTotal + res[0].PaymentRefundFeesTotal
+		report.DeductionAmount = h.newMoney(res[0].PaymentRefundTaxFeeLocal, targetCurrency).Round().Float64()
+		feesAmount = feesAmount.Add(
+			h.newMoney(res[0].PaymentFeesTotal, targetCurrency),
+			h.newMoney(res[0].PaymentRefundFeesTotal, targetCurrency),
+		)
 	}
 
-	report.FeesAmount = tools.FormatAmount(report.FeesAmount)
+	report.FeesAmount = feesAmount.Round().Float64()
 
 	vr, err := h.vatReportRepository.GetByCountryPeriod(ctx, report.Country, from, to)
 
 	if err == mongo.ErrNoDocuments {
-		return h.Service.vatReportRepository.Insert(ctx, report)
+		err = h.Service.vatReportRepository.Insert(ctx, report)
+	} else if err == nil {
+		report.Id = vr.Id
+		report.CreatedAt = vr.CreatedAt
+		err = h.Service.updateVatReport(ctx, report)
 	}
 
 	if err != nil {
 		return err
 	}
 
-	report.Id = vr.Id
-	report.CreatedAt = vr.CreatedAt
-	return h.Service.updateVatReport(ctx, report)
+	return h.processVatReportReverseCharges(ctx, report, from, to)
+}
+
+// processVatReportReverseCharges saves the summary of the reverse charge orders of the VAT report period.
+// These orders are not included to the payable VAT of the report.
+func (h *vatReportProcessor) processVatReportReverseCharges(
+	ctx context.Context,
+	report *billingpb.VatReport,
+	from, to time.Time,
+) error {
+	res, err := h.orderViewRepository.GetVatReverseChargeSummary(ctx, report.OperatingCompanyId, report.Country, from, to)
 
+	if err != nil {
+		return err
+	}
+
+	summary, err := h.vatReportReverseChargeRepository.GetByVatReportId(ctx, report.Id)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		if len(res) == 0 {
+			return nil
+		}
+
+		summary = &intPkg.VatReportReverseCharge{
+			Id:          primitive.NewObjectID(),
+			VatReportId: report.Id,
+			CreatedAt:   time.Now(),
+		}
+	}
+
+	summary.Country = report.Country
+	summary.OperatingCompanyId = report.OperatingCompanyId
+	summary.Currency = report.Currency
+	summary.DateFrom = from
+	summary.DateTo = to
+	summary.TransactionsCount = 0
+	summary.GrossRevenue = 0
+	summary.UpdatedAt = time.Now()
+
+	if len(res) == 1 {
+		summary.TransactionsCount = res[0].Count
+		summary.GrossRevenue = h.newMoney(res[0].PaymentGrossRevenueLocal, summary.Currency).
+			Sub(h.newMoney(res[0].PaymentRefundGrossRevenueLocal, summary.Currency)).
+			Round().
+			Float64()
+	}
+
+	return h.vatReportReverseChargeRepository.Upsert(ctx, summary)
 }
 
 func (h *vatReportProcessor) processAccountingEntriesForPeriod(ctx context.Context, country *billingpb.Country) error {
@@ -748,6 +888,12 @@ func (h *vatReportProcessor) processAccountingEntriesForPeriod(ctx context.Conte
 		return nil
 	}
 
+	aes, err = h.getActiveAccountingEntries(h.ctx, aes)
+
+	if err != nil {
+		return err
+	}
+
 	var aesRealTaxFee = make(map[string]*billingpb.AccountingEntry)
 	for _, ae := range aes {
 		if ae.Type != pkg.AccountingEntryTypeRealTaxFee {
@@ -779,7 +925,10 @@ func (h *vatReportProcessor) processAccountingEntriesForPeriod(ctx context.Conte
 		if ae.Type == pkg.AccountingEntryTypeCentralBankTaxFee {
 			realTaxFee, ok := aesRealTaxFee[ae.Source.Id]
 			if ok {
-				ae.LocalAmount = ae.LocalAmount - realTaxFee.LocalAmount
+				ae.LocalAmount = h.newMoney(ae.LocalAmount, ae.LocalCurrency).
+					Sub(h.newMoney(realTaxFee.LocalAmount, realTaxFee.LocalCurrency)).
+					RoundTo(intPkg.MoneyAccountingPrecision).
+					Float64()
 			}
 		}
 		if amount == ae.LocalAmount {
@@ -795,7 +944,7 @@ func (h *vatReportProcessor) processAccountingEntriesForPeriod(ctx context.Conte
 		return nil
 	}
 
-	if err = h.accountingRepository.BulkWrite(h.ctx, list); err != nil {
+	if err = h.reverseAccountingEntries(h.ctx, list); err != nil {
 		return err
 	}
 
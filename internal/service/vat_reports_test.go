@@ -11,6 +11,7 @@ import (
 	"github.com/paysuper/paysuper-billing-server/internal/config"
 	"github.com/paysuper/paysuper-billing-server/internal/database"
 	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
@@ -453,3 +454,125 @@ func (suite *VatReportsTestSuite) TestVatReports_ProcessVatReports_OnlyTestOrder
 
 	assert.NoError(suite.T(), err)
 }
+
+func (suite *VatReportsTestSuite) TestVatReports_ProcessVatReports_ReverseCharge() {
+	suite.projectFixedAmount.Status = billingpb.ProjectStatusInProduction
+	if err := suite.service.project.Update(context.TODO(), suite.projectFixedAmount); err != nil {
+		suite.FailNow("Update project test data failed", "%v", err)
+	}
+
+	HelperCreateAndPayOrder(suite.Suite, suite.service, 10, "USD", "FI", suite.projectFixedAmount, suite.paymentMethod)
+
+	req := &billingpb.OrderCreateRequest{
+		Type:        pkg.OrderType_simple,
+		ProjectId:   suite.projectFixedAmount.Id,
+		Amount:      10,
+		Currency:    "USD",
+		Account:     "unit test",
+		Description: "unit test",
+		OrderId:     primitive.NewObjectID().Hex(),
+		User: &billingpb.OrderUser{
+			Id:    primitive.NewObjectID().Hex(),
+			Email: "test@unit.unit",
+			Ip:    "127.0.0.1",
+			Address: &billingpb.OrderBillingAddress{
+				Country: "FI",
+			},
+		},
+	}
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateProcess(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.True(suite.T(), rsp.Item.Tax.Rate > 0)
+
+	vatIdRsp := &intPkg.SetOrderVatIdResponse{}
+	err = suite.service.SetOrderVatId(context.TODO(), &intPkg.SetOrderVatIdRequest{OrderId: rsp.Item.Uuid, VatId: "FI 2077 4740"}, vatIdRsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, vatIdRsp.Status)
+	assert.Equal(suite.T(), "FI20774740", vatIdRsp.VatId.VatId)
+	assert.True(suite.T(), vatIdRsp.VatId.IsValid)
+	assert.True(suite.T(), vatIdRsp.VatId.IsReverseCharge)
+	assert.False(suite.T(), vatIdRsp.Item.HasVat)
+	assert.EqualValues(suite.T(), 0, vatIdRsp.Item.Vat)
+
+	order, err := suite.service.getOrderByUuid(context.TODO(), rsp.Item.Uuid)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 0, order.Tax.Rate)
+	assert.Equal(suite.T(), "FI20774740", order.PrivateMetadata[pkg.OrderPrivateMetadataVatId])
+	assert.Equal(suite.T(), "true", order.PrivateMetadata[pkg.OrderPrivateMetadataReverseCharge])
+
+	HelperPayOrder(suite.Suite, suite.service, order, suite.paymentMethod, "FI")
+
+	err = suite.service.ProcessVatReports(context.TODO(), &billingpb.ProcessVatReportsRequest{Date: ptypes.TimestampNow()}, &billingpb.EmptyResponse{})
+	assert.NoError(suite.T(), err)
+
+	repRes := billingpb.VatReportsResponse{}
+	err = suite.service.GetVatReportsForCountry(context.TODO(), &billingpb.VatReportsRequest{Country: "FI"}, &repRes)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, repRes.Status)
+	assert.Equal(suite.T(), int32(1), repRes.Data.Count)
+	assert.EqualValues(suite.T(), 1, repRes.Data.Items[0].TransactionsCount)
+
+	rcRes := &intPkg.VatReportReverseChargesResponse{}
+	err = suite.service.GetVatReportReverseCharges(context.TODO(), &billingpb.VatTransactionsRequest{VatReportId: repRes.Data.Items[0].Id, Limit: 10}, rcRes)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rcRes.Status)
+	assert.NotNil(suite.T(), rcRes.Summary)
+	assert.EqualValues(suite.T(), 1, rcRes.Summary.TransactionsCount)
+	assert.EqualValues(suite.T(), 1, rcRes.Data.Count)
+	assert.Equal(suite.T(), order.Uuid, rcRes.Data.Items[0].Uuid)
+}
+
+func (suite *VatReportsTestSuite) TestVatReports_OrderCreate_ForgedVatIdMetadata_NoReverseCharge() {
+	suite.projectFixedAmount.Status = billingpb.ProjectStatusInProduction
+	if err := suite.service.project.Update(context.TODO(), suite.projectFixedAmount); err != nil {
+		suite.FailNow("Update project test data failed", "%v", err)
+	}
+
+	req := &billingpb.OrderCreateRequest{
+		Type:        pkg.OrderType_simple,
+		ProjectId:   suite.projectFixedAmount.Id,
+		Amount:      10,
+		Currency:    "USD",
+		Account:     "unit test",
+		Description: "unit test",
+		OrderId:     primitive.NewObjectID().Hex(),
+		User: &billingpb.OrderUser{
+			Id:    primitive.NewObjectID().Hex(),
+			Email: "test@unit.unit",
+			Ip:    "127.0.0.1",
+			Address: &billingpb.OrderBillingAddress{
+				Country: "FI",
+			},
+		},
+		PrivateMetadata: map[string]string{
+			pkg.OrderPrivateMetadataVatId:         "FI20774740",
+			pkg.OrderPrivateMetadataVatIdCountry:  "FI",
+			pkg.OrderPrivateMetadataVatIdValid:    "true",
+			pkg.OrderPrivateMetadataReverseCharge: "true",
+			"source":                              "unit test",
+		},
+	}
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateProcess(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.True(suite.T(), rsp.Item.Tax.Rate > 0)
+
+	order, err := suite.service.getOrderByUuid(context.TODO(), rsp.Item.Uuid)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), order.Tax.Rate > 0)
+	assert.Equal(suite.T(), "unit test", order.PrivateMetadata["source"])
+	assert.NotContains(suite.T(), order.PrivateMetadata, pkg.OrderPrivateMetadataVatId)
+	assert.NotContains(suite.T(), order.PrivateMetadata, pkg.OrderPrivateMetadataVatIdValid)
+	assert.NotContains(suite.T(), order.PrivateMetadata, pkg.OrderPrivateMetadataReverseCharge)
+
+	// the metadata written to the order directly is not trusted too
+	order.PrivateMetadata[pkg.OrderPrivateMetadataVatIdCountry] = "FI"
+	order.PrivateMetadata[pkg.OrderPrivateMetadataVatIdValid] = "true"
+
+	reverseCharge, err := suite.service.isOrderReverseCharge(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), reverseCharge)
+}
@@ -157,7 +157,16 @@ func (s *Service) SendWebhookToMerchant(
 	}
 
 	processor.processMetadata()
-	processor.processPrivateMetadata()
+
+	if err := processor.processPrivateMetadata(); err != nil {
+		zap.S().Errorw(pkg.MethodFinishedWithError, "err", err.Error())
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = e
+			return nil
+		}
+		return err
+	}
 
 	order, err := processor.prepareOrder()
 	if err != nil {
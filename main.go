@@ -40,6 +40,33 @@ func main() {
 
 		case "fix_taxes":
 			err = app.TaskFixTaxes()
+
+		case "price_table_generate":
+			err = app.TaskGeneratePriceTable()
+
+		case "invoices_process":
+			err = app.TaskProcessInvoices()
+
+		case "gifts_deliver":
+			err = app.TaskDeliverGifts()
+
+		case "accounting_integrity_check":
+			err = app.TaskCheckAccountingIntegrity(date)
+
+		case "general_ledger_export":
+			err = app.TaskExportGeneralLedger(date)
+
+		case "accounting_chain_verify":
+			err = app.TaskVerifyAccountingChain()
+
+		case "merchant_balance_reconcile":
+			err = app.TaskReconcileMerchantBalances()
+
+		case "rolling_reserve_release":
+			err = app.TaskReleaseRollingReserves()
+
+		case "merchant_debt_escalate":
+			err = app.TaskEscalateMerchantDebts()
 		}
 
 		if err != nil {
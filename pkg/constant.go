@@ -191,9 +191,21 @@ const (
 	PayoutDocumentStatusFailed   = "failed"
 
 	OrderIssuerReferenceTypePaylink = "paylink"
+	OrderIssuerReferenceTypeInvoice = "invoice"
 
 	PaylinkUrlDefaultMask = "/paylink/%s"
 
+	OrderPrivateMetadataGiftRecipientEmail = "GiftRecipientEmail"
+	OrderPrivateMetadataGiftMessage        = "GiftMessage"
+	OrderPrivateMetadataGiftDeliveryDate   = "GiftDeliveryDate"
+
+	OrderPrivateMetadataVatId                 = "VatId"
+	OrderPrivateMetadataVatIdCountry          = "VatIdCountry"
+	OrderPrivateMetadataVatIdValid            = "VatIdValid"
+	OrderPrivateMetadataVatIdValidationSource = "VatIdValidationSource"
+	OrderPrivateMetadataVatIdValidatedAt      = "VatIdValidatedAt"
+	OrderPrivateMetadataReverseCharge         = "ReverseCharge"
+
 	DatabaseRequestDefaultLimit = int64(100)
 
 	ProjectSellCountTypeFractional = "fractional"
@@ -231,6 +243,7 @@ const (
 	PayoutsUrl                 = "%s/payouts"
 	ReceiptPurchaseUrl         = "%s/pay/receipt/purchase/%s/%s"
 	ReceiptRefundUrl           = "%s/pay/receipt/refund/%s/%s"
+	InvoiceCheckoutUrl         = "%s/pay/invoice/%s"
 	MerchantCompanyUrl         = "%s/company"
 	AdminCompanyUrl            = "%s/merchants/%s/company-info"
 	AdminOnboardingRequestsUrl = "%s/agreement-requests"